@@ -0,0 +1,107 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytes_test
+
+import (
+	. "bytes"
+	"iter"
+	"testing"
+	"unicode"
+)
+
+func collect(t *testing.T, seq iter.Seq[[]byte]) []string {
+	var out []string
+	for s := range seq {
+		if len(s) != cap(s) {
+			t.Errorf("yielded slice %q has cap %d, want %d", s, cap(s), len(s))
+		}
+		out = append(out, string(s))
+	}
+	return out
+}
+
+func TestLines(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want []string
+	}{
+		{"", nil},
+		{"\n", []string{"\n"}},
+		{"a", []string{"a"}},
+		{"a\nb", []string{"a\n", "b"}},
+		{"a\nb\n", []string{"a\n", "b\n"}},
+		{"a\n\nb\r\n", []string{"a\n", "\n", "b\r\n"}},
+	} {
+		if got := collect(t, Lines([]byte(tt.s))); !eq(got, tt.want) {
+			t.Errorf("Lines(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestSplitSeq(t *testing.T) {
+	for _, tt := range splittests {
+		if tt.n != -1 {
+			continue
+		}
+		got := collect(t, SplitSeq([]byte(tt.s), []byte(tt.sep)))
+		if want := sliceOfString(Split([]byte(tt.s), []byte(tt.sep))); !eq(got, want) {
+			t.Errorf("SplitSeq(%q, %q) = %q, want %q", tt.s, tt.sep, got, want)
+		}
+	}
+}
+
+func TestSplitAfterSeq(t *testing.T) {
+	for _, tt := range splitaftertests {
+		if tt.n != -1 {
+			continue
+		}
+		got := collect(t, SplitAfterSeq([]byte(tt.s), []byte(tt.sep)))
+		if want := sliceOfString(SplitAfter([]byte(tt.s), []byte(tt.sep))); !eq(got, want) {
+			t.Errorf("SplitAfterSeq(%q, %q) = %q, want %q", tt.s, tt.sep, got, want)
+		}
+	}
+}
+
+func TestFieldsSeq(t *testing.T) {
+	for _, tt := range fieldstests {
+		got := collect(t, FieldsSeq([]byte(tt.s)))
+		if want := sliceOfString(Fields([]byte(tt.s))); !eq(got, want) {
+			t.Errorf("FieldsSeq(%q) = %q, want %q", tt.s, got, want)
+		}
+	}
+}
+
+func TestFieldsFuncSeq(t *testing.T) {
+	pred := func(c rune) bool { return c == 'X' || unicode.IsSpace(c) }
+	for _, s := range []string{"", "XX", "XXhiXXX", "aXXbXXXcX", "  a b ", " x　y"} {
+		got := collect(t, FieldsFuncSeq([]byte(s), pred))
+		if want := sliceOfString(FieldsFunc([]byte(s), pred)); !eq(got, want) {
+			t.Errorf("FieldsFuncSeq(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestSeqBreak(t *testing.T) {
+	seqs := map[string]iter.Seq[[]byte]{
+		"Lines":          Lines([]byte("a\nb\nc\n")),
+		"SplitSeq":       SplitSeq([]byte("a,b,c"), []byte(",")),
+		"SplitSeq/runes": SplitSeq([]byte("abc"), nil),
+		"SplitAfterSeq":  SplitAfterSeq([]byte("a,b,c"), []byte(",")),
+		"FieldsSeq":      FieldsSeq([]byte("a b c")),
+		"FieldsFuncSeq":  FieldsFuncSeq([]byte("a,b,c"), func(r rune) bool { return r == ',' }),
+	}
+	for name, seq := range seqs {
+		n := 0
+		for range seq {
+			n++
+			if n == 2 {
+				break
+			}
+		}
+		if n != 2 {
+			t.Errorf("%s: got %d iterations, want 2", name, n)
+		}
+	}
+}
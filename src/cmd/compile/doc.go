@@ -104,6 +104,9 @@ Flags:
 		and diagnose imports that would cause a circular dependency.
 	-pack
 		Write a package (archive) file rather than an object file
+	-pgoprofile file
+		Use the CPU profile in file, in the format written by runtime/pprof,
+		to guide inlining and devirtualization of hot call sites.
 	-race
 		Compile with race detector enabled.
 	-s
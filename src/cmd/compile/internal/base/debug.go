@@ -29,9 +29,15 @@ type DebugFlags struct {
 	InlFuncsWithClosures int    `help:"allow functions with closures to be inlined"`
 	Libfuzzer            int    `help:"enable coverage instrumentation for libfuzzer"`
 	LocationLists        int    `help:"print information about DWARF location list creation"`
+	LoopVar              int    `help:"per-iteration loop variables\n0: per-iteration for go1.18 and later (default)\n1: also report loops whose variables became per-iteration\n2: report, and use per-iteration variables regardless of -lang"`
 	Nil                  int    `help:"print information about nil checks"`
 	NoOpenDefer          int    `help:"disable open-coded defers"`
 	PCTab                string `help:"print named pc-value table\nOne of: pctospadj, pctofile, pctoline, pctoinline, pctopcdata"`
+	PGODebug             int    `help:"debug profile-guided optimizations"`
+	PGODevirtualize      int    `help:"enable profile-guided devirtualization"`
+	PGOHotCDFThreshold   int    `help:"percentage of profile weight covered by hot call sites"`
+	PGOInline            int    `help:"enable profile-guided inlining"`
+	PGOInlineBudget      int    `help:"inline budget for hot functions"`
 	Panic                int    `help:"show all compiler panics"`
 	Slice                int    `help:"print information about slice compilation"`
 	SoftFloat            int    `help:"force compiler to emit soft-float code"`
@@ -113,6 +113,7 @@ type CmdFlags struct {
 	MutexProfile       string       "help:\"write mutex profile to `file`\""
 	NoLocalImports     bool         "help:\"reject local (relative) imports\""
 	Pack               bool         "help:\"write to file.a instead of file.o\""
+	PgoProfile         string       "help:\"read profile from `file`\""
 	Race               bool         "help:\"enable race detector\""
 	Shared             *bool        "help:\"generate code that can be linked into a shared library\"" // &Ctxt.Flag_shared, set below
 	SmallFrames        bool         "help:\"reduce the size limit for stack allocated objects\""      // small stacks, to diagnose GC latency; see golang.org/issue/27732
@@ -163,6 +164,10 @@ func ParseFlags() {
 	Flag.WB = true
 
 	Debug.InlFuncsWithClosures = 1
+	Debug.PGODevirtualize = 1
+	Debug.PGOHotCDFThreshold = 99
+	Debug.PGOInline = 1
+	Debug.PGOInlineBudget = 2000
 	if buildcfg.Experiment.Unified {
 		Debug.Unified = 1
 	}
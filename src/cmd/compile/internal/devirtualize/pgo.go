@@ -0,0 +1,223 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package devirtualize
+
+import (
+	"fmt"
+	"strings"
+
+	"cmd/compile/internal/base"
+	"cmd/compile/internal/ir"
+	"cmd/compile/internal/pgo"
+	"cmd/compile/internal/typecheck"
+	"cmd/compile/internal/types"
+	"cmd/internal/objabi"
+	"cmd/internal/src"
+)
+
+// ProfileGuided performs profile-guided devirtualization of hot
+// interface method calls within fn.
+//
+// If the profile shows that a hot call
+//
+//	i.M(args)
+//
+// most often calls the method M of concrete type T, the call is
+// rewritten to
+//
+//	if t, ok := i.(T); ok {
+//		t.M(args)
+//	} else {
+//		i.M(args)
+//	}
+//
+// The direct call to t.M is then a candidate for inlining.
+func ProfileGuided(fn *ir.Func, p *pgo.Profile) {
+	ir.CurFunc = fn
+
+	// Calls in go and defer statements must stay calls.
+	goDeferCall := make(map[*ir.CallExpr]bool)
+
+	var edit func(n ir.Node) ir.Node
+	edit = func(n ir.Node) ir.Node {
+		if n == nil {
+			return n
+		}
+		if gds, ok := n.(*ir.GoDeferStmt); ok {
+			if call, ok := gds.Call.(*ir.CallExpr); ok {
+				goDeferCall[call] = true
+			}
+		}
+
+		ir.EditChildren(n, edit)
+
+		call, ok := n.(*ir.CallExpr)
+		if !ok || call.Op() != ir.OCALLINTER || goDeferCall[call] {
+			return n
+		}
+		typ := hotConcreteType(fn, call, p)
+		if typ == nil {
+			return n
+		}
+		if base.Flag.LowerM != 0 {
+			base.WarnfAt(call.Pos(), "PGO devirtualizing %v to %v", call.X, typ)
+		}
+		return rewriteCondCall(call, fn, typ)
+	}
+	ir.EditChildren(fn, edit)
+}
+
+// hotConcreteType returns the concrete receiver type of the hottest
+// callee of call in the profile, or nil if there is none suitable.
+func hotConcreteType(fn *ir.Func, call *ir.CallExpr, p *pgo.Profile) *types.Type {
+	sel := call.X.(*ir.SelectorExpr)
+	if r := ir.StaticValue(sel.X); r.Op() == ir.OCONVIFACE && !r.(*ir.ConvExpr).X.Type().IsInterface() {
+		// Statically devirtualizable; leave it to Func.
+		return nil
+	}
+	if len(call.Args) == 1 && call.Args[0].Type().IsFuncArgStruct() {
+		return nil
+	}
+	iface := sel.X.Type()
+
+	cs := pgo.CallSiteOf(fn, call.Pos())
+	for _, hot := range p.HotCallees(cs.Caller, cs.Line) {
+		typ := receiverType(hot.Callee, sel.Sel.Name)
+		if typ == nil {
+			continue
+		}
+		if op, _ := typecheck.Assignop(typ, iface); op != ir.OCONVIFACE {
+			continue
+		}
+		if base.Debug.PGODebug > 0 {
+			fmt.Printf("%v: hot call %v calls %s\n", ir.Line(call), call.X, hot.Callee)
+		}
+		return typ
+	}
+	return nil
+}
+
+// receiverType returns the receiver type of the method whose linker
+// symbol name is callee, provided that the method is named method and
+// its receiver type is declared in the local package or a directly
+// imported one. Otherwise it returns nil.
+func receiverType(callee, method string) *types.Type {
+	recv := strings.TrimSuffix(callee, "."+method)
+	if recv == callee || strings.Contains(recv, "[") {
+		// Not the method we are looking for, or a method of
+		// a generic type.
+		return nil
+	}
+	ptr := false
+	if strings.HasSuffix(recv, ")") {
+		i := strings.LastIndex(recv, ".(*")
+		if i < 0 {
+			return nil
+		}
+		recv = recv[:i] + "." + recv[i+len(".(*"):len(recv)-len(")")]
+		ptr = true
+	}
+	dot := strings.LastIndex(recv, ".")
+	if dot < 0 {
+		return nil
+	}
+	prefix, name := recv[:dot], recv[dot+1:]
+
+	var pkg *types.Pkg
+	if prefix == objabi.PathToPrefix(base.Ctxt.Pkgpath) {
+		pkg = types.LocalPkg
+	} else {
+		for _, p := range types.ImportedPkgList() {
+			if p.Prefix == prefix {
+				pkg = p
+				break
+			}
+		}
+	}
+	if pkg == nil {
+		return nil
+	}
+
+	sym, ok := pkg.LookupOK(name)
+	if !ok && pkg == types.LocalPkg {
+		return nil
+	}
+	n := typecheck.Resolve(ir.NewIdent(src.NoXPos, sym))
+	if n.Op() != ir.OTYPE || n.Type() == nil || n.Type().HasTParam() || n.Type().IsInterface() {
+		return nil
+	}
+	typ := n.Type()
+	if ptr {
+		typ = types.NewPtr(typ)
+	}
+	return typ
+}
+
+// rewriteCondCall rewrites the interface method call call in curfn
+// into a conditional direct call to the method of typ, as described
+// at ProfileGuided. The result replaces call.
+func rewriteCondCall(call *ir.CallExpr, curfn *ir.Func, typ *types.Type) ir.Node {
+	sel := call.X.(*ir.SelectorExpr)
+	pos := call.Pos()
+	init := ir.TakeInit(call)
+
+	// Evaluate the receiver and the arguments exactly once, ahead
+	// of the type assertion, so that both branches see the same values.
+	recv := copyExpr(pos, curfn, sel.X, &init)
+	sel.X = recv
+	args := make([]ir.Node, len(call.Args))
+	for i, arg := range call.Args {
+		args[i] = copyExpr(pos, curfn, arg, &init)
+	}
+	call.Args = args
+
+	tmp := typecheck.TempAt(pos, curfn, typ)
+	ok := typecheck.TempAt(pos, curfn, types.Types[types.TBOOL])
+	assert := ir.NewTypeAssertExpr(pos, recv, nil)
+	assert.SetType(typ)
+	init.Append(typecheck.Stmt(ir.NewAssignListStmt(pos, ir.OAS2, []ir.Node{tmp, ok}, []ir.Node{assert})))
+
+	callee := typecheck.Callee(ir.NewSelectorExpr(pos, ir.OXDOT, tmp, sel.Sel))
+	direct := typecheck.Call(pos, callee, append([]ir.Node(nil), args...), call.IsDDD)
+
+	// Assign the results, if any, to temporaries that become the
+	// value of the rewritten expression.
+	var retvars []ir.Node
+	thenStmt, elseStmt := ir.Node(direct), ir.Node(call)
+	if results := call.X.Type().Results(); results.NumFields() > 0 {
+		for _, f := range results.FieldSlice() {
+			retvars = append(retvars, typecheck.TempAt(pos, curfn, f.Type))
+		}
+		if len(retvars) == 1 {
+			thenStmt = ir.NewAssignStmt(pos, retvars[0], direct)
+			elseStmt = ir.NewAssignStmt(pos, retvars[0], call)
+		} else {
+			thenStmt = ir.NewAssignListStmt(pos, ir.OAS2FUNC, retvars, []ir.Node{direct})
+			elseStmt = ir.NewAssignListStmt(pos, ir.OAS2FUNC, retvars, []ir.Node{call})
+		}
+		thenStmt = typecheck.Stmt(thenStmt)
+		elseStmt = typecheck.Stmt(elseStmt)
+	}
+
+	nif := ir.NewIfStmt(pos, ok, []ir.Node{thenStmt}, []ir.Node{elseStmt})
+	nif.SetTypecheck(1)
+
+	res := ir.NewInlinedCallExpr(pos, []ir.Node{nif}, retvars)
+	res.SetInit(init)
+	res.SetType(call.Type())
+	res.SetTypecheck(1)
+	return res
+}
+
+// copyExpr returns n, or a temporary holding the value of n, assigned
+// in init, if evaluating n twice could be observed.
+func copyExpr(pos src.XPos, curfn *ir.Func, n ir.Node, init *ir.Nodes) ir.Node {
+	if n.Op() == ir.OLITERAL || n.Op() == ir.ONIL {
+		return n
+	}
+	tmp := typecheck.TempAt(pos, curfn, n.Type())
+	init.Append(typecheck.Stmt(ir.NewAssignStmt(pos, tmp, n)))
+	return tmp
+}
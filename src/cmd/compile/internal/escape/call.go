@@ -204,8 +204,10 @@ func (e *escape) callCommon(ks []hole, call ir.Node, init *ir.Nodes, wrapper *ir
 //	x1, y1 := x, y
 //	defer func() { f(x1, y1) }()
 func (e *escape) goDeferStmt(n *ir.GoDeferStmt) {
+	// Defers in a range-over-func loop body run when the enclosing
+	// function returns, so their arguments always escape the body.
 	k := e.heapHole()
-	if n.Op() == ir.ODEFER && e.loopDepth == 1 {
+	if n.Op() == ir.ODEFER && e.loopDepth == 1 && n.DeferAt == nil {
 		// Top-level defer arguments don't escape to the heap,
 		// but they do need to last until they're invoked.
 		k = e.later(e.discardHole())
@@ -221,6 +223,10 @@ func (e *escape) goDeferStmt(n *ir.GoDeferStmt) {
 	init.Append(ir.TakeInit(call)...)
 	e.stmts(*init)
 
+	if n.DeferAt != nil {
+		e.discard(n.DeferAt)
+	}
+
 	// If the function is already a zero argument/result function call,
 	// just escape analyze it normally.
 	if call, ok := call.(*ir.CallExpr); ok && call.Op() == ir.OCALLFUNC {
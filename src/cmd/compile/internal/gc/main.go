@@ -15,7 +15,9 @@ import (
 	"cmd/compile/internal/inline"
 	"cmd/compile/internal/ir"
 	"cmd/compile/internal/logopt"
+	"cmd/compile/internal/loopvar"
 	"cmd/compile/internal/noder"
+	"cmd/compile/internal/pgo"
 	"cmd/compile/internal/pkginit"
 	"cmd/compile/internal/reflectdata"
 	"cmd/compile/internal/ssa"
@@ -241,10 +243,24 @@ func Main(archInit func(*ssagen.ArchInfo)) {
 		typecheck.AllImportedBodies()
 	}
 
+	// Read the profile, if any, that guides inlining and devirtualization.
+	var profile *pgo.Profile
+	if base.Flag.PgoProfile != "" {
+		base.Timer.Start("fe", "pgoprofile")
+		if t := base.Debug.PGOHotCDFThreshold; t < 0 || t > 100 {
+			log.Fatalf("invalid -d pgohotcdfthreshold=%d: must be between 0 and 100", t)
+		}
+		var err error
+		profile, err = pgo.New(base.Flag.PgoProfile, base.Debug.PGOHotCDFThreshold)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+
 	// Inlining
 	base.Timer.Start("fe", "inlining")
 	if base.Flag.LowerL != 0 {
-		inline.InlinePackage()
+		inline.InlinePackage(profile)
 	}
 	noder.MakeWrappers(typecheck.Target) // must happen after inlining
 
@@ -256,6 +272,18 @@ func Main(archInit func(*ssagen.ArchInfo)) {
 	}
 	ir.CurFunc = nil
 
+	// Give loop variables that are captured by closures or have their
+	// address taken a new instance in every iteration. This must
+	// happen after inlining, which copies loops into their callers,
+	// and before escape analysis.
+	base.Timer.Start("fe", "loopvar")
+	var transformed []loopvar.VarAndLoop
+	for _, n := range typecheck.Target.Decls {
+		if n.Op() == ir.ODCLFUNC {
+			transformed = append(transformed, loopvar.ForCapture(n.(*ir.Func))...)
+		}
+	}
+
 	// Build init task, if needed.
 	if initTask := pkginit.Task(); initTask != nil {
 		typecheck.Export(initTask)
@@ -276,6 +304,8 @@ func Main(archInit func(*ssagen.ArchInfo)) {
 	base.Timer.Start("fe", "escapes")
 	escape.Funcs(typecheck.Target.Decls)
 
+	loopvar.LogTransformations(transformed)
+
 	// TODO(mdempsky): This is a hack. We need a proper, global work
 	// queue for scheduling function compilation so components don't
 	// need to adjust their behavior depending on when they're called.
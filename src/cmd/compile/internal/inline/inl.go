@@ -32,8 +32,10 @@ import (
 	"strings"
 
 	"cmd/compile/internal/base"
+	"cmd/compile/internal/devirtualize"
 	"cmd/compile/internal/ir"
 	"cmd/compile/internal/logopt"
+	"cmd/compile/internal/pgo"
 	"cmd/compile/internal/typecheck"
 	"cmd/compile/internal/types"
 	"cmd/internal/obj"
@@ -53,8 +55,17 @@ const (
 	inlineBigFunctionMaxCost = 20   // Max cost of inlinee when inlining into a "big" function.
 )
 
+// profile is the profile guiding this compilation, if any.
+var profile *pgo.Profile
+
 // InlinePackage finds functions that can be inlined and clones them before walk expands them.
-func InlinePackage() {
+// If p is not nil, hot functions get a larger inlining budget at their hot call sites,
+// and hot interface method calls are devirtualized before inlining.
+func InlinePackage(p *pgo.Profile) {
+	profile = p
+	if profile != nil && base.Debug.PGOInline == 0 {
+		profile = nil
+	}
 	ir.VisitFuncsBottomUp(typecheck.Target.Decls, func(list []*ir.Func, recursive bool) {
 		numfns := numNonClosures(list)
 		for _, n := range list {
@@ -68,11 +79,31 @@ func InlinePackage() {
 					fmt.Printf("%v: cannot inline %v: recursive\n", ir.Line(n), n.Nname)
 				}
 			}
+			if p != nil && base.Debug.PGODevirtualize != 0 {
+				// After CanInline, so that the saved
+				// inline body is the original one.
+				devirtualize.ProfileGuided(n, p)
+			}
 			InlineCalls(n)
 		}
 	})
 }
 
+// inlineBudget returns the maximum inlining cost of fn.
+func inlineBudget(fn *ir.Func) int32 {
+	if profile != nil && profile.IsHotCallee(fn) {
+		// Hot functions may be inlined at their hot call
+		// sites, which mkinlcall checks separately.
+		if budget := int32(base.Debug.PGOInlineBudget); budget > inlineMaxBudget {
+			if base.Debug.PGODebug > 0 {
+				fmt.Printf("%v: hot function %v: inline budget %d\n", ir.Line(fn), fn.Nname, budget)
+			}
+			return budget
+		}
+	}
+	return inlineMaxBudget
+}
+
 // CanInline determines whether fn is inlineable.
 // If so, CanInline saves copies of fn.Body and fn.Dcl in fn.Inl.
 // fn and fn.Body will already have been typechecked.
@@ -166,8 +197,10 @@ func CanInline(fn *ir.Func) {
 	// locals, and we use this map to produce a pruned Inline.Dcl
 	// list. See issue 25249 for more context.
 
+	budget := inlineBudget(fn)
 	visitor := hairyVisitor{
-		budget:        inlineMaxBudget,
+		budget:        budget,
+		maxBudget:     budget,
 		extraCallCost: cc,
 	}
 	if visitor.tooHairy(fn) {
@@ -176,7 +209,7 @@ func CanInline(fn *ir.Func) {
 	}
 
 	n.Func.Inl = &ir.Inline{
-		Cost: inlineMaxBudget - visitor.budget,
+		Cost: budget - visitor.budget,
 		Dcl:  pruneUnusedAutos(n.Defn.(*ir.Func).Dcl, &visitor),
 		Body: inlcopylist(fn.Body),
 
@@ -184,12 +217,12 @@ func CanInline(fn *ir.Func) {
 	}
 
 	if base.Flag.LowerM > 1 {
-		fmt.Printf("%v: can inline %v with cost %d as: %v { %v }\n", ir.Line(fn), n, inlineMaxBudget-visitor.budget, fn.Type(), ir.Nodes(n.Func.Inl.Body))
+		fmt.Printf("%v: can inline %v with cost %d as: %v { %v }\n", ir.Line(fn), n, budget-visitor.budget, fn.Type(), ir.Nodes(n.Func.Inl.Body))
 	} else if base.Flag.LowerM != 0 {
 		fmt.Printf("%v: can inline %v\n", ir.Line(fn), n)
 	}
 	if logopt.Enabled() {
-		logopt.LogOpt(fn.Pos(), "canInlineFunction", "inline", ir.FuncName(fn), fmt.Sprintf("cost: %d", inlineMaxBudget-visitor.budget))
+		logopt.LogOpt(fn.Pos(), "canInlineFunction", "inline", ir.FuncName(fn), fmt.Sprintf("cost: %d", budget-visitor.budget))
 	}
 }
 
@@ -229,6 +262,7 @@ func canDelayResults(fn *ir.Func) bool {
 // hairiness and whether or not it can be inlined.
 type hairyVisitor struct {
 	budget        int32
+	maxBudget     int32
 	reason        string
 	extraCallCost int32
 	usedLocals    ir.NameSet
@@ -241,7 +275,7 @@ func (v *hairyVisitor) tooHairy(fn *ir.Func) bool {
 		return true
 	}
 	if v.budget < 0 {
-		v.reason = fmt.Sprintf("function too complex: cost %d exceeds budget %d", inlineMaxBudget-v.budget, inlineMaxBudget)
+		v.reason = fmt.Sprintf("function too complex: cost %d exceeds budget %d", v.maxBudget-v.budget, v.maxBudget)
 		return true
 	}
 	return false
@@ -272,6 +306,12 @@ func (v *hairyVisitor) doNode(n ir.Node) bool {
 					break
 				}
 			}
+			// Functions that call runtime.deferrangefunc can not be inlined
+			// because it queues defers on the frame of its caller.
+			if name.Class == ir.PFUNC && name.Sym().Pkg == ir.Pkgs.Runtime && name.Sym().Name == "deferrangefunc" {
+				v.reason = "call to deferrangefunc"
+				return true
+			}
 		}
 		if n.X.Op() == ir.OMETHEXPR {
 			if meth := ir.MethodExprName(n.X); meth != nil {
@@ -595,6 +635,23 @@ func inlnode(n ir.Node, maxCost int32, inlMap map[*ir.Func]bool, edit func(ir.No
 	return n
 }
 
+// hotCallSite reports whether the call n to fn is a hot call site
+// in the profile, and fn is cheap enough to inline there anyway.
+func hotCallSite(n *ir.CallExpr, fn *ir.Func) bool {
+	if profile == nil || fn.Inl.Cost > int32(base.Debug.PGOInlineBudget) {
+		return false
+	}
+	cs := pgo.CallSiteOf(ir.CurFunc, n.Pos())
+	cs.Callee = pgo.FuncName(fn)
+	if !profile.IsHot(cs) {
+		return false
+	}
+	if base.Debug.PGODebug > 0 {
+		fmt.Printf("%v: hot call site allows inlining %v with cost %d\n", ir.Line(n), fn, fn.Inl.Cost)
+	}
+	return true
+}
+
 // inlCallee takes a function-typed expression and returns the underlying function ONAME
 // that it refers to if statically known. Otherwise, it returns nil.
 func inlCallee(fn ir.Node) *ir.Func {
@@ -667,7 +724,7 @@ func mkinlcall(n *ir.CallExpr, fn *ir.Func, maxCost int32, inlMap map[*ir.Func]b
 		}
 		return n
 	}
-	if fn.Inl.Cost > maxCost {
+	if fn.Inl.Cost > maxCost && !hotCallSite(n, fn) {
 		// The inlined function body is too big. Typically we use this check to restrict
 		// inlining into very big functions.  See issue 26546 and 17566.
 		if logopt.Enabled() {
@@ -598,6 +598,9 @@ func (n *GoDeferStmt) doChildren(do func(Node) bool) bool {
 	if n.Call != nil && do(n.Call) {
 		return true
 	}
+	if n.DeferAt != nil && do(n.DeferAt) {
+		return true
+	}
 	return false
 }
 func (n *GoDeferStmt) editChildren(edit func(Node) Node) {
@@ -605,6 +608,9 @@ func (n *GoDeferStmt) editChildren(edit func(Node) Node) {
 	if n.Call != nil {
 		n.Call = edit(n.Call).(Node)
 	}
+	if n.DeferAt != nil {
+		n.DeferAt = edit(n.DeferAt).(Node)
+	}
 }
 
 func (n *Ident) Format(s fmt.State, verb rune) { fmtNode(n, s, verb) }
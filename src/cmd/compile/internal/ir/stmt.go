@@ -202,6 +202,10 @@ type ForStmt struct {
 	Post     Node
 	Body     Nodes
 	HasBreak bool
+
+	// DistinctVars reports whether the variables declared by Init
+	// are per-iteration, as they are in Go 1.18 and later.
+	DistinctVars bool
 }
 
 func NewForStmt(pos src.XPos, init Node, cond, post Node, body []Node) *ForStmt {
@@ -229,7 +233,8 @@ func (n *ForStmt) SetOp(op Op) {
 // in a different context (a separate goroutine or a later time).
 type GoDeferStmt struct {
 	miniStmt
-	Call Node
+	Call    Node
+	DeferAt Node // frame of a range-over-func defer; see runtime.deferprocat
 }
 
 func NewGoDeferStmt(pos src.XPos, op Op, call Node) *GoDeferStmt {
@@ -304,6 +309,10 @@ type RangeStmt struct {
 	Body     Nodes
 	HasBreak bool
 	Prealloc *Name
+
+	// DistinctVars reports whether the variables declared by the
+	// range clause are per-iteration, as they are in Go 1.18 and later.
+	DistinctVars bool
 }
 
 func NewRangeStmt(pos src.XPos, key, value, x Node, body []Node) *RangeStmt {
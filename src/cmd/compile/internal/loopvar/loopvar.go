@@ -0,0 +1,302 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package loopvar gives the variables declared by for and range loops
+// a fresh instance in every iteration, as required by Go 1.18 and later.
+//
+// Only variables that may outlive an iteration, because they are
+// captured by a closure or have their address taken, are affected;
+// for all other variables the per-iteration and the shared semantics
+// cannot be told apart.
+package loopvar
+
+import (
+	"cmd/compile/internal/base"
+	"cmd/compile/internal/ir"
+	"cmd/compile/internal/typecheck"
+	"cmd/compile/internal/types"
+	"cmd/internal/src"
+)
+
+// A VarAndLoop is a loop variable that was made per-iteration,
+// together with the loop that declares it.
+type VarAndLoop struct {
+	Name *ir.Name
+	Loop ir.Node // *ir.ForStmt or *ir.RangeStmt
+}
+
+// Enabled reports whether loops of the package being compiled
+// declare per-iteration variables. The noders record it in the
+// DistinctVars field of each loop, so that loops inlined from other
+// packages keep the semantics of the package that declared them.
+func Enabled() bool {
+	return base.Debug.LoopVar >= 2 || types.AllowsGoVersion(types.LocalPkg, 1, 18)
+}
+
+// ForCapture rewrites the loops in fn that have DistinctVars set and
+// whose variables are captured by a closure or have their address
+// taken, so that each iteration uses new instances of those variables.
+// It returns the rewritten variables. It must run after inlining, which
+// may copy loops into fn, and before escape analysis.
+//
+// A range loop
+//
+//	for k, v := range x { body }
+//
+// becomes
+//
+//	for k', v' := range x { k := k'; v := v'; body }
+//
+// and a 3-clause loop
+//
+//	for z := init; cond; post { body }
+//
+// becomes
+//
+//	for z', first := init, true; ; z' = z {
+//		z := z'
+//		if first {
+//			first = false
+//		} else {
+//			post
+//		}
+//		if !cond {
+//			break
+//		}
+//		body
+//	}
+//
+// so that the value of z is carried over to the next iteration's
+// instance before post is applied to it.
+func ForCapture(fn *ir.Func) []VarAndLoop {
+	var transformed []VarAndLoop
+	ir.VisitList(fn.Body, func(n ir.Node) {
+		switch n := n.(type) {
+		case *ir.RangeStmt:
+			if !n.DistinctVars {
+				return
+			}
+			for _, name := range rangeLoopVars(fn, n, leakedVars(n, n.Key, n.Value)) {
+				transformed = append(transformed, VarAndLoop{name, n})
+			}
+		case *ir.ForStmt:
+			if !n.DistinctVars {
+				return
+			}
+			var vars []ir.Node
+			for _, init := range n.Init() {
+				switch init := init.(type) {
+				case *ir.AssignStmt:
+					if init.Def {
+						vars = append(vars, init.X)
+					}
+				case *ir.AssignListStmt:
+					if init.Def {
+						vars = append(vars, init.Lhs...)
+					}
+				}
+			}
+			for _, name := range forLoopVars(fn, n, leakedVars(n, vars...)) {
+				transformed = append(transformed, VarAndLoop{name, n})
+			}
+		}
+	})
+	return transformed
+}
+
+// leakedVars returns the variables among vars that are captured by a
+// closure within loop or have their address taken.
+func leakedVars(loop ir.Node, vars ...ir.Node) map[*ir.Name]bool {
+	leaked := make(map[*ir.Name]bool)
+	candidates := make(map[*ir.Name]bool)
+	for _, v := range vars {
+		name, ok := v.(*ir.Name)
+		if !ok || ir.IsBlank(name) || name.Op() != ir.ONAME || name.Class != ir.PAUTO {
+			continue
+		}
+		if name.Addrtaken() {
+			leaked[name] = true
+		} else {
+			candidates[name] = true
+		}
+	}
+	if len(candidates) == 0 {
+		return leaked
+	}
+	ir.Visit(loop, func(n ir.Node) {
+		if clo, ok := n.(*ir.ClosureExpr); ok {
+			for _, cv := range clo.Func.ClosureVars {
+				if outer := cv.Canonical(); candidates[outer] {
+					leaked[outer] = true
+				}
+			}
+		}
+	})
+	return leaked
+}
+
+// rangeLoopVars rewrites the leaked variables of the range loop n and
+// returns them.
+func rangeLoopVars(fn *ir.Func, n *ir.RangeStmt, leaked map[*ir.Name]bool) []*ir.Name {
+	if len(leaked) == 0 {
+		return nil
+	}
+	var names []*ir.Name
+	var prologue []ir.Node
+	for _, p := range []*ir.Node{&n.Key, &n.Value} {
+		name, ok := (*p).(*ir.Name)
+		if !ok || !leaked[name] || !declares(n.Init(), name) {
+			continue
+		}
+		tmp := typecheck.TempAt(name.Pos(), fn, name.Type())
+		*p = tmp
+		prologue = append(prologue, declare(name.Pos(), name, tmp))
+		names = append(names, name)
+	}
+	n.SetInit(withoutDecls(n.Init(), leaked))
+	n.Body.Prepend(prologue...)
+	return names
+}
+
+// forLoopVars rewrites the leaked variables of the 3-clause loop n and
+// returns them.
+func forLoopVars(fn *ir.Func, n *ir.ForStmt, leaked map[*ir.Name]bool) []*ir.Name {
+	if len(leaked) == 0 {
+		return nil
+	}
+	pos := n.Pos()
+
+	// Replace each leaked variable in the init statement by a temporary
+	// that carries its value from one iteration to the next.
+	var names []*ir.Name
+	var prologue []ir.Node
+	var lhs, rhs []ir.Node
+	replace := func(x ir.Node) ir.Node {
+		name, ok := x.(*ir.Name)
+		if !ok || !leaked[name] {
+			return x
+		}
+		tmp := typecheck.TempAt(name.Pos(), fn, name.Type())
+		prologue = append(prologue, declare(name.Pos(), name, tmp))
+		names = append(names, name)
+		lhs = append(lhs, tmp)
+		rhs = append(rhs, name)
+		return tmp
+	}
+	for _, init := range n.Init() {
+		switch init := init.(type) {
+		case *ir.AssignStmt:
+			if init.Def {
+				init.X = replace(init.X)
+				init.SetInit(withoutDecls(init.Init(), leaked))
+			}
+		case *ir.AssignListStmt:
+			if init.Def {
+				for i, x := range init.Lhs {
+					init.Lhs[i] = replace(x)
+				}
+				init.SetInit(withoutDecls(init.Init(), leaked))
+			}
+		}
+	}
+
+	if n.Post != nil {
+		first := typecheck.TempAt(pos, fn, types.Types[types.TBOOL])
+		n.PtrInit().Append(typecheck.Stmt(ir.NewAssignStmt(pos, first, ir.NewBool(true))))
+		post := ir.NewIfStmt(pos, first, []ir.Node{typecheck.Stmt(ir.NewAssignStmt(pos, first, ir.NewBool(false)))}, []ir.Node{n.Post})
+		post.SetTypecheck(1)
+		prologue = append(prologue, post)
+	}
+	if n.Cond != nil {
+		not := ir.NewUnaryExpr(n.Cond.Pos(), ir.ONOT, n.Cond)
+		not.SetType(n.Cond.Type())
+		not.SetTypecheck(1)
+		brk := ir.NewBranchStmt(pos, ir.OBREAK, nil)
+		brk.SetTypecheck(1)
+		cond := ir.NewIfStmt(pos, not, []ir.Node{brk}, nil)
+		cond.SetTypecheck(1)
+		prologue = append(prologue, cond)
+		n.HasBreak = true
+	}
+	n.Cond = nil
+	n.Body.Prepend(prologue...)
+
+	// Copy the variables back after each iteration, including
+	// iterations ended by a continue statement.
+	if len(lhs) == 1 {
+		n.Post = assign(pos, lhs[0], rhs[0])
+	} else {
+		as := ir.NewAssignListStmt(pos, ir.OAS2, lhs, rhs)
+		as.SetTypecheck(1)
+		n.Post = as
+	}
+	return names
+}
+
+// declare returns the declaration of name, initialized to the value
+// of tmp, at the start of a loop iteration.
+func declare(pos src.XPos, name, tmp *ir.Name) ir.Node {
+	as := ir.NewAssignStmt(pos, name, tmp)
+	as.Def = true
+	dcl := ir.NewDecl(pos, ir.ODCL, name)
+	dcl.SetTypecheck(1)
+	as.PtrInit().Append(dcl)
+	as.SetTypecheck(1)
+	name.Defn = as
+	return as
+}
+
+func assign(pos src.XPos, x, y ir.Node) ir.Node {
+	as := ir.NewAssignStmt(pos, x, y)
+	as.SetTypecheck(1)
+	return as
+}
+
+// declares reports whether init declares name. Range loops that
+// assign to variables declared elsewhere are left alone.
+func declares(init ir.Nodes, name *ir.Name) bool {
+	for _, n := range init {
+		if n.Op() == ir.ODCL && n.(*ir.Decl).X == name {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutDecls returns init without the declarations of the variables
+// in leaked, which are declared in the loop body instead. This includes
+// the zeroing assignments that follow declarations in imported bodies.
+func withoutDecls(init ir.Nodes, leaked map[*ir.Name]bool) ir.Nodes {
+	var out ir.Nodes
+	for _, n := range init {
+		switch n := n.(type) {
+		case *ir.Decl:
+			if n.Op() == ir.ODCL && leaked[n.X] {
+				continue
+			}
+		case *ir.AssignStmt:
+			if name, ok := n.X.(*ir.Name); ok && n.Y == nil && leaked[name] {
+				continue
+			}
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// LogTransformations reports the loop variables in transformed, and
+// whether escape analysis placed them on the heap, if requested by
+// the -d=loopvar flag. It must be called after escape analysis.
+func LogTransformations(transformed []VarAndLoop) {
+	if base.Debug.LoopVar == 0 {
+		return
+	}
+	for _, t := range transformed {
+		alloc := "stack"
+		if t.Name.Esc() == ir.EscHeap {
+			alloc = "heap"
+		}
+		base.WarnfAt(t.Name.Pos(), "loop variable %v now per-iteration, %s-allocated", t.Name, alloc)
+	}
+}
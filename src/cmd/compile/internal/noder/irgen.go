@@ -11,6 +11,7 @@ import (
 	"cmd/compile/internal/base"
 	"cmd/compile/internal/dwarfgen"
 	"cmd/compile/internal/ir"
+	"cmd/compile/internal/rangefunc"
 	"cmd/compile/internal/syntax"
 	"cmd/compile/internal/typecheck"
 	"cmd/compile/internal/types"
@@ -81,6 +82,16 @@ func check2(noders []*noder) {
 		os.Exit(0)
 	}
 
+	// Rewrite range-over-func loops into code that irgen understands.
+	files := make([]*syntax.File, len(noders))
+	for i, p := range noders {
+		files[i] = p.file
+	}
+	rangefunc.Rewrite(pkg, info, files, func(pos syntax.Pos, format string, args ...interface{}) {
+		base.ErrorfAt(m.makeXPos(pos), format, args...)
+	})
+	base.ExitIfErrors()
+
 	g := irgen{
 		target: typecheck.Target,
 		self:   pkg,
@@ -21,6 +21,7 @@ import (
 	"cmd/compile/internal/base"
 	"cmd/compile/internal/dwarfgen"
 	"cmd/compile/internal/ir"
+	"cmd/compile/internal/loopvar"
 	"cmd/compile/internal/syntax"
 	"cmd/compile/internal/typecheck"
 	"cmd/compile/internal/types"
@@ -1269,11 +1270,13 @@ func (p *noder) forStmt(stmt *syntax.ForStmt) ir.Node {
 			}
 		}
 		n.Body = p.blockStmt(stmt.Body)
+		n.DistinctVars = loopvar.Enabled()
 		p.closeAnotherScope()
 		return n
 	}
 
 	n := ir.NewForStmt(p.pos(stmt), p.stmt(stmt.Init), p.expr(stmt.Cond), p.stmt(stmt.Post), p.blockStmt(stmt.Body))
+	n.DistinctVars = loopvar.Enabled()
 	p.closeAnotherScope()
 	return n
 }
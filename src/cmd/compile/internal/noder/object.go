@@ -71,7 +71,13 @@ func (g *irgen) obj(obj types2.Object) *ir.Name {
 		}
 		sym := g.sym(obj)
 		if sym.Def != nil {
-			return sym.Def.(*ir.Name)
+			name := sym.Def.(*ir.Name)
+			if sym.Pkg == ir.Pkgs.Runtime {
+				// Runtime function called by code that was
+				// synthesized after type checking.
+				name.SetTypecheck(1)
+			}
+			return name
 		}
 		n := typecheck.Resolve(ir.NewIdent(src.NoXPos, sym))
 		if n, ok := n.(*ir.Name); ok {
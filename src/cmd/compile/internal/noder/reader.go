@@ -1352,6 +1352,7 @@ func (r *reader) forStmt(label *types.Sym) ir.Node {
 		names, lhs := r.assignList()
 
 		body := r.blockStmt()
+		distinctVars := r.bool()
 		r.closeAnotherScope()
 
 		rang := ir.NewRangeStmt(pos, nil, nil, x, body)
@@ -1363,6 +1364,7 @@ func (r *reader) forStmt(label *types.Sym) ir.Node {
 		}
 		rang.Def = r.initDefn(rang, names)
 		rang.Label = label
+		rang.DistinctVars = distinctVars
 		return rang
 	}
 
@@ -1371,10 +1373,12 @@ func (r *reader) forStmt(label *types.Sym) ir.Node {
 	cond := r.expr()
 	post := r.stmt()
 	body := r.blockStmt()
+	distinctVars := r.bool()
 	r.closeAnotherScope()
 
 	stmt := ir.NewForStmt(pos, init, cond, post, body)
 	stmt.Label = label
+	stmt.DistinctVars = distinctVars
 	return stmt
 }
 
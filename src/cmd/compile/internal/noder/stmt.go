@@ -7,6 +7,7 @@ package noder
 import (
 	"cmd/compile/internal/base"
 	"cmd/compile/internal/ir"
+	"cmd/compile/internal/loopvar"
 	"cmd/compile/internal/syntax"
 	"cmd/compile/internal/typecheck"
 	"cmd/compile/internal/types"
@@ -106,7 +107,11 @@ func (g *irgen) stmt(stmt syntax.Stmt) ir.Node {
 	case *syntax.BranchStmt:
 		return ir.NewBranchStmt(g.pos(stmt), g.tokOp(int(stmt.Tok), branchOps[:]), g.name(stmt.Label))
 	case *syntax.CallStmt:
-		return ir.NewGoDeferStmt(g.pos(stmt), g.tokOp(int(stmt.Tok), callOps[:]), g.expr(stmt.Call))
+		n := ir.NewGoDeferStmt(g.pos(stmt), g.tokOp(int(stmt.Tok), callOps[:]), g.expr(stmt.Call))
+		if stmt.DeferAt != nil {
+			n.DeferAt = g.expr(stmt.DeferAt)
+		}
+		return n
 	case *syntax.ReturnStmt:
 		n := ir.NewReturnStmt(g.pos(stmt), g.exprList(stmt.Results))
 		if !g.delayTransform() {
@@ -254,10 +259,13 @@ func (g *irgen) forStmt(stmt *syntax.ForStmt) ir.Node {
 		if value != nil {
 			transformCheckAssign(n, value)
 		}
+		n.DistinctVars = loopvar.Enabled()
 		return n
 	}
 
-	return ir.NewForStmt(g.pos(stmt), g.stmt(stmt.Init), g.expr(stmt.Cond), g.stmt(stmt.Post), g.blockStmt(stmt.Body))
+	n := ir.NewForStmt(g.pos(stmt), g.stmt(stmt.Init), g.expr(stmt.Cond), g.stmt(stmt.Post), g.blockStmt(stmt.Body))
+	n.DistinctVars = loopvar.Enabled()
+	return n
 }
 
 func (g *irgen) selectStmt(stmt *syntax.SelectStmt) ir.Node {
@@ -12,6 +12,7 @@ import (
 
 	"cmd/compile/internal/base"
 	"cmd/compile/internal/ir"
+	"cmd/compile/internal/loopvar"
 	"cmd/compile/internal/syntax"
 	"cmd/compile/internal/types2"
 )
@@ -1048,6 +1049,10 @@ func (w *writer) forStmt(stmt *syntax.ForStmt) {
 	w.openScope(stmt.Pos())
 
 	if rang, ok := stmt.Init.(*syntax.RangeClause); w.bool(ok) {
+		if _, ok := types2.StructuralType(w.p.info.Types[rang.X].Type).(*types2.Signature); ok {
+			// TODO: support range-over-func loops (see package rangefunc).
+			w.p.errorf(rang, "range over function is not yet supported with unified IR")
+		}
 		w.pos(rang)
 		w.expr(rang.X)
 		w.assignList(rang.Lhs)
@@ -1059,6 +1064,7 @@ func (w *writer) forStmt(stmt *syntax.ForStmt) {
 	}
 
 	w.blockStmt(stmt.Body)
+	w.bool(loopvar.Enabled())
 	w.closeAnotherScope()
 }
 
@@ -0,0 +1,232 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pgo contains the compiler's support for profile-guided
+// optimization (PGO).
+//
+// A profile is a pprof CPU profile of a previous run of the program
+// being built. The compiler summarizes it as a weighted call graph,
+// with one edge per (caller, callee, call line) triple observed in
+// the sampled stacks, and marks the heaviest edges that together
+// account for a fixed fraction of the total weight as hot.
+// The inliner and the devirtualizer consult the hot edges to decide
+// where spending code size is worthwhile.
+//
+// Functions are identified by their linker symbol names, which is
+// what the runtime records in profiles, and call sites by the source
+// line of the call. A profile taken from a slightly different version
+// of the source still applies to the parts that did not move.
+package pgo
+
+import (
+	"fmt"
+	"internal/profile"
+	"os"
+	"sort"
+	"strings"
+
+	"cmd/compile/internal/base"
+	"cmd/compile/internal/ir"
+	"cmd/compile/internal/types"
+	"cmd/internal/objabi"
+	"cmd/internal/src"
+)
+
+// A CallSite is a call edge in the profile's call graph.
+type CallSite struct {
+	Caller string // linker symbol name of the calling function
+	Callee string // linker symbol name of the called function
+	Line   int    // source line of the call in Caller
+}
+
+// A Profile is the call graph of a CPU profile, as used by the
+// optimization passes.
+type Profile struct {
+	// TotalWeight is the sum of the weights of all edges.
+	TotalWeight int64
+
+	// Edges maps each call site observed in the profile to its weight,
+	// the number of samples in which the call was on the stack.
+	Edges map[CallSite]int64
+
+	// HotThreshold is the minimum weight of a hot call site.
+	HotThreshold int64
+
+	hot        map[CallSite]bool      // hot call sites
+	hotCallees map[string]bool        // callees of at least one hot call site
+	hotSites   map[siteKey][]CallSite // hot call sites by call line, hottest first
+}
+
+// A siteKey identifies a call instruction independent of its callee.
+type siteKey struct {
+	caller string
+	line   int
+}
+
+// New reads the CPU profile in file and builds its call graph.
+// The call sites that together account for cdf percent of the total
+// edge weight are considered hot.
+func New(file string, cdf int) (*Profile, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	prof, err := profile.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing profile %s: %v", file, err)
+	}
+
+	// A CPU profile has a sample count and a CPU time per stack.
+	// Use the count, which does not depend on the profiling rate.
+	index := -1
+	for i, st := range prof.SampleType {
+		if st.Type == "samples" && st.Unit == "count" {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("profile %s is not a CPU profile: no samples/count sample type", file)
+	}
+
+	p := &Profile{
+		Edges:      make(map[CallSite]int64),
+		hot:        make(map[CallSite]bool),
+		hotCallees: make(map[string]bool),
+		hotSites:   make(map[siteKey][]CallSite),
+	}
+
+	type frame struct {
+		name string
+		line int
+	}
+	var frames []frame
+	for _, s := range prof.Sample {
+		w := s.Value[index]
+		if w <= 0 {
+			continue
+		}
+		// Flatten the stack, leaf first. Each location lists its
+		// inlined frames innermost first, so the order is preserved.
+		frames = frames[:0]
+		for _, loc := range s.Location {
+			for _, l := range loc.Line {
+				if l.Function == nil {
+					continue
+				}
+				frames = append(frames, frame{l.Function.Name, int(l.Line)})
+			}
+		}
+		for i := 0; i+1 < len(frames); i++ {
+			cs := CallSite{
+				Caller: frames[i+1].name,
+				Callee: frames[i].name,
+				Line:   frames[i+1].line,
+			}
+			p.Edges[cs] += w
+			p.TotalWeight += w
+		}
+	}
+
+	p.computeHot(cdf)
+	return p, nil
+}
+
+// computeHot sets HotThreshold so that the call sites at or above it
+// cover cdf percent of the total weight, and indexes those call sites.
+func (p *Profile) computeHot(cdf int) {
+	sites := make([]CallSite, 0, len(p.Edges))
+	for cs := range p.Edges {
+		sites = append(sites, cs)
+	}
+	// Break ties by name so that the result, and hence the
+	// generated code, does not depend on map iteration order.
+	sort.Slice(sites, func(i, j int) bool {
+		si, sj := sites[i], sites[j]
+		if wi, wj := p.Edges[si], p.Edges[sj]; wi != wj {
+			return wi > wj
+		}
+		if si.Caller != sj.Caller {
+			return si.Caller < sj.Caller
+		}
+		if si.Callee != sj.Callee {
+			return si.Callee < sj.Callee
+		}
+		return si.Line < sj.Line
+	})
+
+	var cum int64
+	for _, cs := range sites {
+		if cum*100 >= p.TotalWeight*int64(cdf) {
+			break
+		}
+		w := p.Edges[cs]
+		cum += w
+		p.HotThreshold = w
+
+		p.hot[cs] = true
+		p.hotCallees[cs.Callee] = true
+		k := siteKey{cs.Caller, cs.Line}
+		p.hotSites[k] = append(p.hotSites[k], cs)
+	}
+
+	if base.Debug.PGODebug > 0 {
+		fmt.Printf("pgo: %d call sites, total weight %d, hot threshold %d (%d%% cdf)\n", len(sites), p.TotalWeight, p.HotThreshold, cdf)
+		if base.Debug.PGODebug > 1 {
+			for _, cs := range sites {
+				if !p.hot[cs] {
+					break
+				}
+				fmt.Printf("pgo: hot call site %s:%d -> %s weight %d\n", cs.Caller, cs.Line, cs.Callee, p.Edges[cs])
+			}
+		}
+	}
+}
+
+// IsHot reports whether cs is a hot call site.
+func (p *Profile) IsHot(cs CallSite) bool {
+	return p.hot[cs]
+}
+
+// IsHotCallee reports whether fn is the callee of any hot call site.
+func (p *Profile) IsHotCallee(fn *ir.Func) bool {
+	return p.hotCallees[FuncName(fn)]
+}
+
+// HotCallees returns the hot call sites at the given line of caller,
+// hottest first. A single call line can have several callees, for
+// instance when it is an interface method call.
+func (p *Profile) HotCallees(caller string, line int) []CallSite {
+	return p.hotSites[siteKey{caller, line}]
+}
+
+// FuncName returns the linker symbol name of fn, as it appears in
+// profiles.
+func FuncName(fn *ir.Func) string {
+	s := fn.Sym()
+	prefix := s.Pkg.Prefix
+	if s.Pkg == types.LocalPkg {
+		prefix = objabi.PathToPrefix(base.Ctxt.Pkgpath)
+	}
+	return prefix + "." + s.Name
+}
+
+// CallSiteOf returns the call site, with an empty callee, of the call
+// at pos in the body of curfn. If pos is inside a function body that
+// has been inlined into curfn, the caller is the inlined function,
+// matching the frames that the runtime reports for such calls.
+func CallSiteOf(curfn *ir.Func, pos src.XPos) CallSite {
+	caller := FuncName(curfn)
+	if idx := base.Ctxt.PosTable.Pos(pos).Base().InliningIndex(); idx >= 0 {
+		caller = base.Ctxt.InlTree.InlinedFunction(idx).Name
+		if strings.HasPrefix(caller, `"".`) {
+			caller = objabi.PathToPrefix(base.Ctxt.Pkgpath) + caller[len(`""`):]
+		}
+	}
+	return CallSite{
+		Caller: caller,
+		Line:   int(base.Ctxt.InnermostPos(pos).Line()),
+	}
+}
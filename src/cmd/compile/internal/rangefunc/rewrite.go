@@ -0,0 +1,700 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package rangefunc rewrites range-over-func loops into ordinary Go code.
+
+After type checking, a loop such as
+
+	for k, v := range f {
+		body
+	}
+
+where f has type func(yield func(K, V) bool), is rewritten to
+
+	{
+		var #state1 = READY
+		f(func(k K, v V) bool {
+			if #state1 != READY {
+				runtime.panicrangestate(#state1)
+			}
+			#state1 = PANIC
+			body
+			#state1 = READY
+			return true
+		})
+		if #state1 == PANIC {
+			runtime.panicrangestate(MISSING_PANIC)
+		}
+		#state1 = EXHAUSTED
+	}
+
+The state variable lets the generated code detect range functions that
+call the loop body after it asked them to stop, after they themselves
+returned, or after the loop body panicked; and range functions that
+swallow a panic from the loop body.
+
+Inside the body, "continue" becomes "return true" and "break" becomes
+"return false". Branches and return statements that leave the body
+record where they are going in #next (and the results in #r1, #r2, ...),
+make the body function return false, and are replayed after the call
+to f returns:
+
+	{
+		var #next int
+		var #r1 T1
+		var #state1 = READY
+		f(func(...) bool {
+			...
+			// return x
+			#r1 = x
+			#next = -1
+			#state1 = DONE
+			return false
+			...
+			// break L (for some loop L outside the body)
+			#next = 1
+			#state1 = DONE
+			return false
+			...
+		})
+		...
+		if #next == -1 {
+			return #r1
+		}
+		if #next == 1 {
+			#next = 0
+			break L
+		}
+	}
+
+Nested range-over-func loops pass such exits outward one level at a
+time. A goto out of the body is handled the same way as a labeled
+break.
+
+Defer statements in the body must run when the function containing
+the loop returns, not when the body function does. If there are any,
+the rewritten outermost loop starts with
+
+	var #defers interface{}
+	runtime.deferrangefunc(&#defers)
+
+and each defer statement in the body records #defers in its DeferAt
+field, which makes the compiler queue the deferred call on the frame
+of the containing function with runtime.deferprocat.
+
+The rewritten code refers to the type checker's objects directly and
+records type information for every node it creates, so that it can be
+turned into IR as if it had been written by hand.
+*/
+package rangefunc
+
+import (
+	"go/constant"
+	"strconv"
+
+	"cmd/compile/internal/syntax"
+	"cmd/compile/internal/types2"
+)
+
+// The states of a loop body function. They must match the constants
+// of the same meaning in package runtime.
+const (
+	ready        = 0 // body may be called
+	panicking    = 1 // body is running, or panicked
+	done         = 2 // body returned false
+	exhausted    = 3 // range function returned
+	missingPanic = 4 // range function recovered a panic of the body
+)
+
+// returnCode is the value of #next that signals a return statement.
+const returnCode = -1
+
+// Rewrite rewrites all range-over-func loops in files, which must have
+// been type checked with the given package and info. The info maps are
+// updated to describe the new code. Errors about loops that cannot be
+// rewritten are reported by calling errorf.
+func Rewrite(pkg *types2.Package, info *types2.Info, files []*syntax.File, errorf func(pos syntax.Pos, format string, args ...interface{})) {
+	for _, file := range files {
+		syntax.Inspect(file, func(n syntax.Node) bool {
+			switch n := n.(type) {
+			case *syntax.FuncDecl:
+				if n.Body != nil {
+					sig := info.Defs[n.Name].Type().(*types2.Signature)
+					rewriteFunc(pkg, info, sig, n.Body, errorf)
+				}
+			case *syntax.FuncLit:
+				sig := info.Types[n].Type.(*types2.Signature)
+				rewriteFunc(pkg, info, sig, n.Body, errorf)
+			}
+			return true
+		})
+	}
+}
+
+// A rewriter rewrites the range-over-func loops in a single function body.
+// Function literals nested in the body are rewritten separately.
+type rewriter struct {
+	pkg    *types2.Package
+	info   *types2.Info
+	errorf func(pos syntax.Pos, format string, args ...interface{})
+	sig    *types2.Signature // signature of the function being rewritten
+
+	loops  []*forLoop // enclosing range-over-func loops, innermost last
+	nstate int        // number of state variables declared so far
+
+	// The following fields are reset for every outermost
+	// range-over-func loop.
+	next    *types2.Var   // #next, if needed
+	results []*types2.Var // #r1, #r2, ..., if needed
+	defers  *types2.Var   // #defers, if needed
+	codes   map[branchKey]int
+}
+
+// A forLoop describes a range-over-func loop being rewritten.
+type forLoop struct {
+	nfor  *syntax.ForStmt
+	state *types2.Var
+	inner map[syntax.Stmt]bool // branch targets within the loop body
+	exits []*syntax.BranchStmt // branches leaving the body, in #next order
+	codes map[int]bool         // #next values in exits
+	ret   bool                 // whether the body contains a return statement
+}
+
+// A branchKey identifies the destination of a branch statement.
+type branchKey struct {
+	cont   bool // continue, as opposed to break or goto
+	target syntax.Stmt
+}
+
+func rewriteFunc(pkg *types2.Package, info *types2.Info, sig *types2.Signature, body *syntax.BlockStmt, errorf func(pos syntax.Pos, format string, args ...interface{})) {
+	if !hasRangeFunc(info, body) {
+		return
+	}
+	r := &rewriter{pkg: pkg, info: info, errorf: errorf, sig: sig}
+	r.stmts(body.List)
+}
+
+// hasRangeFunc reports whether body contains a range-over-func loop
+// outside of any function literals.
+func hasRangeFunc(info *types2.Info, body *syntax.BlockStmt) bool {
+	found := false
+	syntax.Inspect(body, func(n syntax.Node) bool {
+		switch n := n.(type) {
+		case *syntax.FuncLit:
+			return false
+		case *syntax.ForStmt:
+			if isRangeFunc(info, n) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// rangeFuncSig returns the signature of the function ranged over by nfor,
+// or nil if nfor is not a range-over-func loop.
+func rangeFuncSig(info *types2.Info, nfor *syntax.ForStmt) *types2.Signature {
+	rclause, ok := nfor.Init.(*syntax.RangeClause)
+	if !ok {
+		return nil
+	}
+	sig, _ := types2.StructuralType(info.Types[rclause.X].Type).(*types2.Signature)
+	return sig
+}
+
+func isRangeFunc(info *types2.Info, nfor *syntax.ForStmt) bool {
+	return rangeFuncSig(info, nfor) != nil
+}
+
+// innerTargets returns the statements in body that branch statements
+// may refer to.
+func innerTargets(body *syntax.BlockStmt) map[syntax.Stmt]bool {
+	inner := make(map[syntax.Stmt]bool)
+	syntax.Inspect(body, func(n syntax.Node) bool {
+		switch n := n.(type) {
+		case *syntax.FuncLit:
+			return false
+		case *syntax.ForStmt, *syntax.SwitchStmt, *syntax.SelectStmt, *syntax.LabeledStmt:
+			inner[n.(syntax.Stmt)] = true
+		}
+		return true
+	})
+	return inner
+}
+
+func (r *rewriter) stmts(list []syntax.Stmt) {
+	for i, s := range list {
+		list[i] = r.stmt(s)
+	}
+}
+
+// stmt rewrites s and returns the statement that replaces it.
+func (r *rewriter) stmt(s syntax.Stmt) syntax.Stmt {
+	switch s := s.(type) {
+	case *syntax.BlockStmt:
+		r.stmts(s.List)
+	case *syntax.LabeledStmt:
+		s.Stmt = r.stmt(s.Stmt)
+	case *syntax.IfStmt:
+		r.stmts(s.Then.List)
+		if s.Else != nil {
+			s.Else = r.stmt(s.Else)
+		}
+	case *syntax.SwitchStmt:
+		for _, cc := range s.Body {
+			r.stmts(cc.Body)
+		}
+	case *syntax.SelectStmt:
+		for _, cc := range s.Body {
+			r.stmts(cc.Body)
+		}
+	case *syntax.ForStmt:
+		if isRangeFunc(r.info, s) {
+			return r.rangeFunc(s)
+		}
+		r.stmts(s.Body.List)
+	case *syntax.BranchStmt:
+		if len(r.loops) > 0 {
+			return r.branch(s)
+		}
+	case *syntax.ReturnStmt:
+		if len(r.loops) > 0 {
+			return r.ret(s)
+		}
+	case *syntax.CallStmt:
+		if s.Tok == syntax.Defer && len(r.loops) > 0 {
+			s.DeferAt = r.useVar(s.Pos(), r.defersVar())
+		}
+	}
+	return s
+}
+
+// rangeFunc rewrites the range-over-func loop nfor.
+func (r *rewriter) rangeFunc(nfor *syntax.ForStmt) syntax.Stmt {
+	pos := nfor.Pos()
+	rclause := nfor.Init.(*syntax.RangeClause)
+	yield := types2.AsSignature(rangeFuncSig(r.info, nfor).Params().At(0).Type())
+
+	outermost := len(r.loops) == 0
+	if outermost {
+		r.next = nil
+		r.results = nil
+		r.defers = nil
+		r.codes = make(map[branchKey]int)
+	}
+
+	r.nstate++
+	loop := &forLoop{
+		nfor:  nfor,
+		state: types2.NewVar(pos, r.pkg, "#state"+strconv.Itoa(r.nstate), types2.Typ[types2.Int]),
+		inner: innerTargets(nfor.Body),
+		codes: make(map[int]bool),
+	}
+	r.loops = append(r.loops, loop)
+	r.stmts(nfor.Body.List)
+	r.loops = r.loops[:len(r.loops)-1]
+
+	// Parameters of the body function, and the assignments of their
+	// values to the iteration variables in the "=" form of the loop.
+	var lhs []syntax.Expr
+	if rclause.Lhs != nil {
+		lhs = unpackListExpr(rclause.Lhs)
+	}
+	var params []*types2.Var
+	var fields []*syntax.Field
+	var prologue []syntax.Stmt
+	for i := 0; i < yield.Params().Len(); i++ {
+		typ := yield.Params().At(i).Type()
+		field := &syntax.Field{Type: r.typeExpr(pos, typ)}
+		field.SetPos(pos)
+		var param *types2.Var
+		if i < len(lhs) {
+			if rclause.Def {
+				name := lhs[i].(*syntax.Name)
+				param, _ = r.info.Defs[name].(*types2.Var)
+				if param == nil {
+					param = types2.NewParam(name.Pos(), r.pkg, name.Value, typ)
+				}
+			} else {
+				param = types2.NewParam(pos, r.pkg, "#p"+strconv.Itoa(i+1), typ)
+				if name, ok := lhs[i].(*syntax.Name); !ok || name.Value != "_" {
+					prologue = append(prologue, r.assign(pos, lhs[i], r.useVar(pos, param)))
+				}
+			}
+			field.Name = r.defVar(param.Pos(), param)
+		} else {
+			param = types2.NewParam(pos, r.pkg, "", typ)
+		}
+		params = append(params, param)
+		fields = append(fields, field)
+	}
+	result := &syntax.Field{Type: r.typeExpr(pos, types2.Typ[types2.Bool])}
+	result.SetPos(pos)
+	r.info.Implicits[result] = types2.NewParam(pos, r.pkg, "", types2.Typ[types2.Bool])
+
+	ftyp := &syntax.FuncType{ParamList: fields, ResultList: []*syntax.Field{result}}
+	ftyp.SetPos(pos)
+	r.info.Scopes[ftyp] = types2.NewScope(nil, nfor.Body.Pos(), nfor.Body.Rbrace, "function")
+
+	var list []syntax.Stmt
+	list = append(list,
+		r.ifStmt(pos, r.compare(pos, syntax.Neq, r.useVar(pos, loop.state), r.intLit(pos, ready)),
+			r.panicState(pos, r.useVar(pos, loop.state))),
+		r.setState(pos, loop, panicking))
+	list = append(list, prologue...)
+	list = append(list, nfor.Body.List...)
+	list = append(list, r.setState(pos, loop, ready), r.returnBool(pos, true))
+
+	body := &syntax.BlockStmt{List: list, Rbrace: nfor.Body.Rbrace}
+	body.SetPos(nfor.Body.Pos())
+	lit := &syntax.FuncLit{Type: ftyp, Body: body}
+	lit.SetPos(pos)
+	r.info.Types[lit] = types2.ComputedValue(types2.NewSignatureType(nil, nil, nil,
+		types2.NewTuple(params...),
+		types2.NewTuple(types2.NewParam(pos, r.pkg, "", types2.Typ[types2.Bool])),
+		false))
+
+	call := &syntax.CallExpr{Fun: rclause.X, ArgList: []syntax.Expr{lit}}
+	call.SetPos(pos)
+	r.info.Types[call] = types2.VoidValue()
+
+	// Replacement for the loop.
+	var block []syntax.Stmt
+	block = append(block,
+		r.declVar(pos, loop.state, r.intLit(pos, ready)),
+		r.exprStmt(pos, call),
+		r.ifStmt(pos, r.compare(pos, syntax.Eql, r.useVar(pos, loop.state), r.intLit(pos, panicking)),
+			r.panicState(pos, r.intLit(pos, missingPanic))),
+		r.setState(pos, loop, exhausted))
+
+	// Continue the branches and returns that left the body.
+	if loop.ret {
+		block = append(block, r.ifStmt(pos, r.isNext(pos, returnCode), r.exitReturn(pos)...))
+	}
+	for _, b := range loop.exits {
+		block = append(block, r.ifStmt(pos, r.isNext(pos, r.codes[r.key(b)]), r.exitBranch(b)...))
+	}
+
+	if outermost {
+		var decls []syntax.Stmt
+		if r.defers != nil {
+			decls = append(decls, r.declVar(pos, r.defers, nil), r.deferFrame(pos))
+		}
+		if r.next != nil {
+			decls = append(decls, r.declVar(pos, r.next, nil))
+		}
+		for _, v := range r.results {
+			decls = append(decls, r.declVar(pos, v, nil))
+		}
+		block = append(decls, block...)
+	}
+
+	repl := &syntax.BlockStmt{List: block, Rbrace: nfor.Body.Rbrace}
+	repl.SetPos(pos)
+	return repl
+}
+
+// branch rewrites the branch statement b in the body of the innermost
+// range-over-func loop.
+func (r *rewriter) branch(b *syntax.BranchStmt) syntax.Stmt {
+	loop := r.loops[len(r.loops)-1]
+	switch {
+	case b.Tok == syntax.Fallthrough, loop.inner[b.Target]:
+		return b
+	case b.Target == loop.nfor:
+		return r.block(b.Pos(), r.loopBranch(b.Pos(), loop, b.Tok == syntax.Continue)...)
+	}
+	code := r.exit(loop, b)
+	return r.block(b.Pos(),
+		r.assign(b.Pos(), r.useVar(b.Pos(), r.nextVar()), r.intLit(b.Pos(), code)),
+		r.setState(b.Pos(), loop, done),
+		r.returnBool(b.Pos(), false))
+}
+
+// loopBranch returns the statements implementing a continue or break
+// of the range-over-func loop itself inside its body.
+func (r *rewriter) loopBranch(pos syntax.Pos, loop *forLoop, cont bool) []syntax.Stmt {
+	if cont {
+		return []syntax.Stmt{r.setState(pos, loop, ready), r.returnBool(pos, true)}
+	}
+	return []syntax.Stmt{r.setState(pos, loop, done), r.returnBool(pos, false)}
+}
+
+// exit records that b leaves the body of loop and returns the
+// corresponding value of #next.
+func (r *rewriter) exit(loop *forLoop, b *syntax.BranchStmt) int {
+	key := r.key(b)
+	code, ok := r.codes[key]
+	if !ok {
+		code = len(r.codes) + 1
+		r.codes[key] = code
+	}
+	if !loop.codes[code] {
+		loop.codes[code] = true
+		loop.exits = append(loop.exits, b)
+	}
+	return code
+}
+
+func (r *rewriter) key(b *syntax.BranchStmt) branchKey {
+	return branchKey{b.Tok == syntax.Continue, b.Target}
+}
+
+// exitBranch returns the statements that continue the branch b after
+// it left the body of a range-over-func loop, which is the innermost
+// loop left in r.loops or directly in the function body.
+func (r *rewriter) exitBranch(b *syntax.BranchStmt) []syntax.Stmt {
+	pos := b.Pos()
+	if len(r.loops) == 0 || r.loops[len(r.loops)-1].inner[b.Target] {
+		return []syntax.Stmt{r.assign(pos, r.useVar(pos, r.next), r.intLit(pos, 0)), b}
+	}
+	loop := r.loops[len(r.loops)-1]
+	if b.Target == loop.nfor {
+		return append([]syntax.Stmt{r.assign(pos, r.useVar(pos, r.next), r.intLit(pos, 0))},
+			r.loopBranch(pos, loop, b.Tok == syntax.Continue)...)
+	}
+	r.exit(loop, b)
+	return []syntax.Stmt{r.setState(pos, loop, done), r.returnBool(pos, false)}
+}
+
+// ret rewrites the return statement s in the body of the innermost
+// range-over-func loop.
+func (r *rewriter) ret(s *syntax.ReturnStmt) syntax.Stmt {
+	pos := s.Pos()
+	loop := r.loops[len(r.loops)-1]
+	loop.ret = true
+
+	var list []syntax.Stmt
+	results := r.resultVars()
+	if len(results) > 0 {
+		var lhs, rhs []syntax.Expr
+		if s.Results != nil {
+			for _, v := range results {
+				lhs = append(lhs, r.useVar(pos, v))
+			}
+			rhs = unpackListExpr(s.Results)
+		} else {
+			// A bare return returns the current values of the
+			// named results.
+			for i, v := range results {
+				named := r.sig.Results().At(i)
+				if named.Name() == "_" {
+					continue
+				}
+				lhs = append(lhs, r.useVar(pos, v))
+				rhs = append(rhs, r.useVar(pos, named))
+			}
+		}
+		if len(lhs) > 0 {
+			list = append(list, r.assign(pos, r.list(pos, lhs), r.list(pos, rhs)))
+		}
+	}
+	list = append(list,
+		r.assign(pos, r.useVar(pos, r.nextVar()), r.intLit(pos, returnCode)),
+		r.setState(pos, loop, done),
+		r.returnBool(pos, false))
+	return r.block(pos, list...)
+}
+
+// exitReturn returns the statements that continue a return statement
+// after it left the body of a range-over-func loop.
+func (r *rewriter) exitReturn(pos syntax.Pos) []syntax.Stmt {
+	if len(r.loops) > 0 {
+		loop := r.loops[len(r.loops)-1]
+		loop.ret = true
+		return []syntax.Stmt{r.setState(pos, loop, done), r.returnBool(pos, false)}
+	}
+	var results []syntax.Expr
+	for _, v := range r.results {
+		results = append(results, r.useVar(pos, v))
+	}
+	ret := &syntax.ReturnStmt{}
+	ret.SetPos(pos)
+	if len(results) > 0 {
+		ret.Results = r.list(pos, results)
+	}
+	return []syntax.Stmt{ret}
+}
+
+// nextVar returns #next, creating it if necessary.
+func (r *rewriter) nextVar() *types2.Var {
+	if r.next == nil {
+		r.next = types2.NewVar(r.loops[0].nfor.Pos(), r.pkg, "#next", types2.Typ[types2.Int])
+	}
+	return r.next
+}
+
+// resultVars returns #r1, #r2, ..., creating them if necessary.
+func (r *rewriter) resultVars() []*types2.Var {
+	if r.results == nil {
+		pos := r.loops[0].nfor.Pos()
+		for i := 0; i < r.sig.Results().Len(); i++ {
+			typ := r.sig.Results().At(i).Type()
+			r.results = append(r.results, types2.NewVar(pos, r.pkg, "#r"+strconv.Itoa(i+1), typ))
+		}
+	}
+	return r.results
+}
+
+// defersVar returns #defers, creating it if necessary.
+func (r *rewriter) defersVar() *types2.Var {
+	if r.defers == nil {
+		r.defers = types2.NewVar(r.loops[0].nfor.Pos(), r.pkg, "#defers", types2.NewInterfaceType(nil, nil))
+	}
+	return r.defers
+}
+
+// Helpers for building syntax trees with their type information.
+
+func (r *rewriter) typeExpr(pos syntax.Pos, typ types2.Type) syntax.Expr {
+	n := &syntax.Name{Value: typ.String()}
+	n.SetPos(pos)
+	r.info.Types[n] = types2.TypeExprValue(typ)
+	return n
+}
+
+func (r *rewriter) useVar(pos syntax.Pos, v *types2.Var) *syntax.Name {
+	n := &syntax.Name{Value: v.Name()}
+	n.SetPos(pos)
+	r.info.Uses[n] = v
+	r.info.Types[n] = types2.VariableValue(v.Type())
+	return n
+}
+
+func (r *rewriter) defVar(pos syntax.Pos, v *types2.Var) *syntax.Name {
+	n := &syntax.Name{Value: v.Name()}
+	n.SetPos(pos)
+	r.info.Defs[n] = v
+	return n
+}
+
+func (r *rewriter) intLit(pos syntax.Pos, x int) syntax.Expr {
+	n := &syntax.BasicLit{Value: strconv.Itoa(x), Kind: syntax.IntLit}
+	n.SetPos(pos)
+	r.info.Types[n] = types2.ConstantValue(types2.Typ[types2.Int], constant.MakeInt64(int64(x)))
+	return n
+}
+
+func (r *rewriter) boolLit(pos syntax.Pos, x bool) syntax.Expr {
+	n := &syntax.Name{Value: strconv.FormatBool(x)}
+	n.SetPos(pos)
+	r.info.Uses[n] = types2.Universe.Lookup(n.Value)
+	r.info.Types[n] = types2.ConstantValue(types2.Typ[types2.Bool], constant.MakeBool(x))
+	return n
+}
+
+func unpackListExpr(x syntax.Expr) []syntax.Expr {
+	if l, ok := x.(*syntax.ListExpr); ok {
+		return l.ElemList
+	}
+	return []syntax.Expr{x}
+}
+
+func (r *rewriter) list(pos syntax.Pos, list []syntax.Expr) syntax.Expr {
+	if len(list) == 1 {
+		return list[0]
+	}
+	n := &syntax.ListExpr{ElemList: list}
+	n.SetPos(pos)
+	return n
+}
+
+func (r *rewriter) compare(pos syntax.Pos, op syntax.Operator, x, y syntax.Expr) syntax.Expr {
+	n := &syntax.Operation{Op: op, X: x, Y: y}
+	n.SetPos(pos)
+	r.info.Types[n] = types2.ComputedValue(types2.Typ[types2.Bool])
+	return n
+}
+
+// isNext returns the condition #next == code.
+func (r *rewriter) isNext(pos syntax.Pos, code int) syntax.Expr {
+	return r.compare(pos, syntax.Eql, r.useVar(pos, r.next), r.intLit(pos, code))
+}
+
+// panicState returns a call of runtime.panicrangestate(state).
+func (r *rewriter) panicState(pos syntax.Pos, state syntax.Expr) syntax.Stmt {
+	fn := &syntax.Name{Value: "panicrangestate"}
+	fn.SetPos(pos)
+	r.info.Uses[fn] = panicRangeState
+	r.info.Types[fn] = types2.ComputedValue(panicRangeState.Type())
+	call := &syntax.CallExpr{Fun: fn, ArgList: []syntax.Expr{state}}
+	call.SetPos(pos)
+	r.info.Types[call] = types2.VoidValue()
+	return r.exprStmt(pos, call)
+}
+
+// panicRangeState is the object of runtime.panicrangestate. The compiler
+// resolves references to it to its own declaration of the function.
+var panicRangeState = types2.NewFunc(syntax.Pos{}, types2.NewPackage("go.runtime", "runtime"), "panicrangestate",
+	types2.NewSignatureType(nil, nil, nil,
+		types2.NewTuple(types2.NewParam(syntax.Pos{}, nil, "state", types2.Typ[types2.Int])),
+		nil, false))
+
+// deferFrame returns a call of runtime.deferrangefunc(&#defers).
+func (r *rewriter) deferFrame(pos syntax.Pos) syntax.Stmt {
+	fn := &syntax.Name{Value: "deferrangefunc"}
+	fn.SetPos(pos)
+	r.info.Uses[fn] = deferRangeFunc
+	r.info.Types[fn] = types2.ComputedValue(deferRangeFunc.Type())
+	addr := &syntax.Operation{Op: syntax.And, X: r.useVar(pos, r.defers)}
+	addr.SetPos(pos)
+	r.info.Types[addr] = types2.ComputedValue(types2.NewPointer(r.defers.Type()))
+	call := &syntax.CallExpr{Fun: fn, ArgList: []syntax.Expr{addr}}
+	call.SetPos(pos)
+	r.info.Types[call] = types2.VoidValue()
+	return r.exprStmt(pos, call)
+}
+
+// deferRangeFunc is the object of runtime.deferrangefunc, which the
+// compiler resolves like panicRangeState.
+var deferRangeFunc = types2.NewFunc(syntax.Pos{}, panicRangeState.Pkg(), "deferrangefunc",
+	types2.NewSignatureType(nil, nil, nil,
+		types2.NewTuple(types2.NewParam(syntax.Pos{}, nil, "frame", types2.NewPointer(types2.NewInterfaceType(nil, nil)))),
+		nil, false))
+
+func (r *rewriter) setState(pos syntax.Pos, loop *forLoop, state int) syntax.Stmt {
+	return r.assign(pos, r.useVar(pos, loop.state), r.intLit(pos, state))
+}
+
+func (r *rewriter) assign(pos syntax.Pos, lhs, rhs syntax.Expr) syntax.Stmt {
+	n := &syntax.AssignStmt{Lhs: lhs, Rhs: rhs}
+	n.SetPos(pos)
+	return n
+}
+
+// declVar returns a declaration of v, initialized to init if init is
+// not nil.
+func (r *rewriter) declVar(pos syntax.Pos, v *types2.Var, init syntax.Expr) syntax.Stmt {
+	decl := &syntax.VarDecl{NameList: []*syntax.Name{r.defVar(pos, v)}, Type: r.typeExpr(pos, v.Type()), Values: init}
+	decl.SetPos(pos)
+	n := &syntax.DeclStmt{DeclList: []syntax.Decl{decl}}
+	n.SetPos(pos)
+	return n
+}
+
+func (r *rewriter) exprStmt(pos syntax.Pos, x syntax.Expr) syntax.Stmt {
+	n := &syntax.ExprStmt{X: x}
+	n.SetPos(pos)
+	return n
+}
+
+func (r *rewriter) returnBool(pos syntax.Pos, x bool) syntax.Stmt {
+	n := &syntax.ReturnStmt{Results: r.boolLit(pos, x)}
+	n.SetPos(pos)
+	return n
+}
+
+func (r *rewriter) ifStmt(pos syntax.Pos, cond syntax.Expr, then ...syntax.Stmt) syntax.Stmt {
+	n := &syntax.IfStmt{Cond: cond, Then: r.block(pos, then...).(*syntax.BlockStmt)}
+	n.SetPos(pos)
+	return n
+}
+
+func (r *rewriter) block(pos syntax.Pos, list ...syntax.Stmt) syntax.Stmt {
+	n := &syntax.BlockStmt{List: list, Rbrace: pos}
+	n.SetPos(pos)
+	return n
+}
@@ -99,6 +99,7 @@ func InitConfig() {
 	ir.Syms.CheckPtrAlignment = typecheck.LookupRuntimeFunc("checkptrAlignment")
 	ir.Syms.Deferproc = typecheck.LookupRuntimeFunc("deferproc")
 	ir.Syms.DeferprocStack = typecheck.LookupRuntimeFunc("deferprocStack")
+	ir.Syms.Deferrangefunc = typecheck.LookupRuntimeFunc("deferrangefunc")
 	ir.Syms.Deferreturn = typecheck.LookupRuntimeFunc("deferreturn")
 	ir.Syms.Duffcopy = typecheck.LookupRuntimeFunc("duffcopy")
 	ir.Syms.Duffzero = typecheck.LookupRuntimeFunc("duffzero")
@@ -5062,6 +5063,8 @@ func (s *state) call(n *ir.CallExpr, k callKind, returnResultAddr bool) *ssa.Val
 		// 8: fd
 		// 9: varp
 		// 10: framepc
+		// 11: rangefunc, set in deferprocStack
+		// 12: head, set in deferprocStack
 
 		// Call runtime.deferprocStack with pointer to _defer record.
 		ACArgs = append(ACArgs, types.Types[types.TUINTPTR])
@@ -5154,8 +5157,10 @@ func (s *state) call(n *ir.CallExpr, k callKind, returnResultAddr bool) *ssa.Val
 		s.stmt(ir.NewUnaryExpr(n.Pos(), ir.OVARLIVE, name))
 	}
 
-	// Finish block for defers
-	if k == callDefer || k == callDeferStack {
+	// Finish block for defers. A call of deferrangefunc returns like
+	// deferproc, since panics recovered by the defers it records resume
+	// after it.
+	if k == callDefer || k == callDeferStack || callee != nil && callTargetLSym(callee) == ir.Syms.Deferrangefunc {
 		b := s.endBlock()
 		b.Kind = ssa.BlockDefer
 		b.SetControl(call)
@@ -7807,6 +7812,8 @@ func deferstruct() *types.Type {
 		makefield("fd", types.Types[types.TUINTPTR]),
 		makefield("varp", types.Types[types.TUINTPTR]),
 		makefield("framepc", types.Types[types.TUINTPTR]),
+		makefield("rangefunc", types.Types[types.TBOOL]),
+		makefield("head", types.Types[types.TUINTPTR]),
 	}
 
 	// build struct holding the above fields
@@ -26,8 +26,9 @@ type node struct {
 	pos Pos
 }
 
-func (n *node) Pos() Pos { return n.pos }
-func (*node) aNode()     {}
+func (n *node) Pos() Pos       { return n.pos }
+func (n *node) SetPos(pos Pos) { n.pos = pos }
+func (*node) aNode()           {}
 
 // ----------------------------------------------------------------------------
 // Files
@@ -384,8 +385,9 @@ type (
 	}
 
 	CallStmt struct {
-		Tok  token // Go or Defer
-		Call *CallExpr
+		Tok     token // Go or Defer
+		Call    *CallExpr
+		DeferAt Expr // argument to runtime.deferprocat
 		stmt
 	}
 
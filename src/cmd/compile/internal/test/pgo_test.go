@@ -0,0 +1,54 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"internal/testenv"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// TestPGO checks that a hot interface method call in a program is
+// devirtualized, and the direct call inlined, when the program is
+// compiled with a CPU profile of itself.
+func TestPGO(t *testing.T) {
+	testenv.MustHaveGoRun(t)
+	if testing.Short() {
+		t.Skip("skipping in short mode: profiling takes a second")
+	}
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join("testdata", "pgo", "devirtualize.go")
+	prof := filepath.Join(dir, "cpu.pprof")
+	out, err := exec.Command(testenv.GoToolPath(t), "run", src, prof).CombinedOutput()
+	if err != nil {
+		t.Fatalf("running %s: %v\n%s", src, err, out)
+	}
+
+	out, err = exec.Command(testenv.GoToolPath(t), "tool", "compile", "-p", "main", "-m", "-pgoprofile", prof, "-o", filepath.Join(dir, "x.o"), src).CombinedOutput()
+	if err != nil {
+		t.Fatalf("compiling %s: %v\n%s", src, err, out)
+	}
+	for _, want := range []string{
+		`devirtualize.go:\d+:\d+: PGO devirtualizing s.Area to \*Rect`,
+		`devirtualize.go:\d+:\d+: inlining call to \(\*Rect\).Area`,
+	} {
+		if !regexp.MustCompile(want).Match(out) {
+			t.Errorf("compiler output does not match %q:\n%s", want, out)
+		}
+	}
+
+	// Without the profile, nothing is devirtualized.
+	out, err = exec.Command(testenv.GoToolPath(t), "tool", "compile", "-p", "main", "-m", "-o", filepath.Join(dir, "y.o"), src).CombinedOutput()
+	if err != nil {
+		t.Fatalf("compiling %s: %v\n%s", src, err, out)
+	}
+	if regexp.MustCompile(`PGO devirtualizing`).Match(out) {
+		t.Errorf("compiler devirtualized without a profile:\n%s", out)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This program writes a CPU profile of itself to the file named by
+// its first argument. The profile is used by TestPGO.
+
+package main
+
+import (
+	"os"
+	"runtime/pprof"
+	"time"
+)
+
+type Shape interface {
+	Area() int
+}
+
+type Rect struct{ w, h int }
+
+func (r *Rect) Area() int { return r.w * r.h }
+
+type Square struct{ s int }
+
+func (s *Square) Area() int { return s.s * s.s }
+
+var shapes []Shape
+
+func total() int {
+	t := 0
+	for _, s := range shapes {
+		t += s.Area()
+	}
+	return t
+}
+
+func main() {
+	f, err := os.Create(os.Args[1])
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	if err := pprof.StartCPUProfile(f); err != nil {
+		panic(err)
+	}
+	defer pprof.StopCPUProfile()
+
+	for i := 0; i < 1000; i++ {
+		shapes = append(shapes, &Rect{i, i})
+	}
+	shapes = append(shapes, &Square{1})
+	sum := 0
+	for start := time.Now(); time.Since(start) < time.Second; {
+		sum += total()
+	}
+	if sum == 0 {
+		panic("no work")
+	}
+}
@@ -23,6 +23,9 @@ func panicmakeslicelen()
 func panicmakeslicecap()
 func throwinit()
 func panicwrap()
+func panicrangestate(state int)
+func deferrangefunc(frame *interface{})
+func deferprocat(fn func(), frame interface{})
 
 func gopanic(interface{})
 func gorecover(*int32) interface{}
@@ -259,13 +259,16 @@ import (
 // 2: added information for generic function/types.  The export of non-generic
 // functions/types remains largely backward-compatible.  Breaking changes include:
 //    - a 'kind' byte is added to constant values
+// 3: added per-iteration loop variable flags to for and range statements,
+// and range-over-func frames to defer statements, in function bodies.
 const (
 	iexportVersionGo1_11   = 0
 	iexportVersionPosCol   = 1
 	iexportVersionGenerics = 2
 	iexportVersionGo1_18   = 2
+	iexportVersionLoopVar  = 3
 
-	iexportVersionCurrent = 2
+	iexportVersionCurrent = 3
 )
 
 // predeclReserved is the number of type offsets reserved for types
@@ -462,8 +465,9 @@ func (p *iexporter) pushDecl(n *ir.Name) {
 		base.Fatalf("weird Sym: %v, %v", n, n.Sym())
 	}
 
-	// Don't export predeclared declarations.
-	if n.Sym().Pkg == types.BuiltinPkg || n.Sym().Pkg == types.UnsafePkg {
+	// Don't export predeclared declarations. Runtime functions called
+	// by range-over-func loops are declared by the compiler itself.
+	if n.Sym().Pkg == types.BuiltinPkg || n.Sym().Pkg == types.UnsafePkg || n.Sym().Pkg == ir.Pkgs.Runtime {
 		return
 	}
 
@@ -1026,7 +1030,13 @@ func (w *exportWriter) doTyp(t *types.Type) {
 
 	case types.TFUNC:
 		w.startType(signatureType)
-		w.setPkg(t.Pkg(), true)
+		pkg := t.Pkg()
+		if pkg == types.NoPkg {
+			// The compiler's runtime declarations have signatures
+			// without a package.
+			pkg = ir.Pkgs.Runtime
+		}
+		w.setPkg(pkg, true)
 		w.signature(t)
 
 	case types.TSTRUCT:
@@ -1631,6 +1641,9 @@ func (w *exportWriter) stmt(n ir.Node) {
 		w.op(n.Op())
 		w.pos(n.Pos())
 		w.expr(n.Call)
+		if n.Op() == ir.ODEFER && w.bool(n.DeferAt != nil) {
+			w.expr(n.DeferAt)
+		}
 
 	case ir.OIF:
 		n := n.(*ir.IfStmt)
@@ -1648,6 +1661,7 @@ func (w *exportWriter) stmt(n ir.Node) {
 		w.stmtList(n.Init())
 		w.exprsOrNil(n.Cond, n.Post)
 		w.stmtList(n.Body)
+		w.bool(n.DistinctVars)
 
 	case ir.ORANGE:
 		n := n.(*ir.RangeStmt)
@@ -1657,6 +1671,7 @@ func (w *exportWriter) stmt(n ir.Node) {
 		w.exprsOrNil(n.Key, n.Value)
 		w.expr(n.X)
 		w.stmtList(n.Body)
+		w.bool(n.DistinctVars)
 
 	case ir.OSELECT:
 		n := n.(*ir.SelectStmt)
@@ -139,7 +139,7 @@ func ReadImports(pkg *types.Pkg, data string) {
 
 	version := ird.uint64()
 	switch version {
-	case iexportVersionGo1_18, iexportVersionPosCol, iexportVersionGo1_11:
+	case iexportVersionLoopVar, iexportVersionGo1_18, iexportVersionPosCol, iexportVersionGo1_11:
 	default:
 		base.Errorf("import %q: unknown export format version %d", pkg.Path, version)
 		base.ErrorExit()
@@ -1731,7 +1731,11 @@ func (r *importReader) node() ir.Node {
 	// 	unreachable - generated by compiler for trampolin routines (not exported)
 
 	case ir.OGO, ir.ODEFER:
-		return ir.NewGoDeferStmt(r.pos(), op, r.expr())
+		n := ir.NewGoDeferStmt(r.pos(), op, r.expr())
+		if op == ir.ODEFER && r.p.exportVersion >= iexportVersionLoopVar && r.bool() {
+			n.DeferAt = r.expr()
+		}
+		return n
 
 	case ir.OIF:
 		pos, init := r.pos(), r.stmtList()
@@ -1744,6 +1748,9 @@ func (r *importReader) node() ir.Node {
 		cond, post := r.exprsOrNil()
 		n := ir.NewForStmt(pos, nil, cond, post, r.stmtList())
 		n.SetInit(init)
+		if r.p.exportVersion >= iexportVersionLoopVar {
+			n.DistinctVars = r.bool()
+		}
 		return n
 
 	case ir.ORANGE:
@@ -1751,6 +1758,9 @@ func (r *importReader) node() ir.Node {
 		k, v := r.exprsOrNil()
 		n := ir.NewRangeStmt(pos, k, v, r.expr(), r.stmtList())
 		n.SetInit(init)
+		if r.p.exportVersion >= iexportVersionLoopVar {
+			n.DistinctVars = r.bool()
+		}
 		return n
 
 	case ir.OSELECT:
@@ -2140,6 +2140,14 @@ func IsReflectPkg(p *Pkg) bool {
 	return p.Path == "reflect"
 }
 
+// isSyncAtomicPkg reports whether p is package sync/atomic.
+func isSyncAtomicPkg(p *Pkg) bool {
+	if p == LocalPkg {
+		return base.Ctxt.Pkgpath == "sync/atomic"
+	}
+	return p.Path == "sync/atomic"
+}
+
 // ReceiverBaseType returns the underlying type, if any,
 // that owns methods with receiver parameter t.
 // The result is either a named type or an anonymous struct.
@@ -7,6 +7,8 @@
 
 package types2
 
+import "go/constant"
+
 // If t is a pointer, AsPointer returns that type, otherwise it returns nil.
 func AsPointer(t Type) *Pointer {
 	u, _ := t.Underlying().(*Pointer)
@@ -28,3 +30,32 @@ func AsSignature(t Type) *Signature {
 func StructuralType(t Type) Type {
 	return structuralType(t)
 }
+
+// The following functions construct the type information recorded for
+// expressions that the compiler synthesizes after type checking, such as
+// the code generated for range-over-func loops.
+
+// TypeExprValue returns the TypeAndValue of an expression denoting the type t.
+func TypeExprValue(t Type) TypeAndValue {
+	return TypeAndValue{typexpr, t, nil}
+}
+
+// VariableValue returns the TypeAndValue of an addressable variable of type t.
+func VariableValue(t Type) TypeAndValue {
+	return TypeAndValue{variable, t, nil}
+}
+
+// ComputedValue returns the TypeAndValue of a computed value of type t.
+func ComputedValue(t Type) TypeAndValue {
+	return TypeAndValue{value, t, nil}
+}
+
+// ConstantValue returns the TypeAndValue of the constant val of type t.
+func ConstantValue(t Type, val constant.Value) TypeAndValue {
+	return TypeAndValue{constant_, t, val}
+}
+
+// VoidValue returns the TypeAndValue of a call of a function without results.
+func VoidValue() TypeAndValue {
+	return TypeAndValue{novalue, (*Tuple)(nil), nil}
+}
@@ -853,14 +853,30 @@ func (check *Checker) rangeStmt(inner stmtContext, s *syntax.ForStmt, rclause *s
 				cause = check.sprintf("%s has no structural type", x.typ)
 			}
 		}
-		key, val = rangeKeyVal(u)
-		if key == nil || cause != "" {
+		var ok bool
+		var rcause string
+		key, val, rcause, ok = rangeKeyVal(u)
+		if cause == "" {
+			cause = rcause
+		}
+		if !ok || cause != "" {
 			if cause == "" {
 				check.softErrorf(&x, "cannot range over %s", &x)
 			} else {
 				check.softErrorf(&x, "cannot range over %s (%s)", &x, cause)
 			}
 			// ok to continue
+		} else if _, isFunc := u.(*Signature); isFunc {
+			if !check.allowVersion(check.pkg, 1, 18) {
+				check.versionErrorf(&x, "go1.18", "range over %s", &x)
+			}
+			if sKey != nil && key == nil {
+				check.softErrorf(sKey, "range over %s permits no iteration variables", &x)
+				// ok to continue
+			} else if sValue != nil && val == nil {
+				check.softErrorf(sValue, "range over %s permits only one iteration variable", &x)
+				// ok to continue
+			}
 		}
 	}
 
@@ -941,22 +957,42 @@ func (check *Checker) rangeStmt(inner stmtContext, s *syntax.ForStmt, rclause *s
 }
 
 // rangeKeyVal returns the key and value type produced by a range clause
-// over an expression of type typ. If the range clause is not permitted
-// the results are nil.
-func rangeKeyVal(typ Type) (key, val Type) {
+// over an expression of type typ. If the range clause is not permitted,
+// ok is false and cause may describe why. For a range over a function,
+// key and val are nil if the yield function has fewer parameters.
+func rangeKeyVal(typ Type) (key, val Type, cause string, ok bool) {
 	switch typ := arrayPtrDeref(typ).(type) {
 	case *Basic:
 		if isString(typ) {
-			return Typ[Int], universeRune // use 'rune' name
+			return Typ[Int], universeRune, "", true // use 'rune' name
 		}
 	case *Array:
-		return Typ[Int], typ.elem
+		return Typ[Int], typ.elem, "", true
 	case *Slice:
-		return Typ[Int], typ.elem
+		return Typ[Int], typ.elem, "", true
 	case *Map:
-		return typ.key, typ.elem
+		return typ.key, typ.elem, "", true
 	case *Chan:
-		return typ.elem, Typ[Invalid]
+		return typ.elem, Typ[Invalid], "", true
+	case *Signature:
+		// spec: "For a function f, the iteration proceeds by calling f with
+		// a new, synthesized yield function as its argument."
+		const bad = "func must be func(yield func(...) bool)"
+		if typ.TypeParams().Len() != 0 || typ.variadic || typ.params.Len() != 1 || typ.results.Len() != 0 {
+			return nil, nil, bad, false
+		}
+		yield, _ := under(typ.params.vars[0].typ).(*Signature)
+		if yield == nil || yield.variadic || yield.params.Len() > 2 ||
+			yield.results.Len() != 1 || !isBoolean(yield.results.vars[0].typ) {
+			return nil, nil, bad, false
+		}
+		if yield.params.Len() >= 1 {
+			key = yield.params.vars[0].typ
+		}
+		if yield.params.Len() >= 2 {
+			val = yield.params.vars[1].typ
+		}
+		return key, val, "", true
 	}
 	return
 }
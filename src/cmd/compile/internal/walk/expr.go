@@ -550,10 +550,26 @@ func walkCall(n *ir.CallExpr, init *ir.Nodes) ir.Node {
 		return e
 	}
 
+	if isDeferRangeFunc(n) {
+		// The defers queued by the range-over-func loop bodies are
+		// run by the deferreturn call of this function.
+		ir.CurFunc.SetHasDefer(true)
+		ir.CurFunc.SetOpenCodedDeferDisallowed(true)
+	}
+
 	walkCall1(n, init)
 	return n
 }
 
+// isDeferRangeFunc reports whether n is a call of runtime.deferrangefunc.
+func isDeferRangeFunc(n *ir.CallExpr) bool {
+	if n.Op() != ir.OCALLFUNC || n.X.Op() != ir.ONAME {
+		return false
+	}
+	fn := n.X.(*ir.Name)
+	return fn.Class == ir.PFUNC && fn.Sym().Pkg == ir.Pkgs.Runtime && fn.Sym().Name == "deferrangefunc"
+}
+
 func walkCall1(n *ir.CallExpr, init *ir.Nodes) {
 	if n.Walked() {
 		return // already walked
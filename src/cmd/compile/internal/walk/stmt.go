@@ -104,6 +104,9 @@ func walkStmt(n ir.Node) ir.Node {
 
 	case ir.ODEFER:
 		n := n.(*ir.GoDeferStmt)
+		if n.DeferAt != nil {
+			return walkDeferAt(n)
+		}
 		ir.CurFunc.SetHasDefer(true)
 		ir.CurFunc.NumDefers++
 		if ir.CurFunc.NumDefers > maxOpenDefers {
@@ -222,6 +225,19 @@ func walkGoDefer(n *ir.GoDeferStmt) ir.Node {
 	return n
 }
 
+// walkDeferAt walks an ODEFER node in the body of a range-over-func
+// loop, which queues the deferred call on the frame of the function
+// containing the loop instead of the body function's own.
+func walkDeferAt(n *ir.GoDeferStmt) ir.Node {
+	if !validGoDeferCall(n.Call) {
+		base.FatalfAt(n.Pos(), "invalid %v call: %v", n.Op(), n.Call)
+	}
+	init := ir.TakeInit(n)
+	call := n.Call.(*ir.CallExpr)
+	init.Append(mkcallstmt("deferprocat", call.X, n.DeferAt))
+	return ir.NewBlockStmt(n.Pos(), init)
+}
+
 // walkIf walks an OIF node.
 func walkIf(n *ir.IfStmt) ir.Node {
 	n.Cond = walkExpr(n.Cond, n.PtrInit())
@@ -0,0 +1,310 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+const usageMessage = "" +
+	`Usage: go tool covdata <mode> -i=<dir1,dir2,...> -o=<output>
+
+Modes:
+	merge     merge coverage data directories into the -o directory
+	subtract  subtract later input directories from the first one
+	textfmt   convert coverage data to a 'go test -coverprofile' file
+	percent   report the percentage of statements covered by package
+`
+
+func usage() {
+	fmt.Fprint(os.Stderr, usageMessage)
+	fmt.Fprintln(os.Stderr, "\nFlags:")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+var (
+	inDirs = flag.String("i", "", "comma-separated list of input directories")
+	output = flag.String("o", "", "output directory (merge, subtract) or file (textfmt)")
+)
+
+// counterPrefix is the name prefix of the files written by
+// runtime/coverage.
+const counterPrefix = "covcounters."
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("covdata: ")
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+	mode := os.Args[1]
+	flag.Usage = usage
+	flag.CommandLine.Parse(os.Args[2:])
+	if flag.NArg() != 0 || *inDirs == "" {
+		usage()
+	}
+
+	var inputs []*data
+	for _, dir := range strings.Split(*inDirs, ",") {
+		d, err := readDir(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		inputs = append(inputs, d)
+	}
+
+	var result *data
+	var err error
+	switch mode {
+	case "merge", "textfmt", "percent":
+		result, err = merge(inputs)
+	case "subtract":
+		result, err = subtract(inputs)
+	default:
+		fmt.Fprintf(os.Stderr, "covdata: unknown mode %q\n", mode)
+		usage()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch mode {
+	case "merge", "subtract":
+		if *output == "" {
+			log.Fatalf("%s requires an output directory (-o)", mode)
+		}
+		err = writeFile(filepath.Join(*output, counterPrefix+mode), result)
+	case "textfmt":
+		if *output == "" {
+			log.Fatalf("textfmt requires an output file (-o)")
+		}
+		err = writeFile(*output, result)
+	case "percent":
+		err = result.writePercent(os.Stdout)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// A blockKey identifies a basic block in a source file.
+type blockKey struct {
+	file                                 string
+	startLine, startCol, endLine, endCol int
+}
+
+// data is coverage data combined from one or more profiles.
+type data struct {
+	mode    string
+	numStmt map[blockKey]int
+	count   map[blockKey]int
+}
+
+func newData() *data {
+	return &data{
+		numStmt: make(map[blockKey]int),
+		count:   make(map[blockKey]int),
+	}
+}
+
+// add adds the blocks of the profiles in prof to d.
+func (d *data) add(prof []*cover.Profile, source string) error {
+	for _, p := range prof {
+		if d.mode == "" {
+			d.mode = p.Mode
+		} else if p.Mode != d.mode {
+			return fmt.Errorf("%s: coverage mode %q does not match %q of earlier inputs", source, p.Mode, d.mode)
+		}
+		for _, b := range p.Blocks {
+			k := blockKey{p.FileName, b.StartLine, b.StartCol, b.EndLine, b.EndCol}
+			if n, ok := d.numStmt[k]; ok && n != b.NumStmt {
+				return fmt.Errorf("%s: inconsistent number of statements for block %s", source, k)
+			}
+			d.numStmt[k] = b.NumStmt
+			if d.mode == "set" {
+				if b.Count != 0 {
+					d.count[k] = 1
+				} else if _, ok := d.count[k]; !ok {
+					d.count[k] = 0
+				}
+			} else {
+				d.count[k] += b.Count
+			}
+		}
+	}
+	return nil
+}
+
+func (k blockKey) String() string {
+	return fmt.Sprintf("%s:%d.%d,%d.%d", k.file, k.startLine, k.startCol, k.endLine, k.endCol)
+}
+
+// readDir reads and combines the coverage data files in dir.
+func readDir(dir string) (*data, error) {
+	files, err := filepath.Glob(filepath.Join(dir, counterPrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+	d := newData()
+	n := 0
+	for _, file := range files {
+		if strings.Contains(filepath.Base(file), ".tmp") {
+			// Being written by a running program.
+			continue
+		}
+		prof, err := cover.ParseProfiles(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.add(prof, file); err != nil {
+			return nil, err
+		}
+		n++
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("no coverage data files found in %s", dir)
+	}
+	return d, nil
+}
+
+// merge combines inputs into a single data set.
+func merge(inputs []*data) (*data, error) {
+	d := newData()
+	for _, in := range inputs {
+		if d.mode == "" {
+			d.mode = in.mode
+		} else if in.mode != d.mode {
+			return nil, fmt.Errorf("cannot merge coverage mode %q with %q", in.mode, d.mode)
+		}
+		for k, n := range in.numStmt {
+			if m, ok := d.numStmt[k]; ok && m != n {
+				return nil, fmt.Errorf("inconsistent number of statements for block %s", k)
+			}
+			d.numStmt[k] = n
+			c := d.count[k] + in.count[k]
+			if d.mode == "set" && c > 1 {
+				c = 1
+			}
+			d.count[k] = c
+		}
+	}
+	return d, nil
+}
+
+// subtract returns the first of inputs, with the counters of the
+// blocks covered by any of the others reset to zero.
+func subtract(inputs []*data) (*data, error) {
+	d := newData()
+	first := inputs[0]
+	d.mode = first.mode
+	for k, n := range first.numStmt {
+		d.numStmt[k] = n
+		d.count[k] = first.count[k]
+	}
+	for _, in := range inputs[1:] {
+		if in.mode != d.mode {
+			return nil, fmt.Errorf("cannot subtract coverage mode %q from %q", in.mode, d.mode)
+		}
+		for k, c := range in.count {
+			if _, ok := d.count[k]; ok && c != 0 {
+				d.count[k] = 0
+			}
+		}
+	}
+	return d, nil
+}
+
+// keys returns the blocks of d in a deterministic order.
+func (d *data) keys() []blockKey {
+	keys := make([]blockKey, 0, len(d.numStmt))
+	for k := range d.numStmt {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		if a.startLine != b.startLine {
+			return a.startLine < b.startLine
+		}
+		if a.startCol != b.startCol {
+			return a.startCol < b.startCol
+		}
+		if a.endLine != b.endLine {
+			return a.endLine < b.endLine
+		}
+		return a.endCol < b.endCol
+	})
+	return keys
+}
+
+// writeTo writes d to w in the text profile format.
+func (d *data) writeTo(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "mode: %s\n", d.mode)
+	for _, k := range d.keys() {
+		fmt.Fprintf(bw, "%s %d %d\n", k, d.numStmt[k], d.count[k])
+	}
+	return bw.Flush()
+}
+
+// writePercent writes the percentage of statements covered in each
+// package of d to w.
+func (d *data) writePercent(w io.Writer) error {
+	type stmts struct{ covered, total int }
+	pkgs := make(map[string]*stmts)
+	var names []string
+	for _, k := range d.keys() {
+		pkg := path.Dir(filepath.ToSlash(k.file))
+		s := pkgs[pkg]
+		if s == nil {
+			s = new(stmts)
+			pkgs[pkg] = s
+			names = append(names, pkg)
+		}
+		s.total += d.numStmt[k]
+		if d.count[k] != 0 {
+			s.covered += d.numStmt[k]
+		}
+	}
+	sort.Strings(names)
+	bw := bufio.NewWriter(w)
+	for _, pkg := range names {
+		s := pkgs[pkg]
+		pct := 0.0
+		if s.total > 0 {
+			pct = 100 * float64(s.covered) / float64(s.total)
+		}
+		fmt.Fprintf(bw, "\t%s\t\tcoverage: %.1f%% of statements\n", pkg, pct)
+	}
+	return bw.Flush()
+}
+
+// writeFile writes d to the named file in the text profile format.
+func writeFile(name string, d *data) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = d.writeTo(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
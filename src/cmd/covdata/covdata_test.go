@@ -0,0 +1,106 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func parse(t *testing.T, text string) *data {
+	t.Helper()
+	prof, err := cover.ParseProfilesFromReader(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newData()
+	if err := d.add(prof, "test"); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func format(t *testing.T, d *data) string {
+	t.Helper()
+	var sb strings.Builder
+	if err := d.writeTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+const (
+	set1 = `mode: set
+p/a.go:1.1,2.2 1 1
+p/a.go:3.1,4.2 2 0
+p/a.go:5.1,6.2 1 0
+`
+	set2 = `mode: set
+p/a.go:1.1,2.2 1 1
+p/a.go:3.1,4.2 2 1
+p/b.go:1.1,2.2 1 0
+`
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs []string
+		want   string
+	}{
+		{"set", []string{set1, set2}, `mode: set
+p/a.go:1.1,2.2 1 1
+p/a.go:3.1,4.2 2 1
+p/a.go:5.1,6.2 1 0
+p/b.go:1.1,2.2 1 0
+`},
+		{"count", []string{
+			"mode: count\np/a.go:1.1,2.2 1 3\np/a.go:3.1,4.2 2 0\n",
+			"mode: count\np/a.go:1.1,2.2 1 4\np/a.go:3.1,4.2 2 1\n",
+		}, `mode: count
+p/a.go:1.1,2.2 1 7
+p/a.go:3.1,4.2 2 1
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inputs []*data
+			for _, in := range tt.inputs {
+				inputs = append(inputs, parse(t, in))
+			}
+			d, err := merge(inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := format(t, d); got != tt.want {
+				t.Errorf("merge:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeModeMismatch(t *testing.T) {
+	_, err := merge([]*data{parse(t, set1), parse(t, "mode: count\np/a.go:1.1,2.2 1 3\n")})
+	if err == nil {
+		t.Fatal("merge of set and count data succeeded, want error")
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	d, err := subtract([]*data{parse(t, set2), parse(t, set1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `mode: set
+p/a.go:1.1,2.2 1 0
+p/a.go:3.1,4.2 2 1
+p/b.go:1.1,2.2 1 0
+`
+	if got := format(t, d); got != want {
+		t.Errorf("subtract:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Covdata is a program for manipulating the coverage data files written
+by programs built with 'go build -cover'.
+
+Each run of such a program writes a file named covcounters.* to the
+directory named by the GOCOVERDIR environment variable. Covdata reads
+the files in one or more such directories and combines them.
+
+Usage:
+	go tool covdata <mode> -i=<dir1,dir2,...> -o=<output> [flags]
+
+The modes are:
+
+	merge     merge the data in the input directories into a single
+	          file in the output directory
+	subtract  write the data of the first input directory, with the
+	          blocks covered by any later input directory marked as
+	          not covered, to the output directory
+	textfmt   write the data in the input directories to the output
+	          file in the format of 'go test -coverprofile', for use
+	          with 'go tool cover'
+	percent   print the percentage of statements covered, by package
+
+In "set" mode, a block is covered if any input covers it. In "count"
+and "atomic" modes, the counts of the inputs are added. All inputs
+must have been collected in the same coverage mode.
+
+The percent mode does not use -o.
+*/
+package main
@@ -53,6 +53,7 @@ var (
 	output  = flag.String("o", "", "file for output; default: stdout")
 	htmlOut = flag.String("html", "", "generate HTML representation of coverage profile")
 	funcOut = flag.String("func", "", "output coverage profile information for each function")
+	regName = flag.String("register", "", "register counters with runtime/coverage under this file name")
 )
 
 var profile string // The profile to read; the value of -html or -func
@@ -62,6 +63,8 @@ var counterStmt func(*File, string) string
 const (
 	atomicPackagePath = "sync/atomic"
 	atomicPackageName = "_cover_atomic_"
+
+	coveragePackagePath = "runtime/coverage"
 )
 
 func main() {
@@ -136,6 +139,8 @@ func parseFlags() error {
 		} else if flag.NArg() == 1 {
 			return nil
 		}
+	} else if *regName != "" {
+		return fmt.Errorf("-register requires -mode")
 	} else if flag.NArg() == 0 {
 		return nil
 	}
@@ -328,6 +333,17 @@ func annotate(name string) {
 		file.edit.Insert(file.offset(file.astFile.Name.End()),
 			fmt.Sprintf("; import %s %q", atomicPackageName, atomicPackagePath))
 	}
+	if *regName != "" {
+		// The registration function is declared with a linkname
+		// directive, which requires importing unsafe. A main package
+		// also imports runtime/coverage, so that the counters are
+		// written out when the program exits.
+		imports := fmt.Sprintf("; import _ %q", "unsafe")
+		if file.astFile.Name.Name == "main" {
+			imports += fmt.Sprintf("; import _ %q", coveragePackagePath)
+		}
+		file.edit.Insert(file.offset(file.astFile.Name.End()), imports)
+	}
 
 	ast.Walk(file, file.astFile)
 	newContent := file.edit.Bytes()
@@ -689,6 +705,16 @@ func (f *File) addVariables(w io.Writer) {
 	if *mode == "atomic" {
 		fmt.Fprintf(w, "var _ = %s.LoadUint32\n", atomicPackageName)
 	}
+
+	// Register the counters with runtime/coverage, which writes
+	// them out when a program built with "go build -cover" exits.
+	if *regName != "" {
+		fmt.Fprintf(w, "\n//go:linkname %s_register %s.addFile\n", *varVar, coveragePackagePath)
+		fmt.Fprintf(w, "func %s_register(name, mode string, counts, pos []uint32, numStmt []uint16)\n", *varVar)
+		fmt.Fprintf(w, "\nfunc init() {\n")
+		fmt.Fprintf(w, "\t%s_register(%q, %q, %s.Count[:], %s.Pos[:], %s.NumStmt[:])\n", *varVar, *regName, *mode, *varVar, *varVar, *varVar)
+		fmt.Fprintf(w, "}\n")
+	}
 }
 
 // It is possible for positions to repeat when there is a line
@@ -125,6 +125,37 @@
 // 	-asan
 // 		enable interoperation with address sanitizer.
 // 		Supported only on linux/arm64, linux/amd64.
+// 	-cover
+// 		enable code coverage instrumentation of the built program.
+// 		When the program exits, it writes its coverage counters to a new
+// 		file in the directory named by the GOCOVERDIR environment variable.
+// 		Use 'go tool covdata' to merge and convert such files.
+// 		The -cover, -covermode, and -coverpkg flags apply to go build,
+// 		go install, and go run; go test has its own flags of the same
+// 		names (see 'go help testflag').
+// 	-covermode set,count,atomic
+// 		set the mode for coverage analysis.
+// 		The default is "set" unless -race is enabled,
+// 		in which case it is "atomic".
+// 		The values:
+// 		set: bool: does this statement run?
+// 		count: int: how many times does this statement run?
+// 		atomic: int: count, but correct in multithreaded programs;
+// 			significantly more expensive.
+// 		Sets -cover.
+// 	-coverpkg pattern1,pattern2,pattern3
+// 		apply coverage analysis to each package matching the patterns.
+// 		The default is to apply coverage analysis to the packages in the
+// 		main module. The main packages are always instrumented.
+// 		See 'go help packages' for a description of package patterns.
+// 		Sets -cover.
+// 	-pgo file
+// 		specify the file path of a profile for profile-guided optimization (PGO).
+// 		The profile is a CPU profile in the format written by runtime/pprof.
+// 		When the special name "auto" is specified, and the build has exactly
+// 		one main package, the file named "default.pgo" in the main package's
+// 		directory is used, if it exists.
+// 		The special name "off" turns off PGO. The default is "auto".
 // 	-v
 // 		print the names of packages as they are compiled.
 // 	-work
@@ -626,7 +657,7 @@
 //
 // Usage:
 //
-// 	go get [-t] [-u] [-v] [build flags] [packages]
+// 	go get [-t] [-u] [-v] [-tool] [build flags] [packages]
 //
 // Get resolves its command-line arguments to packages at specific module versions,
 // updates go.mod to require those versions, and downloads source code into the
@@ -644,6 +675,28 @@
 //
 // 	go get example.com/mod@none
 //
+// To change the minimum Go version required by the current module,
+// or the Go toolchain that runs go commands in it:
+//
+// 	go get go@1.18
+// 	go get toolchain@go1.18.2
+// 	go get toolchain@none
+//
+// If the requested go version is newer than the running toolchain,
+// the go command switches to a toolchain that supports it when
+// GOTOOLCHAIN allows, and fails otherwise (see GOTOOLCHAIN in
+// 'go help environment').
+//
+// To add a tool, such as a code generator, as a dependency of the current
+// module and record it in a tool line of go.mod, so that 'go tool' can run it:
+//
+// 	go get -tool example.com/cmd/stringer
+//
+// To upgrade all the tools of the current module, or to stop tracking a tool:
+//
+// 	go get tool
+// 	go get -tool example.com/cmd/stringer@none
+//
 // See https://golang.org/ref/mod#go-get for details.
 //
 // In earlier versions of Go, 'go get' was used to build and install packages.
@@ -672,6 +725,11 @@
 // When the -t and -u flags are used together, get will update
 // test dependencies as well.
 //
+// The -tool flag instructs get to add a tool line to go.mod for each
+// package named on the command line, or to remove the tool line for
+// each package named with the version suffix @none. The requirements
+// of the removed tools are dropped by the next 'go mod tidy'.
+//
 // The -x flag prints commands as they are executed. This is useful for
 // debugging version control commands when a module is downloaded directly
 // from a repository.
@@ -1736,6 +1794,13 @@
 // Tool runs the go tool command identified by the arguments.
 // With no arguments it prints the list of known tools.
 //
+// The command may also name a tool listed by a tool line in the
+// main module's go.mod file (see 'go help get'), either by its full
+// import path or by the last element of that path, like stringer for
+// golang.org/x/tools/cmd/stringer. Such a tool is built at the version
+// selected by the module graph, and the executable is kept in the build
+// cache so that later runs do not rebuild it.
+//
 // The -n flag causes tool to print the command that would be
 // executed but not execute it.
 //
@@ -2029,6 +2094,9 @@
 // 	GOCACHE
 // 		The directory where the go command will store cached
 // 		information for reuse in future builds.
+// 	GOCOVERDIR
+// 		The directory into which programs built with 'go build -cover'
+// 		write their coverage data when they exit.
 // 	GOMODCACHE
 // 		The directory where the go command will store downloaded modules.
 // 	GODEBUG
@@ -2071,6 +2139,16 @@
 // 	GOTMPDIR
 // 		The directory where the go command will write
 // 		temporary source files, packages, and binaries.
+// 	GOTOOLCHAIN
+// 		Controls which Go toolchain runs the go command. The default, local,
+// 		always runs the local toolchain. With auto, the go command runs the
+// 		local toolchain unless the main module's go.mod file asks for a
+// 		newer one with its toolchain or go line, in which case that
+// 		toolchain is downloaded as the module golang.org/toolchain,
+// 		verified using the checksum database, and run instead.
+// 		Other settings are goX (always run goX), path (like auto, but only
+// 		run toolchains found in PATH), and goX+auto or goX+path (like auto
+// 		or path, with goX as the minimum).
 // 	GOVCS
 // 		Lists version control commands that may be used with matching servers.
 // 		See 'go help vcs'.
@@ -2245,6 +2323,18 @@
 // 'go get'. For details, see 'go help module-get' or
 // https://golang.org/ref/mod#go-get.
 //
+// The go line states the minimum Go version required by the module, and
+// the optional toolchain line names the Go toolchain, like go1.18.2, that
+// go commands should use in the module. If either asks for a toolchain
+// newer than the one running, the go command switches to it when
+// GOTOOLCHAIN allows; see 'go help environment'. To change these lines,
+// use 'go get go@version' and 'go get toolchain@name'.
+//
+// Each tool line names a package, such as a code generator, that the
+// module runs with 'go tool' (see 'go help tool'). The modules providing
+// tools are required like those providing imported packages. To add or
+// remove tool lines, use 'go get -tool'.
+//
 // To make other changes or to parse go.mod as JSON for use by other tools,
 // use 'go mod edit'. See 'go help mod edit' or
 // https://golang.org/ref/mod#go-mod-edit.
@@ -2723,7 +2813,7 @@
 // If no import paths are given, the action applies to the
 // package in the current directory.
 //
-// There are four reserved names for paths that should not be used
+// There are five reserved names for paths that should not be used
 // for packages to be built with the go tool:
 //
 // - "main" denotes the top-level package in a stand-alone executable.
@@ -2740,6 +2830,10 @@
 // - "cmd" expands to the Go repository's commands and their
 // internal libraries.
 //
+// - "tool" expands to the tools of the main module, which are listed
+// by the tool lines of its go.mod file (see 'go help get'). When using
+// modules, "all" includes the tools as well.
+//
 // Import paths beginning with "cmd/" only match source code in
 // the Go repository.
 //
@@ -2924,6 +3018,13 @@
 // 	    The special syntax Nx means to run the fuzz target N times
 // 	    (for example, -fuzzminimizetime 100x).
 //
+// 	-goroutineleak
+// 	    Fail tests that leak goroutines. After each test, a garbage
+// 	    collection looks for goroutines blocked forever on channels or
+// 	    synchronization objects that no other goroutine can reach, and the
+// 	    test fails, listing their stacks, if any leaked since the previous
+// 	    test finished. See the goroutineleak profile in runtime/pprof.
+//
 // 	-json
 // 	    Log verbose output and test results in JSON. This presents the
 // 	    same information as the -v flag in a machine-readable format.
@@ -27,18 +27,28 @@ const ToolWindowsExtension = ".exe"
 // Tool returns the path to the named tool (for example, "vet").
 // If the tool cannot be found, Tool exits the process.
 func Tool(toolName string) string {
+	toolPath, err := ToolPath(toolName)
+	if err != nil {
+		// Give a nice message if there is no tool with that name.
+		fmt.Fprintf(os.Stderr, "go: no such tool %q\n", toolName)
+		SetExitStatus(2)
+		Exit()
+	}
+	return toolPath
+}
+
+// ToolPath returns the path to the named tool (for example, "vet"),
+// or an error if the tool cannot be found.
+func ToolPath(toolName string) (string, error) {
 	toolPath := filepath.Join(ToolDir, toolName)
 	if ToolIsWindows {
 		toolPath += ToolWindowsExtension
 	}
 	if len(cfg.BuildToolexec) > 0 {
-		return toolPath
+		return toolPath, nil
 	}
-	// Give a nice message if there is no tool with that name.
 	if _, err := os.Stat(toolPath); err != nil {
-		fmt.Fprintf(os.Stderr, "go: no such tool %q\n", toolName)
-		SetExitStatus(2)
-		Exit()
+		return "", err
 	}
-	return toolPath
+	return toolPath, nil
 }
@@ -315,6 +315,7 @@ func (c *Cache) Trim() {
 		subdir := filepath.Join(c.dir, fmt.Sprintf("%02x", i))
 		c.trimSubdir(subdir, cutoff)
 	}
+	c.trimTools(cutoff)
 
 	// Ignore errors from here: if we don't write the complete timestamp, the
 	// cache will appear older than it is, and we'll trim it again next time.
@@ -352,6 +353,34 @@ func (c *Cache) trimSubdir(subdir string, cutoff time.Time) {
 	}
 }
 
+// ToolDir returns the directory of the cache that holds the executable
+// of the 'go tool' command with the given id, and records that it was used.
+// The directory may not exist yet.
+func (c *Cache) ToolDir(id ActionID) string {
+	dir := filepath.Join(c.dir, "tool", fmt.Sprintf("%x", id[:16]))
+	c.used(dir)
+	return dir
+}
+
+// trimTools removes the tool directories that have not been used since cutoff.
+func (c *Cache) trimTools(cutoff time.Time) {
+	tooldir := filepath.Join(c.dir, "tool")
+	f, err := os.Open(tooldir)
+	if err != nil {
+		return
+	}
+	names, _ := f.Readdirnames(-1)
+	f.Close()
+
+	for _, name := range names {
+		dir := filepath.Join(tooldir, name)
+		info, err := os.Stat(dir)
+		if err == nil && info.IsDir() && info.ModTime().Before(cutoff) {
+			os.RemoveAll(dir)
+		}
+	}
+}
+
 // putIndexEntry adds an entry to the cache recording that executing the action
 // with the given id produces an output with the given output id (hash) and size.
 func (c *Cache) putIndexEntry(id ActionID, out OutputID, size int64, allowVerify bool) error {
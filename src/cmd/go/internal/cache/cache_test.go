@@ -267,3 +267,37 @@ func TestCacheTrim(t *testing.T) {
 		t.Fatal("Trim did not remove dummyID(1)")
 	}
 }
+
+func TestCacheTrimTools(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	start := time.Now().Truncate(time.Second)
+	now := start
+	c.now = func() time.Time { return now }
+
+	used := c.ToolDir(ActionID(dummyID(1)))
+	unused := c.ToolDir(ActionID(dummyID(2)))
+	for _, d := range []string{used, unused} {
+		if err := os.MkdirAll(d, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Running a tool after a few days keeps its directory.
+	now = start.Add(3 * 24 * time.Hour)
+	if d := c.ToolDir(ActionID(dummyID(1))); d != used {
+		t.Fatalf("ToolDir = %s, want %s", d, used)
+	}
+
+	now = start.Add(7 * 24 * time.Hour)
+	c.Trim()
+	if _, err := os.Stat(used); err != nil {
+		t.Fatalf("Trim removed tool directory used recently: %v", err)
+	}
+	if _, err := os.Stat(unused); err == nil {
+		t.Fatalf("Trim did not remove unused tool directory")
+	}
+}
@@ -142,6 +142,10 @@ func runClean(ctx context.Context, cmd *base.Command, args []string) {
 			// and not something that we want to remove. Also, we'd like to preserve
 			// the access log for future analysis, even if the cache is cleared.
 			subdirs, _ := filepath.Glob(filepath.Join(dir, "[0-9a-f][0-9a-f]"))
+			// The tool subdirectory holds the executables built by 'go tool'.
+			if _, err := os.Stat(filepath.Join(dir, "tool")); err == nil {
+				subdirs = append(subdirs, filepath.Join(dir, "tool"))
+			}
 			printedErrors := false
 			if len(subdirs) > 0 {
 				if cfg.BuildN || cfg.BuildX {
@@ -0,0 +1,212 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gover implements support for Go toolchain versions like 1.18.1 and 1.19rc1.
+//
+// Go versions are not semantic versions: 1.18 and 1.18.0 denote the
+// same release, and pre-releases are written 1.19beta1 and 1.19rc2,
+// without a hyphen. The semver package therefore cannot compare them.
+package gover
+
+import (
+	"go/build"
+	"runtime"
+	"strings"
+)
+
+// A version is a parsed Go version: major[.minor[.patch]][kind[pre]].
+// The numbers are kept as strings so that arbitrarily large values
+// compare correctly.
+type version struct {
+	major string // "1"
+	minor string // "18"
+	patch string // "1", "0" if absent
+	kind  string // "", "beta", "rc"
+	pre   string // "1", "2", ... for beta and rc
+}
+
+// Compare returns -1, 0, or +1 depending on whether
+// x < y, x == y, or x > y, interpreted as Go versions.
+// The versions x and y must not begin with a "go" prefix: just "1.18" not "go1.18".
+// Invalid versions, including the empty string, compare less than
+// valid versions and equal to each other.
+// Pre-releases come before the release they lead up to:
+// 1.18beta1 < 1.18rc1 < 1.18 == 1.18.0 < 1.18.1.
+func Compare(x, y string) int {
+	vx := parse(x)
+	vy := parse(y)
+
+	if c := cmpInt(vx.major, vy.major); c != 0 {
+		return c
+	}
+	if c := cmpInt(vx.minor, vy.minor); c != 0 {
+		return c
+	}
+	if c := cmpInt(vx.patch, vy.patch); c != 0 {
+		return c
+	}
+	if c := cmpKind(vx.kind, vy.kind); c != 0 {
+		return c
+	}
+	return cmpInt(vx.pre, vy.pre)
+}
+
+// Max returns the maximum of x and y interpreted as Go versions.
+// If x and y compare equal, Max returns x.
+func Max(x, y string) string {
+	if Compare(x, y) < 0 {
+		return y
+	}
+	return x
+}
+
+// IsValid reports whether the version x is valid.
+func IsValid(x string) bool {
+	return parse(x) != version{}
+}
+
+// Local returns the Go version of the running toolchain, like "1.18.1".
+// Development toolchains report the latest release they support.
+func Local() string {
+	v := strings.TrimPrefix(runtime.Version(), "go")
+	if IsValid(v) {
+		return v
+	}
+	tags := build.Default.ReleaseTags
+	return strings.TrimPrefix(tags[len(tags)-1], "go")
+}
+
+// FromToolchain returns the Go version for the named toolchain,
+// like "1.18.1" for "go1.18.1".
+// If the name is not a valid toolchain name, FromToolchain returns "".
+func FromToolchain(name string) string {
+	if !strings.HasPrefix(name, "go") || !IsValid(name[2:]) {
+		return ""
+	}
+	return name[2:]
+}
+
+// parse parses the Go version string x into a version.
+// It returns the zero version if x is malformed.
+func parse(x string) version {
+	var v version
+
+	// Parse major version.
+	var ok bool
+	v.major, x, ok = cutInt(x)
+	if !ok {
+		return version{}
+	}
+	if x == "" {
+		// Interpret "1" as "1.0".
+		v.minor = "0"
+		v.patch = "0"
+		return v
+	}
+
+	// Parse . before minor version.
+	if x[0] != '.' {
+		return version{}
+	}
+
+	// Parse minor version.
+	v.minor, x, ok = cutInt(x[1:])
+	if !ok {
+		return version{}
+	}
+	if x == "" {
+		v.patch = "0"
+		return v
+	}
+
+	// Parse patch if present.
+	if x[0] == '.' {
+		v.patch, x, ok = cutInt(x[1:])
+		if !ok || x != "" {
+			// Note that we are disallowing prereleases (alpha, beta, rc) for patch releases here (x != "").
+			// Allowing them would be a bit confusing because we already have:
+			//	1.18beta1
+			//	1.18rc1
+			//	1.18
+			//	1.18.1
+			// so when would 1.18.1beta1 happen?
+			return version{}
+		}
+		return v
+	}
+
+	// Parse prerelease.
+	v.patch = "0"
+	i := 0
+	for i < len(x) && (x[i] < '0' || '9' < x[i]) {
+		i++
+	}
+	if i == 0 {
+		return version{}
+	}
+	v.kind, x = x[:i], x[i:]
+	if v.kind != "beta" && v.kind != "rc" {
+		return version{}
+	}
+	v.pre, x, ok = cutInt(x)
+	if !ok || x != "" {
+		return version{}
+	}
+	return v
+}
+
+// cutInt scans the leading decimal number at the start of x to an integer
+// and returns that value and the rest of the string.
+func cutInt(x string) (n, rest string, ok bool) {
+	i := 0
+	for i < len(x) && '0' <= x[i] && x[i] <= '9' {
+		i++
+	}
+	if i == 0 || x[0] == '0' && i != 1 {
+		return "", "", false
+	}
+	return x[:i], x[i:], true
+}
+
+// cmpInt returns -1, 0, or +1 depending on whether x < y, x == y, or x > y,
+// interpreting x and y as decimal numbers.
+// (Copied from golang.org/x/mod/semver's compareInt.)
+func cmpInt(x, y string) int {
+	if x == y {
+		return 0
+	}
+	if len(x) < len(y) {
+		return -1
+	}
+	if len(x) > len(y) {
+		return +1
+	}
+	if x < y {
+		return -1
+	} else {
+		return +1
+	}
+}
+
+// cmpKind compares the pre-release kinds x and y.
+// A release ("") sorts after its pre-releases.
+func cmpKind(x, y string) int {
+	rank := func(kind string) int {
+		switch kind {
+		case "beta":
+			return 1
+		case "rc":
+			return 2
+		}
+		return 3
+	}
+	rx, ry := rank(x), rank(y)
+	if rx < ry {
+		return -1
+	}
+	if rx > ry {
+		return +1
+	}
+	return 0
+}
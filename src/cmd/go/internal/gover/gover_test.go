@@ -0,0 +1,86 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gover
+
+import "testing"
+
+var compareTests = []struct {
+	x, y string
+	out  int
+}{
+	{"", "", 0},
+	{"x", "x", 0},
+	{"", "x", 0},
+	{"1", "1.1", -1},
+	{"1.5", "1.6", -1},
+	{"1.5", "1.10", -1},
+	{"1.6", "1.6.1", -1},
+	{"1.18", "1.18.0", 0},
+	{"1.18beta1", "1.18rc1", -1},
+	{"1.18rc1", "1.18", -1},
+	{"1.18rc2", "1.18rc10", -1},
+	{"1.18", "1.19beta1", -1},
+	{"1.18.9", "1.19rc1", -1},
+	{"1.99999999999999998", "1.99999999999999999", -1},
+	{"", "1.18", -1},
+	{"1.18x", "1.18", -1},
+}
+
+func TestCompare(t *testing.T) {
+	for _, tt := range compareTests {
+		if out := Compare(tt.x, tt.y); out != tt.out {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.x, tt.y, out, tt.out)
+		}
+		if out := Compare(tt.y, tt.x); out != -tt.out {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.y, tt.x, out, -tt.out)
+		}
+	}
+}
+
+var isValidTests = []struct {
+	in  string
+	out bool
+}{
+	{"1", true},
+	{"1.18", true},
+	{"1.18.1", true},
+	{"1.19rc1", true},
+	{"1.19beta2", true},
+	{"1.19alpha1", false},
+	{"1.19rc", false},
+	{"1.19.1rc1", false},
+	{"1.018", false},
+	{"go1.18", false},
+	{"", false},
+}
+
+func TestIsValid(t *testing.T) {
+	for _, tt := range isValidTests {
+		if out := IsValid(tt.in); out != tt.out {
+			t.Errorf("IsValid(%q) = %v, want %v", tt.in, out, tt.out)
+		}
+	}
+}
+
+func TestFromToolchain(t *testing.T) {
+	for _, tt := range []struct{ in, out string }{
+		{"go1.18", "1.18"},
+		{"go1.18.2", "1.18.2"},
+		{"go1.19rc1", "1.19rc1"},
+		{"1.18", ""},
+		{"default", ""},
+		{"gox", ""},
+	} {
+		if out := FromToolchain(tt.in); out != tt.out {
+			t.Errorf("FromToolchain(%q) = %q, want %q", tt.in, out, tt.out)
+		}
+	}
+}
+
+func TestLocal(t *testing.T) {
+	if v := Local(); !IsValid(v) {
+		t.Errorf("Local() = %q, not a valid version", v)
+	}
+}
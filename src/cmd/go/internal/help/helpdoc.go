@@ -49,7 +49,7 @@ environment variable (For more details see: 'go help gopath').
 If no import paths are given, the action applies to the
 package in the current directory.
 
-There are four reserved names for paths that should not be used
+There are five reserved names for paths that should not be used
 for packages to be built with the go tool:
 
 - "main" denotes the top-level package in a stand-alone executable.
@@ -66,6 +66,10 @@ Go library.
 - "cmd" expands to the Go repository's commands and their
 internal libraries.
 
+- "tool" expands to the tools of the main module, which are listed
+by the tool lines of its go.mod file (see 'go help get'). When using
+modules, "all" includes the tools as well.
+
 Import paths beginning with "cmd/" only match source code in
 the Go repository.
 
@@ -498,6 +502,9 @@ General-purpose environment variables:
 	GOCACHE
 		The directory where the go command will store cached
 		information for reuse in future builds.
+	GOCOVERDIR
+		The directory into which programs built with 'go build -cover'
+		write their coverage data when they exit.
 	GOMODCACHE
 		The directory where the go command will store downloaded modules.
 	GODEBUG
@@ -540,6 +547,16 @@ General-purpose environment variables:
 	GOTMPDIR
 		The directory where the go command will write
 		temporary source files, packages, and binaries.
+	GOTOOLCHAIN
+		Controls which Go toolchain runs the go command. The default, local,
+		always runs the local toolchain. With auto, the go command runs the
+		local toolchain unless the main module's go.mod file asks for a
+		newer one with its toolchain or go line, in which case that
+		toolchain is downloaded as the module golang.org/toolchain,
+		verified using the checksum database, and run instead.
+		Other settings are goX (always run goX), path (like auto, but only
+		run toolchains found in PATH), and goX+auto or goX+path (like auto
+		or path, with goX as the minimum).
 	GOVCS
 		Lists version control commands that may be used with matching servers.
 		See 'go help vcs'.
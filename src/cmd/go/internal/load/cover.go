@@ -0,0 +1,156 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package load
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+)
+
+// PrepareForCoverageBuild marks the packages that 'go build -cover'
+// should instrument for coverage, among pkgs and their dependencies.
+// By default these are the packages of the main modules (or, in GOPATH
+// mode, the packages named on the command line); -coverpkg selects
+// them by pattern instead. The main packages are always instrumented,
+// so that they import runtime/coverage, which writes out the counters
+// when the program exits.
+func PrepareForCoverageBuild(pkgs []*Package) {
+	var match []func(*Package) bool
+	for _, pattern := range cfg.BuildCoverPkg {
+		match = append(match, MatchPackage(pattern, base.Cwd()))
+	}
+	matched := make([]bool, len(match))
+
+	selected := func(p *Package) bool {
+		cmdline := p.Internal.CmdlinePkg || p.Internal.CmdlineFiles
+		if p.Name == "main" && cmdline {
+			return true
+		}
+		if match == nil {
+			if p.Module != nil {
+				return p.Module.Main
+			}
+			return cmdline
+		}
+		found := false
+		for i := range match {
+			if match[i](p) {
+				matched[i] = true
+				found = true
+			}
+		}
+		return found
+	}
+
+	for _, p := range PackageList(pkgs) {
+		if !selected(p) {
+			continue
+		}
+		// There is nothing to cover in package unsafe; it comes from the compiler.
+		if p.ImportPath == "unsafe" {
+			continue
+		}
+		// A package without Go files has nothing to instrument.
+		if len(p.GoFiles)+len(p.CgoFiles) == 0 {
+			continue
+		}
+		// Atomic coverage mode uses sync/atomic, so we can't
+		// also do coverage on it. Nor can we instrument the
+		// package that collects the counters.
+		if p.Standard && (cfg.BuildCoverMode == "atomic" && p.ImportPath == "sync/atomic" || p.ImportPath == "runtime/coverage") {
+			continue
+		}
+		// Instrumenting the runtime would invoke the race
+		// detector before it has been initialized.
+		if cfg.BuildRace && p.Standard && (p.ImportPath == "runtime" || strings.HasPrefix(p.ImportPath, "runtime/internal")) {
+			continue
+		}
+
+		p.Internal.CoverMode = cfg.BuildCoverMode
+		var coverFiles []string
+		coverFiles = append(coverFiles, p.GoFiles...)
+		coverFiles = append(coverFiles, p.CgoFiles...)
+		p.Internal.CoverVars = DeclareCoverVars(p, coverFiles...)
+		if cfg.BuildCoverMode == "atomic" {
+			EnsureImport(p, "sync/atomic")
+		}
+		if p.Name == "main" {
+			EnsureImport(p, "runtime/coverage")
+		}
+	}
+
+	for i, pattern := range cfg.BuildCoverPkg {
+		if !matched[i] {
+			fmt.Fprintf(os.Stderr, "warning: no packages being built depend on matches for pattern %s\n", pattern)
+		}
+	}
+}
+
+// EnsureImport ensures that package p imports the named package.
+func EnsureImport(p *Package, pkg string) {
+	for _, d := range p.Internal.Imports {
+		if d.Name == pkg {
+			return
+		}
+	}
+
+	p1 := LoadImportWithFlags(pkg, p.Dir, p, &ImportStack{}, nil, 0)
+	if p1.Error != nil {
+		base.Fatalf("load %s: %v", pkg, p1.Error)
+	}
+
+	p.Internal.Imports = append(p.Internal.Imports, p1)
+}
+
+// DeclareCoverVars attaches the required cover variables names
+// to the files, to be used when annotating the files.
+func DeclareCoverVars(p *Package, files ...string) map[string]*CoverVar {
+	coverVars := make(map[string]*CoverVar)
+	coverIndex := 0
+	// We create the cover counters as new top-level variables in the package.
+	// We need to avoid collisions with user variables (GoCover_0 is unlikely but still)
+	// and more importantly with dot imports of other covered packages,
+	// so we append 12 hex digits from the SHA-256 of the import path.
+	// The point is only to avoid accidents, not to defeat users determined to
+	// break things.
+	sum := sha256.Sum256([]byte(p.ImportPath))
+	h := fmt.Sprintf("%x", sum[:6])
+	for _, file := range files {
+		if isTestFile(file) {
+			continue
+		}
+		// For a package that is "local" (imported via ./ import or command line, outside GOPATH),
+		// we record the full path to the file name.
+		// Otherwise we record the import path, then a forward slash, then the file name.
+		// This makes profiles within GOPATH file system-independent.
+		// These names appear in the cmd/cover HTML interface.
+		var longFile string
+		if p.Internal.Local {
+			longFile = filepath.Join(p.Dir, file)
+		} else {
+			longFile = path.Join(p.ImportPath, file)
+		}
+		coverVars[file] = &CoverVar{
+			File: longFile,
+			Var:  fmt.Sprintf("GoCover_%d_%x", coverIndex, h),
+		}
+		coverIndex++
+	}
+	return coverVars
+}
+
+// isTestFile reports whether the source file is a set of tests and should therefore
+// be excluded from coverage analysis.
+func isTestFile(file string) bool {
+	// We don't cover tests, only the code they test.
+	return strings.HasSuffix(file, "_test.go")
+}
@@ -215,6 +215,7 @@ type PackageInternal struct {
 	TestmainGo        *[]byte              // content for _testmain.go
 	Embed             map[string][]string  // //go:embed comment mapping
 	OrigImportPath    string               // original import path before adding '_test' suffix
+	PGOProfile        string               // path to PGO profile
 
 	Asmflags   []string // -asmflags for this package
 	Gcflags    []string // -gcflags for this package
@@ -1640,12 +1641,18 @@ var foldPath = make(map[string]string)
 // a vN path element specifying the major version, then the
 // second last element of the import path is used instead.
 func (p *Package) exeFromImportPath() string {
-	_, elem := pathpkg.Split(p.ImportPath)
+	return DefaultExecName(p.ImportPath)
+}
+
+// DefaultExecName returns the default executable name for the main
+// package with the given import path.
+func DefaultExecName(importPath string) string {
+	_, elem := pathpkg.Split(importPath)
 	if cfg.ModulesEnabled {
 		// If this is example.com/mycmd/v2, it's more useful to
 		// install it as mycmd than as v2. See golang.org/issue/24667.
-		if elem != p.ImportPath && isVersionElement(elem) {
-			_, elem = pathpkg.Split(pathpkg.Dir(p.ImportPath))
+		if elem != importPath && isVersionElement(elem) {
+			_, elem = pathpkg.Split(pathpkg.Dir(importPath))
 		}
 	}
 	return elem
@@ -2670,7 +2677,9 @@ func PackagesAndErrors(ctx context.Context, opts PackageOpts, patterns []string)
 			// We need to test whether the path is an actual Go file and not a
 			// package path or pattern ending in '.go' (see golang.org/issue/34653).
 			if fi, err := fsys.Stat(p); err == nil && !fi.IsDir() {
-				return []*Package{GoFilesPackage(ctx, opts, patterns)}
+				pkgs := []*Package{GoFilesPackage(ctx, opts, patterns)}
+				setPGOProfilePath(pkgs)
+				return pkgs
 			}
 		}
 	}
@@ -2749,9 +2758,56 @@ func PackagesAndErrors(ctx context.Context, opts PackageOpts, patterns []string)
 	// their dependencies).
 	setToolFlags(pkgs...)
 
+	setPGOProfilePath(pkgs)
+
 	return pkgs
 }
 
+// setPGOProfilePath sets the PGO profile path for pkgs and all their
+// dependencies, according to the -pgo flag.
+// In -pgo=auto mode, it looks for a default.pgo file in the directory of
+// the main package, if pkgs contain exactly one main package.
+func setPGOProfilePath(pkgs []*Package) {
+	var file string
+	switch cfg.BuildPGO {
+	case "off":
+		return
+
+	case "auto":
+		var mainpkg *Package
+		for _, p := range pkgs {
+			if p.Name == "main" {
+				if mainpkg != nil {
+					// Which main package's profile would apply
+					// to the shared dependencies is ambiguous.
+					return
+				}
+				mainpkg = p
+			}
+		}
+		if mainpkg == nil || mainpkg.Dir == "" {
+			return
+		}
+		file = filepath.Join(mainpkg.Dir, "default.pgo")
+		if fi, err := fsys.Stat(file); err != nil || fi.IsDir() {
+			return
+		}
+
+	default:
+		// Make the path absolute, as the compiler runs in
+		// the package directory.
+		var err error
+		file, err = filepath.Abs(cfg.BuildPGO)
+		if err != nil {
+			base.Fatalf("go: invalid -pgo file %s: %v", cfg.BuildPGO, err)
+		}
+	}
+
+	for _, p := range PackageList(pkgs) {
+		p.Internal.PGOProfile = file
+	}
+}
+
 // CheckPackageErrors prints errors encountered loading pkgs and their
 // dependencies, then exits with a non-zero status if any errors were found.
 func CheckPackageErrors(pkgs []*Package) {
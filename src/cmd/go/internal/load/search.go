@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"cmd/go/internal/modload"
 	"cmd/go/internal/search"
 )
 
@@ -49,6 +50,8 @@ func MatchPackage(pattern, cwd string) func(*Package) bool {
 		return func(p *Package) bool { return p.Standard }
 	case pattern == "cmd":
 		return func(p *Package) bool { return p.Standard && strings.HasPrefix(p.ImportPath, "cmd/") }
+	case pattern == "tool":
+		return func(p *Package) bool { return modload.MainModules.Tools()[p.ImportPath] }
 	default:
 		matchPath := search.MatchPattern(pattern)
 		return func(p *Package) bool { return matchPath(p.ImportPath) }
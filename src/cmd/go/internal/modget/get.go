@@ -36,11 +36,13 @@ import (
 	"sync"
 
 	"cmd/go/internal/base"
+	"cmd/go/internal/gover"
 	"cmd/go/internal/imports"
 	"cmd/go/internal/modfetch"
 	"cmd/go/internal/modload"
 	"cmd/go/internal/par"
 	"cmd/go/internal/search"
+	"cmd/go/internal/toolchain"
 	"cmd/go/internal/work"
 
 	"golang.org/x/mod/modfile"
@@ -51,7 +53,7 @@ import (
 var CmdGet = &base.Command{
 	// Note: flags below are listed explicitly because they're the most common.
 	// Do not send CLs removing them because they're covered by [get flags].
-	UsageLine: "go get [-t] [-u] [-v] [build flags] [packages]",
+	UsageLine: "go get [-t] [-u] [-v] [-tool] [build flags] [packages]",
 	Short:     "add dependencies to current module and install them",
 	Long: `
 Get resolves its command-line arguments to packages at specific module versions,
@@ -70,6 +72,28 @@ To remove a dependency on a module and downgrade modules that require it:
 
 	go get example.com/mod@none
 
+To change the minimum Go version required by the current module,
+or the Go toolchain that runs go commands in it:
+
+	go get go@1.18
+	go get toolchain@go1.18.2
+	go get toolchain@none
+
+If the requested go version is newer than the running toolchain,
+the go command switches to a toolchain that supports it when
+GOTOOLCHAIN allows, and fails otherwise (see GOTOOLCHAIN in
+'go help environment').
+
+To add a tool, such as a code generator, as a dependency of the current
+module and record it in a tool line of go.mod, so that 'go tool' can run it:
+
+	go get -tool example.com/cmd/stringer
+
+To upgrade all the tools of the current module, or to stop tracking a tool:
+
+	go get tool
+	go get -tool example.com/cmd/stringer@none
+
 See https://golang.org/ref/mod#go-get for details.
 
 In earlier versions of Go, 'go get' was used to build and install packages.
@@ -98,6 +122,11 @@ but changes the default to select patch releases.
 When the -t and -u flags are used together, get will update
 test dependencies as well.
 
+The -tool flag instructs get to add a tool line to go.mod for each
+package named on the command line, or to remove the tool line for
+each package named with the version suffix @none. The requirements
+of the removed tools are dropped by the next 'go mod tidy'.
+
 The -x flag prints commands as they are executed. This is useful for
 debugging version control commands when a module is downloaded directly
 from a repository.
@@ -217,6 +246,7 @@ var (
 	getFix      = CmdGet.Flag.Bool("fix", false, "")
 	getM        = CmdGet.Flag.Bool("m", false, "")
 	getT        = CmdGet.Flag.Bool("t", false, "")
+	getTool     = CmdGet.Flag.Bool("tool", false, "")
 	getU        upgradeFlag
 	getInsecure = CmdGet.Flag.Bool("insecure", false, "")
 	// -v is cfg.BuildV
@@ -276,6 +306,11 @@ func runGet(ctx context.Context, cmd *base.Command, args []string) {
 		base.Fatalf("go: -insecure flag is no longer supported; use GOINSECURE instead")
 	}
 
+	args, goVersion, toolchainName := splitToolchainArgs(args)
+	if goVersion != "" && gover.Compare(goVersion, gover.Local()) > 0 {
+		toolchain.Switch("go" + goVersion)
+	}
+
 	modload.ForceUseModules = true
 
 	// Do not allow any updating of go.mod until we've applied
@@ -301,6 +336,18 @@ func runGet(ctx context.Context, cmd *base.Command, args []string) {
 			"\tor run 'go help get' or 'go help install'.")
 	}
 
+	if len(args) == 0 && (goVersion != "" || toolchainName != "") {
+		// Only the go and toolchain lines change; there is nothing to resolve.
+		modload.LoadModFile(ctx)
+		updateToolchainLines(goVersion, toolchainName)
+		if err := modload.WriteGoMod(ctx); err != nil {
+			base.Fatalf("go: %v", err)
+		}
+		return
+	}
+
+	// Load go.mod so that parseArgs can expand the "tool" pattern.
+	modload.LoadModFile(ctx)
 	queries := parseArgs(ctx, args)
 
 	r := newResolver(ctx, queries)
@@ -369,8 +416,12 @@ func runGet(ctx context.Context, cmd *base.Command, args []string) {
 		}
 	}
 	r.checkPackageProblems(ctx, pkgPatterns)
+	if *getTool {
+		updateTools(ctx, queries)
+	}
 
 	// Everything succeeded. Update go.mod.
+	updateToolchainLines(goVersion, toolchainName)
 	oldReqs := reqsFromGoMod(modload.ModFile())
 
 	if err := modload.WriteGoMod(ctx); err != nil {
@@ -381,6 +432,130 @@ func runGet(ctx context.Context, cmd *base.Command, args []string) {
 	r.reportChanges(oldReqs, newReqs)
 }
 
+// updateTools adds the tool lines of the main module's go.mod file for
+// the packages matched by queries, or removes them for @none queries,
+// as requested by 'go get -tool'.
+func updateTools(ctx context.Context, queries []*query) {
+	defer base.ExitIfErrors()
+
+	tools := modload.MainModules.Tools()
+	var patterns []string
+	for _, q := range queries {
+		if q.version == "none" {
+			for tool := range tools {
+				if q.matchesPath(tool) {
+					modload.DropTool(tool)
+					delete(tools, tool)
+					fmt.Fprintf(os.Stderr, "go: removed tool %s\n", tool)
+				}
+			}
+			continue
+		}
+		if !q.matchesPackages {
+			base.Errorf("go: %s: -tool requires a package, but no package matched", q.raw)
+			continue
+		}
+		patterns = append(patterns, q.pattern)
+	}
+	if len(patterns) == 0 {
+		return
+	}
+
+	pkgOpts := modload.PackageOpts{
+		VendorModulesInGOROOTSrc: true,
+		ResolveMissingImports:    false,
+		AllowErrors:              true,
+		SilenceNoGoErrors:        true,
+		SilenceUnmatchedWarnings: true,
+	}
+	matches, _ := modload.LoadPackages(ctx, pkgOpts, patterns...)
+	var added []string
+	for _, m := range matches {
+		for _, pkg := range m.Pkgs {
+			if !tools[pkg] {
+				modload.AddTool(pkg)
+				tools[pkg] = true
+				added = append(added, pkg)
+				fmt.Fprintf(os.Stderr, "go: added tool %s\n", pkg)
+			}
+		}
+	}
+	if len(added) > 0 {
+		// Load the new tools again, so that the modules providing them
+		// become direct requirements of the main module.
+		modload.LoadPackages(ctx, pkgOpts, added...)
+	}
+}
+
+// splitToolchainArgs removes the go@version and toolchain@name
+// arguments from args, which name lines of go.mod rather than modules,
+// and returns the remaining arguments and the requested lines.
+func splitToolchainArgs(args []string) (rest []string, goVersion, toolchainName string) {
+	defer base.ExitIfErrors()
+
+	for _, arg := range args {
+		path, vers, found := strings.Cut(arg, "@")
+		switch {
+		case found && path == "go":
+			if !modfile.GoVersionRE.MatchString(vers) {
+				base.Errorf("go: %s: invalid go version %q: must match format 1.18", arg, vers)
+				continue
+			}
+			goVersion = vers
+		case found && path == "toolchain":
+			if vers != "none" && !modfile.ToolchainRE.MatchString(vers) {
+				base.Errorf("go: %s: invalid toolchain name %q: must be none, default or match format go1.18", arg, vers)
+				continue
+			}
+			toolchainName = vers
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, goVersion, toolchainName
+}
+
+// updateToolchainLines sets the go and toolchain lines of the main
+// module's go.mod file as requested by go@version and toolchain@name
+// arguments, and reports the changes.
+func updateToolchainLines(goVersion, toolchainName string) {
+	modFile := modload.ModFile()
+	if goVersion != "" {
+		old := ""
+		if modFile.Go != nil {
+			old = modFile.Go.Version
+		}
+		if old != goVersion {
+			modload.SetGoVersion(goVersion)
+			switch {
+			case old == "":
+				fmt.Fprintf(os.Stderr, "go: added go %s\n", goVersion)
+			case gover.Compare(goVersion, old) > 0:
+				fmt.Fprintf(os.Stderr, "go: upgraded go %s => %s\n", old, goVersion)
+			default:
+				fmt.Fprintf(os.Stderr, "go: downgraded go %s => %s\n", old, goVersion)
+			}
+		}
+	}
+	if toolchainName != "" {
+		old := ""
+		if modFile.Toolchain != nil {
+			old = modFile.Toolchain.Name
+		}
+		if old != toolchainName && (old != "" || toolchainName != "none") {
+			modload.SetToolchain(toolchainName)
+			switch {
+			case old == "":
+				fmt.Fprintf(os.Stderr, "go: added toolchain %s\n", toolchainName)
+			case toolchainName == "none":
+				fmt.Fprintf(os.Stderr, "go: removed toolchain %s\n", old)
+			default:
+				fmt.Fprintf(os.Stderr, "go: changed toolchain %s => %s\n", old, toolchainName)
+			}
+		}
+	}
+}
+
 // parseArgs parses command-line arguments and reports errors.
 //
 // The command-line arguments are of the form path@version or simply path, with
@@ -389,12 +564,16 @@ func parseArgs(ctx context.Context, rawArgs []string) []*query {
 	defer base.ExitIfErrors()
 
 	var queries []*query
-	for _, arg := range search.CleanPatterns(rawArgs) {
+	for _, arg := range expandToolPattern(search.CleanPatterns(rawArgs)) {
 		q, err := newQuery(arg)
 		if err != nil {
 			base.Errorf("go: %v", err)
 			continue
 		}
+		if *getTool && search.IsMetaPackage(q.pattern) {
+			base.Errorf("go: %s: -tool requires package arguments, not the %q pattern", q.raw, q.pattern)
+			continue
+		}
 
 		// If there were no arguments, CleanPatterns returns ".". Set the raw
 		// string back to "" for better errors.
@@ -422,6 +601,39 @@ func parseArgs(ctx context.Context, rawArgs []string) []*query {
 	return queries
 }
 
+// expandToolPattern replaces the "tool" pattern in args, keeping any
+// version suffix, with the import paths of the main module's tools.
+// Tool arguments are not expanded with -tool, so that parseArgs can
+// reject them.
+func expandToolPattern(args []string) []string {
+	if *getTool {
+		return args
+	}
+	var out []string
+	for _, arg := range args {
+		pattern, vers, _ := strings.Cut(arg, "@")
+		if pattern != "tool" {
+			out = append(out, arg)
+			continue
+		}
+		var tools []string
+		for tool := range modload.MainModules.Tools() {
+			tools = append(tools, tool)
+		}
+		if len(tools) == 0 {
+			fmt.Fprintf(os.Stderr, "go: warning: %q matched no packages\n", arg)
+		}
+		sort.Strings(tools)
+		for _, tool := range tools {
+			if vers != "" {
+				tool += "@" + vers
+			}
+			out = append(out, tool)
+		}
+	}
+	return out
+}
+
 type resolver struct {
 	localQueries      []*query // queries for absolute or relative paths
 	pathQueries       []*query // package path literal queries in original order
@@ -57,6 +57,18 @@ To add, upgrade, downgrade, or remove a specific module requirement, use
 'go get'. For details, see 'go help module-get' or
 https://golang.org/ref/mod#go-get.
 
+The go line states the minimum Go version required by the module, and
+the optional toolchain line names the Go toolchain, like go1.18.2, that
+go commands should use in the module. If either asks for a toolchain
+newer than the one running, the go command switches to it when
+GOTOOLCHAIN allows; see 'go help environment'. To change these lines,
+use 'go get go@version' and 'go get toolchain@name'.
+
+Each tool line names a package, such as a code generator, that the
+module runs with 'go tool' (see 'go help tool'). The modules providing
+tools are required like those providing imported packages. To add or
+remove tool lines, use 'go get -tool'.
+
 To make other changes or to parse go.mod as JSON for use by other tools,
 use 'go mod edit'. See 'go help mod edit' or
 https://golang.org/ref/mod#go-mod-edit.
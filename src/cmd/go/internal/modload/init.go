@@ -218,6 +218,23 @@ func (mms *MainModuleSet) HighestReplaced() map[string]string {
 	return mms.highestReplaced
 }
 
+// Tools returns the import paths of the tools named by the tool lines
+// of the main modules' go.mod files.
+func (mms *MainModuleSet) Tools() map[string]bool {
+	tools := make(map[string]bool)
+	if mms == nil {
+		return tools
+	}
+	for _, m := range mms.versions {
+		if modFile := mms.modFiles[m]; modFile != nil {
+			for _, t := range modFile.Tool {
+				tools[t.Path] = true
+			}
+		}
+	}
+	return tools
+}
+
 // GoVersion returns the go version set on the single module, in module mode,
 // or the go.work file in workspace mode.
 func (mms *MainModuleSet) GoVersion() string {
@@ -1198,6 +1215,51 @@ func addGoStmt(modFile *modfile.File, mod module.Version, v string) {
 	rawGoVersion.Store(mod, v)
 }
 
+// SetGoVersion sets the go line of the main module's go.mod file to v.
+// The change is written by the next call to WriteGoMod.
+func SetGoVersion(v string) {
+	mainModule := MainModules.mustGetSingleMainModule()
+	modFile := MainModules.ModFile(mainModule)
+	if err := modFile.AddGoStmt(v); err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	rawGoVersion.Store(mainModule, v)
+}
+
+// SetToolchain sets the toolchain line of the main module's go.mod file
+// to name, or removes it if name is "none".
+// The change is written by the next call to WriteGoMod.
+func SetToolchain(name string) {
+	modFile := MainModules.ModFile(MainModules.mustGetSingleMainModule())
+	if name == "none" {
+		modFile.DropToolchainStmt()
+		return
+	}
+	if err := modFile.AddToolchainStmt(name); err != nil {
+		base.Fatalf("go: %v", err)
+	}
+}
+
+// AddTool adds a tool line for the package with import path path to the
+// main module's go.mod file.
+// The change is written by the next call to WriteGoMod.
+func AddTool(path string) {
+	modFile := MainModules.ModFile(MainModules.mustGetSingleMainModule())
+	if err := modFile.AddTool(path); err != nil {
+		base.Fatalf("go: %v", err)
+	}
+}
+
+// DropTool removes the tool line for the package with import path path
+// from the main module's go.mod file.
+// The change is written by the next call to WriteGoMod.
+func DropTool(path string) {
+	modFile := MainModules.ModFile(MainModules.mustGetSingleMainModule())
+	if err := modFile.DropTool(path); err != nil {
+		base.Fatalf("go: %v", err)
+	}
+}
+
 // LatestGoVersion returns the latest version of the Go language supported by
 // this toolchain, like "1.17".
 func LatestGoVersion() string {
@@ -1266,6 +1328,17 @@ func findModuleRoot(dir string) (roots string) {
 	return ""
 }
 
+// FindGoMod returns the path of the go.mod file of the module
+// enclosing dir, or "" if dir is not inside a module.
+// It may be called before Init.
+func FindGoMod(dir string) string {
+	root := findModuleRoot(dir)
+	if root == "" {
+		return ""
+	}
+	return filepath.Join(root, "go.mod")
+}
+
 func findWorkspaceFile(dir string) (root string) {
 	if dir == "" {
 		panic("dir not set")
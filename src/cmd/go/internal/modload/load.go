@@ -313,7 +313,7 @@ func LoadPackages(ctx context.Context, opts PackageOpts, patterns ...string) (ma
 
 			case m.Pattern() == "all":
 				if ld == nil {
-					// The initial roots are the packages in the main module.
+					// The initial roots are the packages and tools in the main module.
 					// loadFromRoots will expand that to "all".
 					m.Errs = m.Errs[:0]
 					matchModules := MainModules.Versions()
@@ -321,6 +321,9 @@ func LoadPackages(ctx context.Context, opts PackageOpts, patterns ...string) (ma
 						matchModules = []module.Version{opts.MainModule}
 					}
 					matchPackages(ctx, m, opts.Tags, omitStd, matchModules)
+					for tool := range MainModules.Tools() {
+						m.Pkgs = append(m.Pkgs, tool)
+					}
 				} else {
 					// Starting with the packages in the main module,
 					// enumerate the full list of "all".
@@ -332,6 +335,13 @@ func LoadPackages(ctx context.Context, opts PackageOpts, patterns ...string) (ma
 					m.MatchPackages() // Locate the packages within GOROOT/src.
 				}
 
+			case m.Pattern() == "tool":
+				m.Pkgs = m.Pkgs[:0]
+				for tool := range MainModules.Tools() {
+					m.Pkgs = append(m.Pkgs, tool)
+				}
+				sort.Strings(m.Pkgs)
+
 			default:
 				panic(fmt.Sprintf("internal error: modload missing case for pattern %s", m.Pattern()))
 			}
@@ -826,6 +836,10 @@ type loader struct {
 	// transitively *imported by* the packages and tests in the main module.)
 	allClosesOverTests bool
 
+	// tools is the set of import paths named by the main modules' tool lines.
+	// Like the packages in the main modules, tools are in "all".
+	tools map[string]bool
+
 	work *par.Queue
 
 	// reset on each iteration
@@ -985,6 +999,7 @@ var errMissing = errors.New("cannot find package")
 func loadFromRoots(ctx context.Context, params loaderParams) *loader {
 	ld := &loader{
 		loaderParams: params,
+		tools:        MainModules.Tools(),
 		work:         par.NewQueue(runtime.GOMAXPROCS(0)),
 	}
 
@@ -1253,6 +1268,10 @@ func (ld *loader) updateRequirements(ctx context.Context) (changed bool, err err
 	}
 
 	for _, pkg := range ld.pkgs {
+		if ld.tools[pkg.path] && pkg.fromExternalModule() {
+			// The main module runs its tools directly, as if it imported them.
+			direct[pkg.mod.Path] = true
+		}
 		if pkg.mod.Version != "" || !MainModules.Contains(pkg.mod.Path) {
 			continue
 		}
@@ -1706,7 +1725,7 @@ func (ld *loader) load(ctx context.Context, pkg *loadPkg) {
 	if pkg.dir == "" {
 		return
 	}
-	if MainModules.Contains(pkg.mod.Path) {
+	if MainModules.Contains(pkg.mod.Path) || ld.tools[pkg.path] {
 		// Go ahead and mark pkg as in "all". This provides the invariant that a
 		// package that is *only* imported by other packages in "all" is always
 		// marked as such before loading its imports.
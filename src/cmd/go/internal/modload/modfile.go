@@ -103,6 +103,8 @@ type modFileIndex struct {
 	dataNeedsFix bool // true if fixVersion applied a change while parsing data
 	module       module.Version
 	goVersionV   string // GoVersion with "v" prefix
+	toolchain    string
+	tool         map[string]bool
 	require      map[module.Version]requireMeta
 	replace      map[module.Version]module.Version
 	exclude      map[module.Version]bool
@@ -435,6 +437,16 @@ func indexModFile(data []byte, modFile *modfile.File, mod module.Version, needsF
 		rawGoVersion.Store(mod, modFile.Go.Version)
 	}
 
+	i.toolchain = ""
+	if modFile.Toolchain != nil {
+		i.toolchain = modFile.Toolchain.Name
+	}
+
+	i.tool = make(map[string]bool, len(modFile.Tool))
+	for _, t := range modFile.Tool {
+		i.tool[t.Path] = true
+	}
+
 	i.require = make(map[module.Version]requireMeta, len(modFile.Require))
 	for _, r := range modFile.Require {
 		i.require[r.Mod] = requireMeta{indirect: r.Indirect}
@@ -485,6 +497,23 @@ func (i *modFileIndex) modFileIsDirty(modFile *modfile.File) bool {
 		}
 	}
 
+	var toolchain string
+	if modFile.Toolchain != nil {
+		toolchain = modFile.Toolchain.Name
+	}
+	if toolchain != i.toolchain {
+		return true
+	}
+
+	if len(modFile.Tool) != len(i.tool) {
+		return true
+	}
+	for _, t := range modFile.Tool {
+		if !i.tool[t.Path] {
+			return true
+		}
+	}
+
 	if len(modFile.Require) != len(i.require) ||
 		len(modFile.Replace) != len(i.replace) ||
 		len(modFile.Exclude) != len(i.exclude) {
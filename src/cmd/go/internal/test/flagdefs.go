@@ -22,6 +22,7 @@ var passFlagToTest = map[string]bool{
 	"fuzz":                 true,
 	"fuzzminimizetime":     true,
 	"fuzztime":             true,
+	"goroutineleak":        true,
 	"list":                 true,
 	"memprofile":           true,
 	"memprofilerate":       true,
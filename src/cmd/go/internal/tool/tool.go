@@ -11,11 +11,16 @@ import (
 	exec "internal/execabs"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sort"
 	"strings"
 
 	"cmd/go/internal/base"
+	"cmd/go/internal/cache"
 	"cmd/go/internal/cfg"
+	"cmd/go/internal/load"
+	"cmd/go/internal/modload"
+	"cmd/go/internal/work"
 )
 
 var CmdTool = &base.Command{
@@ -26,6 +31,13 @@ var CmdTool = &base.Command{
 Tool runs the go tool command identified by the arguments.
 With no arguments it prints the list of known tools.
 
+The command may also name a tool listed by a tool line in the
+main module's go.mod file (see 'go help get'), either by its full
+import path or by the last element of that path, like stringer for
+golang.org/x/tools/cmd/stringer. Such a tool is built at the version
+selected by the module graph, and the executable is kept in the build
+cache so that later runs do not rebuild it.
+
 The -n flag causes tool to print the command that would be
 executed but not execute it.
 
@@ -52,22 +64,23 @@ func init() {
 
 func runTool(ctx context.Context, cmd *base.Command, args []string) {
 	if len(args) == 0 {
-		listTools()
+		listTools(ctx)
 		return
 	}
 	toolName := args[0]
-	// The tool name must be lower-case letters, numbers or underscores.
-	for _, c := range toolName {
-		switch {
-		case 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '_':
-		default:
-			fmt.Fprintf(os.Stderr, "go: bad tool name %q\n", toolName)
-			base.SetExitStatus(2)
+	toolPath, err := base.ToolPath(toolName)
+	if !isToolName(toolName) || err != nil {
+		// Not a tool in the Go distribution; look for a tool of the main module.
+		if pkgPath := modTool(ctx, toolName); pkgPath != "" {
+			runModTool(ctx, toolName, pkgPath, args[1:])
 			return
 		}
-	}
-	toolPath := base.Tool(toolName)
-	if toolPath == "" {
+		if !isToolName(toolName) {
+			fmt.Fprintf(os.Stderr, "go: bad tool name %q\n", toolName)
+		} else {
+			fmt.Fprintf(os.Stderr, "go: no such tool %q\n", toolName)
+		}
+		base.SetExitStatus(2)
 		return
 	}
 	if toolN {
@@ -79,6 +92,26 @@ func runTool(ctx context.Context, cmd *base.Command, args []string) {
 		return
 	}
 	args[0] = toolPath // in case the tool wants to re-exec itself, e.g. cmd/dist
+	runToolCmd(toolName, args)
+}
+
+// isToolName reports whether name is a valid name for a tool in the
+// Go distribution: lower-case letters, numbers or underscores.
+func isToolName(name string) bool {
+	for _, c := range name {
+		switch {
+		case 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// runToolCmd runs the tool command line args, forwarding signals to it,
+// and sets the exit status if it fails.
+func runToolCmd(toolName string, args []string) {
+	toolPath := args[0]
 	toolCmd := &exec.Cmd{
 		Path:   toolPath,
 		Args:   args,
@@ -113,8 +146,9 @@ func runTool(ctx context.Context, cmd *base.Command, args []string) {
 	}
 }
 
-// listTools prints a list of the available tools in the tools directory.
-func listTools() {
+// listTools prints a list of the available tools in the tools directory,
+// followed by the tools of the main module.
+func listTools(ctx context.Context) {
 	f, err := os.Open(base.ToolDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "go: no tool directory: %s\n", err)
@@ -144,4 +178,93 @@ func listTools() {
 		}
 		fmt.Println(name)
 	}
+
+	for _, tool := range modTools(ctx) {
+		fmt.Println(tool)
+	}
+}
+
+// modTools returns the sorted import paths of the tools listed in the
+// main module's go.mod file, or nil if there is no main module.
+func modTools(ctx context.Context) []string {
+	modload.InitWorkfile()
+	modload.Init()
+	if !modload.HasModRoot() {
+		return nil
+	}
+	modload.LoadModFile(ctx)
+	var tools []string
+	for tool := range modload.MainModules.Tools() {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	return tools
+}
+
+// modTool returns the import path of the main module's tool named by
+// name, which is either the full import path or the default executable
+// name of the tool. It returns "" if there is no such tool.
+func modTool(ctx context.Context, name string) string {
+	for _, tool := range modTools(ctx) {
+		if tool == name || load.DefaultExecName(tool) == name {
+			return tool
+		}
+	}
+	return ""
+}
+
+// runModTool builds the main module's tool with import path pkgPath
+// and runs it with the arguments args.
+//
+// The executable is installed in a directory of the build cache that
+// depends on the tool, the version of the module providing it (or the
+// module's directory, for modules without a version), and the target
+// platform. If it is already there and up to date, it is run without
+// relinking, like an installed binary. Directories of tools that have
+// not been run recently are removed when the cache is trimmed.
+func runModTool(ctx context.Context, toolName, pkgPath string, args []string) {
+	work.BuildInit()
+	var b work.Builder
+	b.Init()
+
+	pkgs := load.PackagesAndErrors(ctx, load.PackageOpts{MainOnly: true}, []string{pkgPath})
+	load.CheckPackageErrors(pkgs)
+	p := pkgs[0]
+	p.Internal.OmitDebug = true
+	p.Internal.ExeName = load.DefaultExecName(p.ImportPath)
+	p.Target = ""
+	mode := work.ModeBuild
+	if cache.DefaultDir() != "off" {
+		h := cache.NewHash("tool")
+		fmt.Fprintf(h, "%s %s/%s\n", p.ImportPath, cfg.Goos, cfg.Goarch)
+		if m := p.Module; m != nil {
+			if m.Replace != nil {
+				m = m.Replace
+			}
+			if m.Version != "" {
+				fmt.Fprintf(h, "module %s@%s\n", m.Path, m.Version)
+			} else {
+				fmt.Fprintf(h, "module dir %s\n", m.Dir)
+			}
+		}
+		dir := cache.Default().ToolDir(cache.ActionID(h.Sum()))
+		p.Target = filepath.Join(dir, p.Internal.ExeName+cfg.ExeSuffix)
+		mode = work.ModeInstall
+	}
+
+	a1 := b.LinkAction(mode, work.ModeBuild, p)
+	a := &work.Action{
+		Mode: "go tool",
+		Func: func(b *work.Builder, ctx context.Context, a *work.Action) error {
+			toolPath := a.Deps[0].Target
+			if toolN {
+				fmt.Printf("%s\n", strings.Join(append([]string{toolPath}, args...), " "))
+				return nil
+			}
+			runToolCmd(toolName, append([]string{toolPath}, args...))
+			return nil
+		},
+		Deps: []*work.Action{a1},
+	}
+	b.Do(ctx, a)
 }
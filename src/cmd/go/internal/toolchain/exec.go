@@ -0,0 +1,22 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !js && !windows
+// +build !js,!windows
+
+package toolchain
+
+import (
+	"os"
+	"syscall"
+
+	"cmd/go/internal/base"
+)
+
+// execGoToolchain replaces the current process with the go command exe
+// of toolchain gotoolchain, installed in dir.
+func execGoToolchain(gotoolchain, dir, exe string) {
+	err := syscall.Exec(exe, os.Args, toolchainEnv(dir))
+	base.Fatalf("go: exec %s: %v", gotoolchain, err)
+}
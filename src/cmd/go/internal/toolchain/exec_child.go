@@ -0,0 +1,36 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build js || windows
+// +build js windows
+
+package toolchain
+
+import (
+	"errors"
+	exec "internal/execabs"
+	"os"
+
+	"cmd/go/internal/base"
+)
+
+// execGoToolchain runs the go command exe of toolchain gotoolchain,
+// installed in dir, as a child process and exits with its status.
+// These systems cannot replace the running process.
+func execGoToolchain(gotoolchain, dir, exe string) {
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = toolchainEnv(dir)
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		base.Fatalf("go: exec %s: %v", gotoolchain, err)
+	}
+	os.Exit(0)
+}
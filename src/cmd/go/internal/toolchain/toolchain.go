@@ -0,0 +1,223 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package toolchain implements the selection of the Go toolchain that
+// runs a go command, as directed by the GOTOOLCHAIN setting and by the
+// go and toolchain lines of the main module's go.mod file.
+package toolchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	exec "internal/execabs"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/gover"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/modload"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+const (
+	// gotoolchainModule is the module path of the downloadable Go
+	// toolchains. The module version for toolchain goX on GOOS/GOARCH
+	// is gotoolchainVersion-goX.GOOS-GOARCH.
+	gotoolchainModule  = "golang.org/toolchain"
+	gotoolchainVersion = "v0.0.1"
+)
+
+// Select invokes a different Go toolchain if directed by the GOTOOLCHAIN
+// setting or by the main module's go.mod file, in which case it does
+// not return. args are the command line arguments after the flags of
+// the go command itself.
+//
+// GOTOOLCHAIN may be:
+//
+//	local            run the local toolchain
+//	goX              run toolchain goX
+//	goX+auto, auto   run toolchain goX (or the local one), or a newer
+//	                 one required by go.mod, downloading it if needed
+//	goX+path, path   like +auto, but only run toolchains found in PATH
+func Select(args []string) {
+	if len(args) > 0 && args[0] == "env" {
+		// Allow 'go env -w GOTOOLCHAIN=local' and 'go env -u GOTOOLCHAIN'
+		// to repair a setting that makes the go command unusable.
+		for _, arg := range args[1:] {
+			if strings.HasPrefix(arg, "GOTOOLCHAIN") {
+				return
+			}
+		}
+	}
+
+	gotoolchain, mode, err := parse(cfg.GOTOOLCHAIN)
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	if mode == "local" {
+		return
+	}
+	if wd, err := os.Getwd(); err == nil && mode != "" {
+		if file := modload.FindGoMod(wd); file != "" && modload.WillBeEnabled() {
+			if need := goModToolchain(file); gover.Compare(gover.FromToolchain(need), gover.FromToolchain(gotoolchain)) > 0 {
+				gotoolchain = need
+			}
+		}
+	}
+	if gotoolchain == "go"+gover.Local() {
+		return
+	}
+	Exec(gotoolchain, mode == "path")
+}
+
+// parse parses the GOTOOLCHAIN setting into the minimum toolchain to
+// run and the switching mode: "local", "auto", "path", or "" if the
+// toolchain is fixed.
+func parse(gotoolchain string) (name, mode string, err error) {
+	switch gotoolchain {
+	case "local":
+		return "go" + gover.Local(), "local", nil
+	case "auto", "path":
+		return "go" + gover.Local(), gotoolchain, nil
+	}
+	name = gotoolchain
+	if i := strings.Index(name, "+"); i >= 0 {
+		name, mode = name[:i], name[i+1:]
+		if mode != "auto" && mode != "path" {
+			return "", "", fmt.Errorf("invalid GOTOOLCHAIN %q: suffix must be +auto or +path", gotoolchain)
+		}
+	}
+	if gover.FromToolchain(name) == "" {
+		return "", "", fmt.Errorf("invalid GOTOOLCHAIN %q", gotoolchain)
+	}
+	return name, mode, nil
+}
+
+// goModToolchain returns the toolchain that the go.mod file requires:
+// the one named by its toolchain line, or else the release matching
+// its go line. It returns "" if the file does not require one, or if
+// it cannot be parsed, leaving the error to be reported by the command.
+func goModToolchain(file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	f, err := modfile.Parse(file, data, nil)
+	if err != nil {
+		return ""
+	}
+	if f.Toolchain != nil && f.Toolchain.Name != "default" {
+		return f.Toolchain.Name
+	}
+	if f.Go != nil {
+		return "go" + f.Go.Version
+	}
+	return ""
+}
+
+// Switch runs the go command again with the toolchain goX required
+// by the current command, such as 'go get go@X', if GOTOOLCHAIN
+// allows switching to it. Switch does not return.
+func Switch(gotoolchain string) {
+	_, mode, err := parse(cfg.GOTOOLCHAIN)
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	if mode == "" || mode == "local" {
+		base.Fatalf("go: %s requires %s (running go %s; GOTOOLCHAIN=%s)", strings.Join(os.Args[1:], " "), gotoolchain, gover.Local(), cfg.GOTOOLCHAIN)
+	}
+	Exec(gotoolchain, mode == "path")
+}
+
+// Exec runs the go command of toolchain gotoolchain in place of the
+// current one, with the same arguments. If pathOnly is set, the
+// toolchain must be found in PATH as a command named gotoolchain;
+// otherwise it is downloaded as a module, verified by the checksum
+// database, and run from the module cache. Exec does not return.
+func Exec(gotoolchain string, pathOnly bool) {
+	if pathOnly {
+		exe, err := exec.LookPath(gotoolchain)
+		if err != nil {
+			base.Fatalf("go: cannot find %q in PATH", gotoolchain)
+		}
+		execGoToolchain(gotoolchain, "", exe)
+	}
+
+	m := module.Version{
+		Path:    gotoolchainModule,
+		Version: gotoolchainVersion + "-" + gotoolchain + "." + runtime.GOOS + "-" + runtime.GOARCH,
+	}
+	dir, err := modfetch.Download(context.Background(), m)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			base.Fatalf("go: download %s for %s/%s: toolchain not available", gotoolchain, runtime.GOOS, runtime.GOARCH)
+		}
+		base.Fatalf("go: download %s: %v", gotoolchain, err)
+	}
+
+	// Module zips do not record file modes, so the commands of a newly
+	// extracted toolchain are not executable yet. Set the execute bits
+	// in pkg/tool before those of bin/go, which is checked to tell
+	// whether this has already been done.
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join(dir, "bin/go"))
+		if err != nil {
+			base.Fatalf("go: download %s: invalid toolchain: %v", gotoolchain, err)
+		}
+		if info.Mode()&0111 == 0 {
+			allowExec(filepath.Join(dir, "pkg/tool"))
+			allowExec(filepath.Join(dir, "bin"))
+		}
+	}
+
+	exe := filepath.Join(dir, "bin", "go")
+	if runtime.GOOS == "windows" {
+		exe += ".exe"
+	}
+	execGoToolchain(gotoolchain, dir, exe)
+}
+
+// allowExec sets the execute bits on all files in dir.
+func allowExec(dir string) {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Chmod(path, info.Mode()&0777|0111); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		base.Fatalf("go: %v", err)
+	}
+}
+
+// toolchainEnv returns the environment for running the go command of
+// a toolchain installed in dir, or found in PATH if dir is empty.
+// The toolchain is told to run locally, so that it does not try to
+// switch again.
+func toolchainEnv(dir string) []string {
+	os.Setenv("GOTOOLCHAIN", "local")
+	if dir != "" {
+		os.Setenv("GOROOT", dir)
+	} else {
+		os.Unsetenv("GOROOT")
+	}
+	return os.Environ()
+}
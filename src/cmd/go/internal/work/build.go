@@ -78,6 +78,37 @@ and test commands:
 	-asan
 		enable interoperation with address sanitizer.
 		Supported only on linux/arm64, linux/amd64.
+	-cover
+		enable code coverage instrumentation of the built program.
+		When the program exits, it writes its coverage counters to a new
+		file in the directory named by the GOCOVERDIR environment variable.
+		Use 'go tool covdata' to merge and convert such files.
+		The -cover, -covermode, and -coverpkg flags apply to go build,
+		go install, and go run; go test has its own flags of the same
+		names (see 'go help testflag').
+	-covermode set,count,atomic
+		set the mode for coverage analysis.
+		The default is "set" unless -race is enabled,
+		in which case it is "atomic".
+		The values:
+		set: bool: does this statement run?
+		count: int: how many times does this statement run?
+		atomic: int: count, but correct in multithreaded programs;
+			significantly more expensive.
+		Sets -cover.
+	-coverpkg pattern1,pattern2,pattern3
+		apply coverage analysis to each package matching the patterns.
+		The default is to apply coverage analysis to the packages in the
+		main module. The main packages are always instrumented.
+		See 'go help packages' for a description of package patterns.
+		Sets -cover.
+	-pgo file
+		specify the file path of a profile for profile-guided optimization (PGO).
+		The profile is a CPU profile in the format written by runtime/pprof.
+		When the special name "auto" is specified, and the build has exactly
+		one main package, the file named "default.pgo" in the main package's
+		directory is used, if it exists.
+		The special name "off" turns off PGO. The default is "auto".
 	-v
 		print the names of packages as they are compiled.
 	-work
@@ -218,6 +249,8 @@ func init() {
 
 	AddBuildFlags(CmdBuild, DefaultBuildFlags)
 	AddBuildFlags(CmdInstall, DefaultBuildFlags)
+	AddCoverFlags(CmdBuild)
+	AddCoverFlags(CmdInstall)
 	base.AddWorkfileFlag(&CmdBuild.Flag)
 }
 
@@ -304,6 +337,7 @@ func AddBuildFlags(cmd *base.Command, mask BuildFlagMask) {
 	cmd.Flag.StringVar(&cfg.BuildContext.InstallSuffix, "installsuffix", "", "")
 	cmd.Flag.Var(&load.BuildLdflags, "ldflags", "")
 	cmd.Flag.BoolVar(&cfg.BuildLinkshared, "linkshared", false, "")
+	cmd.Flag.StringVar(&cfg.BuildPGO, "pgo", "auto", "")
 	cmd.Flag.StringVar(&cfg.BuildPkgdir, "pkgdir", "", "")
 	cmd.Flag.BoolVar(&cfg.BuildRace, "race", false, "")
 	cmd.Flag.BoolVar(&cfg.BuildMSan, "msan", false, "")
@@ -319,6 +353,34 @@ func AddBuildFlags(cmd *base.Command, mask BuildFlagMask) {
 	cmd.Flag.StringVar(&cfg.DebugTrace, "debug-trace", "", "")
 }
 
+// AddCoverFlags adds the coverage-related build flags to cmd.
+// They are separate from AddBuildFlags because 'go test' defines
+// flags of the same names with its own meaning.
+func AddCoverFlags(cmd *base.Command) {
+	cmd.Flag.BoolVar(&cfg.BuildCover, "cover", false, "")
+	cmd.Flag.StringVar(&cfg.BuildCoverMode, "covermode", "", "")
+	cmd.Flag.Var(commaListFlag{&cfg.BuildCoverPkg}, "coverpkg", "")
+}
+
+// commaListFlag is a flag.Value for a comma-separated list.
+type commaListFlag struct{ vals *[]string }
+
+func (f commaListFlag) String() string {
+	if f.vals == nil {
+		return ""
+	}
+	return strings.Join(*f.vals, ",")
+}
+
+func (f commaListFlag) Set(v string) error {
+	if v == "" {
+		*f.vals = nil
+	} else {
+		*f.vals = strings.Split(v, ",")
+	}
+	return nil
+}
+
 // tagsFlag is the implementation of the -tags flag.
 type tagsFlag []string
 
@@ -392,6 +454,10 @@ func runBuild(ctx context.Context, cmd *base.Command, args []string) {
 	pkgs := load.PackagesAndErrors(ctx, load.PackageOpts{}, args)
 	load.CheckPackageErrors(pkgs)
 
+	if cfg.BuildCover {
+		load.PrepareForCoverageBuild(pkgs)
+	}
+
 	explicitO := len(cfg.BuildO) > 0
 
 	if len(pkgs) == 1 && pkgs[0].Name == "main" && cfg.BuildO == "" {
@@ -705,6 +771,10 @@ func InstallPackages(ctx context.Context, patterns []string, pkgs []*load.Packag
 	}
 	base.ExitIfErrors()
 
+	if cfg.BuildCover {
+		load.PrepareForCoverageBuild(pkgs)
+	}
+
 	var b Builder
 	b.Init()
 	depMode := ModeBuild
@@ -298,6 +298,9 @@ func (b *Builder) buildActionID(a *Action) cache.ActionID {
 	}
 	if p.Internal.CoverMode != "" {
 		fmt.Fprintf(h, "cover %q %q\n", p.Internal.CoverMode, b.toolID("cover"))
+		if cfg.BuildCover {
+			fmt.Fprintf(h, "cover register\n")
+		}
 	}
 	if p.Internal.FuzzInstrument {
 		if fuzzFlags := fuzzInstrumentFlags(); fuzzFlags != nil {
@@ -392,6 +395,11 @@ func (b *Builder) buildActionID(a *Action) cache.ActionID {
 	for _, file := range inputFiles {
 		fmt.Fprintf(h, "file %s %s\n", file, b.fileHash(filepath.Join(p.Dir, file)))
 	}
+	if p.Internal.PGOProfile != "" {
+		// Only the content of the profile affects the build,
+		// not its location.
+		fmt.Fprintf(h, "pgofile %s\n", b.fileHash(p.Internal.PGOProfile))
+	}
 	for _, a1 := range a.Deps {
 		p1 := a1.Package
 		if p1 != nil {
@@ -649,7 +657,7 @@ OverlayLoop:
 				// Not covering this file.
 				continue
 			}
-			if err := b.cover(a, coverFile, sourceFile, cover.Var); err != nil {
+			if err := b.cover(a, coverFile, sourceFile, cover); err != nil {
 				return err
 			}
 			if i < len(gofiles) {
@@ -1884,12 +1892,19 @@ func (b *Builder) installHeader(ctx context.Context, a *Action) error {
 
 // cover runs, in effect,
 //	go tool cover -mode=b.coverMode -var="varName" -o dst.go src.go
-func (b *Builder) cover(a *Action, dst, src string, varName string) error {
+// For 'go build -cover', it also asks cover to register the counters
+// with runtime/coverage under the file name recorded in profiles.
+func (b *Builder) cover(a *Action, dst, src string, cv *load.CoverVar) error {
+	var register []string
+	if cfg.BuildCover {
+		register = []string{"-register", cv.File}
+	}
 	return b.run(a, a.Objdir, "cover "+a.Package.ImportPath, nil,
 		cfg.BuildToolexec,
 		base.Tool("cover"),
 		"-mode", a.Package.Internal.CoverMode,
-		"-var", varName,
+		"-var", cv.Var,
+		register,
 		"-o", dst,
 		src)
 }
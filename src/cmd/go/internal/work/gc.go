@@ -119,9 +119,9 @@ func (gcToolchain) gc(b *Builder, a *Action, archive string, importcfg, embedcfg
 		switch p.ImportPath {
 		case "bytes", "internal/poll", "net", "os":
 			fallthrough
-		case "runtime/metrics", "runtime/pprof", "runtime/trace":
+		case "runtime/coverage", "runtime/metrics", "runtime/pprof", "runtime/trace":
 			fallthrough
-		case "sync", "syscall", "time":
+		case "sync", "syscall", "testing/synctest", "time":
 			extFiles++
 		}
 	}
@@ -183,6 +183,9 @@ func (gcToolchain) gc(b *Builder, a *Action, archive string, importcfg, embedcfg
 		}
 		args = append(args, "-embedcfg", objdir+"embedcfg")
 	}
+	if p.Internal.PGOProfile != "" {
+		args = append(args, "-pgoprofile", p.Internal.PGOProfile)
+	}
 	if ofile == archive {
 		args = append(args, "-pack")
 	}
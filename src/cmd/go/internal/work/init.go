@@ -22,6 +22,7 @@ import (
 func BuildInit() {
 	modload.Init()
 	instrumentInit()
+	coverInit()
 	buildModeInit()
 	if err := fsys.Init(base.Cwd()); err != nil {
 		base.Fatalf("go: %v", err)
@@ -73,6 +74,35 @@ func fuzzInstrumentFlags() []string {
 	return []string{"-d=libfuzzer"}
 }
 
+// coverInit validates the coverage flags of 'go build', 'go install',
+// and 'go run'.
+func coverInit() {
+	if cfg.BuildCoverMode != "" || len(cfg.BuildCoverPkg) > 0 {
+		// Like 'go test', -covermode and -coverpkg imply -cover.
+		cfg.BuildCover = true
+	}
+	if !cfg.BuildCover {
+		return
+	}
+	switch cfg.BuildCoverMode {
+	case "":
+		cfg.BuildCoverMode = "set"
+		if cfg.BuildRace {
+			// Default coverage mode is atomic when -race is set.
+			cfg.BuildCoverMode = "atomic"
+		}
+	case "set", "count", "atomic":
+	default:
+		base.Fatalf(`go: -covermode must be "set", "count", or "atomic", not %q`, cfg.BuildCoverMode)
+	}
+	if cfg.BuildRace && cfg.BuildCoverMode != "atomic" {
+		base.Fatalf(`go: -covermode must be "atomic", not %q, when -race is enabled`, cfg.BuildCoverMode)
+	}
+	if cfg.BuildContext.Compiler == "gccgo" {
+		base.Fatalf("go: -cover is not supported with gccgo")
+	}
+}
+
 func instrumentInit() {
 	if !cfg.BuildRace && !cfg.BuildMSan && !cfg.BuildASan {
 		return
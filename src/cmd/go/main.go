@@ -37,6 +37,7 @@ import (
 	"cmd/go/internal/run"
 	"cmd/go/internal/test"
 	"cmd/go/internal/tool"
+	"cmd/go/internal/toolchain"
 	"cmd/go/internal/trace"
 	"cmd/go/internal/version"
 	"cmd/go/internal/vet"
@@ -147,6 +148,9 @@ func main() {
 		os.Exit(2)
 	}
 
+	// Run a different toolchain if GOTOOLCHAIN or go.mod asks for one.
+	toolchain.Select(args)
+
 BigCmdLoop:
 	for bigCmd := base.Go; ; {
 		for _, cmd := range bigCmd.Commands {
@@ -0,0 +1,129 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import "math/bits"
+
+// A forwardBitReader reads a bit stream from the start of a byte
+// slice, least significant bits first. FSE table descriptions are
+// stored this way (section 4.1.1).
+type forwardBitReader struct {
+	data []byte
+	off  int    // next byte of data to load
+	bits uint64 // the low cnt bits are the next bits of the stream
+	cnt  uint
+}
+
+// peek returns the next n bits without consuming them.
+// Bits beyond the end of the data read as zero.
+func (br *forwardBitReader) peek(n uint) uint32 {
+	for br.cnt < n {
+		if br.off < len(br.data) {
+			br.bits |= uint64(br.data[br.off]) << br.cnt
+		}
+		br.off++
+		br.cnt += 8
+	}
+	return uint32(br.bits & (1<<n - 1))
+}
+
+// skip consumes n bits, which must have been peeked.
+func (br *forwardBitReader) skip(n uint) {
+	br.bits >>= n
+	br.cnt -= n
+}
+
+// val consumes and returns the next n bits.
+func (br *forwardBitReader) val(n uint) uint32 {
+	v := br.peek(n)
+	br.skip(n)
+	return v
+}
+
+// used returns the number of bytes that contain consumed bits,
+// or -1 if more bits were consumed than the data holds.
+func (br *forwardBitReader) used() int {
+	n := (br.off*8 - int(br.cnt) + 7) / 8
+	if n > len(br.data) {
+		return -1
+	}
+	return n
+}
+
+// A reverseBitReader reads a bit stream from the end of a byte
+// slice, most significant bits first. The last byte of the slice
+// starts with zero bits followed by a 1 bit that marks the start of
+// the stream. Huffman-coded literals and sequences are stored this
+// way (section 4.1).
+type reverseBitReader struct {
+	data     []byte
+	off      int    // data[:off] has not been loaded yet
+	bits     uint64 // the low cnt bits are the next bits of the stream, most significant first
+	cnt      uint
+	overflow bool // more bits were consumed than the stream holds
+}
+
+// init prepares to read the bit stream stored in data.
+func (br *reverseBitReader) init(data []byte) error {
+	if len(data) == 0 {
+		return corrupt("empty bit stream")
+	}
+	last := data[len(data)-1]
+	if last == 0 {
+		return corrupt("missing bit stream start marker")
+	}
+	br.data = data
+	br.off = len(data) - 1
+	br.cnt = uint(bits.Len8(last)) - 1
+	br.bits = uint64(last) & (1<<br.cnt - 1)
+	br.overflow = false
+	return nil
+}
+
+// fill loads bytes until at least 57 bits are available
+// or the data is exhausted.
+func (br *reverseBitReader) fill() {
+	for br.cnt <= 56 && br.off > 0 {
+		br.off--
+		br.bits = br.bits<<8 | uint64(br.data[br.off])
+		br.cnt += 8
+	}
+}
+
+// peek returns the next n bits, n <= 56, without consuming them.
+// Bits beyond the end of the stream read as zero.
+// The caller must have called fill.
+func (br *reverseBitReader) peek(n uint) uint32 {
+	if br.cnt < n {
+		return uint32(br.bits << (n - br.cnt))
+	}
+	return uint32(br.bits >> (br.cnt - n))
+}
+
+// skip consumes n bits.
+func (br *reverseBitReader) skip(n uint) {
+	if n > br.cnt {
+		br.overflow = true
+		br.cnt = 0
+	} else {
+		br.cnt -= n
+	}
+	br.bits &= 1<<br.cnt - 1
+}
+
+// val consumes and returns the next n bits, n <= 32.
+func (br *reverseBitReader) val(n uint) uint32 {
+	if br.cnt < n {
+		br.fill()
+	}
+	v := br.peek(n) & (1<<n - 1)
+	br.skip(n)
+	return v
+}
+
+// finished reports whether the stream has been consumed exactly.
+func (br *reverseBitReader) finished() bool {
+	return br.off == 0 && br.cnt == 0 && !br.overflow
+}
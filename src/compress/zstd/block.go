@@ -0,0 +1,221 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+// Compression modes of the sequence tables (section 3.1.1.3.2.1).
+const (
+	predefinedMode = 0
+	rleMode        = 1
+	fseMode        = 2
+	repeatMode     = 3
+)
+
+// compressedBlock decompresses the contents of a compressed block
+// (section 3.1.1.3), appending the result to z.window.
+func (z *Reader) compressedBlock(data []byte) error {
+	lits, data, err := z.readLiterals(data)
+	if err != nil {
+		return err
+	}
+	if len(data) < 1 {
+		return corrupt("missing sequences section")
+	}
+
+	nseq := int(data[0])
+	data = data[1:]
+	if nseq == 0 {
+		if len(data) != 0 {
+			return corrupt("extra data after empty sequences section")
+		}
+		return z.appendLiterals(lits)
+	}
+	if nseq >= 128 {
+		if nseq < 255 {
+			if len(data) < 1 {
+				return corrupt("truncated sequences header")
+			}
+			nseq = (nseq-128)<<8 + int(data[0])
+			data = data[1:]
+		} else {
+			if len(data) < 2 {
+				return corrupt("truncated sequences header")
+			}
+			nseq = int(le.Uint16(data)) + 0x7f00
+			data = data[2:]
+		}
+	}
+
+	if len(data) < 1 {
+		return corrupt("truncated sequences header")
+	}
+	modes := data[0]
+	data = data[1:]
+	if modes&3 != 0 {
+		return corrupt("reserved bits set in sequences header")
+	}
+	for i, kind := range [3]int{litLenKind, offsetKind, matchLenKind} {
+		n, err := z.readSeqTable(data, modes>>(6-2*i)&3, kind)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+
+	return z.execSequences(data, nseq, lits)
+}
+
+// readSeqTable sets up the decoding table of the given kind of
+// sequence field for the given compression mode, reading its
+// description from the start of data if needed. It returns the
+// number of bytes of data consumed.
+func (z *Reader) readSeqTable(data []byte, mode byte, kind int) (int, error) {
+	switch mode {
+	case predefinedMode:
+		z.seq[kind] = seqPredefined[kind]
+		return 0, nil
+
+	case rleMode:
+		if len(data) < 1 {
+			return 0, corrupt("missing RLE sequence symbol")
+		}
+		if int(data[0]) > seqMaxSym[kind] {
+			return 0, corrupt("RLE sequence symbol %d exceeds maximum of %d", data[0], seqMaxSym[kind])
+		}
+		z.seqRLE[kind][0] = fseEntry{sym: data[0]}
+		z.seq[kind] = z.seqRLE[kind][:]
+		return 1, nil
+
+	case fseMode:
+		if z.seqBuf[kind] == nil {
+			z.seqBuf[kind] = make([]fseEntry, 1<<seqMaxLog[kind])
+		}
+		t, n, err := readFSE(data, seqMaxSym[kind], seqMaxLog[kind], z.seqBuf[kind])
+		if err != nil {
+			return 0, err
+		}
+		z.seq[kind] = t
+		return n, nil
+
+	default: // repeatMode
+		if z.seq[kind] == nil {
+			return 0, corrupt("repeated sequence table without previous table")
+		}
+		return 0, nil
+	}
+}
+
+// execSequences decodes nseq sequences from the bit stream in data
+// and executes them (sections 3.1.1.3.2.2 and 3.1.1.4), appending
+// the result to z.window.
+func (z *Reader) execSequences(data []byte, nseq int, lits []byte) error {
+	var br reverseBitReader
+	if err := br.init(data); err != nil {
+		return err
+	}
+	llTable, ofTable, mlTable := z.seq[litLenKind], z.seq[offsetKind], z.seq[matchLenKind]
+	llState := br.val(fseLog(llTable))
+	ofState := br.val(fseLog(ofTable))
+	mlState := br.val(fseLog(mlTable))
+
+	start := len(z.window)
+	for i := 0; i < nseq; i++ {
+		// The extra bits of the fields are read in the order
+		// offset, match length, literal length.
+		ofCode := ofTable[ofState].sym
+		mlCode := mlTable[mlState].sym
+		llCode := llTable[llState].sym
+
+		offset := uint32(1)<<ofCode + br.val(uint(ofCode))
+		mc := matchLenCodes[mlCode]
+		matchLen := mc.base + br.val(uint(mc.extra))
+		lc := litLenCodes[llCode]
+		litLen := lc.base + br.val(uint(lc.extra))
+
+		// Resolve repeated offsets (section 3.1.1.5).
+		if offset > 3 {
+			offset -= 3
+			z.repeat[2], z.repeat[1], z.repeat[0] = z.repeat[1], z.repeat[0], offset
+		} else {
+			if litLen == 0 {
+				offset++
+			}
+			switch offset {
+			case 1:
+				offset = z.repeat[0]
+			case 2:
+				offset = z.repeat[1]
+				z.repeat[1], z.repeat[0] = z.repeat[0], offset
+			case 3:
+				offset = z.repeat[2]
+				z.repeat[2], z.repeat[1], z.repeat[0] = z.repeat[1], z.repeat[0], offset
+			case 4:
+				offset = z.repeat[0] - 1
+				if offset == 0 {
+					return corrupt("zero offset")
+				}
+				z.repeat[2], z.repeat[1], z.repeat[0] = z.repeat[1], z.repeat[0], offset
+			}
+		}
+
+		if i < nseq-1 {
+			e := llTable[llState]
+			llState = uint32(e.base) + br.val(uint(e.bits))
+			e = mlTable[mlState]
+			mlState = uint32(e.base) + br.val(uint(e.bits))
+			e = ofTable[ofState]
+			ofState = uint32(e.base) + br.val(uint(e.bits))
+		}
+
+		if uint32(len(lits)) < litLen {
+			return corrupt("sequence literal length %d exceeds remaining literals", litLen)
+		}
+		if len(z.window)-start+int(litLen)+int(matchLen) > z.blockMaxSize {
+			return corrupt("block size exceeds maximum of %d", z.blockMaxSize)
+		}
+		z.window = append(z.window, lits[:litLen]...)
+		lits = lits[litLen:]
+		if offset > uint32(len(z.window)) {
+			return corrupt("match offset %d exceeds window", offset)
+		}
+		z.copyMatch(int(offset), int(matchLen))
+	}
+	if !br.finished() {
+		return corrupt("invalid sequences bit stream")
+	}
+	if len(z.window)-start+len(lits) > z.blockMaxSize {
+		return corrupt("block size exceeds maximum of %d", z.blockMaxSize)
+	}
+	z.window = append(z.window, lits...)
+	return nil
+}
+
+// appendLiterals appends the literals of a block without sequences
+// to z.window.
+func (z *Reader) appendLiterals(lits []byte) error {
+	if len(lits) > z.blockMaxSize {
+		return corrupt("block size exceeds maximum of %d", z.blockMaxSize)
+	}
+	z.window = append(z.window, lits...)
+	return nil
+}
+
+// copyMatch appends length bytes to the window, copied from offset
+// bytes back. The source and the destination may overlap, in which
+// case the last offset bytes repeat.
+func (z *Reader) copyMatch(offset, length int) {
+	w := z.window
+	start := len(w) - offset
+	for length > 0 {
+		// The bytes after start, including those appended by earlier
+		// iterations, are all valid sources.
+		n := len(w) - start
+		if n > length {
+			n = length
+		}
+		w = append(w, w[start:start+n]...)
+		length -= n
+	}
+	z.window = w
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+const dictMagic = 0xEC30A437
+
+// A dict is a parsed dictionary (section 5).
+type dict struct {
+	id      uint32 // zero for raw content dictionaries
+	content []byte
+	repeat  [3]uint32
+	huff    huffTable
+	seq     [3][]fseEntry
+}
+
+// parseDict parses a dictionary. Data that does not start with the
+// dictionary magic number is a raw content dictionary.
+func parseDict(data []byte) (*dict, error) {
+	if len(data) < 8 || le.Uint32(data) != dictMagic {
+		return &dict{
+			content: append([]byte(nil), data...),
+			repeat:  [3]uint32{1, 4, 8},
+		}, nil
+	}
+
+	d := &dict{id: le.Uint32(data[4:])}
+	data = data[8:]
+
+	// The entropy tables, in the same format as in compressed
+	// blocks, followed by the repeated offsets.
+	t, n, err := readHuff(data, make([]uint16, 1<<maxHuffBits))
+	if err != nil {
+		return nil, err
+	}
+	d.huff = t
+	data = data[n:]
+	for _, kind := range [3]int{offsetKind, matchLenKind, litLenKind} {
+		t, n, err := readFSE(data, seqMaxSym[kind], seqMaxLog[kind], make([]fseEntry, 1<<seqMaxLog[kind]))
+		if err != nil {
+			return nil, err
+		}
+		d.seq[kind] = t
+		data = data[n:]
+	}
+	if len(data) < 12 {
+		return nil, corrupt("truncated dictionary")
+	}
+	d.content = append([]byte(nil), data[12:]...)
+	for i := range d.repeat {
+		r := le.Uint32(data[4*i:])
+		if r == 0 || r > uint32(len(d.content)) {
+			return nil, corrupt("invalid dictionary repeated offset %d", r)
+		}
+		d.repeat[i] = r
+	}
+	return d, nil
+}
+
+// init sets up z to decompress a frame using the dictionary.
+func (d *dict) init(z *Reader) {
+	z.window = append(z.window, d.content...)
+	z.repeat = d.repeat
+	z.huff = d.huff
+	z.seq = d.seq
+}
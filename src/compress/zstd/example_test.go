@@ -0,0 +1,28 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd_test
+
+import (
+	"compress/zstd"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+func ExampleNewReader() {
+	// A frame holding a single uncompressed block.
+	compressed := "\x28\xb5\x2f\xfd\x20\x0d\x69\x00\x00Hello, world!"
+
+	r := zstd.NewReader(strings.NewReader(compressed))
+	b, err := io.ReadAll(r)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n", b)
+
+	// Output:
+	// Hello, world!
+}
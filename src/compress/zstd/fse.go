@@ -0,0 +1,220 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import "math/bits"
+
+// An fseEntry is an entry of an FSE decoding table (section 4.1).
+// In state s, the decoder emits sym, then reads bits bits and adds
+// them to base to get the next state.
+type fseEntry struct {
+	sym  uint8
+	bits uint8
+	base uint16
+}
+
+// fseLog returns the accuracy log of the decoding table t.
+func fseLog(t []fseEntry) uint {
+	return uint(bits.TrailingZeros(uint(len(t))))
+}
+
+// readFSE reads an FSE table description (section 4.1.1) from the
+// start of data, for symbols up to maxSym and an accuracy log up to
+// maxLog. It builds the decoding table in buf, which must have room
+// for 1<<maxLog entries, and returns it along with the number of
+// bytes of data consumed.
+func readFSE(data []byte, maxSym, maxLog int, buf []fseEntry) ([]fseEntry, int, error) {
+	br := forwardBitReader{data: data}
+	log := int(br.val(4)) + 5
+	if log > maxLog {
+		return nil, 0, corrupt("FSE accuracy log %d exceeds maximum of %d", log, maxLog)
+	}
+
+	var norm [256]int16
+	remaining := 1<<log + 1
+	threshold := 1 << log
+	nbits := uint(log + 1)
+	sym := 0
+	prevZero := false
+	for remaining > 1 && sym <= maxSym {
+		if prevZero {
+			// A zero probability is followed by a 2-bit repeat
+			// count of further zero probabilities, where 3 means
+			// that another repeat count follows.
+			n := sym
+			for {
+				r := int(br.val(2))
+				n += r
+				if r != 3 {
+					break
+				}
+			}
+			if n > maxSym {
+				return nil, 0, corrupt("FSE symbol %d exceeds maximum of %d", n, maxSym)
+			}
+			sym = n
+		}
+
+		max := 2*threshold - 1 - remaining
+		count := int(br.peek(nbits))
+		if count&(threshold-1) < max {
+			count &= threshold - 1
+			br.skip(nbits - 1)
+		} else {
+			if count >= threshold {
+				count -= max
+			}
+			br.skip(nbits)
+		}
+		count-- // the stored value is the probability plus one
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+		if remaining < 1 {
+			return nil, 0, corrupt("FSE probabilities exceed table size")
+		}
+		norm[sym] = int16(count)
+		sym++
+		prevZero = count == 0
+		for remaining < threshold {
+			nbits--
+			threshold >>= 1
+		}
+	}
+	n := br.used()
+	if remaining != 1 || n < 0 {
+		return nil, 0, corrupt("invalid FSE table description")
+	}
+
+	t := buf[:1<<log]
+	if err := buildFSE(norm[:sym], log, t); err != nil {
+		return nil, 0, err
+	}
+	return t, n, nil
+}
+
+// buildFSE builds in t the decoding table of accuracy log log for
+// the normalized probabilities norm (section 4.1.1). A probability
+// of -1 means "less than 1".
+func buildFSE(norm []int16, log int, t []fseEntry) error {
+	size := 1 << log
+	high := size - 1
+	var next [256]uint16
+
+	// Symbols with a probability of less than 1 take one
+	// entry each at the end of the table.
+	for s, n := range norm {
+		if n == -1 {
+			t[high].sym = uint8(s)
+			high--
+			next[s] = 1
+		} else {
+			next[s] = uint16(n)
+		}
+	}
+
+	// Spread the other symbols over the rest of the table.
+	pos := 0
+	step := size>>1 + size>>3 + 3
+	mask := size - 1
+	for s, n := range norm {
+		for i := 0; i < int(n); i++ {
+			t[pos].sym = uint8(s)
+			pos = (pos + step) & mask
+			for pos > high {
+				pos = (pos + step) & mask
+			}
+		}
+	}
+	if pos != 0 {
+		return corrupt("invalid FSE probabilities")
+	}
+
+	for i := range t {
+		s := t[i].sym
+		ns := next[s]
+		next[s]++
+		nb := log - (bits.Len16(ns) - 1)
+		t[i].bits = uint8(nb)
+		t[i].base = uint16(int(ns)<<nb - size)
+	}
+	return nil
+}
+
+// Kinds of sequence fields, which have their own FSE tables.
+const (
+	litLenKind = iota
+	offsetKind
+	matchLenKind
+)
+
+// Limits of the sequence FSE tables, by kind (section 3.1.1.3.2.2).
+var (
+	seqMaxSym = [3]int{35, 31, 52}
+	seqMaxLog = [3]int{9, 8, 9}
+)
+
+// Predefined distributions of the sequence fields (section 3.1.1.3.2.2).
+var (
+	litLenDefault = []int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	}
+	offsetDefault = []int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	}
+	matchLenDefault = []int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	}
+)
+
+// seqPredefined holds the decoding tables of the predefined
+// distributions, by kind.
+var seqPredefined = [3][]fseEntry{
+	predefinedTable(litLenDefault, 6),
+	predefinedTable(offsetDefault, 5),
+	predefinedTable(matchLenDefault, 6),
+}
+
+func predefinedTable(norm []int16, log int) []fseEntry {
+	t := make([]fseEntry, 1<<log)
+	if err := buildFSE(norm, log, t); err != nil {
+		panic("zstd: bad predefined distribution")
+	}
+	return t
+}
+
+// A seqCode gives the value of a literal length or match length
+// code: the baseline plus an integer read from the given number of
+// extra bits (section 3.1.1.3.2.1.1).
+type seqCode struct {
+	base  uint32
+	extra uint8
+}
+
+var litLenCodes = [36]seqCode{
+	{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0},
+	{8, 0}, {9, 0}, {10, 0}, {11, 0}, {12, 0}, {13, 0}, {14, 0}, {15, 0},
+	{16, 1}, {18, 1}, {20, 1}, {22, 1}, {24, 2}, {28, 2}, {32, 3}, {40, 3},
+	{48, 4}, {64, 6}, {128, 7}, {256, 8}, {512, 9}, {1024, 10}, {2048, 11}, {4096, 12},
+	{8192, 13}, {16384, 14}, {32768, 15}, {65536, 16},
+}
+
+var matchLenCodes = [53]seqCode{
+	{3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0}, {8, 0}, {9, 0}, {10, 0},
+	{11, 0}, {12, 0}, {13, 0}, {14, 0}, {15, 0}, {16, 0}, {17, 0}, {18, 0},
+	{19, 0}, {20, 0}, {21, 0}, {22, 0}, {23, 0}, {24, 0}, {25, 0}, {26, 0},
+	{27, 0}, {28, 0}, {29, 0}, {30, 0}, {31, 0}, {32, 0}, {33, 0}, {34, 0},
+	{35, 1}, {37, 1}, {39, 1}, {41, 1}, {43, 2}, {47, 2}, {51, 3}, {59, 3},
+	{67, 4}, {83, 4}, {99, 5}, {131, 7}, {259, 8}, {515, 9}, {1027, 10}, {2051, 11},
+	{4099, 12}, {8195, 13}, {16387, 14}, {32771, 15}, {65539, 16},
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+)
+
+// addCorpus adds the compressed test files and hand-made frames
+// to the seed corpus of f.
+func addCorpus(f *testing.F) {
+	files, err := filepath.Glob("testdata/*.zst")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, name := range files {
+		f.Add(mustReadFile(f, name))
+	}
+	for _, tt := range frames {
+		f.Add([]byte(tt.data))
+	}
+}
+
+// maxFuzzOutput limits how much FuzzReader decompresses from one input.
+// Small frames can describe very large outputs.
+const maxFuzzOutput = 1 << 16
+
+// FuzzReader checks that the Reader does not crash on arbitrary
+// input, and that it decompresses data the same way however it is
+// read.
+func FuzzReader(f *testing.F) {
+	addCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		got, err := io.ReadAll(io.LimitReader(NewReader(bytes.NewReader(data)), maxFuzzOutput))
+		got1, err1 := io.ReadAll(io.LimitReader(iotest.OneByteReader(NewReader(iotest.OneByteReader(bytes.NewReader(data)))), maxFuzzOutput))
+		if !bytes.Equal(got, got1) || (err == nil) != (err1 == nil) {
+			t.Errorf("reading in one-byte pieces: got %d bytes, %v; want %d bytes, %v", len(got1), err1, len(got), err)
+		}
+	})
+}
+
+// FuzzReference checks that the Reader decompresses data that the
+// reference implementation accepts, with the same result.
+func FuzzReference(f *testing.F) {
+	zstd := zstdPath(f)
+	addCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		cmd := exec.Command(zstd, "-d", "-q", "-c")
+		cmd.Stdin = bytes.NewReader(data)
+		want, err := cmd.Output()
+		if err != nil {
+			// The reference implementation rejects the input;
+			// the Reader may or may not notice the corruption.
+			t.Skip()
+		}
+		got, err := io.ReadAll(NewReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatalf("reference implementation succeeds, Reader fails: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("got %d bytes, reference implementation got %d bytes", len(got), len(want))
+		}
+	})
+}
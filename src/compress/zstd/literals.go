@@ -0,0 +1,310 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import "math/bits"
+
+// Literals block types (section 3.1.1.3.1.1).
+const (
+	rawLiterals        = 0
+	rleLiterals        = 1
+	compressedLiterals = 2
+	treelessLiterals   = 3
+)
+
+// maxHuffBits is the largest length of a Huffman code.
+const maxHuffBits = 11
+
+// A huffTable is a Huffman decoding table (section 4.2). It is
+// indexed by the next maxBits bits of the stream; each entry holds
+// a symbol in the high byte and the length of its code in the low byte.
+type huffTable struct {
+	entries []uint16
+	maxBits uint
+}
+
+// readLiterals decodes the literals section at the start of data
+// (section 3.1.1.3.1). It returns the literals and the rest of data.
+func (z *Reader) readLiterals(data []byte) ([]byte, []byte, error) {
+	if len(data) < 1 {
+		return nil, nil, corrupt("missing literals section")
+	}
+	typ := data[0] & 3
+	sizeFormat := (data[0] >> 2) & 3
+
+	if typ == rawLiterals || typ == rleLiterals {
+		var size, hdr int
+		switch sizeFormat {
+		case 0, 2:
+			size, hdr = int(data[0]>>3), 1
+		case 1:
+			if len(data) < 2 {
+				return nil, nil, corrupt("truncated literals header")
+			}
+			size, hdr = int(data[0]>>4)|int(data[1])<<4, 2
+		case 3:
+			if len(data) < 3 {
+				return nil, nil, corrupt("truncated literals header")
+			}
+			size, hdr = int(data[0]>>4)|int(data[1])<<4|int(data[2])<<12, 3
+		}
+		if size > maxBlockSize {
+			return nil, nil, corrupt("literals size %d exceeds maximum of %d", size, maxBlockSize)
+		}
+		data = data[hdr:]
+		if typ == rawLiterals {
+			if len(data) < size {
+				return nil, nil, corrupt("truncated raw literals")
+			}
+			return data[:size], data[size:], nil
+		}
+		if len(data) < 1 {
+			return nil, nil, corrupt("truncated RLE literals")
+		}
+		lits := z.literalsBuf(size)
+		for i := range lits {
+			lits[i] = data[0]
+		}
+		return lits, data[1:], nil
+	}
+
+	var regenSize, compSize, hdr int
+	streams := 4
+	switch sizeFormat {
+	case 0, 1:
+		if len(data) < 3 {
+			return nil, nil, corrupt("truncated literals header")
+		}
+		if sizeFormat == 0 {
+			streams = 1
+		}
+		v := int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+		regenSize, compSize, hdr = v>>4&0x3ff, v>>14&0x3ff, 3
+	case 2:
+		if len(data) < 4 {
+			return nil, nil, corrupt("truncated literals header")
+		}
+		v := int(le.Uint32(data))
+		regenSize, compSize, hdr = v>>4&0x3fff, v>>18&0x3fff, 4
+	case 3:
+		if len(data) < 5 {
+			return nil, nil, corrupt("truncated literals header")
+		}
+		v := int(le.Uint32(data))
+		regenSize, compSize, hdr = v>>4&0x3ffff, v>>22&0x3ff|int(data[4])<<10, 5
+	}
+	if regenSize > maxBlockSize {
+		return nil, nil, corrupt("literals size %d exceeds maximum of %d", regenSize, maxBlockSize)
+	}
+	data = data[hdr:]
+	if len(data) < compSize {
+		return nil, nil, corrupt("truncated compressed literals")
+	}
+	comp, rest := data[:compSize], data[compSize:]
+
+	if typ == compressedLiterals {
+		t, n, err := readHuff(comp, z.huffBuf[:])
+		if err != nil {
+			return nil, nil, err
+		}
+		z.huff = t
+		comp = comp[n:]
+	} else if z.huff.entries == nil {
+		return nil, nil, corrupt("treeless literals without previous Huffman table")
+	}
+
+	lits := z.literalsBuf(regenSize)
+	if streams == 1 {
+		if err := z.huff.decode(comp, lits); err != nil {
+			return nil, nil, err
+		}
+		return lits, rest, nil
+	}
+
+	// Four streams, preceded by a jump table of the sizes
+	// of the first three.
+	if len(comp) < 6 {
+		return nil, nil, corrupt("truncated literals jump table")
+	}
+	sizes := [4]int{int(le.Uint16(comp)), int(le.Uint16(comp[2:])), int(le.Uint16(comp[4:]))}
+	comp = comp[6:]
+	sizes[3] = len(comp) - sizes[0] - sizes[1] - sizes[2]
+	if sizes[3] < 0 {
+		return nil, nil, corrupt("invalid literals jump table")
+	}
+	segment := (regenSize + 3) / 4
+	out := lits
+	for i, size := range sizes {
+		n := segment
+		if i == 3 || n > len(out) {
+			n = len(out)
+		}
+		if err := z.huff.decode(comp[:size], out[:n]); err != nil {
+			return nil, nil, err
+		}
+		comp, out = comp[size:], out[n:]
+	}
+	return lits, rest, nil
+}
+
+// literalsBuf returns a buffer for n literals.
+func (z *Reader) literalsBuf(n int) []byte {
+	if cap(z.literals) < n {
+		z.literals = make([]byte, n, maxBlockSize)
+	}
+	return z.literals[:n]
+}
+
+// readHuff reads a Huffman tree description (section 4.2.1) from the
+// start of data and builds its decoding table in buf. It returns the
+// table and the number of bytes of data consumed.
+func readHuff(data []byte, buf []uint16) (huffTable, int, error) {
+	if len(data) < 1 {
+		return huffTable{}, 0, corrupt("missing Huffman tree description")
+	}
+	var weights [256]uint8
+	var count, n int
+	if hdr := int(data[0]); hdr < 128 {
+		// FSE-compressed weights.
+		if len(data) < 1+hdr {
+			return huffTable{}, 0, corrupt("truncated Huffman weights")
+		}
+		var err error
+		count, err = readHuffWeights(data[1:1+hdr], weights[:255])
+		if err != nil {
+			return huffTable{}, 0, err
+		}
+		n = 1 + hdr
+	} else {
+		// Weights stored directly, 4 bits each.
+		count = hdr - 127
+		n = 1 + (count+1)/2
+		if len(data) < n {
+			return huffTable{}, 0, corrupt("truncated Huffman weights")
+		}
+		for i := 0; i < count; i += 2 {
+			b := data[1+i/2]
+			weights[i], weights[i+1] = b>>4, b&0xf
+		}
+	}
+
+	// The weight of the last symbol is implied: it brings the sum
+	// of 2^(weight-1) to the next power of two.
+	var sum uint32
+	for _, w := range weights[:count] {
+		if w > maxHuffBits {
+			return huffTable{}, 0, corrupt("Huffman weight %d exceeds maximum of %d", w, maxHuffBits)
+		}
+		if w > 0 {
+			sum += 1 << (w - 1)
+		}
+	}
+	if sum == 0 {
+		return huffTable{}, 0, corrupt("all Huffman weights are zero")
+	}
+	maxBits := uint(bits.Len32(sum))
+	if maxBits > maxHuffBits {
+		return huffTable{}, 0, corrupt("Huffman code length %d exceeds maximum of %d", maxBits, maxHuffBits)
+	}
+	rest := uint32(1)<<maxBits - sum
+	if rest&(rest-1) != 0 {
+		return huffTable{}, 0, corrupt("invalid Huffman weights")
+	}
+	weights[count] = uint8(bits.Len32(rest))
+	count++
+
+	// Assign table ranges to symbols by increasing weight,
+	// then by increasing symbol value.
+	var rankCount [maxHuffBits + 2]int
+	for _, w := range weights[:count] {
+		rankCount[w]++
+	}
+	var rankStart [maxHuffBits + 2]int
+	next := 0
+	for w := 1; w <= int(maxBits); w++ {
+		rankStart[w] = next
+		next += rankCount[w] << (w - 1)
+	}
+	t := buf[:1<<maxBits]
+	for s, w := range weights[:count] {
+		if w == 0 {
+			continue
+		}
+		e := uint16(s)<<8 | uint16(maxBits+1-uint(w))
+		start := rankStart[w]
+		end := start + 1<<(w-1)
+		for i := start; i < end; i++ {
+			t[i] = e
+		}
+		rankStart[w] = end
+	}
+	return huffTable{entries: t, maxBits: maxBits}, n, nil
+}
+
+// readHuffWeights decodes FSE-compressed Huffman weights
+// (section 4.2.1.2) into weights and returns their number.
+func readHuffWeights(data []byte, weights []uint8) (int, error) {
+	var buf [1 << 6]fseEntry
+	t, n, err := readFSE(data, maxHuffBits+1, 6, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	log := fseLog(t)
+
+	// Two interleaved states share the bit stream, which ends
+	// when a state update reads past its end.
+	var br reverseBitReader
+	if err := br.init(data[n:]); err != nil {
+		return 0, err
+	}
+	s1 := br.val(log)
+	s2 := br.val(log)
+	count := 0
+	for {
+		if count+2 > len(weights) {
+			return 0, corrupt("too many Huffman weights")
+		}
+		e := t[s1]
+		weights[count] = e.sym
+		count++
+		s1 = uint32(e.base) + br.val(uint(e.bits))
+		if br.overflow {
+			weights[count] = t[s2].sym
+			count++
+			break
+		}
+		e = t[s2]
+		weights[count] = e.sym
+		count++
+		s2 = uint32(e.base) + br.val(uint(e.bits))
+		if br.overflow {
+			weights[count] = t[s1].sym
+			count++
+			break
+		}
+	}
+	return count, nil
+}
+
+// decode decodes a Huffman-coded stream into out, which must be
+// filled exactly.
+func (t *huffTable) decode(data []byte, out []byte) error {
+	var br reverseBitReader
+	if err := br.init(data); err != nil {
+		return err
+	}
+	for i := range out {
+		if br.cnt < t.maxBits {
+			br.fill()
+		}
+		e := t.entries[br.peek(t.maxBits)]
+		out[i] = byte(e >> 8)
+		br.skip(uint(e & 0xff))
+	}
+	if !br.finished() {
+		return corrupt("invalid Huffman-coded literals")
+	}
+	return nil
+}
@@ -0,0 +1,393 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package zstd implements reading of Zstandard compressed data,
+// as specified in RFC 8878.
+//
+// The Reader supports every frame and block type of the format,
+// skippable frames, content checksums, and both raw and structured
+// dictionaries. There is no compressor.
+package zstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	frameMagic     = 0xFD2FB528
+	skippableMagic = 0x184D2A50 // the low 4 bits may take any value
+	skippableMask  = 0xFFFFFFF0
+
+	// maxBlockSize is the largest decompressed size of a block, and
+	// the largest size of the compressed contents of a block (section 3.1.1.2.3).
+	maxBlockSize = 128 << 10
+
+	// maxWindowSize is the largest window the Reader accepts.
+	// It is the default limit of the reference implementation.
+	maxWindowSize = 1 << 27
+)
+
+// Block types (section 3.1.1.2.2).
+const (
+	rawBlock        = 0
+	rleBlock        = 1
+	compressedBlock = 2
+)
+
+// ErrChecksum is returned when reading Zstandard data whose content
+// checksum does not match.
+var ErrChecksum = errors.New("zstd: invalid checksum")
+
+var le = binary.LittleEndian
+
+// corrupt returns an error describing malformed input.
+func corrupt(format string, args ...interface{}) error {
+	return fmt.Errorf("zstd: corrupt input: "+format, args...)
+}
+
+// noEOF converts io.EOF to io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// A Reader is an io.Reader that decompresses Zstandard data.
+//
+// A Zstandard stream is a concatenation of frames. Reads from the
+// Reader return the concatenation of the decompressed contents of
+// all frames; skippable frames are ignored.
+//
+// A frame may store the size and a checksum of its contents.
+// The Reader returns an error when it reaches the end of a frame
+// that does not have the expected size or checksum. Clients should
+// treat data returned by Read as tentative until they receive the
+// io.EOF marking the end of the data.
+type Reader struct {
+	r    io.Reader
+	dict *dict // dictionary passed to NewReaderDict, or nil
+	err  error // sticky error
+
+	// State of the current frame.
+	inFrame      bool
+	lastBlock    bool   // the last block of the frame has been read
+	hasChecksum  bool   // the frame ends with a checksum
+	hasSize      bool   // the frame header records the content size
+	frameSize    uint64 // declared content size, if hasSize
+	produced     uint64 // bytes of the frame decompressed so far
+	windowSize   int
+	blockMaxSize int
+	digest       xxhash64
+
+	// Entropy state carried over from block to block in a frame.
+	repeat  [3]uint32      // repeated offsets
+	huff    huffTable      // Huffman table of the last compressed literals
+	seq     [3][]fseEntry  // decoding tables of the last sequences, by kind
+	seqBuf  [3][]fseEntry  // storage for compressed sequence tables
+	seqRLE  [3][1]fseEntry // storage for RLE sequence tables
+	huffBuf [1 << maxHuffBits]uint16
+
+	// window holds the recent decompressed data that matches may
+	// refer to, preceded by the dictionary content at the start of
+	// a frame. The contents of the current block are at its end.
+	window []byte
+	buf    []byte // contents of the current block, a suffix of window
+	off    int    // next byte of buf to return
+
+	compressed []byte // contents of the current compressed block
+	literals   []byte // decompressed literals of the current block
+	scratch    [16]byte
+}
+
+// NewReader creates a new Reader that decompresses data read from r.
+func NewReader(r io.Reader) *Reader {
+	z := new(Reader)
+	z.Reset(r)
+	return z
+}
+
+// NewReaderDict is like NewReader but decompresses frames using
+// the given dictionary. The dictionary is either in the format
+// produced by the reference implementation's dictionary builder,
+// or raw content.
+//
+// The dictionary is used for every frame. Reading a frame that names
+// a different dictionary by ID fails; since raw content dictionaries
+// have no ID, that check only applies to structured dictionaries.
+func NewReaderDict(r io.Reader, dict []byte) (*Reader, error) {
+	d, err := parseDict(dict)
+	if err != nil {
+		return nil, err
+	}
+	z := NewReader(r)
+	z.dict = d
+	return z, nil
+}
+
+// Reset discards the Reader's state and makes it equivalent to its
+// original state from NewReader or NewReaderDict, but reading from
+// r instead. The dictionary, if any, is kept.
+func (z *Reader) Reset(r io.Reader) {
+	z.r = r
+	z.err = nil
+	z.inFrame = false
+	z.buf = nil
+	z.off = 0
+}
+
+// Read implements io.Reader, reading decompressed bytes.
+func (z *Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, z.err
+	}
+	for z.off >= len(z.buf) {
+		if z.err != nil {
+			return 0, z.err
+		}
+		z.err = z.refill()
+	}
+	n := copy(p, z.buf[z.off:])
+	z.off += n
+	return n, nil
+}
+
+// refill decompresses the next block into z.buf, reading the frame
+// header or trailer as needed. It returns io.EOF at the end of the
+// input if that is the end of a frame.
+func (z *Reader) refill() error {
+	z.buf = z.buf[:0]
+	z.off = 0
+	if !z.inFrame {
+		return z.readFrameHeader()
+	}
+	if z.lastBlock {
+		z.inFrame = false
+		return z.readFrameTrailer()
+	}
+	return z.readBlock()
+}
+
+// readFrameHeader reads the header of the next frame, skipping over
+// skippable frames (section 3.1.1.1).
+func (z *Reader) readFrameHeader() error {
+	var magic uint32
+	for {
+		if _, err := io.ReadFull(z.r, z.scratch[:4]); err != nil {
+			// A clean end of input between frames is the end of the data.
+			return err
+		}
+		magic = le.Uint32(z.scratch[:4])
+		if magic&skippableMask != skippableMagic {
+			break
+		}
+		if _, err := io.ReadFull(z.r, z.scratch[:4]); err != nil {
+			return noEOF(err)
+		}
+		size := int64(le.Uint32(z.scratch[:4]))
+		if n, err := io.CopyN(io.Discard, z.r, size); n < size {
+			return noEOF(err)
+		}
+	}
+	if magic != frameMagic {
+		return fmt.Errorf("zstd: invalid magic number %#x", magic)
+	}
+
+	if _, err := io.ReadFull(z.r, z.scratch[:1]); err != nil {
+		return noEOF(err)
+	}
+	desc := z.scratch[0]
+	fcsFlag := desc >> 6
+	singleSegment := desc&0x20 != 0
+	if desc&0x08 != 0 {
+		return corrupt("reserved bit set in frame header")
+	}
+	z.hasChecksum = desc&0x04 != 0
+	dictIDSize := [4]int{0, 1, 2, 4}[desc&3]
+	fcsSize := [4]int{0, 2, 4, 8}[fcsFlag]
+	if fcsFlag == 0 && singleSegment {
+		fcsSize = 1
+	}
+	windowDescSize := 1
+	if singleSegment {
+		windowDescSize = 0
+	}
+
+	hdr := z.scratch[:windowDescSize+dictIDSize+fcsSize]
+	if _, err := io.ReadFull(z.r, hdr); err != nil {
+		return noEOF(err)
+	}
+
+	var windowSize uint64
+	if !singleSegment {
+		exp, mantissa := hdr[0]>>3, hdr[0]&7
+		windowBase := uint64(1) << (10 + exp)
+		windowSize = windowBase + windowBase/8*uint64(mantissa)
+		hdr = hdr[1:]
+	}
+
+	var dictID uint32
+	switch dictIDSize {
+	case 1:
+		dictID = uint32(hdr[0])
+	case 2:
+		dictID = uint32(le.Uint16(hdr))
+	case 4:
+		dictID = le.Uint32(hdr)
+	}
+	hdr = hdr[dictIDSize:]
+
+	z.hasSize = fcsSize > 0
+	switch fcsSize {
+	case 1:
+		z.frameSize = uint64(hdr[0])
+	case 2:
+		z.frameSize = uint64(le.Uint16(hdr)) + 256
+	case 4:
+		z.frameSize = uint64(le.Uint32(hdr))
+	case 8:
+		z.frameSize = le.Uint64(hdr)
+	}
+	if singleSegment {
+		windowSize = z.frameSize
+	}
+	if windowSize > maxWindowSize {
+		return fmt.Errorf("zstd: window size %d exceeds maximum of %d", windowSize, maxWindowSize)
+	}
+	z.windowSize = int(windowSize)
+	z.blockMaxSize = z.windowSize
+	if z.blockMaxSize > maxBlockSize {
+		z.blockMaxSize = maxBlockSize
+	}
+
+	z.repeat = [3]uint32{1, 4, 8}
+	z.huff = huffTable{}
+	z.seq = [3][]fseEntry{}
+	z.window = z.window[:0]
+	if dictID != 0 {
+		if z.dict == nil {
+			return fmt.Errorf("zstd: frame requires dictionary %d", dictID)
+		}
+		if z.dict.id != 0 && z.dict.id != dictID {
+			return fmt.Errorf("zstd: frame requires dictionary %d, have dictionary %d", dictID, z.dict.id)
+		}
+	}
+	if z.dict != nil {
+		z.dict.init(z)
+	}
+
+	z.inFrame = true
+	z.lastBlock = false
+	z.produced = 0
+	z.digest.reset()
+	return nil
+}
+
+// readFrameTrailer checks the size and checksum of the frame that
+// has just ended.
+func (z *Reader) readFrameTrailer() error {
+	if z.hasSize && z.produced != z.frameSize {
+		return corrupt("frame content size is %d, header says %d", z.produced, z.frameSize)
+	}
+	if !z.hasChecksum {
+		return nil
+	}
+	if _, err := io.ReadFull(z.r, z.scratch[:4]); err != nil {
+		return noEOF(err)
+	}
+	if le.Uint32(z.scratch[:4]) != uint32(z.digest.sum64()) {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// readBlock reads and decompresses the next block of the frame
+// (section 3.1.1.2).
+func (z *Reader) readBlock() error {
+	if _, err := io.ReadFull(z.r, z.scratch[:3]); err != nil {
+		return noEOF(err)
+	}
+	hdr := uint32(z.scratch[0]) | uint32(z.scratch[1])<<8 | uint32(z.scratch[2])<<16
+	z.lastBlock = hdr&1 != 0
+	typ := (hdr >> 1) & 3
+	size := int(hdr >> 3)
+
+	z.trimWindow()
+	start := len(z.window)
+	switch typ {
+	case rawBlock:
+		if size > z.blockMaxSize {
+			return corrupt("block size %d exceeds maximum of %d", size, z.blockMaxSize)
+		}
+		z.window = z.window[:start+size]
+		if _, err := io.ReadFull(z.r, z.window[start:]); err != nil {
+			return noEOF(err)
+		}
+
+	case rleBlock:
+		if size > z.blockMaxSize {
+			return corrupt("block size %d exceeds maximum of %d", size, z.blockMaxSize)
+		}
+		if _, err := io.ReadFull(z.r, z.scratch[:1]); err != nil {
+			return noEOF(err)
+		}
+		z.window = z.window[:start+size]
+		for i := range z.window[start:] {
+			z.window[start+i] = z.scratch[0]
+		}
+
+	case compressedBlock:
+		if size > z.blockMaxSize {
+			return corrupt("compressed block size %d exceeds maximum of %d", size, z.blockMaxSize)
+		}
+		if cap(z.compressed) < size {
+			z.compressed = make([]byte, size, maxBlockSize)
+		}
+		z.compressed = z.compressed[:size]
+		if _, err := io.ReadFull(z.r, z.compressed); err != nil {
+			return noEOF(err)
+		}
+		if err := z.compressedBlock(z.compressed); err != nil {
+			return err
+		}
+
+	default:
+		return corrupt("reserved block type")
+	}
+
+	z.buf = z.window[start:]
+	z.produced += uint64(len(z.buf))
+	if z.hasSize && z.produced > z.frameSize {
+		return corrupt("frame content exceeds declared size %d", z.frameSize)
+	}
+	if z.hasChecksum {
+		z.digest.write(z.buf)
+	}
+	return nil
+}
+
+// trimWindow discards data at the start of the window that matches
+// can no longer refer to, and makes room for a block at its end.
+// To keep the cost of copying low, it only does so once the window
+// holds twice as much data as needed.
+func (z *Reader) trimWindow() {
+	keep := z.windowSize
+	if z.dict != nil {
+		// Matches may refer to the dictionary content in addition
+		// to a full window. Keeping it longer than required is harmless.
+		keep += len(z.dict.content)
+	}
+	if len(z.window) > 2*keep {
+		n := copy(z.window, z.window[len(z.window)-keep:])
+		z.window = z.window[:n]
+	}
+	if cap(z.window)-len(z.window) < z.blockMaxSize {
+		w := make([]byte, len(z.window), 2*len(z.window)+z.blockMaxSize)
+		copy(w, z.window)
+		z.window = w
+	}
+}
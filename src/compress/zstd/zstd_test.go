@@ -0,0 +1,309 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"bytes"
+	"errors"
+	"internal/testenv"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func mustReadFile(t testing.TB, name string) []byte {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+var (
+	gettysburg = "../testdata/gettysburg.txt"
+	e          = "../testdata/e.txt"
+)
+
+func TestFiles(t *testing.T) {
+	twice := func(name string) func(testing.TB) []byte {
+		return func(t testing.TB) []byte {
+			b := mustReadFile(t, name)
+			return append(b, b...)
+		}
+	}
+	once := func(name string) func(testing.TB) []byte {
+		return func(t testing.TB) []byte { return mustReadFile(t, name) }
+	}
+	tests := []struct {
+		compressed string
+		dict       string
+		want       func(testing.TB) []byte
+	}{
+		{"gettysburg.txt.zst", "", once(gettysburg)},
+		{"gettysburg.txt.dict.zst", "testdata/dict", once(gettysburg)},
+		{"gettysburg.txt.rawdict.zst", gettysburg, once(gettysburg)},
+		{"e2.txt.zst", "", twice(e)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.compressed, func(t *testing.T) {
+			data := mustReadFile(t, filepath.Join("testdata", tt.compressed))
+			var r io.Reader = NewReader(bytes.NewReader(data))
+			if tt.dict != "" {
+				var err error
+				r, err = NewReaderDict(bytes.NewReader(data), mustReadFile(t, tt.dict))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.want(t); !bytes.Equal(got, want) {
+				t.Errorf("got %d bytes, want %d bytes", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestDictMissing(t *testing.T) {
+	data := mustReadFile(t, "testdata/gettysburg.txt.dict.zst")
+	_, err := io.ReadAll(NewReader(bytes.NewReader(data)))
+	if err == nil || !strings.Contains(err.Error(), "requires dictionary") {
+		t.Errorf("got error %v, want missing dictionary error", err)
+	}
+
+	// A dictionary with a different ID.
+	dict := mustReadFile(t, "testdata/dict")
+	dict[4]++
+	r, err := NewReaderDict(bytes.NewReader(data), dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(r); err == nil || !strings.Contains(err.Error(), "requires dictionary") {
+		t.Errorf("got error %v, want wrong dictionary error", err)
+	}
+}
+
+// Hand-made frames.
+var frames = []struct {
+	name string
+	data string
+	want string
+	err  error
+}{
+	{
+		name: "empty",
+		data: "",
+		want: "",
+	},
+	{
+		name: "raw",
+		// Single segment frame, content size 5, one raw block.
+		data: "\x28\xb5\x2f\xfd\x20\x05\x29\x00\x00hello",
+		want: "hello",
+	},
+	{
+		name: "rle",
+		// Window of 1KB, no content size, one RLE block of 10 bytes.
+		data: "\x28\xb5\x2f\xfd\x00\x00\x53\x00\x00x",
+		want: "xxxxxxxxxx",
+	},
+	{
+		name: "skippable",
+		data: "\x5a\x2a\x4d\x18\x03\x00\x00\x00abc" +
+			"\x28\xb5\x2f\xfd\x20\x05\x29\x00\x00hello" +
+			"\x50\x2a\x4d\x18\x00\x00\x00\x00",
+		want: "hello",
+	},
+	{
+		name: "concatenated",
+		data: "\x28\xb5\x2f\xfd\x20\x05\x29\x00\x00hello" +
+			"\x28\xb5\x2f\xfd\x20\x05\x29\x00\x00world",
+		want: "helloworld",
+	},
+	{
+		name: "empty frame",
+		data: "\x28\xb5\x2f\xfd\x20\x00\x01\x00\x00",
+		want: "",
+	},
+	{
+		name: "checksum",
+		// xxh64("hello") = 0x26c7827d889f6da3
+		data: "\x28\xb5\x2f\xfd\x24\x05\x29\x00\x00hello\xa3\x6d\x9f\x88",
+		want: "hello",
+	},
+	{
+		name: "bad checksum",
+		data: "\x28\xb5\x2f\xfd\x24\x05\x29\x00\x00hello\xa3\x6d\x9f\x89",
+		want: "hello",
+		err:  ErrChecksum,
+	},
+	{
+		name: "truncated",
+		data: "\x28\xb5\x2f\xfd\x20\x05\x29\x00\x00hel",
+		err:  io.ErrUnexpectedEOF,
+	},
+	{
+		name: "truncated magic",
+		data: "\x28\xb5",
+		err:  io.ErrUnexpectedEOF,
+	},
+	{
+		name: "wrong content size",
+		data: "\x28\xb5\x2f\xfd\x20\x06\x29\x00\x00hello",
+		want: "hello",
+		err:  errCorrupt,
+	},
+	{
+		name: "reserved block type",
+		data: "\x28\xb5\x2f\xfd\x20\x05\x2f\x00\x00hello",
+		err:  errCorrupt,
+	},
+	{
+		name: "block exceeds window",
+		data: "\x28\xb5\x2f\xfd\x20\x04\x29\x00\x00hello",
+		err:  errCorrupt,
+	},
+}
+
+var errCorrupt = errors.New("corrupt input")
+
+func TestFrames(t *testing.T) {
+	for _, tt := range frames {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(NewReader(strings.NewReader(tt.data)))
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			switch {
+			case tt.err == errCorrupt:
+				if err == nil || !strings.Contains(err.Error(), "corrupt input") {
+					t.Errorf("got error %v, want corrupt input", err)
+				}
+			case err != tt.err:
+				t.Errorf("got error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	data := mustReadFile(t, "testdata/gettysburg.txt.zst")
+	want := mustReadFile(t, gettysburg)
+	r := NewReader(strings.NewReader("garbage"))
+	if _, err := io.ReadAll(r); err == nil {
+		t.Fatal("no error for garbage input")
+	}
+	for i := 0; i < 2; i++ {
+		r.Reset(bytes.NewReader(data))
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("got %d bytes, want %d bytes", len(got), len(want))
+		}
+	}
+}
+
+func TestXXHash64(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"hello", 0x26c7827d889f6da3},
+		{"abcdefghijklmnopqrstuvwxyz012345", 0xbf2cd639b4143b80},
+		{"abcdefghijklmnopqrstuvwxyz0123456789", 0x64f23ecf1609b766},
+	}
+	for _, tt := range tests {
+		// Write the input in pieces of every size.
+		for n := 1; n <= len(tt.in)+1; n++ {
+			var h xxhash64
+			h.reset()
+			for in := tt.in; in != ""; {
+				m := n
+				if m > len(in) {
+					m = len(in)
+				}
+				h.write([]byte(in[:m]))
+				in = in[m:]
+			}
+			if got := h.sum64(); got != tt.want {
+				t.Errorf("xxhash64(%q) in pieces of %d = %#x, want %#x", tt.in, n, got, tt.want)
+			}
+		}
+	}
+}
+
+// zstdPath returns the path of the reference implementation's
+// command, skipping the test if it is not installed.
+func zstdPath(t testing.TB) string {
+	testenv.MustHaveExec(t)
+	path, err := exec.LookPath("zstd")
+	if err != nil {
+		t.Skip("zstd command not found")
+	}
+	return path
+}
+
+// TestReference checks that the Reader decompresses the output of
+// the reference implementation at a range of settings.
+func TestReference(t *testing.T) {
+	zstd := zstdPath(t)
+	input := append(mustReadFile(t, e), mustReadFile(t, gettysburg)...)
+	input = append(input, bytes.Repeat([]byte("0123456789"), 1e4)...)
+	if testing.Short() {
+		input = input[:10000]
+	}
+	for _, args := range [][]string{
+		{"-1"},
+		{"-3", "--no-check"},
+		{"-9", "--zstd=wlog=10"},
+		{"-19"},
+		{"--fast=5"},
+		{"-22", "--ultra", "--long=24"},
+		{"-3", "-B1024"},
+	} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			cmd := exec.Command(zstd, append(args, "-q", "-c")...)
+			cmd.Stdin = bytes.NewReader(input)
+			compressed, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("%v: %v", cmd, err)
+			}
+			got, err := io.ReadAll(NewReader(bytes.NewReader(compressed)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, input) {
+				t.Errorf("got %d bytes, want %d bytes", len(got), len(input))
+			}
+		})
+	}
+}
+
+func BenchmarkLarge(b *testing.B) {
+	data := mustReadFile(b, "testdata/e2.txt.zst")
+	r := NewReader(nil)
+	n, err := io.Copy(io.Discard, NewReader(bytes.NewReader(data)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(n)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Reset(bytes.NewReader(data))
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
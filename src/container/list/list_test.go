@@ -340,3 +340,54 @@ func TestMoveUnknownMark(t *testing.T) {
 	checkList(t, &l1, []any{1})
 	checkList(t, &l2, []any{2})
 }
+
+func TestAll(t *testing.T) {
+	var l List
+	for e := range l.All() {
+		t.Fatalf("All on empty list yielded %v", e.Value)
+	}
+
+	for i := 1; i <= 4; i++ {
+		l.PushBack(i)
+	}
+	var got []any
+	for e := range l.All() {
+		got = append(got, e.Value)
+		if e.Value == 2 {
+			l.Remove(e)
+		}
+		if e.Value == 3 {
+			break
+		}
+	}
+	checkValues(t, got, []any{1, 2, 3})
+	checkList(t, &l, []any{1, 3, 4})
+}
+
+func TestBackward(t *testing.T) {
+	var l List
+	for i := 1; i <= 4; i++ {
+		l.PushBack(i)
+	}
+	var got []any
+	for e := range l.Backward() {
+		got = append(got, e.Value)
+		if e.Value == 3 {
+			l.Remove(e)
+		}
+	}
+	checkValues(t, got, []any{4, 3, 2, 1})
+	checkList(t, &l, []any{1, 2, 4})
+}
+
+func checkValues(t *testing.T, got, want []any) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
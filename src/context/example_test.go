@@ -6,7 +6,9 @@ package context_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"time"
 )
 
@@ -118,3 +120,64 @@ func ExampleWithValue() {
 	// found value: Go
 	// key not found: color
 }
+
+// This example uses AfterFunc to close a network connection, interrupting
+// a blocked read, when a context is canceled.
+func ExampleAfterFunc_connection() {
+	readFromConn := func(ctx context.Context, conn net.Conn, b []byte) (n int, err error) {
+		stopc := make(chan struct{})
+		stop := context.AfterFunc(ctx, func() {
+			conn.SetReadDeadline(time.Now())
+			close(stopc)
+		})
+		n, err = conn.Read(b)
+		if !stop() {
+			// The AfterFunc was started.
+			// Wait for it to complete, and reset the Conn's deadline.
+			<-stopc
+			conn.SetReadDeadline(time.Time{})
+			return n, ctx.Err()
+		}
+		return n, err
+	}
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer listener.Close()
+
+	conn, err := net.Dial(listener.Addr().Network(), listener.Addr().String())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shortDuration)
+	defer cancel()
+
+	b := make([]byte, 1024)
+	_, err = readFromConn(ctx, conn, b)
+	fmt.Println(err)
+
+	// Output:
+	// context deadline exceeded
+}
+
+// This example uses WithCancelCause to report why an operation was
+// abandoned.
+func ExampleWithCancelCause() {
+	errTooManyRetries := errors.New("too many retries")
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errTooManyRetries)
+
+	fmt.Println(ctx.Err())
+	fmt.Println(context.Cause(ctx))
+
+	// Output:
+	// context canceled
+	// too many retries
+}
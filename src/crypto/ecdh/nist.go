@@ -0,0 +1,193 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ecdh
+
+import (
+	"crypto/elliptic"
+	"crypto/internal/randutil"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+)
+
+// nistCurve is a Curve implementation based on the constant-time scalar
+// multiplications of the crypto/elliptic P-256, P-384 and P-521 curves.
+//
+// Scalars and points are kept in their byte encodings; the big.Int values
+// required by the crypto/elliptic API only ever hold public values, or the
+// shared secret on its way out.
+type nistCurve struct {
+	name        string
+	curve       elliptic.Curve
+	scalarOrder []byte
+}
+
+func (c *nistCurve) String() string {
+	return c.name
+}
+
+var errInvalidPrivateKey = errors.New("crypto/ecdh: invalid private key")
+
+func (c *nistCurve) GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	key := make([]byte, len(c.scalarOrder))
+	randutil.MaybeReadByte(rand)
+	for {
+		if _, err := io.ReadFull(rand, key); err != nil {
+			return nil, err
+		}
+
+		// Mask off any excess bits if the size of the underlying field is not
+		// a whole number of bytes, which is only the case for P-521.
+		if c.curve.Params().BitSize%8 != 0 {
+			key[0] &= 1<<(c.curve.Params().BitSize%8) - 1
+		}
+
+		// In tests, rand will return all zeros and NewPrivateKey will reject
+		// the zero key. This also makes this function consistent with
+		// crypto/elliptic.GenerateKey.
+		key[1] ^= 0x42
+
+		k, err := c.NewPrivateKey(key)
+		if err == errInvalidPrivateKey {
+			continue
+		}
+		return k, err
+	}
+}
+
+func (c *nistCurve) NewPrivateKey(key []byte) (*PrivateKey, error) {
+	if len(key) != len(c.scalarOrder) {
+		return nil, errors.New("crypto/ecdh: invalid private key size")
+	}
+	if isZero(key) || !isLess(key, c.scalarOrder) {
+		return nil, errInvalidPrivateKey
+	}
+	return &PrivateKey{
+		curve:      c,
+		privateKey: append([]byte{}, key...),
+	}, nil
+}
+
+func (c *nistCurve) privateKeyToPublicKey(key *PrivateKey) *PublicKey {
+	if key.curve != c {
+		panic("crypto/ecdh: internal error: converting the wrong key type")
+	}
+	x, y := c.curve.ScalarBaseMult(key.privateKey)
+	return &PublicKey{
+		curve:     key.curve,
+		publicKey: elliptic.Marshal(c.curve, x, y),
+	}
+}
+
+// isZero returns whether a is all zeroes in constant time.
+func isZero(a []byte) bool {
+	var acc byte
+	for _, b := range a {
+		acc |= b
+	}
+	return acc == 0
+}
+
+// isLess returns whether a < b, where a and b are big-endian buffers of the
+// same length and shorter than 72 bytes.
+func isLess(a, b []byte) bool {
+	if len(a) != len(b) {
+		panic("crypto/ecdh: internal error: mismatched isLess inputs")
+	}
+
+	// Copy the values into a fixed-size preallocated little-endian buffer.
+	// 72 bytes is enough for every scalar in this package, and having a fixed
+	// size lets us avoid heap allocations.
+	if len(a) > 72 {
+		panic("crypto/ecdh: internal error: isLess input too large")
+	}
+	bufA, bufB := make([]byte, 72), make([]byte, 72)
+	for i := range a {
+		bufA[i], bufB[i] = a[len(a)-i-1], b[len(b)-i-1]
+	}
+
+	// Perform a subtraction with borrow.
+	var borrow uint64
+	for i := 0; i < len(bufA); i += 8 {
+		limbA, limbB := binary.LittleEndian.Uint64(bufA[i:]), binary.LittleEndian.Uint64(bufB[i:])
+		_, borrow = bits.Sub64(limbA, limbB, borrow)
+	}
+
+	// If there is a borrow at the end of the operation, then a < b.
+	return borrow == 1
+}
+
+func (c *nistCurve) NewPublicKey(key []byte) (*PublicKey, error) {
+	// Reject the point at infinity and compressed encodings.
+	if len(key) == 0 || key[0] != 4 {
+		return nil, errors.New("crypto/ecdh: invalid public key")
+	}
+	// Unmarshal checks the length of the encoding, and that the point is
+	// on the curve.
+	if x, _ := elliptic.Unmarshal(c.curve, key); x == nil {
+		return nil, errors.New("crypto/ecdh: invalid public key")
+	}
+	return &PublicKey{
+		curve:     c,
+		publicKey: append([]byte{}, key...),
+	}, nil
+}
+
+func (c *nistCurve) ecdh(local *PrivateKey, remote *PublicKey) ([]byte, error) {
+	// Note that this function can't return an error, as NewPublicKey rejects
+	// invalid points and the point at infinity, and NewPrivateKey rejects
+	// invalid scalars and the zero value. The curves have prime order, so the
+	// result can't be the point at infinity either. We check it anyway to
+	// protect against changes to the behavior of the crypto/elliptic curves.
+	if local.curve != c || remote.curve != c {
+		return nil, errors.New("crypto/ecdh: mismatched key curves")
+	}
+	x, y := elliptic.Unmarshal(c.curve, remote.publicKey)
+	x, y = c.curve.ScalarMult(x, y, local.privateKey)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, errors.New("crypto/ecdh: ECDH result is the point at infinity")
+	}
+	return x.FillBytes(make([]byte, (c.curve.Params().BitSize+7)/8)), nil
+}
+
+// P256 returns a Curve which implements NIST P-256 (FIPS 186-3, section D.2.3),
+// also known as secp256r1 or prime256v1.
+//
+// Multiple invocations of this function will return the same value, which can
+// be used for equality checks and switch statements.
+func P256() Curve { return p256 }
+
+var p256 = &nistCurve{
+	name:        "P-256",
+	curve:       elliptic.P256(),
+	scalarOrder: elliptic.P256().Params().N.Bytes(),
+}
+
+// P384 returns a Curve which implements NIST P-384 (FIPS 186-3, section D.2.4),
+// also known as secp384r1.
+//
+// Multiple invocations of this function will return the same value, which can
+// be used for equality checks and switch statements.
+func P384() Curve { return p384 }
+
+var p384 = &nistCurve{
+	name:        "P-384",
+	curve:       elliptic.P384(),
+	scalarOrder: elliptic.P384().Params().N.Bytes(),
+}
+
+// P521 returns a Curve which implements NIST P-521 (FIPS 186-3, section D.2.5),
+// also known as secp521r1.
+//
+// Multiple invocations of this function will return the same value, which can
+// be used for equality checks and switch statements.
+func P521() Curve { return p521 }
+
+var p521 = &nistCurve{
+	name:        "P-521",
+	curve:       elliptic.P521(),
+	scalarOrder: elliptic.P521().Params().N.Bytes(),
+}
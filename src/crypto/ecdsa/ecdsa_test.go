@@ -6,6 +6,7 @@ package ecdsa
 
 import (
 	"bufio"
+	"bytes"
 	"compress/bzip2"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -173,6 +174,45 @@ func fromHex(s string) *big.Int {
 	return r
 }
 
+func TestECDH(t *testing.T) {
+	testAllCurves(t, testECDH)
+}
+
+func testECDH(t *testing.T, c elliptic.Curve) {
+	priv, err := GenerateKey(c, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := priv.ECDH()
+	if c == elliptic.P224() {
+		if err == nil {
+			t.Error("converted a P-224 private key")
+		}
+		if _, err := priv.PublicKey.ECDH(); err == nil {
+			t.Error("converted a P-224 public key")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := priv.PublicKey.ECDH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !k.PublicKey().Equal(pub) {
+		t.Error("converted public key does not match the converted private key")
+	}
+	if want := priv.D.FillBytes(make([]byte, len(k.Bytes()))); !bytes.Equal(k.Bytes(), want) {
+		t.Errorf("converted private key is %x, want %x", k.Bytes(), want)
+	}
+
+	invalid := &PublicKey{Curve: c, X: big.NewInt(1), Y: big.NewInt(1)}
+	if _, err := invalid.ECDH(); err == nil {
+		t.Error("converted a public key that is not on the curve")
+	}
+}
+
 func TestVectors(t *testing.T) {
 	// This test runs the full set of NIST test vectors from
 	// https://csrc.nist.gov/groups/STM/cavp/documents/dss/186-3ecdsatestvectors.zip
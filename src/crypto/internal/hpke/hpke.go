@@ -0,0 +1,351 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hpke implements the base mode of Hybrid Public Key Encryption, as
+// specified in RFC 9180, for the algorithms needed by crypto/tls.
+package hpke
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// KEM, KDF and AEAD identifiers, as registered in RFC 9180, Section 7.
+const (
+	DHKEM_X25519_HKDF_SHA256 uint16 = 0x0020
+
+	KDF_HKDF_SHA256 uint16 = 0x0001
+
+	AEAD_AES_128_GCM      uint16 = 0x0001
+	AEAD_AES_256_GCM      uint16 = 0x0002
+	AEAD_ChaCha20Poly1305 uint16 = 0x0003
+)
+
+// hkdfKDF is the HKDF-based labeled key derivation function of RFC 9180,
+// Section 4.
+type hkdfKDF struct {
+	hash crypto.Hash
+}
+
+func (kdf *hkdfKDF) LabeledExtract(suiteID []byte, salt []byte, label string, inputKey []byte) []byte {
+	labeledIKM := make([]byte, 0, 7+len(suiteID)+len(label)+len(inputKey))
+	labeledIKM = append(labeledIKM, []byte("HPKE-v1")...)
+	labeledIKM = append(labeledIKM, suiteID...)
+	labeledIKM = append(labeledIKM, label...)
+	labeledIKM = append(labeledIKM, inputKey...)
+	return hkdf.Extract(kdf.hash.New, labeledIKM, salt)
+}
+
+func (kdf *hkdfKDF) LabeledExpand(suiteID []byte, randomKey []byte, label string, info []byte, length uint16) []byte {
+	labeledInfo := make([]byte, 0, 2+7+len(suiteID)+len(label)+len(info))
+	labeledInfo = appendUint16(labeledInfo, length)
+	labeledInfo = append(labeledInfo, []byte("HPKE-v1")...)
+	labeledInfo = append(labeledInfo, suiteID...)
+	labeledInfo = append(labeledInfo, label...)
+	labeledInfo = append(labeledInfo, info...)
+	out := make([]byte, length)
+	n, err := hkdf.Expand(kdf.hash.New, randomKey, labeledInfo).Read(out)
+	if err != nil || n != int(length) {
+		panic("hpke: LabeledExpand failed unexpectedly")
+	}
+	return out
+}
+
+// dhKEM implements the DHKEM of RFC 9180, Section 4.1.
+type dhKEM struct {
+	dh  ecdh.Curve
+	kdf hkdfKDF
+
+	suiteID []byte
+	nSecret uint16
+}
+
+// SupportedKEMs is the set of KEMs supported by this package.
+var SupportedKEMs = map[uint16]struct {
+	curve   ecdh.Curve
+	hash    crypto.Hash
+	nSecret uint16
+}{
+	DHKEM_X25519_HKDF_SHA256: {ecdh.X25519(), crypto.SHA256, 32},
+}
+
+func newDHKem(kemID uint16) (*dhKEM, error) {
+	suite, ok := SupportedKEMs[kemID]
+	if !ok {
+		return nil, errors.New("hpke: unsupported KEM id")
+	}
+	return &dhKEM{
+		dh:      suite.curve,
+		kdf:     hkdfKDF{suite.hash},
+		suiteID: appendUint16([]byte("KEM"), kemID),
+		nSecret: suite.nSecret,
+	}, nil
+}
+
+func (dh *dhKEM) ExtractAndExpand(dhKey, kemContext []byte) []byte {
+	eaePRK := dh.kdf.LabeledExtract(dh.suiteID[:], nil, "eae_prk", dhKey)
+	return dh.kdf.LabeledExpand(dh.suiteID[:], eaePRK, "shared_secret", kemContext, dh.nSecret)
+}
+
+// testingOnlyGenerateKey, if not nil, is used in place of a random ephemeral
+// key by Encap, so that tests can check against known answers.
+var testingOnlyGenerateKey func() (*ecdh.PrivateKey, error)
+
+func (dh *dhKEM) Encap(pubRecipient *ecdh.PublicKey) (sharedSecret []byte, encapPub []byte, err error) {
+	var privEph *ecdh.PrivateKey
+	if testingOnlyGenerateKey != nil {
+		privEph, err = testingOnlyGenerateKey()
+	} else {
+		privEph, err = dh.dh.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	dhVal, err := privEph.ECDH(pubRecipient)
+	if err != nil {
+		return nil, nil, err
+	}
+	encPubEph := privEph.PublicKey().Bytes()
+
+	encPubRecip := pubRecipient.Bytes()
+	kemContext := append(encPubEph, encPubRecip...)
+
+	return dh.ExtractAndExpand(dhVal, kemContext), encPubEph, nil
+}
+
+func (dh *dhKEM) Decap(encPubEph []byte, secRecipient *ecdh.PrivateKey) ([]byte, error) {
+	pubEph, err := dh.dh.NewPublicKey(encPubEph)
+	if err != nil {
+		return nil, err
+	}
+	dhVal, err := secRecipient.ECDH(pubEph)
+	if err != nil {
+		return nil, err
+	}
+	kemContext := append(encPubEph[:len(encPubEph):len(encPubEph)], secRecipient.PublicKey().Bytes()...)
+
+	return dh.ExtractAndExpand(dhVal, kemContext), nil
+}
+
+// SupportedKDFs is the set of KDFs supported by this package.
+var SupportedKDFs = map[uint16]func() *hkdfKDF{
+	KDF_HKDF_SHA256: func() *hkdfKDF { return &hkdfKDF{crypto.SHA256} },
+}
+
+// SupportedAEADs is the set of AEADs supported by this package, along with
+// their key sizes.
+var SupportedAEADs = map[uint16]struct {
+	keySize   int
+	nonceSize int
+	aead      func([]byte) (cipher.AEAD, error)
+}{
+	AEAD_AES_128_GCM:      {keySize: 16, nonceSize: 12, aead: aesGCMNew},
+	AEAD_AES_256_GCM:      {keySize: 32, nonceSize: 12, aead: aesGCMNew},
+	AEAD_ChaCha20Poly1305: {keySize: chacha20poly1305.KeySize, nonceSize: chacha20poly1305.NonceSize, aead: chacha20poly1305.New},
+}
+
+func aesGCMNew(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type context struct {
+	aead      cipher.AEAD
+	baseNonce []byte
+	seqNum    uint128
+}
+
+// Sender is the sending side of an HPKE context. Messages must be opened by
+// the Receiver in the same order as they were sealed.
+type Sender struct {
+	*context
+}
+
+// Receiver is the receiving side of an HPKE context.
+type Receiver struct {
+	*context
+}
+
+func newContext(sharedSecret []byte, kemID, kdfID, aeadID uint16, info []byte) (*context, error) {
+	sid := suiteID(kemID, kdfID, aeadID)
+
+	kdfInit, ok := SupportedKDFs[kdfID]
+	if !ok {
+		return nil, errors.New("hpke: unsupported KDF id")
+	}
+	kdf := kdfInit()
+
+	aeadInfo, ok := SupportedAEADs[aeadID]
+	if !ok {
+		return nil, errors.New("hpke: unsupported AEAD id")
+	}
+
+	pskIDHash := kdf.LabeledExtract(sid, nil, "psk_id_hash", nil)
+	infoHash := kdf.LabeledExtract(sid, nil, "info_hash", info)
+	ksContext := append([]byte{0}, pskIDHash...)
+	ksContext = append(ksContext, infoHash...)
+
+	secret := kdf.LabeledExtract(sid, sharedSecret, "secret", nil)
+
+	key := kdf.LabeledExpand(sid, secret, "key", ksContext, uint16(aeadInfo.keySize))
+	baseNonce := kdf.LabeledExpand(sid, secret, "base_nonce", ksContext, uint16(aeadInfo.nonceSize))
+
+	aead, err := aeadInfo.aead(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &context{aead: aead, baseNonce: baseNonce}, nil
+}
+
+// SetupSender sets up a base mode HPKE context for sending to pubRecipient.
+// It returns the encapsulated key, which must be sent to the recipient along
+// with the first message.
+func SetupSender(kemID, kdfID, aeadID uint16, pubRecipient *ecdh.PublicKey, info []byte) ([]byte, *Sender, error) {
+	kem, err := newDHKem(kemID)
+	if err != nil {
+		return nil, nil, err
+	}
+	sharedSecret, encapsulatedKey, err := kem.Encap(pubRecipient)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	context, err := newContext(sharedSecret, kemID, kdfID, aeadID, info)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return encapsulatedKey, &Sender{context}, nil
+}
+
+// SetupReceiver sets up a base mode HPKE context for receiving messages
+// encrypted to privRecipient with the encapsulated key encPubEph.
+func SetupReceiver(kemID, kdfID, aeadID uint16, privRecipient *ecdh.PrivateKey, info, encPubEph []byte) (*Receiver, error) {
+	kem, err := newDHKem(kemID)
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := kem.Decap(encPubEph, privRecipient)
+	if err != nil {
+		return nil, err
+	}
+
+	context, err := newContext(sharedSecret, kemID, kdfID, aeadID, info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Receiver{context}, nil
+}
+
+func (ctx *context) nextNonce() []byte {
+	nonce := ctx.seqNum.bytes()[16-ctx.aead.NonceSize():]
+	for i := range ctx.baseNonce {
+		nonce[i] ^= ctx.baseNonce[i]
+	}
+	return nonce
+}
+
+var errMessageLimit = errors.New("hpke: message limit reached")
+
+// checkSeqNum returns an error if the sequence number would wrap around the
+// nonce space (RFC 9180, Section 5.2).
+func (ctx *context) checkSeqNum() error {
+	if ctx.seqNum.bitLen() >= ctx.aead.NonceSize()*8 {
+		return errMessageLimit
+	}
+	return nil
+}
+
+// Seal encrypts and authenticates plaintext, authenticating aad as well.
+func (s *Sender) Seal(aad, plaintext []byte) ([]byte, error) {
+	if err := s.checkSeqNum(); err != nil {
+		return nil, err
+	}
+	ciphertext := s.aead.Seal(nil, s.nextNonce(), plaintext, aad)
+	s.seqNum = s.seqNum.addOne()
+	return ciphertext, nil
+}
+
+// Open decrypts and authenticates ciphertext, authenticating aad as well.
+func (r *Receiver) Open(aad, ciphertext []byte) ([]byte, error) {
+	if err := r.checkSeqNum(); err != nil {
+		return nil, err
+	}
+	plaintext, err := r.aead.Open(nil, r.nextNonce(), ciphertext, aad)
+	if err != nil {
+		return nil, err
+	}
+	r.seqNum = r.seqNum.addOne()
+	return plaintext, nil
+}
+
+func suiteID(kemID, kdfID, aeadID uint16) []byte {
+	suiteID := make([]byte, 0, 4+2+2+2)
+	suiteID = append(suiteID, []byte("HPKE")...)
+	suiteID = appendUint16(suiteID, kemID)
+	suiteID = appendUint16(suiteID, kdfID)
+	suiteID = appendUint16(suiteID, aeadID)
+	return suiteID
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// ParseHPKEPublicKey parses the serialized public key of the KEM kemID, as
+// found for example in an ECHConfig.
+func ParseHPKEPublicKey(kemID uint16, bytes []byte) (*ecdh.PublicKey, error) {
+	kemInfo, ok := SupportedKEMs[kemID]
+	if !ok {
+		return nil, errors.New("hpke: unsupported KEM id")
+	}
+	return kemInfo.curve.NewPublicKey(bytes)
+}
+
+// ParseHPKEPrivateKey parses the serialized private key of the KEM kemID.
+func ParseHPKEPrivateKey(kemID uint16, bytes []byte) (*ecdh.PrivateKey, error) {
+	kemInfo, ok := SupportedKEMs[kemID]
+	if !ok {
+		return nil, errors.New("hpke: unsupported KEM id")
+	}
+	return kemInfo.curve.NewPrivateKey(bytes)
+}
+
+// uint128 is a 128-bit big-endian counter, used for the sequence number.
+type uint128 struct {
+	hi, lo uint64
+}
+
+func (u uint128) addOne() uint128 {
+	lo, carry := bits.Add64(u.lo, 1, 0)
+	return uint128{u.hi + carry, lo}
+}
+
+func (u uint128) bitLen() int {
+	if u.hi != 0 {
+		return 64 + bits.Len64(u.hi)
+	}
+	return bits.Len64(u.lo)
+}
+
+func (u uint128) bytes() []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[0:], u.hi)
+	binary.BigEndian.PutUint64(b[8:], u.lo)
+	return b
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hpke
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func mustDecodeHex(t *testing.T, in string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestRFC9180Vectors checks the base mode known answers of RFC 9180,
+// Appendix A, for the supported algorithms.
+func TestRFC9180Vectors(t *testing.T) {
+	vectorsJSON, err := os.ReadFile("testdata/rfc9180-vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []struct {
+		Mode        uint16 `json:"mode"`
+		KEM         uint16 `json:"kem_id"`
+		KDF         uint16 `json:"kdf_id"`
+		AEAD        uint16 `json:"aead_id"`
+		Info        string `json:"info"`
+		IkmE        string `json:"ikmE"`
+		SkRm        string `json:"skRm"`
+		PkRm        string `json:"pkRm"`
+		Enc         string `json:"enc"`
+		Encryptions []struct {
+			Aad string `json:"aad"`
+			Pt  string `json:"pt"`
+			Ct  string `json:"ct"`
+		} `json:"encryptions"`
+	}
+	if err := json.Unmarshal(vectorsJSON, &vectors); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, vector := range vectors {
+		name := fmt.Sprintf("mode %04x kem %04x kdf %04x aead %04x",
+			vector.Mode, vector.KEM, vector.KDF, vector.AEAD)
+		t.Run(name, func(t *testing.T) {
+			if vector.Mode != 0 {
+				t.Skip("only mode 0 (base) is supported")
+			}
+
+			kem, err := newDHKem(vector.KEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ikmE := mustDecodeHex(t, vector.IkmE)
+			testingOnlyGenerateKey = func() (*ecdh.PrivateKey, error) {
+				// DeriveKeyPair, RFC 9180, Section 7.1.3.
+				dkpPRK := kem.kdf.LabeledExtract(kem.suiteID, nil, "dkp_prk", ikmE)
+				sk := kem.kdf.LabeledExpand(kem.suiteID, dkpPRK, "sk", nil, 32)
+				return kem.dh.NewPrivateKey(sk)
+			}
+			t.Cleanup(func() { testingOnlyGenerateKey = nil })
+
+			pubKey, err := ParseHPKEPublicKey(vector.KEM, mustDecodeHex(t, vector.PkRm))
+			if err != nil {
+				t.Fatal(err)
+			}
+			privKey, err := ParseHPKEPrivateKey(vector.KEM, mustDecodeHex(t, vector.SkRm))
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := mustDecodeHex(t, vector.Info)
+
+			encap, sender, err := SetupSender(vector.KEM, vector.KDF, vector.AEAD, pubKey, info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := mustDecodeHex(t, vector.Enc); !bytes.Equal(encap, want) {
+				t.Errorf("unexpected encapsulated key, got: %x, want %x", encap, want)
+			}
+
+			receiver, err := SetupReceiver(vector.KEM, vector.KDF, vector.AEAD, privKey, info, encap)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, enc := range vector.Encryptions {
+				aad := mustDecodeHex(t, enc.Aad)
+				plaintext := mustDecodeHex(t, enc.Pt)
+				expectedCiphertext := mustDecodeHex(t, enc.Ct)
+
+				ciphertext, err := sender.Seal(aad, plaintext)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(ciphertext, expectedCiphertext) {
+					t.Errorf("unexpected ciphertext, got: %x, want %x", ciphertext, expectedCiphertext)
+				}
+
+				got, err := receiver.Open(aad, ciphertext)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, plaintext) {
+					t.Errorf("unexpected plaintext: got %x want %x", got, plaintext)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenOutOfOrder(t *testing.T) {
+	priv, err := ParseHPKEPrivateKey(DHKEM_X25519_HKDF_SHA256, bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := []byte("test")
+	encap, sender, err := SetupSender(DHKEM_X25519_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_AES_128_GCM, priv.PublicKey(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, err := SetupReceiver(DHKEM_X25519_HKDF_SHA256, KDF_HKDF_SHA256, AEAD_AES_128_GCM, priv, info, encap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ct1, _ := sender.Seal(nil, []byte("first"))
+	ct2, _ := sender.Seal(nil, []byte("second"))
+	if _, err := receiver.Open(nil, ct2); err == nil {
+		t.Error("Open succeeded for a message sealed with a later sequence number")
+	}
+	if pt, err := receiver.Open(nil, ct1); err != nil || string(pt) != "first" {
+		t.Errorf("Open(ct1) = %q, %v; want \"first\", nil", pt, err)
+	}
+	if pt, err := receiver.Open(nil, ct2); err != nil || string(pt) != "second" {
+		t.Errorf("Open(ct2) = %q, %v; want \"second\", nil", pt, err)
+	}
+}
+
+func TestUnsupportedAlgorithms(t *testing.T) {
+	if _, err := ParseHPKEPublicKey(0x0010, make([]byte, 65)); err == nil {
+		t.Error("ParseHPKEPublicKey succeeded for an unsupported KEM")
+	}
+	priv, err := ParseHPKEPrivateKey(DHKEM_X25519_HKDF_SHA256, bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := SetupSender(DHKEM_X25519_HKDF_SHA256, 0x0003, AEAD_AES_128_GCM, priv.PublicKey(), nil); err == nil {
+		t.Error("SetupSender succeeded for an unsupported KDF")
+	}
+	if _, _, err := SetupSender(DHKEM_X25519_HKDF_SHA256, KDF_HKDF_SHA256, 0xffff, priv.PublicKey(), nil); err == nil {
+		t.Error("SetupSender succeeded for an unsupported AEAD")
+	}
+}
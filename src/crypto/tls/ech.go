@@ -0,0 +1,808 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"crypto/internal/hpke"
+	"errors"
+	"hash"
+	"io"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// This file implements Encrypted Client Hello, as specified in
+// draft-ietf-tls-esni-22. Only the DHKEM(X25519, HKDF-SHA256) KEM and the
+// HKDF-SHA256 KDF are supported, together with the AES-128-GCM, AES-256-GCM
+// and ChaCha20Poly1305 AEADs.
+
+const extensionEncryptedClientHelloVersion = 0xfe0d
+
+// ECH ClientHello extension types. See draft-ietf-tls-esni, Section 5.
+const (
+	echTypeOuter uint8 = 0
+	echTypeInner uint8 = 1
+)
+
+// echAcceptConfirmationLength is the length of the acceptance signal carried
+// in the ServerHello random or in the HelloRetryRequest extension.
+const echAcceptConfirmationLength = 8
+
+const (
+	echAcceptConfirmationLabel    = "ech accept confirmation"
+	echAcceptConfirmationHRRLabel = "hrr ech accept confirmation"
+)
+
+type echCipher struct {
+	KDFID  uint16
+	AEADID uint16
+}
+
+type echExtension struct {
+	Type uint16
+	Data []byte
+}
+
+type echConfig struct {
+	raw []byte
+
+	Version uint16
+	Length  uint16
+
+	ConfigID             uint8
+	KemID                uint16
+	PublicKey            []byte
+	SymmetricCipherSuite []echCipher
+
+	MaxNameLength uint8
+	PublicName    []byte
+	Extensions    []echExtension
+}
+
+var errMalformedECHConfig = errors.New("tls: malformed ECHConfigList")
+
+// parseECHConfig parses a single ECHConfig. It returns skip == true if the
+// config has an unsupported version, in which case the rest of ec is not
+// populated.
+func parseECHConfig(enc []byte) (skip bool, ec echConfig, err error) {
+	s := cryptobyte.String(enc)
+	ec.raw = []byte(enc)
+	if !s.ReadUint16(&ec.Version) {
+		return false, echConfig{}, errMalformedECHConfig
+	}
+	if !s.ReadUint16(&ec.Length) {
+		return false, echConfig{}, errMalformedECHConfig
+	}
+	if len(ec.raw) < int(ec.Length)+4 {
+		return false, echConfig{}, errMalformedECHConfig
+	}
+	ec.raw = ec.raw[:ec.Length+4]
+	if ec.Version != extensionEncryptedClientHelloVersion {
+		s.Skip(int(ec.Length))
+		return true, echConfig{}, nil
+	}
+	if !s.ReadUint8(&ec.ConfigID) {
+		return false, echConfig{}, errMalformedECHConfig
+	}
+	if !s.ReadUint16(&ec.KemID) {
+		return false, echConfig{}, errMalformedECHConfig
+	}
+	if !readUint16LengthPrefixed(&s, &ec.PublicKey) {
+		return false, echConfig{}, errMalformedECHConfig
+	}
+	var cipherSuites cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&cipherSuites) {
+		return false, echConfig{}, errMalformedECHConfig
+	}
+	for !cipherSuites.Empty() {
+		var c echCipher
+		if !cipherSuites.ReadUint16(&c.KDFID) {
+			return false, echConfig{}, errMalformedECHConfig
+		}
+		if !cipherSuites.ReadUint16(&c.AEADID) {
+			return false, echConfig{}, errMalformedECHConfig
+		}
+		ec.SymmetricCipherSuite = append(ec.SymmetricCipherSuite, c)
+	}
+	if !s.ReadUint8(&ec.MaxNameLength) {
+		return false, echConfig{}, errMalformedECHConfig
+	}
+	var publicName cryptobyte.String
+	if !s.ReadUint8LengthPrefixed(&publicName) {
+		return false, echConfig{}, errMalformedECHConfig
+	}
+	ec.PublicName = publicName
+	var extensions cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&extensions) {
+		return false, echConfig{}, errMalformedECHConfig
+	}
+	for !extensions.Empty() {
+		var e echExtension
+		if !extensions.ReadUint16(&e.Type) {
+			return false, echConfig{}, errMalformedECHConfig
+		}
+		if !extensions.ReadUint16LengthPrefixed((*cryptobyte.String)(&e.Data)) {
+			return false, echConfig{}, errMalformedECHConfig
+		}
+		ec.Extensions = append(ec.Extensions, e)
+	}
+
+	return false, ec, nil
+}
+
+// parseECHConfigList parses a draft-ietf-tls-esni-22 formatted ECHConfigList,
+// returning a slice of parsed ECHConfigs, in the same order they were parsed,
+// or an error if the list is malformed.
+func parseECHConfigList(data []byte) ([]echConfig, error) {
+	s := cryptobyte.String(data)
+	var length uint16
+	if !s.ReadUint16(&length) {
+		return nil, errMalformedECHConfig
+	}
+	if length != uint16(len(data)-2) {
+		return nil, errMalformedECHConfig
+	}
+	var configs []echConfig
+	for len(s) > 0 {
+		if len(s) < 4 {
+			return nil, errors.New("tls: malformed ECHConfig")
+		}
+		configLen := uint16(s[2])<<8 | uint16(s[3])
+		skip, ec, err := parseECHConfig(s)
+		if err != nil {
+			return nil, err
+		}
+		s = s[configLen+4:]
+		if !skip {
+			configs = append(configs, ec)
+		}
+	}
+	return configs, nil
+}
+
+// pickECHConfig returns the first config in list that is usable by this
+// implementation, or nil if there is none.
+func pickECHConfig(list []echConfig) *echConfig {
+	for _, ec := range list {
+		if _, ok := hpke.SupportedKEMs[ec.KemID]; !ok {
+			continue
+		}
+		var validSCS bool
+		for _, cs := range ec.SymmetricCipherSuite {
+			if _, ok := hpke.SupportedAEADs[cs.AEADID]; !ok {
+				continue
+			}
+			if _, ok := hpke.SupportedKDFs[cs.KDFID]; !ok {
+				continue
+			}
+			validSCS = true
+			break
+		}
+		if !validSCS {
+			continue
+		}
+		if !validDNSName(string(ec.PublicName)) {
+			continue
+		}
+		var unsupportedExt bool
+		for _, ext := range ec.Extensions {
+			// If high order bit is set to 1 the extension is mandatory.
+			// Since we don't support any extensions, if we see a mandatory
+			// bit, we skip the config.
+			if ext.Type&uint16(1<<15) != 0 {
+				unsupportedExt = true
+			}
+		}
+		if unsupportedExt {
+			continue
+		}
+		return &ec
+	}
+	return nil
+}
+
+func pickECHCipherSuite(suites []echCipher) (echCipher, error) {
+	for _, s := range suites {
+		// NOTE: all of the supported AEADs and KDFs are fine, rather than
+		// imposing some sort of preference here, we just pick the first valid
+		// suite.
+		if _, ok := hpke.SupportedAEADs[s.AEADID]; !ok {
+			continue
+		}
+		if _, ok := hpke.SupportedKDFs[s.KDFID]; !ok {
+			continue
+		}
+		return s, nil
+	}
+	return echCipher{}, errors.New("tls: no supported symmetric ciphersuites for ECH")
+}
+
+// validDNSName reports whether name is a syntactically valid DNS name that
+// can be used as an ECHConfig public_name. See draft-ietf-tls-esni,
+// Section 4.
+func validDNSName(name string) bool {
+	if len(name) == 0 || len(name) > 253 {
+		return false
+	}
+	if net.ParseIP(name) != nil {
+		return false
+	}
+	labels := strings.Split(name, ".")
+	for _, l := range labels {
+		if len(l) == 0 || len(l) > 63 || l[0] == '-' || l[len(l)-1] == '-' {
+			return false
+		}
+		for _, r := range l {
+			if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	// The last label must not be all numeric.
+	last := labels[len(labels)-1]
+	for _, r := range last {
+		if r < '0' || r > '9' {
+			return true
+		}
+	}
+	return false
+}
+
+// echClientContext holds the client state of an ECH attempt.
+type echClientContext struct {
+	config          *echConfig
+	hpkeContext     *hpke.Sender
+	encapsulatedKey []byte
+	kdfID           uint16
+	aeadID          uint16
+
+	innerHello      *clientHelloMsg
+	outerHello      *clientHelloMsg
+	innerTranscript hash.Hash
+
+	// acceptedInHRR is set if the server confirmed ECH acceptance in a
+	// HelloRetryRequest, in which case it must also do so in the ServerHello.
+	acceptedInHRR bool
+	echRejected   bool
+	retryConfigs  []byte
+}
+
+// newECHClientContext parses the EncryptedClientHelloConfigList and sets up
+// the HPKE context for the first usable config.
+func (c *Conn) newECHClientContext() (*echClientContext, error) {
+	echConfigs, err := parseECHConfigList(c.config.EncryptedClientHelloConfigList)
+	if err != nil {
+		return nil, err
+	}
+	echConfig := pickECHConfig(echConfigs)
+	if echConfig == nil {
+		return nil, errors.New("tls: EncryptedClientHelloConfigList contains no valid configs")
+	}
+	ech := &echClientContext{config: echConfig}
+	echPK, err := hpke.ParseHPKEPublicKey(echConfig.KemID, echConfig.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	suite, err := pickECHCipherSuite(echConfig.SymmetricCipherSuite)
+	if err != nil {
+		return nil, err
+	}
+	ech.kdfID, ech.aeadID = suite.KDFID, suite.AEADID
+	info := append([]byte("tls ech\x00"), echConfig.raw...)
+	ech.encapsulatedKey, ech.hpkeContext, err = hpke.SetupSender(echConfig.KemID, suite.KDFID, suite.AEADID, echPK, info)
+	if err != nil {
+		return nil, err
+	}
+	return ech, nil
+}
+
+// makeOuterClientHello derives the ClientHelloOuter from inner, which must be
+// complete, including PSK binders.
+func (c *Conn) makeOuterClientHello(inner *clientHelloMsg, ech *echClientContext) (*clientHelloMsg, error) {
+	outer := *inner
+	outer.raw = nil
+	outer.serverName = string(ech.config.PublicName)
+	outer.random = make([]byte, 32)
+	if _, err := io.ReadFull(c.config.rand(), outer.random); err != nil {
+		return nil, errors.New("tls: short read from Rand: " + err.Error())
+	}
+	// The PSK and early data can only be used with the inner hello, as the
+	// client-facing server would not be able to validate the binders. Like
+	// BoringSSL, we don't send GREASE PSKs in the outer hello instead.
+	outer.pskIdentities = nil
+	outer.pskBinders = nil
+	outer.earlyData = false
+	outer.sessionTicket = nil
+	if err := computeAndUpdateOuterECHExtension(&outer, inner, ech, true); err != nil {
+		return nil, err
+	}
+	return &outer, nil
+}
+
+// encodeInnerClientHello returns the EncodedClientHelloInner for inner,
+// padded as recommended by draft-ietf-tls-esni, Section 6.1.3. We don't
+// compress any extensions with ech_outer_extensions.
+func encodeInnerClientHello(inner *clientHelloMsg, maxNameLength int) []byte {
+	h := *inner
+	h.raw = nil
+	h.sessionId = nil
+	b := h.marshal()[4:] // Strip the handshake header.
+
+	var paddingLen int
+	if inner.serverName != "" {
+		paddingLen = maxNameLength - len(inner.serverName)
+		if paddingLen < 0 {
+			paddingLen = 0
+		}
+	} else {
+		paddingLen = 9 + maxNameLength
+	}
+	paddingLen += 31 - ((len(b) + paddingLen - 1) % 32)
+
+	return append(b, make([]byte, paddingLen)...)
+}
+
+func generateOuterECHExt(id uint8, kdfID, aeadID uint16, encodedKey []byte, payload []byte) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddUint8(echTypeOuter)
+	b.AddUint16(kdfID)
+	b.AddUint16(aeadID)
+	b.AddUint8(id)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(encodedKey) })
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(payload) })
+	return b.Bytes()
+}
+
+// computeAndUpdateOuterECHExtension encrypts inner and sets the resulting
+// encrypted_client_hello extension in outer. useKey is false for the second
+// ClientHello after a HelloRetryRequest, which doesn't repeat the
+// encapsulated key.
+func computeAndUpdateOuterECHExtension(outer, inner *clientHelloMsg, ech *echClientContext, useKey bool) error {
+	var encapKey []byte
+	if useKey {
+		encapKey = ech.encapsulatedKey
+	}
+	encodedInner := encodeInnerClientHello(inner, int(ech.config.MaxNameLength))
+	// NOTE: the tag lengths for all of the supported AEADs are the same (16
+	// bytes), so we have hardcoded it here. If we add support for another AEAD
+	// with a different tag length, we will need to change this.
+	encryptedLen := len(encodedInner) + 16 // AEAD tag length
+	var err error
+	outer.encryptedClientHello, err = generateOuterECHExt(ech.config.ConfigID, ech.kdfID, ech.aeadID, encapKey, make([]byte, encryptedLen))
+	if err != nil {
+		return err
+	}
+	outer.raw = nil
+	serializedOuter := outer.marshal()
+	serializedOuter = serializedOuter[4:] // strip the four byte prefix
+	encryptedInner, err := ech.hpkeContext.Seal(serializedOuter, encodedInner)
+	if err != nil {
+		return err
+	}
+	outer.encryptedClientHello, err = generateOuterECHExt(ech.config.ConfigID, ech.kdfID, ech.aeadID, encapKey, encryptedInner)
+	if err != nil {
+		return err
+	}
+	outer.raw = nil
+	return nil
+}
+
+// greaseECHExtension returns a GREASE encrypted_client_hello extension for
+// hello, as described in draft-ietf-tls-esni, Section 6.2. The payload length
+// mimics the one of a real EncodedClientHelloInner for hello.
+func (c *Conn) greaseECHExtension(hello *clientHelloMsg) ([]byte, error) {
+	var configID [1]byte
+	if _, err := io.ReadFull(c.config.rand(), configID[:]); err != nil {
+		return nil, errors.New("tls: short read from Rand: " + err.Error())
+	}
+	encapKey := make([]byte, 32) // the size of an X25519 public key
+	if _, err := io.ReadFull(c.config.rand(), encapKey); err != nil {
+		return nil, errors.New("tls: short read from Rand: " + err.Error())
+	}
+	payload := make([]byte, len(encodeInnerClientHello(hello, 0))+16)
+	if _, err := io.ReadFull(c.config.rand(), payload); err != nil {
+		return nil, errors.New("tls: short read from Rand: " + err.Error())
+	}
+	return generateOuterECHExt(configID[0], hpke.KDF_HKDF_SHA256, hpke.AEAD_AES_128_GCM, encapKey, payload)
+}
+
+// echAcceptConfirmation computes the ECH acceptance signal of
+// draft-ietf-tls-esni, Section 7.2. transcript must cover the messages up to
+// and including the ServerHello or HelloRetryRequest, with the signal bytes
+// set to zero.
+func echAcceptConfirmation(suite *cipherSuiteTLS13, innerRandom []byte, label string, transcript hash.Hash) []byte {
+	return suite.expandLabel(suite.extract(innerRandom, nil), label,
+		transcript.Sum(nil), echAcceptConfirmationLength)
+}
+
+// rawExtension is an extension of a marshaled ClientHello or ServerHello.
+type rawExtension struct {
+	extType uint16
+	data    []byte
+	offset  int // offset of data in the message
+}
+
+// helloExtensions returns the extensions of the marshaled ClientHello or
+// ServerHello m, in order, without parsing their contents.
+func helloExtensions(m []byte) ([]rawExtension, bool) {
+	s := cryptobyte.String(m)
+	var msgType uint8
+	var sessionID cryptobyte.String
+	if !s.ReadUint8(&msgType) || !s.Skip(3) || // uint24 length field
+		!s.Skip(2+32) || // version and random
+		!s.ReadUint8LengthPrefixed(&sessionID) {
+		return nil, false
+	}
+	switch msgType {
+	case typeClientHello:
+		var cipherSuites, compressionMethods cryptobyte.String
+		if !s.ReadUint16LengthPrefixed(&cipherSuites) ||
+			!s.ReadUint8LengthPrefixed(&compressionMethods) {
+			return nil, false
+		}
+	case typeServerHello:
+		if !s.Skip(2 + 1) { // cipher_suite and compression_method
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	if s.Empty() {
+		return nil, true
+	}
+
+	var extensions cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&extensions) || !s.Empty() {
+		return nil, false
+	}
+	var exts []rawExtension
+	for !extensions.Empty() {
+		var ext rawExtension
+		if !extensions.ReadUint16(&ext.extType) ||
+			!readUint16LengthPrefixed(&extensions, &ext.data) {
+			return nil, false
+		}
+		ext.offset = len(m) - len(extensions) - len(ext.data)
+		exts = append(exts, ext)
+	}
+	return exts, true
+}
+
+// ECHRejectionError is the error type returned when ECH is rejected by a remote
+// server. If the server offered a ECHConfigList to use for retries, the
+// RetryConfigList field will contain this list.
+//
+// The client may treat an ECHRejectionError with an empty set of RetryConfigs
+// as a secure signal from the server.
+type ECHRejectionError struct {
+	RetryConfigList []byte
+}
+
+func (e *ECHRejectionError) Error() string {
+	return "tls: server rejected ECH"
+}
+
+// echServerContext holds the server state of an accepted ECH attempt, which
+// is needed to decrypt the second ClientHello after a HelloRetryRequest.
+type echServerContext struct {
+	hpkeContext *hpke.Receiver
+	configID    uint8
+	ciphersuite echCipher
+}
+
+type echExtensionOuter struct {
+	cipherSuite echCipher
+	configID    uint8
+	encap       []byte
+	payload     []byte
+}
+
+// parseECHExt parses the encrypted_client_hello extension of a ClientHello.
+// For an inner extension, outer is nil.
+func parseECHExt(ext []byte) (echType uint8, outer *echExtensionOuter, err error) {
+	data := make([]byte, len(ext))
+	copy(data, ext)
+	s := cryptobyte.String(data)
+	if !s.ReadUint8(&echType) {
+		return 0, nil, errMalformedECHExt
+	}
+	if echType == echTypeInner {
+		if !s.Empty() {
+			return 0, nil, errMalformedECHExt
+		}
+		return echType, nil, nil
+	}
+	if echType != echTypeOuter {
+		return 0, nil, errMalformedECHExt
+	}
+	outer = new(echExtensionOuter)
+	if !s.ReadUint16(&outer.cipherSuite.KDFID) ||
+		!s.ReadUint16(&outer.cipherSuite.AEADID) ||
+		!s.ReadUint8(&outer.configID) ||
+		!readUint16LengthPrefixed(&s, &outer.encap) ||
+		!readUint16LengthPrefixed(&s, &outer.payload) ||
+		len(outer.payload) == 0 || !s.Empty() {
+		return 0, nil, errMalformedECHExt
+	}
+	return echType, outer, nil
+}
+
+var errMalformedECHExt = errors.New("tls: malformed encrypted_client_hello extension")
+
+// echAAD returns the ClientHelloOuterAAD for outer, which is the marshaled
+// ClientHelloOuter, without the handshake header, with the ECH payload
+// replaced by zeroes. See draft-ietf-tls-esni, Section 5.2.
+func echAAD(outer *clientHelloMsg, payloadLen int) ([]byte, error) {
+	raw := outer.marshal()
+	exts, ok := helloExtensions(raw)
+	if !ok {
+		return nil, errMalformedECHExt
+	}
+	for _, ext := range exts {
+		if ext.extType != extensionEncryptedClientHello {
+			continue
+		}
+		aad := make([]byte, len(raw))
+		copy(aad, raw)
+		payloadEnd := ext.offset + len(ext.data)
+		for i := payloadEnd - payloadLen; i < payloadEnd; i++ {
+			aad[i] = 0
+		}
+		return aad[4:], nil
+	}
+	return nil, errMalformedECHExt
+}
+
+// decodeInnerClientHello reconstructs the ClientHelloInner from its encoded
+// form, restoring the legacy_session_id and any extensions referenced by an
+// ech_outer_extensions extension from outer. See draft-ietf-tls-esni,
+// Section 5.1.
+func decodeInnerClientHello(outer *clientHelloMsg, encoded []byte) (*clientHelloMsg, error) {
+	errInvalidInner := errors.New("tls: client sent invalid encrypted client hello")
+
+	s := cryptobyte.String(encoded)
+	var vers uint16
+	var random, sessionID, cipherSuites, compressionMethods []byte
+	var extensions cryptobyte.String
+	if !s.ReadUint16(&vers) || !s.ReadBytes(&random, 32) ||
+		!readUint8LengthPrefixed(&s, &sessionID) ||
+		!readUint16LengthPrefixed(&s, &cipherSuites) ||
+		!readUint8LengthPrefixed(&s, &compressionMethods) ||
+		!s.ReadUint16LengthPrefixed(&extensions) {
+		return nil, errInvalidInner
+	}
+	if len(sessionID) != 0 {
+		return nil, errInvalidInner
+	}
+	// The rest must be padding.
+	for _, b := range s {
+		if b != 0 {
+			return nil, errInvalidInner
+		}
+	}
+
+	outerExts, ok := helloExtensions(outer.marshal())
+	if !ok {
+		return nil, errInvalidInner
+	}
+
+	var b cryptobyte.Builder
+	b.AddUint8(typeClientHello)
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16(vers)
+		b.AddBytes(random)
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(outer.sessionId)
+		})
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(cipherSuites)
+		})
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(compressionMethods)
+		})
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			for !extensions.Empty() {
+				var extType uint16
+				var extData cryptobyte.String
+				if !extensions.ReadUint16(&extType) ||
+					!extensions.ReadUint16LengthPrefixed(&extData) {
+					b.SetError(errInvalidInner)
+					return
+				}
+				if extType != extensionECHOuterExtensions {
+					b.AddUint16(extType)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddBytes(extData)
+					})
+					continue
+				}
+				// The referenced extensions must appear in the outer hello
+				// in the same relative order, and can't include ECH itself.
+				var refs cryptobyte.String
+				if !extData.ReadUint8LengthPrefixed(&refs) || refs.Empty() ||
+					!extData.Empty() {
+					b.SetError(errInvalidInner)
+					return
+				}
+				for !refs.Empty() {
+					var ref uint16
+					if !refs.ReadUint16(&ref) || ref == extensionEncryptedClientHello {
+						b.SetError(errInvalidInner)
+						return
+					}
+					for len(outerExts) > 0 && outerExts[0].extType != ref {
+						outerExts = outerExts[1:]
+					}
+					if len(outerExts) == 0 {
+						b.SetError(errInvalidInner)
+						return
+					}
+					b.AddUint16(ref)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddBytes(outerExts[0].data)
+					})
+					outerExts = outerExts[1:]
+				}
+			}
+		})
+	})
+	msg, err := b.Bytes()
+	if err != nil {
+		return nil, errInvalidInner
+	}
+
+	inner := new(clientHelloMsg)
+	if !inner.unmarshal(msg) {
+		return nil, errInvalidInner
+	}
+
+	// The inner hello must carry the inner ECH extension, and it must not
+	// offer anything below TLS 1.3. See draft-ietf-tls-esni, Section 7.1.
+	if echType, _, err := parseECHExt(inner.encryptedClientHello); err != nil || echType != echTypeInner {
+		return nil, errInvalidInner
+	}
+	if len(inner.supportedVersions) == 0 {
+		return nil, errInvalidInner
+	}
+	for _, v := range inner.supportedVersions {
+		if v < VersionTLS13 {
+			return nil, errInvalidInner
+		}
+	}
+
+	return inner, nil
+}
+
+// processECHClientHello attempts to decrypt the ClientHelloInner in outer
+// with one of the configured EncryptedClientHelloKeys. If it succeeds, it
+// returns the ClientHelloInner and the HPKE context. Otherwise, including if
+// the extension is GREASE, it returns outer and a nil context, and the
+// handshake continues with the ClientHelloOuter.
+func (c *Conn) processECHClientHello(outer *clientHelloMsg) (*clientHelloMsg, *echServerContext, error) {
+	echType, echExt, err := parseECHExt(outer.encryptedClientHello)
+	if err != nil {
+		c.sendAlert(alertDecodeError)
+		return nil, nil, err
+	}
+	if echType == echTypeInner {
+		// We are a client-facing server, which doesn't expect to receive the
+		// inner extension directly.
+		c.sendAlert(alertIllegalParameter)
+		return nil, nil, errors.New("tls: client sent an inner encrypted_client_hello extension in the outer hello")
+	}
+
+	aad, err := echAAD(outer, len(echExt.payload))
+	if err != nil {
+		c.sendAlert(alertDecodeError)
+		return nil, nil, err
+	}
+
+	for _, echKey := range c.config.EncryptedClientHelloKeys {
+		skip, config, err := parseECHConfig(echKey.Config)
+		if err != nil || skip {
+			c.sendAlert(alertInternalError)
+			return nil, nil, errors.New("tls: invalid EncryptedClientHelloKeys Config")
+		}
+		if config.ConfigID != echExt.configID {
+			continue
+		}
+		var validSCS bool
+		for _, cs := range config.SymmetricCipherSuite {
+			if cs == echExt.cipherSuite {
+				validSCS = true
+				break
+			}
+		}
+		if !validSCS {
+			continue
+		}
+		echPriv, err := hpke.ParseHPKEPrivateKey(config.KemID, echKey.PrivateKey)
+		if err != nil {
+			c.sendAlert(alertInternalError)
+			return nil, nil, errors.New("tls: invalid EncryptedClientHelloKeys PrivateKey")
+		}
+		info := append([]byte("tls ech\x00"), echKey.Config...)
+		hpkeContext, err := hpke.SetupReceiver(config.KemID, echExt.cipherSuite.KDFID, echExt.cipherSuite.AEADID, echPriv, info, echExt.encap)
+		if err != nil {
+			// Trial decryption: the key might simply not be the right one.
+			continue
+		}
+		encodedInner, err := hpkeContext.Open(aad, echExt.payload)
+		if err != nil {
+			continue
+		}
+
+		inner, err := decodeInnerClientHello(outer, encodedInner)
+		if err != nil {
+			c.sendAlert(alertIllegalParameter)
+			return nil, nil, err
+		}
+
+		return inner, &echServerContext{
+			hpkeContext: hpkeContext,
+			configID:    config.ConfigID,
+			ciphersuite: echExt.cipherSuite,
+		}, nil
+	}
+
+	return outer, nil, nil
+}
+
+// processECHClientHelloRetry decrypts the second ClientHelloInner, after a
+// HelloRetryRequest that accepted ECH, using the existing HPKE context.
+func (c *Conn) processECHClientHelloRetry(outer *clientHelloMsg, ech *echServerContext) (*clientHelloMsg, error) {
+	if len(outer.encryptedClientHello) == 0 {
+		c.sendAlert(alertMissingExtension)
+		return nil, errors.New("tls: second client hello is missing the encrypted_client_hello extension")
+	}
+	echType, echExt, err := parseECHExt(outer.encryptedClientHello)
+	if err != nil {
+		c.sendAlert(alertDecodeError)
+		return nil, err
+	}
+	if echType != echTypeOuter || echExt.configID != ech.configID ||
+		echExt.cipherSuite != ech.ciphersuite || len(echExt.encap) != 0 {
+		c.sendAlert(alertIllegalParameter)
+		return nil, errors.New("tls: client changed the encrypted_client_hello extension in the second client hello")
+	}
+	aad, err := echAAD(outer, len(echExt.payload))
+	if err != nil {
+		c.sendAlert(alertDecodeError)
+		return nil, err
+	}
+	encodedInner, err := ech.hpkeContext.Open(aad, echExt.payload)
+	if err != nil {
+		c.sendAlert(alertDecryptError)
+		return nil, errors.New("tls: failed to decrypt second client hello")
+	}
+	inner, err := decodeInnerClientHello(outer, encodedInner)
+	if err != nil {
+		c.sendAlert(alertIllegalParameter)
+		return nil, err
+	}
+	return inner, nil
+}
+
+// echRetryConfigList returns the ECHConfigList made of the configs of the
+// EncryptedClientHelloKeys with SendAsRetry set, or nil if there are none.
+func (c *Conn) echRetryConfigList() ([]byte, error) {
+	var atLeastOneRetryConfig bool
+	var retryBuilder cryptobyte.Builder
+	retryBuilder.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, c := range c.config.EncryptedClientHelloKeys {
+			if !c.SendAsRetry {
+				continue
+			}
+			atLeastOneRetryConfig = true
+			b.AddBytes(c.Config)
+		}
+	})
+	if !atLeastOneRetryConfig {
+		return nil, nil
+	}
+	return retryBuilder.Bytes()
+}
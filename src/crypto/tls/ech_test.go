@@ -0,0 +1,422 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// marshalECHConfig returns an ECHConfig for an X25519 key, supporting all
+// the AEADs this package implements.
+func marshalECHConfig(id uint8, pubKey []byte, publicName string, maxNameLen uint8) []byte {
+	var b cryptobyte.Builder
+	b.AddUint16(extensionEncryptedClientHelloVersion)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(id)
+		b.AddUint16(0x0020) // DHKEM(X25519, HKDF-SHA256)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(pubKey)
+		})
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			for _, aeadID := range []uint16{0x0001, 0x0002, 0x0003} {
+				b.AddUint16(0x0001) // HKDF-SHA256
+				b.AddUint16(aeadID)
+			}
+		})
+		b.AddUint8(maxNameLen)
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes([]byte(publicName))
+		})
+		b.AddUint16(0) // extensions
+	})
+	return b.BytesOrPanic()
+}
+
+func marshalECHConfigList(configs ...[]byte) []byte {
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, c := range configs {
+			b.AddBytes(c)
+		}
+	})
+	return b.BytesOrPanic()
+}
+
+// testECHKey generates a new ECH key and config, and returns the
+// EncryptedClientHelloKey for the server and the ECHConfigList for the client.
+func testECHKey(t *testing.T, id uint8, publicName string) (EncryptedClientHelloKey, []byte) {
+	t.Helper()
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := marshalECHConfig(id, priv.PublicKey().Bytes(), publicName, 32)
+	key := EncryptedClientHelloKey{
+		Config:      config,
+		PrivateKey:  priv.Bytes(),
+		SendAsRetry: true,
+	}
+	return key, marshalECHConfigList(config)
+}
+
+func testECHConfigs(t *testing.T, publicName string) (clientConfig, serverConfig *Config) {
+	t.Helper()
+	key, configList := testECHKey(t, 42, publicName)
+
+	clientConfig = testConfig.Clone()
+	clientConfig.ServerName = "secret.example"
+	clientConfig.MinVersion = VersionTLS13
+	clientConfig.EncryptedClientHelloConfigList = configList
+
+	serverConfig = testConfig.Clone()
+	serverConfig.EncryptedClientHelloKeys = []EncryptedClientHelloKey{key}
+
+	return clientConfig, serverConfig
+}
+
+// echHandshake runs a handshake between a client and a server, returning the
+// unwrapped errors of both sides. The client reads until the server closes
+// the connection, in order to process any session tickets.
+func echHandshake(t *testing.T, clientConfig, serverConfig *Config) (clientState, serverState ConnectionState, clientErr, serverErr error) {
+	c, s := localPipe(t)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server := Server(s, serverConfig)
+		serverErr = server.Handshake()
+		if serverErr == nil {
+			serverState = server.ConnectionState()
+		}
+		server.Close()
+	}()
+	cli := Client(c, clientConfig)
+	clientErr = cli.Handshake()
+	if clientErr == nil {
+		clientState = cli.ConnectionState()
+		io.ReadAll(cli)
+	}
+	cli.Close()
+	<-done
+	return
+}
+
+func TestECHAccepted(t *testing.T) {
+	clientConfig, serverConfig := testECHConfigs(t, "example.golang")
+
+	var serverName string
+	serverConfig.GetConfigForClient = func(chi *ClientHelloInfo) (*Config, error) {
+		serverName = chi.ServerName
+		return nil, nil
+	}
+
+	clientState, serverState, clientErr, serverErr := echHandshake(t, clientConfig, serverConfig)
+	if clientErr != nil || serverErr != nil {
+		t.Fatalf("handshake failed: client: %v, server: %v", clientErr, serverErr)
+	}
+	if !clientState.ECHAccepted || !serverState.ECHAccepted {
+		t.Errorf("ECHAccepted: client %v, server %v; want true", clientState.ECHAccepted, serverState.ECHAccepted)
+	}
+	if serverName != "secret.example" {
+		t.Errorf("GetConfigForClient saw server name %q, want the inner one", serverName)
+	}
+	if serverState.ServerName != "secret.example" || clientState.ServerName != "secret.example" {
+		t.Errorf("ServerName: client %q, server %q; want %q", clientState.ServerName, serverState.ServerName, "secret.example")
+	}
+}
+
+func TestECHHelloRetryRequest(t *testing.T) {
+	clientConfig, serverConfig := testECHConfigs(t, "example.golang")
+	clientConfig.CurvePreferences = []CurveID{X25519, CurveP256}
+	serverConfig.CurvePreferences = []CurveID{CurveP256}
+
+	clientState, serverState, clientErr, serverErr := echHandshake(t, clientConfig, serverConfig)
+	if clientErr != nil || serverErr != nil {
+		t.Fatalf("handshake failed: client: %v, server: %v", clientErr, serverErr)
+	}
+	if !clientState.ECHAccepted || !serverState.ECHAccepted {
+		t.Errorf("ECHAccepted: client %v, server %v; want true", clientState.ECHAccepted, serverState.ECHAccepted)
+	}
+	if serverState.ServerName != "secret.example" {
+		t.Errorf("server ServerName = %q, want %q", serverState.ServerName, "secret.example")
+	}
+}
+
+func TestECHResumption(t *testing.T) {
+	clientConfig, serverConfig := testECHConfigs(t, "example.golang")
+	clientConfig.ClientSessionCache = NewLRUClientSessionCache(1)
+
+	for i, wantResume := range []bool{false, true} {
+		clientState, _, clientErr, serverErr := echHandshake(t, clientConfig, serverConfig)
+		if clientErr != nil || serverErr != nil {
+			t.Fatalf("handshake #%d failed: client: %v, server: %v", i, clientErr, serverErr)
+		}
+		if !clientState.ECHAccepted {
+			t.Errorf("handshake #%d: ECH was not accepted", i)
+		}
+		if clientState.DidResume != wantResume {
+			t.Errorf("handshake #%d: DidResume = %v, want %v", i, clientState.DidResume, wantResume)
+		}
+	}
+}
+
+func TestECHRejected(t *testing.T) {
+	issuer, err := x509.ParseCertificate(testRSACertificateIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(issuer)
+
+	// The server has a different key than the one the client knows about,
+	// so it will reject ECH and send its own config as a retry config.
+	clientConfig, _ := testECHConfigs(t, "example.golang")
+	_, serverConfig := testECHConfigs(t, "example.golang")
+	clientConfig.RootCAs = rootCAs
+	clientConfig.Time = func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+	// InsecureSkipVerify is ignored when ECH is rejected, and the certificate
+	// is verified for the public name.
+	clientConfig.InsecureSkipVerify = true
+	clientConfig.VerifyConnection = func(ConnectionState) error {
+		return errors.New("VerifyConnection called on ECH rejection")
+	}
+
+	_, _, clientErr, serverErr := echHandshake(t, clientConfig, serverConfig)
+	var echErr *ECHRejectionError
+	if !errors.As(clientErr, &echErr) {
+		t.Fatalf("client error = %v, want an ECHRejectionError", clientErr)
+	}
+	wantRetry := marshalECHConfigList(serverConfig.EncryptedClientHelloKeys[0].Config)
+	if !bytes.Equal(echErr.RetryConfigList, wantRetry) {
+		t.Errorf("RetryConfigList = %x, want %x", echErr.RetryConfigList, wantRetry)
+	}
+	if serverErr == nil || !strings.Contains(serverErr.Error(), "encrypted client hello required") {
+		t.Errorf("server error = %v, want an ech_required alert", serverErr)
+	}
+
+	// The certificate is not valid for this public name.
+	clientConfig, _ = testECHConfigs(t, "other.golang")
+	clientConfig.RootCAs = rootCAs
+	clientConfig.Time = func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+	_, _, clientErr, _ = echHandshake(t, clientConfig, serverConfig)
+	if clientErr == nil || errors.As(clientErr, &echErr) {
+		t.Errorf("client error = %v, want a certificate verification error", clientErr)
+	}
+}
+
+func TestECHRejectionVerify(t *testing.T) {
+	clientConfig, _ := testECHConfigs(t, "other.golang")
+	_, serverConfig := testECHConfigs(t, "example.golang")
+	serverConfig.EncryptedClientHelloKeys[0].SendAsRetry = false
+
+	var called bool
+	clientConfig.EncryptedClientHelloRejectionVerify = func(cs ConnectionState) error {
+		called = true
+		if cs.ServerName != "other.golang" {
+			t.Errorf("ServerName = %q, want the public name", cs.ServerName)
+		}
+		if len(cs.PeerCertificates) == 0 {
+			t.Error("no peer certificates")
+		}
+		return nil
+	}
+
+	_, _, clientErr, _ := echHandshake(t, clientConfig, serverConfig)
+	var echErr *ECHRejectionError
+	if !errors.As(clientErr, &echErr) {
+		t.Fatalf("client error = %v, want an ECHRejectionError", clientErr)
+	}
+	if echErr.RetryConfigList != nil {
+		t.Errorf("RetryConfigList = %x, want none", echErr.RetryConfigList)
+	}
+	if !called {
+		t.Error("EncryptedClientHelloRejectionVerify was not called")
+	}
+
+	errReject := errors.New("rejected")
+	clientConfig.EncryptedClientHelloRejectionVerify = func(ConnectionState) error { return errReject }
+	_, _, clientErr, _ = echHandshake(t, clientConfig, serverConfig)
+	if !errors.Is(clientErr, errReject) {
+		t.Errorf("client error = %v, want %v", clientErr, errReject)
+	}
+}
+
+func TestECHGREASE(t *testing.T) {
+	_, serverConfigWithKeys := testECHConfigs(t, "example.golang")
+	for _, serverConfig := range []*Config{testConfig, serverConfigWithKeys} {
+		clientConfig := testConfig.Clone()
+		clientConfig.EncryptedClientHelloGREASE = true
+
+		var sawECH bool
+		serverConfig = serverConfig.Clone()
+		serverConfig.GetConfigForClient = func(chi *ClientHelloInfo) (*Config, error) {
+			sawECH = true
+			return nil, nil
+		}
+
+		clientState, serverState, clientErr, serverErr := echHandshake(t, clientConfig, serverConfig)
+		if clientErr != nil || serverErr != nil {
+			t.Fatalf("handshake failed: client: %v, server: %v", clientErr, serverErr)
+		}
+		if clientState.ECHAccepted || serverState.ECHAccepted {
+			t.Errorf("ECHAccepted: client %v, server %v; want false", clientState.ECHAccepted, serverState.ECHAccepted)
+		}
+		if !sawECH {
+			t.Error("GetConfigForClient was not called")
+		}
+	}
+}
+
+func TestECHClientVersions(t *testing.T) {
+	_, configList := testECHKey(t, 1, "example.golang")
+	for _, v := range [][2]uint16{{VersionTLS12, 0}, {0, VersionTLS12}} {
+		config := testConfig.Clone()
+		config.MinVersion, config.MaxVersion = v[0], v[1]
+		config.EncryptedClientHelloConfigList = configList
+		c, s := localPipe(t)
+		s.Close()
+		if err := Client(c, config).Handshake(); err == nil || !strings.Contains(err.Error(), "VersionTLS13") {
+			t.Errorf("MinVersion %x, MaxVersion %x: got error %v, want a version error", v[0], v[1], err)
+		}
+		c.Close()
+	}
+}
+
+func TestParseECHConfigList(t *testing.T) {
+	pub := bytes.Repeat([]byte{0x42}, 32)
+	valid := marshalECHConfig(1, pub, "example.golang", 32)
+	unknownVersion := append([]byte{0xfe, 0x0c, 0x00, 0x02}, 0xaa, 0xbb)
+
+	configs, err := parseECHConfigList(marshalECHConfigList(unknownVersion, valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 1 {
+		t.Fatalf("got %d configs, want 1", len(configs))
+	}
+	c := configs[0]
+	if c.ConfigID != 1 || c.KemID != 0x0020 || !bytes.Equal(c.PublicKey, pub) ||
+		string(c.PublicName) != "example.golang" || c.MaxNameLength != 32 ||
+		len(c.SymmetricCipherSuite) != 3 || !bytes.Equal(c.raw, valid) {
+		t.Errorf("unexpected parsed config: %+v", c)
+	}
+	if pickECHConfig(configs) == nil {
+		t.Error("pickECHConfig found no usable config")
+	}
+
+	for _, list := range [][]byte{
+		nil,
+		{0x00},
+		{0x00, 0x05, 0xfe, 0x0d, 0x00},
+		append(marshalECHConfigList(valid), 0x00),
+		marshalECHConfigList(valid[:len(valid)-1]),
+	} {
+		if _, err := parseECHConfigList(list); err == nil {
+			t.Errorf("parseECHConfigList(%x) succeeded, want error", list)
+		}
+	}
+
+	for _, publicName := range []string{"", "192.0.2.1", "example.123", "-example.golang", "exa mple.golang"} {
+		configs, err := parseECHConfigList(marshalECHConfigList(marshalECHConfig(1, pub, publicName, 32)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pickECHConfig(configs) != nil {
+			t.Errorf("pickECHConfig accepted public name %q", publicName)
+		}
+	}
+}
+
+func TestDecodeInnerClientHello(t *testing.T) {
+	outer := &clientHelloMsg{
+		vers:                         VersionTLS12,
+		random:                       make([]byte, 32),
+		sessionId:                    bytes.Repeat([]byte{0x11}, 32),
+		cipherSuites:                 []uint16{TLS_AES_128_GCM_SHA256},
+		compressionMethods:           []uint8{compressionNone},
+		serverName:                   "example.golang",
+		supportedCurves:              []CurveID{X25519},
+		supportedSignatureAlgorithms: []SignatureScheme{Ed25519},
+		supportedVersions:            []uint16{VersionTLS13},
+		encryptedClientHello:         []byte{echTypeOuter},
+	}
+
+	encodeInner := func(outerExts []uint16, padding []byte) []byte {
+		var b cryptobyte.Builder
+		b.AddUint16(VersionTLS12)
+		b.AddBytes(bytes.Repeat([]byte{0x22}, 32))
+		b.AddUint8(0) // empty legacy_session_id
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(TLS_AES_128_GCM_SHA256)
+		})
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint8(compressionNone)
+		})
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(extensionServerName)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint8(0) // name_type = host_name
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddBytes([]byte("secret.example"))
+					})
+				})
+			})
+			b.AddUint16(extensionECHOuterExtensions)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+					for _, e := range outerExts {
+						b.AddUint16(e)
+					}
+				})
+			})
+			b.AddUint16(extensionEncryptedClientHello)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint8(echTypeInner)
+			})
+		})
+		b.AddBytes(padding)
+		return b.BytesOrPanic()
+	}
+
+	inner, err := decodeInnerClientHello(outer, encodeInner([]uint16{
+		extensionSupportedCurves, extensionSignatureAlgorithms, extensionSupportedVersions,
+	}, make([]byte, 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.serverName != "secret.example" {
+		t.Errorf("serverName = %q, want the inner one", inner.serverName)
+	}
+	if !bytes.Equal(inner.sessionId, outer.sessionId) {
+		t.Errorf("sessionId = %x, want the outer one", inner.sessionId)
+	}
+	if len(inner.supportedCurves) != 1 || inner.supportedCurves[0] != X25519 ||
+		len(inner.supportedSignatureAlgorithms) != 1 || inner.supportedSignatureAlgorithms[0] != Ed25519 ||
+		len(inner.supportedVersions) != 1 || inner.supportedVersions[0] != VersionTLS13 {
+		t.Errorf("outer extensions were not decompressed: %+v", inner)
+	}
+
+	for name, encoded := range map[string][]byte{
+		"non-zero padding":   encodeInner([]uint16{extensionSupportedVersions}, []byte{0, 1}),
+		"ECH reference":      encodeInner([]uint16{extensionSupportedVersions, extensionEncryptedClientHello}, nil),
+		"missing reference":  encodeInner([]uint16{extensionSupportedVersions, extensionALPN}, nil),
+		"out of order":       encodeInner([]uint16{extensionSupportedVersions, extensionSupportedCurves}, nil),
+		"no TLS 1.3 offered": encodeInner([]uint16{extensionSupportedCurves}, nil),
+	} {
+		if _, err := decodeInnerClientHello(outer, encoded); err == nil {
+			t.Errorf("%s: decodeInnerClientHello succeeded, want error", name)
+		}
+	}
+}
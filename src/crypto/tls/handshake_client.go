@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"context"
 	"crypto"
+	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/rsa"
@@ -34,27 +35,39 @@ type clientHandshakeState struct {
 	session      *ClientSessionState
 }
 
-func (c *Conn) makeClientHello() (*clientHelloMsg, ecdheParameters, error) {
+func (c *Conn) makeClientHello() (*clientHelloMsg, *ecdh.PrivateKey, *echClientContext, error) {
 	config := c.config
 	if len(config.ServerName) == 0 && !config.InsecureSkipVerify {
-		return nil, nil, errors.New("tls: either ServerName or InsecureSkipVerify must be specified in the tls.Config")
+		return nil, nil, nil, errors.New("tls: either ServerName or InsecureSkipVerify must be specified in the tls.Config")
 	}
 
 	nextProtosLength := 0
 	for _, proto := range config.NextProtos {
 		if l := len(proto); l == 0 || l > 255 {
-			return nil, nil, errors.New("tls: invalid NextProtos value")
+			return nil, nil, nil, errors.New("tls: invalid NextProtos value")
 		} else {
 			nextProtosLength += 1 + l
 		}
 	}
 	if nextProtosLength > 0xffff {
-		return nil, nil, errors.New("tls: NextProtos values too large")
+		return nil, nil, nil, errors.New("tls: NextProtos values too large")
 	}
 
 	supportedVersions := config.supportedVersions(roleClient)
 	if len(supportedVersions) == 0 {
-		return nil, nil, errors.New("tls: no supported versions satisfy MinVersion and MaxVersion")
+		return nil, nil, nil, errors.New("tls: no supported versions satisfy MinVersion and MaxVersion")
+	}
+
+	if len(config.EncryptedClientHelloConfigList) > 0 {
+		if config.MinVersion != 0 && config.MinVersion < VersionTLS13 {
+			return nil, nil, nil, errors.New("tls: MinVersion must be VersionTLS13 if EncryptedClientHelloConfigList is set")
+		}
+		if supportedVersions[0] != VersionTLS13 {
+			return nil, nil, nil, errors.New("tls: MaxVersion must be VersionTLS13 if EncryptedClientHelloConfigList is set")
+		}
+		// The ClientHelloInner must not offer versions below TLS 1.3.
+		// See draft-ietf-tls-esni, Section 6.1.
+		supportedVersions = []uint16{VersionTLS13}
 	}
 
 	clientHelloVersion := config.maxSupportedVersion(roleClient)
@@ -69,7 +82,6 @@ func (c *Conn) makeClientHello() (*clientHelloMsg, ecdheParameters, error) {
 		vers:                         clientHelloVersion,
 		compressionMethods:           []uint8{compressionNone},
 		random:                       make([]byte, 32),
-		sessionId:                    make([]byte, 32),
 		ocspStapling:                 true,
 		scts:                         true,
 		serverName:                   hostnameInSNI(config.ServerName),
@@ -106,21 +118,26 @@ func (c *Conn) makeClientHello() (*clientHelloMsg, ecdheParameters, error) {
 
 	_, err := io.ReadFull(config.rand(), hello.random)
 	if err != nil {
-		return nil, nil, errors.New("tls: short read from Rand: " + err.Error())
+		return nil, nil, nil, errors.New("tls: short read from Rand: " + err.Error())
 	}
 
 	// A random session ID is used to detect when the server accepted a ticket
 	// and is resuming a session (see RFC 5077). In TLS 1.3, it's always set as
 	// a compatibility measure (see RFC 8446, Section 4.1.2).
-	if _, err := io.ReadFull(config.rand(), hello.sessionId); err != nil {
-		return nil, nil, errors.New("tls: short read from Rand: " + err.Error())
+	//
+	// The session ID is not set for QUIC connections (see RFC 9001, Section 8.4).
+	if c.quic == nil {
+		hello.sessionId = make([]byte, 32)
+		if _, err := io.ReadFull(config.rand(), hello.sessionId); err != nil {
+			return nil, nil, nil, errors.New("tls: short read from Rand: " + err.Error())
+		}
 	}
 
 	if hello.vers >= VersionTLS12 {
 		hello.supportedSignatureAlgorithms = supportedSignatureAlgorithms
 	}
 
-	var params ecdheParameters
+	var key *ecdh.PrivateKey
 	if hello.supportedVersions[0] == VersionTLS13 {
 		if hasAESGCMHardwareSupport {
 			hello.cipherSuites = append(hello.cipherSuites, defaultCipherSuitesTLS13...)
@@ -129,17 +146,39 @@ func (c *Conn) makeClientHello() (*clientHelloMsg, ecdheParameters, error) {
 		}
 
 		curveID := config.curvePreferences()[0]
-		if _, ok := curveForCurveID(curveID); curveID != X25519 && !ok {
-			return nil, nil, errors.New("tls: CurvePreferences includes unsupported curve")
+		if _, ok := curveForCurveID(curveID); !ok {
+			return nil, nil, nil, errors.New("tls: CurvePreferences includes unsupported curve")
 		}
-		params, err = generateECDHEParameters(config.rand(), curveID)
+		key, err = generateECDHEKey(config.rand(), curveID)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
-		hello.keyShares = []keyShare{{group: curveID, data: params.PublicKey()}}
+		hello.keyShares = []keyShare{{group: curveID, data: key.PublicKey().Bytes()}}
 	}
 
-	return hello, params, nil
+	if c.quic != nil {
+		p, err := c.quicGetTransportParameters()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		hello.quicTransportParameters = p
+	}
+
+	var ech *echClientContext
+	if len(config.EncryptedClientHelloConfigList) > 0 {
+		ech, err = c.newECHClientContext()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		hello.encryptedClientHello = []byte{echTypeInner}
+	} else if config.EncryptedClientHelloGREASE && hello.supportedVersions[0] == VersionTLS13 {
+		hello.encryptedClientHello, err = c.greaseECHExtension(hello)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return hello, key, ech, nil
 }
 
 func (c *Conn) clientHandshake(ctx context.Context) (err error) {
@@ -151,7 +190,7 @@ func (c *Conn) clientHandshake(ctx context.Context) (err error) {
 	// need to be reset.
 	c.didResume = false
 
-	hello, ecdheParams, err := c.makeClientHello()
+	hello, ecdheKey, ech, err := c.makeClientHello()
 	if err != nil {
 		return err
 	}
@@ -172,10 +211,30 @@ func (c *Conn) clientHandshake(ctx context.Context) (err error) {
 		}()
 	}
 
-	if _, err := c.writeRecord(recordTypeHandshake, hello.marshal()); err != nil {
+	// With ECH, the ClientHelloInner is complete, including PSK binders, and
+	// the ClientHelloOuter that carries it is the one sent on the wire.
+	wireHello := hello
+	if ech != nil {
+		ech.innerHello = hello
+		ech.outerHello, err = c.makeOuterClientHello(hello, ech)
+		if err != nil {
+			return err
+		}
+		wireHello = ech.outerHello
+	}
+
+	if _, err := c.writeRecord(recordTypeHandshake, wireHello.marshal()); err != nil {
 		return err
 	}
 
+	if hello.earlyData {
+		suite := cipherSuiteTLS13ByID(session.cipherSuite)
+		transcript := suite.hash.New()
+		transcript.Write(hello.marshal())
+		earlyTrafficSecret := suite.deriveSecret(earlySecret, clientEarlyTrafficLabel, transcript)
+		c.quicSetWriteSecret(QUICEncryptionLevelEarly, suite.id, earlyTrafficSecret)
+	}
+
 	msg, err := c.readHandshake()
 	if err != nil {
 		return err
@@ -208,11 +267,12 @@ func (c *Conn) clientHandshake(ctx context.Context) (err error) {
 			c:           c,
 			ctx:         ctx,
 			serverHello: serverHello,
-			hello:       hello,
-			ecdheParams: ecdheParams,
+			hello:       wireHello,
+			ecdheKey:    ecdheKey,
 			session:     session,
 			earlySecret: earlySecret,
 			binderKey:   binderKey,
+			echContext:  ech,
 		}
 
 		// In TLS 1.3, session tickets are delivered after the handshake.
@@ -262,7 +322,10 @@ func (c *Conn) loadSession(hello *clientHelloMsg) (cacheKey string,
 	}
 
 	// Try to resume a previously negotiated TLS session, if available.
-	cacheKey = clientSessionCacheKey(c.conn.RemoteAddr(), c.config)
+	cacheKey = c.clientSessionCacheKey()
+	if cacheKey == "" {
+		return "", nil, nil, nil
+	}
 	session, ok := c.config.ClientSessionCache.Get(cacheKey)
 	if !ok || session == nil {
 		return cacheKey, nil, nil, nil
@@ -334,6 +397,18 @@ func (c *Conn) loadSession(hello *clientHelloMsg) (cacheKey string,
 		return cacheKey, nil, nil, nil
 	}
 
+	// Offer 0-RTT if the ticket allows it, and we are offering the same
+	// cipher suite and ALPN protocol. See RFC 9001, Section 4.6.1.
+	if c.quic != nil && session.earlyData &&
+		mutualCipherSuiteTLS13(hello.cipherSuites, session.cipherSuite) != nil {
+		for _, proto := range hello.alpnProtocols {
+			if proto == session.alpnProtocol {
+				hello.earlyData = true
+				break
+			}
+		}
+	}
+
 	// Set the pre_shared_key extension. See RFC 8446, Section 4.2.11.1.
 	ticketAge := uint32(c.config.time().Sub(session.receivedAt) / time.Millisecond)
 	identity := pskIdentity{
@@ -854,11 +929,25 @@ func (c *Conn) verifyServerCertificate(certificates [][]byte) error {
 		certs[i] = cert
 	}
 
-	if !c.config.InsecureSkipVerify {
+	// If ECH was offered and rejected, the certificate must be valid for the
+	// public name in the ECHConfig, which is in c.serverName by now. See
+	// draft-ietf-tls-esni, Section 6.1.7.
+	echRejected := len(c.config.EncryptedClientHelloConfigList) > 0 && !c.echAccepted
+	if echRejected && c.config.EncryptedClientHelloRejectionVerify != nil {
+		c.peerCertificates = certs
+		if err := c.config.EncryptedClientHelloRejectionVerify(c.connectionStateLocked()); err != nil {
+			c.sendAlert(alertBadCertificate)
+			return err
+		}
+	} else if echRejected || !c.config.InsecureSkipVerify {
+		dnsName := c.config.ServerName
+		if echRejected {
+			dnsName = c.serverName
+		}
 		opts := x509.VerifyOptions{
 			Roots:         c.config.RootCAs,
 			CurrentTime:   c.config.time(),
-			DNSName:       c.config.ServerName,
+			DNSName:       dnsName,
 			Intermediates: x509.NewCertPool(),
 		}
 		for _, cert := range certs[1:] {
@@ -882,6 +971,10 @@ func (c *Conn) verifyServerCertificate(certificates [][]byte) error {
 
 	c.peerCertificates = certs
 
+	if echRejected {
+		return nil
+	}
+
 	if c.config.VerifyPeerCertificate != nil {
 		if err := c.config.VerifyPeerCertificate(certificates, c.verifiedChains); err != nil {
 			c.sendAlert(alertBadCertificate)
@@ -983,11 +1076,16 @@ func (c *Conn) getClientCertificate(cri *CertificateRequestInfo) (*Certificate,
 
 // clientSessionCacheKey returns a key used to cache sessionTickets that could
 // be used to resume previously negotiated TLS sessions with a server.
-func clientSessionCacheKey(serverAddr net.Addr, config *Config) string {
-	if len(config.ServerName) > 0 {
-		return config.ServerName
+// It returns "" if there is no suitable key, which is the case for QUIC
+// connections without a ServerName.
+func (c *Conn) clientSessionCacheKey() string {
+	if len(c.config.ServerName) > 0 {
+		return c.config.ServerName
+	}
+	if c.conn != nil {
+		return c.conn.RemoteAddr().String()
 	}
-	return serverAddr.String()
+	return ""
 }
 
 // hostnameInSNI converts name into an appropriate hostname for SNI.
@@ -8,8 +8,10 @@ import (
 	"bytes"
 	"context"
 	"crypto"
+	"crypto/ecdh"
 	"crypto/hmac"
 	"crypto/rsa"
+	"crypto/subtle"
 	"errors"
 	"hash"
 	"sync/atomic"
@@ -21,12 +23,16 @@ type clientHandshakeStateTLS13 struct {
 	ctx         context.Context
 	serverHello *serverHelloMsg
 	hello       *clientHelloMsg
-	ecdheParams ecdheParameters
+	ecdheKey    *ecdh.PrivateKey
 
 	session     *ClientSessionState
 	earlySecret []byte
 	binderKey   []byte
 
+	// echContext is set if the client offered ECH, in which case hs.hello
+	// is the ClientHelloOuter until the server accepts ECH.
+	echContext *echClientContext
+
 	certReq       *certificateRequestMsgTLS13
 	usingPSK      bool
 	sentDummyCCS  bool
@@ -36,8 +42,9 @@ type clientHandshakeStateTLS13 struct {
 	trafficSecret []byte // client_application_traffic_secret_0
 }
 
-// handshake requires hs.c, hs.hello, hs.serverHello, hs.ecdheParams, and,
-// optionally, hs.session, hs.earlySecret and hs.binderKey to be set.
+// handshake requires hs.c, hs.hello, hs.serverHello, hs.ecdheKey, and,
+// optionally, hs.session, hs.earlySecret, hs.binderKey and hs.echContext
+// to be set.
 func (hs *clientHandshakeStateTLS13) handshake() error {
 	c := hs.c
 
@@ -49,7 +56,7 @@ func (hs *clientHandshakeStateTLS13) handshake() error {
 	}
 
 	// Consistency check on the presence of a keyShare and its parameters.
-	if hs.ecdheParams == nil || len(hs.hello.keyShares) != 1 {
+	if hs.ecdheKey == nil || len(hs.hello.keyShares) != 1 {
 		return c.sendAlert(alertInternalError)
 	}
 
@@ -60,6 +67,11 @@ func (hs *clientHandshakeStateTLS13) handshake() error {
 	hs.transcript = hs.suite.hash.New()
 	hs.transcript.Write(hs.hello.marshal())
 
+	if hs.echContext != nil {
+		hs.echContext.innerTranscript = hs.suite.hash.New()
+		hs.echContext.innerTranscript.Write(hs.echContext.innerHello.marshal())
+	}
+
 	if bytes.Equal(hs.serverHello.random, helloRetryRequestRandom) {
 		if err := hs.sendDummyChangeCipherSpec(); err != nil {
 			return err
@@ -69,6 +81,10 @@ func (hs *clientHandshakeStateTLS13) handshake() error {
 		}
 	}
 
+	if err := hs.checkECHAcceptance(); err != nil {
+		return err
+	}
+
 	hs.transcript.Write(hs.serverHello.marshal())
 
 	c.buffering = true
@@ -90,6 +106,12 @@ func (hs *clientHandshakeStateTLS13) handshake() error {
 	if err := hs.readServerFinished(); err != nil {
 		return err
 	}
+	if hs.echContext != nil && hs.echContext.echRejected {
+		// The server authenticated as the client-facing server, so the retry
+		// configs can be trusted. See draft-ietf-tls-esni, Section 6.1.6.
+		c.sendAlert(alertECHRequired)
+		return &ECHRejectionError{RetryConfigList: hs.echContext.retryConfigs}
+	}
 	if err := hs.sendClientCertificate(); err != nil {
 		return err
 	}
@@ -163,6 +185,9 @@ func (hs *clientHandshakeStateTLS13) checkServerHelloOrHRR() error {
 // sendDummyChangeCipherSpec sends a ChangeCipherSpec record for compatibility
 // with middleboxes that didn't implement TLS correctly. See RFC 8446, Appendix D.4.
 func (hs *clientHandshakeStateTLS13) sendDummyChangeCipherSpec() error {
+	if hs.c.quic != nil {
+		return nil
+	}
 	if hs.sentDummyCCS {
 		return nil
 	}
@@ -177,6 +202,10 @@ func (hs *clientHandshakeStateTLS13) sendDummyChangeCipherSpec() error {
 func (hs *clientHandshakeStateTLS13) processHelloRetryRequest() error {
 	c := hs.c
 
+	if err := hs.checkECHAcceptanceHRR(); err != nil {
+		return err
+	}
+
 	// The first ClientHello gets double-hashed into the transcript upon a
 	// HelloRetryRequest. (The idea is that the server might offload transcript
 	// storage to the client in the cookie.) See RFC 8446, Section 4.4.1.
@@ -218,21 +247,28 @@ func (hs *clientHandshakeStateTLS13) processHelloRetryRequest() error {
 			c.sendAlert(alertIllegalParameter)
 			return errors.New("tls: server selected unsupported group")
 		}
-		if hs.ecdheParams.CurveID() == curveID {
+		if sentID, _ := curveIDForCurve(hs.ecdheKey.Curve()); sentID == curveID {
 			c.sendAlert(alertIllegalParameter)
 			return errors.New("tls: server sent an unnecessary HelloRetryRequest key_share")
 		}
-		if _, ok := curveForCurveID(curveID); curveID != X25519 && !ok {
+		if _, ok := curveForCurveID(curveID); !ok {
 			c.sendAlert(alertInternalError)
 			return errors.New("tls: CurvePreferences includes unsupported curve")
 		}
-		params, err := generateECDHEParameters(c.config.rand(), curveID)
+		key, err := generateECDHEKey(c.config.rand(), curveID)
 		if err != nil {
 			c.sendAlert(alertInternalError)
 			return err
 		}
-		hs.ecdheParams = params
-		hs.hello.keyShares = []keyShare{{group: curveID, data: params.PublicKey()}}
+		hs.ecdheKey = key
+		hs.hello.keyShares = []keyShare{{group: curveID, data: key.PublicKey().Bytes()}}
+	}
+
+	if hs.hello.earlyData {
+		// Early data can't be sent in the second ClientHello.
+		// See RFC 8446, Section 4.2.10.
+		hs.hello.earlyData = false
+		c.quicRejectedEarlyData()
 	}
 
 	hs.hello.raw = nil
@@ -261,7 +297,21 @@ func (hs *clientHandshakeStateTLS13) processHelloRetryRequest() error {
 	}
 
 	hs.transcript.Write(hs.hello.marshal())
-	if _, err := c.writeRecord(recordTypeHandshake, hs.hello.marshal()); err != nil {
+	wireHello := hs.hello
+	if hs.echContext != nil && hs.echContext.acceptedInHRR {
+		// Mirror the changes in the ClientHelloOuter, and encrypt the new
+		// ClientHelloInner with the same HPKE context, without repeating the
+		// encapsulated key. See draft-ietf-tls-esni, Section 6.1.5.
+		outer := hs.echContext.outerHello
+		outer.keyShares = hs.hello.keyShares
+		outer.cookie = hs.hello.cookie
+		if err := computeAndUpdateOuterECHExtension(outer, hs.hello, hs.echContext, false); err != nil {
+			c.sendAlert(alertInternalError)
+			return err
+		}
+		wireHello = outer
+	}
+	if _, err := c.writeRecord(recordTypeHandshake, wireHello.marshal()); err != nil {
 		return err
 	}
 
@@ -284,6 +334,111 @@ func (hs *clientHandshakeStateTLS13) processHelloRetryRequest() error {
 	return nil
 }
 
+// checkECHAcceptanceHRR checks the ECH acceptance signal in the
+// HelloRetryRequest in hs.serverHello. If ECH was accepted, it switches
+// hs.hello and hs.transcript to the ClientHelloInner.
+func (hs *clientHandshakeStateTLS13) checkECHAcceptanceHRR() error {
+	c := hs.c
+	ech := hs.echContext
+	confirmation := hs.serverHello.encryptedClientHello
+
+	if ech == nil {
+		if confirmation != nil {
+			c.sendAlert(alertUnsupportedExtension)
+			return errors.New("tls: server sent an unexpected encrypted_client_hello extension")
+		}
+		return nil
+	}
+	if confirmation != nil && len(confirmation) != echAcceptConfirmationLength {
+		c.sendAlert(alertDecodeError)
+		return errors.New("tls: received malformed encrypted_client_hello extension")
+	}
+	if confirmation == nil {
+		ech.echRejected = true
+		return nil
+	}
+
+	// The confirmation is computed over the HelloRetryRequest with the
+	// extension payload replaced by zeroes. See draft-ietf-tls-esni,
+	// Section 7.2.1.
+	hrr := hs.serverHello.marshal()
+	exts, ok := helloExtensions(hrr)
+	if !ok {
+		c.sendAlert(alertDecodeError)
+		return errors.New("tls: received malformed HelloRetryRequest")
+	}
+	zeroed := make([]byte, len(hrr))
+	copy(zeroed, hrr)
+	for _, ext := range exts {
+		if ext.extType == extensionEncryptedClientHello {
+			copy(zeroed[ext.offset:], make([]byte, echAcceptConfirmationLength))
+		}
+	}
+
+	innerHash := ech.innerTranscript.Sum(nil)
+	transcript := hs.suite.hash.New()
+	transcript.Write([]byte{typeMessageHash, 0, 0, uint8(len(innerHash))})
+	transcript.Write(innerHash)
+	transcript.Write(zeroed)
+	expected := echAcceptConfirmation(hs.suite, ech.innerHello.random, echAcceptConfirmationHRRLabel, transcript)
+	if subtle.ConstantTimeCompare(expected, confirmation) != 1 {
+		ech.echRejected = true
+		return nil
+	}
+
+	ech.acceptedInHRR = true
+	hs.hello = ech.innerHello
+	hs.transcript = ech.innerTranscript
+	return nil
+}
+
+// checkECHAcceptance checks the ECH acceptance signal in the ServerHello in
+// hs.serverHello, and switches hs.hello and hs.transcript to the
+// ClientHelloInner if ECH was accepted. It must be called before
+// hs.serverHello is added to the transcript.
+func (hs *clientHandshakeStateTLS13) checkECHAcceptance() error {
+	c := hs.c
+	ech := hs.echContext
+	if ech == nil || ech.echRejected {
+		if ech != nil {
+			c.serverName = string(ech.config.PublicName)
+		}
+		return nil
+	}
+
+	// The confirmation replaces the last 8 bytes of the ServerHello random,
+	// which are at offset 30 of the marshaled message, and is computed over
+	// the ServerHello with those bytes set to zero.
+	sh := hs.serverHello.marshal()
+	if len(sh) < 38 {
+		c.sendAlert(alertDecodeError)
+		return errors.New("tls: received malformed ServerHello")
+	}
+	transcript := cloneHash(ech.innerTranscript, hs.suite.hash)
+	if transcript == nil {
+		c.sendAlert(alertInternalError)
+		return errors.New("tls: internal error: failed to clone hash")
+	}
+	transcript.Write(sh[:30])
+	transcript.Write(make([]byte, echAcceptConfirmationLength))
+	transcript.Write(sh[38:])
+	expected := echAcceptConfirmation(hs.suite, ech.innerHello.random, echAcceptConfirmationLabel, transcript)
+	if subtle.ConstantTimeCompare(expected, hs.serverHello.random[24:]) != 1 {
+		if ech.acceptedInHRR {
+			c.sendAlert(alertIllegalParameter)
+			return errors.New("tls: server accepted ECH in the HelloRetryRequest but not in the ServerHello")
+		}
+		ech.echRejected = true
+		c.serverName = string(ech.config.PublicName)
+		return nil
+	}
+
+	c.echAccepted = true
+	hs.hello = ech.innerHello
+	hs.transcript = ech.innerTranscript
+	return nil
+}
+
 func (hs *clientHandshakeStateTLS13) processServerHello() error {
 	c := hs.c
 
@@ -302,11 +457,16 @@ func (hs *clientHandshakeStateTLS13) processServerHello() error {
 		return errors.New("tls: malformed key_share extension")
 	}
 
+	if hs.serverHello.encryptedClientHello != nil {
+		c.sendAlert(alertUnsupportedExtension)
+		return errors.New("tls: server sent an encrypted_client_hello extension in a normal ServerHello")
+	}
+
 	if hs.serverHello.serverShare.group == 0 {
 		c.sendAlert(alertIllegalParameter)
 		return errors.New("tls: server did not send a key share")
 	}
-	if hs.serverHello.serverShare.group != hs.ecdheParams.CurveID() {
+	if sentID, _ := curveIDForCurve(hs.ecdheKey.Curve()); hs.serverHello.serverShare.group != sentID {
 		c.sendAlert(alertIllegalParameter)
 		return errors.New("tls: server selected unsupported group")
 	}
@@ -344,8 +504,13 @@ func (hs *clientHandshakeStateTLS13) processServerHello() error {
 func (hs *clientHandshakeStateTLS13) establishHandshakeKeys() error {
 	c := hs.c
 
-	sharedKey := hs.ecdheParams.SharedKey(hs.serverHello.serverShare.data)
-	if sharedKey == nil {
+	peerKey, err := hs.ecdheKey.Curve().NewPublicKey(hs.serverHello.serverShare.data)
+	if err != nil {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: invalid server key share")
+	}
+	sharedKey, err := hs.ecdheKey.ECDH(peerKey)
+	if err != nil {
 		c.sendAlert(alertIllegalParameter)
 		return errors.New("tls: invalid server key share")
 	}
@@ -359,12 +524,20 @@ func (hs *clientHandshakeStateTLS13) establishHandshakeKeys() error {
 
 	clientSecret := hs.suite.deriveSecret(handshakeSecret,
 		clientHandshakeTrafficLabel, hs.transcript)
-	c.out.setTrafficSecret(hs.suite, clientSecret)
+	c.out.setTrafficSecret(hs.suite, QUICEncryptionLevelHandshake, clientSecret)
 	serverSecret := hs.suite.deriveSecret(handshakeSecret,
 		serverHandshakeTrafficLabel, hs.transcript)
-	c.in.setTrafficSecret(hs.suite, serverSecret)
+	c.in.setTrafficSecret(hs.suite, QUICEncryptionLevelHandshake, serverSecret)
 
-	err := c.config.writeKeyLog(keyLogLabelClientHandshake, hs.hello.random, clientSecret)
+	if c.quic != nil {
+		if err := c.quicCheckLevelChange(); err != nil {
+			return err
+		}
+		c.quicSetWriteSecret(QUICEncryptionLevelHandshake, hs.suite.id, clientSecret)
+		c.quicSetReadSecret(QUICEncryptionLevelHandshake, hs.suite.id, serverSecret)
+	}
+
+	err = c.config.writeKeyLog(keyLogLabelClientHandshake, hs.hello.random, clientSecret)
 	if err != nil {
 		c.sendAlert(alertInternalError)
 		return err
@@ -402,6 +575,59 @@ func (hs *clientHandshakeStateTLS13) readServerParameters() error {
 	}
 	c.clientProtocol = encryptedExtensions.alpnProtocol
 
+	if c.quic != nil {
+		if encryptedExtensions.quicTransportParameters == nil {
+			// RFC 9001, Section 8.2.
+			c.sendAlert(alertMissingExtension)
+			return errors.New("tls: server did not send a quic_transport_parameters extension")
+		}
+		c.quicSetTransportParameters(encryptedExtensions.quicTransportParameters)
+	} else {
+		if encryptedExtensions.quicTransportParameters != nil {
+			c.sendAlert(alertUnsupportedExtension)
+			return errors.New("tls: server sent an unexpected quic_transport_parameters extension")
+		}
+	}
+
+	if len(encryptedExtensions.echRetryConfigs) > 0 {
+		switch {
+		case hs.echContext != nil && hs.echContext.echRejected:
+			hs.echContext.retryConfigs = encryptedExtensions.echRetryConfigs
+		case hs.echContext == nil && hs.hello.encryptedClientHello != nil:
+			// We sent a GREASE extension, and the server rejected it as it
+			// should. Ignore the retry configs. See draft-ietf-tls-esni,
+			// Section 6.2.
+		default:
+			c.sendAlert(alertUnsupportedExtension)
+			return errors.New("tls: server sent an unexpected encrypted_client_hello extension")
+		}
+	}
+
+	if !hs.hello.earlyData && encryptedExtensions.earlyData {
+		c.sendAlert(alertUnsupportedExtension)
+		return errors.New("tls: server sent an unexpected early_data extension")
+	}
+	if hs.hello.earlyData && !encryptedExtensions.earlyData {
+		c.quicRejectedEarlyData()
+	}
+	if encryptedExtensions.earlyData {
+		// The server may only accept 0-RTT for the first PSK, and only if the
+		// connection parameters match the ones of the original session.
+		// See RFC 8446, Section 4.2.10.
+		if !hs.usingPSK || hs.serverHello.selectedIdentity != 0 {
+			c.sendAlert(alertIllegalParameter)
+			return errors.New("tls: server accepted 0-RTT without accepting the first PSK")
+		}
+		if hs.session.cipherSuite != c.cipherSuite {
+			c.sendAlert(alertHandshakeFailure)
+			return errors.New("tls: server accepted 0-RTT with the wrong cipher suite")
+		}
+		if hs.session.alpnProtocol != c.clientProtocol {
+			c.sendAlert(alertHandshakeFailure)
+			return errors.New("tls: server accepted 0-RTT with the wrong ALPN")
+		}
+	}
+
 	return nil
 }
 
@@ -522,7 +748,15 @@ func (hs *clientHandshakeStateTLS13) readServerFinished() error {
 		clientApplicationTrafficLabel, hs.transcript)
 	serverSecret := hs.suite.deriveSecret(hs.masterSecret,
 		serverApplicationTrafficLabel, hs.transcript)
-	c.in.setTrafficSecret(hs.suite, serverSecret)
+	c.in.setTrafficSecret(hs.suite, QUICEncryptionLevelApplication, serverSecret)
+
+	if c.quic != nil {
+		// The read secret is handed to the QUIC layer when the handshake
+		// completes. See Conn.handshakeContext.
+		if err := c.quicCheckLevelChange(); err != nil {
+			return err
+		}
+	}
 
 	err = c.config.writeKeyLog(keyLogLabelClientTraffic, hs.hello.random, hs.trafficSecret)
 	if err != nil {
@@ -621,7 +855,11 @@ func (hs *clientHandshakeStateTLS13) sendClientFinished() error {
 		return err
 	}
 
-	c.out.setTrafficSecret(hs.suite, hs.trafficSecret)
+	c.out.setTrafficSecret(hs.suite, QUICEncryptionLevelApplication, hs.trafficSecret)
+
+	if c.quic != nil {
+		c.quicSetWriteSecret(QUICEncryptionLevelApplication, hs.suite.id, hs.trafficSecret)
+	}
 
 	if !c.config.SessionTicketsDisabled && c.config.ClientSessionCache != nil {
 		c.resumptionSecret = hs.suite.deriveSecret(hs.masterSecret,
@@ -645,6 +883,12 @@ func (c *Conn) handleNewSessionTicket(msg *newSessionTicketMsgTLS13) error {
 	if msg.lifetime == 0 {
 		return nil
 	}
+	// QUIC servers must set max_early_data_size to 0xffffffff when they
+	// allow 0-RTT. See RFC 9001, Section 4.6.1.
+	if c.quic != nil && msg.maxEarlyData != 0 && msg.maxEarlyData != 0xffffffff {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: invalid early data for QUIC connection")
+	}
 	lifetime := time.Duration(msg.lifetime) * time.Second
 	if lifetime > maxSessionTicketLifetime {
 		c.sendAlert(alertIllegalParameter)
@@ -673,10 +917,14 @@ func (c *Conn) handleNewSessionTicket(msg *newSessionTicketMsgTLS13) error {
 		ageAdd:             msg.ageAdd,
 		ocspResponse:       c.ocspResponse,
 		scts:               c.scts,
+		earlyData:          c.quic != nil && msg.maxEarlyData == 0xffffffff,
+		alpnProtocol:       c.clientProtocol,
 	}
 
-	cacheKey := clientSessionCacheKey(c.conn.RemoteAddr(), c.config)
-	c.config.ClientSessionCache.Put(cacheKey, session)
+	cacheKey := c.clientSessionCacheKey()
+	if cacheKey != "" {
+		c.config.ClientSessionCache.Put(cacheKey, session)
+	}
 
 	return nil
 }
@@ -92,6 +92,8 @@ type clientHelloMsg struct {
 	pskModes                         []uint8
 	pskIdentities                    []pskIdentity
 	pskBinders                       [][]byte
+	quicTransportParameters          []byte
+	encryptedClientHello             []byte
 }
 
 func (m *clientHelloMsg) marshal() []byte {
@@ -257,6 +259,20 @@ func (m *clientHelloMsg) marshal() []byte {
 				b.AddUint16(extensionEarlyData)
 				b.AddUint16(0) // empty extension_data
 			}
+			if m.quicTransportParameters != nil { // marshal zero-length parameters when present
+				// RFC 9001, Section 8.2
+				b.AddUint16(extensionQUICTransportParameters)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddBytes(m.quicTransportParameters)
+				})
+			}
+			if len(m.encryptedClientHello) > 0 {
+				// draft-ietf-tls-esni, Section 5
+				b.AddUint16(extensionEncryptedClientHello)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddBytes(m.encryptedClientHello)
+				})
+			}
 			if len(m.pskModes) > 0 {
 				// RFC 8446, Section 4.2.9
 				b.AddUint16(extensionPSKModes)
@@ -540,6 +556,21 @@ func (m *clientHelloMsg) unmarshal(data []byte) bool {
 		case extensionEarlyData:
 			// RFC 8446, Section 4.2.10
 			m.earlyData = true
+		case extensionQUICTransportParameters:
+			// RFC 9001, Section 8.2
+			m.quicTransportParameters = make([]byte, len(extData))
+			if !extData.CopyBytes(m.quicTransportParameters) {
+				return false
+			}
+		case extensionEncryptedClientHello:
+			// draft-ietf-tls-esni, Section 5
+			if len(extData) == 0 {
+				return false
+			}
+			m.encryptedClientHello = make([]byte, len(extData))
+			if !extData.CopyBytes(m.encryptedClientHello) {
+				return false
+			}
 		case extensionPSKModes:
 			// RFC 8446, Section 4.2.9
 			if !readUint8LengthPrefixed(&extData, &m.pskModes) {
@@ -608,8 +639,9 @@ type serverHelloMsg struct {
 	supportedPoints              []uint8
 
 	// HelloRetryRequest extensions
-	cookie        []byte
-	selectedGroup CurveID
+	cookie               []byte
+	selectedGroup        CurveID
+	encryptedClientHello []byte
 }
 
 func (m *serverHelloMsg) marshal() []byte {
@@ -707,6 +739,13 @@ func (m *serverHelloMsg) marshal() []byte {
 					b.AddUint16(uint16(m.selectedGroup))
 				})
 			}
+			if len(m.encryptedClientHello) > 0 {
+				// draft-ietf-tls-esni, Section 7.2.1
+				b.AddUint16(extensionEncryptedClientHello)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddBytes(m.encryptedClientHello)
+				})
+			}
 			if len(m.supportedPoints) > 0 {
 				b.AddUint16(extensionSupportedPoints)
 				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
@@ -819,6 +858,12 @@ func (m *serverHelloMsg) unmarshal(data []byte) bool {
 			if !extData.ReadUint16(&m.selectedIdentity) {
 				return false
 			}
+		case extensionEncryptedClientHello:
+			// draft-ietf-tls-esni, Section 7.2.1
+			if !extData.ReadBytes(&m.encryptedClientHello, len(extData)) ||
+				len(m.encryptedClientHello) == 0 {
+				return false
+			}
 		case extensionSupportedPoints:
 			// RFC 4492, Section 5.1.2
 			if !readUint8LengthPrefixed(&extData, &m.supportedPoints) ||
@@ -839,8 +884,11 @@ func (m *serverHelloMsg) unmarshal(data []byte) bool {
 }
 
 type encryptedExtensionsMsg struct {
-	raw          []byte
-	alpnProtocol string
+	raw                     []byte
+	alpnProtocol            string
+	quicTransportParameters []byte
+	earlyData               bool
+	echRetryConfigs         []byte
 }
 
 func (m *encryptedExtensionsMsg) marshal() []byte {
@@ -862,6 +910,25 @@ func (m *encryptedExtensionsMsg) marshal() []byte {
 					})
 				})
 			}
+			if m.quicTransportParameters != nil { // marshal zero-length parameters when present
+				// RFC 9001, Section 8.2
+				b.AddUint16(extensionQUICTransportParameters)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddBytes(m.quicTransportParameters)
+				})
+			}
+			if m.earlyData {
+				// RFC 8446, Section 4.2.10
+				b.AddUint16(extensionEarlyData)
+				b.AddUint16(0) // empty extension_data
+			}
+			if len(m.echRetryConfigs) > 0 {
+				// draft-ietf-tls-esni, Section 7.1
+				b.AddUint16(extensionEncryptedClientHello)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddBytes(m.echRetryConfigs)
+				})
+			}
 		})
 	})
 
@@ -899,6 +966,20 @@ func (m *encryptedExtensionsMsg) unmarshal(data []byte) bool {
 				return false
 			}
 			m.alpnProtocol = string(proto)
+		case extensionQUICTransportParameters:
+			m.quicTransportParameters = make([]byte, len(extData))
+			if !extData.CopyBytes(m.quicTransportParameters) {
+				return false
+			}
+		case extensionEarlyData:
+			// RFC 8446, Section 4.2.10
+			m.earlyData = true
+		case extensionEncryptedClientHello:
+			// draft-ietf-tls-esni, Section 7.1
+			m.echRetryConfigs = make([]byte, len(extData))
+			if !extData.CopyBytes(m.echRetryConfigs) || len(m.echRetryConfigs) == 0 {
+				return false
+			}
 		default:
 			// Ignore unknown extensions.
 			continue
@@ -190,6 +190,12 @@ func (*clientHelloMsg) Generate(rand *rand.Rand, size int) reflect.Value {
 	if rand.Intn(10) > 5 {
 		m.earlyData = true
 	}
+	if rand.Intn(10) > 5 {
+		m.quicTransportParameters = randomBytes(rand.Intn(500), rand)
+	}
+	if rand.Intn(10) > 5 {
+		m.encryptedClientHello = randomBytes(rand.Intn(500)+1, rand)
+	}
 
 	return reflect.ValueOf(m)
 }
@@ -239,6 +245,9 @@ func (*serverHelloMsg) Generate(rand *rand.Rand, size int) reflect.Value {
 		m.selectedIdentityPresent = true
 		m.selectedIdentity = uint16(rand.Intn(0xffff))
 	}
+	if rand.Intn(10) > 5 {
+		m.encryptedClientHello = randomBytes(8, rand)
+	}
 
 	return reflect.ValueOf(m)
 }
@@ -249,6 +258,15 @@ func (*encryptedExtensionsMsg) Generate(rand *rand.Rand, size int) reflect.Value
 	if rand.Intn(10) > 5 {
 		m.alpnProtocol = randomString(rand.Intn(32)+1, rand)
 	}
+	if rand.Intn(10) > 5 {
+		m.quicTransportParameters = randomBytes(rand.Intn(500), rand)
+	}
+	if rand.Intn(10) > 5 {
+		m.earlyData = true
+	}
+	if rand.Intn(10) > 5 {
+		m.echRetryConfigs = randomBytes(rand.Intn(500)+1, rand)
+	}
 
 	return reflect.ValueOf(m)
 }
@@ -334,6 +352,10 @@ func (*sessionStateTLS13) Generate(rand *rand.Rand, size int) reflect.Value {
 				s.certificate.SignedCertificateTimestamps, randomBytes(rand.Intn(500)+1, rand))
 		}
 	}
+	if rand.Intn(10) > 5 {
+		s.earlyData = true
+		s.alpnProtocol = randomString(rand.Intn(20), rand)
+	}
 	return reflect.ValueOf(s)
 }
 
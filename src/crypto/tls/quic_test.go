@@ -0,0 +1,552 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testQUICConn struct {
+	t           *testing.T
+	conn        *QUICConn
+	readSecret  map[QUICEncryptionLevel]suiteSecret
+	writeSecret map[QUICEncryptionLevel]suiteSecret
+	gotParams   []byte
+	complete    bool
+	rejected    bool // got QUICRejectedEarlyData
+	sendTicket  *QUICSessionTicketOptions
+}
+
+func newTestQUICClient(t *testing.T, config *Config) *testQUICConn {
+	q := &testQUICConn{t: t}
+	q.conn = QUICClient(&QUICConfig{
+		TLSConfig: config,
+	})
+	t.Cleanup(func() {
+		q.conn.Close()
+	})
+	return q
+}
+
+func newTestQUICServer(t *testing.T, config *Config) *testQUICConn {
+	q := &testQUICConn{t: t}
+	q.conn = QUICServer(&QUICConfig{
+		TLSConfig: config,
+	})
+	t.Cleanup(func() {
+		q.conn.Close()
+	})
+	return q
+}
+
+type suiteSecret struct {
+	suite  uint16
+	secret []byte
+}
+
+func (q *testQUICConn) setReadSecret(level QUICEncryptionLevel, suite uint16, secret []byte) {
+	if _, ok := q.writeSecret[level]; !ok && level != QUICEncryptionLevelEarly {
+		q.t.Errorf("SetReadSecret for level %v called before SetWriteSecret", level)
+	}
+	if level == QUICEncryptionLevelApplication && !q.complete {
+		q.t.Errorf("SetReadSecret for level %v called before HandshakeComplete", level)
+	}
+	if _, ok := q.readSecret[level]; ok {
+		q.t.Errorf("SetReadSecret for level %v called twice", level)
+	}
+	if q.readSecret == nil {
+		q.readSecret = map[QUICEncryptionLevel]suiteSecret{}
+	}
+	switch level {
+	case QUICEncryptionLevelHandshake, QUICEncryptionLevelApplication, QUICEncryptionLevelEarly:
+		q.readSecret[level] = suiteSecret{suite, append([]byte(nil), secret...)}
+	default:
+		q.t.Errorf("SetReadSecret for unexpected level %v", level)
+	}
+}
+
+func (q *testQUICConn) setWriteSecret(level QUICEncryptionLevel, suite uint16, secret []byte) {
+	if _, ok := q.writeSecret[level]; ok {
+		q.t.Errorf("SetWriteSecret for level %v called twice", level)
+	}
+	if q.writeSecret == nil {
+		q.writeSecret = map[QUICEncryptionLevel]suiteSecret{}
+	}
+	switch level {
+	case QUICEncryptionLevelHandshake, QUICEncryptionLevelApplication, QUICEncryptionLevelEarly:
+		q.writeSecret[level] = suiteSecret{suite, append([]byte(nil), secret...)}
+	default:
+		q.t.Errorf("SetWriteSecret for unexpected level %v", level)
+	}
+}
+
+var errTransportParametersRequired = errors.New("transport parameters required")
+
+// runTestQUICConnection runs a QUIC handshake between cli and srv, shuttling
+// handshake data between them in memory. onEvent, if non-nil, is called for
+// every event before it is processed, and can stop the exchange by returning
+// true.
+func runTestQUICConnection(ctx context.Context, cli, srv *testQUICConn, onEvent func(e QUICEvent, src, dst *testQUICConn) bool) error {
+	a, b := cli, srv
+	for _, c := range []*testQUICConn{a, b} {
+		if !c.conn.conn.quic.started {
+			if err := c.conn.Start(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	idleCount := 0
+	for {
+		e := a.conn.NextEvent()
+		if onEvent != nil && onEvent(e, a, b) {
+			continue
+		}
+		switch e.Kind {
+		case QUICNoEvent:
+			idleCount++
+			if idleCount == 2 {
+				if !a.complete || !b.complete {
+					return errors.New("handshake incomplete")
+				}
+				return nil
+			}
+			a, b = b, a
+		case QUICSetReadSecret:
+			a.setReadSecret(e.Level, e.Suite, e.Data)
+		case QUICSetWriteSecret:
+			a.setWriteSecret(e.Level, e.Suite, e.Data)
+		case QUICWriteData:
+			if err := b.conn.HandleData(e.Level, e.Data); err != nil {
+				return err
+			}
+		case QUICTransportParameters:
+			a.gotParams = append([]byte{}, e.Data...)
+		case QUICTransportParametersRequired:
+			return errTransportParametersRequired
+		case QUICRejectedEarlyData:
+			a.rejected = true
+		case QUICHandshakeDone:
+			a.complete = true
+			if a == srv && srv.sendTicket != nil {
+				if err := srv.conn.SendSessionTicket(*srv.sendTicket); err != nil {
+					return err
+				}
+			}
+		}
+		if e.Kind != QUICNoEvent {
+			idleCount = 0
+		}
+	}
+}
+
+func testQUICConfig() *Config {
+	config := testConfig.Clone()
+	config.MinVersion = VersionTLS13
+	config.NextProtos = []string{"h3"}
+	return config
+}
+
+func TestQUICConnection(t *testing.T) {
+	config := testQUICConfig()
+
+	cli := newTestQUICClient(t, config)
+	cli.conn.SetTransportParameters(nil)
+
+	srv := newTestQUICServer(t, config)
+	srv.conn.SetTransportParameters(nil)
+
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != nil {
+		t.Fatalf("error during connection handshake: %v", err)
+	}
+
+	if _, ok := cli.readSecret[QUICEncryptionLevelHandshake]; !ok {
+		t.Errorf("client has no Handshake secret")
+	}
+	if _, ok := cli.readSecret[QUICEncryptionLevelApplication]; !ok {
+		t.Errorf("client has no Application secret")
+	}
+	if _, ok := srv.readSecret[QUICEncryptionLevelHandshake]; !ok {
+		t.Errorf("server has no Handshake secret")
+	}
+	if _, ok := srv.readSecret[QUICEncryptionLevelApplication]; !ok {
+		t.Errorf("server has no Application secret")
+	}
+	for _, level := range []QUICEncryptionLevel{QUICEncryptionLevelHandshake, QUICEncryptionLevelApplication} {
+		if _, ok := cli.readSecret[level]; !ok {
+			t.Errorf("client has no %v read secret", level)
+		}
+		if _, ok := srv.readSecret[level]; !ok {
+			t.Errorf("server has no %v read secret", level)
+		}
+		if !reflect.DeepEqual(cli.readSecret[level], srv.writeSecret[level]) {
+			t.Errorf("client read secret does not match server write secret for level %v", level)
+		}
+		if !reflect.DeepEqual(cli.writeSecret[level], srv.readSecret[level]) {
+			t.Errorf("client write secret does not match server read secret for level %v", level)
+		}
+	}
+	if _, ok := cli.writeSecret[QUICEncryptionLevelEarly]; ok {
+		t.Errorf("client has an Early secret without resumption")
+	}
+
+	cs, ss := cli.conn.ConnectionState(), srv.conn.ConnectionState()
+	if !cs.HandshakeComplete || !ss.HandshakeComplete {
+		t.Errorf("ConnectionState().HandshakeComplete = %v, %v; want true, true", cs.HandshakeComplete, ss.HandshakeComplete)
+	}
+	if cs.Version != VersionTLS13 || ss.Version != VersionTLS13 {
+		t.Errorf("ConnectionState().Version = %x, %x; want TLS 1.3", cs.Version, ss.Version)
+	}
+	if cs.NegotiatedProtocol != "h3" || ss.NegotiatedProtocol != "h3" {
+		t.Errorf("ConnectionState().NegotiatedProtocol = %q, %q; want \"h3\"", cs.NegotiatedProtocol, ss.NegotiatedProtocol)
+	}
+}
+
+func TestQUICSessionResumption(t *testing.T) {
+	clientConfig := testQUICConfig()
+	clientConfig.ServerName = "example.go.dev"
+	clientConfig.ClientSessionCache = NewLRUClientSessionCache(1)
+
+	serverConfig := testQUICConfig()
+
+	cli := newTestQUICClient(t, clientConfig)
+	cli.conn.SetTransportParameters(nil)
+	srv := newTestQUICServer(t, serverConfig)
+	srv.conn.SetTransportParameters(nil)
+	srv.sendTicket = &QUICSessionTicketOptions{}
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != nil {
+		t.Fatalf("error during first connection handshake: %v", err)
+	}
+	if cli.conn.ConnectionState().DidResume {
+		t.Errorf("first connection unexpectedly used session resumption")
+	}
+
+	cli2 := newTestQUICClient(t, clientConfig)
+	cli2.conn.SetTransportParameters(nil)
+	srv2 := newTestQUICServer(t, serverConfig)
+	srv2.conn.SetTransportParameters(nil)
+	if err := runTestQUICConnection(context.Background(), cli2, srv2, nil); err != nil {
+		t.Fatalf("error during second connection handshake: %v", err)
+	}
+	if !cli2.conn.ConnectionState().DidResume {
+		t.Errorf("second connection did not use session resumption")
+	}
+	if _, ok := cli2.writeSecret[QUICEncryptionLevelEarly]; ok {
+		t.Errorf("client offered 0-RTT with a ticket that does not allow it")
+	}
+}
+
+func TestQUICSessionTicketWithoutCache(t *testing.T) {
+	// Without a ServerName and a net.Conn, there is no cache key, and the
+	// client must not try to store or load sessions.
+	clientConfig := testQUICConfig()
+	clientConfig.ClientSessionCache = NewLRUClientSessionCache(1)
+
+	cli := newTestQUICClient(t, clientConfig)
+	cli.conn.SetTransportParameters(nil)
+	srv := newTestQUICServer(t, testQUICConfig())
+	srv.conn.SetTransportParameters(nil)
+	srv.sendTicket = &QUICSessionTicketOptions{}
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != nil {
+		t.Fatalf("error during connection handshake: %v", err)
+	}
+	if _, ok := clientConfig.ClientSessionCache.Get(""); ok {
+		t.Errorf("session was cached without a cache key")
+	}
+}
+
+func TestQUICEarlyData(t *testing.T) {
+	clientConfig := testQUICConfig()
+	clientConfig.ServerName = "example.go.dev"
+	clientConfig.ClientSessionCache = NewLRUClientSessionCache(1)
+
+	serverConfig := testQUICConfig()
+
+	cli := newTestQUICClient(t, clientConfig)
+	cli.conn.SetTransportParameters(nil)
+	srv := newTestQUICServer(t, serverConfig)
+	srv.conn.SetTransportParameters(nil)
+	srv.sendTicket = &QUICSessionTicketOptions{EarlyData: true}
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != nil {
+		t.Fatalf("error during first connection handshake: %v", err)
+	}
+
+	cli2 := newTestQUICClient(t, clientConfig)
+	cli2.conn.SetTransportParameters(nil)
+	srv2 := newTestQUICServer(t, serverConfig)
+	srv2.conn.SetTransportParameters(nil)
+	if err := runTestQUICConnection(context.Background(), cli2, srv2, nil); err != nil {
+		t.Fatalf("error during second connection handshake: %v", err)
+	}
+	if !cli2.conn.ConnectionState().DidResume {
+		t.Errorf("second connection did not use session resumption")
+	}
+	if cli2.rejected {
+		t.Errorf("server rejected 0-RTT")
+	}
+	cliSecret, ok := cli2.writeSecret[QUICEncryptionLevelEarly]
+	if !ok {
+		t.Fatalf("client has no Early write secret")
+	}
+	srvSecret, ok := srv2.readSecret[QUICEncryptionLevelEarly]
+	if !ok {
+		t.Fatalf("server has no Early read secret")
+	}
+	if !reflect.DeepEqual(cliSecret, srvSecret) {
+		t.Errorf("client Early write secret does not match server Early read secret")
+	}
+}
+
+func TestQUICEarlyDataRejected(t *testing.T) {
+	clientConfig := testQUICConfig()
+	clientConfig.ServerName = "example.go.dev"
+	clientConfig.ClientSessionCache = NewLRUClientSessionCache(1)
+	clientConfig.NextProtos = []string{"h3", "h3-29"}
+
+	serverConfig := testQUICConfig()
+
+	cli := newTestQUICClient(t, clientConfig)
+	cli.conn.SetTransportParameters(nil)
+	srv := newTestQUICServer(t, serverConfig)
+	srv.conn.SetTransportParameters(nil)
+	srv.sendTicket = &QUICSessionTicketOptions{EarlyData: true}
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != nil {
+		t.Fatalf("error during first connection handshake: %v", err)
+	}
+
+	// The second server negotiates a different ALPN protocol, so it must
+	// accept the session but not the early data.
+	serverConfig2 := testQUICConfig()
+	serverConfig2.NextProtos = []string{"h3-29"}
+
+	cli2 := newTestQUICClient(t, clientConfig)
+	cli2.conn.SetTransportParameters(nil)
+	srv2 := newTestQUICServer(t, serverConfig2)
+	srv2.conn.SetTransportParameters(nil)
+	if err := runTestQUICConnection(context.Background(), cli2, srv2, nil); err != nil {
+		t.Fatalf("error during second connection handshake: %v", err)
+	}
+	if !cli2.conn.ConnectionState().DidResume {
+		t.Errorf("second connection did not use session resumption")
+	}
+	if _, ok := cli2.writeSecret[QUICEncryptionLevelEarly]; !ok {
+		t.Errorf("client did not offer 0-RTT")
+	}
+	if !cli2.rejected {
+		t.Errorf("client did not get a QUICRejectedEarlyData event")
+	}
+	if _, ok := srv2.readSecret[QUICEncryptionLevelEarly]; ok {
+		t.Errorf("server accepted 0-RTT with a different ALPN protocol")
+	}
+}
+
+func TestQUICTransportParameters(t *testing.T) {
+	config := testQUICConfig()
+
+	cliParams := "client params"
+	srvParams := "server params"
+
+	cli := newTestQUICClient(t, config)
+	cli.conn.SetTransportParameters([]byte(cliParams))
+	srv := newTestQUICServer(t, config)
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != errTransportParametersRequired {
+		t.Fatalf("server which has not set transport parameters: got %v, want errTransportParametersRequired", err)
+	}
+	if got := string(srv.gotParams); got != cliParams {
+		t.Errorf("server got transport params %q, want %q", got, cliParams)
+	}
+
+	// The server's handshake is blocked until it has transport parameters.
+	srv.conn.SetTransportParameters([]byte(srvParams))
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != nil {
+		t.Fatalf("error during connection handshake: %v", err)
+	}
+	if got := string(cli.gotParams); got != srvParams {
+		t.Errorf("client got transport params %q, want %q", got, srvParams)
+	}
+}
+
+func TestQUICTransportParametersRequired(t *testing.T) {
+	// The QUIC transport parameters extension is mandatory (RFC 9001,
+	// Section 8.2). Check that a server rejects a QUIC ClientHello
+	// without it, by stripping the extension from the client's message.
+	config := testQUICConfig()
+
+	cli := newTestQUICClient(t, config)
+	cli.conn.SetTransportParameters(nil)
+	srv := newTestQUICServer(t, config)
+	srv.conn.SetTransportParameters(nil)
+	var srvErr error
+	err := runTestQUICConnection(context.Background(), cli, srv, func(e QUICEvent, src, dst *testQUICConn) bool {
+		if e.Kind != QUICWriteData || src != cli || e.Level != QUICEncryptionLevelInitial {
+			return false
+		}
+		ch := new(clientHelloMsg)
+		if !ch.unmarshal(append([]byte(nil), e.Data...)) {
+			t.Fatalf("failed to parse ClientHello")
+		}
+		ch.raw = nil
+		ch.quicTransportParameters = nil
+		srvErr = dst.conn.HandleData(e.Level, ch.marshal())
+		return true
+	})
+	if err == nil {
+		t.Fatalf("handshake succeeded without client transport parameters")
+	}
+	var alert AlertError
+	if !errors.As(srvErr, &alert) || alert != AlertError(alertMissingExtension) {
+		t.Errorf("server error = %v, want an AlertError of %v", srvErr, alertMissingExtension)
+	}
+}
+
+func TestQUICHandshakeError(t *testing.T) {
+	clientConfig := testQUICConfig()
+	clientConfig.InsecureSkipVerify = false
+	clientConfig.ServerName = "name"
+
+	serverConfig := testQUICConfig()
+
+	cli := newTestQUICClient(t, clientConfig)
+	cli.conn.SetTransportParameters(nil)
+	srv := newTestQUICServer(t, serverConfig)
+	srv.conn.SetTransportParameters(nil)
+	err := runTestQUICConnection(context.Background(), cli, srv, nil)
+	if !errors.Is(err, AlertError(alertBadCertificate)) {
+		t.Errorf("connection handshake terminated with error %q, want alertBadCertificate", err)
+	}
+	var e x509.CertificateInvalidError
+	if !errors.As(err, &e) {
+		t.Errorf("connection handshake terminated with error %q, want x509.CertificateInvalidError", err)
+	}
+}
+
+func TestQUICConnectionState(t *testing.T) {
+	// ConnectionState can be called while the handshake is blocked.
+	config := testQUICConfig()
+	cli := newTestQUICClient(t, config)
+	cli.conn.SetTransportParameters(nil)
+	srv := newTestQUICServer(t, config)
+	srv.conn.SetTransportParameters(nil)
+	onEvent := func(e QUICEvent, src, dst *testQUICConn) bool {
+		cliCS := cli.conn.ConnectionState()
+		if _, ok := cli.readSecret[QUICEncryptionLevelApplication]; ok {
+			if got, want := cliCS.NegotiatedProtocol, "h3"; got != want {
+				t.Errorf("cli.ConnectionState().NegotiatedProtocol = %q, want %q", got, want)
+			}
+		}
+		return false
+	}
+	if err := runTestQUICConnection(context.Background(), cli, srv, onEvent); err != nil {
+		t.Fatalf("error during connection handshake: %v", err)
+	}
+}
+
+func TestQUICStartContextPropagation(t *testing.T) {
+	const key = "key"
+	const value = "value"
+	ctx := context.WithValue(context.Background(), key, value)
+	config := testQUICConfig()
+	calls := 0
+	config.GetConfigForClient = func(info *ClientHelloInfo) (*Config, error) {
+		calls++
+		got, _ := info.Context().Value(key).(string)
+		if got != value {
+			t.Errorf("GetConfigForClient context key %q has value %q, want %q", key, got, value)
+		}
+		return nil, nil
+	}
+	cli := newTestQUICClient(t, config)
+	cli.conn.SetTransportParameters(nil)
+	srv := newTestQUICServer(t, config)
+	srv.conn.SetTransportParameters(nil)
+	if err := runTestQUICConnection(ctx, cli, srv, nil); err != nil {
+		t.Fatalf("error during connection handshake: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("GetConfigForClient called %v times, want 1", calls)
+	}
+}
+
+func TestQUICCanceledWaitingForData(t *testing.T) {
+	config := testQUICConfig()
+	cli := newTestQUICClient(t, config)
+	cli.conn.SetTransportParameters(nil)
+	cli.conn.Start(context.Background())
+	for cli.conn.NextEvent().Kind != QUICNoEvent {
+	}
+	err := cli.conn.Close()
+	if !errors.Is(err, AlertError(alertCloseNotify)) {
+		t.Errorf("conn.Close() = %v, want alertCloseNotify", err)
+	}
+}
+
+func TestQUICCanceledWaitingForTransportParams(t *testing.T) {
+	config := testQUICConfig()
+	cli := newTestQUICClient(t, config)
+	cli.conn.Start(context.Background())
+	for cli.conn.NextEvent().Kind != QUICTransportParametersRequired {
+	}
+	err := cli.conn.Close()
+	if !errors.Is(err, AlertError(alertCloseNotify)) {
+		t.Errorf("conn.Close() = %v, want alertCloseNotify", err)
+	}
+}
+
+func TestQUICRejectsOldTLSVersions(t *testing.T) {
+	config := testConfig.Clone()
+	config.MinVersion = VersionTLS12
+	cli := newTestQUICClient(t, config)
+	if err := cli.conn.Start(context.Background()); err == nil {
+		t.Errorf("Start with MinVersion = TLS 1.2 succeeded")
+	}
+}
+
+func TestQUICPostHandshakeKeyUpdate(t *testing.T) {
+	// KeyUpdate messages are forbidden in QUIC. See RFC 9001, Section 6.
+	config := testQUICConfig()
+	cli := newTestQUICClient(t, config)
+	cli.conn.SetTransportParameters(nil)
+	srv := newTestQUICServer(t, config)
+	srv.conn.SetTransportParameters(nil)
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != nil {
+		t.Fatalf("error during connection handshake: %v", err)
+	}
+	keyUpdate := &keyUpdateMsg{}
+	err := cli.conn.HandleData(QUICEncryptionLevelApplication, keyUpdate.marshal())
+	if !errors.Is(err, AlertError(alertUnexpectedMessage)) {
+		t.Errorf("key update request: got error %v, want alertUnexpectedMessage", err)
+	}
+}
+
+func TestQUICHandshakeDataAtWrongLevel(t *testing.T) {
+	config := testQUICConfig()
+	cli := newTestQUICClient(t, config)
+	cli.conn.SetTransportParameters(nil)
+	srv := newTestQUICServer(t, config)
+	srv.conn.SetTransportParameters(nil)
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != nil {
+		t.Fatalf("error during connection handshake: %v", err)
+	}
+	if err := cli.conn.HandleData(QUICEncryptionLevelHandshake, []byte{0}); err == nil {
+		t.Errorf("HandleData at the Handshake level after the handshake succeeded")
+	}
+}
+
+func TestQUICEncryptionLevelString(t *testing.T) {
+	for level, want := range map[QUICEncryptionLevel]string{
+		QUICEncryptionLevelInitial:     "Initial",
+		QUICEncryptionLevelEarly:       "Early",
+		QUICEncryptionLevelHandshake:   "Handshake",
+		QUICEncryptionLevelApplication: "Application",
+		QUICEncryptionLevel(42):        "QUICEncryptionLevel(42)",
+	} {
+		if got := level.String(); got != want {
+			t.Errorf("QUICEncryptionLevel(%d).String() = %q, want %q", int(level), got, want)
+		}
+	}
+}
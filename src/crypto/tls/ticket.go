@@ -76,26 +76,40 @@ func (m *sessionState) unmarshal(data []byte) bool {
 
 // sessionStateTLS13 is the content of a TLS 1.3 session ticket. Its first
 // version (revision = 0) doesn't carry any of the information needed for 0-RTT
-// validation and the nonce is always empty.
+// validation and the nonce is always empty. Revision 1 is only used for
+// tickets that allow 0-RTT, which are only issued to QUIC clients, and
+// additionally carries the negotiated ALPN protocol.
 type sessionStateTLS13 struct {
 	// uint8 version  = 0x0304;
-	// uint8 revision = 0;
+	// uint8 revision = 0 or 1;
 	cipherSuite      uint16
 	createdAt        uint64
 	resumptionSecret []byte      // opaque resumption_master_secret<1..2^8-1>;
 	certificate      Certificate // CertificateEntry certificate_list<0..2^24-1>;
+	// The following fields are only present in revision 1.
+	earlyData    bool   // set if revision = 1
+	alpnProtocol string // opaque alpn_protocol<0..2^8-1>;
 }
 
 func (m *sessionStateTLS13) marshal() []byte {
 	var b cryptobyte.Builder
 	b.AddUint16(VersionTLS13)
-	b.AddUint8(0) // revision
+	if m.earlyData {
+		b.AddUint8(1) // revision
+	} else {
+		b.AddUint8(0) // revision
+	}
 	b.AddUint16(m.cipherSuite)
 	addUint64(&b, m.createdAt)
 	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
 		b.AddBytes(m.resumptionSecret)
 	})
 	marshalCertificate(&b, m.certificate)
+	if m.earlyData {
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes([]byte(m.alpnProtocol))
+		})
+	}
 	return b.BytesOrPanic()
 }
 
@@ -104,16 +118,26 @@ func (m *sessionStateTLS13) unmarshal(data []byte) bool {
 	s := cryptobyte.String(data)
 	var version uint16
 	var revision uint8
-	return s.ReadUint16(&version) &&
-		version == VersionTLS13 &&
-		s.ReadUint8(&revision) &&
-		revision == 0 &&
-		s.ReadUint16(&m.cipherSuite) &&
-		readUint64(&s, &m.createdAt) &&
-		readUint8LengthPrefixed(&s, &m.resumptionSecret) &&
-		len(m.resumptionSecret) != 0 &&
-		unmarshalCertificate(&s, &m.certificate) &&
-		s.Empty()
+	if !s.ReadUint16(&version) ||
+		version != VersionTLS13 ||
+		!s.ReadUint8(&revision) ||
+		revision > 1 ||
+		!s.ReadUint16(&m.cipherSuite) ||
+		!readUint64(&s, &m.createdAt) ||
+		!readUint8LengthPrefixed(&s, &m.resumptionSecret) ||
+		len(m.resumptionSecret) == 0 ||
+		!unmarshalCertificate(&s, &m.certificate) {
+		return false
+	}
+	if revision == 1 {
+		var alpn []byte
+		if !readUint8LengthPrefixed(&s, &alpn) {
+			return false
+		}
+		m.earlyData = true
+		m.alpnProtocol = string(alpn)
+	}
+	return s.Empty()
 }
 
 func (c *Conn) encryptTicket(state []byte) ([]byte, error) {
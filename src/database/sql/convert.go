@@ -12,6 +12,8 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 	"unicode"
 	"unicode/utf8"
@@ -559,6 +561,82 @@ func callValuerValue(vr driver.Valuer) (v driver.Value, err error) {
 	return vr.Value()
 }
 
+var scannerReflectType = reflect.TypeOf((*Scanner)(nil)).Elem()
+
+// structColumnFieldsCache maps a struct type to its structColumnFields.
+var structColumnFieldsCache sync.Map // map[reflect.Type]map[string][]int
+
+// structColumnFields returns the index sequences of the fields of the
+// struct type t that columns may be scanned into by Rows.ScanStruct,
+// keyed by their lower-cased names.
+func structColumnFields(t reflect.Type) map[string][]int {
+	if f, ok := structColumnFieldsCache.Load(t); ok {
+		return f.(map[string][]int)
+	}
+
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	fields := make(map[string][]int)
+	seen := make(map[string]bool) // names found at a shallower depth
+	visited := make(map[reflect.Type]bool)
+
+	// Search the embedded structs breadth first, so that a field hides
+	// the fields of the same name that are embedded more deeply.
+	for current := []embedded{{t, nil}}; len(current) > 0; {
+		var next []embedded
+		count := make(map[string]int)
+		found := make(map[string][]int)
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				tag := sf.Tag.Get("sql")
+				if tag == "-" {
+					continue
+				}
+				index := make([]int, len(e.index)+1)
+				copy(index, e.index)
+				index[len(e.index)] = i
+				if sf.Anonymous && tag == "" && sf.Type.Kind() == reflect.Struct &&
+					!reflect.PointerTo(sf.Type).Implements(scannerReflectType) {
+					next = append(next, embedded{sf.Type, index})
+					continue
+				}
+				if !sf.IsExported() {
+					continue
+				}
+				name := tag
+				if name == "" {
+					name = sf.Name
+				}
+				name = strings.ToLower(name)
+				count[name]++
+				found[name] = index
+			}
+		}
+		for name, index := range found {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			// Fields of the same name at the same depth are ambiguous,
+			// and neither is used.
+			if count[name] == 1 {
+				fields[name] = index
+			}
+		}
+		current = next
+	}
+
+	f, _ := structColumnFieldsCache.LoadOrStore(t, fields)
+	return f.(map[string][]int)
+}
+
 // decimal composes or decomposes a decimal value to and from individual parts.
 // There are four parts: a boolean negative flag, a form byte with three possible states
 // (finite=0, infinite=1, NaN=2), a base-2 big-endian integer
@@ -28,7 +28,9 @@
 // it should implement the following interfaces: RowsColumnTypeScanType,
 // RowsColumnTypeDatabaseTypeName, RowsColumnTypeLength, RowsColumnTypeNullable,
 // and RowsColumnTypePrecisionScale. A given row value may also return a Rows
-// type, which may represent a database cursor value.
+// type, which may represent a database cursor value. To scan values such as
+// arrays and composite types directly into their destinations, Rows may
+// implement RowsColumnScanner.
 //
 // Before a connection is returned to the connection pool after use, IsValid is
 // called if implemented. Before a connection is reused for another query,
@@ -512,6 +514,20 @@ type RowsColumnTypePrecisionScale interface {
 	ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool)
 }
 
+// RowsColumnScanner may be implemented by Rows. It allows the driver to
+// scan a column of the current row directly into a destination passed to
+// the sql package's Rows.Scan, such as a slice for an array column or a
+// struct for a composite column, without first converting the column to
+// a Value such as []byte.
+//
+// ScanColumn is called for each column after Next has populated the row.
+// It should return ErrSkip if it does not support dest, in which case the
+// column is converted from the value populated by Next as usual.
+type RowsColumnScanner interface {
+	Rows
+	ScanColumn(dest any, index int) error
+}
+
 // Tx is a transaction.
 type Tx interface {
 	Commit() error
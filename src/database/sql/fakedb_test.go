@@ -1150,6 +1150,22 @@ func (rc *rowsCursor) Next(dest []driver.Value) error {
 	return nil
 }
 
+// ScanColumn scans string columns holding comma-separated lists
+// directly into *[]string destinations, standing in for a driver
+// that supports array columns.
+func (rc *rowsCursor) ScanColumn(dest any, index int) error {
+	d, ok := dest.(*[]string)
+	if !ok {
+		return driver.ErrSkip
+	}
+	v, ok := rc.rows[rc.posSet][rc.posRow].cols[index].(string)
+	if !ok {
+		return fmt.Errorf("fakedb: can't scan column %d into *[]string", index)
+	}
+	*d = strings.Split(v, ",")
+	return nil
+}
+
 func (rc *rowsCursor) HasNextResultSet() bool {
 	rc.touchMem()
 	return rc.posSet < len(rc.rows)-1
@@ -0,0 +1,193 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// An Observer is notified of the operations a DB performs on its
+// connections. It may be used to collect metrics or to trace the
+// use of a database.
+//
+// An Observer must be safe for concurrent use by multiple goroutines.
+// Its Observe method is called synchronously, after the operation
+// completes, and so should return quickly. It must not use the DB
+// on which it is registered.
+type Observer interface {
+	// Observe is called with the context of the operation described by e.
+	// OpRelease events, and OpConnect events for the connections the DB
+	// opens in the background to refill its pool, have a context that
+	// carries no values of the caller.
+	Observe(ctx context.Context, e Event)
+}
+
+// An Op is a kind of operation reported to an Observer.
+type Op int
+
+const (
+	// OpConnect is the opening of a new connection by the driver.
+	OpConnect Op = iota + 1
+
+	// OpAcquire is the acquisition of a connection from the pool,
+	// including any time spent waiting for a connection to become
+	// available or opening a new one.
+	OpAcquire
+
+	// OpRelease is the return of a connection to the pool. Its
+	// Duration is the time for which the connection was in use, and
+	// its Err is the last error that occurred on the connection.
+	OpRelease
+
+	// OpPrepare is the preparation of a statement on a connection.
+	OpPrepare
+
+	// OpExec is the execution of a query that returns no rows.
+	OpExec
+
+	// OpQuery is the execution of a query that returns rows. Its
+	// Duration does not include the time spent reading the rows.
+	OpQuery
+
+	// OpBegin is the start of a transaction.
+	OpBegin
+
+	// OpCommit is the commit of a transaction.
+	OpCommit
+
+	// OpRollback is the rollback of a transaction.
+	OpRollback
+)
+
+var opNames = [...]string{
+	OpConnect:  "Connect",
+	OpAcquire:  "Acquire",
+	OpRelease:  "Release",
+	OpPrepare:  "Prepare",
+	OpExec:     "Exec",
+	OpQuery:    "Query",
+	OpBegin:    "Begin",
+	OpCommit:   "Commit",
+	OpRollback: "Rollback",
+}
+
+// String returns the name of the operation.
+func (op Op) String() string {
+	if op > 0 && int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return "Op(" + strconv.Itoa(int(op)) + ")"
+}
+
+// An Event describes an operation reported to an Observer.
+type Event struct {
+	Op       Op
+	Query    string        // The query, for OpPrepare, OpExec and OpQuery.
+	Start    time.Time     // The time the operation started.
+	Duration time.Duration // The time the operation took.
+	Err      error         // The error returned by the operation, if any.
+}
+
+// observerValue is the type stored in DB.observer.
+type observerValue struct {
+	o Observer
+}
+
+// SetObserver sets the Observer notified of the operations of the DB.
+//
+// If o is nil, operations are no longer reported.
+func (db *DB) SetObserver(o Observer) {
+	db.observer.Store(observerValue{o})
+}
+
+// observerLoad returns the Observer of the DB, or nil.
+func (db *DB) observerLoad() Observer {
+	v, _ := db.observer.Load().(observerValue)
+	return v.o
+}
+
+// observeStart returns the start time of an operation, to be passed
+// to observe when it completes. It returns the zero Time if the DB
+// has no Observer, so that unobserved operations don't read the clock.
+func (db *DB) observeStart() time.Time {
+	if db.observerLoad() == nil {
+		return time.Time{}
+	}
+	return nowFunc()
+}
+
+// observe reports the operation op, started at start, to the Observer
+// of the DB. It does nothing if start is the zero Time.
+func (db *DB) observe(ctx context.Context, op Op, query string, start time.Time, err error) {
+	if start.IsZero() {
+		return
+	}
+	o := db.observerLoad()
+	if o == nil {
+		return
+	}
+	o.Observe(ctx, Event{
+		Op:       op,
+		Query:    query,
+		Start:    start,
+		Duration: nowFunc().Sub(start),
+		Err:      err,
+	})
+}
+
+// NumWaitBuckets is the number of buckets of DBStats.WaitHistogram.
+// Their upper bounds are 10µs, 100µs, 1ms, 10ms, 100ms, 1s, 10s
+// and, for the last bucket, math.MaxInt64.
+const NumWaitBuckets = 8
+
+// waitBucketBounds are the upper bounds of the buckets of
+// DBStats.WaitHistogram. The last bucket is unbounded.
+var waitBucketBounds = [NumWaitBuckets]time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+	math.MaxInt64,
+}
+
+// A WaitBucket is a bucket of DBStats.WaitHistogram.
+type WaitBucket struct {
+	// UpperBound is the longest wait counted in the bucket. Each bucket
+	// counts the waits longer than the UpperBound of the previous one.
+	// The UpperBound of the last bucket is math.MaxInt64.
+	UpperBound time.Duration
+
+	Count int64 // The number of waits in the bucket.
+}
+
+// recordWait adds a wait of duration d for a connection to the
+// statistics of the DB.
+func (db *DB) recordWait(d time.Duration) {
+	atomic.AddInt64(&db.waitDuration, int64(d))
+	i := 0
+	for d > waitBucketBounds[i] {
+		i++
+	}
+	atomic.AddInt64(&db.waitHistogram[i], 1)
+}
+
+// waitHistogramLoad returns the histogram of the waits for a connection.
+func (db *DB) waitHistogramLoad() [NumWaitBuckets]WaitBucket {
+	var h [NumWaitBuckets]WaitBucket
+	for i := range h {
+		h[i] = WaitBucket{
+			UpperBound: waitBucketBounds[i],
+			Count:      atomic.LoadInt64(&db.waitHistogram[i]),
+		}
+	}
+	return h
+}
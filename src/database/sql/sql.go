@@ -25,6 +25,7 @@ import (
 	"runtime"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -392,6 +393,49 @@ func (n NullTime) Value() (driver.Value, error) {
 	return n.Time, nil
 }
 
+// Null represents a value that may be null.
+// Null implements the Scanner interface so
+// it can be used as a scan destination:
+//
+//  var s Null[string]
+//  err := db.QueryRow("SELECT name FROM foo WHERE id=?", id).Scan(&s)
+//  ...
+//  if s.Valid {
+//     // use s.V
+//  } else {
+//     // NULL value
+//  }
+//
+type Null[T any] struct {
+	V     T
+	Valid bool // Valid is true if V is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (n *Null[T]) Scan(value any) error {
+	if value == nil {
+		n.V, n.Valid = *new(T), false
+		return nil
+	}
+	n.Valid = true
+	return convertAssign(&n.V, value)
+}
+
+// Value implements the driver Valuer interface.
+func (n Null[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	v := any(n.V)
+	if vr, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = callValuerValue(vr); err != nil {
+			return nil, err
+		}
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
 // Scanner is an interface used by Scan.
 type Scanner interface {
 	// Scan assigns a value from a database driver.
@@ -456,7 +500,8 @@ var ErrNoRows = errors.New("sql: no rows in result set")
 type DB struct {
 	// Atomic access only. At top of struct to prevent mis-alignment
 	// on 32-bit platforms. Of type time.Duration.
-	waitDuration int64 // Total time waited for new connections.
+	waitDuration  int64                 // Total time waited for new connections.
+	waitHistogram [NumWaitBuckets]int64 // Counts of waits for new connections, by duration.
 
 	connector driver.Connector
 	// numClosed is an atomic counter which represents a total number of
@@ -488,6 +533,8 @@ type DB struct {
 	maxIdleTimeClosed int64 // Total number of connections closed due to idle time.
 	maxLifetimeClosed int64 // Total number of connections closed due to max connection lifetime limit.
 
+	observer atomic.Value // observerValue
+
 	stop func() // stop cancels the connection opener.
 }
 
@@ -508,8 +555,9 @@ const (
 // interfaces returned via that Conn, such as calls on Tx, Stmt,
 // Result, Rows)
 type driverConn struct {
-	db        *DB
-	createdAt time.Time
+	db         *DB
+	createdAt  time.Time
+	acquiredAt time.Time // Time the connection was last acquired, if observed.
 
 	sync.Mutex  // guards following
 	ci          driver.Conn
@@ -575,7 +623,9 @@ func (dc *driverConn) validateConnection(needsReset bool) bool {
 // prepareLocked prepares the query on dc. When cg == nil the dc must keep track of
 // the prepared statements in a pool.
 func (dc *driverConn) prepareLocked(ctx context.Context, cg stmtConnGrabber, query string) (*driverStmt, error) {
+	start := dc.db.observeStart()
 	si, err := ctxDriverPrepare(ctx, dc.ci, query)
+	dc.db.observe(ctx, OpPrepare, query, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -1174,6 +1224,11 @@ type DBStats struct {
 	MaxIdleClosed     int64         // The total number of connections closed due to SetMaxIdleConns.
 	MaxIdleTimeClosed int64         // The total number of connections closed due to SetConnMaxIdleTime.
 	MaxLifetimeClosed int64         // The total number of connections closed due to SetConnMaxLifetime.
+
+	// WaitHistogram is the distribution of the times blocked waiting
+	// for a new connection, in buckets of increasing duration.
+	// See NumWaitBuckets for the bounds of the buckets.
+	WaitHistogram [NumWaitBuckets]WaitBucket
 }
 
 // Stats returns database statistics.
@@ -1195,6 +1250,7 @@ func (db *DB) Stats() DBStats {
 		MaxIdleClosed:     db.maxIdleClosed,
 		MaxIdleTimeClosed: db.maxIdleTimeClosed,
 		MaxLifetimeClosed: db.maxLifetimeClosed,
+		WaitHistogram:     db.waitHistogramLoad(),
 	}
 	return stats
 }
@@ -1237,7 +1293,9 @@ func (db *DB) openNewConnection(ctx context.Context) {
 	// maybeOpenNewConnections has already executed db.numOpen++ before it sent
 	// on db.openerCh. This function must execute db.numOpen-- if the
 	// connection fails or is closed before returning.
+	start := db.observeStart()
 	ci, err := db.connector.Connect(ctx)
+	db.observe(ctx, OpConnect, "", start, err)
 	db.mu.Lock()
 	defer db.mu.Unlock()
 	if db.closed {
@@ -1286,7 +1344,15 @@ func (db *DB) nextRequestKeyLocked() uint64 {
 }
 
 // conn returns a newly-opened or cached *driverConn.
-func (db *DB) conn(ctx context.Context, strategy connReuseStrategy) (*driverConn, error) {
+func (db *DB) conn(ctx context.Context, strategy connReuseStrategy) (dc *driverConn, err error) {
+	if start := db.observeStart(); !start.IsZero() {
+		defer func() {
+			db.observe(ctx, OpAcquire, "", start, err)
+			if dc != nil {
+				dc.acquiredAt = nowFunc()
+			}
+		}()
+	}
 	db.mu.Lock()
 	if db.closed {
 		db.mu.Unlock()
@@ -1348,7 +1414,7 @@ func (db *DB) conn(ctx context.Context, strategy connReuseStrategy) (*driverConn
 			delete(db.connRequests, reqKey)
 			db.mu.Unlock()
 
-			atomic.AddInt64(&db.waitDuration, int64(time.Since(waitStart)))
+			db.recordWait(time.Since(waitStart))
 
 			select {
 			default:
@@ -1359,7 +1425,7 @@ func (db *DB) conn(ctx context.Context, strategy connReuseStrategy) (*driverConn
 			}
 			return nil, ctx.Err()
 		case ret, ok := <-req:
-			atomic.AddInt64(&db.waitDuration, int64(time.Since(waitStart)))
+			db.recordWait(time.Since(waitStart))
 
 			if !ok {
 				return nil, errDBClosed
@@ -1392,7 +1458,9 @@ func (db *DB) conn(ctx context.Context, strategy connReuseStrategy) (*driverConn
 
 	db.numOpen++ // optimistically
 	db.mu.Unlock()
+	start := db.observeStart()
 	ci, err := db.connector.Connect(ctx)
+	db.observe(ctx, OpConnect, "", start, err)
 	if err != nil {
 		db.mu.Lock()
 		db.numOpen-- // correct for earlier optimism
@@ -1401,7 +1469,7 @@ func (db *DB) conn(ctx context.Context, strategy connReuseStrategy) (*driverConn
 		return nil, err
 	}
 	db.mu.Lock()
-	dc := &driverConn{
+	dc = &driverConn{
 		db:         db,
 		createdAt:  nowFunc(),
 		returnedAt: nowFunc(),
@@ -1443,6 +1511,10 @@ const debugGetPut = false
 // putConn adds a connection to the db's free pool.
 // err is optionally the last error that occurred on this connection.
 func (db *DB) putConn(dc *driverConn, err error, resetSession bool) {
+	if !dc.acquiredAt.IsZero() {
+		db.observe(context.Background(), OpRelease, "", dc.acquiredAt, err)
+		dc.acquiredAt = time.Time{}
+	}
 	if !errors.Is(err, driver.ErrBadConn) {
 		if !dc.validateConnection(resetSession) {
 			err = driver.ErrBadConn
@@ -1662,7 +1734,9 @@ func (db *DB) exec(ctx context.Context, query string, args []any, strategy connR
 }
 
 func (db *DB) execDC(ctx context.Context, dc *driverConn, release func(error), query string, args []any) (res Result, err error) {
+	start := db.observeStart()
 	defer func() {
+		db.observe(ctx, OpExec, query, start, err)
 		release(err)
 	}()
 	execerCtx, ok := dc.ci.(driver.ExecerContext)
@@ -1742,6 +1816,7 @@ func (db *DB) query(ctx context.Context, query string, args []any, strategy conn
 // The ctx context is from a query method and the txctx context is from an
 // optional transaction context.
 func (db *DB) queryDC(ctx, txctx context.Context, dc *driverConn, releaseConn func(error), query string, args []any) (*Rows, error) {
+	start := db.observeStart()
 	queryerCtx, ok := dc.ci.(driver.QueryerContext)
 	var queryer driver.Queryer
 	if !ok {
@@ -1759,6 +1834,7 @@ func (db *DB) queryDC(ctx, txctx context.Context, dc *driverConn, releaseConn fu
 			rowsi, err = ctxDriverQuery(ctx, queryerCtx, queryer, query, nvdargs)
 		})
 		if err != driver.ErrSkip {
+			db.observe(ctx, OpQuery, query, start, err)
 			if err != nil {
 				releaseConn(err)
 				return nil, err
@@ -1781,12 +1857,14 @@ func (db *DB) queryDC(ctx, txctx context.Context, dc *driverConn, releaseConn fu
 		si, err = ctxDriverPrepare(ctx, dc.ci, query)
 	})
 	if err != nil {
+		db.observe(ctx, OpQuery, query, start, err)
 		releaseConn(err)
 		return nil, err
 	}
 
 	ds := &driverStmt{Locker: dc, si: si}
 	rowsi, err := rowsiFromStatement(ctx, dc.ci, ds, args...)
+	db.observe(ctx, OpQuery, query, start, err)
 	if err != nil {
 		ds.Close()
 		releaseConn(err)
@@ -1877,12 +1955,14 @@ func (db *DB) begin(ctx context.Context, opts *TxOptions, strategy connReuseStra
 func (db *DB) beginDC(ctx context.Context, dc *driverConn, release func(error), opts *TxOptions) (tx *Tx, err error) {
 	var txi driver.Tx
 	keepConnOnRollback := false
+	start := db.observeStart()
 	withLock(dc, func() {
 		_, hasSessionResetter := dc.ci.(driver.SessionResetter)
 		_, hasConnectionValidator := dc.ci.(driver.Validator)
 		keepConnOnRollback = hasSessionResetter && hasConnectionValidator
 		txi, err = ctxDriverBegin(ctx, opts, dc.ci)
 	})
+	db.observe(ctx, OpBegin, "", start, err)
 	if err != nil {
 		release(err)
 		return nil, err
@@ -2286,9 +2366,11 @@ func (tx *Tx) Commit() error {
 	tx.closemu.Unlock()
 
 	var err error
+	start := tx.db.observeStart()
 	withLock(tx.dc, func() {
 		err = tx.txi.Commit()
 	})
+	tx.db.observe(tx.ctx, OpCommit, "", start, err)
 	if !errors.Is(err, driver.ErrBadConn) {
 		tx.closePrepared()
 	}
@@ -2318,9 +2400,11 @@ func (tx *Tx) rollback(discardConn bool) error {
 	tx.closemu.Unlock()
 
 	var err error
+	start := tx.db.observeStart()
 	withLock(tx.dc, func() {
 		err = tx.txi.Rollback()
 	})
+	tx.db.observe(tx.ctx, OpRollback, "", start, err)
 	if !errors.Is(err, driver.ErrBadConn) {
 		tx.closePrepared()
 	}
@@ -2411,9 +2495,11 @@ func (tx *Tx) StmtContext(ctx context.Context, stmt *Stmt) *Stmt {
 		// re-prepare the statement in this case. No need to add
 		// code-complexity for this.
 		stmt.mu.Unlock()
+		start := tx.db.observeStart()
 		withLock(dc, func() {
 			si, err = ctxDriverPrepare(ctx, dc.ci, stmt.query)
 		})
+		tx.db.observe(ctx, OpPrepare, stmt.query, start, err)
 		if err != nil {
 			return &Stmt{stickyErr: err}
 		}
@@ -2633,7 +2719,9 @@ func (s *Stmt) ExecContext(ctx context.Context, args ...any) (Result, error) {
 			return nil, err
 		}
 
+		start := s.db.observeStart()
 		res, err = resultFromStatement(ctx, dc.ci, ds, args...)
+		s.db.observe(ctx, OpExec, s.query, start, err)
 		releaseConn(err)
 		if !errors.Is(err, driver.ErrBadConn) {
 			return res, err
@@ -2781,7 +2869,9 @@ func (s *Stmt) QueryContext(ctx context.Context, args ...any) (*Rows, error) {
 			return nil, err
 		}
 
+		start := s.db.observeStart()
 		rowsi, err = rowsiFromStatement(ctx, dc.ci, ds, args...)
+		s.db.observe(ctx, OpQuery, s.query, start, err)
 		if err == nil {
 			// Note: ownership of ci passes to the *Rows, to be freed
 			// with releaseConn.
@@ -3261,6 +3351,10 @@ func rowsColumnInfoSetupConnLocked(rowsi driver.Rows) []*ColumnType {
 // *Rows value that can itself be scanned from. The parent
 // select query will close any cursor *Rows if the parent *Rows is closed.
 //
+// If the driver's Rows implement driver.RowsColumnScanner, the driver
+// may scan columns directly into the destinations it supports, such as
+// slices for array columns, instead of the conversions described above.
+//
 // If any of the first arguments implementing Scanner returns an error,
 // that error will be wrapped in the returned error
 func (rs *Rows) Scan(dest ...any) error {
@@ -3283,8 +3377,17 @@ func (rs *Rows) Scan(dest ...any) error {
 	if len(dest) != len(rs.lastcols) {
 		return fmt.Errorf("sql: expected %d destination arguments in Scan, not %d", len(rs.lastcols), len(dest))
 	}
+	colScanner, _ := rs.rowsi.(driver.RowsColumnScanner)
 	for i, sv := range rs.lastcols {
-		err := convertAssignRows(dest[i], sv, rs)
+		err := driver.ErrSkip
+		if colScanner != nil {
+			withLock(rs.dc, func() {
+				err = colScanner.ScanColumn(dest[i], i)
+			})
+		}
+		if err == driver.ErrSkip {
+			err = convertAssignRows(dest[i], sv, rs)
+		}
 		if err != nil {
 			return fmt.Errorf(`sql: Scan error on column index %d, name %q: %w`, i, rs.rowsi.Columns()[i], err)
 		}
@@ -3292,6 +3395,52 @@ func (rs *Rows) Scan(dest ...any) error {
 	return nil
 }
 
+// ScanStruct copies the columns in the current row into the fields of
+// the struct pointed at by dest. Each column is copied as by Scan into
+// the field of the same name.
+//
+// The name of a field is its name in the struct, or the name given by
+// its "sql" struct tag, as in `sql:"first_name"`. Names are matched
+// against column names without regard to case. Fields with the tag
+// `sql:"-"` and unexported fields are ignored. The fields of an
+// embedded struct are treated as if they were fields of the outer
+// struct, unless the embedded struct implements Scanner; a field in
+// the outer struct hides a field of the same name in an embedded one.
+//
+// It is an error for a column to have no corresponding field. Fields
+// that have no corresponding column are left unchanged.
+func (rs *Rows) ScanStruct(dest any) error {
+	fields, err := rs.structDest(dest)
+	if err != nil {
+		return err
+	}
+	return rs.Scan(fields...)
+}
+
+// structDest returns pointers to the fields of the struct pointed at
+// by dest that correspond to the columns of rs, for ScanStruct.
+func (rs *Rows) structDest(dest any) ([]any, error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("sql: ScanStruct destination must be a non-nil pointer to a struct, not %T", dest)
+	}
+	cols, err := rs.Columns()
+	if err != nil {
+		return nil, err
+	}
+	v = v.Elem()
+	fields := structColumnFields(v.Type())
+	ptrs := make([]any, len(cols))
+	for i, col := range cols {
+		index, ok := fields[strings.ToLower(col)]
+		if !ok {
+			return nil, fmt.Errorf("sql: no field in %v for column index %d, name %q", v.Type(), i, col)
+		}
+		ptrs[i] = v.FieldByIndex(index).Addr().Interface()
+	}
+	return ptrs, nil
+}
+
 // rowsCloseHook returns a function so tests may install the
 // hook through a test only mutex.
 var rowsCloseHook = func() func(*Rows, *error) { return nil }
@@ -3385,6 +3534,23 @@ func (r *Row) Scan(dest ...any) error {
 	return r.rows.Close()
 }
 
+// ScanStruct copies the columns from the matched row into the fields
+// of the struct pointed at by dest, as described by Rows.ScanStruct.
+// If more than one row matches the query, ScanStruct uses the first
+// row and discards the rest. If no row matches the query, ScanStruct
+// returns ErrNoRows.
+func (r *Row) ScanStruct(dest any) error {
+	if r.err != nil {
+		return r.err
+	}
+	fields, err := r.rows.structDest(dest)
+	if err != nil {
+		r.rows.Close()
+		return err
+	}
+	return r.Scan(fields...)
+}
+
 // Err provides a way for wrapping packages to check for
 // query errors without calling Scan.
 // Err returns the error, if any, that was encountered while running the query.
@@ -9,6 +9,7 @@ import (
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"reflect"
 	"runtime"
@@ -838,6 +839,132 @@ func TestQueryRow(t *testing.T) {
 	}
 }
 
+type scanPersonName struct {
+	Name string
+}
+
+type scanPerson struct {
+	scanPersonName
+	Years    int32     `sql:"age"`
+	Photo    []byte    `sql:"-"`
+	Birthday time.Time `sql:"bdate"`
+	Other    string
+	age      int
+}
+
+func TestScanStruct(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	rows, err := db.Query("SELECT|people|age,name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []scanPerson
+	for rows.Next() {
+		p := scanPerson{Other: "unchanged"}
+		if err := rows.ScanStruct(&p); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, p)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []scanPerson{
+		{scanPersonName: scanPersonName{"Alice"}, Years: 1, Other: "unchanged"},
+		{scanPersonName: scanPersonName{"Bob"}, Years: 2, Other: "unchanged"},
+		{scanPersonName: scanPersonName{"Chris"}, Years: 3, Other: "unchanged"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scanned %+v\nwant %+v", got, want)
+	}
+
+	var p scanPerson
+	err = db.QueryRow("SELECT|people|bdate,name|age=?", 3).ScanStruct(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Chris" || !p.Birthday.Equal(chrisBirthday) {
+		t.Errorf("QueryRow.ScanStruct: got %+v", p)
+	}
+
+	err = db.QueryRow("SELECT|people|name|age=?", 4).ScanStruct(&p)
+	if err != ErrNoRows {
+		t.Errorf("ScanStruct of no rows: got %v, want ErrNoRows", err)
+	}
+
+	err = db.QueryRow("SELECT|people|name,photo|age=?", 1).ScanStruct(&p)
+	if err == nil || !strings.Contains(err.Error(), `no field in sql.scanPerson for column index 1, name "photo"`) {
+		t.Errorf("ScanStruct with an ignored field: got %v", err)
+	}
+
+	for _, dest := range []any{p, (*scanPerson)(nil), new(int)} {
+		err = db.QueryRow("SELECT|people|name|age=?", 1).ScanStruct(dest)
+		if err == nil || !strings.Contains(err.Error(), "must be a non-nil pointer to a struct") {
+			t.Errorf("ScanStruct(%T): got %v", dest, err)
+		}
+	}
+}
+
+func TestStructColumnFields(t *testing.T) {
+	type Inner struct {
+		A, B int
+		C    int `sql:"x"`
+	}
+	type Other struct {
+		B int
+	}
+	type outer struct {
+		Inner
+		Other
+		NullString
+		A int `sql:"a"`
+		D NullInt64
+		E Inner `sql:"e"`
+	}
+	got := structColumnFields(reflect.TypeOf(outer{}))
+	want := map[string][]int{
+		"a":          {3},
+		"x":          {0, 2},
+		"nullstring": {2},
+		"d":          {4},
+		"e":          {5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("structColumnFields = %v, want %v", got, want)
+	}
+}
+
+func TestRowsColumnScanner(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|id=int32,tags=string")
+	exec(t, db, "INSERT|t|id=1,tags=?", "a,b,c")
+
+	var tags []string
+	if err := db.QueryRow("SELECT|t|tags|id=?", 1).Scan(&tags); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags = %q, want %q", tags, want)
+	}
+
+	// Destinations the driver doesn't support are converted as usual.
+	var s string
+	if err := db.QueryRow("SELECT|t|tags|id=?", 1).Scan(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s != "a,b,c" {
+		t.Errorf("s = %q, want %q", s, "a,b,c")
+	}
+
+	if err := db.QueryRow("SELECT|t|id|id=?", 1).Scan(&tags); err == nil ||
+		!strings.Contains(err.Error(), `Scan error on column index 0, name "id": fakedb: can't scan`) {
+		t.Errorf("Scan of int32 column into *[]string: got %v", err)
+	}
+}
+
 func TestRowErr(t *testing.T) {
 	db := newTestDB(t, "people")
 
@@ -1883,6 +2010,54 @@ func TestNullBoolParam(t *testing.T) {
 	nullTestRun(t, spec)
 }
 
+func TestGenericNullStringParam(t *testing.T) {
+	spec := nullTestSpec{"nullstring", "string", [6]nullTestRow{
+		{Null[string]{"aqua", true}, "", Null[string]{"aqua", true}},
+		{Null[string]{"brown", false}, "", Null[string]{"", false}},
+		{"chartreuse", "", Null[string]{"chartreuse", true}},
+		{Null[string]{"darkred", true}, "", Null[string]{"darkred", true}},
+		{Null[string]{"eel", false}, "", Null[string]{"", false}},
+		{"foo", Null[string]{"black", false}, nil},
+	}}
+	nullTestRun(t, spec)
+}
+
+func TestGenericNullInt32Param(t *testing.T) {
+	spec := nullTestSpec{"nullint32", "int32", [6]nullTestRow{
+		{Null[int32]{31, true}, 1, Null[int32]{31, true}},
+		{Null[int32]{-22, false}, 1, Null[int32]{0, false}},
+		{22, 1, Null[int32]{22, true}},
+		{Null[int32]{33, true}, 1, Null[int32]{33, true}},
+		{Null[int32]{222, false}, 1, Null[int32]{0, false}},
+		{0, Null[int32]{31, false}, nil},
+	}}
+	nullTestRun(t, spec)
+}
+
+func TestGenericNullValue(t *testing.T) {
+	tests := []struct {
+		in   driver.Valuer
+		want driver.Value
+	}{
+		{Null[int]{}, nil},
+		{Null[int]{V: 7, Valid: true}, int64(7)},
+		{Null[uint8]{V: 7, Valid: true}, int64(7)},
+		{Null[float32]{V: 1.5, Valid: true}, float64(1.5)},
+		{Null[string]{V: "x", Valid: true}, "x"},
+		{Null[NullString]{V: NullString{"y", true}, Valid: true}, "y"},
+		{Null[NullString]{V: NullString{"y", false}, Valid: true}, nil},
+	}
+	for _, tt := range tests {
+		got, err := tt.in.Value()
+		if err != nil || got != tt.want {
+			t.Errorf("%#v.Value() = %#v, %v; want %#v, nil", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := (Null[struct{}]{Valid: true}).Value(); err == nil {
+		t.Errorf("Value of Null[struct{}] succeeded, want error")
+	}
+}
+
 func TestNullTimeParam(t *testing.T) {
 	t0 := time.Time{}
 	t1 := time.Date(2000, 1, 1, 8, 9, 10, 11, time.UTC)
@@ -3915,6 +4090,220 @@ func TestStatsMaxIdleClosedTen(t *testing.T) {
 	}
 }
 
+// recordingObserver records the events it observes.
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (o *recordingObserver) Observe(ctx context.Context, e Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, e)
+}
+
+// take returns the observed events, as "Op query" strings,
+// and forgets them.
+func (o *recordingObserver) take() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var ops []string
+	for _, e := range o.events {
+		s := e.Op.String()
+		if e.Query != "" {
+			s += " " + e.Query
+		}
+		if e.Err != nil {
+			s += ": " + e.Err.Error()
+		}
+		ops = append(ops, s)
+	}
+	o.events = nil
+	return ops
+}
+
+func TestObserver(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxIdleConns(1)
+
+	o := new(recordingObserver)
+	db.SetObserver(o)
+	check := func(want ...string) {
+		t.Helper()
+		if got := o.take(); !reflect.DeepEqual(got, want) {
+			t.Errorf("observed events:\n%q\nwant:\n%q", got, want)
+		}
+	}
+
+	const query = "SELECT|people|name|"
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("Acquire", "Query "+query)
+	rows.Close()
+	check("Release")
+
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("Acquire", "Prepare "+query, "Release")
+	rows, err = stmt.Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	stmt.Close()
+	check("Acquire", "Query "+query, "Release")
+
+	const bad = "BOGUS"
+	if _, err := db.Exec(bad); err == nil {
+		t.Fatal("Exec succeeded, want error")
+	}
+	if got := o.take(); len(got) != 3 || got[0] != "Acquire" || !strings.HasPrefix(got[1], "Exec "+bad+": ") || !strings.HasPrefix(got[2], "Release: ") {
+		t.Errorf("observed events for bad Exec: %q", got)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	check("Acquire", "Begin", "Commit", "Release")
+
+	tx, err = db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	check("Acquire", "Begin", "Rollback", "Release")
+
+	// Without idle connections, every connection is opened.
+	db.SetMaxIdleConns(0)
+	for i := 0; i < 2; i++ {
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		check("Connect", "Acquire", "Release")
+	}
+
+	db.SetObserver(nil)
+	if _, err := db.Exec("WIPE"); err != nil {
+		t.Fatal(err)
+	}
+	check()
+}
+
+func TestObserverDurations(t *testing.T) {
+	defer func() { nowFunc = time.Now }()
+	var now time.Time
+	nowFunc = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	o := new(recordingObserver)
+	db.SetObserver(o)
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.PingContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if len(o.events) != 2 {
+		t.Fatalf("observed %d events, want 2", len(o.events))
+	}
+	for _, e := range o.events {
+		if e.Start.IsZero() || e.Duration <= 0 {
+			t.Errorf("%v event: Start = %v, Duration = %v", e.Op, e.Start, e.Duration)
+		}
+	}
+	if acquire, release := o.events[0], o.events[1]; release.Start.Before(acquire.Start.Add(acquire.Duration)) {
+		t.Errorf("Release started at %v, before Acquire completed at %v", release.Start, acquire.Start.Add(acquire.Duration))
+	}
+}
+
+func TestOpString(t *testing.T) {
+	for op, want := range map[Op]string{
+		OpConnect:  "Connect",
+		OpRollback: "Rollback",
+		0:          "Op(0)",
+		100:        "Op(100)",
+	} {
+		if got := op.String(); got != want {
+			t.Errorf("Op(%d).String() = %q, want %q", int(op), got, want)
+		}
+	}
+}
+
+func TestStatsWaitHistogram(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const waiters = 3
+	done := make(chan error)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			c, err := db.Conn(ctx)
+			if err == nil {
+				err = c.Close()
+			}
+			done <- err
+		}()
+	}
+	waitCondition(t, func() bool {
+		return db.Stats().WaitCount == waiters
+	})
+	time.Sleep(20 * time.Millisecond)
+	conn.Close()
+	for i := 0; i < waiters; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st := db.Stats()
+	var n int64
+	prev := time.Duration(0)
+	for i, b := range st.WaitHistogram {
+		if b.UpperBound <= prev {
+			t.Errorf("WaitHistogram[%d].UpperBound = %v, not above %v", i, b.UpperBound, prev)
+		}
+		prev = b.UpperBound
+		n += b.Count
+		// Every waiter waited at least 20ms.
+		if b.UpperBound < 20*time.Millisecond && b.Count != 0 {
+			t.Errorf("WaitHistogram[%d] = %+v, want no waits", i, b)
+		}
+	}
+	if last := st.WaitHistogram[len(st.WaitHistogram)-1].UpperBound; last != math.MaxInt64 {
+		t.Errorf("last bucket UpperBound = %v, want unbounded", last)
+	}
+	if n != st.WaitCount {
+		t.Errorf("WaitHistogram counts %d waits, WaitCount is %d", n, st.WaitCount)
+	}
+}
+
 // testUseConns uses count concurrent connections with 1 nanosecond apart.
 // Returns the returnedAt time of the final connection.
 func testUseConns(t *testing.T, count int, tm time.Time, db *DB) time.Time {
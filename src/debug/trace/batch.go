@@ -0,0 +1,325 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Event types in the trace, written by the runtime. See runtime/trace.go.
+const (
+	evNone              = 0  // unused
+	evEventBatch        = 1  // start of a batch of events [generation, M id, timestamp, batch length]
+	evFrequency         = 2  // tracer timer frequency [frequency (ticks per second)]
+	evStack             = 3  // stack [stack id, number of frames, array of {PC, func string ID, file string ID, line}]
+	evString            = 4  // string dictionary entry [string ID, length, string]
+	evProcsChange       = 5  // current value of GOMAXPROCS [timestamp, GOMAXPROCS, stack ID]
+	evProcStart         = 6  // start of P [timestamp, P ID, P seq]
+	evProcStop          = 7  // stop of P [timestamp]
+	evProcSteal         = 8  // P was taken away from an M in a syscall [timestamp, P ID, P seq, M ID]
+	evProcStatus        = 9  // P status at the start of a generation [timestamp, P ID, status]
+	evGoCreate          = 10 // goroutine creation [timestamp, new goroutine ID, new stack ID, stack ID]
+	evGoCreateSyscall   = 11 // goroutine appears in a syscall, from a cgo callback [timestamp, new goroutine ID, new stack ID]
+	evGoStart           = 12 // goroutine starts running [timestamp, goroutine ID, goroutine seq]
+	evGoDestroy         = 13 // goroutine ends [timestamp]
+	evGoDestroySyscall  = 14 // goroutine ends in a syscall, returning from a cgo callback [timestamp]
+	evGoStop            = 15 // goroutine yields its time, but is runnable [timestamp, reason string ID, stack ID]
+	evGoBlock           = 16 // goroutine blocks [timestamp, reason string ID, stack ID]
+	evGoUnblock         = 17 // goroutine is unblocked [timestamp, goroutine ID, goroutine seq, stack ID]
+	evGoSyscallBegin    = 18 // syscall enter [timestamp, P seq, stack ID]
+	evGoSyscallEnd      = 19 // syscall exit, on the P of the syscall [timestamp]
+	evGoSyscallEndBlock = 20 // syscall exit, after the P was taken away [timestamp]
+	evGoStatus          = 21 // goroutine status at the start of a generation [timestamp, goroutine ID, M ID, status, start stack ID]
+	evSTWBegin          = 22 // stop-the-world start [timestamp, kind string ID]
+	evSTWEnd            = 23 // stop-the-world done [timestamp]
+	evGCBegin           = 24 // GC start [timestamp, GC seq, stack ID]
+	evGCEnd             = 25 // GC done [timestamp, GC seq]
+	evGCSweepBegin      = 26 // GC sweep start [timestamp, stack ID]
+	evGCSweepEnd        = 27 // GC sweep done [timestamp, swept bytes, reclaimed bytes]
+	evGCMarkAssistBegin = 28 // GC mark assist start [timestamp, stack ID]
+	evGCMarkAssistEnd   = 29 // GC mark assist done [timestamp]
+	evHeapAlloc         = 30 // gcController.heapLive change [timestamp, heap alloc in bytes]
+	evHeapGoal          = 31 // gcController.heapGoal change [timestamp, heap goal in bytes]
+	evGoLabel           = 32 // label of the goroutine that just started [timestamp, label string ID]
+	evUserTaskBegin     = 33 // trace.NewTask [timestamp, internal task ID, internal parent task ID, name string ID, stack ID]
+	evUserTaskEnd       = 34 // end of a task [timestamp, internal task ID, stack ID]
+	evUserRegionBegin   = 35 // trace.{Start,With}Region [timestamp, internal task ID, name string ID, stack ID]
+	evUserRegionEnd     = 36 // trace.{End,With}Region [timestamp, internal task ID, name string ID, stack ID]
+	evUserLog           = 37 // trace.Log [timestamp, internal task ID, key string ID, stack ID, value string]
+	evCount             = 38
+)
+
+// evInfo describes the events of a batch of an M.
+var evInfo = [evCount]struct {
+	name  string
+	kind  EventKind
+	nargs int // number of arguments, not counting the timestamp
+	stack int // index of the stack ID argument, or -1
+}{
+	evProcsChange:       {"ProcsChange", EventMetric, 2, 1},
+	evProcStart:         {"ProcStart", EventStateTransition, 2, -1},
+	evProcStop:          {"ProcStop", EventStateTransition, 0, -1},
+	evProcSteal:         {"ProcSteal", EventStateTransition, 3, -1},
+	evProcStatus:        {"ProcStatus", EventStateTransition, 2, -1},
+	evGoCreate:          {"GoCreate", EventStateTransition, 3, 2},
+	evGoCreateSyscall:   {"GoCreateSyscall", EventStateTransition, 2, -1},
+	evGoStart:           {"GoStart", EventStateTransition, 2, -1},
+	evGoDestroy:         {"GoDestroy", EventStateTransition, 0, -1},
+	evGoDestroySyscall:  {"GoDestroySyscall", EventStateTransition, 0, -1},
+	evGoStop:            {"GoStop", EventStateTransition, 2, 1},
+	evGoBlock:           {"GoBlock", EventStateTransition, 2, 1},
+	evGoUnblock:         {"GoUnblock", EventStateTransition, 3, 2},
+	evGoSyscallBegin:    {"GoSyscallBegin", EventStateTransition, 2, 1},
+	evGoSyscallEnd:      {"GoSyscallEnd", EventStateTransition, 0, -1},
+	evGoSyscallEndBlock: {"GoSyscallEndBlock", EventStateTransition, 0, -1},
+	evGoStatus:          {"GoStatus", EventStateTransition, 4, -1},
+	evSTWBegin:          {"STWBegin", EventRangeBegin, 1, -1},
+	evSTWEnd:            {"STWEnd", EventRangeEnd, 0, -1},
+	evGCBegin:           {"GCBegin", EventRangeBegin, 2, 1},
+	evGCEnd:             {"GCEnd", EventRangeEnd, 1, -1},
+	evGCSweepBegin:      {"GCSweepBegin", EventRangeBegin, 1, 0},
+	evGCSweepEnd:        {"GCSweepEnd", EventRangeEnd, 2, -1},
+	evGCMarkAssistBegin: {"GCMarkAssistBegin", EventRangeBegin, 1, 0},
+	evGCMarkAssistEnd:   {"GCMarkAssistEnd", EventRangeEnd, 0, -1},
+	evHeapAlloc:         {"HeapAlloc", EventMetric, 1, -1},
+	evHeapGoal:          {"HeapGoal", EventMetric, 1, -1},
+	evGoLabel:           {"GoLabel", EventLabel, 1, -1},
+	evUserTaskBegin:     {"UserTaskBegin", EventTaskBegin, 4, 3},
+	evUserTaskEnd:       {"UserTaskEnd", EventTaskEnd, 2, 1},
+	evUserRegionBegin:   {"UserRegionBegin", EventRegionBegin, 3, 2},
+	evUserRegionEnd:     {"UserRegionEnd", EventRegionEnd, 3, 2},
+	evUserLog:           {"UserLog", EventLog, 3, 2},
+}
+
+// Goroutine statuses in evGoStatus.
+const (
+	goStatusRunnable = 1
+	goStatusRunning  = 2
+	goStatusSyscall  = 3
+	goStatusWaiting  = 4
+)
+
+// P statuses in evProcStatus.
+const (
+	procStatusRunning = 1
+	procStatusIdle    = 2
+)
+
+// noM is the M ID of the batches of the tables of a generation.
+const noM = ^uint64(0)
+
+// A batch is a sequence of events written by one M, or of the entries
+// of the tables of a generation, without any events of a different
+// generation in between.
+type batch struct {
+	gen   uint64
+	m     uint64
+	ticks uint64 // timestamp at the start of the batch
+	data  []byte
+	off   int // offset of data in the trace
+}
+
+// rawEvent is an event of a batch of an M, as read from the trace.
+type rawEvent struct {
+	typ  byte
+	time Time
+	args [4]uint64
+	str  string // the value of evUserLog, or a string resolved by the reader
+	off  int
+}
+
+// readBatch reads the next batch from r, whose offset in the trace is
+// off. It returns io.EOF at the end of the trace.
+func readBatch(r *bufio.Reader, off int) (batch, int, error) {
+	typ, err := r.ReadByte()
+	if err == io.EOF {
+		return batch{}, off, io.EOF
+	}
+	if err != nil {
+		return batch{}, off, err
+	}
+	if typ != evEventBatch {
+		return batch{}, off, fmt.Errorf("trace: expected batch at offset %d, found event type %d", off, typ)
+	}
+	off++
+	var hdr [4]uint64
+	for i := range hdr {
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			return batch{}, off, fmt.Errorf("trace: reading batch header at offset %d: %v", off, unexpectedEOF(err))
+		}
+		hdr[i] = v
+	}
+	// The length is padded to a fixed size, see traceFullQueue.
+	off += uvarintLen(hdr[0]) + uvarintLen(hdr[1]) + uvarintLen(hdr[2]) + 10
+	b := batch{gen: hdr[0], m: hdr[1], ticks: hdr[2], off: off}
+	if hdr[3] > 1<<30 {
+		return batch{}, off, fmt.Errorf("trace: batch at offset %d is too long (%d bytes)", off, hdr[3])
+	}
+	b.data = make([]byte, hdr[3])
+	if _, err := io.ReadFull(r, b.data); err != nil {
+		return batch{}, off, fmt.Errorf("trace: reading batch at offset %d: %v", off, unexpectedEOF(err))
+	}
+	off += len(b.data)
+	return b, off, nil
+}
+
+func uvarintLen(v uint64) int {
+	n := 1
+	for ; v >= 0x80; v >>= 7 {
+		n++
+	}
+	return n
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// errMalformed is returned for a batch whose contents cannot be
+// decoded.
+var errMalformed = errors.New("malformed batch")
+
+// decoder decodes the contents of a batch.
+type decoder struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (d *decoder) done() bool {
+	return d.err != nil || d.pos >= len(d.data)
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		d.err = errMalformed
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if d.pos >= len(d.data) {
+		d.err = errMalformed
+		return 0
+	}
+	v := d.data[d.pos]
+	d.pos++
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if n > uint64(len(d.data)-d.pos) {
+		d.err = errMalformed
+		return ""
+	}
+	s := string(d.data[d.pos : d.pos+int(n)])
+	d.pos += int(n)
+	return s
+}
+
+// frame is a frame of a stack in the tables of a generation.
+type frame struct {
+	pc     uint64
+	funcID uint64
+	fileID uint64
+	line   uint64
+}
+
+// generation is a part of the trace that can be decoded on its own.
+type generation struct {
+	gen     uint64
+	freq    uint64 // ticks per second
+	strings map[uint64]string
+	stacks  map[uint64][]frame
+	batches map[uint64][]batch // batches of events by M, in order
+}
+
+// addTables decodes a batch of the tables of the generation.
+func (g *generation) addTables(b batch) error {
+	d := decoder{data: b.data}
+	for !d.done() {
+		off := b.off + d.pos
+		switch typ := d.byte(); typ {
+		case evFrequency:
+			freq := d.uvarint()
+			if d.err == nil && freq == 0 {
+				return fmt.Errorf("trace: zero frequency at offset %d", off)
+			}
+			g.freq = freq
+		case evStack:
+			id := d.uvarint()
+			n := d.uvarint()
+			if n > uint64(len(d.data)) {
+				return fmt.Errorf("trace: stack at offset %d has too many frames (%d)", off, n)
+			}
+			frames := make([]frame, n)
+			for i := range frames {
+				frames[i] = frame{pc: d.uvarint(), funcID: d.uvarint(), fileID: d.uvarint(), line: d.uvarint()}
+			}
+			if _, dup := g.stacks[id]; dup {
+				return fmt.Errorf("trace: stack at offset %d has duplicate id %d", off, id)
+			}
+			g.stacks[id] = frames
+		case evString:
+			id := d.uvarint()
+			s := d.string()
+			if id == 0 {
+				return fmt.Errorf("trace: string at offset %d has invalid id 0", off)
+			}
+			if _, dup := g.strings[id]; dup {
+				return fmt.Errorf("trace: string at offset %d has duplicate id %d", off, id)
+			}
+			g.strings[id] = s
+		default:
+			return fmt.Errorf("trace: unexpected event type %d in table batch at offset %d", typ, off)
+		}
+	}
+	if d.err != nil {
+		return fmt.Errorf("trace: table batch at offset %d: %v", b.off, d.err)
+	}
+	return nil
+}
+
+// readEvent decodes the next event of the batch of an M from d, whose
+// events before the one to decode end at ticks.
+func readEvent(d *decoder, b *batch, ticks *uint64) (rawEvent, error) {
+	ev := rawEvent{off: b.off + d.pos}
+	ev.typ = d.byte()
+	if ev.typ >= evCount || evInfo[ev.typ].name == "" {
+		return rawEvent{}, fmt.Errorf("trace: unknown event type %d at offset %d", ev.typ, ev.off)
+	}
+	*ticks += d.uvarint()
+	for i := 0; i < evInfo[ev.typ].nargs; i++ {
+		ev.args[i] = d.uvarint()
+	}
+	if ev.typ == evUserLog {
+		ev.str = d.string()
+	}
+	if d.err != nil {
+		return rawEvent{}, fmt.Errorf("trace: event at offset %d: %v", ev.off, d.err)
+	}
+	return ev, nil
+}
@@ -0,0 +1,615 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Time is a timestamp in nanoseconds.
+//
+// It corresponds to the monotonic clock on the platform that the trace
+// was taken on, and has an arbitrary origin. Only differences between
+// timestamps of the same trace are meaningful.
+type Time int64
+
+// Sub subtracts t0 from t, returning the duration in nanoseconds.
+func (t Time) Sub(t0 Time) time.Duration {
+	return time.Duration(int64(t) - int64(t0))
+}
+
+// GoID is the ID of a goroutine.
+type GoID int64
+
+// NoGoroutine indicates that the relevant events don't correspond to
+// any goroutine in particular.
+const NoGoroutine = GoID(-1)
+
+// ProcID is the ID of a P (a logical processor, see GOMAXPROCS).
+type ProcID int64
+
+// NoProc indicates that the relevant events don't correspond to any P
+// in particular.
+const NoProc = ProcID(-1)
+
+// ThreadID is the ID of an M (an OS thread running Go code).
+type ThreadID int64
+
+// NoThread indicates that the relevant events don't correspond to any
+// thread in particular.
+const NoThread = ThreadID(-1)
+
+// TaskID is the internal ID of a task created with runtime/trace.NewTask.
+type TaskID uint64
+
+// NoTask indicates the lack of a task.
+const NoTask = TaskID(0)
+
+// EventKind is the kind of an Event.
+type EventKind uint8
+
+const (
+	EventBad EventKind = iota
+
+	// EventSync indicates the start of a generation of the trace,
+	// from which the trace can be read on its own. The state of all
+	// goroutines and Ps that is known is followed by events of the
+	// generation.
+	EventSync
+
+	// EventMetric is an event that represents the value of a metric
+	// at a point in time.
+	EventMetric
+
+	// EventLabel attaches a label to a resource.
+	EventLabel
+
+	// EventRangeBegin and EventRangeEnd are the start and end of a
+	// range of time in which a goroutine or a P, or the whole program,
+	// is doing something in particular, such as a GC phase.
+	EventRangeBegin
+	EventRangeEnd
+
+	// EventTaskBegin and EventTaskEnd are the start and end of a task
+	// created with runtime/trace.NewTask.
+	EventTaskBegin
+	EventTaskEnd
+
+	// EventRegionBegin and EventRegionEnd are the start and end of a
+	// region of a goroutine, created with runtime/trace.StartRegion or
+	// runtime/trace.WithRegion.
+	EventRegionBegin
+	EventRegionEnd
+
+	// EventLog is a message logged with runtime/trace.Log.
+	EventLog
+
+	// EventStateTransition is a change of the state of a goroutine or
+	// a P.
+	EventStateTransition
+)
+
+var eventKindStrings = [...]string{
+	EventBad:             "Bad",
+	EventSync:            "Sync",
+	EventMetric:          "Metric",
+	EventLabel:           "Label",
+	EventRangeBegin:      "RangeBegin",
+	EventRangeEnd:        "RangeEnd",
+	EventTaskBegin:       "TaskBegin",
+	EventTaskEnd:         "TaskEnd",
+	EventRegionBegin:     "RegionBegin",
+	EventRegionEnd:       "RegionEnd",
+	EventLog:             "Log",
+	EventStateTransition: "StateTransition",
+}
+
+func (k EventKind) String() string {
+	if int(k) < len(eventKindStrings) {
+		return eventKindStrings[k]
+	}
+	return "Bad"
+}
+
+// GoState is the state of a goroutine.
+type GoState uint8
+
+const (
+	GoUndetermined GoState = iota // No information is known about the goroutine.
+	GoNotExist                    // The goroutine does not exist.
+	GoRunnable                    // The goroutine is runnable but not running.
+	GoRunning                     // The goroutine is running.
+	GoWaiting                     // The goroutine is waiting on something to happen.
+	GoSyscall                     // The goroutine is in a system call.
+)
+
+var goStateStrings = [...]string{
+	GoUndetermined: "Undetermined",
+	GoNotExist:     "NotExist",
+	GoRunnable:     "Runnable",
+	GoRunning:      "Running",
+	GoWaiting:      "Waiting",
+	GoSyscall:      "Syscall",
+}
+
+func (s GoState) String() string {
+	if int(s) < len(goStateStrings) {
+		return goStateStrings[s]
+	}
+	return "Bad"
+}
+
+// Executing reports whether the goroutine is running on a thread,
+// which includes being in a system call.
+func (s GoState) Executing() bool {
+	return s == GoRunning || s == GoSyscall
+}
+
+// ProcState is the state of a P.
+type ProcState uint8
+
+const (
+	ProcUndetermined ProcState = iota // No information is known about the P.
+	ProcRunning                       // The P is running on a thread.
+	ProcIdle                          // The P is not running on a thread.
+)
+
+var procStateStrings = [...]string{
+	ProcUndetermined: "Undetermined",
+	ProcRunning:      "Running",
+	ProcIdle:         "Idle",
+}
+
+func (s ProcState) String() string {
+	if int(s) < len(procStateStrings) {
+		return procStateStrings[s]
+	}
+	return "Bad"
+}
+
+// ResourceKind is the kind of a resource.
+type ResourceKind uint8
+
+const (
+	ResourceNone      ResourceKind = iota // No resource.
+	ResourceGoroutine                     // A goroutine.
+	ResourceProc                          // A P.
+)
+
+func (k ResourceKind) String() string {
+	switch k {
+	case ResourceNone:
+		return "None"
+	case ResourceGoroutine:
+		return "Goroutine"
+	case ResourceProc:
+		return "Proc"
+	}
+	return "Bad"
+}
+
+// ResourceID identifies a goroutine or a P.
+type ResourceID struct {
+	Kind ResourceKind
+	id   int64
+}
+
+// MakeResourceID creates a ResourceID for a goroutine or a P. id must
+// be a GoID or a ProcID.
+func MakeResourceID(id any) ResourceID {
+	switch id := id.(type) {
+	case GoID:
+		return ResourceID{Kind: ResourceGoroutine, id: int64(id)}
+	case ProcID:
+		return ResourceID{Kind: ResourceProc, id: int64(id)}
+	}
+	panic(fmt.Sprintf("trace: invalid resource ID type %T", id))
+}
+
+// Goroutine returns the goroutine of the resource. It panics if the
+// resource is not a goroutine.
+func (r ResourceID) Goroutine() GoID {
+	if r.Kind != ResourceGoroutine {
+		panic(fmt.Sprintf("trace: attempted to get GoID from %s resource", r.Kind))
+	}
+	return GoID(r.id)
+}
+
+// Proc returns the P of the resource. It panics if the resource is not
+// a P.
+func (r ResourceID) Proc() ProcID {
+	if r.Kind != ResourceProc {
+		panic(fmt.Sprintf("trace: attempted to get ProcID from %s resource", r.Kind))
+	}
+	return ProcID(r.id)
+}
+
+func (r ResourceID) String() string {
+	if r.Kind == ResourceNone {
+		return r.Kind.String()
+	}
+	return fmt.Sprintf("%s(%d)", r.Kind, r.id)
+}
+
+// Stack is a stack trace recorded in the trace.
+type Stack struct {
+	gen *generation
+	id  uint64
+}
+
+// NoStack is the Stack of an event without a stack trace.
+var NoStack = Stack{}
+
+// StackFrame is a frame of a stack trace.
+type StackFrame struct {
+	PC   uint64
+	Func string
+	File string
+	Line uint64
+}
+
+// Frames returns the frames of the stack trace, innermost first.
+func (s Stack) Frames() []StackFrame {
+	if s.gen == nil {
+		return nil
+	}
+	raw := s.gen.stacks[s.id]
+	frames := make([]StackFrame, len(raw))
+	for i, f := range raw {
+		frames[i] = StackFrame{
+			PC:   f.pc,
+			Func: s.gen.strings[f.funcID],
+			File: s.gen.strings[f.fileID],
+			Line: f.line,
+		}
+	}
+	return frames
+}
+
+// Metric is the value of a metric at a point in time, named as in
+// runtime/metrics.
+//
+// The metrics in the trace are:
+//
+//	/sched/gomaxprocs:threads
+//	/memory/classes/heap/objects:bytes
+//	/gc/heap/goal:bytes
+type Metric struct {
+	Name  string
+	Value uint64
+}
+
+// Label is a label attached to a resource, such as the kind of GC
+// work a goroutine is started for.
+type Label struct {
+	Label    string
+	Resource ResourceID
+}
+
+// Range is a range of time in which a resource, or the whole program
+// if Scope is ResourceNone, is doing something in particular.
+//
+// The ranges in the trace are:
+//
+//	GC concurrent mark phase
+//	stop-the-world (<kind>)
+//	GC incremental sweep
+//	GC mark assist
+type Range struct {
+	Name  string
+	Scope ResourceID
+}
+
+// RangeAttribute is a value attached to the end of a range.
+type RangeAttribute struct {
+	Name  string
+	Value uint64
+}
+
+// Task is a task created with runtime/trace.NewTask.
+type Task struct {
+	ID     TaskID
+	Parent TaskID // NoTask if the task has no parent
+	Type   string // empty if the task was created in an earlier generation that is not in the trace
+}
+
+// Region is a region of a goroutine in a task.
+type Region struct {
+	Task TaskID
+	Type string
+}
+
+// Log is a message logged with runtime/trace.Log.
+type Log struct {
+	Task     TaskID
+	Category string
+	Message  string
+}
+
+// StateTransition is a change of the state of a goroutine or a P.
+type StateTransition struct {
+	// Resource is the goroutine or the P that changes its state.
+	Resource ResourceID
+
+	// Reason is a human-readable reason for the transition, if known.
+	Reason string
+
+	// Stack is the stack trace of the resource at the transition.
+	// For a new goroutine, or one whose state was not known before,
+	// it is the function the goroutine started with. It may differ
+	// from the stack trace of the event, such as when a goroutine
+	// unblocks another one.
+	Stack Stack
+
+	from, to uint8
+}
+
+// Goroutine returns the states of a goroutine before and after the
+// transition. It panics if the resource is not a goroutine.
+func (s StateTransition) Goroutine() (from, to GoState) {
+	if s.Resource.Kind != ResourceGoroutine {
+		panic("trace: Goroutine called on non-goroutine state transition")
+	}
+	return GoState(s.from), GoState(s.to)
+}
+
+// Proc returns the states of a P before and after the transition. It
+// panics if the resource is not a P.
+func (s StateTransition) Proc() (from, to ProcState) {
+	if s.Resource.Kind != ResourceProc {
+		panic("trace: Proc called on non-proc state transition")
+	}
+	return ProcState(s.from), ProcState(s.to)
+}
+
+// schedCtx is the goroutine, P and thread that an event happens on.
+type schedCtx struct {
+	G GoID
+	P ProcID
+	M ThreadID
+}
+
+// Event is a single event in a trace.
+//
+// The goroutine, P and thread of an event describe the state before
+// the event: the goroutine that blocks in an event that blocks a
+// goroutine is running the event, but the goroutine that starts in an
+// event that starts a goroutine is not.
+type Event struct {
+	gen  *generation
+	ctx  schedCtx
+	kind EventKind
+	ev   rawEvent
+
+	// The resource and states of a state transition.
+	res      ResourceID
+	from, to uint8
+}
+
+// Kind returns the kind of the event.
+func (e Event) Kind() EventKind {
+	return e.kind
+}
+
+// Time returns the timestamp of the event.
+func (e Event) Time() Time {
+	return e.ev.time
+}
+
+// Goroutine returns the goroutine that the event happens on, or
+// NoGoroutine.
+func (e Event) Goroutine() GoID {
+	return e.ctx.G
+}
+
+// Proc returns the P that the event happens on, or NoProc.
+func (e Event) Proc() ProcID {
+	return e.ctx.P
+}
+
+// Thread returns the thread that the event happens on, or NoThread.
+func (e Event) Thread() ThreadID {
+	return e.ctx.M
+}
+
+// Stack returns the stack trace of the event, or NoStack.
+func (e Event) Stack() Stack {
+	if e.kind == EventSync || e.kind == EventBad {
+		return NoStack
+	}
+	i := evInfo[e.ev.typ].stack
+	if i < 0 || e.ev.args[i] == 0 {
+		return NoStack
+	}
+	return Stack{e.gen, e.ev.args[i]}
+}
+
+// Metric returns the metric of an EventMetric event. It panics for
+// other kinds of events.
+func (e Event) Metric() Metric {
+	if e.kind != EventMetric {
+		panic("trace: Metric called on non-Metric event")
+	}
+	var m Metric
+	switch e.ev.typ {
+	case evProcsChange:
+		m.Name = "/sched/gomaxprocs:threads"
+	case evHeapAlloc:
+		m.Name = "/memory/classes/heap/objects:bytes"
+	case evHeapGoal:
+		m.Name = "/gc/heap/goal:bytes"
+	}
+	m.Value = e.ev.args[0]
+	return m
+}
+
+// Label returns the label of an EventLabel event. It panics for other
+// kinds of events.
+func (e Event) Label() Label {
+	if e.kind != EventLabel {
+		panic("trace: Label called on non-Label event")
+	}
+	return Label{
+		Label:    e.gen.strings[e.ev.args[0]],
+		Resource: MakeResourceID(e.ctx.G),
+	}
+}
+
+// Range returns the range of an EventRangeBegin or EventRangeEnd event.
+// It panics for other kinds of events.
+func (e Event) Range() Range {
+	if e.kind != EventRangeBegin && e.kind != EventRangeEnd {
+		panic("trace: Range called on non-Range event")
+	}
+	var r Range
+	switch e.ev.typ {
+	case evSTWBegin, evSTWEnd:
+		r.Name = "stop-the-world (" + e.ev.str + ")"
+	case evGCBegin, evGCEnd:
+		r.Name = "GC concurrent mark phase"
+	case evGCSweepBegin, evGCSweepEnd:
+		r.Name = "GC incremental sweep"
+		r.Scope = MakeResourceID(e.ctx.P)
+	case evGCMarkAssistBegin, evGCMarkAssistEnd:
+		r.Name = "GC mark assist"
+		r.Scope = MakeResourceID(e.ctx.G)
+	}
+	return r
+}
+
+// RangeAttributes returns the attributes of an EventRangeEnd event, if
+// any. It panics for other kinds of events.
+func (e Event) RangeAttributes() []RangeAttribute {
+	if e.kind != EventRangeEnd {
+		panic("trace: RangeAttributes called on non-RangeEnd event")
+	}
+	if e.ev.typ != evGCSweepEnd {
+		return nil
+	}
+	return []RangeAttribute{
+		{Name: "bytes swept", Value: e.ev.args[0]},
+		{Name: "bytes reclaimed", Value: e.ev.args[1]},
+	}
+}
+
+// Task returns the task of an EventTaskBegin or EventTaskEnd event. It
+// panics for other kinds of events.
+func (e Event) Task() Task {
+	if e.kind != EventTaskBegin && e.kind != EventTaskEnd {
+		panic("trace: Task called on non-Task event")
+	}
+	t := Task{ID: TaskID(e.ev.args[0]), Type: e.ev.str}
+	if e.kind == EventTaskBegin {
+		t.Parent = TaskID(e.ev.args[1])
+	}
+	return t
+}
+
+// Region returns the region of an EventRegionBegin or EventRegionEnd
+// event. It panics for other kinds of events.
+func (e Event) Region() Region {
+	if e.kind != EventRegionBegin && e.kind != EventRegionEnd {
+		panic("trace: Region called on non-Region event")
+	}
+	return Region{
+		Task: TaskID(e.ev.args[0]),
+		Type: e.gen.strings[e.ev.args[1]],
+	}
+}
+
+// Log returns the message of an EventLog event. It panics for other
+// kinds of events.
+func (e Event) Log() Log {
+	if e.kind != EventLog {
+		panic("trace: Log called on non-Log event")
+	}
+	return Log{
+		Task:     TaskID(e.ev.args[0]),
+		Category: e.gen.strings[e.ev.args[1]],
+		Message:  e.ev.str,
+	}
+}
+
+// StateTransition returns the state transition of an
+// EventStateTransition event. It panics for other kinds of events.
+func (e Event) StateTransition() StateTransition {
+	if e.kind != EventStateTransition {
+		panic("trace: StateTransition called on non-StateTransition event")
+	}
+	s := StateTransition{
+		Resource: e.res,
+		from:     e.from,
+		to:       e.to,
+	}
+	switch e.ev.typ {
+	case evGoStop, evGoBlock:
+		s.Reason = e.gen.strings[e.ev.args[0]]
+		s.Stack = e.Stack()
+	case evGoCreate, evGoCreateSyscall:
+		s.Stack = Stack{e.gen, e.ev.args[1]}
+	case evGoStatus:
+		s.Stack = Stack{e.gen, e.ev.args[3]}
+	case evGoSyscallBegin:
+		s.Stack = e.Stack()
+	}
+	if s.Stack.id == 0 {
+		s.Stack = NoStack
+	}
+	return s
+}
+
+// String returns a human-readable description of the event.
+func (e Event) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "M=%d P=%d G=%d %s Time=%d", e.ctx.M, e.ctx.P, e.ctx.G, e.kind, e.ev.time)
+	switch e.kind {
+	case EventMetric:
+		m := e.Metric()
+		fmt.Fprintf(&b, " Name=%q Value=%d", m.Name, m.Value)
+	case EventLabel:
+		l := e.Label()
+		fmt.Fprintf(&b, " Label=%q Resource=%s", l.Label, l.Resource)
+	case EventRangeBegin, EventRangeEnd:
+		r := e.Range()
+		fmt.Fprintf(&b, " Name=%q Scope=%s", r.Name, r.Scope)
+		if e.kind == EventRangeEnd {
+			for _, a := range e.RangeAttributes() {
+				fmt.Fprintf(&b, " %s=%d", strings.ReplaceAll(a.Name, " ", "_"), a.Value)
+			}
+		}
+	case EventTaskBegin, EventTaskEnd:
+		t := e.Task()
+		fmt.Fprintf(&b, " ID=%d Parent=%d Type=%q", t.ID, t.Parent, t.Type)
+	case EventRegionBegin, EventRegionEnd:
+		r := e.Region()
+		fmt.Fprintf(&b, " Task=%d Type=%q", r.Task, r.Type)
+	case EventLog:
+		l := e.Log()
+		fmt.Fprintf(&b, " Task=%d Category=%q Message=%q", l.Task, l.Category, l.Message)
+	case EventStateTransition:
+		s := e.StateTransition()
+		fmt.Fprintf(&b, " Resource=%s", s.Resource)
+		switch s.Resource.Kind {
+		case ResourceGoroutine:
+			from, to := s.Goroutine()
+			fmt.Fprintf(&b, " GoroutineTransition=%s->%s", from, to)
+		case ResourceProc:
+			from, to := s.Proc()
+			fmt.Fprintf(&b, " ProcTransition=%s->%s", from, to)
+		}
+		if s.Reason != "" {
+			fmt.Fprintf(&b, " Reason=%q", s.Reason)
+		}
+	}
+	if frames := e.Stack().Frames(); len(frames) > 0 {
+		b.WriteString("\n  Stack=\n")
+		for _, f := range frames {
+			fmt.Fprintf(&b, "\t%s @ 0x%x\n\t\t%s:%d\n", f.Func, f.PC, f.File, f.Line)
+		}
+	}
+	return b.String()
+}
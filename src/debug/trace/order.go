@@ -0,0 +1,440 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The runtime writes the events of each M into batches in the order in
+// which they happen, but does not order the events of different Ms.
+// Timestamps are not enough to order them either, because the clocks
+// of different CPUs may be skewed. Instead, the events that depend on
+// the events of other Ms carry sequence numbers: the sequence number of
+// a goroutine is incremented when it starts running or is unblocked,
+// and the sequence number of a P when it is started, enters a syscall
+// or is taken away from a syscall. The events of different Ms are
+// merged by taking the earliest event that is consistent with the
+// state of the program so far.
+
+// gState is the state of a goroutine as known by the reader.
+type gState struct {
+	status GoState
+	seq    uint64
+}
+
+// pState is the state of a P as known by the reader.
+type pState struct {
+	status    ProcState
+	seq       uint64
+	inSyscall bool     // the P is running a goroutine in a syscall
+	m         ThreadID // the thread the P runs on, or NoThread
+}
+
+// mState is the state of a thread as known by the reader.
+type mState struct {
+	g GoID
+	p ProcID
+}
+
+// A cursor iterates over the events of an M in a generation.
+type cursor struct {
+	m       ThreadID
+	batches []batch
+	b       *batch
+	d       decoder
+	ticks   uint64   // timestamp of ev
+	ev      rawEvent // the next event of the M
+	valid   bool     // whether ev is valid
+}
+
+// advance decodes the next event of the M into c.ev.
+func (c *cursor) advance() error {
+	for c.b == nil || c.d.pos >= len(c.d.data) {
+		if len(c.batches) == 0 {
+			c.valid = false
+			return nil
+		}
+		c.b = &c.batches[0]
+		c.batches = c.batches[1:]
+		c.d = decoder{data: c.b.data}
+		c.ticks = c.b.ticks
+	}
+	ev, err := readEvent(&c.d, c.b, &c.ticks)
+	if err != nil {
+		c.valid = false
+		return err
+	}
+	c.ev = ev
+	c.valid = true
+	return nil
+}
+
+// ordering merges the events of the Ms of a trace, and tracks the
+// state of goroutines, Ps and threads across generations.
+type ordering struct {
+	gStates map[GoID]*gState
+	pStates map[ProcID]*pState
+	mStates map[ThreadID]*mState
+	gcSeq   uint64
+	stwKind string            // kind of the ongoing stop-the-world
+	tasks   map[TaskID]string // names of live tasks
+	cursors []*cursor
+}
+
+func (o *ordering) init() {
+	o.gStates = make(map[GoID]*gState)
+	o.pStates = make(map[ProcID]*pState)
+	o.mStates = make(map[ThreadID]*mState)
+	o.tasks = make(map[TaskID]string)
+}
+
+func (o *ordering) gState(g GoID) *gState {
+	gs := o.gStates[g]
+	if gs == nil {
+		gs = &gState{status: GoUndetermined}
+		o.gStates[g] = gs
+	}
+	return gs
+}
+
+func (o *ordering) pState(p ProcID) *pState {
+	ps := o.pStates[p]
+	if ps == nil {
+		ps = &pState{status: ProcUndetermined, m: NoThread}
+		o.pStates[p] = ps
+	}
+	return ps
+}
+
+func (o *ordering) mState(m ThreadID) *mState {
+	ms := o.mStates[m]
+	if ms == nil {
+		ms = &mState{g: NoGoroutine, p: NoProc}
+		o.mStates[m] = ms
+	}
+	return ms
+}
+
+// startGeneration prepares to merge the events of g. It returns the
+// events that start the generation: a Sync event, and the changes of
+// the state of goroutines and Ps since the previous generation, or
+// their initial state if it is the first one.
+func (o *ordering) startGeneration(r *Reader, g *generation) ([]Event, error) {
+	o.cursors = o.cursors[:0]
+	for m, batches := range g.batches {
+		c := &cursor{m: ThreadID(m), batches: batches}
+		if err := c.advance(); err != nil {
+			return nil, err
+		}
+		if c.valid {
+			o.cursors = append(o.cursors, c)
+		}
+	}
+	sort.Slice(o.cursors, func(i, j int) bool {
+		return o.cursors[i].m < o.cursors[j].m
+	})
+
+	// Sequence numbers restart in each generation, and the statuses
+	// at its start tell which goroutines and Ps are where.
+	o.gcSeq = 0
+	for _, ms := range o.mStates {
+		*ms = mState{g: NoGoroutine, p: NoProc}
+	}
+	seenG := make(map[GoID]bool)
+	seenP := make(map[ProcID]bool)
+
+	var start uint64
+	for i, c := range o.cursors {
+		if i == 0 || c.ticks < start {
+			start = c.ticks
+		}
+	}
+	sync := Event{gen: g, ctx: schedCtx{NoGoroutine, NoProc, NoThread}, kind: EventSync}
+	sync.ev.time = r.time(start)
+	events := []Event{sync}
+
+	for _, c := range o.cursors {
+		for first := true; c.valid; first = false {
+			ev := c.ev
+			if ev.typ != evGoStatus && ev.typ != evProcStatus && (ev.typ != evProcsChange || !first) {
+				break
+			}
+			ev.time = r.time(c.ticks)
+			e := Event{gen: g, ctx: schedCtx{NoGoroutine, NoProc, c.m}, kind: evInfo[ev.typ].kind, ev: ev}
+			switch ev.typ {
+			case evProcsChange:
+				events = append(events, e)
+			case evProcStatus:
+				p := ProcID(ev.args[0])
+				seenP[p] = true
+				ps := o.pState(p)
+				to := ProcIdle
+				ps.m = NoThread
+				if ev.args[1] == procStatusRunning {
+					to = ProcRunning
+					ps.m = c.m
+					o.mState(c.m).p = p
+				}
+				if ps.status != to {
+					events = append(events, e.withTransition(MakeResourceID(p), uint8(ps.status), uint8(to)))
+				}
+				*ps = pState{status: to, m: ps.m}
+			case evGoStatus:
+				gid := GoID(ev.args[0])
+				seenG[gid] = true
+				gs := o.gState(gid)
+				var to GoState
+				switch ev.args[2] {
+				case goStatusRunnable:
+					to = GoRunnable
+				case goStatusRunning:
+					to = GoRunning
+				case goStatusSyscall:
+					to = GoSyscall
+				case goStatusWaiting:
+					to = GoWaiting
+				default:
+					return nil, fmt.Errorf("trace: invalid status %d of goroutine %d at offset %d", ev.args[2], gid, ev.off)
+				}
+				if to.Executing() {
+					o.mState(ThreadID(ev.args[1])).g = gid
+				}
+				if gs.status != to {
+					events = append(events, e.withTransition(MakeResourceID(gid), uint8(gs.status), uint8(to)))
+				}
+				*gs = gState{status: to}
+			}
+			if err := c.advance(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Goroutines and Ps without a status no longer exist.
+	for gid := range o.gStates {
+		if !seenG[gid] {
+			delete(o.gStates, gid)
+		}
+	}
+	for p := range o.pStates {
+		if !seenP[p] {
+			delete(o.pStates, p)
+		}
+	}
+	return events, nil
+}
+
+// next returns the next event of the generation, in an order that is
+// consistent with the state of goroutines and Ps. It returns false at
+// the end of the generation.
+func (o *ordering) next(r *Reader) (Event, bool, error) {
+	var best *cursor
+	for _, c := range o.cursors {
+		if !c.valid || best != nil && c.ticks >= best.ticks {
+			continue
+		}
+		if o.ready(c) {
+			best = c
+		}
+	}
+	if best == nil {
+		for _, c := range o.cursors {
+			if c.valid {
+				return Event{}, false, fmt.Errorf("trace: inconsistent trace: no event can be ordered next, event %s at offset %d is pending", evInfo[c.ev.typ].name, c.ev.off)
+			}
+		}
+		return Event{}, false, nil
+	}
+	e, err := o.apply(r, best)
+	if err != nil {
+		return Event{}, false, err
+	}
+	if err := best.advance(); err != nil {
+		return Event{}, false, err
+	}
+	return e, true, nil
+}
+
+// ready reports whether the next event of c can happen given the
+// state of the program so far.
+func (o *ordering) ready(c *cursor) bool {
+	args := &c.ev.args
+	switch c.ev.typ {
+	case evProcStart:
+		ps := o.pStates[ProcID(args[0])]
+		if ps == nil {
+			return args[1] == 1
+		}
+		return ps.status != ProcRunning && args[1] == ps.seq+1
+	case evProcSteal:
+		ps := o.pStates[ProcID(args[0])]
+		if ps == nil {
+			return args[1] == 1
+		}
+		return args[1] == ps.seq+1
+	case evGoStart:
+		gs := o.gStates[GoID(args[0])]
+		return gs != nil && gs.status == GoRunnable && args[1] == gs.seq+1
+	case evGoUnblock:
+		gs := o.gStates[GoID(args[0])]
+		return gs != nil && gs.status == GoWaiting && args[1] == gs.seq+1
+	case evGoSyscallBegin:
+		ps := o.pStates[o.mState(c.m).p]
+		return ps != nil && args[0] == ps.seq+1
+	case evGoSyscallEnd:
+		ps := o.pStates[o.mState(c.m).p]
+		return ps != nil && ps.inSyscall
+	case evGoSyscallEndBlock:
+		ps := o.pStates[o.mState(c.m).p]
+		return ps == nil || !ps.inSyscall
+	case evGCBegin, evGCEnd:
+		return args[0] == o.gcSeq+1
+	}
+	return true
+}
+
+// apply applies the next event of c, which must be ready, to the state
+// of the program, and returns it.
+func (o *ordering) apply(r *Reader, c *cursor) (Event, error) {
+	ev := c.ev
+	ev.time = r.time(c.ticks)
+	ms := o.mState(c.m)
+	e := Event{gen: r.gen, ctx: schedCtx{ms.g, ms.p, c.m}, kind: evInfo[ev.typ].kind, ev: ev}
+	args := &e.ev.args
+
+	// curG returns the state of the goroutine running on the thread.
+	curG := func() (*gState, error) {
+		if ms.g == NoGoroutine {
+			return nil, fmt.Errorf("trace: event %s at offset %d on thread %d without a goroutine", evInfo[ev.typ].name, ev.off, c.m)
+		}
+		return o.gState(ms.g), nil
+	}
+	// curP returns the state of the P of the thread.
+	curP := func() (*pState, error) {
+		if ms.p == NoProc {
+			return nil, fmt.Errorf("trace: event %s at offset %d on thread %d without a P", evInfo[ev.typ].name, ev.off, c.m)
+		}
+		return o.pState(ms.p), nil
+	}
+
+	switch ev.typ {
+	case evProcStart:
+		p := ProcID(args[0])
+		ps := o.pState(p)
+		e = e.withTransition(MakeResourceID(p), uint8(ps.status), uint8(ProcRunning))
+		*ps = pState{status: ProcRunning, seq: args[1], m: c.m}
+		ms.p = p
+	case evProcStop:
+		ps, err := curP()
+		if err != nil {
+			return Event{}, err
+		}
+		e = e.withTransition(MakeResourceID(ms.p), uint8(ps.status), uint8(ProcIdle))
+		*ps = pState{status: ProcIdle, seq: ps.seq, m: NoThread}
+		ms.p = NoProc
+	case evProcSteal:
+		p := ProcID(args[0])
+		ps := o.pState(p)
+		e = e.withTransition(MakeResourceID(p), uint8(ps.status), uint8(ProcIdle))
+		if ps.m != NoThread {
+			if victim := o.mState(ps.m); victim.p == p {
+				victim.p = NoProc
+			}
+		}
+		*ps = pState{status: ProcIdle, seq: args[1], m: NoThread}
+	case evGoCreate, evGoCreateSyscall:
+		g := GoID(args[0])
+		to := GoRunnable
+		if ev.typ == evGoCreateSyscall {
+			to = GoSyscall
+			ms.g = g
+		}
+		e = e.withTransition(MakeResourceID(g), uint8(GoNotExist), uint8(to))
+		o.gStates[g] = &gState{status: to}
+	case evGoStart:
+		g := GoID(args[0])
+		gs := o.gStates[g]
+		e = e.withTransition(MakeResourceID(g), uint8(gs.status), uint8(GoRunning))
+		*gs = gState{status: GoRunning, seq: args[1]}
+		ms.g = g
+	case evGoDestroy, evGoDestroySyscall, evGoStop, evGoBlock:
+		gs, err := curG()
+		if err != nil {
+			return Event{}, err
+		}
+		to := GoNotExist
+		switch ev.typ {
+		case evGoStop:
+			to = GoRunnable
+		case evGoBlock:
+			to = GoWaiting
+		}
+		e = e.withTransition(MakeResourceID(ms.g), uint8(gs.status), uint8(to))
+		if to == GoNotExist {
+			delete(o.gStates, ms.g)
+		} else {
+			gs.status = to
+		}
+		ms.g = NoGoroutine
+	case evGoUnblock:
+		g := GoID(args[0])
+		gs := o.gStates[g]
+		e = e.withTransition(MakeResourceID(g), uint8(gs.status), uint8(GoRunnable))
+		*gs = gState{status: GoRunnable, seq: args[1]}
+	case evGoSyscallBegin:
+		gs, err := curG()
+		if err != nil {
+			return Event{}, err
+		}
+		e = e.withTransition(MakeResourceID(ms.g), uint8(gs.status), uint8(GoSyscall))
+		gs.status = GoSyscall
+		ps := o.pStates[ms.p]
+		ps.seq = args[0]
+		ps.inSyscall = true
+	case evGoSyscallEnd:
+		gs, err := curG()
+		if err != nil {
+			return Event{}, err
+		}
+		e = e.withTransition(MakeResourceID(ms.g), uint8(gs.status), uint8(GoRunning))
+		gs.status = GoRunning
+		o.pStates[ms.p].inSyscall = false
+	case evGoSyscallEndBlock:
+		gs, err := curG()
+		if err != nil {
+			return Event{}, err
+		}
+		e = e.withTransition(MakeResourceID(ms.g), uint8(gs.status), uint8(GoRunnable))
+		gs.status = GoRunnable
+		ms.g = NoGoroutine
+	case evGoStatus, evProcStatus:
+		return Event{}, fmt.Errorf("trace: status event %s at offset %d in the middle of a generation", evInfo[ev.typ].name, ev.off)
+	case evSTWBegin:
+		e.ev.str = r.gen.strings[args[0]]
+		o.stwKind = e.ev.str
+	case evSTWEnd:
+		e.ev.str = o.stwKind
+	case evGCBegin, evGCEnd:
+		o.gcSeq = args[0]
+	case evUserTaskBegin:
+		e.ev.str = r.gen.strings[args[2]]
+		o.tasks[TaskID(args[0])] = e.ev.str
+	case evUserTaskEnd:
+		e.ev.str = o.tasks[TaskID(args[0])]
+		delete(o.tasks, TaskID(args[0]))
+	}
+	return e, nil
+}
+
+// withTransition returns e as a state transition of res from one
+// state to another.
+func (e Event) withTransition(res ResourceID, from, to uint8) Event {
+	e.res = res
+	e.from = from
+	e.to = to
+	return e
+}
@@ -0,0 +1,273 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	. "debug/trace"
+	"io"
+	"os"
+	"runtime"
+	rtrace "runtime/trace"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// record runs f while tracing and returns the trace.
+func record(t *testing.T, f func()) []byte {
+	if rtrace.IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	buf := new(bytes.Buffer)
+	if err := rtrace.Start(buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	f()
+	rtrace.Stop()
+	return buf.Bytes()
+}
+
+// readAll reads all the events of a trace, and checks that the state
+// transitions of goroutines and Ps are consistent.
+func readAll(t *testing.T, data []byte) []Event {
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	goStates := make(map[GoID]GoState)
+	procStates := make(map[ProcID]ProcState)
+	var last Time
+	for {
+		ev, err := r.ReadEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading event %d: %v", len(events), err)
+		}
+		if ev.Time() < last {
+			t.Errorf("time goes backwards at event %d: %v", len(events), ev)
+		}
+		last = ev.Time()
+		_ = ev.String()
+		if ev.Kind() == EventStateTransition {
+			st := ev.StateTransition()
+			switch st.Resource.Kind {
+			case ResourceGoroutine:
+				id := st.Resource.Goroutine()
+				from, to := st.Goroutine()
+				if cur, ok := goStates[id]; ok && cur != from {
+					t.Errorf("goroutine %d transitions from %v, but it is %v: %v", id, from, cur, ev)
+				}
+				goStates[id] = to
+			case ResourceProc:
+				id := st.Resource.Proc()
+				from, to := st.Proc()
+				if cur, ok := procStates[id]; ok && cur != from {
+					t.Errorf("P %d transitions from %v, but it is %v: %v", id, from, cur, ev)
+				}
+				procStates[id] = to
+			}
+		}
+		events = append(events, ev)
+	}
+	if len(events) == 0 || events[0].Kind() != EventSync {
+		t.Fatalf("trace does not start with a Sync event")
+	}
+	return events
+}
+
+func hasFrame(stk Stack, fn string) bool {
+	for _, f := range stk.Frames() {
+		if strings.HasSuffix(f.Func, fn) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReader(t *testing.T) {
+	data := record(t, func() {
+		ctx, task := rtrace.NewTask(context.Background(), "task0")
+		rtrace.Log(ctx, "key0", "0123456789abcdef")
+		var wg sync.WaitGroup
+		c := make(chan int)
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rtrace.WithRegion(ctx, "region0", func() {
+					for v := range c {
+						_ = make([]byte, v)
+					}
+				})
+			}()
+		}
+		for i := 0; i < 1000; i++ {
+			c <- 1 << 10
+		}
+		close(c)
+		runtime.GC()
+		wg.Wait()
+		time.Sleep(time.Millisecond)
+		task.End()
+	})
+	events := readAll(t, data)
+
+	var gcBegin, gcEnd, created, logs, regions, tasks int
+	for _, ev := range events {
+		switch ev.Kind() {
+		case EventRangeBegin:
+			if ev.Range().Name == "GC concurrent mark phase" {
+				gcBegin++
+			}
+		case EventRangeEnd:
+			if ev.Range().Name == "GC concurrent mark phase" {
+				gcEnd++
+			}
+		case EventStateTransition:
+			st := ev.StateTransition()
+			if st.Resource.Kind != ResourceGoroutine {
+				break
+			}
+			if from, _ := st.Goroutine(); from == GoNotExist && hasFrame(st.Stack, "TestReader.func1.1") {
+				created++
+				if !hasFrame(ev.Stack(), "TestReader.func1") {
+					t.Errorf("goroutine creation has unexpected stack: %v", ev)
+				}
+			}
+		case EventLog:
+			if l := ev.Log(); l.Category == "key0" && l.Message == "0123456789abcdef" {
+				logs++
+			}
+		case EventRegionBegin:
+			if ev.Region().Type == "region0" {
+				regions++
+			}
+		case EventTaskBegin, EventTaskEnd:
+			if ev.Task().Type == "task0" {
+				tasks++
+			}
+		}
+	}
+	if gcBegin == 0 || gcEnd == 0 {
+		t.Errorf("got %d GC begin and %d GC end events, want at least one each", gcBegin, gcEnd)
+	}
+	if created != 4 {
+		t.Errorf("got %d goroutine creations, want 4", created)
+	}
+	if logs != 1 {
+		t.Errorf("got %d log events, want 1", logs)
+	}
+	if regions != 4 {
+		t.Errorf("got %d region begin events, want 4", regions)
+	}
+	if tasks != 2 {
+		t.Errorf("got %d task begin and end events, want 2", tasks)
+	}
+}
+
+func TestReaderSyscalls(t *testing.T) {
+	if runtime.GOOS == "js" {
+		t.Skip("no os.Pipe on js")
+	}
+	rp, wp, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer func() {
+		rp.Close()
+		wp.Close()
+	}()
+	data := record(t, func() {
+		done := make(chan bool)
+		go func() {
+			var tmp [1]byte
+			rp.Read(tmp[:])
+			done <- true
+		}()
+		// Let the reader block in the syscall, so that its P is
+		// taken away.
+		time.Sleep(100 * time.Millisecond)
+		wp.Write([]byte{0})
+		<-done
+	})
+	events := readAll(t, data)
+	var syscalls int
+	for _, ev := range events {
+		if ev.Kind() != EventStateTransition || ev.StateTransition().Resource.Kind != ResourceGoroutine {
+			continue
+		}
+		if _, to := ev.StateTransition().Goroutine(); to == GoSyscall && hasFrame(ev.Stack(), "TestReaderSyscalls.func2.1") {
+			syscalls++
+		}
+	}
+	if syscalls == 0 {
+		t.Errorf("no syscall of the reading goroutine in the trace")
+	}
+}
+
+func TestReaderGenerations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in -short mode")
+	}
+	// The runtime starts a new generation every second.
+	data := record(t, func() {
+		deadline := time.Now().Add(2500 * time.Millisecond)
+		for time.Now().Before(deadline) {
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					time.Sleep(time.Millisecond)
+				}()
+			}
+			wg.Wait()
+		}
+	})
+	var syncs int
+	for _, ev := range readAll(t, data) {
+		if ev.Kind() == EventSync {
+			syncs++
+		}
+	}
+	if syncs < 3 {
+		t.Errorf("got %d generations, want at least 3", syncs)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	if _, err := NewReader(strings.NewReader("go 1.5 trace\x00\x00\x00\x00")); err == nil {
+		t.Errorf("NewReader succeeded for an unsupported version")
+	}
+	if _, err := NewReader(strings.NewReader("go 1")); err == nil {
+		t.Errorf("NewReader succeeded for a short header")
+	}
+
+	data := record(t, func() {
+		time.Sleep(time.Millisecond)
+	})
+	r, err := NewReader(bytes.NewReader(data[:len(data)-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		_, err := r.ReadEvent()
+		if err == io.EOF {
+			t.Fatalf("reading a truncated trace succeeded")
+		}
+		if err != nil {
+			if _, err2 := r.ReadEvent(); err2 != err {
+				t.Errorf("ReadEvent returned %v after error %v", err2, err)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package trace reads execution traces written by the runtime/trace
+// package.
+//
+// A trace is a sequence of generations, each of which can be decoded
+// on its own: it starts with the state of all goroutines and Ps, and
+// contains the stacks and strings used by its events. The Reader reads
+// a trace one generation at a time and returns its events in an order
+// that is consistent with the state of the program, so that a trace
+// can be processed as a stream, and a trace that was cut at the start
+// of a generation, such as one written by runtime/trace.FlightRecorder,
+// can be read like a complete one.
+//
+// This package only reads traces written by the version of the runtime
+// it is part of.
+package trace
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// header is the header of the supported version of the trace format.
+const header = "go 1.18 trace\x00\x00\x00"
+
+// A Reader reads the events of a trace.
+type Reader struct {
+	r     *bufio.Reader
+	off   int
+	spill *batch // first batch of the next generation, if read
+	err   error
+
+	freq     uint64 // ticks per second, of the first generation
+	lastTime Time
+
+	gen   *generation
+	queue []Event // events to return before the rest of gen
+	order ordering
+}
+
+// NewReader returns a Reader that reads a trace from r. It returns an
+// error if r does not start with the header of a supported trace.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	var hdr [len(header)]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, fmt.Errorf("trace: reading header: %v", unexpectedEOF(err))
+	}
+	if string(hdr[:]) != header {
+		return nil, errors.New("trace: unsupported trace version or not a trace")
+	}
+	rd := &Reader{r: br, off: len(header)}
+	rd.order.init()
+	return rd, nil
+}
+
+// ReadEvent returns the next event of the trace. It returns io.EOF at
+// the end of the trace, and any other error if the trace is invalid or
+// cannot be read. After an error, ReadEvent returns the same error.
+func (r *Reader) ReadEvent() (Event, error) {
+	if r.err != nil {
+		return Event{}, r.err
+	}
+	ev, err := r.readEvent()
+	if err != nil {
+		r.err = err
+	}
+	return ev, err
+}
+
+func (r *Reader) readEvent() (Event, error) {
+	for {
+		if len(r.queue) > 0 {
+			ev := r.queue[0]
+			r.queue = r.queue[1:]
+			return ev, nil
+		}
+		if r.gen != nil {
+			ev, ok, err := r.order.next(r)
+			if err != nil {
+				return Event{}, err
+			}
+			if ok {
+				return ev, nil
+			}
+			r.gen = nil
+		}
+		if err := r.readGeneration(); err != nil {
+			return Event{}, err
+		}
+	}
+}
+
+// readGeneration reads the batches of the next generation. It returns
+// io.EOF at the end of the trace.
+func (r *Reader) readGeneration() error {
+	var b batch
+	if r.spill != nil {
+		b = *r.spill
+		r.spill = nil
+	} else {
+		var err error
+		if b, r.off, err = readBatch(r.r, r.off); err != nil {
+			return err
+		}
+	}
+	g := &generation{
+		gen:     b.gen,
+		strings: make(map[uint64]string),
+		stacks:  make(map[uint64][]frame),
+		batches: make(map[uint64][]batch),
+	}
+	for {
+		if b.m == noM {
+			if err := g.addTables(b); err != nil {
+				return err
+			}
+		} else {
+			g.batches[b.m] = append(g.batches[b.m], b)
+		}
+		var err error
+		b, r.off, err = readBatch(r.r, r.off)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if b.gen != g.gen {
+			if b.gen < g.gen {
+				return fmt.Errorf("trace: batch at offset %d of generation %d follows generation %d", b.off, b.gen, g.gen)
+			}
+			r.spill = &b
+			break
+		}
+	}
+	if g.freq == 0 {
+		// The tables are written at the end of a generation, so the
+		// trace is truncated.
+		return fmt.Errorf("trace: generation %d is incomplete", g.gen)
+	}
+	if r.freq == 0 {
+		r.freq = g.freq
+	}
+	r.gen = g
+	events, err := r.order.startGeneration(r, g)
+	if err != nil {
+		return err
+	}
+	r.queue = events
+	return nil
+}
+
+// time converts a timestamp in ticks to nanoseconds. Timestamps of
+// different CPUs may not be in sync, so time makes timestamps
+// monotonic in the order in which the Reader returns the events.
+func (r *Reader) time(ticks uint64) Time {
+	t := Time(ticks/r.freq*1e9 + ticks%r.freq*1e9/r.freq)
+	if t < r.lastTime {
+		t = r.lastTime
+	}
+	r.lastTime = t
+	return t
+}
@@ -70,10 +70,11 @@ import (
 var depsRules = `
 	# No dependencies allowed for any of these packages.
 	NONE
-	< constraints, container/list, container/ring,
+	< constraints, container/ring,
 	  internal/cfg, internal/cpu, internal/goarch,
 	  internal/goexperiment, internal/goos,
 	  internal/goversion, internal/nettrace,
+	  log/internal,
 	  unicode/utf8, unicode/utf16, unicode,
 	  unsafe;
 
@@ -103,6 +104,13 @@ var depsRules = `
 	< sort
 	< container/heap;
 
+	RUNTIME
+	< iter
+	< container/list;
+
+	RUNTIME, constraints, iter
+	< slices, maps;
+
 	RUNTIME
 	< io;
 
@@ -154,7 +162,7 @@ var depsRules = `
 	unicode !< strconv;
 
 	# STR is basic string and buffer manipulation.
-	RUNTIME, io, unicode/utf8, unicode/utf16, unicode
+	RUNTIME, io, iter, unicode/utf8, unicode/utf16, unicode
 	< bytes, strings
 	< bufio;
 
@@ -246,7 +254,7 @@ var depsRules = `
 
 	# compression
 	FMT, encoding/binary, hash/adler32, hash/crc32
-	< compress/bzip2, compress/flate, compress/lzw
+	< compress/bzip2, compress/flate, compress/lzw, compress/zstd
 	< archive/zip, compress/gzip, compress/zlib;
 
 	# templates
@@ -278,6 +286,10 @@ var depsRules = `
 	< debug/buildinfo
 	< DEBUG;
 
+	# execution traces
+	FMT, encoding/binary
+	< debug/trace;
+
 	# go parser and friends.
 	FMT
 	< go/token
@@ -389,14 +401,23 @@ var depsRules = `
 	< NET;
 
 	# logging - most packages should not import; http and up is allowed
-	FMT
+	FMT, log/internal
 	< log;
 
-	log !< crypto/tls, database/sql, go/importer, testing;
+	log, log/slog !< crypto/tls, database/sql, go/importer, testing;
 
 	FMT, log, net
 	< log/syslog;
 
+	RUNTIME
+	< log/slog/internal/buffer;
+
+	FMT,
+	encoding, encoding/json,
+	log, log/internal,
+	log/slog/internal/buffer
+	< log/slog;
+
 	NET, log
 	< net/mail;
 
@@ -427,6 +448,7 @@ var depsRules = `
 	< golang.org/x/crypto/cryptobyte
 	< golang.org/x/crypto/curve25519
 	< crypto/dsa, crypto/elliptic, crypto/rsa
+	< crypto/ecdh
 	< crypto/ecdsa
 	< CRYPTO-MATH;
 
@@ -439,6 +461,7 @@ var depsRules = `
 	< golang.org/x/crypto/internal/poly1305
 	< golang.org/x/crypto/chacha20poly1305
 	< golang.org/x/crypto/hkdf
+	< crypto/internal/hpke
 	< crypto/x509/internal/macos
 	< crypto/x509/pkix
 	< crypto/x509
@@ -470,6 +493,7 @@ var depsRules = `
 	< net/http/httptrace;
 
 	compress/gzip,
+	compress/zstd,
 	golang.org/x/net/http/httpguts,
 	golang.org/x/net/http/httpproxy,
 	golang.org/x/net/http2/hpack,
@@ -503,6 +527,9 @@ var depsRules = `
 	OS, compress/gzip, regexp
 	< internal/profile;
 
+	FMT
+	< runtime/coverage;
+
 	html, internal/profile, net/http, runtime/pprof, runtime/trace
 	< net/http/pprof;
 
@@ -520,10 +547,13 @@ var depsRules = `
 	< testing/iotest
 	< testing/fstest;
 
+	NONE
+	< testing/synctest;
+
 	FMT, flag, math/rand
 	< testing/quick;
 
-	FMT, DEBUG, flag, runtime/trace, internal/sysinfo, math/rand
+	FMT, DEBUG, encoding, flag, runtime/trace, internal/sysinfo, math/rand
 	< testing;
 
 	FMT, crypto/sha256, encoding/json, go/ast, go/parser, go/token,
@@ -548,7 +578,7 @@ var depsRules = `
 	syscall
 	< os/exec/internal/fdtest;
 
-	FMT, container/heap, math/rand
+	FMT, container/heap, math/rand, debug/trace
 	< internal/trace;
 `
 
@@ -48,8 +48,9 @@ const (
 	iexportVersionPosCol   = 1
 	iexportVersionGenerics = 2
 	iexportVersionGo1_18   = 2
+	iexportVersionLoopVar  = 3
 
-	iexportVersionCurrent = 2
+	iexportVersionCurrent = 3
 )
 
 type ident struct {
@@ -98,7 +99,7 @@ func iImportData(fset *token.FileSet, imports map[string]*types.Package, dataRea
 
 	version = int64(r.uint64())
 	switch version {
-	case iexportVersionGo1_18, iexportVersionPosCol, iexportVersionGo1_11:
+	case iexportVersionLoopVar, iexportVersionGo1_18, iexportVersionPosCol, iexportVersionGo1_11:
 	default:
 		errorf("unknown iexport format version %d", version)
 	}
@@ -59,6 +59,7 @@ const KnownEnv = `
 	GOROOT
 	GOSUMDB
 	GOTMPDIR
+	GOTOOLCHAIN
 	GOTOOLDIR
 	GOVCS
 	GOWASM
@@ -6,10 +6,13 @@ package fuzz
 
 import (
 	"bytes"
+	"encoding"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"internal/fmtsort"
+	"reflect"
 	"strconv"
 )
 
@@ -38,14 +41,91 @@ func marshalCorpusFile(vals ...any) []byte {
 		case []byte: // []uint8
 			fmt.Fprintf(b, "[]byte(%q)\n", t)
 		default:
-			panic(fmt.Sprintf("unsupported type: %T", t))
+			encodeValue(b, reflect.ValueOf(val))
+			b.WriteString("\n")
 		}
 	}
 	return b.Bytes()
 }
 
+// encodeValue writes the encoding of v, a value of a structured type, to b.
+//
+// Values of types implementing encoding.BinaryMarshaler are written as
+// conversions of their binary encoding, such as pkg.T([]byte("\x01")).
+// Structs, slices, arrays and maps are written as composite literals whose
+// elements are encoded in turn, such as pkg.Point{X: int(1), Y: int(2)}.
+// Slices and arrays of bytes are written as []byte("..."), and other values
+// are written as conversions to their kind, such as int(1) or string("a").
+func encodeValue(b *bytes.Buffer, v reflect.Value) {
+	t := v.Type()
+	if isBinaryMarshaler(t) {
+		data, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			panic(fmt.Sprintf("marshaling %v: %v", t, err))
+		}
+		fmt.Fprintf(b, "%v([]byte(%q))", t, data)
+		return
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		fmt.Fprintf(b, "bool(%v)", v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fmt.Fprintf(b, "%v(%d)", t.Kind(), v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		fmt.Fprintf(b, "%v(%d)", t.Kind(), v.Uint())
+	case reflect.Float32:
+		fmt.Fprintf(b, "float32(%v)", float32(v.Float()))
+	case reflect.Float64:
+		fmt.Fprintf(b, "float64(%v)", v.Float())
+	case reflect.String:
+		fmt.Fprintf(b, "string(%q)", v.String())
+	case reflect.Slice, reflect.Array:
+		if isByteElem(t.Elem()) {
+			fmt.Fprintf(b, "[]byte(%q)", bytesOf(v))
+			return
+		}
+		fmt.Fprintf(b, "%v{", t)
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			encodeValue(b, v.Index(i))
+		}
+		b.WriteString("}")
+	case reflect.Map:
+		fmt.Fprintf(b, "%v{", t)
+		// Sort the keys so that equal maps have equal encodings.
+		sorted := fmtsort.Sort(v)
+		for i, k := range sorted.Key {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			encodeValue(b, k)
+			b.WriteString(": ")
+			encodeValue(b, sorted.Value[i])
+		}
+		b.WriteString("}")
+	case reflect.Struct:
+		fmt.Fprintf(b, "%v{", t)
+		for i := 0; i < t.NumField(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(b, "%s: ", t.Field(i).Name)
+			encodeValue(b, v.Field(i))
+		}
+		b.WriteString("}")
+	default:
+		panic(fmt.Sprintf("unsupported type: %v", t))
+	}
+}
+
 // unmarshalCorpusFile decodes corpus bytes into their respective values.
-func unmarshalCorpusFile(b []byte) ([]any, error) {
+//
+// types holds the types of the values, which are needed to decode values of
+// structured types. It may be nil or short, in which case only values of
+// primitive types can be decoded.
+func unmarshalCorpusFile(b []byte, types []reflect.Type) ([]any, error) {
 	if len(b) == 0 {
 		return nil, fmt.Errorf("cannot unmarshal empty string")
 	}
@@ -62,7 +142,11 @@ func unmarshalCorpusFile(b []byte) ([]any, error) {
 		if len(line) == 0 {
 			continue
 		}
-		v, err := parseCorpusValue(line)
+		var t reflect.Type
+		if len(vals) < len(types) {
+			t = types[len(vals)]
+		}
+		v, err := parseCorpusValue(line, t)
 		if err != nil {
 			return nil, fmt.Errorf("malformed line %q: %v", line, err)
 		}
@@ -71,12 +155,61 @@ func unmarshalCorpusFile(b []byte) ([]any, error) {
 	return vals, nil
 }
 
-func parseCorpusValue(line []byte) (any, error) {
+// parseCorpusValue decodes a value of type t from line. If t is nil or a
+// primitive type, the value has the type it is written with, and it is up to
+// the caller to check that it is the expected one.
+func parseCorpusValue(line []byte, t reflect.Type) (any, error) {
 	fs := token.NewFileSet()
 	expr, err := parser.ParseExprFrom(fs, "(test)", line, 0)
 	if err != nil {
 		return nil, err
 	}
+	v, err := parseValue(expr)
+	if err != nil {
+		return nil, err
+	}
+	switch v.(type) {
+	case compositeValue, binaryValue:
+		if t == nil {
+			return nil, fmt.Errorf("cannot decode value of unknown type")
+		}
+	default:
+		if t == nil || isPrimitiveType(t) {
+			return v, nil
+		}
+	}
+	rv, err := convertValue(v, t)
+	if err != nil {
+		return nil, err
+	}
+	return rv.Interface(), nil
+}
+
+// A compositeValue is a composite literal parsed from a corpus file. It is
+// converted to the type of the fuzz target's argument by convertValue; the
+// type written in the literal is ignored.
+type compositeValue []compositeElem
+
+// A compositeElem is an element of a composite literal.
+type compositeElem struct {
+	key   any // nil, the fieldName of a struct field, or a parsed map key
+	value any
+}
+
+// A fieldName is the name of a struct field, used as the key of an element
+// of a composite literal.
+type fieldName string
+
+// A binaryValue is the binary encoding of a value of a type implementing
+// encoding.BinaryUnmarshaler, parsed from a corpus file.
+type binaryValue []byte
+
+// parseValue parses expr, a value in a corpus file. It returns a value of a
+// primitive type, a compositeValue or a binaryValue.
+func parseValue(expr ast.Expr) (any, error) {
+	if lit, ok := expr.(*ast.CompositeLit); ok {
+		return parseCompositeLit(lit)
+	}
 	call, ok := expr.(*ast.CallExpr)
 	if !ok {
 		return nil, fmt.Errorf("expected call expression")
@@ -107,7 +240,17 @@ func parseCorpusValue(line []byte) (any, error) {
 
 	idType, ok := call.Fun.(*ast.Ident)
 	if !ok {
-		return nil, fmt.Errorf("expected []byte or primitive type")
+		// A conversion to a named type, such as pkg.T([]byte("...")), holds
+		// the binary encoding of a value of that type.
+		v, err := parseValue(arg)
+		if err != nil {
+			return nil, err
+		}
+		data, ok := v.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("[]byte value required for binary encoding")
+		}
+		return binaryValue(data), nil
 	}
 	if idType.Name == "bool" {
 		id, ok := arg.(*ast.Ident)
@@ -238,3 +381,209 @@ func parseUint(val, typ string) (any, error) {
 		panic("unreachable")
 	}
 }
+
+// parseCompositeLit parses the elements of lit.
+func parseCompositeLit(lit *ast.CompositeLit) (compositeValue, error) {
+	c := make(compositeValue, 0, len(lit.Elts))
+	for _, elt := range lit.Elts {
+		var e compositeElem
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if id, ok := kv.Key.(*ast.Ident); ok {
+				e.key = fieldName(id.Name)
+			} else {
+				k, err := parseValue(kv.Key)
+				if err != nil {
+					return nil, err
+				}
+				e.key = k
+			}
+			elt = kv.Value
+		}
+		v, err := parseValue(elt)
+		if err != nil {
+			return nil, err
+		}
+		e.value = v
+		c = append(c, e)
+	}
+	return c, nil
+}
+
+// convertValue converts v, a value returned by parseValue, to type t.
+func convertValue(v any, t reflect.Type) (reflect.Value, error) {
+	if isBinaryMarshaler(t) {
+		data, ok := v.(binaryValue)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("binary encoding required for type %v", t)
+		}
+		return unmarshalBinary(t, data)
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if isByteElem(t.Elem()) {
+			b, ok := v.([]byte)
+			if !ok {
+				return reflect.Value{}, fmt.Errorf("[]byte value required for type %v", t)
+			}
+			return bytesValue(t, b)
+		}
+		c, ok := v.(compositeValue)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("composite literal required for type %v", t)
+		}
+		if t.Kind() == reflect.Slice && len(c) == 0 {
+			return reflect.Zero(t), nil
+		}
+		if t.Kind() == reflect.Array && len(c) > t.Len() {
+			return reflect.Value{}, fmt.Errorf("too many elements for type %v", t)
+		}
+		var nv reflect.Value
+		if t.Kind() == reflect.Slice {
+			nv = reflect.MakeSlice(t, len(c), len(c))
+		} else {
+			nv = reflect.New(t).Elem()
+		}
+		for i, e := range c {
+			if e.key != nil {
+				return reflect.Value{}, fmt.Errorf("unexpected key in value of type %v", t)
+			}
+			ev, err := convertValue(e.value, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			nv.Index(i).Set(ev)
+		}
+		return nv, nil
+	case reflect.Map:
+		c, ok := v.(compositeValue)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("composite literal required for type %v", t)
+		}
+		if len(c) == 0 {
+			return reflect.Zero(t), nil
+		}
+		nv := reflect.MakeMapWithSize(t, len(c))
+		for _, e := range c {
+			if _, ok := e.key.(fieldName); ok || e.key == nil {
+				return reflect.Value{}, fmt.Errorf("value required for key in value of type %v", t)
+			}
+			kv, err := convertValue(e.key, t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			ev, err := convertValue(e.value, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			nv.SetMapIndex(kv, ev)
+		}
+		return nv, nil
+	case reflect.Struct:
+		c, ok := v.(compositeValue)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("composite literal required for type %v", t)
+		}
+		nv := reflect.New(t).Elem()
+		for _, e := range c {
+			name, ok := e.key.(fieldName)
+			if !ok {
+				return reflect.Value{}, fmt.Errorf("field name required in value of type %v", t)
+			}
+			f, ok := t.FieldByName(string(name))
+			if !ok || len(f.Index) != 1 || !f.IsExported() {
+				return reflect.Value{}, fmt.Errorf("unknown field %s in type %v", name, t)
+			}
+			fv, err := convertValue(e.value, f.Type)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			nv.Field(f.Index[0]).Set(fv)
+		}
+		return nv, nil
+	}
+	switch v.(type) {
+	case nil, compositeValue, binaryValue:
+		return reflect.Value{}, fmt.Errorf("%v value required for type %v", t.Kind(), t)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != t.Kind() {
+		return reflect.Value{}, fmt.Errorf("%v value required for type %v, got %v", t.Kind(), t, rv.Type())
+	}
+	return rv.Convert(t), nil
+}
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+// isBinaryMarshaler reports whether values of type t are fuzzed as their
+// binary encoding: t implements encoding.BinaryMarshaler and *t implements
+// encoding.BinaryUnmarshaler.
+func isBinaryMarshaler(t reflect.Type) bool {
+	return t.Implements(binaryMarshalerType) && reflect.PtrTo(t).Implements(binaryUnmarshalerType)
+}
+
+// marshalBinary returns the binary encoding of v, whose type must satisfy
+// isBinaryMarshaler.
+func marshalBinary(v reflect.Value) ([]byte, error) {
+	return v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+}
+
+// unmarshalBinary returns a value of type t decoded from data. t must satisfy
+// isBinaryMarshaler.
+func unmarshalBinary(t reflect.Type, data []byte) (reflect.Value, error) {
+	p := reflect.New(t)
+	if err := p.Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data); err != nil {
+		return reflect.Value{}, fmt.Errorf("unmarshaling %v: %v", t, err)
+	}
+	return p.Elem(), nil
+}
+
+// isByteElem reports whether slices and arrays with elements of type t are
+// encoded and mutated as strings of bytes.
+func isByteElem(t reflect.Type) bool {
+	return t.Kind() == reflect.Uint8 && !isBinaryMarshaler(t)
+}
+
+// bytesOf returns a copy of the contents of v, a slice or array whose
+// elements satisfy isByteElem.
+func bytesOf(v reflect.Value) []byte {
+	b := make([]byte, v.Len())
+	if v.Kind() == reflect.Slice {
+		copy(b, v.Bytes())
+		return b
+	}
+	for i := range b {
+		b[i] = byte(v.Index(i).Uint())
+	}
+	return b
+}
+
+// bytesValue returns a value of type t, a slice or array whose elements
+// satisfy isByteElem, holding a copy of b.
+func bytesValue(t reflect.Type, b []byte) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	if t.Kind() == reflect.Slice {
+		v.SetBytes(append([]byte(nil), b...))
+		return v, nil
+	}
+	if len(b) != t.Len() {
+		return reflect.Value{}, fmt.Errorf("%d bytes required for type %v, got %d", t.Len(), t, len(b))
+	}
+	for i, c := range b {
+		v.Index(i).SetUint(uint64(c))
+	}
+	return v, nil
+}
+
+// isPrimitiveType reports whether t is one of the primitive types, which
+// have their own encodings.
+func isPrimitiveType(t reflect.Type) bool {
+	for _, v := range zeroVals {
+		if reflect.TypeOf(v) == t {
+			return true
+		}
+	}
+	return false
+}
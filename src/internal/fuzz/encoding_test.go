@@ -5,6 +5,9 @@
 package fuzz
 
 import (
+	"encoding/binary"
+	"errors"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -106,7 +109,7 @@ float32(2.5)`,
 	}
 	for _, test := range tests {
 		t.Run(test.in, func(t *testing.T) {
-			vals, err := unmarshalCorpusFile([]byte(test.in))
+			vals, err := unmarshalCorpusFile([]byte(test.in), nil)
 			if test.ok && err != nil {
 				t.Fatalf("unmarshal unexpected error: %v", err)
 			} else if !test.ok && err == nil {
@@ -130,6 +133,149 @@ float32(2.5)`,
 	}
 }
 
+type testPoint struct {
+	X, Y int
+	Name string
+}
+
+type testLevel int8
+
+// testStamp is fuzzed through its binary encoding. It has an unexported field,
+// so it could not be fuzzed otherwise.
+type testStamp struct {
+	sec uint32
+}
+
+func (s testStamp) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, s.sec)
+	return b, nil
+}
+
+func (s *testStamp) UnmarshalBinary(b []byte) error {
+	if len(b) != 4 {
+		return errors.New("testStamp: wrong length")
+	}
+	s.sec = binary.BigEndian.Uint32(b)
+	return nil
+}
+
+type testRecord struct {
+	ID     uint16
+	Tags   []string
+	Points []testPoint
+	Attrs  map[string]float64
+	Hash   [4]byte
+	Data   []byte
+	Runes  []rune
+	OK     bool
+	Level  testLevel
+	Stamp  testStamp
+	Counts map[testLevel]uint8
+}
+
+func TestUnmarshalMarshalStructured(t *testing.T) {
+	var tests = []struct {
+		in   string
+		typ  reflect.Type
+		want any
+	}{
+		{
+			in:   `fuzz.testPoint{X: int(1), Y: int(-2), Name: string("a")}`,
+			typ:  reflect.TypeOf(testPoint{}),
+			want: testPoint{X: 1, Y: -2, Name: "a"},
+		},
+		{
+			in:   `int8(-3)`,
+			typ:  reflect.TypeOf(testLevel(0)),
+			want: testLevel(-3),
+		},
+		{
+			in:   `fuzz.testStamp([]byte("\x00\x00\x01\x02"))`,
+			typ:  reflect.TypeOf(testStamp{}),
+			want: testStamp{sec: 258},
+		},
+		{
+			in:   `[]uint16{uint16(1), uint16(65535)}`,
+			typ:  reflect.TypeOf([]uint16(nil)),
+			want: []uint16{1, 65535},
+		},
+		{
+			in:   `map[string]bool{string("a"): bool(true), string("b"): bool(false)}`,
+			typ:  reflect.TypeOf(map[string]bool(nil)),
+			want: map[string]bool{"a": true, "b": false},
+		},
+		{
+			in:  `fuzz.testRecord{ID: uint16(7), Tags: []string{string("x"), string("")}, Points: []fuzz.testPoint{fuzz.testPoint{X: int(0), Y: int(0), Name: string("")}}, Attrs: map[string]float64{string("pi"): float64(3.14)}, Hash: []byte("\x01\x02\x03\x04"), Data: []byte("data"), Runes: []int32{int32(-1), int32(97)}, OK: bool(true), Level: int8(2), Stamp: fuzz.testStamp([]byte("\x00\x00\x00\x05")), Counts: map[fuzz.testLevel]uint8{int8(-1): uint8(0), int8(1): uint8(255)}}`,
+			typ: reflect.TypeOf(testRecord{}),
+			want: testRecord{
+				ID:     7,
+				Tags:   []string{"x", ""},
+				Points: []testPoint{{}},
+				Attrs:  map[string]float64{"pi": 3.14},
+				Hash:   [4]byte{1, 2, 3, 4},
+				Data:   []byte("data"),
+				Runes:  []rune{-1, 'a'},
+				OK:     true,
+				Level:  2,
+				Stamp:  testStamp{sec: 5},
+				Counts: map[testLevel]uint8{-1: 0, 1: 255},
+			},
+		},
+		{
+			in:   `fuzz.testRecord{ID: uint16(0), Tags: []string{}, Points: []fuzz.testPoint{}, Attrs: map[string]float64{}, Hash: []byte("\x00\x00\x00\x00"), Data: []byte(""), Runes: []int32{}, OK: bool(false), Level: int8(0), Stamp: fuzz.testStamp([]byte("\x00\x00\x00\x00")), Counts: map[fuzz.testLevel]uint8{}}`,
+			typ:  reflect.TypeOf(testRecord{}),
+			want: testRecord{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			in := encVersion1 + "\n" + test.in + "\n"
+			vals, err := unmarshalCorpusFile([]byte(in), []reflect.Type{test.typ})
+			if err != nil {
+				t.Fatalf("unmarshal unexpected error: %v", err)
+			}
+			if len(vals) != 1 || !reflect.DeepEqual(vals[0], test.want) {
+				t.Fatalf("unmarshal: got %#v, want %#v", vals, test.want)
+			}
+			if got := string(marshalCorpusFile(vals...)); got != in {
+				t.Errorf("values changed after unmarshal then marshal\nbefore: %q\nafter:  %q", in, got)
+			}
+		})
+	}
+}
+
+func TestUnmarshalStructuredErrors(t *testing.T) {
+	var tests = []struct {
+		in  string
+		typ reflect.Type
+	}{
+		{`fuzz.testPoint{X: int(1)}`, nil},                                 // unknown type
+		{`fuzz.testPoint{Z: int(1)}`, reflect.TypeOf(testPoint{})},         // unknown field
+		{`fuzz.testPoint{X: uint(1)}`, reflect.TypeOf(testPoint{})},        // wrong kind
+		{`fuzz.testPoint{int(1)}`, reflect.TypeOf(testPoint{})},            // missing field name
+		{`[]int{int(1)}`, reflect.TypeOf(testPoint{})},                     // wrong composite
+		{`[]int{X: int(1)}`, reflect.TypeOf([]int(nil))},                   // key in slice
+		{`map[int]int{int(1)}`, reflect.TypeOf(map[int]int(nil))},          // missing map key
+		{`[2]int{int(1), int(2), int(3)}`, reflect.TypeOf([2]int{})},       // too many elements
+		{`[]byte("abc")`, reflect.TypeOf([4]byte{})},                       // wrong length
+		{`fuzz.testStamp([]byte("abc"))`, reflect.TypeOf(testStamp{})},     // UnmarshalBinary fails
+		{`fuzz.testStamp(string("abcd"))`, reflect.TypeOf(testStamp{})},    // not []byte
+		{`fuzz.testStamp{}`, reflect.TypeOf(testStamp{})},                  // not binary
+		{`string("a")`, reflect.TypeOf(testLevel(0))},                      // wrong kind
+		{`fuzz.testPoint{X: int(1+1)}`, reflect.TypeOf(testPoint{})},       // expression
+		{`fuzz.testPoint{X: fuzz.f(int(1))}`, reflect.TypeOf(testPoint{})}, // binary for int
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			in := encVersion1 + "\n" + test.in + "\n"
+			if vals, err := unmarshalCorpusFile([]byte(in), []reflect.Type{test.typ}); err == nil {
+				t.Errorf("unmarshal unexpected success: %#v", vals)
+			}
+		})
+	}
+}
+
 // BenchmarkMarshalCorpusFile measures the time it takes to serialize byte
 // slices of various sizes to a corpus file. The slice contains a repeating
 // sequence of bytes 0-255 to mix escaped and non-escaped characters.
@@ -165,7 +311,7 @@ func BenchmarkUnmarshalCorpusFile(b *testing.B) {
 		b.Run(strconv.Itoa(sz), func(b *testing.B) {
 			for i := 0; i < b.N; i++ {
 				b.SetBytes(int64(sz))
-				unmarshalCorpusFile(data)
+				unmarshalCorpusFile(data, nil)
 			}
 		})
 	}
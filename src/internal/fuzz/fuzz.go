@@ -983,7 +983,7 @@ func ReadCorpus(dir string, types []reflect.Type) ([]CorpusEntry, error) {
 }
 
 func readCorpusData(data []byte, types []reflect.Type) ([]any, error) {
-	vals, err := unmarshalCorpusFile(data)
+	vals, err := unmarshalCorpusFile(data, types)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal: %v", err)
 	}
@@ -1038,7 +1038,7 @@ func zeroValue(t reflect.Type) any {
 			return v
 		}
 	}
-	panic(fmt.Sprintf("unsupported type: %v", t))
+	return reflect.Zero(t).Interface()
 }
 
 var zeroVals []any = []any{
@@ -5,11 +5,26 @@
 package fuzz
 
 import (
+	"internal/fmtsort"
 	"reflect"
 )
 
 func isMinimizable(t reflect.Type) bool {
-	return t == reflect.TypeOf("") || t == reflect.TypeOf([]byte(nil))
+	if t == reflect.TypeOf("") || t == reflect.TypeOf([]byte(nil)) {
+		return true
+	}
+	if isPrimitiveType(t) {
+		return false
+	}
+	// Values of structured types are minimized by minimizeValue.
+	if isBinaryMarshaler(t) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		return true
+	}
+	return false
 }
 
 func minimizeBytes(v []byte, try func([]byte) bool, shouldStop func() bool) {
@@ -93,3 +108,152 @@ func minimizeBytes(v []byte, try func([]byte) bool, shouldStop func() bool) {
 		}
 	}
 }
+
+// minimizeValue minimizes v, a value of a structured type, by calling try
+// with smaller candidates: slices and maps with fewer elements, elements and
+// fields minimized in turn, and zero numbers and bools. Strings, slices of
+// bytes and binary encodings are minimized with minimizeBytes. try reports
+// whether a candidate is still interesting, in which case further candidates
+// are derived from it. v itself is never modified.
+func minimizeValue(v reflect.Value, try func(reflect.Value) bool, shouldStop func() bool) {
+	if shouldStop() {
+		return
+	}
+	t := v.Type()
+	tryValue := func(candidate reflect.Value) bool {
+		if !try(candidate) {
+			return false
+		}
+		v = candidate
+		return true
+	}
+
+	if isBinaryMarshaler(t) {
+		data, err := marshalBinary(v)
+		if err != nil {
+			return
+		}
+		// minimizeBytes modifies data, which may be shared with v.
+		data = append([]byte(nil), data...)
+		minimizeBytes(data, func(b []byte) bool {
+			candidate, err := unmarshalBinary(t, append([]byte(nil), b...))
+			return err == nil && tryValue(candidate)
+		}, shouldStop)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			tryValue(reflect.Zero(t))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if !v.IsZero() {
+			tryValue(reflect.Zero(t))
+		}
+	case reflect.String:
+		minimizeBytes([]byte(v.String()), func(b []byte) bool {
+			candidate := reflect.New(t).Elem()
+			candidate.SetString(string(b))
+			return tryValue(candidate)
+		}, shouldStop)
+	case reflect.Slice, reflect.Array:
+		if isByteElem(t.Elem()) {
+			minimizeBytes(bytesOf(v), func(b []byte) bool {
+				candidate, err := bytesValue(t, b)
+				return err == nil && tryValue(candidate)
+			}, shouldStop)
+			return
+		}
+		if t.Kind() == reflect.Slice {
+			// First, try to cut the tail.
+			for n := v.Len(); n != 0; n /= 2 {
+				for v.Len() >= n {
+					if shouldStop() {
+						return
+					}
+					if !tryValue(v.Slice(0, v.Len()-n)) {
+						break
+					}
+				}
+			}
+			// Then, try to remove each individual element.
+			for i := 0; i < v.Len(); i++ {
+				if shouldStop() {
+					return
+				}
+				candidate := reflect.MakeSlice(t, 0, v.Len()-1)
+				candidate = reflect.AppendSlice(candidate, v.Slice(0, i))
+				candidate = reflect.AppendSlice(candidate, v.Slice(i+1, v.Len()))
+				if tryValue(candidate) {
+					i--
+				}
+			}
+		}
+		// Then, try to minimize each element.
+		for i := 0; i < v.Len(); i++ {
+			i := i
+			minimizeValue(v.Index(i), func(e reflect.Value) bool {
+				candidate := copyValue(v)
+				candidate.Index(i).Set(e)
+				return tryValue(candidate)
+			}, shouldStop)
+		}
+	case reflect.Map:
+		// First, try to remove each entry.
+		for _, k := range fmtsort.Sort(v).Key {
+			if shouldStop() {
+				return
+			}
+			candidate := copyValue(v)
+			candidate.SetMapIndex(k, reflect.Value{})
+			tryValue(candidate)
+		}
+		// Then, try to minimize each value.
+		for _, k := range fmtsort.Sort(v).Key {
+			k := k
+			e := v.MapIndex(k)
+			if !e.IsValid() {
+				continue // NaN key
+			}
+			minimizeValue(e, func(e reflect.Value) bool {
+				candidate := copyValue(v)
+				candidate.SetMapIndex(k, e)
+				return tryValue(candidate)
+			}, shouldStop)
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			i := i
+			minimizeValue(v.Field(i), func(f reflect.Value) bool {
+				candidate := copyValue(v)
+				candidate.Field(i).Set(f)
+				return tryValue(candidate)
+			}, shouldStop)
+		}
+	}
+}
+
+// copyValue returns a shallow copy of v, a slice, array, map or struct, which
+// can be modified without modifying v.
+func copyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Slice:
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(c, v)
+		return c
+	case reflect.Map:
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return c
+	default:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		return c
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 	"unicode"
@@ -129,6 +130,55 @@ func TestMinimizeInput(t *testing.T) {
 			input:    []any{"ZZZZZ"},
 			expected: []any{"A"},
 		},
+		{
+			name: "slice_elements",
+			fn: func(e CorpusEntry) error {
+				var has7, has9 bool
+				for _, v := range e.Values[0].([]int) {
+					has7 = has7 || v == 7
+					has9 = has9 || v == 9
+				}
+				if has7 && has9 {
+					return fmt.Errorf("bad %v", e.Values[0])
+				}
+				return nil
+			},
+			input:    []any{[]int{5, 0, 7, 3, 9, 1}},
+			expected: []any{[]int{7, 9}},
+		},
+		{
+			name: "struct_fields",
+			fn: func(e CorpusEntry) error {
+				if strings.Contains(e.Values[0].(testPoint).Name, "l") {
+					return fmt.Errorf("bad %v", e.Values[0])
+				}
+				return nil
+			},
+			input:    []any{testPoint{X: 3, Y: 4, Name: "hello"}},
+			expected: []any{testPoint{Name: "l"}},
+		},
+		{
+			name: "map_entries",
+			fn: func(e CorpusEntry) error {
+				if len(e.Values[0].(map[string]int)) >= 2 {
+					return fmt.Errorf("bad %v", e.Values[0])
+				}
+				return nil
+			},
+			input:    []any{map[string]int{"a": 1, "b": 2, "c": 3}},
+			expected: []any{map[string]int{"b": 0, "c": 0}},
+		},
+		{
+			name: "binary_marshaler",
+			fn: func(e CorpusEntry) error {
+				if e.Values[0].(testStamp).sec&0xff != 0 {
+					return fmt.Errorf("bad %v", e.Values[0])
+				}
+				return nil
+			},
+			input:    []any{testStamp{sec: 0x01020304}},
+			expected: []any{testStamp{sec: 0x30303030}}, // "0000"
+		},
 	}
 
 	for _, tc := range cases {
@@ -140,7 +190,7 @@ func TestMinimizeInput(t *testing.T) {
 					return time.Second, tc.fn(e)
 				},
 			}
-			mem := &sharedMem{region: make([]byte, 100)} // big enough to hold value and header
+			mem := &sharedMem{region: make([]byte, 200)} // big enough to hold value and header
 			vals := tc.input
 			success, err := ws.minimizeInput(context.Background(), vals, mem, minimizeArgs{})
 			if !success {
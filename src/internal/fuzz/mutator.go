@@ -5,8 +5,10 @@
 package fuzz
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+	"internal/fmtsort"
 	"math"
 	"reflect"
 	"unsafe"
@@ -120,10 +122,216 @@ func (m *mutator) mutate(vals []any, maxBytes int) {
 		m.mutateBytes(&m.scratch)
 		vals[i] = m.scratch
 	default:
-		panic(fmt.Sprintf("type not supported for mutating: %T", vals[i]))
+		vals[i] = m.mutateStructured(reflect.ValueOf(v), maxPerVal).Interface()
 	}
 }
 
+// mutateStructured returns a mutated copy of v, a value of a structured type:
+// a struct, slice, array or map, a type whose underlying type is primitive,
+// or a type implementing encoding.BinaryMarshaler and
+// encoding.BinaryUnmarshaler. The encoding of the result is at most maxBytes
+// long. v itself is not modified.
+func (m *mutator) mutateStructured(v reflect.Value, maxBytes int) reflect.Value {
+	// Some mutations may fail, for example if the mutated binary encoding of a
+	// value can't be unmarshaled, so try a few times.
+	for tries := 0; tries < 100; tries++ {
+		nv, ok := m.mutateValue(v)
+		if !ok {
+			continue
+		}
+		var b bytes.Buffer
+		encodeValue(&b, nv)
+		if b.Len() <= maxBytes {
+			return nv
+		}
+	}
+	return v
+}
+
+// mutateValue returns a copy of v with a single mutation, and whether it
+// succeeded. The copy may share memory with v, so neither is modified.
+func (m *mutator) mutateValue(v reflect.Value) (reflect.Value, bool) {
+	t := v.Type()
+	if isBinaryMarshaler(t) {
+		data, err := marshalBinary(v)
+		if err != nil {
+			return v, false
+		}
+		nv, err := unmarshalBinary(t, m.mutatedBytes(data))
+		return nv, err == nil
+	}
+
+	nv := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Bool:
+		nv.SetBool(!v.Bool())
+	case reflect.Int, reflect.Int64:
+		nv.SetInt(m.mutateInt(v.Int(), maxInt))
+	case reflect.Int8:
+		nv.SetInt(m.mutateInt(v.Int(), math.MaxInt8))
+	case reflect.Int16:
+		nv.SetInt(m.mutateInt(v.Int(), math.MaxInt16))
+	case reflect.Int32:
+		nv.SetInt(m.mutateInt(v.Int(), math.MaxInt32))
+	case reflect.Uint, reflect.Uint64:
+		nv.SetUint(m.mutateUInt(v.Uint(), maxUint))
+	case reflect.Uint8:
+		nv.SetUint(m.mutateUInt(v.Uint(), math.MaxUint8))
+	case reflect.Uint16:
+		nv.SetUint(m.mutateUInt(v.Uint(), math.MaxUint16))
+	case reflect.Uint32:
+		nv.SetUint(m.mutateUInt(v.Uint(), math.MaxUint32))
+	case reflect.Float32:
+		nv.SetFloat(m.mutateFloat(v.Float(), math.MaxFloat32))
+	case reflect.Float64:
+		nv.SetFloat(m.mutateFloat(v.Float(), math.MaxFloat64))
+	case reflect.String:
+		nv.SetString(string(m.mutatedBytes([]byte(v.String()))))
+	case reflect.Slice, reflect.Array:
+		if isByteElem(t.Elem()) {
+			var err error
+			nv, err = bytesValue(t, m.mutatedBytes(bytesOf(v)))
+			return nv, err == nil
+		}
+		if t.Kind() == reflect.Slice {
+			return m.mutateSlice(v)
+		}
+		if v.Len() == 0 {
+			return v, false
+		}
+		nv.Set(v)
+		i := m.rand(v.Len())
+		e, ok := m.mutateValue(v.Index(i))
+		if !ok {
+			return v, false
+		}
+		nv.Index(i).Set(e)
+	case reflect.Map:
+		return m.mutateMap(v)
+	case reflect.Struct:
+		if t.NumField() == 0 {
+			return v, false
+		}
+		nv.Set(v)
+		i := m.rand(t.NumField())
+		f, ok := m.mutateValue(v.Field(i))
+		if !ok {
+			return v, false
+		}
+		nv.Field(i).Set(f)
+	default:
+		panic(fmt.Sprintf("type not supported for mutating: %v", t))
+	}
+	return nv, true
+}
+
+// mutatedBytes returns a mutated copy of b.
+func (m *mutator) mutatedBytes(b []byte) []byte {
+	// Leave room for mutations which insert bytes.
+	c := make([]byte, len(b), len(b)+1024)
+	copy(c, b)
+	m.mutateBytes(&c)
+	return c
+}
+
+// mutateSlice returns a copy of v, a slice, with an element mutated,
+// inserted, removed or duplicated.
+func (m *mutator) mutateSlice(v reflect.Value) (reflect.Value, bool) {
+	t := v.Type()
+	n := v.Len()
+	op := 0
+	if n > 0 {
+		op = m.rand(4)
+	}
+	switch op {
+	case 0:
+		// Insert a mutated zero element.
+		e := reflect.Zero(t.Elem())
+		if me, ok := m.mutateValue(e); ok {
+			e = me
+		}
+		return insertElem(v, m.rand(n+1), e), true
+	case 1:
+		// Mutate an element.
+		i := m.rand(n)
+		e, ok := m.mutateValue(v.Index(i))
+		if !ok {
+			return v, false
+		}
+		nv := reflect.MakeSlice(t, n, n)
+		reflect.Copy(nv, v)
+		nv.Index(i).Set(e)
+		return nv, true
+	case 2:
+		// Remove an element.
+		i := m.rand(n)
+		nv := reflect.MakeSlice(t, 0, n-1)
+		nv = reflect.AppendSlice(nv, v.Slice(0, i))
+		return reflect.AppendSlice(nv, v.Slice(i+1, n)), true
+	default:
+		// Duplicate an element.
+		return insertElem(v, m.rand(n+1), v.Index(m.rand(n))), true
+	}
+}
+
+// insertElem returns a copy of the slice v with e inserted at index i.
+func insertElem(v reflect.Value, i int, e reflect.Value) reflect.Value {
+	n := v.Len()
+	nv := reflect.MakeSlice(v.Type(), 0, n+1)
+	nv = reflect.AppendSlice(nv, v.Slice(0, i))
+	nv = reflect.Append(nv, e)
+	return reflect.AppendSlice(nv, v.Slice(i, n))
+}
+
+// mutateMap returns a copy of v, a map, with the value of an entry mutated,
+// an entry added, or an entry removed.
+func (m *mutator) mutateMap(v reflect.Value) (reflect.Value, bool) {
+	t := v.Type()
+	// Choose entries in key order, so that mutations are deterministic.
+	sorted := fmtsort.Sort(v)
+	n := len(sorted.Key)
+	skip := -1
+	var key, elem reflect.Value
+	op := 0
+	if n > 0 {
+		op = m.rand(3)
+	}
+	switch op {
+	case 0:
+		// Add an entry with a mutated key, and a zero value.
+		key = reflect.Zero(t.Key())
+		if n > 0 {
+			key = sorted.Key[m.rand(n)]
+		}
+		var ok bool
+		if key, ok = m.mutateValue(key); !ok {
+			return v, false
+		}
+		elem = reflect.Zero(t.Elem())
+	case 1:
+		// Mutate the value of an entry.
+		skip = m.rand(n)
+		var ok bool
+		if elem, ok = m.mutateValue(sorted.Value[skip]); !ok {
+			return v, false
+		}
+		key = sorted.Key[skip]
+	default:
+		// Remove an entry.
+		skip = m.rand(n)
+	}
+	nv := reflect.MakeMapWithSize(t, n+1)
+	for i, k := range sorted.Key {
+		if i != skip {
+			nv.SetMapIndex(k, sorted.Value[i])
+		}
+	}
+	if key.IsValid() {
+		nv.SetMapIndex(key, elem)
+	}
+	return nv, true
+}
+
 func (m *mutator) mutateInt(v, maxValue int64) int64 {
 	var max int64
 	for {
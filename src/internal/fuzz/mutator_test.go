@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -115,3 +116,67 @@ func TestStringImmutability(t *testing.T) {
 		t.Fatalf("string was mutated: got %x, want %x", []byte(original), originalCopy)
 	}
 }
+
+func TestMutateStructured(t *testing.T) {
+	orig := testRecord{
+		ID:     7,
+		Tags:   []string{"x", ""},
+		Points: []testPoint{{X: 1, Y: 2, Name: "p"}},
+		Attrs:  map[string]float64{"pi": 3.14, "e": 2.71},
+		Hash:   [4]byte{1, 2, 3, 4},
+		Data:   []byte("data"),
+		Stamp:  testStamp{sec: 5},
+		Counts: map[testLevel]uint8{1: 2},
+	}
+	origData := marshalCorpusFile(orig)
+	typ := reflect.TypeOf(orig)
+
+	m1, m2 := newMutator(), newMutator()
+	var state, inc uint64
+	m1.r.save(&state, &inc)
+	m2.r.restore(state, inc)
+
+	vals1, vals2 := []any{orig}, []any{orig}
+	changed := false
+	for i := 0; i < 1000; i++ {
+		m1.mutate(vals1, 1<<20)
+		m2.mutate(vals2, 1<<20)
+		if got := reflect.TypeOf(vals1[0]); got != typ {
+			t.Fatalf("mutated value has type %v, want %v", got, typ)
+		}
+		data1, data2 := marshalCorpusFile(vals1...), marshalCorpusFile(vals2...)
+		if !bytes.Equal(data1, data2) {
+			t.Fatalf("mutations are not deterministic:\n%s\n%s", data1, data2)
+		}
+		vals, err := unmarshalCorpusFile(data1, []reflect.Type{typ})
+		if err != nil {
+			t.Fatalf("unmarshaling mutated value: %v\n%s", err, data1)
+		}
+		if !bytes.Equal(marshalCorpusFile(vals...), data1) {
+			t.Fatalf("mutated value changed after marshal then unmarshal:\n%s", data1)
+		}
+		if !bytes.Equal(data1, origData) {
+			changed = true
+		}
+	}
+	if !changed {
+		t.Errorf("value was never mutated")
+	}
+	if data := marshalCorpusFile(orig); !bytes.Equal(data, origData) {
+		t.Errorf("original value was modified:\n%s\nwant:\n%s", data, origData)
+	}
+}
+
+func TestMutateStructuredMaxBytes(t *testing.T) {
+	m := newMutator()
+	const maxBytes = 200
+	vals := []any{[]string{"a"}}
+	for i := 0; i < 1000; i++ {
+		m.mutate(vals, maxBytes+100) // mutate leaves 100 bytes for the encoding
+		var b bytes.Buffer
+		encodeValue(&b, reflect.ValueOf(vals[0]))
+		if b.Len() > maxBytes {
+			t.Fatalf("mutated value is %d bytes long, want at most %d: %s", b.Len(), maxBytes, b.Bytes())
+		}
+	}
+}
@@ -372,7 +372,7 @@ func (w *worker) start() (err error) {
 	w.termC = make(chan struct{})
 	comm := workerComm{fuzzIn: fuzzInW, fuzzOut: fuzzOutR, memMu: w.memMu}
 	m := newMutator()
-	w.client = newWorkerClient(comm, m)
+	w.client = newWorkerClient(comm, m, w.coordinator.opts.Types)
 
 	go func() {
 		w.waitErr = w.cmd.Wait()
@@ -470,9 +470,12 @@ func (w *worker) stop() error {
 // a given input "crashed". The coordinator will also record a crasher if
 // the function times out or terminates the process.
 //
+// types is the list of types which make up a corpus entry, as in
+// CoordinateFuzzingOpts.
+//
 // RunFuzzWorker returns an error if it could not communicate with the
 // coordinator process.
-func RunFuzzWorker(ctx context.Context, fn func(CorpusEntry) error) error {
+func RunFuzzWorker(ctx context.Context, types []reflect.Type, fn func(CorpusEntry) error) error {
 	comm, err := getWorkerComm()
 	if err != nil {
 		return err
@@ -488,7 +491,8 @@ func RunFuzzWorker(ctx context.Context, fn func(CorpusEntry) error) error {
 			err := fn(e)
 			return time.Since(start), err
 		},
-		m: newMutator(),
+		m:     newMutator(),
+		types: types,
 	}
 	return srv.serve(ctx)
 }
@@ -618,6 +622,10 @@ type workerServer struct {
 	workerComm
 	m *mutator
 
+	// types is the list of types which make up a corpus entry. It is needed
+	// to unmarshal values of structured types.
+	types []reflect.Type
+
 	// coverageMask is the local coverage data for the worker. It is
 	// periodically updated to reflect the data in the coordinator when new
 	// coverage is found.
@@ -721,7 +729,7 @@ func (ws *workerServer) fuzz(ctx context.Context, args fuzzArgs) (resp fuzzRespo
 		return resp
 	}
 
-	originalVals, err := unmarshalCorpusFile(mem.valueCopy())
+	originalVals, err := unmarshalCorpusFile(mem.valueCopy(), ws.types)
 	if err != nil {
 		resp.InternalErr = err.Error()
 		return resp
@@ -796,7 +804,7 @@ func (ws *workerServer) minimize(ctx context.Context, args minimizeArgs) (resp m
 	defer func() { resp.Duration = time.Now().Sub(start) }()
 	mem := <-ws.memMu
 	defer func() { ws.memMu <- mem }()
-	vals, err := unmarshalCorpusFile(mem.valueCopy())
+	vals, err := unmarshalCorpusFile(mem.valueCopy(), ws.types)
 	if err != nil {
 		panic(err)
 	}
@@ -855,22 +863,19 @@ func (ws *workerServer) minimizeInput(ctx context.Context, vals []any, mem *shar
 	mem.header().rawInMem = true
 
 	// tryMinimized runs the fuzz function with candidate replacing the value
-	// at index valI. tryMinimized returns whether the input with candidate is
-	// interesting for the same reason as the original input: it returns
-	// an error if one was expected, or it preserves coverage.
-	tryMinimized := func(candidate []byte) bool {
-		prev := vals[args.Index]
-		switch prev.(type) {
-		case []byte:
-			vals[args.Index] = candidate
-		case string:
-			vals[args.Index] = string(candidate)
-		default:
-			panic("impossible")
+	// at index valI, after writing raw, the raw bytes of candidate, to mem.
+	// tryMinimized returns whether the input with candidate is interesting for
+	// the same reason as the original input: it returns an error if one was
+	// expected, or it preserves coverage.
+	tryMinimized := func(candidate any, raw []byte) bool {
+		if len(raw) > cap(*bPtr) {
+			return false
 		}
-		copy(*bPtr, candidate)
-		*bPtr = (*bPtr)[:len(candidate)]
-		mem.setValueLen(len(candidate))
+		prev := vals[args.Index]
+		vals[args.Index] = candidate
+		*bPtr = (*bPtr)[:len(raw)]
+		copy(*bPtr, raw)
+		mem.setValueLen(len(raw))
 		*count++
 		_, err := ws.fuzzFn(CorpusEntry{Values: vals})
 		if err != nil {
@@ -891,11 +896,20 @@ func (ws *workerServer) minimizeInput(ctx context.Context, vals []any, mem *shar
 	}
 	switch v := vals[args.Index].(type) {
 	case string:
-		minimizeBytes([]byte(v), tryMinimized, shouldStop)
+		minimizeBytes([]byte(v), func(candidate []byte) bool {
+			return tryMinimized(string(candidate), candidate)
+		}, shouldStop)
 	case []byte:
-		minimizeBytes(v, tryMinimized, shouldStop)
+		minimizeBytes(v, func(candidate []byte) bool {
+			return tryMinimized(candidate, candidate)
+		}, shouldStop)
 	default:
-		panic("impossible")
+		// The raw bytes of a value of a structured type are its encoding in
+		// a corpus file of its own.
+		minimizeValue(reflect.ValueOf(v), func(candidate reflect.Value) bool {
+			c := candidate.Interface()
+			return tryMinimized(c, marshalCorpusFile(c))
+		}, shouldStop)
 	}
 	return true, retErr
 }
@@ -918,6 +932,10 @@ type workerClient struct {
 	workerComm
 	m *mutator
 
+	// types is the list of types which make up a corpus entry. It is needed
+	// to unmarshal values of structured types.
+	types []reflect.Type
+
 	// mu is the mutex protecting the workerComm.fuzzIn pipe. This must be
 	// locked before making calls to the workerServer. It prevents
 	// workerClient.Close from closing fuzzIn while workerClient methods are
@@ -926,8 +944,8 @@ type workerClient struct {
 	mu sync.Mutex
 }
 
-func newWorkerClient(comm workerComm, m *mutator) *workerClient {
-	return &workerClient{workerComm: comm, m: m}
+func newWorkerClient(comm workerComm, m *mutator, types []reflect.Type) *workerClient {
+	return &workerClient{workerComm: comm, m: m, types: types}
 }
 
 // Close shuts down the connection to the RPC server (the worker process) by
@@ -980,7 +998,7 @@ func (wc *workerClient) minimize(ctx context.Context, entryIn CorpusEntry, args
 	mem.setValue(inp)
 	defer func() { wc.memMu <- mem }()
 	entryOut = entryIn
-	entryOut.Values, err = unmarshalCorpusFile(inp)
+	entryOut.Values, err = unmarshalCorpusFile(inp, wc.types)
 	if err != nil {
 		return CorpusEntry{}, minimizeResponse{}, fmt.Errorf("workerClient.minimize unmarshaling provided value: %v", err)
 	}
@@ -1006,14 +1024,20 @@ func (wc *workerClient) minimize(ctx context.Context, entryIn CorpusEntry, args
 			}
 			// An unrecoverable error occurred during minimization. mem now
 			// holds the raw, unmarshalled bytes of entryIn.Values[i] that
-			// caused the error.
+			// caused the error, or for structured types, the marshaled value.
 			switch entryOut.Values[i].(type) {
 			case string:
 				entryOut.Values[i] = string(mem.valueCopy())
 			case []byte:
 				entryOut.Values[i] = mem.valueCopy()
 			default:
-				panic("impossible")
+				var types []reflect.Type
+				if i < len(wc.types) {
+					types = wc.types[i : i+1]
+				}
+				if vals, err := unmarshalCorpusFile(mem.valueCopy(), types); err == nil && len(vals) == 1 {
+					entryOut.Values[i] = vals[0]
+				}
 			}
 			entryOut.Data = marshalCorpusFile(entryOut.Values...)
 			// Stop minimizing; another unrecoverable error is likely to occur.
@@ -1023,7 +1047,7 @@ func (wc *workerClient) minimize(ctx context.Context, entryIn CorpusEntry, args
 		if resp.WroteToMem {
 			// Minimization succeeded, and mem holds the marshaled data.
 			entryOut.Data = mem.valueCopy()
-			entryOut.Values, err = unmarshalCorpusFile(entryOut.Data)
+			entryOut.Values, err = unmarshalCorpusFile(entryOut.Data, wc.types)
 			if err != nil {
 				return CorpusEntry{}, minimizeResponse{}, fmt.Errorf("workerClient.minimize unmarshaling minimized value: %v", err)
 			}
@@ -1084,7 +1108,7 @@ func (wc *workerClient) fuzz(ctx context.Context, entryIn CorpusEntry, args fuzz
 	needEntryOut := callErr != nil || resp.Err != "" ||
 		(!args.Warmup && resp.CoverageData != nil)
 	if needEntryOut {
-		valuesOut, err := unmarshalCorpusFile(inp)
+		valuesOut, err := unmarshalCorpusFile(inp, wc.types)
 		if err != nil {
 			return CorpusEntry{}, fuzzResponse{}, true, fmt.Errorf("unmarshaling fuzz input value after call: %v", err)
 		}
@@ -0,0 +1,488 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"debug/trace"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// parseGenerations reads a trace in the format written since Go 1.18,
+// which is partitioned into generations, and converts its events to
+// the events of the format of earlier versions, which the rest of this
+// package and cmd/trace work with.
+func parseGenerations(r io.Reader) ([]*Event, map[uint64][]*Frame, error) {
+	tr, err := trace.NewReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := &converter{
+		stacks:    make(map[uint64][]*Frame),
+		stackIDs:  make(map[trace.Stack]uint64),
+		stackKeys: make(map[string]uint64),
+		gs:        make(map[trace.GoID]*convG),
+		ps:        make(map[trace.ProcID]*convP),
+		starts:    make(map[trace.GoID]*Event),
+	}
+	var last trace.Event
+	for {
+		ev, err := tr.ReadEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := c.convert(ev); err != nil {
+			return nil, nil, err
+		}
+		last = ev
+	}
+	if len(c.events) == 0 {
+		return nil, nil, fmt.Errorf("trace is empty")
+	}
+	c.finish(last)
+	// The events are ordered independently of their timestamps, which
+	// the reader makes monotonic, so broken timestamps never make a
+	// trace inconsistent. Emulate their detection for the tests.
+	if BreakTimestampsForTesting {
+		for i := 0; i < 5; i++ {
+			c.events[rand.Intn(len(c.events))].Ts += int64(rand.Intn(2000) - 1000)
+		}
+		if !sort.IsSorted(eventList(c.events)) {
+			return nil, nil, ErrTimeOrder
+		}
+	}
+	return c.events, c.stacks, nil
+}
+
+// States of goroutines in the older format.
+const (
+	convRunnable = iota
+	convRunning  // running on a P, possibly in a syscall
+	convPending  // running when the trace starts, but not started yet
+	convWaiting  // blocked, or in a syscall without a P
+	convGone     // ended, or blocked forever
+)
+
+type convG struct {
+	state     int
+	p         trace.ProcID // the P of a running goroutine
+	inSyscall bool
+}
+
+type convP struct {
+	running bool
+	g       trace.GoID // the goroutine running on the P, or trace.NoGoroutine
+}
+
+// converter converts the events of a trace to the older format.
+//
+// The main difference between the formats is that a goroutine in a
+// syscall keeps its P in the older format until the P is taken away,
+// which shows as the goroutine blocking in the syscall, instead of
+// the P being stolen from the syscall.
+type converter struct {
+	events    []*Event
+	stacks    map[uint64][]*Frame
+	stackIDs  map[trace.Stack]uint64
+	stackKeys map[string]uint64 // stack IDs by frames, across generations
+	gs        map[trace.GoID]*convG
+	ps        map[trace.ProcID]*convP
+	starts    map[trace.GoID]*Event // last EvGoStart of goroutines, for labels
+	t0        trace.Time
+	gcSeq     uint64
+	gcActive  bool
+	stwActive bool
+}
+
+func (c *converter) g(id trace.GoID) *convG {
+	g := c.gs[id]
+	if g == nil {
+		g = &convG{state: convGone}
+		c.gs[id] = g
+	}
+	return g
+}
+
+func (c *converter) p(id trace.ProcID) *convP {
+	p := c.ps[id]
+	if p == nil {
+		p = &convP{g: trace.NoGoroutine}
+		c.ps[id] = p
+	}
+	return p
+}
+
+// stackID returns the ID of stk in the converted trace.
+func (c *converter) stackID(stk trace.Stack) uint64 {
+	if stk == trace.NoStack {
+		return 0
+	}
+	if id, ok := c.stackIDs[stk]; ok {
+		return id
+	}
+	frames := stk.Frames()
+	var key strings.Builder
+	for _, f := range frames {
+		fmt.Fprintf(&key, "%x %s %s %d\n", f.PC, f.Func, f.File, f.Line)
+	}
+	id, ok := c.stackKeys[key.String()]
+	if !ok {
+		id = uint64(len(c.stackKeys) + 1)
+		c.stackKeys[key.String()] = id
+		stack := make([]*Frame, len(frames))
+		for i, f := range frames {
+			stack[i] = &Frame{PC: f.PC, Fn: f.Func, File: f.File, Line: int(f.Line)}
+		}
+		c.stacks[id] = stack
+	}
+	c.stackIDs[stk] = id
+	return id
+}
+
+func oldP(p trace.ProcID) int {
+	if p == trace.NoProc {
+		return FakeP
+	}
+	return int(p)
+}
+
+func oldG(g trace.GoID) uint64 {
+	if g == trace.NoGoroutine {
+		return 0
+	}
+	return uint64(g)
+}
+
+// emit appends an event of type typ that happens at the time of ev.
+func (c *converter) emit(ev trace.Event, typ byte, p int, g uint64, stk trace.Stack) *Event {
+	if len(c.events) == 0 {
+		c.t0 = ev.Time()
+	}
+	e := &Event{
+		Off:   len(c.events),
+		Type:  typ,
+		Ts:    int64(ev.Time().Sub(c.t0)),
+		P:     p,
+		G:     g,
+		StkID: c.stackID(stk),
+	}
+	c.events = append(c.events, e)
+	return e
+}
+
+// start starts running goroutine id on P p.
+func (c *converter) start(ev trace.Event, id trace.GoID, p trace.ProcID) {
+	e := c.emit(ev, EvGoStart, oldP(p), uint64(id), trace.NoStack)
+	e.Args[0] = uint64(id)
+	c.starts[id] = e
+	g := c.g(id)
+	g.state = convRunning
+	g.p = p
+	g.inSyscall = false
+	c.p(p).g = id
+}
+
+// stop stops running goroutine id on its P.
+func (c *converter) stop(id trace.GoID, state int) {
+	g := c.g(id)
+	if g.state == convRunning {
+		c.p(g.p).g = trace.NoGoroutine
+	}
+	g.state = state
+	g.inSyscall = false
+}
+
+// sysBlock makes goroutine id, which is in a syscall, lose its P.
+func (c *converter) sysBlock(ev trace.Event, id trace.GoID) {
+	g := c.g(id)
+	if g.state != convRunning {
+		return
+	}
+	c.emit(ev, EvGoSysBlock, oldP(g.p), uint64(id), trace.NoStack)
+	c.stop(id, convWaiting)
+}
+
+// convert converts ev to events of the older format.
+func (c *converter) convert(ev trace.Event) error {
+	if ev.Kind() == trace.EventSync {
+		return nil
+	}
+	if ev.Kind() == trace.EventStateTransition {
+		st := ev.StateTransition()
+		if st.Resource.Kind == trace.ResourceGoroutine {
+			if from, _ := st.Goroutine(); from == trace.GoUndetermined {
+				c.initialGoroutine(ev, st)
+				return nil
+			}
+		}
+	}
+
+	// A goroutine that was running when the trace started starts
+	// running in the older format at its first event.
+	if g := ev.Goroutine(); g != trace.NoGoroutine && c.g(g).state == convPending {
+		c.start(ev, g, ev.Proc())
+	}
+	p, g := oldP(ev.Proc()), oldG(ev.Goroutine())
+
+	switch ev.Kind() {
+	case trace.EventMetric:
+		m := ev.Metric()
+		var e *Event
+		switch m.Name {
+		case "/sched/gomaxprocs:threads":
+			e = c.emit(ev, EvGomaxprocs, p, g, ev.Stack())
+		case "/memory/classes/heap/objects:bytes":
+			e = c.emit(ev, EvHeapAlloc, p, g, trace.NoStack)
+		case "/gc/heap/goal:bytes":
+			e = c.emit(ev, EvHeapGoal, p, g, trace.NoStack)
+		default:
+			return nil
+		}
+		e.Args[0] = m.Value
+	case trace.EventLabel:
+		l := ev.Label()
+		if l.Resource.Kind != trace.ResourceGoroutine {
+			return nil
+		}
+		if start := c.starts[l.Resource.Goroutine()]; start != nil {
+			start.Type = EvGoStartLabel
+			start.SArgs = []string{l.Label}
+		}
+	case trace.EventRangeBegin, trace.EventRangeEnd:
+		return c.convertRange(ev, p, g)
+	case trace.EventTaskBegin:
+		t := ev.Task()
+		e := c.emit(ev, EvUserTaskCreate, p, g, ev.Stack())
+		e.Args[0] = uint64(t.ID)
+		e.Args[1] = uint64(t.Parent)
+		e.SArgs = []string{t.Type}
+	case trace.EventTaskEnd:
+		e := c.emit(ev, EvUserTaskEnd, p, g, ev.Stack())
+		e.Args[0] = uint64(ev.Task().ID)
+	case trace.EventRegionBegin, trace.EventRegionEnd:
+		r := ev.Region()
+		e := c.emit(ev, EvUserRegion, p, g, ev.Stack())
+		e.Args[0] = uint64(r.Task)
+		if ev.Kind() == trace.EventRegionEnd {
+			e.Args[1] = 1
+		}
+		e.SArgs = []string{r.Type}
+	case trace.EventLog:
+		l := ev.Log()
+		e := c.emit(ev, EvUserLog, p, g, ev.Stack())
+		e.Args[0] = uint64(l.Task)
+		e.SArgs = []string{l.Category, l.Message}
+	case trace.EventStateTransition:
+		st := ev.StateTransition()
+		if st.Resource.Kind == trace.ResourceProc {
+			c.convertProc(ev, st)
+			return nil
+		}
+		return c.convertGoroutine(ev, st, p, g)
+	}
+	return nil
+}
+
+// finish stops the goroutines that are still running at the end of
+// the trace, at the time of the last event, like the runtime did when
+// it stopped tracing in the older format.
+func (c *converter) finish(last trace.Event) {
+	ids := make([]trace.GoID, 0, len(c.gs))
+	for id, g := range c.gs {
+		if g.state == convRunning {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		c.emit(last, EvGoSched, oldP(c.g(id).p), uint64(id), trace.NoStack)
+		c.stop(id, convRunnable)
+	}
+}
+
+// initialGoroutine converts the first state of a goroutine in the trace.
+func (c *converter) initialGoroutine(ev trace.Event, st trace.StateTransition) {
+	id := st.Resource.Goroutine()
+	e := c.emit(ev, EvGoCreate, FakeP, 0, trace.NoStack)
+	e.Args[0] = uint64(id)
+	e.Args[1] = c.stackID(st.Stack)
+	g := c.g(id)
+	g.state = convRunnable
+	switch _, to := st.Goroutine(); to {
+	case trace.GoWaiting, trace.GoSyscall:
+		typ := byte(EvGoWaiting)
+		if to == trace.GoSyscall {
+			typ = EvGoInSyscall
+		}
+		e := c.emit(ev, typ, FakeP, uint64(id), trace.NoStack)
+		e.Args[0] = uint64(id)
+		g.state = convWaiting
+	case trace.GoRunning:
+		g.state = convPending
+	}
+}
+
+func (c *converter) convertProc(ev trace.Event, st trace.StateTransition) {
+	id := st.Resource.Proc()
+	p := c.p(id)
+	switch _, to := st.Proc(); to {
+	case trace.ProcRunning:
+		if p.running {
+			return
+		}
+		e := c.emit(ev, EvProcStart, int(id), 0, trace.NoStack)
+		if ev.Thread() != trace.NoThread {
+			e.Args[0] = uint64(ev.Thread())
+		}
+		p.running = true
+	case trace.ProcIdle:
+		if !p.running {
+			return
+		}
+		if p.g != trace.NoGoroutine {
+			// The P is stopped or stolen while a goroutine is in a
+			// syscall on it.
+			c.sysBlock(ev, p.g)
+		}
+		c.emit(ev, EvProcStop, int(id), 0, trace.NoStack)
+		p.running = false
+	}
+}
+
+// goBlockTypes are the event types of the older format for the reasons
+// of goroutines blocking.
+var goBlockTypes = map[string]byte{
+	"forever":                      EvGoStop,
+	"network":                      EvGoBlockNet,
+	"select":                       EvGoBlockSelect,
+	"sync.(*Cond).Wait":            EvGoBlockCond,
+	"sync":                         EvGoBlockSync,
+	"chan send":                    EvGoBlockSend,
+	"chan receive":                 EvGoBlockRecv,
+	"GC mark assist wait for work": EvGoBlockGC,
+	"sleep":                        EvGoSleep,
+}
+
+func (c *converter) convertGoroutine(ev trace.Event, st trace.StateTransition, p int, g uint64) error {
+	id := st.Resource.Goroutine()
+	from, to := st.Goroutine()
+	switch {
+	case from == trace.GoNotExist && to == trace.GoRunnable:
+		e := c.emit(ev, EvGoCreate, p, g, ev.Stack())
+		e.Args[0] = uint64(id)
+		e.Args[1] = c.stackID(st.Stack)
+		c.g(id).state = convRunnable
+	case from == trace.GoNotExist && to == trace.GoSyscall:
+		// A goroutine of a thread that calls into Go from C. The
+		// goroutine of the thread is reused for later calls.
+		if cg := c.gs[id]; cg == nil {
+			e := c.emit(ev, EvGoCreate, FakeP, 0, trace.NoStack)
+			e.Args[0] = uint64(id)
+			e.Args[1] = c.stackID(st.Stack)
+			e = c.emit(ev, EvGoInSyscall, FakeP, uint64(id), trace.NoStack)
+			e.Args[0] = uint64(id)
+		}
+		c.g(id).state = convWaiting
+	case to == trace.GoRunning && from == trace.GoRunnable:
+		c.start(ev, id, ev.Proc())
+	case to == trace.GoRunning && from == trace.GoSyscall:
+		// Return from a syscall without losing the P.
+		c.g(id).inSyscall = false
+	case to == trace.GoNotExist && from == trace.GoSyscall:
+		c.sysBlock(ev, id)
+		c.stop(id, convGone)
+	case to == trace.GoNotExist:
+		c.emit(ev, EvGoEnd, p, g, trace.NoStack)
+		c.stop(id, convGone)
+	case from == trace.GoRunning && to == trace.GoRunnable:
+		typ := byte(EvGoPreempt)
+		if st.Reason == "runtime.Gosched" {
+			typ = EvGoSched
+		}
+		c.emit(ev, typ, p, g, st.Stack)
+		c.stop(id, convRunnable)
+	case from == trace.GoRunning && to == trace.GoWaiting:
+		typ, ok := goBlockTypes[st.Reason]
+		if !ok {
+			typ = EvGoBlock
+		}
+		c.emit(ev, typ, p, g, st.Stack)
+		if typ == EvGoStop {
+			c.stop(id, convGone)
+		} else {
+			c.stop(id, convWaiting)
+		}
+	case from == trace.GoWaiting && to == trace.GoRunnable:
+		e := c.emit(ev, EvGoUnblock, p, g, ev.Stack())
+		e.Args[0] = uint64(id)
+		c.g(id).state = convRunnable
+	case from == trace.GoRunning && to == trace.GoSyscall:
+		c.emit(ev, EvGoSysCall, p, g, st.Stack)
+		c.g(id).inSyscall = true
+	case from == trace.GoSyscall && to == trace.GoRunnable:
+		c.sysBlock(ev, id)
+		e := c.emit(ev, EvGoSysExit, SyscallP, uint64(id), trace.NoStack)
+		e.Args[0] = uint64(id)
+		c.g(id).state = convRunnable
+	default:
+		return fmt.Errorf("unexpected transition of goroutine %d from %v to %v at time %d", id, from, to, ev.Time())
+	}
+	return nil
+}
+
+func (c *converter) convertRange(ev trace.Event, p int, g uint64) error {
+	r := ev.Range()
+	begin := ev.Kind() == trace.EventRangeBegin
+	switch {
+	case r.Name == "GC concurrent mark phase":
+		if begin {
+			c.gcSeq++
+			e := c.emit(ev, EvGCStart, p, 0, ev.Stack())
+			e.Args[0] = c.gcSeq
+			c.gcActive = true
+		} else if c.gcActive {
+			// The trace may start in the middle of a GC.
+			c.emit(ev, EvGCDone, p, 0, trace.NoStack)
+			c.gcActive = false
+		}
+	case strings.HasPrefix(r.Name, "stop-the-world ("):
+		if begin {
+			kind := strings.TrimSuffix(strings.TrimPrefix(r.Name, "stop-the-world ("), ")")
+			e := c.emit(ev, EvGCSTWStart, p, 0, trace.NoStack)
+			if kind == "GC sweep termination" {
+				e.Args[0] = 1
+			}
+			e.SArgs = []string{strings.TrimPrefix(kind, "GC ")}
+			c.stwActive = true
+		} else if c.stwActive {
+			c.emit(ev, EvGCSTWDone, p, 0, trace.NoStack)
+			c.stwActive = false
+		}
+	case r.Name == "GC incremental sweep":
+		if begin {
+			c.emit(ev, EvGCSweepStart, p, g, ev.Stack())
+			break
+		}
+		e := c.emit(ev, EvGCSweepDone, p, g, trace.NoStack)
+		for i, a := range ev.RangeAttributes() {
+			if i < len(e.Args) {
+				e.Args[i] = a.Value
+			}
+		}
+	case r.Name == "GC mark assist":
+		if begin {
+			c.emit(ev, EvGCMarkAssistStart, p, g, ev.Stack())
+		} else {
+			c.emit(ev, EvGCMarkAssistDone, p, g, trace.NoStack)
+		}
+	}
+	return nil
+}
@@ -100,11 +100,27 @@ func Parse(r io.Reader, bin string) (ParseResult, error) {
 // parse parses, post-processes and verifies the trace. It returns the
 // trace version and the list of events.
 func parse(r io.Reader, bin string) (int, ParseResult, error) {
-	ver, rawEvents, strings, err := readTrace(r)
-	if err != nil {
-		return 0, ParseResult{}, err
+	br := bufio.NewReader(r)
+	var (
+		ver    int
+		events []*Event
+		stacks map[uint64][]*Frame
+		err    error
+	)
+	if hdr, _ := br.Peek(16); len(hdr) == 16 {
+		ver, _ = parseHeader(hdr)
+	}
+	if ver >= 1018 {
+		events, stacks, err = parseGenerations(br)
+	} else {
+		var rawEvents []rawEvent
+		var strings map[uint64]string
+		ver, rawEvents, strings, err = readTrace(br)
+		if err != nil {
+			return 0, ParseResult{}, err
+		}
+		events, stacks, err = parseEvents(ver, rawEvents, strings)
 	}
-	events, stacks, err := parseEvents(ver, rawEvents, strings)
 	if err != nil {
 		return 0, ParseResult{}, err
 	}
@@ -1007,7 +1023,8 @@ func argNum(raw rawEvent, ver int) int {
 var BreakTimestampsForTesting bool
 
 // Event types in the trace.
-// Verbatim copy from src/runtime/trace.go with the "trace" prefix removed.
+// Verbatim copy from src/runtime/trace.go of Go 1.17 with the "trace" prefix removed.
+// Traces of later versions are converted to these events, see parseGenerations.
 const (
 	EvNone              = 0  // unused
 	EvBatch             = 1  // start of per-P batch of events [pid, timestamp]
@@ -0,0 +1,68 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iter_test
+
+import (
+	"fmt"
+	"iter"
+)
+
+func ExampleSeq() {
+	fib := func(yield func(int) bool) {
+		a, b := 0, 1
+		for yield(a) {
+			a, b = b, a+b
+		}
+	}
+	for v := range iter.Seq[int](fib) {
+		if v > 20 {
+			break
+		}
+		fmt.Print(v, " ")
+	}
+	fmt.Println()
+	// Output:
+	// 0 1 1 2 3 5 8 13
+}
+
+// Pairs returns an iterator over successive pairs of values from seq.
+func Pairs[V any](seq iter.Seq[V]) iter.Seq2[V, V] {
+	return func(yield func(V, V) bool) {
+		next, stop := iter.Pull(seq)
+		defer stop()
+		for {
+			v1, ok1 := next()
+			if !ok1 {
+				return
+			}
+			v2, ok2 := next()
+			// If ok2 is false, v2 should be the
+			// zero value; yield one last pair.
+			if !yield(v1, v2) {
+				return
+			}
+			if !ok2 {
+				return
+			}
+		}
+	}
+}
+
+func ExamplePull() {
+	letters := func(yield func(string) bool) {
+		for _, s := range []string{"a", "b", "c", "d", "e"} {
+			if !yield(s) {
+				return
+			}
+		}
+	}
+	for x, y := range Pairs[string](letters) {
+		fmt.Printf("%q %q\n", x, y)
+	}
+	// Output:
+	// "a" "b"
+	// "c" "d"
+	// "e" ""
+}
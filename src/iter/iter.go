@@ -0,0 +1,207 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package iter provides basic definitions and operations related to
+iterators over sequences.
+
+An iterator is a function that passes successive elements of a
+sequence to a callback function, conventionally named yield.
+The function stops either when the sequence is finished or
+when yield returns false, indicating to stop the iteration early.
+This package defines Seq and Seq2 as shorthands for iterators
+that pass 1 or 2 values per sequence element to yield:
+
+	type (
+		Seq[V any]     func(yield func(V) bool)
+		Seq2[K, V any] func(yield func(K, V) bool)
+	)
+
+Seq2 represents a sequence of paired values, conventionally key-value
+or index-value pairs.
+
+Yield returns true if the iterator should continue with the next
+element in the sequence, false if it should stop.
+
+Iterator functions are most often called by a range loop, as in:
+
+	func PrintAll[V any](seq iter.Seq[V]) {
+		for v := range seq {
+			fmt.Println(v)
+		}
+	}
+
+Iterator functions that wrap sequences in other packages are
+conventionally named All, for the whole sequence, or after the
+elements they produce, such as Keys and Values for maps.
+
+Some iterators are more naturally consumed one element at a time.
+Pull and Pull2 convert such "push" iterators into "pull" iterators
+that return the next element each time they are called.
+*/
+package iter
+
+import "runtime"
+
+// Seq is an iterator over sequences of individual values.
+// When called as seq(yield), seq calls yield(v) for each value v in the sequence,
+// stopping early if yield returns false.
+// See the package documentation for more details.
+type Seq[V any] func(yield func(V) bool)
+
+// Seq2 is an iterator over sequences of pairs of values, most commonly key-value pairs.
+// When called as seq(yield), seq calls yield(k, v) for each pair (k, v) in the sequence,
+// stopping early if yield returns false.
+// See the package documentation for more details.
+type Seq2[K, V any] func(yield func(K, V) bool)
+
+// Pull converts the "push-style" iterator sequence seq
+// into a "pull-style" iterator accessed by the two functions
+// next and stop.
+//
+// Next returns the next value in the sequence
+// and a boolean indicating whether the value is valid.
+// When the sequence is over, next returns the zero V and false.
+// It is valid to call next after reaching the end of the sequence
+// or after calling stop. These calls will continue
+// to return the zero V and false.
+//
+// Stop ends the iteration. It must be called when the caller is
+// no longer interested in next values and next has not yet
+// signaled that the sequence is over (with a false boolean return).
+// It is valid to call stop multiple times and when next has
+// already returned false.
+//
+// It is an error to call next or stop from multiple goroutines
+// simultaneously.
+//
+// If the iterator function panics, or if it calls runtime.Goexit,
+// a call to next or stop propagates the panic or Goexit to its caller.
+func Pull[V any](seq Seq[V]) (next func() (V, bool), stop func()) {
+	next2, stop := pull(func(yield func(struct{}, V) bool) {
+		seq(func(v V) bool { return yield(struct{}{}, v) })
+	})
+	next = func() (V, bool) {
+		_, v, ok := next2()
+		return v, ok
+	}
+	return next, stop
+}
+
+// Pull2 converts the "push-style" iterator sequence seq
+// into a "pull-style" iterator accessed by the two functions
+// next and stop.
+//
+// Next returns the next pair in the sequence
+// and a boolean indicating whether the pair is valid.
+// When the sequence is over, next returns a pair of zero values and false.
+// It is valid to call next after reaching the end of the sequence
+// or after calling stop. These calls will continue
+// to return a pair of zero values and false.
+//
+// Stop ends the iteration. It must be called when the caller is
+// no longer interested in next values and next has not yet
+// signaled that the sequence is over (with a false boolean return).
+// It is valid to call stop multiple times and when next has
+// already returned false.
+//
+// It is an error to call next or stop from multiple goroutines
+// simultaneously.
+//
+// If the iterator function panics, or if it calls runtime.Goexit,
+// a call to next or stop propagates the panic or Goexit to its caller.
+func Pull2[K, V any](seq Seq2[K, V]) (next func() (K, V, bool), stop func()) {
+	return pull(seq)
+}
+
+// pull implements Pull and Pull2. It runs seq in its own goroutine,
+// which is started by the first call to next. The goroutines hand
+// control back and forth over the unbuffered channels resume and
+// yielded, so that only one of them runs at a time.
+func pull[K, V any](seq func(yield func(K, V) bool)) (next func() (K, V, bool), stop func()) {
+	var (
+		k       K
+		v       V
+		ok      bool
+		done    bool // seq returned, or stop was called
+		started bool // the goroutine running seq was started
+
+		panicValue interface{}
+		panicked   bool
+		goexit     bool
+
+		resume  = make(chan struct{})
+		yielded = make(chan struct{})
+	)
+
+	yield := func(k1 K, v1 V) bool {
+		if done {
+			return false
+		}
+		k, v, ok = k1, v1, true
+		yielded <- struct{}{}
+		<-resume
+		return !done
+	}
+
+	run := func() {
+		returned := false
+		defer func() {
+			if p := recover(); p != nil {
+				panicValue, panicked = p, true
+			} else if !returned {
+				goexit = true
+			}
+			var k0 K
+			var v0 V
+			k, v, ok = k0, v0, false
+			done = true
+			close(yielded)
+		}()
+		<-resume
+		if !done {
+			seq(yield)
+		}
+		returned = true
+	}
+
+	// wait hands control to the goroutine running seq and waits for it
+	// to yield or finish, propagating any panic or Goexit.
+	wait := func() {
+		resume <- struct{}{}
+		<-yielded
+		if panicked {
+			panicked = false
+			panic(panicValue)
+		}
+		if goexit {
+			goexit = false
+			runtime.Goexit()
+		}
+	}
+
+	next = func() (k1 K, v1 V, ok1 bool) {
+		if done {
+			return
+		}
+		if !started {
+			started = true
+			go run()
+		}
+		wait()
+		return k, v, ok
+	}
+
+	stop = func() {
+		if done {
+			return
+		}
+		done = true
+		if started {
+			wait()
+		}
+	}
+
+	return next, stop
+}
@@ -0,0 +1,180 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iter_test
+
+import (
+	"fmt"
+	. "iter"
+	"runtime"
+	"testing"
+)
+
+func count(n int) Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i) {
+				break
+			}
+		}
+	}
+}
+
+func squares(n int) Seq2[int, int64] {
+	return func(yield func(int, int64) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i, int64(i)*int64(i)) {
+				break
+			}
+		}
+	}
+}
+
+func TestPull(t *testing.T) {
+	for end := 0; end <= 3; end++ {
+		t.Run(fmt.Sprint(end), func(t *testing.T) {
+			next, stop := Pull(count(3))
+			for i := 0; i < end; i++ {
+				v, ok := next()
+				if v != i || !ok {
+					t.Fatalf("next() = %d, %v, want %d, %v", v, ok, i, true)
+				}
+			}
+			stop()
+			v, ok := next()
+			if v != 0 || ok {
+				t.Fatalf("next() after stop = %d, %v, want %d, %v", v, ok, 0, false)
+			}
+			stop()
+		})
+	}
+}
+
+func TestPullToEnd(t *testing.T) {
+	next, stop := Pull(count(2))
+	var got []int
+	for {
+		v, ok := next()
+		if !ok {
+			break
+		}
+		got = append(got, v)
+	}
+	if len(got) != 2 || got[0] != 0 || got[1] != 1 {
+		t.Errorf("got %v, want [0 1]", got)
+	}
+	if v, ok := next(); v != 0 || ok {
+		t.Errorf("next() after end = %d, %v, want %d, %v", v, ok, 0, false)
+	}
+	stop()
+}
+
+func TestPull2(t *testing.T) {
+	for end := 0; end <= 3; end++ {
+		t.Run(fmt.Sprint(end), func(t *testing.T) {
+			next, stop := Pull2(squares(3))
+			for i := 0; i < end; i++ {
+				k, v, ok := next()
+				if k != i || v != int64(i*i) || !ok {
+					t.Fatalf("next() = %d, %d, %v, want %d, %d, %v", k, v, ok, i, i*i, true)
+				}
+			}
+			stop()
+			k, v, ok := next()
+			if k != 0 || v != 0 || ok {
+				t.Fatalf("next() after stop = %d, %d, %v, want %d, %d, %v", k, v, ok, 0, 0, false)
+			}
+		})
+	}
+}
+
+func TestPullStopBeforeNext(t *testing.T) {
+	called := false
+	next, stop := Pull(Seq[int](func(yield func(int) bool) { called = true }))
+	stop()
+	if _, ok := next(); ok {
+		t.Errorf("next() after stop returned ok")
+	}
+	if called {
+		t.Errorf("stop before next ran the iterator")
+	}
+}
+
+func TestPullStopEndsIterator(t *testing.T) {
+	yieldResult := true
+	next, stop := Pull(Seq[int](func(yield func(int) bool) {
+		yieldResult = yield(1)
+		yield(2) // ignored after stop
+	}))
+	if v, ok := next(); v != 1 || !ok {
+		t.Fatalf("next() = %d, %v, want 1, true", v, ok)
+	}
+	stop()
+	if yieldResult {
+		t.Errorf("yield returned true after stop")
+	}
+}
+
+func panicSeq(yield func(int) bool) {
+	yield(1)
+	panic("boom")
+}
+
+func TestPullPanic(t *testing.T) {
+	t.Run("next", func(t *testing.T) {
+		next, stop := Pull(Seq[int](panicSeq))
+		defer stop()
+		if v, ok := next(); v != 1 || !ok {
+			t.Fatalf("next() = %d, %v, want 1, true", v, ok)
+		}
+		if !panics("boom", func() { next() }) {
+			t.Fatal("next() did not propagate panic")
+		}
+		if _, ok := next(); ok {
+			t.Fatal("next() after panic returned ok")
+		}
+	})
+	t.Run("stop", func(t *testing.T) {
+		next, stop := Pull(Seq[int](func(yield func(int) bool) {
+			if !yield(1) {
+				panic("boom")
+			}
+		}))
+		next()
+		if !panics("boom", stop) {
+			t.Fatal("stop() did not propagate panic")
+		}
+		stop()
+	})
+}
+
+func panics(want interface{}, f func()) (didPanic bool) {
+	defer func() {
+		if p := recover(); p == want {
+			didPanic = true
+		}
+	}()
+	f()
+	return false
+}
+
+func TestPullGoexit(t *testing.T) {
+	next, stop := Pull(Seq[int](func(yield func(int) bool) {
+		runtime.Goexit()
+	}))
+	defer stop()
+
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		next()
+		done <- true // not reached
+	}()
+	if <-done {
+		t.Fatal("next() did not propagate Goexit")
+	}
+	if _, ok := next(); ok {
+		t.Fatal("next() after Goexit returned ok")
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog_test
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+func ExampleGroup() {
+	r, _ := http.NewRequest("GET", "localhost", nil)
+	// ...
+
+	logger := slog.New(
+		slog.NewTextHandler(
+			os.Stdout,
+			&slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey && len(groups) == 0 {
+						return slog.Attr{}
+					}
+					return a
+				},
+			},
+		),
+	)
+	logger.Info("finished",
+		slog.Group("req",
+			slog.String("method", r.Method),
+			slog.String("url", r.URL.String())),
+		slog.Int("status", http.StatusOK),
+		slog.Duration("duration", time.Second))
+
+	// Output:
+	// level=INFO msg=finished req.method=GET req.url=localhost status=200 duration=1s
+}
+
+func ExampleHandlerOptions_customLevels() {
+	// This example demonstrates using custom log levels and custom log level names.
+	// In addition to the default log levels, it introduces Trace (-8) and
+	// Emergency (12) levels.
+
+	const (
+		LevelTrace     = slog.Level(-8)
+		LevelEmergency = slog.Level(12)
+	)
+
+	th := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		// Set a custom level to show all log output. The default value is
+		// LevelInfo, which would drop Debug and Trace logs.
+		Level: LevelTrace,
+
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			// Remove time from the output for predictable test output.
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			// Customize the name of the level key and the output string,
+			// including custom level values.
+			if a.Key == slog.LevelKey {
+				// Rename the level key from "level" to "sev".
+				a.Key = "sev"
+
+				// Handle custom level values.
+				level := a.Value.Any().(slog.Level)
+
+				switch {
+				case level < slog.LevelDebug:
+					a.Value = slog.StringValue("TRACE")
+				case level < slog.LevelInfo:
+					a.Value = slog.StringValue("DEBUG")
+				case level < slog.LevelWarn:
+					a.Value = slog.StringValue("INFO")
+				case level < slog.LevelError:
+					a.Value = slog.StringValue("WARNING")
+				case level < LevelEmergency:
+					a.Value = slog.StringValue("ERROR")
+				default:
+					a.Value = slog.StringValue("EMERGENCY")
+				}
+			}
+
+			return a
+		},
+	})
+
+	logger := slog.New(th)
+	logger.Log(nil, LevelEmergency, "missing pilots")
+	logger.Error("failed to start engines", "err", "missing fuel")
+	logger.Warn("falling back to default value")
+	logger.Info("starting engines")
+	logger.Debug("button clicked")
+	logger.Log(nil, LevelTrace, "power on")
+
+	// Output:
+	// sev=EMERGENCY msg="missing pilots"
+	// sev=ERROR msg="failed to start engines" err="missing fuel"
+	// sev=WARNING msg="falling back to default value"
+	// sev=INFO msg="starting engines"
+	// sev=DEBUG msg="button clicked"
+	// sev=TRACE msg="power on"
+}
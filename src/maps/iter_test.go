@@ -0,0 +1,47 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package maps
+
+import "testing"
+
+func TestAll(t *testing.T) {
+	got := make(map[int]int)
+	for k, v := range All(m1) {
+		if _, ok := got[k]; ok {
+			t.Fatalf("key %d yielded twice", k)
+		}
+		got[k] = v
+	}
+	if !Equal(got, m1) {
+		t.Errorf("All(%v) yielded %v", m1, got)
+	}
+
+	n := 0
+	for range All(m1) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("All did not stop after break: %d iterations", n)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	got := map[int]int{1: 1, 16: 32}
+	Insert(got, All(m1))
+	want := map[int]int{1: 2, 2: 4, 4: 8, 8: 16, 16: 32}
+	if !Equal(got, want) {
+		t.Errorf("Insert result = %v, want %v", got, want)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	if got := Collect(All(m1)); !Equal(got, m1) {
+		t.Errorf("Collect(All(%v)) = %v", m1, got)
+	}
+	if got := Collect(All(map[string]int(nil))); got == nil || len(got) != 0 {
+		t.Errorf("Collect of an empty sequence = %#v, want empty non-nil map", got)
+	}
+}
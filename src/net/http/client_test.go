@@ -1526,7 +1526,7 @@ func TestClientCopyHeadersOnRedirect(t *testing.T) {
 			"User-Agent":      []string{ua},
 			"X-Foo":           []string{xfoo},
 			"Referer":         []string{ts2URL},
-			"Accept-Encoding": []string{"gzip"},
+			"Accept-Encoding": []string{"gzip, zstd"},
 		}
 		if !reflect.DeepEqual(r.Header, want) {
 			t.Errorf("Request.Header = %#v; want %#v", r.Header, want)
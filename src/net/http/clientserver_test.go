@@ -25,6 +25,7 @@ import (
 	"reflect"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -428,8 +429,13 @@ func TestH12_HandlerWritesTooMuch(t *testing.T) {
 func TestH12_AutoGzip(t *testing.T) {
 	h12Compare{
 		Handler: func(w ResponseWriter, r *Request) {
-			if ae := r.Header.Get("Accept-Encoding"); ae != "gzip" {
-				t.Errorf("%s Accept-Encoding = %q; want gzip", r.Proto, ae)
+			// The HTTP/2 transport doesn't request zstd yet.
+			want := "gzip, zstd"
+			if r.ProtoMajor == 2 {
+				want = "gzip"
+			}
+			if ae := r.Header.Get("Accept-Encoding"); ae != want {
+				t.Errorf("%s Accept-Encoding = %q; want %q", r.Proto, ae, want)
 			}
 			w.Header().Set("Content-Encoding", "gzip")
 			gz := gzip.NewWriter(w)
@@ -439,6 +445,48 @@ func TestH12_AutoGzip(t *testing.T) {
 	}.run(t)
 }
 
+// zstdContent is "I am some zstd compressed content. Go go go go go go go go go go go go should compress well.",
+// compressed with zstd.
+const zstdContent = "\x28\xb5\x2f\xfd\x04\x68\xbd\x01\x00\x22\x03\x0b\x11\xc0\xeb\xa8\xd9\x42\x5e\xb9\xf1\xda\x72" +
+	"\xd3\x93\x7a\x83\x2e\x73\x02\xe0\xee\xcc\xb7\xf2\xa8\x71\x06\x83\x45\x5f\x94\xc4\xe5\xaa\x57\x09\x93" +
+	"\xb8\xac\x36\x1f\x53\x8d\xc3\x22\x02\x00\x42\xd8\xf4\xc5\xdd\x13\xa0\x9b\xf6\x35"
+
+// TestAutoZstd_h1 tests that the HTTP/1 transport requests and decodes zstd.
+// The HTTP/2 transport, bundled from golang.org/x/net/http2, requests only gzip.
+func TestAutoZstd_h1(t *testing.T) {
+	defer afterTest(t)
+	cst := newClientServerTest(t, h1Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if ae := r.Header.Get("Accept-Encoding"); ae != "gzip, zstd" {
+			t.Errorf("Accept-Encoding = %q; want gzip, zstd", ae)
+		}
+		w.Header().Set("Content-Encoding", "zstd")
+		w.Header().Set("Content-Length", strconv.Itoa(len(zstdContent)))
+		io.WriteString(w, zstdContent)
+	}))
+	defer cst.close()
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(body), "I am some zstd compressed content. Go go go go go go go go go go go go should compress well."; got != want {
+		t.Errorf("body = %q; want %q", got, want)
+	}
+	if !res.Uncompressed {
+		t.Error("Uncompressed = false; want true")
+	}
+	if res.ContentLength != -1 {
+		t.Errorf("ContentLength = %d; want -1", res.ContentLength)
+	}
+	if ce := res.Header.Get("Content-Encoding"); ce != "" {
+		t.Errorf("Content-Encoding = %q; want empty", ce)
+	}
+}
+
 func TestH12_AutoGzip_Disabled(t *testing.T) {
 	h12Compare{
 		Opts: []any{
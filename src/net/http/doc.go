@@ -90,6 +90,11 @@ currently supported:
 	GODEBUG=http2debug=1   # enable verbose HTTP/2 debug logs
 	GODEBUG=http2debug=2   # ... even more verbose, with frame dumps
 
+The pattern syntax of ServeMux changed in Go 1.18. The following
+GODEBUG environment variable restores the Go 1.17 behavior:
+
+	GODEBUG=httpmuxgo118=0 # disable Go 1.18 ServeMux patterns
+
 The GODEBUG variables are not covered by Go's API compatibility
 promise. Please report any issues before disabling HTTP/2
 support: https://golang.org/s/http2bug
@@ -0,0 +1,155 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMapping(t *testing.T) {
+	var m mapping
+	var nodes []*routingNode
+	for i := 0; i < maxSlice; i++ {
+		nodes = append(nodes, &routingNode{})
+		m.add(strings.Repeat("*", i), nodes[i])
+	}
+	if m.m != nil {
+		t.Fatal("m.m != nil")
+	}
+	for i := 0; i < maxSlice; i++ {
+		g, _ := m.find(strings.Repeat("*", i))
+		if w := nodes[i]; g != w {
+			t.Fatalf("%d: got %p, want %p", i, g, w)
+		}
+	}
+	n := &routingNode{}
+	m.add("****", n)
+	if m.m == nil {
+		t.Fatal("m.m == nil")
+	}
+	if g, _ := m.find("****"); g != n {
+		t.Fatalf("got %p, want %p", g, n)
+	}
+}
+
+func TestMappingEachPair(t *testing.T) {
+	var m mapping
+	var want []entry
+	for i := 0; i < maxSlice*2; i++ {
+		k := strings.Repeat("*", i)
+		v := &routingNode{}
+		m.add(k, v)
+		want = append(want, entry{k, v})
+
+	}
+
+	var got []entry
+	m.eachPair(func(k string, v *routingNode) bool {
+		got = append(got, entry{k, v})
+		return true
+	})
+	sort.Slice(got, func(i, j int) bool { return got[i].key < got[j].key })
+	if len(got) != len(want) {
+		t.Fatalf("got %d pairs, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got[i], want[i])
+		}
+	}
+}
+
+func BenchmarkFindChild(b *testing.B) {
+	key := "articles"
+	children := []string{
+		"*",
+		"cmd.html",
+		"code.html",
+		"contrib.html",
+		"contribute.html",
+		"debugging_with_gdb.html",
+		"docs.html",
+		"effective_go.html",
+		"files.log",
+		"gccgo_contribute.html",
+		"gccgo_install.html",
+		"go-logo-black.png",
+		"go-logo-blue.png",
+		"go-logo-white.png",
+		"go1.1.html",
+		"go1.2.html",
+		"go1.html",
+		"go1compat.html",
+		"go_faq.html",
+		"go_mem.html",
+		"go_spec.html",
+		"help.html",
+		"ie.css",
+		"install-source.html",
+		"install.html",
+		"logo-153x55.png",
+		"Makefile",
+		"root.html",
+		"share.png",
+		"sieve.gif",
+		"tos.html",
+		"articles",
+	}
+	if len(children) != 32 {
+		panic("bad len")
+	}
+	for _, n := range []int{2, 4, 8, 16, 32} {
+		list := children[:n]
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+
+			b.Run("rep=linear", func(b *testing.B) {
+				var entries []entry
+				for _, c := range list {
+					entries = append(entries, entry{c, nil})
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					findChildLinear(key, entries)
+				}
+			})
+			b.Run("rep=map", func(b *testing.B) {
+				m := map[string]*routingNode{}
+				for _, c := range list {
+					m[c] = nil
+				}
+				var x *routingNode
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					x = m[key]
+				}
+				_ = x
+			})
+			b.Run(fmt.Sprintf("rep=hybrid%d", maxSlice), func(b *testing.B) {
+				var h mapping
+				for _, c := range list {
+					h.add(c, nil)
+				}
+				var x *routingNode
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					x, _ = h.find(key)
+				}
+				_ = x
+			})
+		})
+	}
+}
+
+func findChildLinear(key string, entries []entry) *routingNode {
+	for _, e := range entries {
+		if key == e.key {
+			return e.value
+		}
+	}
+	return nil
+}
@@ -6587,6 +6587,131 @@ func TestMuxRedirectRelative(t *testing.T) {
 	}
 }
 
+func TestServeMuxMethodNotAllowed(t *testing.T) {
+	setParallel(t)
+	mux := NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w ResponseWriter, r *Request) {})
+	mux.HandleFunc("POST /items/{id}", func(w ResponseWriter, r *Request) {})
+	mux.HandleFunc("/other", func(w ResponseWriter, r *Request) {})
+
+	for _, test := range []struct {
+		method    string
+		path      string
+		wantCode  int
+		wantAllow string
+	}{
+		{"GET", "/items/1", StatusOK, ""},
+		{"HEAD", "/items/1", StatusOK, ""},
+		{"POST", "/items/1", StatusOK, ""},
+		{"DELETE", "/items/1", StatusMethodNotAllowed, "GET, HEAD, POST"},
+		{"DELETE", "/other", StatusOK, ""},
+		{"DELETE", "/missing", StatusNotFound, ""},
+	} {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != test.wantCode {
+			t.Errorf("%s %s: got code %d, want %d", test.method, test.path, rec.Code, test.wantCode)
+		}
+		if g := rec.Header().Get("Allow"); g != test.wantAllow {
+			t.Errorf("%s %s: got Allow %q, want %q", test.method, test.path, g, test.wantAllow)
+		}
+	}
+}
+
+func TestServeMuxPatternConflictPanics(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/a/{x}", func(ResponseWriter, *Request) {})
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expected panic for conflicting pattern")
+		}
+		if !strings.Contains(fmt.Sprint(r), "conflicts with pattern") {
+			t.Errorf("unexpected panic value: %v", r)
+		}
+	}()
+	mux.HandleFunc("/a/{y}", func(ResponseWriter, *Request) {})
+}
+
+func TestPathValue(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		url     string
+		want    map[string]string
+	}{
+		{
+			"/{a}/is/{b}/{c...}",
+			"/now/is/the/time/for/all",
+			map[string]string{
+				"a": "now",
+				"b": "the",
+				"c": "time/for/all",
+				"d": "",
+			},
+		},
+		{
+			"/names/{name}/{other...}",
+			"/names/%2fjohn/address",
+			map[string]string{
+				"name":  "/john",
+				"other": "address",
+			},
+		},
+		{
+			"/names/{name}/{other...}",
+			"/names/john%2Fdoe/there/is%2F/more",
+			map[string]string{
+				"name":  "john/doe",
+				"other": "there/is//more",
+			},
+		},
+	} {
+		mux := NewServeMux()
+		mux.HandleFunc(test.pattern, func(w ResponseWriter, r *Request) {
+			for name, want := range test.want {
+				got := r.PathValue(name)
+				if got != want {
+					t.Errorf("%q, %q: got %q, want %q", test.pattern, name, got, want)
+				}
+			}
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+		res, err := Get(server.URL + test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+}
+
+func TestSetPathValue(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/a/{b}/c/{d...}", func(_ ResponseWriter, r *Request) {
+		kvs := map[string]string{
+			"b": "X",
+			"d": "Y",
+			"a": "Z",
+		}
+		for k, v := range kvs {
+			r.SetPathValue(k, v)
+		}
+		for k, w := range kvs {
+			if g := r.PathValue(k); g != w {
+				t.Errorf("got %q, want %q", g, w)
+			}
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	res, err := Get(server.URL + "/a/b/c/d/e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+}
+
 // TestQuerySemicolon tests the behavior of semicolons in queries. See Issue 25192.
 func TestQuerySemicolon(t *testing.T) {
 	t.Cleanup(func() { afterTest(t) })
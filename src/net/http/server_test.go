@@ -8,6 +8,8 @@ package http
 
 import (
 	"fmt"
+	"net/url"
+	"regexp"
 	"testing"
 	"time"
 )
@@ -90,9 +92,160 @@ func BenchmarkServerMatch(b *testing.B) {
 		"/products/", "/products/3/image.jpg"}
 	b.StartTimer()
 	for i := 0; i < b.N; i++ {
-		if h, p := mux.match(paths[i%len(paths)]); h != nil && p == "" {
+		r, err := NewRequest("GET", "http://example.com/"+paths[i%len(paths)], nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if h, p, _, _ := mux.findHandler(r); h != nil && p == "" {
 			b.Error("impossible")
 		}
 	}
 	b.StopTimer()
 }
+
+func TestFindHandler(t *testing.T) {
+	mux := NewServeMux()
+	for _, ph := range []struct {
+		pat string
+		h   Handler
+	}{
+		{"/", &handler{1}},
+		{"/foo/", &handler{2}},
+		{"/foo", &handler{3}},
+		{"/bar/", &handler{4}},
+		{"//foo", &handler{5}},
+	} {
+		mux.Handle(ph.pat, ph.h)
+	}
+
+	for _, test := range []struct {
+		method      string
+		path        string
+		wantHandler string
+	}{
+		{"GET", "/", "&http.handler{i:1}"},
+		{"GET", "//", `&http.redirectHandler{url:"/", code:301}`},
+		{"GET", "/foo/../bar/./..//baz", `&http.redirectHandler{url:"/baz", code:301}`},
+		{"GET", "/foo", "&http.handler{i:3}"},
+		{"GET", "/foo/x", "&http.handler{i:2}"},
+		{"GET", "/bar/x", "&http.handler{i:4}"},
+		{"GET", "/bar", `&http.redirectHandler{url:"/bar/", code:301}`},
+		{"CONNECT", "/", "&http.handler{i:1}"},
+		{"CONNECT", "//", "&http.handler{i:1}"},
+		{"CONNECT", "//foo", "&http.handler{i:5}"},
+		{"CONNECT", "/foo/../bar/./..//baz", "&http.handler{i:2}"},
+		{"CONNECT", "/foo", "&http.handler{i:3}"},
+		{"CONNECT", "/foo/x", "&http.handler{i:2}"},
+		{"CONNECT", "/bar/x", "&http.handler{i:4}"},
+		{"CONNECT", "/bar", `&http.redirectHandler{url:"/bar/", code:301}`},
+	} {
+		var r Request
+		r.Method = test.method
+		r.Host = "example.com"
+		r.URL = &url.URL{Path: test.path}
+		gotH, _, _, _ := mux.findHandler(&r)
+		got := fmt.Sprintf("%#v", gotH)
+		if got != test.wantHandler {
+			t.Errorf("%s %q: got %q, want %q", test.method, test.path, got, test.wantHandler)
+		}
+	}
+}
+
+func TestExactMatch(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"", "/a", false},
+		{"/", "/a", false},
+		{"/a", "/a", true},
+		{"/a/{x...}", "/a/b", false},
+		{"/a/{x}", "/a/b", true},
+		{"/a/b/", "/a/b/", true},
+		{"/a/b/{$}", "/a/b/", true},
+		{"/a/", "/a/b/", false},
+	} {
+		var n *routingNode
+		if test.pattern != "" {
+			pat := mustParsePattern(t, test.pattern)
+			n = &routingNode{pattern: pat}
+		}
+		got := exactMatch(n, test.path)
+		if got != test.want {
+			t.Errorf("%q, %s: got %t, want %t", test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+func TestRegisterErr(t *testing.T) {
+	mux := NewServeMux()
+	h := &handler{}
+	mux.Handle("/a", h)
+
+	for _, test := range []struct {
+		pattern    string
+		handler    Handler
+		wantRegexp string
+	}{
+		{"", h, "invalid pattern"},
+		{"/", nil, "nil handler"},
+		{"/", HandlerFunc(nil), "nil handler"},
+		{"/{x", h, `parsing "/\{x": at offset 1: bad wildcard segment`},
+		{"/a", h, `conflicts with pattern.* \(registered at .*/server_test.go:\d+`},
+	} {
+		t.Run(fmt.Sprintf("%s:%#v", test.pattern, test.handler), func(t *testing.T) {
+			err := mux.registerErr(test.pattern, test.handler)
+			if err == nil {
+				t.Fatal("got nil error")
+			}
+			re := regexp.MustCompile(test.wantRegexp)
+			if g := err.Error(); !re.MatchString(g) {
+				t.Errorf("\ngot %q\nwant string matching %q", g, test.wantRegexp)
+			}
+		})
+	}
+}
+
+func TestServeMux117(t *testing.T) {
+	defer func(old bool) { use117 = old }(use117)
+	use117 = true
+
+	mux := NewServeMux()
+	mux.Handle("/{x}", &handler{1})
+	mux.Handle("/a{x}", &handler{2}) // invalid with the new semantics
+	mux.Handle("/a/b", &handler{3})
+	mux.Handle("GET /c", &handler{4}) // host "GET ", not a method
+
+	for _, test := range []struct {
+		method      string
+		path        string
+		rawPath     string
+		wantHandler string
+	}{
+		{"GET", "/{x}", "", "&http.handler{i:1}"},
+		{"GET", "/y", "", "&http.handler{i:0}"},
+		{"GET", "/a{x}", "", "&http.handler{i:2}"},
+		{"GET", "/a/b", "/a%2Fb", "&http.handler{i:3}"},
+		{"GET", "/c", "", "&http.handler{i:0}"},
+		{"POST", "/c", "", "&http.handler{i:0}"},
+	} {
+		var r Request
+		r.Method = test.method
+		r.Host = "example.com"
+		r.URL = &url.URL{Path: test.path, RawPath: test.rawPath}
+		gotH, _ := mux.Handler(&r)
+		if _, ok := gotH.(HandlerFunc); ok {
+			// NotFoundHandler.
+			gotH = &handler{0}
+		}
+		got := fmt.Sprintf("%#v", gotH)
+		if got != test.wantHandler {
+			t.Errorf("%s %q: got %q, want %q", test.method, test.path, got, test.wantHandler)
+		}
+	}
+}
+
+type handler struct{ i int }
+
+func (handler) ServeHTTP(ResponseWriter, *Request) {}
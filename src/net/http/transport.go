@@ -12,6 +12,7 @@ package http
 import (
 	"bufio"
 	"compress/gzip"
+	"compress/zstd"
 	"container/list"
 	"context"
 	"crypto/tls"
@@ -180,13 +181,16 @@ type Transport struct {
 	DisableKeepAlives bool
 
 	// DisableCompression, if true, prevents the Transport from
-	// requesting compression with an "Accept-Encoding: gzip"
+	// requesting compression with an "Accept-Encoding: gzip, zstd"
 	// request header when the Request contains no existing
-	// Accept-Encoding value. If the Transport requests gzip on
-	// its own and gets a gzipped response, it's transparently
-	// decoded in the Response.Body. However, if the user
-	// explicitly requested gzip it is not automatically
+	// Accept-Encoding value. If the Transport requests compression
+	// on its own and gets a gzip or zstd compressed response, it's
+	// transparently decoded in the Response.Body. However, if the
+	// user explicitly requested compression it is not automatically
 	// uncompressed.
+	//
+	// Zstandard compression is requested over HTTP/1 only;
+	// HTTP/2 requests ask for gzip.
 	DisableCompression bool
 
 	// MaxIdleConns controls the maximum number of idle (keep-alive)
@@ -2187,8 +2191,15 @@ func (pc *persistConn) readLoop() {
 		}
 
 		resp.Body = body
-		if rc.addedGzip && ascii.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
-			resp.Body = &gzipReader{body: body}
+		if rc.addedCompression {
+			switch ce := resp.Header.Get("Content-Encoding"); {
+			case ascii.EqualFold(ce, "gzip"):
+				resp.Body = &gzipReader{body: body}
+			case ascii.EqualFold(ce, "zstd"):
+				resp.Body = &zstdReader{body: body}
+			}
+		}
+		if resp.Body != body {
 			resp.Header.Del("Content-Encoding")
 			resp.Header.Del("Content-Length")
 			resp.ContentLength = -1
@@ -2468,9 +2479,9 @@ type requestAndChan struct {
 	ch        chan responseAndError // unbuffered; always send in select on callerGone
 
 	// whether the Transport (as opposed to the user client code)
-	// added the Accept-Encoding gzip header. If the Transport
-	// set it, only then do we transparently decode the gzip.
-	addedGzip bool
+	// added the Accept-Encoding header. If the Transport set it,
+	// only then do we transparently decode the response body.
+	addedCompression bool
 
 	// Optional blocking chan for Expect: 100-continue (for send).
 	// If the request has an "Expect: 100-continue" header and
@@ -2542,15 +2553,15 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 
 	// Ask for a compressed version if the caller didn't set their
 	// own value for Accept-Encoding. We only attempt to
-	// uncompress the gzip stream if we were the layer that
+	// uncompress the response if we were the layer that
 	// requested it.
-	requestedGzip := false
+	requestedCompression := false
 	if !pc.t.DisableCompression &&
 		req.Header.Get("Accept-Encoding") == "" &&
 		req.Header.Get("Range") == "" &&
 		req.Method != "HEAD" {
-		// Request gzip only, not deflate. Deflate is ambiguous and
-		// not as universally supported anyway.
+		// Request gzip and zstd only, not deflate. Deflate is
+		// ambiguous and not as universally supported anyway.
 		// See: https://zlib.net/zlib_faq.html#faq39
 		//
 		// Note that we don't request this for HEAD requests,
@@ -2558,11 +2569,11 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 		//   https://trac.nginx.org/nginx/ticket/358
 		//   https://golang.org/issue/5522
 		//
-		// We don't request gzip if the request is for a range, since
-		// auto-decoding a portion of a gzipped document will just fail
-		// anyway. See https://golang.org/issue/8923
-		requestedGzip = true
-		req.extraHeaders().Set("Accept-Encoding", "gzip")
+		// We don't request compression if the request is for a range,
+		// since auto-decoding a portion of a compressed document will
+		// just fail anyway. See https://golang.org/issue/8923
+		requestedCompression = true
+		req.extraHeaders().Set("Accept-Encoding", "gzip, zstd")
 	}
 
 	var continueCh chan struct{}
@@ -2596,12 +2607,12 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 
 	resc := make(chan responseAndError)
 	pc.reqch <- requestAndChan{
-		req:        req.Request,
-		cancelKey:  req.cancelKey,
-		ch:         resc,
-		addedGzip:  requestedGzip,
-		continueCh: continueCh,
-		callerGone: gone,
+		req:              req.Request,
+		cancelKey:        req.cancelKey,
+		ch:               resc,
+		addedCompression: requestedCompression,
+		continueCh:       continueCh,
+		callerGone:       gone,
 	}
 
 	var respHeaderTimer <-chan time.Time
@@ -2836,6 +2847,34 @@ func (gz *gzipReader) Close() error {
 	return gz.body.Close()
 }
 
+// zstdReader wraps a response body so it can lazily
+// call zstd.NewReader on the first call to Read
+type zstdReader struct {
+	_    incomparable
+	body *bodyEOFSignal // underlying HTTP/1 response body framing
+	zr   *zstd.Reader   // lazily-initialized zstd reader
+}
+
+func (zr *zstdReader) Read(p []byte) (n int, err error) {
+	zr.body.mu.Lock()
+	if zr.body.closed {
+		err = errReadOnClosedResBody
+	}
+	zr.body.mu.Unlock()
+
+	if err != nil {
+		return 0, err
+	}
+	if zr.zr == nil {
+		zr.zr = zstd.NewReader(zr.body)
+	}
+	return zr.zr.Read(p)
+}
+
+func (zr *zstdReader) Close() error {
+	return zr.body.Close()
+}
+
 type tlsHandshakeTimeoutError struct{}
 
 func (tlsHandshakeTimeoutError) Timeout() bool   { return true }
@@ -963,7 +963,7 @@ var roundTripTests = []struct {
 	compressed   bool
 }{
 	// Requests with no accept-encoding header use transparent compression
-	{"", "gzip", false},
+	{"", "gzip, zstd", false},
 	// Requests with other accept-encoding should pass through unmodified
 	{"foo", "foo", false},
 	// Requests with accept-encoding == gzip should be passed through
@@ -981,7 +981,7 @@ func TestRoundTripGzip(t *testing.T) {
 			t.Errorf("in handler, test %v: Accept-Encoding = %q, want %q",
 				req.FormValue("testnum"), accept, expect)
 		}
-		if accept == "gzip" {
+		if accept == "gzip" || accept == "gzip, zstd" {
 			rw.Header().Set("Content-Encoding", "gzip")
 			gz := gzip.NewWriter(rw)
 			gz.Write([]byte(responseBody))
@@ -996,7 +996,7 @@ func TestRoundTripGzip(t *testing.T) {
 
 	for i, test := range roundTripTests {
 		// Test basic request (no accept-encoding)
-		req, _ := NewRequest("GET", fmt.Sprintf("%s/?testnum=%d&expect_accept=%s", ts.URL, i, test.expectAccept), nil)
+		req, _ := NewRequest("GET", fmt.Sprintf("%s/?testnum=%d&expect_accept=%s", ts.URL, i, url.QueryEscape(test.expectAccept)), nil)
 		if test.accept != "" {
 			req.Header.Set("Accept-Encoding", test.accept)
 		}
@@ -1047,7 +1047,7 @@ func TestTransportGzip(t *testing.T) {
 			}
 			return
 		}
-		if g, e := req.Header.Get("Accept-Encoding"), "gzip"; g != e {
+		if g, e := req.Header.Get("Accept-Encoding"), "gzip, zstd"; g != e {
 			t.Errorf("Accept-Encoding = %q, want %q", g, e)
 		}
 		rw.Header().Set("Content-Encoding", "gzip")
@@ -3466,7 +3466,7 @@ func TestRetryRequestsOnError(t *testing.T) {
 			req: func() *Request {
 				return newRequest("GET", "http://fake.golang", nil)
 			},
-			reqString: `GET / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nAccept-Encoding: gzip\r\n\r\n`,
+			reqString: `GET / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nAccept-Encoding: gzip, zstd\r\n\r\n`,
 		},
 		{
 			name: "IdempotentGetBodySomeWritten",
@@ -3478,7 +3478,7 @@ func TestRetryRequestsOnError(t *testing.T) {
 			req: func() *Request {
 				return newRequest("GET", "http://fake.golang", strings.NewReader("foo\n"))
 			},
-			reqString: `GET / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nContent-Length: 4\r\nAccept-Encoding: gzip\r\n\r\nfoo\n`,
+			reqString: `GET / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nContent-Length: 4\r\nAccept-Encoding: gzip, zstd\r\n\r\nfoo\n`,
 		},
 		{
 			name: "NothingWrittenNoBody",
@@ -3489,7 +3489,7 @@ func TestRetryRequestsOnError(t *testing.T) {
 			req: func() *Request {
 				return newRequest("DELETE", "http://fake.golang", nil)
 			},
-			reqString: `DELETE / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nAccept-Encoding: gzip\r\n\r\n`,
+			reqString: `DELETE / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nAccept-Encoding: gzip, zstd\r\n\r\n`,
 		},
 		{
 			name: "NothingWrittenGetBody",
@@ -3502,7 +3502,7 @@ func TestRetryRequestsOnError(t *testing.T) {
 			req: func() *Request {
 				return newRequest("POST", "http://fake.golang", strings.NewReader("foo\n"))
 			},
-			reqString: `POST / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nContent-Length: 4\r\nAccept-Encoding: gzip\r\n\r\nfoo\n`,
+			reqString: `POST / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nContent-Length: 4\r\nAccept-Encoding: gzip, zstd\r\n\r\nfoo\n`,
 		},
 	}
 
@@ -4103,7 +4103,7 @@ func TestTransportFlushesBodyChunks(t *testing.T) {
 	defer res.Body.Close()
 
 	want := []string{
-		"POST / HTTP/1.1\r\nHost: localhost:8080\r\nUser-Agent: x\r\nTransfer-Encoding: chunked\r\nAccept-Encoding: gzip\r\n\r\n",
+		"POST / HTTP/1.1\r\nHost: localhost:8080\r\nUser-Agent: x\r\nTransfer-Encoding: chunked\r\nAccept-Encoding: gzip, zstd\r\n\r\n",
 		"5\r\nnum0\n\r\n",
 		"5\r\nnum1\n\r\n",
 		"5\r\nnum2\n\r\n",
@@ -4611,7 +4611,7 @@ func testTransportEventTrace(t *testing.T, h2 bool, noHooks bool) {
 		wantOnce(fmt.Sprintf("WroteHeaderField: Host: [dns-is-faked.golang:%s]", port))
 		wantOnce(fmt.Sprintf("WroteHeaderField: Content-Length: [%d]", len(body)))
 		wantOnce("WroteHeaderField: X-Foo-Multiple-Vals: [bar baz]")
-		wantOnce("WroteHeaderField: Accept-Encoding: [gzip]")
+		wantOnce("WroteHeaderField: Accept-Encoding: [gzip, zstd]")
 	}
 	wantOnce("WroteHeaders")
 	wantOnce("Wait100Continue")
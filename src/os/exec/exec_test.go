@@ -11,6 +11,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"internal/poll"
 	"internal/testenv"
@@ -821,6 +822,16 @@ func TestHelperProcess(*testing.T) {
 	case "sleep":
 		time.Sleep(3 * time.Second)
 		os.Exit(0)
+	case "leakstdout":
+		// Start a grandchild that inherits our stdout and outlives us.
+		cmd := exec.Command(os.Args[0], "-test.run=TestHelperProcess", "--", "sleep")
+		cmd.Stdout = os.Stdout
+		if err := cmd.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "Child: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("started")
+		os.Exit(0)
 	case "pipehandle":
 		handle, _ := strconv.ParseUint(args[0], 16, 64)
 		pipe := os.NewFile(uintptr(handle), "")
@@ -1081,3 +1092,131 @@ func TestChildCriticalEnv(t *testing.T) {
 		t.Error("no SYSTEMROOT found")
 	}
 }
+
+func TestCancelWithoutContext(t *testing.T) {
+	c := helperCommand(t, "echo")
+	c.Cancel = func() error { return nil }
+	if err := c.Start(); err == nil {
+		c.Wait()
+		t.Fatal("Start succeeded for command with Cancel but no Context")
+	}
+}
+
+func TestCancelCustom(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := helperCommandContext(t, ctx, "cat")
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	c.Stdout = &out
+	canceled := false
+	c.Cancel = func() error {
+		// Ask the process to stop gracefully by closing its input.
+		canceled = true
+		return stdin.Close()
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(stdin, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	// The process exits successfully, but it was interrupted,
+	// so Wait reports the Context's error.
+	if err := c.Wait(); err != context.Canceled {
+		t.Errorf("Wait: %v, want %v", err, context.Canceled)
+	}
+	if !canceled {
+		t.Errorf("Cancel was not called")
+	}
+	if got := out.String(); got != "hello" {
+		t.Errorf("output = %q, want %q", got, "hello")
+	}
+}
+
+func TestCancelError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := helperCommandContext(t, ctx, "cat")
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCancel := errors.New("cancel failed")
+	c.Cancel = func() error {
+		stdin.Close()
+		return errCancel
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := c.Wait(); !errors.Is(err, errCancel) {
+		t.Errorf("Wait: %v, want error wrapping %v", err, errCancel)
+	}
+}
+
+func TestCancelProcessDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := helperCommandContext(t, ctx, "cat")
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Cancel = func() error {
+		stdin.Close()
+		return os.ErrProcessDone
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := c.Wait(); err != nil {
+		t.Errorf("Wait: %v, want nil", err)
+	}
+}
+
+func TestWaitDelayKill(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := helperCommandContext(t, ctx, "cat")
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	// Cancel does nothing, so the process must be killed
+	// when WaitDelay expires.
+	c.Cancel = func() error { return nil }
+	c.WaitDelay = 10 * time.Millisecond
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	err = c.Wait()
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Errorf("Wait: %v, want *exec.ExitError", err)
+	}
+}
+
+func TestWaitDelayLeakedPipe(t *testing.T) {
+	c := helperCommand(t, "leakstdout")
+	var out bytes.Buffer
+	c.Stdout = &out
+	c.WaitDelay = 100 * time.Millisecond
+
+	start := time.Now()
+	err := c.Run()
+	if err != exec.ErrWaitDelay {
+		t.Errorf("Run: %v, want %v", err, exec.ErrWaitDelay)
+	}
+	// The grandchild holds the pipe open for several seconds.
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("Run took %v, want about %v", d, c.WaitDelay)
+	}
+	if got := out.String(); got != "started\n" {
+		t.Errorf("output = %q, want %q", got, "started\n")
+	}
+}
@@ -60,19 +60,20 @@ func Getgroups() ([]int, error) {
 //
 // For portability, the status code should be in the range [0, 125].
 func Exit(code int) {
-	if code == 0 {
-		if testlog.PanicOnExit0() {
-			// We were told to panic on calls to os.Exit(0).
-			// This is used to fail tests that make an early
-			// unexpected call to os.Exit(0).
-			panic("unexpected call to os.Exit(0) during test")
-		}
-
-		// Give race detector a chance to fail the program.
-		// Racy programs do not have the right to finish successfully.
-		runtime_beforeExit()
+	if code == 0 && testlog.PanicOnExit0() {
+		// We were told to panic on calls to os.Exit(0).
+		// This is used to fail tests that make an early
+		// unexpected call to os.Exit(0).
+		panic("unexpected call to os.Exit(0) during test")
 	}
+
+	// Inform the runtime that os.Exit is being called. If -race is
+	// enabled, this will give race detector a chance to fail the
+	// program (racy programs do not have the right to finish
+	// successfully). If the program was built with "go build -cover",
+	// this is where the coverage counters are written out.
+	runtime_beforeExit(code)
 	syscall.Exit(code)
 }
 
-func runtime_beforeExit() // implemented in runtime
+func runtime_beforeExit(exitCode int) // implemented in runtime
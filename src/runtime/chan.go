@@ -49,6 +49,11 @@ type hchan struct {
 	// (in particular, do not ready a G), as this can deadlock
 	// with stack shrinking.
 	lock mutex
+
+	// synctest is the id of the synctest group that created the
+	// channel, or 0. It is an id rather than a *synctestGroup because
+	// hchan may be allocated without pointer bitmaps.
+	synctest uint64
 }
 
 type waitq struct {
@@ -110,6 +115,9 @@ func makechan(t *chantype, size int) *hchan {
 	c.elemsize = uint16(elem.size)
 	c.elemtype = elem
 	c.dataqsiz = uint(size)
+	if sg := getg().syncGroup; sg != nil {
+		c.synctest = sg.id
+	}
 	lockInit(&c.lock, lockRankHchan)
 
 	if debugChan {
@@ -161,7 +169,7 @@ func chansend(c *hchan, ep unsafe.Pointer, block bool, callerpc uintptr) bool {
 		if !block {
 			return false
 		}
-		gopark(nil, nil, waitReasonChanSendNilChan, traceEvGoStop, 2)
+		gopark(nil, nil, waitReasonChanSendNilChan, traceBlockForever, 2)
 		throw("unreachable")
 	}
 
@@ -169,6 +177,10 @@ func chansend(c *hchan, ep unsafe.Pointer, block bool, callerpc uintptr) bool {
 		print("chansend: chan=", c, "\n")
 	}
 
+	if c.synctest != 0 && !c.inSyncGroup(getg()) {
+		panic(plainError("send on synctest channel from outside bubble"))
+	}
+
 	if raceenabled {
 		racereadpc(c.raceaddr(), callerpc, abi.FuncPCABIInternal(chansend))
 	}
@@ -246,7 +258,7 @@ func chansend(c *hchan, ep unsafe.Pointer, block bool, callerpc uintptr) bool {
 	mysg.waitlink = nil
 	mysg.g = gp
 	mysg.isSelect = false
-	mysg.c = c
+	mysg.c = uintptr(unsafe.Pointer(c))
 	gp.waiting = mysg
 	gp.param = nil
 	c.sendq.enqueue(mysg)
@@ -255,7 +267,11 @@ func chansend(c *hchan, ep unsafe.Pointer, block bool, callerpc uintptr) bool {
 	// changes and when we set gp.activeStackChans is not safe for
 	// stack shrinking.
 	atomic.Store8(&gp.parkingOnChan, 1)
-	gopark(chanparkcommit, unsafe.Pointer(&c.lock), waitReasonChanSend, traceEvGoBlockSend, 2)
+	reason := waitReasonChanSend
+	if c.synctest != 0 {
+		reason = waitReasonSynctestChanSend
+	}
+	gopark(chanparkcommit, unsafe.Pointer(&c.lock), reason, traceBlockChanSend, 2)
 	// Ensure the value being sent is kept alive until the
 	// receiver copies it out. The sudog has a pointer to the
 	// stack object, but sudogs aren't considered as roots of the
@@ -273,7 +289,7 @@ func chansend(c *hchan, ep unsafe.Pointer, block bool, callerpc uintptr) bool {
 	if mysg.releasetime > 0 {
 		blockevent(mysg.releasetime-t0, 2)
 	}
-	mysg.c = nil
+	mysg.c = 0
 	releaseSudog(mysg)
 	if closed {
 		if c.closed == 0 {
@@ -357,6 +373,9 @@ func closechan(c *hchan) {
 	if c == nil {
 		panic(plainError("close of nil channel"))
 	}
+	if c.synctest != 0 && !c.inSyncGroup(getg()) {
+		panic(plainError("close of synctest channel from outside bubble"))
+	}
 
 	lock(&c.lock)
 	if c.closed != 0 {
@@ -464,10 +483,14 @@ func chanrecv(c *hchan, ep unsafe.Pointer, block bool) (selected, received bool)
 		if !block {
 			return
 		}
-		gopark(nil, nil, waitReasonChanReceiveNilChan, traceEvGoStop, 2)
+		gopark(nil, nil, waitReasonChanReceiveNilChan, traceBlockForever, 2)
 		throw("unreachable")
 	}
 
+	if c.synctest != 0 && !c.inSyncGroup(getg()) {
+		panic(plainError("receive on synctest channel from outside bubble"))
+	}
+
 	// Fast path: check for failed non-blocking operation without acquiring the lock.
 	if !block && empty(c) {
 		// After observing that the channel is not ready for receiving, we observe whether the
@@ -566,7 +589,7 @@ func chanrecv(c *hchan, ep unsafe.Pointer, block bool) (selected, received bool)
 	gp.waiting = mysg
 	mysg.g = gp
 	mysg.isSelect = false
-	mysg.c = c
+	mysg.c = uintptr(unsafe.Pointer(c))
 	gp.param = nil
 	c.recvq.enqueue(mysg)
 	// Signal to anyone trying to shrink our stack that we're about
@@ -574,7 +597,14 @@ func chanrecv(c *hchan, ep unsafe.Pointer, block bool) (selected, received bool)
 	// changes and when we set gp.activeStackChans is not safe for
 	// stack shrinking.
 	atomic.Store8(&gp.parkingOnChan, 1)
-	gopark(chanparkcommit, unsafe.Pointer(&c.lock), waitReasonChanReceive, traceEvGoBlockRecv, 2)
+	reason := waitReasonChanReceive
+	if c.synctest != 0 {
+		reason = waitReasonSynctestChanReceive
+	}
+	gopark(chanparkcommit, unsafe.Pointer(&c.lock), reason, traceBlockChanRecv, 2)
+	// Keep the channel alive while we are blocked on it.
+	// mysg.c is hidden from the garbage collector.
+	KeepAlive(c)
 
 	// someone woke us up
 	if mysg != gp.waiting {
@@ -587,7 +617,7 @@ func chanrecv(c *hchan, ep unsafe.Pointer, block bool) (selected, received bool)
 	}
 	success := mysg.success
 	gp.param = nil
-	mysg.c = nil
+	mysg.c = 0
 	releaseSudog(mysg)
 	return true, success
 }
@@ -748,6 +778,11 @@ func reflect_chanclose(c *hchan) {
 	closechan(c)
 }
 
+// hchan returns the channel sg is blocked on.
+func (sg *sudog) hchan() *hchan {
+	return (*hchan)(unsafe.Pointer(sg.c))
+}
+
 func (q *waitq) enqueue(sgp *sudog) {
 	sgp.next = nil
 	x := q.last
@@ -794,6 +829,11 @@ func (q *waitq) dequeue() *sudog {
 	}
 }
 
+// inSyncGroup reports whether c was created in the synctest group of gp.
+func (c *hchan) inSyncGroup(gp *g) bool {
+	return gp.syncGroup != nil && gp.syncGroup.id == c.synctest
+}
+
 func (c *hchan) raceaddr() unsafe.Pointer {
 	// Treat read-like and write-like operations on the channel to
 	// happen at this address. Avoid using the address of qcount
@@ -0,0 +1,157 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package coverage contains APIs for writing the coverage counters of
+// a program built with "go build -cover".
+//
+// When such a program exits, either by returning from main.main or by
+// calling os.Exit, it writes its coverage counters to a new file in
+// the directory named by the GOCOVERDIR environment variable. The
+// files are in the text format of "go test -coverprofile", and the
+// files of many runs can be combined with "go tool covdata".
+//
+// Programs that do not exit, such as servers, can use WriteCountersDir
+// to write out the counters while running, and ClearCounters to reset
+// them.
+package coverage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// fileCounters are the coverage counters of one source file,
+// as declared by cmd/cover.
+type fileCounters struct {
+	name    string // file name as recorded in profiles
+	mode    string // "set", "count", or "atomic"
+	counts  []uint32
+	pos     []uint32 // start line, end line, end col<<16 | start col per block
+	numStmt []uint16
+}
+
+var (
+	mu    sync.Mutex
+	files []fileCounters
+)
+
+// addFile registers the counters of a source file. It is called,
+// by way of a linkname directive, from the init functions that
+// cmd/cover adds to instrumented files. Since those packages may be
+// initialized before this one, addFile must not depend on package
+// initialization.
+func addFile(name, mode string, counts, pos []uint32, numStmt []uint16) {
+	mu.Lock()
+	defer mu.Unlock()
+	files = append(files, fileCounters{name, mode, counts, pos, numStmt})
+}
+
+var errNotCovered = errors.New("no coverage counters available (binary not built with -cover?)")
+
+// WriteCounters writes the current coverage counters of the program
+// to w, in the text format of "go test -coverprofile".
+// It returns an error if the program was not built with "go build -cover".
+func WriteCounters(w io.Writer) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if len(files) == 0 {
+		return errNotCovered
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "mode: %s\n", files[0].mode)
+	for _, f := range files {
+		for i := range f.counts {
+			fmt.Fprintf(bw, "%s:%d.%d,%d.%d %d %d\n", f.name,
+				f.pos[3*i+0], uint16(f.pos[3*i+2]),
+				f.pos[3*i+1], uint16(f.pos[3*i+2]>>16),
+				f.numStmt[i],
+				atomic.LoadUint32(&f.counts[i]))
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteCountersDir writes the current coverage counters of the program
+// to a new file in dir, as the program does on exit.
+// It returns an error if the program was not built with "go build -cover".
+func WriteCountersDir(dir string) error {
+	mu.Lock()
+	n := len(files)
+	mu.Unlock()
+	if n == 0 {
+		return errNotCovered
+	}
+
+	// Write to a temporary file and rename it, so that a reader of
+	// dir never sees a partial file.
+	name := fmt.Sprintf("covcounters.%d.%d", os.Getpid(), time.Now().UnixNano())
+	f, err := os.CreateTemp(dir, name+".tmp*")
+	if err != nil {
+		return err
+	}
+	err = WriteCounters(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(dir, name))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// ClearCounters resets all coverage counters of the program to zero.
+// It is only supported for programs built with -covermode=atomic,
+// as in other modes it would race with the instrumented code.
+func ClearCounters() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if len(files) == 0 {
+		return errNotCovered
+	}
+	if mode := files[0].mode; mode != "atomic" {
+		return fmt.Errorf("ClearCounters is not supported in -covermode=%s, only in -covermode=atomic", mode)
+	}
+	for _, f := range files {
+		for i := range f.counts {
+			atomic.StoreUint32(&f.counts[i], 0)
+		}
+	}
+	return nil
+}
+
+// emitOnExit writes the coverage counters to $GOCOVERDIR.
+func emitOnExit() {
+	mu.Lock()
+	n := len(files)
+	mu.Unlock()
+	if n == 0 {
+		return
+	}
+	dir := os.Getenv("GOCOVERDIR")
+	if dir == "" {
+		fmt.Fprintf(os.Stderr, "warning: GOCOVERDIR not set, no coverage data emitted\n")
+		return
+	}
+	if err := WriteCountersDir(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "error: coverage data emit failed: %v\n", err)
+	}
+}
+
+func init() {
+	runtime_addExitHook(emitOnExit, true)
+}
+
+// Implemented in runtime.
+func runtime_addExitHook(f func(), runOnNonZeroExit bool)
@@ -0,0 +1,63 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteCounters(t *testing.T) {
+	if err := WriteCounters(new(strings.Builder)); err == nil {
+		t.Fatal("WriteCounters succeeded without counters")
+	}
+	defer func() { files = nil }()
+
+	counts := []uint32{1, 0}
+	pos := []uint32{
+		3, 4, 11<<16 | 19,
+		7, 7, 10<<16 | 2,
+	}
+	addFile("example.com/p/p.go", "atomic", counts, pos, []uint16{1, 2})
+
+	want := "mode: atomic\n" +
+		"example.com/p/p.go:3.19,4.11 1 1\n" +
+		"example.com/p/p.go:7.2,7.10 2 0\n"
+	var sb strings.Builder
+	if err := WriteCounters(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if got := sb.String(); got != want {
+		t.Errorf("WriteCounters:\n%s\nwant:\n%s", got, want)
+	}
+
+	dir := t.TempDir()
+	if err := WriteCountersDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || !strings.HasPrefix(filepath.Base(names[0]), "covcounters.") {
+		t.Fatalf("WriteCountersDir wrote %q, want one covcounters file", names)
+	}
+	data, err := os.ReadFile(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("WriteCountersDir wrote:\n%s\nwant:\n%s", data, want)
+	}
+
+	if err := ClearCounters(); err != nil {
+		t.Fatal(err)
+	}
+	if counts[0] != 0 {
+		t.Errorf("ClearCounters left count %d, want 0", counts[0])
+	}
+}
@@ -6,6 +6,7 @@ package debug_test
 
 import (
 	"internal/testenv"
+	"math"
 	"os"
 	"runtime"
 	. "runtime/debug"
@@ -217,6 +218,44 @@ func TestSetGCPercent(t *testing.T) {
 	}
 }
 
+var setMemoryLimitBallast any
+
+func TestSetMemoryLimit(t *testing.T) {
+	// Test that the variable is being set and returned correctly.
+	old := SetMemoryLimit(123 << 20)
+	if old != math.MaxInt64 && os.Getenv("GOMEMLIMIT") == "" {
+		t.Errorf("SetMemoryLimit(123<<20) = %d, want %d", old, int64(math.MaxInt64))
+	}
+	if got := SetMemoryLimit(-1); got != 123<<20 {
+		t.Errorf("SetMemoryLimit(-1) = %d, want %d", got, 123<<20)
+	}
+	if got := SetMemoryLimit(old); got != 123<<20 {
+		t.Errorf("SetMemoryLimit(old) = %d, want %d", got, 123<<20)
+	}
+
+	// Test that the limit bounds the heap goal even with GOGC=off.
+	defer SetGCPercent(SetGCPercent(-1))
+	defer func() {
+		SetMemoryLimit(old)
+		setMemoryLimitBallast = nil
+	}()
+	runtime.GC()
+	// Create 50 MB of live heap as a baseline.
+	const baseline = 50 << 20
+	setMemoryLimitBallast = make([]byte, baseline)
+	runtime.GC()
+	const limit = 2 * baseline
+	SetMemoryLimit(limit)
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	if ms.NextGC >= limit {
+		t.Errorf("NextGC = %d MB, want < %d MB", ms.NextGC>>20, limit>>20)
+	}
+	if ms.NextGC < baseline {
+		t.Errorf("NextGC = %d MB, want >= %d MB", ms.NextGC>>20, baseline>>20)
+	}
+}
+
 func abs64(a int64) int64 {
 	if a < 0 {
 		return -a
@@ -33,6 +33,7 @@ var Fastlog2 = fastlog2
 
 var Atoi = atoi
 var Atoi32 = atoi32
+var ParseByteCount = parseByteCount
 
 var Nanotime = nanotime
 var NetpollBreak = netpollBreak
@@ -1255,7 +1256,7 @@ func NewGCController(gcPercent int) *GCController {
 	// space.
 	g := escape(new(GCController)).(*GCController)
 	g.gcControllerState.test = true // Mark it as a test copy.
-	g.init(int32(gcPercent))
+	g.init(int32(gcPercent), maxInt64)
 	return g
 }
 
@@ -694,8 +694,7 @@ func writeheapdump_m(fd uintptr, m *MemStats) {
 	assertWorldStopped()
 
 	_g_ := getg()
-	casgstatus(_g_.m.curg, _Grunning, _Gwaiting)
-	_g_.waitreason = waitReasonDumpingHeap
+	casGToWaiting(_g_.m.curg, _Grunning, waitReasonDumpingHeap)
 
 	// Update stats so we can dump them.
 	// As a side effect, flushes all the mcaches so the mspan.freelist
@@ -79,6 +79,13 @@ func initMetrics() {
 				out.scalar = in.sysStats.gcCyclesForced
 			},
 		},
+		"/gc/cycles/memory-limit:gc-cycles": {
+			deps: makeStatDepSet(sysStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.sysStats.gcCyclesLimit
+			},
+		},
 		"/gc/cycles/total:gc-cycles": {
 			deps: makeStatDepSet(sysStatsDep),
 			compute: func(in *statAggregate, out *metricValue) {
@@ -86,6 +93,12 @@ func initMetrics() {
 				out.scalar = in.sysStats.gcCyclesDone
 			},
 		},
+		"/gc/gomemlimit:bytes": {
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = uint64(gcController.memoryLimit.Load())
+			},
+		},
 		"/gc/heap/allocs-by-size:bytes": {
 			deps: makeStatDepSet(heapStatsDep),
 			compute: func(in *statAggregate, out *metricValue) {
@@ -423,6 +436,7 @@ type sysStatsAggregate struct {
 	heapGoal       uint64
 	gcCyclesDone   uint64
 	gcCyclesForced uint64
+	gcCyclesLimit  uint64
 }
 
 // compute populates the sysStatsAggregate with values from the runtime.
@@ -434,6 +448,7 @@ func (a *sysStatsAggregate) compute() {
 	a.heapGoal = atomic.Load64(&gcController.heapGoal)
 	a.gcCyclesDone = uint64(memstats.numgc)
 	a.gcCyclesForced = uint64(memstats.numforcedgc)
+	a.gcCyclesLimit = memstats.numlimitgc
 
 	systemstack(func() {
 		lock(&mheap_.lock)
@@ -63,12 +63,24 @@ var allDesc = []Description{
 		Kind:        KindUint64,
 		Cumulative:  true,
 	},
+	{
+		Name:        "/gc/cycles/memory-limit:gc-cycles",
+		Description: "Count of completed GC cycles whose heap goal was set by the memory limit instead of GOGC.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
 	{
 		Name:        "/gc/cycles/total:gc-cycles",
 		Description: "Count of all completed GC cycles.",
 		Kind:        KindUint64,
 		Cumulative:  true,
 	},
+	{
+		Name: "/gc/gomemlimit:bytes",
+		Description: "Go runtime memory limit configured by the user, otherwise math.MaxInt64. " +
+			"This value is set by the GOMEMLIMIT environment variable, and the runtime/debug.SetMemoryLimit function.",
+		Kind: KindUint64,
+	},
 	{
 		Name: "/gc/heap/allocs-by-size:bytes",
 		Description: "Distribution of heap allocations by approximate size. " +
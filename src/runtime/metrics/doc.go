@@ -57,9 +57,16 @@ Below is the full list of supported metrics, ordered lexicographically.
 	/gc/cycles/forced:gc-cycles
 		Count of completed GC cycles forced by the application.
 
+	/gc/cycles/memory-limit:gc-cycles
+		Count of completed GC cycles whose heap goal was set by the memory limit instead of GOGC.
+
 	/gc/cycles/total:gc-cycles
 		Count of all completed GC cycles.
 
+	/gc/gomemlimit:bytes
+		Go runtime memory limit configured by the user, otherwise math.MaxInt64.
+		This value is set by the GOMEMLIMIT environment variable, and the runtime/debug.SetMemoryLimit function.
+
 	/gc/heap/allocs-by-size:bytes
 		Distribution of heap allocations by approximate size.
 		Note that this does not include tiny objects as defined by /gc/heap/tiny/allocs:objects,
@@ -158,7 +158,7 @@ func gcinit() {
 
 	// Initialize GC pacer state.
 	// Use the environment variable GOGC for the initial gcPercent value.
-	gcController.init(readGOGC())
+	gcController.init(readGOGC(), readGOMEMLIMIT())
 
 	work.startSema = 1
 	work.markDoneSema = 1
@@ -363,6 +363,18 @@ var work struct {
 	// explicit user call.
 	userForced bool
 
+	// goroutineLeak is the state of goroutine leak detection.
+	goroutineLeak struct {
+		// pending is set to request that the next GC cycle
+		// detect leaked goroutines. It is accessed atomically.
+		pending uint32
+
+		// enabled is set while the current cycle is detecting
+		// leaked goroutines. While it is set, markroot does not
+		// scan the stacks of goroutines that may have leaked.
+		enabled bool
+	}
+
 	// totaltime is the CPU nanoseconds spent in GC since the
 	// program started if debug.gctrace > 0.
 	totaltime int64
@@ -499,7 +511,7 @@ func gcWaitOnMark(n uint32) {
 		// Wait until sweep termination, mark, and mark
 		// termination of cycle N complete.
 		work.sweepWaiters.list.push(getg())
-		goparkunlock(&work.sweepWaiters.lock, waitReasonWaitForGCCycle, traceEvGoBlock, 1)
+		goparkunlock(&work.sweepWaiters.lock, waitReasonWaitForGCCycle, traceBlockUntilGCEnds, 1)
 	}
 }
 
@@ -669,6 +681,7 @@ func gcStart(trigger gcTrigger) {
 	clearpools()
 
 	work.cycles++
+	work.goroutineLeak.enabled = atomic.Xchg(&work.goroutineLeak.pending, 0) != 0
 
 	// Assists and workers can start the moment we start
 	// the world.
@@ -801,7 +814,7 @@ top:
 		// Otherwise, our attempt to force all P's to a safepoint could
 		// result in a deadlock as we attempt to preempt a worker that's
 		// trying to preempt us (e.g. for a stack scan).
-		casgstatus(gp, _Grunning, _Gwaiting)
+		casGToWaiting(gp, _Grunning, waitReasonGCMarkTermination)
 		forEachP(func(_p_ *p) {
 			// Flush the write barrier buffer, since this may add
 			// work to the gcWork.
@@ -867,6 +880,13 @@ top:
 			}
 		}
 	})
+	if !restart && work.goroutineLeak.enabled {
+		// Marking is otherwise complete, but the stacks of
+		// goroutines that may have leaked have not been scanned.
+		systemstack(func() {
+			restart = gcScanBlockedStacks(&getg().m.p.ptr().gcw)
+		})
+	}
 	if restart {
 		getg().m.preemptoff = ""
 		systemstack(func() {
@@ -913,13 +933,16 @@ func gcMarkTermination(nextTriggerRatio float64) {
 	work.heap1 = gcController.heapLive
 	startTime := nanotime()
 
+	// Record whether this cycle's goal came from the memory limit
+	// before the goal is recomputed for the next cycle.
+	limitBound := gcController.limitBound
+
 	mp := acquirem()
 	mp.preemptoff = "gcing"
 	_g_ := getg()
 	_g_.m.traceback = 2
 	gp := _g_.m.curg
-	casgstatus(gp, _Grunning, _Gwaiting)
-	gp.waitreason = waitReasonGarbageCollection
+	casGToWaiting(gp, _Grunning, waitReasonGarbageCollection)
 
 	// Run gc on the g0 stack. We do this so that the g stack
 	// we're currently running on will no longer change. Cuts
@@ -1013,6 +1036,9 @@ func gcMarkTermination(nextTriggerRatio float64) {
 	if work.userForced {
 		memstats.numforcedgc++
 	}
+	if limitBound {
+		memstats.numlimitgc++
+	}
 
 	// Bump GC cycle count and wake goroutines waiting on sweep.
 	lock(&work.sweepWaiters.lock)
@@ -1231,7 +1257,7 @@ func gcBgMarkWorker() {
 			// Note that at this point, the G may immediately be
 			// rescheduled and may be running.
 			return true
-		}, unsafe.Pointer(node), waitReasonGCWorkerIdle, traceEvGoBlock, 0)
+		}, unsafe.Pointer(node), waitReasonGCWorkerIdle, traceBlockSystemGoroutine, 0)
 
 		// Preemption must not occur here, or another G might see
 		// p.gcMarkWorkerMode.
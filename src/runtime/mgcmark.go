@@ -146,6 +146,138 @@ func gcMarkRootCheck() {
 	})
 }
 
+// maybeLeaked reports whether gp is a user goroutine blocked on a
+// channel or synchronization primitive. If no goroutine that can run
+// is able to reach the objects gp is blocked on, gp has leaked: it
+// can never run again.
+func (gp *g) maybeLeaked() bool {
+	if readgstatus(gp)&^_Gscan != _Gwaiting || isSystemGoroutine(gp, false) {
+		return false
+	}
+	switch gp.waitreason {
+	case waitReasonChanReceiveNilChan, waitReasonChanSendNilChan,
+		waitReasonSelectNoCases, waitReasonChanReceive, waitReasonChanSend,
+		waitReasonSelect, waitReasonSemacquire, waitReasonSyncCondWait,
+		waitReasonSynctestChanReceive, waitReasonSynctestChanSend,
+		waitReasonSynctestSelect:
+		return true
+	}
+	return false
+}
+
+// blockedOnReachable reports whether any of the objects that gp,
+// which must be blocked as described by maybeLeaked, is waiting on
+// has been marked or is outside both the heap and gp's stack.
+//
+// The world must be stopped.
+func (gp *g) blockedOnReachable() bool {
+	reachable := func(p uintptr) bool {
+		if gp.stack.lo <= p && p < gp.stack.hi {
+			// Only gp can refer to its own stack.
+			return false
+		}
+		s := spanOfHeap(p)
+		if s == nil {
+			return true
+		}
+		objIndex := s.objIndex(p)
+		if s.markBitsForIndex(objIndex).isMarked() {
+			return true
+		}
+		// An object with a finalizer is not marked, but its
+		// finalizer may still use it.
+		offset := objIndex * s.elemsize
+		for sp := s.specials; sp != nil; sp = sp.next {
+			if sp.kind == _KindSpecialFinalizer && uintptr(sp.offset) == offset {
+				return true
+			}
+		}
+		return false
+	}
+	switch gp.waitreason {
+	case waitReasonSemacquire, waitReasonSyncCondWait:
+		s := (*sudog)(gp.param)
+		return s == nil || s.c == 0 || reachable(s.c)
+	}
+	for sg := gp.waiting; sg != nil; sg = sg.waitlink {
+		if reachable(sg.c) {
+			return true
+		}
+	}
+	return false
+}
+
+// gcScanBlockedStacks finishes goroutine leak detection for the
+// current cycle. markroot did not scan the stacks of goroutines
+// blocked in a way that may have leaked. gcScanBlockedStacks scans
+// the stacks of those that can run again: those that are no longer
+// blocked, or whose channel or synchronization object has been
+// marked. It repeats this until no more such goroutines are found
+// or scanning found more mark work, which it adds to gcw. Once there
+// are none left, the goroutines that are still unscanned have leaked.
+// It marks them as leaked and scans their stacks too, since their
+// memory must stay valid.
+//
+// It reports whether it added work to gcw, in which case marking
+// must resume.
+//
+// The world must be stopped.
+//
+//go:systemstack
+func gcScanBlockedStacks(gcw *gcWork) bool {
+	assertWorldStopped()
+
+	// Put the user G calling gcMarkDone in _Gwaiting, so that we
+	// may suspend goroutines and scan the user G's own stack.
+	userG := getg().m.curg
+	casGToWaiting(userG, _Grunning, waitReasonGCMarkTermination)
+	more := scanBlockedStacks(gcw)
+	casgstatus(userG, _Gwaiting, _Grunning)
+	return more
+}
+
+func scanBlockedStacks(gcw *gcWork) bool {
+	for {
+		found := false
+		for _, gp := range work.stackRoots[:work.nStackRoots] {
+			if gp.gcscandone || gp.maybeLeaked() && !gp.blockedOnReachable() {
+				continue
+			}
+			scanStackSTW(gp, gcw)
+			found = true
+		}
+		if !found {
+			break
+		}
+		if !gcw.empty() {
+			return true
+		}
+		// Scanning only marked objects without pointers,
+		// which may still have made goroutines able to run.
+	}
+
+	for _, gp := range work.stackRoots[:work.nStackRoots] {
+		if !gp.gcscandone {
+			gp.leaked = true
+			scanStackSTW(gp, gcw)
+		}
+	}
+	work.goroutineLeak.enabled = false
+	return !gcw.empty()
+}
+
+// scanStackSTW scans gp's stack while the world is stopped.
+func scanStackSTW(gp *g, gcw *gcWork) {
+	stopped := suspendG(gp)
+	if stopped.dead {
+		gp.gcscandone = true
+		return
+	}
+	gcController.stackScanWork.Add(scanstack(gp, gcw))
+	gp.gcscandone = true
+	resumeG(stopped)
+}
+
 // ptrmask for an allocation containing a single pointer.
 var oneptrmask = [...]uint8{1}
 
@@ -209,6 +341,12 @@ func markroot(gcw *gcWork, i uint32, flushBgCredit bool) int64 {
 			gp.waitsince = work.tstart
 		}
 
+		if work.goroutineLeak.enabled && gp.maybeLeaked() {
+			// Leave gp's stack for gcScanBlockedStacks, which
+			// scans it once gp is known to be able to run again.
+			break
+		}
+
 		// scanstack must be done on the system stack in case
 		// we're trying to scan our own stack.
 		systemstack(func() {
@@ -219,8 +357,7 @@ func markroot(gcw *gcWork, i uint32, flushBgCredit bool) int64 {
 			userG := getg().m.curg
 			selfScan := gp == userG && readgstatus(userG) == _Grunning
 			if selfScan {
-				casgstatus(userG, _Grunning, _Gwaiting)
-				userG.waitreason = waitReasonGarbageCollectionScan
+				casGToWaiting(userG, _Grunning, waitReasonGarbageCollectionScan)
 			}
 
 			// TODO: suspendG blocks (and spins) until gp
@@ -549,8 +686,7 @@ func gcAssistAlloc1(gp *g, scanWork int64) {
 	}
 
 	// gcDrainN requires the caller to be preemptible.
-	casgstatus(gp, _Grunning, _Gwaiting)
-	gp.waitreason = waitReasonGCAssistMarking
+	casGToWaiting(gp, _Grunning, waitReasonGCAssistMarking)
 
 	// drain own cached work first in the hopes that it
 	// will be more cache friendly.
@@ -634,7 +770,7 @@ func gcParkAssist() bool {
 		return false
 	}
 	// Park.
-	goparkunlock(&work.assistQueue.lock, waitReasonGCAssistWait, traceEvGoBlockGC, 2)
+	goparkunlock(&work.assistQueue.lock, waitReasonGCAssistWait, traceBlockGCMarkAssist, 2)
 	return true
 }
 
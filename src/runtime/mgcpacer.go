@@ -52,6 +52,13 @@ const (
 	// assist by pre-paying for this many bytes of future allocations.
 	gcOverAssistWork = 64 << 10
 
+	// memoryLimitHeapGoalHeadroom is the amount of headroom the pacer gives to
+	// the heap goal when operating in the memory-limited regime, as a
+	// percentage of the goal. That is, it intentionally tries to place the
+	// heap goal this far below the goal derived from the memory limit, to
+	// account for fragmentation and other memory the pacer can't see.
+	memoryLimitHeapGoalHeadroom = 3
+
 	// defaultHeapMinimum is the value of heapMinimum for GOGC==100.
 	defaultHeapMinimum = (goexperiment.HeapMinimum512KiBInt)*(512<<10) +
 		(1-goexperiment.HeapMinimum512KiBInt)*(4<<20)
@@ -90,6 +97,16 @@ type gcControllerState struct {
 
 	_ uint32 // padding so following 64-bit values are 8-byte aligned
 
+	// memoryLimit is the soft memory limit in bytes.
+	//
+	// Initialized from GOMEMLIMIT. GOMEMLIMIT=off is equivalent to maxInt64
+	// which means no soft memory limit in practice.
+	//
+	// This is an int64 instead of a uint64 to more easily maintain parity with
+	// the SetMemoryLimit API, which sets a maximum at maxInt64. This value
+	// should never be negative.
+	memoryLimit atomic.Int64
+
 	// heapMinimum is the minimum heap size at which to trigger GC.
 	// For small heaps, this overrides the usual GOGC*live set rule.
 	//
@@ -328,13 +345,19 @@ type gcControllerState struct {
 	// If this is zero, no fractional workers are needed.
 	fractionalUtilizationGoal float64
 
+	// limitBound indicates whether the heap goal for the current cycle was
+	// determined by the memory limit rather than by GOGC.
+	//
+	// Protected by mheap_.lock or a STW.
+	limitBound bool
+
 	// test indicates that this is a test-only copy of gcControllerState.
 	test bool
 
 	_ cpu.CacheLinePad
 }
 
-func (c *gcControllerState) init(gcPercent int32) {
+func (c *gcControllerState) init(gcPercent int32, memoryLimit int64) {
 	c.heapMinimum = defaultHeapMinimum
 
 	if goexperiment.PacerRedesign {
@@ -368,6 +391,8 @@ func (c *gcControllerState) init(gcPercent int32) {
 		c.heapMarked = uint64(float64(c.heapMinimum) / (1 + c.triggerRatio))
 	}
 
+	c.memoryLimit.Store(memoryLimit)
+
 	// This will also compute and set the GC trigger and goal.
 	c.setGCPercent(gcPercent)
 }
@@ -970,8 +995,22 @@ func (c *gcControllerState) commit(triggerRatio float64) {
 		goal = c.heapMarked + (c.heapMarked+atomic.Load64(&c.stackScan)+atomic.Load64(&c.globalsScan))*uint64(gcPercent)/100
 	}
 
-	// Don't trigger below the minimum heap size.
+	// If the memory limit demands a lower goal, use that instead.
+	c.limitBound = false
+	if c.memoryLimit.Load() != maxInt64 {
+		if limitGoal := c.memoryLimitHeapGoal(); limitGoal < goal {
+			goal = limitGoal
+			c.limitBound = true
+		}
+	}
+
+	// Don't trigger below the minimum heap size, unless the memory
+	// limit is what's bounding the goal: then the heap minimum would
+	// only push us past the limit.
 	minTrigger := c.heapMinimum
+	if c.limitBound {
+		minTrigger = c.heapMarked
+	}
 	if !isSweepDone() {
 		// Concurrent sweep happens in the heap growth
 		// from gcController.heapLive to trigger, so ensure
@@ -1079,6 +1118,8 @@ func (c *gcControllerState) commit(triggerRatio float64) {
 // This depends on gcPercent, gcController.heapMarked, and
 // gcController.heapLive. These must be up to date.
 //
+// The memory limit is not taken into account here.
+//
 // For !goexperiment.PacerRedesign.
 func (c *gcControllerState) oldCommit(triggerRatio float64) {
 	gcPercent := c.gcPercent.Load()
@@ -1213,7 +1254,10 @@ func (c *gcControllerState) setGCPercent(in int32) int32 {
 	if in < 0 {
 		in = -1
 	}
-	c.heapMinimum = defaultHeapMinimum * uint64(in) / 100
+	c.heapMinimum = defaultHeapMinimum
+	if in >= 0 {
+		c.heapMinimum = defaultHeapMinimum * uint64(in) / 100
+	}
 	c.gcPercent.Store(in)
 	// Update pacing in response to gcPercent change.
 	c.commit(c.triggerRatio)
@@ -1241,6 +1285,65 @@ func setGCPercent(in int32) (out int32) {
 	return out
 }
 
+// memoryLimitHeapGoal returns the heap goal implied by the memory limit:
+// the limit less all memory the runtime has mapped for purposes other
+// than the heap, less some headroom. It never returns less than heapMarked,
+// since a goal below the live heap is unattainable.
+//
+// mheap_.lock must be held or the world must be stopped.
+func (c *gcControllerState) memoryLimitHeapGoal() uint64 {
+	limit := uint64(c.memoryLimit.Load())
+	nonHeap := mappedReady() - heapRetained()
+	if nonHeap >= limit {
+		// We're already over the limit without the heap. The best
+		// we can do is to collect as often as possible.
+		return c.heapMarked
+	}
+	goal := limit - nonHeap
+	goal -= goal / 100 * memoryLimitHeapGoalHeadroom
+	if goal < c.heapMarked {
+		goal = c.heapMarked
+	}
+	return goal
+}
+
+// setMemoryLimit updates memoryLimit and all related pacer state.
+// Returns the old value of memoryLimit. A negative input leaves the
+// limit unchanged.
+//
+// Calls gcControllerState.commit.
+//
+// The world must be stopped, or mheap_.lock must be held.
+func (c *gcControllerState) setMemoryLimit(in int64) int64 {
+	if !c.test {
+		assertWorldStoppedOrLockHeld(&mheap_.lock)
+	}
+
+	out := c.memoryLimit.Load()
+	if in >= 0 {
+		c.memoryLimit.Store(in)
+	}
+	// Update pacing in response to memoryLimit change.
+	c.commit(c.triggerRatio)
+
+	return out
+}
+
+//go:linkname setMemoryLimit runtime/debug.setMemoryLimit
+func setMemoryLimit(in int64) (out int64) {
+	// Run on the system stack since we grab the heap lock.
+	systemstack(func() {
+		lock(&mheap_.lock)
+		out = gcController.setMemoryLimit(in)
+		if in >= 0 {
+			gcPaceSweeper(gcController.trigger)
+			gcPaceScavenger(gcController.heapGoal, gcController.lastHeapGoal)
+		}
+		unlock(&mheap_.lock)
+	})
+	return out
+}
+
 func readGOGC() int32 {
 	p := gogetenv("GOGC")
 	if p == "off" {
@@ -1252,6 +1355,21 @@ func readGOGC() int32 {
 	return 100
 }
 
+// readGOMEMLIMIT reads the memory limit from the environment.
+// It throws if GOMEMLIMIT is malformed.
+func readGOMEMLIMIT() int64 {
+	p := gogetenv("GOMEMLIMIT")
+	if p == "" || p == "off" {
+		return maxInt64
+	}
+	n, ok := parseByteCount(p)
+	if !ok {
+		print("GOMEMLIMIT=", p, "\n")
+		throw("malformed GOMEMLIMIT; see `go doc runtime/debug.SetMemoryLimit`")
+	}
+	return n
+}
+
 type piController struct {
 	kp float64 // Proportional constant.
 	ti float64 // Integral time constant.
@@ -41,6 +41,12 @@
 // that there's more unscavenged memory to allocate out of, since each allocation
 // out of scavenged memory incurs a potentially expensive page fault.
 //
+// If a memory limit is set (see debug.SetMemoryLimit), the goal is further
+// capped so that the heap's retained memory plus all of the runtime's other
+// mapped memory stays reduceExtraPercent below the limit. While above that
+// cap, the background scavenger is allowed to use up to scavengeLimitPercent
+// of the mutator's time instead of scavengePercent.
+//
 // The goal is updated after each GC and the scavenger's pacing parameters
 // (which live in mheap_) are updated to match. The pacing parameters work much
 // like the background sweeping parameters. The parameters define a line whose
@@ -69,6 +75,11 @@ const (
 	// to spend on scavenging in percent.
 	scavengePercent = 1 // 1%
 
+	// scavengeLimitPercent is the portion of mutator time in percent the
+	// background scavenger is willing to spend when retained memory exceeds
+	// the goal imposed by the memory limit.
+	scavengeLimitPercent = 10 // 10%
+
 	// retainExtraPercent represents the amount of memory over the heap goal
 	// that the scavenger should keep as a buffer space for the allocator.
 	//
@@ -78,6 +89,17 @@ const (
 	// the ever-changing layout of the heap.
 	retainExtraPercent = 10
 
+	// reduceExtraPercent represents the amount of memory under the memory
+	// limit that the scavenger should target. For example, 5 means we
+	// target 95% of the limit.
+	//
+	// The purpose of shooting lower than the limit is to ensure that, once
+	// close to the limit, the scavenger is working hard to maintain it. If
+	// we have a memory limit set but are far away from it, there's no harm
+	// in leaving up to 100-reduceExtraPercent live, and it's more efficient
+	// anyway, for the same reasons that retainExtraPercent exists.
+	reduceExtraPercent = 5
+
 	// maxPagesPerPhysPage is the maximum number of supported runtime pages per
 	// physical page, based on maxPhysPageSize.
 	maxPagesPerPhysPage = maxPhysPageSize / pageSize
@@ -104,6 +126,18 @@ func heapRetained() uint64 {
 	return memstats.heap_sys.load() - atomic.Load64(&memstats.heap_released)
 }
 
+// mappedReady returns an estimate of the total memory the runtime has
+// mapped and not returned to the OS: the retained heap, manually-managed
+// spans such as stacks, and all other runtime-internal memory.
+//
+// This is the quantity the memory limit applies to.
+func mappedReady() uint64 {
+	return heapRetained() + atomic.Load64(&memstats.manual_inuse) +
+		memstats.stacks_sys.load() + memstats.mspan_sys.load() +
+		memstats.mcache_sys.load() + memstats.buckhash_sys.load() +
+		memstats.gcMiscSys.load() + memstats.other_sys.load()
+}
+
 // gcPaceScavenger updates the scavenger's pacing, particularly
 // its rate and RSS goal. For this, it requires the current heapGoal,
 // and the heapGoal for the previous GC cycle.
@@ -120,25 +154,43 @@ func heapRetained() uint64 {
 func gcPaceScavenger(heapGoal, lastHeapGoal uint64) {
 	assertWorldStoppedOrLockHeld(&mheap_.lock)
 
-	// If we're called before the first GC completed, disable scavenging.
-	// We never scavenge before the 2nd GC cycle anyway (we don't have enough
-	// information about the heap yet) so this is fine, and avoids a fault
-	// or garbage data later.
-	if lastHeapGoal == 0 {
-		atomic.Store64(&mheap_.scavengeGoal, ^uint64(0))
-		return
+	// Compute the goal imposed by the memory limit, if any: the heap may
+	// retain whatever is left of (100-reduceExtraPercent)% of the limit
+	// after accounting for all non-heap memory.
+	limitGoal := ^uint64(0)
+	if limit := gcController.memoryLimit.Load(); limit != maxInt64 {
+		target := uint64(float64(limit) * (100.0 - reduceExtraPercent) / 100.0)
+		nonHeap := mappedReady() - heapRetained()
+		if nonHeap < target {
+			limitGoal = target - nonHeap
+		} else {
+			limitGoal = 0
+		}
+		limitGoal = alignUp64(limitGoal, uint64(physPageSize))
+	}
+	atomic.Store64(&mheap_.scavengeLimitGoal, limitGoal)
+
+	// If we're called before the first GC completed, only the memory limit
+	// applies. We never scavenge for the heap goal before the 2nd GC cycle
+	// anyway (we don't have enough information about the heap yet) so this
+	// is fine, and avoids a fault or garbage data later.
+	retainedGoal := ^uint64(0)
+	if lastHeapGoal != 0 {
+		// Compute our scavenging goal.
+		goalRatio := float64(heapGoal) / float64(lastHeapGoal)
+		retainedGoal = uint64(float64(memstats.last_heap_inuse) * goalRatio)
+		// Add retainExtraPercent overhead to retainedGoal. This calculation
+		// looks strange but the purpose is to arrive at an integer division
+		// (e.g. if retainExtraPercent = 12.5, then we get a divisor of 8)
+		// that also avoids the overflow from a multiplication.
+		retainedGoal += retainedGoal / (1.0 / (retainExtraPercent / 100.0))
+		// Align it to a physical page boundary to make the following calculations
+		// a bit more exact.
+		retainedGoal = alignUp64(retainedGoal, uint64(physPageSize))
+	}
+	if limitGoal < retainedGoal {
+		retainedGoal = limitGoal
 	}
-	// Compute our scavenging goal.
-	goalRatio := float64(heapGoal) / float64(lastHeapGoal)
-	retainedGoal := uint64(float64(memstats.last_heap_inuse) * goalRatio)
-	// Add retainExtraPercent overhead to retainedGoal. This calculation
-	// looks strange but the purpose is to arrive at an integer division
-	// (e.g. if retainExtraPercent = 12.5, then we get a divisor of 8)
-	// that also avoids the overflow from a multiplication.
-	retainedGoal += retainedGoal / (1.0 / (retainExtraPercent / 100.0))
-	// Align it to a physical page boundary to make the following calculations
-	// a bit more exact.
-	retainedGoal = (retainedGoal + uint64(physPageSize) - 1) &^ (uint64(physPageSize) - 1)
 
 	// Represents where we are now in the heap's contribution to RSS in bytes.
 	//
@@ -163,6 +215,16 @@ func gcPaceScavenger(heapGoal, lastHeapGoal uint64) {
 	atomic.Store64(&mheap_.scavengeGoal, retainedGoal)
 }
 
+// alignUp64 rounds n up to a multiple of a, which must be a power of 2.
+// Unlike alignUp, it does not overflow: values within a of the maximum
+// uint64 are returned unchanged.
+func alignUp64(n, a uint64) uint64 {
+	if n > ^uint64(0)-a {
+		return n
+	}
+	return (n + a - 1) &^ (a - 1)
+}
+
 // Sleep/wait state of the background scavenger.
 var scavenge struct {
 	lock       mutex
@@ -200,7 +262,7 @@ func wakeScavenger() {
 		// In the case that we're racing with there's the low chance that
 		// we experience a spurious wake-up of the scavenger, but that's
 		// totally safe.
-		stopTimer(scavenge.timer)
+		deltimer(scavenge.timer)
 
 		// Unpark the goroutine and tell it that there may have been a pacing
 		// change. Note that we skip the scheduler's runnext slot because we
@@ -244,7 +306,7 @@ func scavengeSleep(ns int64) int64 {
 
 	// Mark ourself as asleep and go to sleep.
 	scavenge.parked = true
-	goparkunlock(&scavenge.lock, waitReasonSleep, traceEvGoSleep, 2)
+	goparkunlock(&scavenge.lock, waitReasonSleep, traceBlockSleep, 2)
 
 	// Return how long we actually slept for.
 	return nanotime() - start
@@ -268,12 +330,16 @@ func bgscavenge(c chan int) {
 	}
 
 	c <- 1
-	goparkunlock(&scavenge.lock, waitReasonGCScavengeWait, traceEvGoBlock, 1)
+	goparkunlock(&scavenge.lock, waitReasonGCScavengeWait, traceBlockSystemGoroutine, 1)
 
 	// idealFraction is the ideal % of overall application CPU time that we
 	// spend scavenging.
 	idealFraction := float64(scavengePercent) / 100.0
 
+	// idealLimitFraction is the % of overall application CPU time that we
+	// spend scavenging while over the memory limit's goal.
+	idealLimitFraction := float64(scavengeLimitPercent) / 100.0
+
 	// Input: fraction of CPU time used.
 	// Setpoint: idealFraction.
 	// Output: ratio of critical time to sleep time (determines sleep time).
@@ -354,7 +420,7 @@ func bgscavenge(c chan int) {
 		if released == 0 {
 			lock(&scavenge.lock)
 			scavenge.parked = true
-			goparkunlock(&scavenge.lock, waitReasonGCScavengeWait, traceEvGoBlock, 1)
+			goparkunlock(&scavenge.lock, waitReasonGCScavengeWait, traceBlockSystemGoroutine, 1)
 			continue
 		}
 
@@ -394,8 +460,14 @@ func bgscavenge(c chan int) {
 		// that small inaccuracy is in the noise.
 		cpuFraction := float64(crit) / ((float64(slept) + crit) * float64(gomaxprocs))
 
+		// Work harder while we're over the goal imposed by the memory limit.
+		fraction := idealFraction
+		if heapRetained() > atomic.Load64(&mheap_.scavengeLimitGoal) {
+			fraction = idealLimitFraction
+		}
+
 		// Update the critSleepRatio, adjusting until we reach our ideal fraction.
-		critSleepRatio = critSleepController.next(cpuFraction, idealFraction, float64(slept)+crit)
+		critSleepRatio = critSleepController.next(cpuFraction, fraction, float64(slept)+crit)
 	}
 }
 
@@ -115,6 +115,13 @@ type mheap struct {
 	// Accessed atomically.
 	scavengeGoal uint64
 
+	// scavengeLimitGoal is the part of scavengeGoal imposed by the memory
+	// limit, or ^uint64(0) if there is no limit. While retained heap memory
+	// exceeds it, the background scavenger is allowed more CPU time.
+	//
+	// Accessed atomically.
+	scavengeLimitGoal uint64
+
 	// Page reclaimer state
 
 	// reclaimIndex is the page index in allArenas of next page to
@@ -1202,26 +1209,44 @@ func (h *mheap) allocSpan(npages uintptr, typ spanAllocType, spanclass spanClass
 
 	unlock(&h.lock)
 
+HaveSpan:
+	// Decide if we need to scavenge in response to what we just allocated.
+	// We track the maximum amount of memory to scavenge of all the
+	// alternatives below, since the maximum satisfies all of them.
+	//
+	// The scavenging algorithm requires the heap lock to be dropped so it
+	// can acquire it only sparingly. This is a potentially expensive operation
+	// so it frees up other goroutines to allocate in the meanwhile.
+	var bytesToScavenge uintptr
+	if limit := gcController.memoryLimit.Load(); limit != maxInt64 && scav != 0 {
+		// We're about to page in scavenged memory. If that puts us
+		// over the memory limit, release an equivalent amount of
+		// other free memory to stay under it.
+		if inuse := mappedReady() + uint64(scav); inuse > uint64(limit) {
+			bytesToScavenge = uintptr(inuse - uint64(limit))
+		}
+	}
 	if growth > 0 {
 		// We just caused a heap growth, so scavenge down what will soon be used.
 		// By scavenging inline we deal with the failure to allocate out of
 		// memory fragments by scavenging the memory fragments that are least
-		// likely to be re-used.
+		// likely to be re-used. Other goroutines can make use of the growth
+		// we just created in the meanwhile.
 		scavengeGoal := atomic.Load64(&h.scavengeGoal)
 		if retained := heapRetained(); retained+uint64(growth) > scavengeGoal {
-			// The scavenging algorithm requires the heap lock to be dropped so it
-			// can acquire it only sparingly. This is a potentially expensive operation
-			// so it frees up other goroutines to allocate in the meanwhile. In fact,
-			// they can make use of the growth we just created.
 			todo := growth
 			if overage := uintptr(retained + uint64(growth) - scavengeGoal); todo > overage {
 				todo = overage
 			}
-			h.pages.scavenge(todo)
+			if todo > bytesToScavenge {
+				bytesToScavenge = todo
+			}
 		}
 	}
+	if bytesToScavenge > 0 {
+		h.pages.scavenge(bytesToScavenge)
+	}
 
-HaveSpan:
 	// At this point, both s != nil and base != 0, and the heap
 	// lock is no longer held. Initialize the span.
 	s.init(base, npages)
@@ -1286,6 +1311,7 @@ HaveSpan:
 	if typ.manual() {
 		// Manually managed memory doesn't count toward heap_sys.
 		memstats.heap_sys.add(-int64(nbytes))
+		atomic.Xadd64(&memstats.manual_inuse, int64(nbytes))
 	}
 	// Update consistent stats.
 	stats := memstats.heapStats.acquire()
@@ -1492,6 +1518,7 @@ func (h *mheap) freeSpanLocked(s *mspan, typ spanAllocType) {
 	if typ.manual() {
 		// Manually managed memory doesn't count toward heap_sys, so add it back.
 		memstats.heap_sys.add(int64(nbytes))
+		atomic.Xadd64(&memstats.manual_inuse, -int64(nbytes))
 	}
 	// Update consistent stats.
 	stats := memstats.heapStats.acquire()
@@ -822,6 +822,110 @@ func goroutineProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int
 	return n, ok
 }
 
+//go:linkname runtime_goroutineLeakGC runtime/pprof.runtime_goroutineLeakGC
+func runtime_goroutineLeakGC() {
+	atomic.Store(&work.goroutineLeak.pending, 1)
+	GC()
+}
+
+// isLeaked reports whether the most recent goroutine leak detection
+// found gp to have leaked.
+func isLeaked(gp *g) bool {
+	return gp.leaked && readgstatus(gp)&^_Gscan == _Gwaiting
+}
+
+//go:linkname runtime_goroutineLeakCount runtime/pprof.runtime_goroutineLeakCount
+func runtime_goroutineLeakCount() int {
+	n := 0
+	forEachG(func(gp *g) {
+		if isLeaked(gp) {
+			n++
+		}
+	})
+	return n
+}
+
+//go:linkname runtime_goroutineLeakProfileWithLabels runtime/pprof.runtime_goroutineLeakProfileWithLabels
+func runtime_goroutineLeakProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int, ok bool) {
+	return goroutineLeakProfileWithLabels(p, labels)
+}
+
+// goroutineLeakProfileWithLabels is like goroutineProfileWithLabels,
+// but records only leaked goroutines.
+func goroutineLeakProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int, ok bool) {
+	if labels != nil && len(labels) != len(p) {
+		labels = nil
+	}
+
+	stopTheWorld("profile")
+
+	// World is stopped, no locking required.
+	forEachGRace(func(gp1 *g) {
+		if isLeaked(gp1) {
+			n++
+		}
+	})
+
+	if n <= len(p) {
+		ok = true
+		r, lbl := p, labels
+		forEachGRace(func(gp1 *g) {
+			if !isLeaked(gp1) || len(r) == 0 {
+				return
+			}
+			// See goroutineProfileWithLabels.
+			systemstack(func() { saveg(^uintptr(0), ^uintptr(0), gp1, &r[0]) })
+			if labels != nil {
+				lbl[0] = gp1.labels
+				lbl = lbl[1:]
+			}
+			r = r[1:]
+		})
+	}
+
+	startTheWorld()
+	return n, ok
+}
+
+//go:linkname runtime_goroutineLeakStacks runtime/pprof.runtime_goroutineLeakStacks
+func runtime_goroutineLeakStacks(buf []byte) int {
+	return goroutineLeakStacks(buf)
+}
+
+// goroutineLeakStacks is like Stack(buf, true), but formats
+// the stack traces of leaked goroutines only.
+func goroutineLeakStacks(buf []byte) int {
+	stopTheWorld("stack trace")
+
+	n := 0
+	if len(buf) > 0 {
+		systemstack(func() {
+			g0 := getg()
+			// See Stack.
+			g0.m.traceback = 1
+			g0.writebuf = buf[0:0:len(buf)]
+			first := true
+			forEachGRace(func(gp *g) {
+				if !isLeaked(gp) {
+					return
+				}
+				if !first {
+					print("\n")
+				}
+				first = false
+				goroutineheader(gp)
+				traceback(^uintptr(0), ^uintptr(0), 0, gp)
+			})
+			g0.m.traceback = 0
+			n = len(g0.writebuf)
+			g0.writebuf = nil
+		})
+	}
+
+	startTheWorld()
+	return n
+}
+
 // GoroutineProfile returns n, the number of records in the active goroutine stack profile.
 // If len(p) >= n, GoroutineProfile copies the profile into p and returns n, true.
 // If len(p) < n, GoroutineProfile does not change p and returns n, false.
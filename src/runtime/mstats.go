@@ -88,6 +88,8 @@ type mstats struct {
 
 	last_gc_nanotime uint64 // last gc (monotonic time)
 	last_heap_inuse  uint64 // heap_inuse at mark termination of the previous GC
+	manual_inuse     uint64 // bytes in manually-managed spans; updated atomically
+	numlimitgc       uint64 // number of GCs whose heap goal was set by the memory limit
 
 	// heapStats is a set of statistics
 	heapStats consistentHeapStats
@@ -225,6 +225,40 @@ func panicmemAddr(addr uintptr) {
 	panic(errorAddressString{msg: "invalid memory address or nil pointer dereference", addr: addr})
 }
 
+// The states of the loop body of a range-over-func loop, as tracked by
+// the code the compiler generates for it. They must match the constants
+// in cmd/compile/internal/rangefunc.
+const (
+	rangeReady        = 0 // loop body may be called
+	rangePanic        = 1 // loop body is running, or panicked
+	rangeDone         = 2 // loop body returned false
+	rangeExhausted    = 3 // range function returned
+	rangeMissingPanic = 4 // range function recovered a loop body panic
+)
+
+var (
+	rangePanicError        = error(errorString("range function continued iteration after loop body panic"))
+	rangeDoneError         = error(errorString("range function continued iteration after function for loop body returned false"))
+	rangeExhaustedError    = error(errorString("range function continued iteration after whole loop exit"))
+	rangeMissingPanicError = error(errorString("range function recovered a loop body panic and did not resume panicking"))
+)
+
+// panicrangestate is called by the code generated for a range-over-func
+// loop when the range function misuses the loop body function.
+func panicrangestate(state int) {
+	switch state {
+	case rangePanic:
+		panic(rangePanicError)
+	case rangeDone:
+		panic(rangeDoneError)
+	case rangeExhausted:
+		panic(rangeExhaustedError)
+	case rangeMissingPanic:
+		panic(rangeMissingPanicError)
+	}
+	throw("unexpected state passed to panicrangestate")
+}
+
 // Create a new deferred function fn, which has no arguments and results.
 // The compiler turns a defer statement into a call to this.
 func deferproc(fn func()) {
@@ -258,6 +292,107 @@ func deferproc(fn func()) {
 	// been set and must not be clobbered.
 }
 
+// deferrangefunc is called by functions that are about to execute a
+// range-over-func loop whose body contains defer statements. It adds a
+// rangefunc defer record for the caller's frame and stores in *frame the
+// token that the loop body passes to deferprocat to queue its deferred
+// calls on that record, so that they run when the caller returns.
+//
+// Like deferproc, deferrangefunc returns 0 normally, and 1 if one of
+// those deferred calls stops a panic. The compiler checks the return
+// value in the same way.
+func deferrangefunc(frame *any) {
+	gp := getg()
+	if gp.m.curg != gp {
+		// go code on the system stack can't defer
+		throw("defer on system stack")
+	}
+
+	d := newdefer()
+	d.link = gp._defer
+	gp._defer = d
+	d.pc = getcallerpc()
+	// We must not be preempted between calling getcallersp and
+	// storing it to d.sp because getcallersp's result is a
+	// uintptr stack pointer.
+	d.sp = getcallersp()
+	d.rangefunc = true
+	d.head = new(unsafe.Pointer)
+	*frame = d.head
+
+	return0()
+	// No code can go here - the C return register has
+	// been set and must not be clobbered.
+}
+
+// badDefer returns a fixed bad defer pointer for use by deferconvert
+// to mark the queue of a rangefunc defer record as closed.
+func badDefer() *_defer {
+	return (*_defer)(unsafe.Pointer(uintptr(1)))
+}
+
+// deferprocat is like deferproc but queues fn on the rangefunc defer
+// record identified by frame, the token stored by deferrangefunc. The loop
+// body may run on a different goroutine than the frame, so the queue
+// is updated atomically.
+func deferprocat(fn func(), frame any) {
+	head := frame.(*unsafe.Pointer)
+	d := newdefer()
+	d.fn = fn
+	for {
+		d.link = (*_defer)(atomic.Loadp(unsafe.Pointer(head)))
+		if d.link == badDefer() {
+			throw("defer after range func returned")
+		}
+		if writeBarrier.enabled {
+			atomicwb(head, unsafe.Pointer(d))
+		}
+		if atomic.Casp1(head, unsafe.Pointer(d.link), unsafe.Pointer(d)) {
+			break
+		}
+	}
+}
+
+// deferconvert replaces the rangefunc defer record d, which must be at
+// the top of the defer chain of the current goroutine, with the defer
+// records queued on it, so that they can be run like the other defers
+// of its frame.
+func deferconvert(d *_defer) {
+	gp := getg()
+	if gp._defer != d || !d.rangefunc {
+		throw("bad rangefunc defer entry")
+	}
+
+	var list *_defer
+	for {
+		list = (*_defer)(atomic.Loadp(unsafe.Pointer(d.head)))
+		if writeBarrier.enabled {
+			atomicwb(d.head, unsafe.Pointer(badDefer()))
+		}
+		if atomic.Casp1(d.head, unsafe.Pointer(list), unsafe.Pointer(badDefer())) {
+			break
+		}
+	}
+
+	// The queue holds the most recent defer first, which is the order
+	// in which they run.
+	gp._defer = d.link
+	if list != nil {
+		last := list
+		for {
+			last.sp = d.sp
+			last.pc = d.pc
+			if last.link == nil {
+				break
+			}
+			last = last.link
+		}
+		last.link = d.link
+		gp._defer = list
+	}
+	freedefer(d)
+}
+
 // deferprocStack queues a new deferred function with a defer record on the stack.
 // The defer record must have its fn field initialized.
 // All other fields can contain junk.
@@ -280,9 +415,11 @@ func deferprocStack(d *_defer) {
 	d.pc = getcallerpc()
 	d.framepc = 0
 	d.varp = 0
+	d.rangefunc = false
 	// The lines below implement:
 	//   d.panic = nil
 	//   d.fd = nil
+	//   d.head = nil
 	//   d.link = gp._defer
 	//   gp._defer = d
 	// But without write barriers. The first three are writes to
@@ -293,6 +430,7 @@ func deferprocStack(d *_defer) {
 	// keep track of pointers to them with a write barrier.
 	*(*uintptr)(unsafe.Pointer(&d._panic)) = 0
 	*(*uintptr)(unsafe.Pointer(&d.fd)) = 0
+	*(*uintptr)(unsafe.Pointer(&d.head)) = 0
 	*(*uintptr)(unsafe.Pointer(&d.link)) = uintptr(unsafe.Pointer(gp._defer))
 	*(*uintptr)(unsafe.Pointer(&gp._defer)) = uintptr(unsafe.Pointer(d))
 
@@ -416,6 +554,10 @@ func deferreturn() {
 		if d.sp != sp {
 			return
 		}
+		if d.rangefunc {
+			deferconvert(d)
+			continue
+		}
 		if d.openDefer {
 			done := runOpenDeferFrame(gp, d)
 			if !done {
@@ -464,6 +606,10 @@ func Goexit() {
 		if d == nil {
 			break
 		}
+		if d.rangefunc {
+			deferconvert(d)
+			continue
+		}
 		if d.started {
 			if d._panic != nil {
 				d._panic.aborted = true
@@ -802,6 +948,10 @@ func gopanic(e any) {
 		if d == nil {
 			break
 		}
+		if d.rangefunc {
+			deferconvert(d)
+			continue
+		}
 
 		// If defer was started by earlier panic or Goexit (and, since we're back here, that triggered a new panic),
 		// take defer off list. An earlier panic will not continue running, but we will make sure below that an
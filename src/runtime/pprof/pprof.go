@@ -100,12 +100,13 @@ import (
 //
 // Each Profile has a unique name. A few profiles are predefined:
 //
-//	goroutine    - stack traces of all current goroutines
-//	heap         - a sampling of memory allocations of live objects
-//	allocs       - a sampling of all past memory allocations
-//	threadcreate - stack traces that led to the creation of new OS threads
-//	block        - stack traces that led to blocking on synchronization primitives
-//	mutex        - stack traces of holders of contended mutexes
+//	goroutine     - stack traces of all current goroutines
+//	goroutineleak - stack traces of goroutines blocked forever on unreachable objects
+//	heap          - a sampling of memory allocations of live objects
+//	allocs        - a sampling of all past memory allocations
+//	threadcreate  - stack traces that led to the creation of new OS threads
+//	block         - stack traces that led to blocking on synchronization primitives
+//	mutex         - stack traces of holders of contended mutexes
 //
 // These predefined profiles maintain themselves and panic on an explicit
 // Add or Remove method call.
@@ -127,6 +128,16 @@ import (
 // pprof display to -alloc_space, the total number of bytes allocated since
 // the program began (including garbage-collected bytes).
 //
+// The goroutineleak profile reports leaked goroutines: goroutines blocked on
+// channels, in select statements, or on sync.Mutex, sync.RWMutex,
+// sync.WaitGroup or sync.Cond values that no goroutine able to run can reach.
+// Such a goroutine can never run again. Writing the profile first runs a
+// garbage collection that finds leaked goroutines; its Count method reports
+// the leaked goroutines found by the most recent such collection.
+// Detection is conservative: a goroutine blocked on an object that is also
+// reachable from a leaked goroutine's stack, or from a deferred call, or that
+// shares a memory block with a reachable object, may not be reported.
+//
 // The CPU profile is not available as a Profile. It has a special API,
 // the StartCPUProfile and StopCPUProfile functions, because it streams
 // output to a writer during profiling.
@@ -151,6 +162,12 @@ var goroutineProfile = &Profile{
 	write: writeGoroutine,
 }
 
+var goroutineLeakProfile = &Profile{
+	name:  "goroutineleak",
+	count: runtime_goroutineLeakCount,
+	write: writeGoroutineLeak,
+}
+
 var threadcreateProfile = &Profile{
 	name:  "threadcreate",
 	count: countThreadCreate,
@@ -186,12 +203,13 @@ func lockProfiles() {
 	if profiles.m == nil {
 		// Initial built-in profiles.
 		profiles.m = map[string]*Profile{
-			"goroutine":    goroutineProfile,
-			"threadcreate": threadcreateProfile,
-			"heap":         heapProfile,
-			"allocs":       allocsProfile,
-			"block":        blockProfile,
-			"mutex":        mutexProfile,
+			"goroutine":     goroutineProfile,
+			"goroutineleak": goroutineLeakProfile,
+			"threadcreate":  threadcreateProfile,
+			"heap":          heapProfile,
+			"allocs":        allocsProfile,
+			"block":         blockProfile,
+			"mutex":         mutexProfile,
 		}
 	}
 }
@@ -321,9 +339,9 @@ func (p *Profile) Remove(value any) {
 // programmer can read the profile without tools.
 //
 // The predefined profiles may assign meaning to other debug values;
-// for example, when printing the "goroutine" profile, debug=2 means to
-// print the goroutine stacks in the same form that a Go program uses
-// when dying due to an unrecovered panic.
+// for example, when printing the "goroutine" or "goroutineleak" profile,
+// debug=2 means to print the goroutine stacks in the same form that a Go
+// program uses when dying due to an unrecovered panic.
 func (p *Profile) WriteTo(w io.Writer, debug int) error {
 	if p.name == "" {
 		panic("pprof: use of zero Profile")
@@ -680,18 +698,40 @@ func runtime_goroutineProfileWithLabels(p []runtime.StackRecord, labels []unsafe
 // writeGoroutine writes the current runtime GoroutineProfile to w.
 func writeGoroutine(w io.Writer, debug int) error {
 	if debug >= 2 {
-		return writeGoroutineStacks(w)
+		return writeGoroutineStacks(w, func(buf []byte) int { return runtime.Stack(buf, true) })
 	}
 	return writeRuntimeProfile(w, debug, "goroutine", runtime_goroutineProfileWithLabels)
 }
 
-func writeGoroutineStacks(w io.Writer) error {
+// runtime_goroutineLeakGC is defined in runtime/mprof.go
+func runtime_goroutineLeakGC()
+
+// runtime_goroutineLeakCount is defined in runtime/mprof.go
+func runtime_goroutineLeakCount() int
+
+// runtime_goroutineLeakProfileWithLabels is defined in runtime/mprof.go
+func runtime_goroutineLeakProfileWithLabels(p []runtime.StackRecord, labels []unsafe.Pointer) (n int, ok bool)
+
+// runtime_goroutineLeakStacks is defined in runtime/mprof.go
+func runtime_goroutineLeakStacks(buf []byte) int
+
+// writeGoroutineLeak runs a garbage collection that detects leaked
+// goroutines, then writes their stacks to w.
+func writeGoroutineLeak(w io.Writer, debug int) error {
+	runtime_goroutineLeakGC()
+	if debug >= 2 {
+		return writeGoroutineStacks(w, runtime_goroutineLeakStacks)
+	}
+	return writeRuntimeProfile(w, debug, "goroutineleak", runtime_goroutineLeakProfileWithLabels)
+}
+
+func writeGoroutineStacks(w io.Writer, stacks func([]byte) int) error {
 	// We don't know how big the buffer needs to be to collect
 	// all the goroutines. Start with 1 MB and try a few times, doubling each time.
 	// Give up and use a truncated trace if 64 MB is not enough.
 	buf := make([]byte, 1<<20)
 	for i := 0; ; i++ {
-		n := runtime.Stack(buf, true)
+		n := stacks(buf)
 		if n < len(buf) {
 			buf = buf[:n]
 			break
@@ -1132,6 +1132,7 @@ func blockInfrequentLong(rate int) {
 }
 
 // Used by TestBlockProfileBias.
+//
 //go:linkname blockevent runtime.blockevent
 func blockevent(cycles int64, skip int)
 
@@ -1288,6 +1289,91 @@ func TestGoroutineCounts(t *testing.T) {
 	time.Sleep(10 * time.Millisecond) // let goroutines exit
 }
 
+// These functions leak the goroutines that call them.
+func leakChanRecv() { <-make(chan int) }
+func leakChanSend() { make(chan int) <- 1 }
+func leakSelect() {
+	select {
+	case <-make(chan int):
+	case make(chan int) <- 1:
+	}
+}
+func leakMutex() {
+	var mu sync.Mutex
+	mu.Lock()
+	mu.Lock()
+}
+func leakWaitGroup() {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	wg.Wait()
+}
+func leakCond() {
+	c := sync.NewCond(new(sync.Mutex))
+	c.L.Lock()
+	c.Wait()
+}
+
+func blockedChan(c chan int) { <-c }
+func blockedMutex(mu *sync.Mutex) {
+	mu.Lock()
+	mu.Unlock()
+}
+
+func TestGoroutineLeakProfile(t *testing.T) {
+	leaks := []func(){leakChanRecv, leakChanSend, leakSelect, leakMutex, leakWaitGroup, leakCond}
+	for _, f := range leaks {
+		go f()
+	}
+	// These goroutines are blocked, but the test can still wake them.
+	c := make(chan int)
+	defer close(c)
+	go blockedChan(c)
+	var mu sync.Mutex
+	mu.Lock()
+	defer mu.Unlock()
+	go blockedMutex(&mu)
+
+	want := []string{"leakChanRecv", "leakChanSend", "leakSelect", "leakMutex", "leakWaitGroup", "leakCond"}
+	var prof string
+	for i := 0; ; i++ {
+		var w bytes.Buffer
+		Lookup("goroutineleak").WriteTo(&w, 1)
+		prof = w.String()
+		found := 0
+		for _, f := range want {
+			if strings.Contains(prof, "pprof."+f+"+") {
+				found++
+			}
+		}
+		if found == len(want) {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("goroutineleak profile does not contain all of %v:\n%s", want, prof)
+		}
+		time.Sleep(10 * time.Millisecond) // let goroutines block
+	}
+	if !strings.HasPrefix(prof, "goroutineleak profile: total ") {
+		t.Errorf("unexpected profile header:\n%s", prof)
+	}
+	for _, f := range []string{"blockedChan", "blockedMutex"} {
+		if strings.Contains(prof, "pprof."+f+"+") {
+			t.Errorf("goroutineleak profile contains %s, which has not leaked:\n%s", f, prof)
+		}
+	}
+	if n := Lookup("goroutineleak").Count(); n < len(want) {
+		t.Errorf("goroutineleak Count() = %d, want at least %d", n, len(want))
+	}
+
+	var w bytes.Buffer
+	Lookup("goroutineleak").WriteTo(&w, 2)
+	stacks := w.String()
+	if !strings.Contains(stacks, " [semacquire (leaked)]:\n") || strings.Contains(stacks, "blockedChan") {
+		t.Errorf("unexpected goroutineleak stacks:\n%s", stacks)
+	}
+}
+
 func containsInOrder(s string, all ...string) bool {
 	for _, t := range all {
 		var ok bool
@@ -253,6 +253,7 @@ func main() {
 	}
 	fn := main_main // make an indirect call, as the linker doesn't know the address of the main package when laying down the runtime
 	fn()
+	runExitHooks(0)
 	if raceenabled {
 		racefini()
 	}
@@ -271,7 +272,7 @@ func main() {
 		}
 	}
 	if atomic.Load(&panicking) != 0 {
-		gopark(nil, nil, waitReasonPanicWait, traceEvGoStop, 1)
+		gopark(nil, nil, waitReasonPanicWait, traceBlockForever, 1)
 	}
 
 	exit(0)
@@ -281,10 +282,11 @@ func main() {
 	}
 }
 
-// os_beforeExit is called from os.Exit(0).
+// os_beforeExit is called from os.Exit.
 //go:linkname os_beforeExit os.runtime_beforeExit
-func os_beforeExit() {
-	if raceenabled {
+func os_beforeExit(exitCode int) {
+	runExitHooks(exitCode)
+	if exitCode == 0 && raceenabled {
 		racefini()
 	}
 }
@@ -303,7 +305,7 @@ func forcegchelper() {
 			throw("forcegc: phase error")
 		}
 		atomic.Store(&forcegc.idle, 1)
-		goparkunlock(&forcegc.lock, waitReasonForceGCIdle, traceEvGoBlock, 1)
+		goparkunlock(&forcegc.lock, waitReasonForceGCIdle, traceBlockSystemGoroutine, 1)
 		// this goroutine is explicitly resumed by sysmon
 		if debug.gctrace > 0 {
 			println("GC forced")
@@ -346,7 +348,10 @@ func goschedguarded() {
 // Reason explains why the goroutine has been parked. It is displayed in stack
 // traces and heap dumps. Reasons should be unique and descriptive. Do not
 // re-use reasons, add new ones.
-func gopark(unlockf func(*g, unsafe.Pointer) bool, lock unsafe.Pointer, reason waitReason, traceEv byte, traceskip int) {
+//
+// TraceReason is the reason recorded in the execution trace. Unlike
+// reason, it may be shared by many kinds of waits.
+func gopark(unlockf func(*g, unsafe.Pointer) bool, lock unsafe.Pointer, reason waitReason, traceReason traceBlockReason, traceskip int) {
 	if reason != waitReasonSleep {
 		checkTimeouts() // timeouts may expire while two goroutines keep the scheduler busy
 	}
@@ -359,7 +364,7 @@ func gopark(unlockf func(*g, unsafe.Pointer) bool, lock unsafe.Pointer, reason w
 	mp.waitlock = lock
 	mp.waitunlockf = unlockf
 	gp.waitreason = reason
-	mp.waittraceev = traceEv
+	mp.waittrace = traceReason
 	mp.waittraceskip = traceskip
 	releasem(mp)
 	// can't do anything that might move the G between Ms here.
@@ -368,8 +373,8 @@ func gopark(unlockf func(*g, unsafe.Pointer) bool, lock unsafe.Pointer, reason w
 
 // Puts the current goroutine into a waiting state and unlocks the lock.
 // The goroutine can be made runnable again by calling goready(gp).
-func goparkunlock(lock *mutex, reason waitReason, traceEv byte, traceskip int) {
-	gopark(parkunlock_c, unsafe.Pointer(lock), reason, traceEv, traceskip)
+func goparkunlock(lock *mutex, reason waitReason, traceReason traceBlockReason, traceskip int) {
+	gopark(parkunlock_c, unsafe.Pointer(lock), reason, traceReason, traceskip)
 }
 
 func goready(gp *g, traceskip int) {
@@ -433,7 +438,7 @@ func releaseSudog(s *sudog) {
 	if s.waitlink != nil {
 		throw("runtime: sudog with non-nil waitlink")
 	}
-	if s.c != nil {
+	if s.c != 0 {
 		throw("runtime: sudog with non-nil c")
 	}
 	gp := getg()
@@ -848,6 +853,9 @@ func ready(gp *g, traceskip int, next bool) {
 		throw("bad g->status in ready")
 	}
 
+	// gp can run again, so it did not leak after all.
+	gp.leaked = false
+
 	// status is Gwaiting or Gscanwaiting, make Grunnable and put on runq
 	casgstatus(gp, _Gwaiting, _Grunnable)
 	runqput(_g_.m.p.ptr(), gp, next)
@@ -985,6 +993,12 @@ func casgstatus(gp *g, oldval, newval uint32) {
 		}
 	}
 
+	if gp.syncGroup != nil {
+		systemstack(func() {
+			gp.syncGroup.changegstatus(gp, oldval, newval)
+		})
+	}
+
 	// Handle tracking for scheduling latencies.
 	if oldval == _Grunning {
 		// Track every 8th time a goroutine transitions out of running.
@@ -1018,6 +1032,14 @@ func casgstatus(gp *g, oldval, newval uint32) {
 	}
 }
 
+// casGToWaiting transitions gp from old to _Gwaiting, and sets the wait reason.
+// Use it rather than casgstatus when possible, so that the wait reason is
+// set before the transition, when synctest groups account for it.
+func casGToWaiting(gp *g, old uint32, reason waitReason) {
+	gp.waitreason = reason
+	casgstatus(gp, old, _Gwaiting)
+}
+
 // casgstatus(gp, oldstatus, Gcopystack), assuming oldstatus is Gwaiting or Grunnable.
 // Returns old status. Cannot call casgstatus directly, because we are racing with an
 // async wakeup that might come in from netpoll. If we see Gwaiting from the readgstatus,
@@ -1088,7 +1110,7 @@ func stopTheWorld(reason string) {
 		// must have preempted all goroutines, including any attempting
 		// to scan our stack, in which case, any stack shrinking will
 		// have already completed by the time we exit.
-		casgstatus(gp, _Grunning, _Gwaiting)
+		casGToWaiting(gp, _Grunning, waitReasonStoppingTheWorld)
 		stopTheWorldWithSema()
 		casgstatus(gp, _Gwaiting, _Grunning)
 	})
@@ -1187,8 +1209,7 @@ func stopTheWorldWithSema() {
 		s := p.status
 		if s == _Psyscall && atomic.Cas(&p.status, s, _Pgcstop) {
 			if trace.enabled {
-				traceGoSysBlock(p)
-				traceProcStop(p)
+				traceProcSteal(p)
 			}
 			p.syscalltick++
 			sched.stopwait--
@@ -1530,8 +1551,13 @@ found:
 
 	atomic.Xadd64(&ncgocall, int64(m.ncgocall))
 
-	// Release the P.
-	handoffp(releasep())
+	// Release the P, after queueing the trace buffer of the M,
+	// which is no longer in allm.
+	pp := releasep()
+	if trace.enabled {
+		traceThreadDestroy(m)
+	}
+	handoffp(pp)
 	// After this point we must not have write barriers.
 
 	// Invoke the deadlock detector. This must happen after
@@ -1622,8 +1648,7 @@ func forEachP(fn func(*p)) {
 		s := p.status
 		if s == _Psyscall && p.runSafePointFn == 1 && atomic.Cas(&p.status, s, _Pidle) {
 			if trace.enabled {
-				traceGoSysBlock(p)
-				traceProcStop(p)
+				traceProcSteal(p)
 			}
 			p.syscalltick++
 			handoffp(p)
@@ -1997,7 +2022,12 @@ func needm() {
 	minit()
 
 	// mp.curg is now a real goroutine.
+	tmp := traceAcquireStatus()
 	casgstatus(mp.curg, _Gdead, _Gsyscall)
+	if trace.enabled {
+		traceGoCreateSyscall(mp.curg)
+	}
+	traceReleaseStatus(tmp)
 	atomic.Xadd(&sched.ngsys, -1)
 }
 
@@ -2099,7 +2129,12 @@ func dropm() {
 	mp := getg().m
 
 	// Return mp.curg to dead state.
+	traceAcquireStatus()
+	if trace.enabled {
+		traceGoDestroySyscall()
+	}
 	casgstatus(mp.curg, _Gsyscall, _Gdead)
+	traceReleaseStatus(mp)
 	mp.curg.preemptStop = false
 	atomic.Xadd(&sched.ngsys, +1)
 
@@ -2698,11 +2733,6 @@ func execute(gp *g, inheritTime bool) {
 	}
 
 	if trace.enabled {
-		// GoSysExit has to happen when we have a P, but before GoStart.
-		// So we emit it here.
-		if gp.syscallsp != 0 && gp.sysblocktraced {
-			traceGoSysExit(gp.sysexitticks)
-		}
 		traceGoStart()
 	}
 
@@ -3237,6 +3267,7 @@ func injectglist(glist *gList) {
 	if glist.empty() {
 		return
 	}
+	mp := traceAcquireStatus()
 	if trace.enabled {
 		for gp := glist.head.ptr(); gp != nil; gp = gp.schedlink.ptr() {
 			traceGoUnpark(gp, 0)
@@ -3253,6 +3284,7 @@ func injectglist(glist *gList) {
 		qsize++
 		casgstatus(gp, _Gwaiting, _Grunnable)
 	}
+	traceReleaseStatus(mp)
 
 	// Turn the gList into a gQueue.
 	var q gQueue
@@ -3504,7 +3536,15 @@ func park_m(gp *g) {
 	_g_ := getg()
 
 	if trace.enabled {
-		traceGoPark(_g_.m.waittraceev, _g_.m.waittraceskip)
+		traceGoPark(_g_.m.waittrace, _g_.m.waittraceskip)
+	}
+
+	// If gp is in a synctest group, hold the group active until we
+	// know whether gp parked: the unlock function may decline to park,
+	// and the group must not be considered blocked meanwhile.
+	sg := gp.syncGroup
+	if sg != nil {
+		sg.incActive()
 	}
 
 	casgstatus(gp, _Grunning, _Gwaiting)
@@ -3519,9 +3559,16 @@ func park_m(gp *g) {
 				traceGoUnpark(gp, 2)
 			}
 			casgstatus(gp, _Gwaiting, _Grunnable)
+			if sg != nil {
+				sg.decActive()
+			}
 			execute(gp, true) // Schedule it back, never returns.
 		}
 	}
+
+	if sg != nil {
+		sg.decActive()
+	}
 	schedule()
 }
 
@@ -3573,7 +3620,7 @@ func gopreempt_m(gp *g) {
 //go:systemstack
 func preemptPark(gp *g) {
 	if trace.enabled {
-		traceGoPark(traceEvGoBlock, 0)
+		traceGoPark(traceBlockPreempted, 0)
 	}
 	status := readgstatus(gp)
 	if status&^_Gscan != _Grunning {
@@ -3630,6 +3677,9 @@ func goyield_m(gp *g) {
 // Finishes execution of the current goroutine.
 func goexit1() {
 	if raceenabled {
+		if sg := getg().syncGroup; sg != nil {
+			racereleasemergeg(getg(), sg.raceaddr())
+		}
 		racegoend()
 	}
 	if trace.enabled {
@@ -3643,7 +3693,17 @@ func goexit0(gp *g) {
 	_g_ := getg()
 	_p_ := _g_.m.p.ptr()
 
+	// Hold gp's synctest group active until gp is dead, so that the
+	// group's root is woken if gp was the last goroutine running in it.
+	sg := gp.syncGroup
+	if sg != nil {
+		sg.incActive()
+	}
 	casgstatus(gp, _Grunning, _Gdead)
+	gp.syncGroup = nil
+	if sg != nil {
+		sg.decActive()
+	}
 	gcController.addScannableStack(_p_, -int64(gp.stack.hi-gp.stack.lo))
 	if isSystemGoroutine(gp, false) {
 		atomic.Xadd(&sched.ngsys, -1)
@@ -3658,6 +3718,7 @@ func goexit0(gp *g) {
 	gp._panic = nil // non-nil for Goexit during panic. points at stack-allocated data.
 	gp.writebuf = nil
 	gp.waitreason = 0
+	gp.leaked = false
 	gp.param = nil
 	gp.labels = nil
 	gp.timer = nil
@@ -3758,17 +3819,14 @@ func save(pc, sp uintptr) {
 // entry point for syscalls, which obtains the SP and PC from the caller.
 //
 // Syscall tracing:
-// At the start of a syscall we emit traceGoSysCall to capture the stack trace.
-// If the syscall does not block, that is it, we do not emit any other events.
-// If the syscall blocks (that is, P is retaken), retaker emits traceGoSysBlock;
-// when syscall returns we emit traceGoSysExit and when the goroutine starts running
-// (potentially instantly, if exitsyscallfast returns true) we emit traceGoStart.
-// To ensure that traceGoSysExit is emitted strictly after traceGoSysBlock,
-// we remember current value of syscalltick in m (_g_.m.syscalltick = _g_.m.p.ptr().syscalltick),
-// whoever emits traceGoSysBlock increments p.syscalltick afterwards;
-// and we wait for the increment before emitting traceGoSysExit.
-// Note that the increment is done even if tracing is not enabled,
-// because tracing can be enabled in the middle of syscall. We don't want the wait to hang.
+// At the start of a syscall we emit traceGoSysCall to capture the stack trace,
+// along with the sequence number of the P, so that the P can be taken away later.
+// If the syscall blocks (that is, P is retaken), retaker emits traceProcSteal.
+// When the syscall returns we emit traceGoSysExit, which records whether the
+// P was lost, and if it was, traceGoStart when the goroutine starts running
+// again (potentially instantly, if exitsyscallfast returns true).
+// The trace reader orders the steal before the exit by the sequence number
+// of the P, so the exit does not wait for the steal to be traced.
 //
 //go:nosplit
 func reentersyscall(pc, sp uintptr) {
@@ -3817,7 +3875,6 @@ func reentersyscall(pc, sp uintptr) {
 	}
 
 	_g_.m.syscalltick = _g_.m.p.ptr().syscalltick
-	_g_.sysblocktraced = true
 	pp := _g_.m.p.ptr()
 	pp.m = 0
 	_g_.m.oldp.set(pp)
@@ -3857,8 +3914,7 @@ func entersyscall_gcwait() {
 	lock(&sched.lock)
 	if sched.stopwait > 0 && atomic.Cas(&_p_.status, _Psyscall, _Pgcstop) {
 		if trace.enabled {
-			traceGoSysBlock(_p_)
-			traceProcStop(_p_)
+			traceProcSteal(_p_)
 		}
 		_p_.syscalltick++
 		if sched.stopwait--; sched.stopwait == 0 {
@@ -3877,7 +3933,6 @@ func entersyscallblock() {
 	_g_.throwsplit = true
 	_g_.stackguard0 = stackPreempt // see comment in entersyscall
 	_g_.m.syscalltick = _g_.m.p.ptr().syscalltick
-	_g_.sysblocktraced = true
 	_g_.m.p.ptr().syscalltick++
 
 	// Leave SP around for GC and traceback.
@@ -3914,7 +3969,6 @@ func entersyscallblock() {
 func entersyscallblock_handoff() {
 	if trace.enabled {
 		traceGoSysCall()
-		traceGoSysBlock(getg().m.p.ptr())
 	}
 	handoffp(releasep())
 }
@@ -3944,9 +3998,13 @@ func exitsyscall() {
 	_g_.m.oldp = 0
 	if exitsyscallfast(oldp) {
 		if trace.enabled {
-			if oldp != _g_.m.p.ptr() || _g_.m.syscalltick != _g_.m.p.ptr().syscalltick {
-				systemstack(traceGoStart)
-			}
+			lostP := oldp != _g_.m.p.ptr() || _g_.m.syscalltick != _g_.m.p.ptr().syscalltick
+			systemstack(func() {
+				traceGoSysExit(lostP)
+				if lostP {
+					traceGoStart()
+				}
+			})
 		}
 		// There's a cpu for us, so we can run.
 		_g_.m.p.ptr().syscalltick++
@@ -3974,20 +4032,6 @@ func exitsyscall() {
 		return
 	}
 
-	_g_.sysexitticks = 0
-	if trace.enabled {
-		// Wait till traceGoSysBlock event is emitted.
-		// This ensures consistency of the trace (the goroutine is started after it is blocked).
-		for oldp != nil && oldp.syscalltick == _g_.m.syscalltick {
-			osyield()
-		}
-		// We can't trace syscall exit right now because we don't have a P.
-		// Tracing code can invoke write barriers that cannot run without a P.
-		// So instead we remember the syscall exit time and emit the event
-		// in execute when we have a P.
-		_g_.sysexitticks = cputicks()
-	}
-
 	_g_.m.locks--
 
 	// Call the scheduler.
@@ -4006,8 +4050,6 @@ func exitsyscall() {
 
 //go:nosplit
 func exitsyscallfast(oldp *p) bool {
-	_g_ := getg()
-
 	// Freezetheworld sets stopwait but does not retake P's.
 	if sched.stopwait == freezeStopWait {
 		return false
@@ -4026,16 +4068,6 @@ func exitsyscallfast(oldp *p) bool {
 		var ok bool
 		systemstack(func() {
 			ok = exitsyscallfast_pidle()
-			if ok && trace.enabled {
-				if oldp != nil {
-					// Wait till traceGoSysBlock event is emitted.
-					// This ensures consistency of the trace (the goroutine is started after it is blocked).
-					for oldp.syscalltick == _g_.m.syscalltick {
-						osyield()
-					}
-				}
-				traceGoSysExit(0)
-			}
 		})
 		if ok {
 			return true
@@ -4054,13 +4086,11 @@ func exitsyscallfast_reacquired() {
 	if _g_.m.syscalltick != _g_.m.p.ptr().syscalltick {
 		if trace.enabled {
 			// The p was retaken and then enter into syscall again (since _g_.m.syscalltick has changed).
-			// traceGoSysBlock for this syscall was already emitted,
+			// traceProcSteal for this syscall was already emitted,
 			// but here we effectively retake the p from the new syscall running on the same p.
 			systemstack(func() {
-				// Denote blocking of the new syscall.
-				traceGoSysBlock(_g_.m.p.ptr())
-				// Denote completion of the current syscall.
-				traceGoSysExit(0)
+				traceProcSteal(_g_.m.p.ptr())
+				traceProcStart()
 			})
 		}
 		_g_.m.p.ptr().syscalltick++
@@ -4089,7 +4119,12 @@ func exitsyscallfast_pidle() bool {
 //
 //go:nowritebarrierrec
 func exitsyscall0(gp *g) {
+	mp := traceAcquireStatus()
 	casgstatus(gp, _Gsyscall, _Grunnable)
+	if trace.enabled {
+		traceGoSysExit(true)
+	}
+	traceReleaseStatus(mp)
 	dropg()
 	lock(&sched.lock)
 	var _p_ *p
@@ -4303,9 +4338,10 @@ func newproc1(fn *funcval, callergp *g, callerpc uintptr) *g {
 	if isSystemGoroutine(newg, false) {
 		atomic.Xadd(&sched.ngsys, +1)
 	} else {
-		// Only user goroutines inherit pprof labels.
+		// Only user goroutines inherit pprof labels and synctest groups.
 		if _g_.m.curg != nil {
 			newg.labels = _g_.m.curg.labels
+			newg.syncGroup = _g_.m.curg.syncGroup
 		}
 	}
 	// Track initial transition?
@@ -4878,7 +4914,6 @@ func (pp *p) destroy() {
 	freemcache(pp.mcache)
 	pp.mcache = nil
 	gfpurge(pp)
-	traceProcFree(pp)
 	if raceenabled {
 		if pp.timerRaceCtx != 0 {
 			// The race detector code uses a callback to fetch
@@ -4989,7 +5024,7 @@ func procresize(nprocs int32) *p {
 				// and then scheduled again to keep
 				// the trace sane.
 				traceGoSched()
-				traceProcStop(_g_.m.p.ptr())
+				traceProcStop()
 			}
 			_g_.m.p.ptr().m = 0
 		}
@@ -5102,7 +5137,7 @@ func releasep() *p {
 		throw("releasep: invalid p state")
 	}
 	if trace.enabled {
-		traceProcStop(_g_.m.p.ptr())
+		traceProcStop()
 	}
 	_g_.m.p = 0
 	_p_.m = 0
@@ -5452,8 +5487,7 @@ func retake(now int64) uint32 {
 			incidlelocked(-1)
 			if atomic.Cas(&_p_.status, s, _Pidle) {
 				if trace.enabled {
-					traceGoSysBlock(_p_)
-					traceProcStop(_p_)
+					traceProcSteal(_p_)
 				}
 				n++
 				_p_.syscalltick++
@@ -376,7 +376,13 @@ type sudog struct {
 	parent   *sudog // semaRoot binary tree
 	waitlink *sudog // g.waiting list or semaRoot
 	waittail *sudog // semaRoot
-	c        *hchan // channel
+
+	// c is the address of the object g is blocked on: the channel,
+	// semaphore or notify list. It is a uintptr, hidden from the
+	// garbage collector, because goroutine leak detection depends on
+	// a blocked goroutine not keeping that object reachable through
+	// its sudog. The blocked goroutine keeps it alive from its stack.
+	c uintptr
 }
 
 type libcall struct {
@@ -424,7 +430,7 @@ type g struct {
 	// param is a generic pointer parameter field used to pass
 	// values in particular contexts where other storage for the
 	// parameter would be difficult to find. It is currently used
-	// in three ways:
+	// in four ways:
 	// 1. When a channel operation wakes up a blocked goroutine, it sets param to
 	//    point to the sudog of the completed blocking operation.
 	// 2. By gcAssistAlloc1 to signal back to its caller that the goroutine completed
@@ -432,6 +438,9 @@ type g struct {
 	//    stack may have moved in the meantime.
 	// 3. By debugCallWrap to pass parameters to a new goroutine because allocating a
 	//    closure in the runtime is forbidden.
+	// 4. By semacquire1 and notifyListWait to point to the sudog of the goroutine
+	//    while it is blocked, so that goroutine leak detection can find the
+	//    semaphore or notify list it is blocked on.
 	param        unsafe.Pointer
 	atomicstatus uint32
 	stackLock    uint32 // sigprof/scang lock; TODO: fold in to atomicstatus
@@ -451,6 +460,7 @@ type g struct {
 
 	paniconfault bool // panic (instead of crash) on unexpected fault address
 	gcscandone   bool // g has scanned stack; protected by _Gscan bit in status
+	leaked       bool // g is blocked forever on unreachable objects; set by the GC
 	throwsplit   bool // must not split stack
 	// activeStackChans indicates that there are unlocked channels
 	// pointing into this goroutine's stack. If true, stack
@@ -462,30 +472,28 @@ type g struct {
 	// for stack shrinking. It's a boolean value, but is updated atomically.
 	parkingOnChan uint8
 
-	raceignore     int8     // ignore race detection events
-	sysblocktraced bool     // StartTrace has emitted EvGoInSyscall about this goroutine
-	tracking       bool     // whether we're tracking this G for sched latency statistics
-	trackingSeq    uint8    // used to decide whether to track this G
-	runnableStamp  int64    // timestamp of when the G last became runnable, only used when tracking
-	runnableTime   int64    // the amount of time spent runnable, cleared when running, only used when tracking
-	sysexitticks   int64    // cputicks when syscall has returned (for tracing)
-	traceseq       uint64   // trace event sequencer
-	tracelastp     puintptr // last P emitted an event for this goroutine
-	lockedm        muintptr
-	sig            uint32
-	writebuf       []byte
-	sigcode0       uintptr
-	sigcode1       uintptr
-	sigpc          uintptr
-	gopc           uintptr         // pc of go statement that created this goroutine
-	ancestors      *[]ancestorInfo // ancestor information goroutine(s) that created this goroutine (only used if debug.tracebackancestors)
-	startpc        uintptr         // pc of goroutine function
-	racectx        uintptr
-	waiting        *sudog         // sudog structures this g is waiting on (that have a valid elem ptr); in lock order
-	cgoCtxt        []uintptr      // cgo traceback context
-	labels         unsafe.Pointer // profiler labels
-	timer          *timer         // cached timer for time.Sleep
-	selectDone     uint32         // are we participating in a select and did someone win the race?
+	raceignore    int8   // ignore race detection events
+	tracking      bool   // whether we're tracking this G for sched latency statistics
+	trackingSeq   uint8  // used to decide whether to track this G
+	runnableStamp int64  // timestamp of when the G last became runnable, only used when tracking
+	runnableTime  int64  // the amount of time spent runnable, cleared when running, only used when tracking
+	traceseq      uint64 // trace event sequencer
+	lockedm       muintptr
+	sig           uint32
+	writebuf      []byte
+	sigcode0      uintptr
+	sigcode1      uintptr
+	sigpc         uintptr
+	gopc          uintptr         // pc of go statement that created this goroutine
+	ancestors     *[]ancestorInfo // ancestor information goroutine(s) that created this goroutine (only used if debug.tracebackancestors)
+	startpc       uintptr         // pc of goroutine function
+	racectx       uintptr
+	waiting       *sudog         // sudog structures this g is waiting on (that have a valid elem ptr); in lock order
+	cgoCtxt       []uintptr      // cgo traceback context
+	labels        unsafe.Pointer // profiler labels
+	timer         *timer         // cached timer for time.Sleep
+	syncGroup     *synctestGroup // synctest group this goroutine belongs to, if any
+	selectDone    uint32         // are we participating in a select and did someone win the race?
 
 	// Per-G GC state
 
@@ -558,12 +566,19 @@ type m struct {
 	nextwaitm     muintptr    // next m waiting for lock
 	waitunlockf   func(*g, unsafe.Pointer) bool
 	waitlock      unsafe.Pointer
-	waittraceev   byte
+	waittrace     traceBlockReason // reason recorded in the trace when parking
 	waittraceskip int
-	startingtrace bool
 	syscalltick   uint32
 	freelink      *m // on sched.freem
 
+	// tracebuf is the trace buffer of the M. traceBufLocks counts
+	// the nested acquisitions of trace.bufLock by the M, and
+	// traceStatusBusy is set while the M changes the status of a
+	// goroutine (see traceAcquireStatus).
+	tracebuf        traceBufPtr
+	traceBufLocks   int32
+	traceStatusBusy uint32
+
 	// mFixup is used to synchronize OS related m state
 	// (credentials etc) use mutex to access. To avoid deadlocks
 	// an atomic.Load() of used being zero in mDoFixupFn()
@@ -660,7 +675,12 @@ type p struct {
 		buf [128]*mspan
 	}
 
-	tracebuf traceBufPtr
+	// traceseq is the sequence number of the trace events that
+	// synchronize with the Ms that acquire the P, and traceSyscallM
+	// the M of the last syscall on the P, which the P is taken from
+	// if the syscall blocks.
+	traceseq      uint64
+	traceSyscallM int64
 
 	// traceSweep indicates the sweep events should be traced.
 	// This is used to defer the sweep start event until a span
@@ -672,8 +692,6 @@ type p struct {
 
 	palloc persistentAlloc // per-P to avoid mutex
 
-	_ uint32 // Alignment for atomic fields below
-
 	// The when field of the first entry on the timer heap.
 	// This is updated using atomic functions.
 	// This is 0 if the timer heap is empty.
@@ -972,6 +990,12 @@ type _defer struct {
 	// framepc/sp can be used as pc/sp pair to continue a stack trace via
 	// gentraceback().
 	framepc uintptr
+
+	// If rangefunc is true, this _defer stands for the defers run by
+	// the body of a range-over-func loop in the frame, which are
+	// queued on *head by deferprocat. See deferrangefunc.
+	rangefunc bool
+	head      *unsafe.Pointer
 }
 
 // A _panic holds information about an active panic.
@@ -1055,6 +1079,13 @@ const (
 	waitReasonGCWorkerIdle                            // "GC worker (idle)"
 	waitReasonPreempted                               // "preempted"
 	waitReasonDebugCall                               // "debug call"
+	waitReasonGCMarkTermination                       // "GC mark termination"
+	waitReasonStoppingTheWorld                        // "stopping the world"
+	waitReasonSynctestRun                             // "synctest.Run"
+	waitReasonSynctestWait                            // "synctest.Wait"
+	waitReasonSynctestChanReceive                     // "chan receive (synctest)"
+	waitReasonSynctestChanSend                        // "chan send (synctest)"
+	waitReasonSynctestSelect                          // "select (synctest)"
 )
 
 var waitReasonStrings = [...]string{
@@ -1085,6 +1116,13 @@ var waitReasonStrings = [...]string{
 	waitReasonGCWorkerIdle:          "GC worker (idle)",
 	waitReasonPreempted:             "preempted",
 	waitReasonDebugCall:             "debug call",
+	waitReasonGCMarkTermination:     "GC mark termination",
+	waitReasonStoppingTheWorld:      "stopping the world",
+	waitReasonSynctestRun:           "synctest.Run",
+	waitReasonSynctestWait:          "synctest.Wait",
+	waitReasonSynctestChanReceive:   "chan receive (synctest)",
+	waitReasonSynctestChanSend:      "chan send (synctest)",
+	waitReasonSynctestSelect:        "select (synctest)",
 }
 
 func (w waitReason) String() string {
@@ -1094,6 +1132,26 @@ func (w waitReason) String() string {
 	return waitReasonStrings[w]
 }
 
+// isIdleInSynctest reports whether a goroutine waiting for reason w
+// is durably blocked: it can only be unblocked by another goroutine
+// in its synctest group, so the group may advance its fake clock.
+func (w waitReason) isIdleInSynctest() bool {
+	switch w {
+	case waitReasonChanReceiveNilChan,
+		waitReasonChanSendNilChan,
+		waitReasonSelectNoCases,
+		waitReasonSleep,
+		waitReasonSyncCondWait,
+		waitReasonSynctestRun,
+		waitReasonSynctestWait,
+		waitReasonSynctestChanReceive,
+		waitReasonSynctestChanSend,
+		waitReasonSynctestSelect:
+		return true
+	}
+	return false
+}
+
 var (
 	allm       *m
 	gomaxprocs int32
@@ -83,7 +83,7 @@ func selparkcommit(gp *g, _ unsafe.Pointer) bool {
 	// channels in lock order.
 	var lastc *hchan
 	for sg := gp.waiting; sg != nil; sg = sg.waitlink {
-		if sg.c != lastc && lastc != nil {
+		if sg.hchan() != lastc && lastc != nil {
 			// As soon as we unlock the channel, fields in
 			// any sudog with that channel may change,
 			// including c and waitlink. Since multiple
@@ -92,7 +92,7 @@ func selparkcommit(gp *g, _ unsafe.Pointer) bool {
 			// of a channel.
 			unlock(&lastc.lock)
 		}
-		lastc = sg.c
+		lastc = sg.hchan()
 	}
 	if lastc != nil {
 		unlock(&lastc.lock)
@@ -101,7 +101,7 @@ func selparkcommit(gp *g, _ unsafe.Pointer) bool {
 }
 
 func block() {
-	gopark(nil, nil, waitReasonSelectNoCases, traceEvGoStop, 1) // forever
+	gopark(nil, nil, waitReasonSelectNoCases, traceBlockForever, 1) // forever
 }
 
 // selectgo implements the select statement.
@@ -239,6 +239,7 @@ func selectgo(cas0 *scase, order0 *uint16, pc0 *uintptr, nsends, nrecvs int, blo
 		sgnext *sudog
 		qp     unsafe.Pointer
 		nextp  **sudog
+		reason waitReason
 	)
 
 	// pass 1 - look for something already waiting
@@ -291,11 +292,20 @@ func selectgo(cas0 *scase, order0 *uint16, pc0 *uintptr, nsends, nrecvs int, blo
 	if gp.waiting != nil {
 		throw("gp.waiting != nil")
 	}
+	// The select is durably blocked in a synctest bubble only if
+	// every channel in it belongs to the goroutine's bubble.
+	reason = waitReasonSelect
+	if gp.syncGroup != nil {
+		reason = waitReasonSynctestSelect
+	}
 	nextp = &gp.waiting
 	for _, casei := range lockorder {
 		casi = int(casei)
 		cas = &scases[casi]
 		c = cas.c
+		if !c.inSyncGroup(gp) {
+			reason = waitReasonSelect
+		}
 		sg := acquireSudog()
 		sg.g = gp
 		sg.isSelect = true
@@ -306,7 +316,7 @@ func selectgo(cas0 *scase, order0 *uint16, pc0 *uintptr, nsends, nrecvs int, blo
 		if t0 != 0 {
 			sg.releasetime = -1
 		}
-		sg.c = c
+		sg.c = uintptr(unsafe.Pointer(c))
 		// Construct waiting list in lock order.
 		*nextp = sg
 		nextp = &sg.waitlink
@@ -325,7 +335,7 @@ func selectgo(cas0 *scase, order0 *uint16, pc0 *uintptr, nsends, nrecvs int, blo
 	// changes and when we set gp.activeStackChans is not safe for
 	// stack shrinking.
 	atomic.Store8(&gp.parkingOnChan, 1)
-	gopark(selparkcommit, nil, waitReasonSelect, traceEvGoBlockSelect, 1)
+	gopark(selparkcommit, nil, reason, traceBlockSelect, 1)
 	gp.activeStackChans = false
 
 	sellock(scases, lockorder)
@@ -346,7 +356,7 @@ func selectgo(cas0 *scase, order0 *uint16, pc0 *uintptr, nsends, nrecvs int, blo
 	for sg1 := gp.waiting; sg1 != nil; sg1 = sg1.waitlink {
 		sg1.isSelect = false
 		sg1.elem = nil
-		sg1.c = nil
+		sg1.c = 0
 	}
 	gp.waiting = nil
 
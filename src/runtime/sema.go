@@ -27,7 +27,7 @@ import (
 
 // Asynchronous semaphore for sync.Mutex.
 
-// A semaRoot holds a balanced tree of sudog with distinct addresses (s.elem).
+// A semaRoot holds a balanced tree of sudog with distinct addresses (s.c).
 // Each of those sudog may in turn point (through s.waitlink) to a list
 // of other sudogs waiting on the same address.
 // The operations on the inner lists of sudogs with the same address
@@ -128,6 +128,9 @@ func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags, skipframes i
 		}
 		s.acquiretime = t0
 	}
+	// Record s in gp.param so that goroutine leak detection
+	// can find the semaphore we are blocked on.
+	gp.param = unsafe.Pointer(s)
 	for {
 		lockWithRank(&root.lock, lockRankRoot)
 		// Add ourselves to nwait to disable "easy case" in semrelease.
@@ -141,11 +144,12 @@ func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags, skipframes i
 		// Any semrelease after the cansemacquire knows we're waiting
 		// (we set nwait above), so go to sleep.
 		root.queue(addr, s, lifo)
-		goparkunlock(&root.lock, waitReasonSemacquire, traceEvGoBlockSync, 4+skipframes)
+		goparkunlock(&root.lock, waitReasonSemacquire, traceBlockSync, 4+skipframes)
 		if s.ticket != 0 || cansemacquire(addr) {
 			break
 		}
 	}
+	gp.param = nil
 	if s.releasetime > 0 {
 		blockevent(s.releasetime-t0, 3+skipframes)
 	}
@@ -233,14 +237,14 @@ func cansemacquire(addr *uint32) bool {
 // queue adds s to the blocked goroutines in semaRoot.
 func (root *semaRoot) queue(addr *uint32, s *sudog, lifo bool) {
 	s.g = getg()
-	s.elem = unsafe.Pointer(addr)
+	s.c = uintptr(unsafe.Pointer(addr))
 	s.next = nil
 	s.prev = nil
 
 	var last *sudog
 	pt := &root.treap
 	for t := *pt; t != nil; t = *pt {
-		if t.elem == unsafe.Pointer(addr) {
+		if t.c == uintptr(unsafe.Pointer(addr)) {
 			// Already have addr in list.
 			if lifo {
 				// Substitute s in t's place in treap.
@@ -279,7 +283,7 @@ func (root *semaRoot) queue(addr *uint32, s *sudog, lifo bool) {
 			return
 		}
 		last = t
-		if uintptr(unsafe.Pointer(addr)) < uintptr(t.elem) {
+		if uintptr(unsafe.Pointer(addr)) < t.c {
 			pt = &t.prev
 		} else {
 			pt = &t.next
@@ -288,7 +292,7 @@ func (root *semaRoot) queue(addr *uint32, s *sudog, lifo bool) {
 
 	// Add s as new leaf in tree of unique addrs.
 	// The balanced tree is a treap using ticket as the random heap priority.
-	// That is, it is a binary tree ordered according to the elem addresses,
+	// That is, it is a binary tree ordered according to the c addresses,
 	// but then among the space of possible binary trees respecting those
 	// addresses, it is kept balanced on average by maintaining a heap ordering
 	// on the ticket: s.ticket <= both s.prev.ticket and s.next.ticket.
@@ -322,10 +326,10 @@ func (root *semaRoot) dequeue(addr *uint32) (found *sudog, now int64) {
 	ps := &root.treap
 	s := *ps
 	for ; s != nil; s = *ps {
-		if s.elem == unsafe.Pointer(addr) {
+		if s.c == uintptr(unsafe.Pointer(addr)) {
 			goto Found
 		}
-		if uintptr(unsafe.Pointer(addr)) < uintptr(s.elem) {
+		if uintptr(unsafe.Pointer(addr)) < s.c {
 			ps = &s.prev
 		} else {
 			ps = &s.next
@@ -380,7 +384,7 @@ Found:
 		}
 	}
 	s.parent = nil
-	s.elem = nil
+	s.c = 0
 	s.next = nil
 	s.prev = nil
 	s.ticket = 0
@@ -495,8 +499,10 @@ func notifyListWait(l *notifyList, t uint32) {
 	}
 
 	// Enqueue itself.
+	gp := getg()
 	s := acquireSudog()
-	s.g = getg()
+	s.g = gp
+	s.c = uintptr(unsafe.Pointer(l))
 	s.ticket = t
 	s.releasetime = 0
 	t0 := int64(0)
@@ -510,10 +516,15 @@ func notifyListWait(l *notifyList, t uint32) {
 		l.tail.next = s
 	}
 	l.tail = s
-	goparkunlock(&l.lock, waitReasonSyncCondWait, traceEvGoBlockCond, 3)
+	// Record s in gp.param so that goroutine leak detection
+	// can find the notify list we are blocked on.
+	gp.param = unsafe.Pointer(s)
+	goparkunlock(&l.lock, waitReasonSyncCondWait, traceBlockCondWait, 3)
+	gp.param = nil
 	if t0 != 0 {
 		blockevent(s.releasetime-t0, 2)
 	}
+	s.c = 0
 	releaseSudog(s)
 }
 
@@ -0,0 +1,395 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// A synctestGroup is a group of goroutines started by synctest.Run.
+//
+// Goroutines started by a goroutine in the group join the group.
+// The group has its own fake clock, which advances only when every
+// goroutine in the group is durably blocked.
+type synctestGroup struct {
+	id      uint64 // unique id of the group, recorded by its channels
+	mu      mutex
+	timers  []*timer // heap of pending timers, ordered by when
+	now     int64    // current fake time, in nanoseconds since the Unix epoch
+	root    *g       // caller of synctest.Run
+	waiter  *g       // caller of synctest.Wait, while it is parked
+	waiting bool     // true if a goroutine is calling synctest.Wait
+
+	// The group is active (not blocked) so long as running > 0 || active > 0.
+	//
+	// running is the number of goroutines which are not durably blocked:
+	// goroutines which are running, runnable, or blocked for a reason
+	// that something outside the group may resolve, such as a system call.
+	//
+	// active keeps the group from becoming blocked even if all of its
+	// goroutines are. park_m may choose to resume a goroutine right
+	// after parking it, so it holds the group active until it knows
+	// whether the goroutine parked. A goroutine that has been woken
+	// because the group is blocked also holds it active until it runs.
+	total   int // total goroutines
+	running int // non-blocked goroutines
+	active  int // other sources of activity
+}
+
+// synctestGroupID is the id of the most recently created synctest group.
+var synctestGroupID atomic.Uint64
+
+// synctestEpoch is the initial time of the fake clock:
+// midnight UTC 2000-01-01, in nanoseconds since the Unix epoch.
+const synctestEpoch = 946684800000000000
+
+// changegstatus is called when the non-lock status of a g changes.
+// It is never called with a Gscanstatus.
+func (sg *synctestGroup) changegstatus(gp *g, oldval, newval uint32) {
+	totalDelta := 0
+	wasRunning := true
+	switch oldval {
+	case _Gdead:
+		wasRunning = false
+		totalDelta++
+	case _Gwaiting:
+		if gp.waitreason.isIdleInSynctest() {
+			wasRunning = false
+		}
+	}
+	isRunning := true
+	switch newval {
+	case _Gdead:
+		isRunning = false
+		totalDelta--
+	case _Gwaiting:
+		if gp.waitreason.isIdleInSynctest() {
+			isRunning = false
+		}
+	}
+	if wasRunning == isRunning && totalDelta == 0 {
+		return
+	}
+
+	lock(&sg.mu)
+	sg.total += totalDelta
+	if wasRunning != isRunning {
+		if isRunning {
+			sg.running++
+		} else {
+			sg.running--
+		}
+	}
+	if sg.running < 0 || sg.total < 0 {
+		throw("synctest: bad goroutine count")
+	}
+	unlock(&sg.mu)
+
+	if raceenabled && totalDelta == 0 {
+		// A goroutine that resumes after being durably blocked
+		// happens after everything the other goroutines in the
+		// group did before they blocked.
+		if isRunning {
+			raceacquireg(gp, sg.raceaddr())
+		} else {
+			racereleasemergeg(gp, sg.raceaddr())
+		}
+	}
+}
+
+// raceaddr returns an address used by the race detector to establish
+// happens-before relationships between goroutines of the group.
+func (sg *synctestGroup) raceaddr() unsafe.Pointer {
+	// Any address in the group will do.
+	return unsafe.Pointer(sg)
+}
+
+// incActive increments the active-count for the group.
+// A group does not become durably blocked while the active-count is non-zero.
+func (sg *synctestGroup) incActive() {
+	lock(&sg.mu)
+	sg.active++
+	unlock(&sg.mu)
+}
+
+// decActive decrements the active-count for the group,
+// and wakes the group's root or waiter if it has become blocked.
+func (sg *synctestGroup) decActive() {
+	lock(&sg.mu)
+	sg.active--
+	if sg.active < 0 {
+		throw("synctest: active < 0")
+	}
+	gp := sg.maybeWakeLocked()
+	unlock(&sg.mu)
+	if gp != nil {
+		goready(gp, 0)
+	}
+}
+
+// maybeWakeLocked returns the goroutine to wake if the group is blocked:
+// the caller of synctest.Wait if there is one, or else the root.
+// The woken goroutine holds the group active until it runs.
+func (sg *synctestGroup) maybeWakeLocked() *g {
+	if sg.running > 0 || sg.active > 0 {
+		return nil
+	}
+	sg.active++
+	if gp := sg.waiter; gp != nil {
+		return gp
+	}
+	return sg.root
+}
+
+//go:linkname synctestRun testing/synctest.Run
+func synctestRun(f func()) {
+	gp := getg()
+	if gp.syncGroup != nil {
+		panic(plainError("synctest.Run called from within a synctest bubble"))
+	}
+	sg := &synctestGroup{
+		id:      synctestGroupID.Add(1),
+		total:   1,
+		running: 1,
+		root:    gp,
+		now:     synctestEpoch,
+	}
+	lockInit(&sg.mu, lockRankSynctest)
+	gp.syncGroup = sg
+	defer func() {
+		gp.syncGroup = nil
+	}()
+
+	fv := *(**funcval)(unsafe.Pointer(&f))
+	newproc(fv)
+
+	for {
+		sg.runTimers()
+		gopark(synctestidle_c, unsafe.Pointer(sg), waitReasonSynctestRun, traceBlockSynctest, 0)
+
+		lock(&sg.mu)
+		sg.active--
+		if sg.total == 1 {
+			// Every goroutine in the group but the root has exited.
+			// Timers still pending in the group are discarded.
+			unlock(&sg.mu)
+			break
+		}
+		if len(sg.timers) == 0 {
+			unlock(&sg.mu)
+			panic(plainError("deadlock: all goroutines in bubble are blocked"))
+		}
+		if next := sg.timers[0].when; next > sg.now {
+			sg.now = next
+		}
+		unlock(&sg.mu)
+	}
+}
+
+// synctestidle_c is the unlock function for the root of a group
+// parking in synctest.Run. It declines to park if the group is
+// already blocked, since then there is nobody left to wake the root.
+func synctestidle_c(gp *g, _ unsafe.Pointer) bool {
+	sg := gp.syncGroup
+	lock(&sg.mu)
+	park := true
+	if sg.running == 0 && sg.active == 1 {
+		// We are the only active source of activity: the group is blocked.
+		sg.active++
+		park = false
+	}
+	unlock(&sg.mu)
+	return park
+}
+
+// runTimers runs the group's timers that are due at the current fake time.
+// It runs on the root goroutine, so that goroutines started by timer
+// functions, such as those of time.AfterFunc, join the group.
+func (sg *synctestGroup) runTimers() {
+	lock(&sg.mu)
+	for len(sg.timers) > 0 && sg.timers[0].when <= sg.now {
+		t := sg.timers[0]
+		if raceenabled {
+			raceacquire(unsafe.Pointer(t))
+		}
+		f := t.f
+		arg := t.arg
+		seq := t.seq
+		if t.period > 0 {
+			// Leave in heap but adjust next time to fire.
+			delta := t.when - sg.now
+			t.when += t.period * (1 + -delta/t.period)
+			if t.when < 0 { // check for overflow.
+				t.when = maxWhen
+			}
+			siftdownTimer(sg.timers, 0)
+		} else {
+			sg.deltimerLocked(0)
+			t.status = timerNoStatus
+		}
+		unlock(&sg.mu)
+		f(arg, seq)
+		lock(&sg.mu)
+	}
+	unlock(&sg.mu)
+}
+
+// addtimer adds t to the group's timer heap.
+func (sg *synctestGroup) addtimer(t *timer) {
+	lock(&sg.mu)
+	if t.status != timerNoStatus {
+		unlock(&sg.mu)
+		badTimer()
+	}
+	sg.addtimerLocked(t)
+	unlock(&sg.mu)
+}
+
+func (sg *synctestGroup) addtimerLocked(t *timer) {
+	// when must be positive, as in addtimer.
+	if t.when <= 0 {
+		throw("timer when must be positive")
+	}
+	if t.period < 0 {
+		throw("timer period must be non-negative")
+	}
+	t.status = timerWaiting
+	i := len(sg.timers)
+	sg.timers = append(sg.timers, t)
+	siftupTimer(sg.timers, i)
+}
+
+// deltimer removes t from the group's timer heap.
+// It reports whether t was removed before it ran.
+func (sg *synctestGroup) deltimer(t *timer) bool {
+	lock(&sg.mu)
+	pending := sg.removeLocked(t)
+	unlock(&sg.mu)
+	return pending
+}
+
+// modtimer modifies an existing timer of the group.
+// It reports whether the timer was modified before it ran.
+func (sg *synctestGroup) modtimer(t *timer, when, period int64, f func(any, uintptr), arg any, seq uintptr) bool {
+	lock(&sg.mu)
+	pending := sg.removeLocked(t)
+	t.when = when
+	t.period = period
+	t.f = f
+	t.arg = arg
+	t.seq = seq
+	sg.addtimerLocked(t)
+	unlock(&sg.mu)
+	return pending
+}
+
+// removeLocked removes t from the group's timer heap, if it is there,
+// and reports whether it was.
+func (sg *synctestGroup) removeLocked(t *timer) bool {
+	if t.status != timerWaiting {
+		return false
+	}
+	for i, tt := range sg.timers {
+		if tt == t {
+			sg.deltimerLocked(i)
+			break
+		}
+	}
+	t.status = timerNoStatus
+	return true
+}
+
+// deltimerLocked removes timer i from the group's timer heap.
+// It is like dodeltimer.
+func (sg *synctestGroup) deltimerLocked(i int) {
+	if sg.timers[i].status != timerWaiting {
+		badTimer()
+	}
+	last := len(sg.timers) - 1
+	if i != last {
+		sg.timers[i] = sg.timers[last]
+	}
+	sg.timers[last] = nil
+	sg.timers = sg.timers[:last]
+	if i != last {
+		// Moving to i may have moved the last timer to a new parent,
+		// so sift up to preserve the heap guarantee.
+		smallestChanged := siftupTimer(sg.timers, i)
+		siftdownTimer(sg.timers, smallestChanged)
+	}
+}
+
+//go:linkname synctestWait testing/synctest.Wait
+func synctestWait() {
+	gp := getg()
+	if gp.syncGroup == nil {
+		panic(plainError("goroutine is not in a bubble"))
+	}
+	sg := gp.syncGroup
+	lock(&sg.mu)
+	// waiter is only set once the caller parks, so use a separate
+	// flag to detect simultaneous calls to Wait.
+	if sg.waiting {
+		unlock(&sg.mu)
+		panic(plainError("wait already in progress"))
+	}
+	sg.waiting = true
+	unlock(&sg.mu)
+
+	// Park until the group is blocked.
+	gopark(synctestwait_c, nil, waitReasonSynctestWait, traceBlockSynctest, 0)
+
+	lock(&sg.mu)
+	sg.active--
+	if sg.waiter != gp {
+		throw("synctest: wrong waiter")
+	}
+	sg.waiter = nil
+	sg.waiting = false
+	unlock(&sg.mu)
+}
+
+// synctestwait_c is the unlock function for a goroutine parking in
+// synctest.Wait. It declines to park if the group is already blocked.
+func synctestwait_c(gp *g, _ unsafe.Pointer) bool {
+	sg := gp.syncGroup
+	lock(&sg.mu)
+	sg.waiter = gp
+	park := true
+	if sg.running == 0 && sg.active == 1 {
+		// The group is already blocked.
+		sg.active++
+		park = false
+	}
+	unlock(&sg.mu)
+	return park
+}
+
+// synctestNow returns the current time of sg's fake clock,
+// in nanoseconds since the Unix epoch.
+func synctestNow(sg *synctestGroup) int64 {
+	lock(&sg.mu)
+	now := sg.now
+	unlock(&sg.mu)
+	return now
+}
+
+//go:linkname time_runtimeNow time.runtimeNow
+func time_runtimeNow() (sec int64, nsec int32, mono int64) {
+	if sg := getg().syncGroup; sg != nil {
+		now := synctestNow(sg)
+		return now / 1e9, int32(now % 1e9), now
+	}
+	return time_now()
+}
+
+//go:linkname time_runtimeNano time.runtimeNano
+func time_runtimeNano() int64 {
+	if sg := getg().syncGroup; sg != nil {
+		return synctestNow(sg)
+	}
+	return nanotime()
+}
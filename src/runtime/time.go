@@ -37,6 +37,11 @@ type timer struct {
 
 	// The status field holds one of the values below.
 	status uint32
+
+	// If this timer was started by a goroutine in a synctest group,
+	// that group. Its when field is on the group's fake clock, and it
+	// is kept on the group's timer heap rather than on a P's.
+	syncGroup *synctestGroup
 }
 
 // Code outside this file has to be careful in using a timer value.
@@ -181,17 +186,22 @@ func timeSleep(ns int64) {
 
 	gp := getg()
 	t := gp.timer
-	if t == nil {
+	if t == nil || t.syncGroup != gp.syncGroup {
 		t = new(timer)
+		t.syncGroup = gp.syncGroup
 		gp.timer = t
 	}
 	t.f = goroutineReady
 	t.arg = gp
-	t.nextwhen = nanotime() + ns
+	if sg := gp.syncGroup; sg != nil {
+		t.nextwhen = synctestNow(sg) + ns
+	} else {
+		t.nextwhen = nanotime() + ns
+	}
 	if t.nextwhen < 0 { // check for overflow.
 		t.nextwhen = maxWhen
 	}
-	gopark(resetForSleep, unsafe.Pointer(t), waitReasonSleep, traceEvGoSleep, 1)
+	gopark(resetForSleep, unsafe.Pointer(t), waitReasonSleep, traceBlockSleep, 1)
 }
 
 // resetForSleep is called after the goroutine is parked for timeSleep.
@@ -200,16 +210,26 @@ func timeSleep(ns int64) {
 // timer function, goroutineReady, before the goroutine has been parked.
 func resetForSleep(gp *g, ut unsafe.Pointer) bool {
 	t := (*timer)(ut)
+	if t.syncGroup != nil {
+		t.syncGroup.modtimer(t, t.nextwhen, t.period, t.f, t.arg, t.seq)
+		return true
+	}
 	resettimer(t, t.nextwhen)
 	return true
 }
 
-// startTimer adds t to the timer heap.
+// startTimer adds t to the timer heap, or to the heap of the
+// calling goroutine's synctest group.
 //go:linkname startTimer time.startTimer
 func startTimer(t *timer) {
 	if raceenabled {
 		racerelease(unsafe.Pointer(t))
 	}
+	if sg := getg().syncGroup; sg != nil {
+		t.syncGroup = sg
+		sg.addtimer(t)
+		return
+	}
 	addtimer(t)
 }
 
@@ -217,6 +237,9 @@ func startTimer(t *timer) {
 // It reports whether t was stopped before being run.
 //go:linkname stopTimer time.stopTimer
 func stopTimer(t *timer) bool {
+	if t.syncGroup != nil {
+		return t.syncGroup.deltimer(t)
+	}
 	return deltimer(t)
 }
 
@@ -227,12 +250,19 @@ func resetTimer(t *timer, when int64) bool {
 	if raceenabled {
 		racerelease(unsafe.Pointer(t))
 	}
+	if t.syncGroup != nil {
+		return t.syncGroup.modtimer(t, when, t.period, t.f, t.arg, t.seq)
+	}
 	return resettimer(t, when)
 }
 
 // modTimer modifies an existing timer.
 //go:linkname modTimer time.modTimer
 func modTimer(t *timer, when, period int64, f func(any, uintptr), arg any, seq uintptr) {
+	if t.syncGroup != nil {
+		t.syncGroup.modtimer(t, when, period, f, arg, seq)
+		return
+	}
 	modtimer(t, when, period, f, arg, seq)
 }
 
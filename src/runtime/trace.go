@@ -4,11 +4,24 @@
 
 // Go execution tracer.
 // The tracer captures a wide range of execution events like goroutine
-// creation/blocking/unblocking, syscall enter/exit/block, GC-related events,
+// creation/blocking/unblocking, syscall enter/exit, GC-related events,
 // changes of heap size, processor start/stop, etc and writes them to a buffer
 // in a compact form. A precise nanosecond-precision timestamp and a stack
 // trace is captured for most events.
-// See https://golang.org/s/go15trace for more info.
+//
+// The trace is split into generations. Each generation is self-contained:
+// it starts with the state of all Ps and goroutines, and ends with the
+// timer frequency and the stacks and strings used by its events, so that
+// a generation can be decoded without any of the data before it. The
+// runtime starts a new generation periodically (see traceAdvance).
+//
+// Events are written into per-M buffers, each of which holds a batch of
+// events of one M in the order they happened. A batch is prefixed with
+// the generation, the M, a timestamp and the length of the batch.
+// Events that synchronize with events on other Ms carry a sequence
+// number of the P or goroutine they act on, so that a reader can order
+// the batches of a generation without sorting the whole trace.
+// See debug/trace for a reader.
 
 package runtime
 
@@ -20,60 +33,48 @@ import (
 )
 
 // Event types in the trace, args are given in square brackets.
+// The timestamp of an event is the difference from the timestamp of
+// the previous event in the same batch.
 const (
 	traceEvNone              = 0  // unused
-	traceEvBatch             = 1  // start of per-P batch of events [pid, timestamp]
-	traceEvFrequency         = 2  // contains tracer timer frequency [frequency (ticks per second)]
-	traceEvStack             = 3  // stack [stack id, number of PCs, array of {PC, func string ID, file string ID, line}]
-	traceEvGomaxprocs        = 4  // current value of GOMAXPROCS [timestamp, GOMAXPROCS, stack id]
-	traceEvProcStart         = 5  // start of P [timestamp, thread id]
-	traceEvProcStop          = 6  // stop of P [timestamp]
-	traceEvGCStart           = 7  // GC start [timestamp, seq, stack id]
-	traceEvGCDone            = 8  // GC done [timestamp]
-	traceEvGCSTWStart        = 9  // GC STW start [timestamp, kind]
-	traceEvGCSTWDone         = 10 // GC STW done [timestamp]
-	traceEvGCSweepStart      = 11 // GC sweep start [timestamp, stack id]
-	traceEvGCSweepDone       = 12 // GC sweep done [timestamp, swept, reclaimed]
-	traceEvGoCreate          = 13 // goroutine creation [timestamp, new goroutine id, new stack id, stack id]
-	traceEvGoStart           = 14 // goroutine starts running [timestamp, goroutine id, seq]
-	traceEvGoEnd             = 15 // goroutine ends [timestamp]
-	traceEvGoStop            = 16 // goroutine stops (like in select{}) [timestamp, stack]
-	traceEvGoSched           = 17 // goroutine calls Gosched [timestamp, stack]
-	traceEvGoPreempt         = 18 // goroutine is preempted [timestamp, stack]
-	traceEvGoSleep           = 19 // goroutine calls Sleep [timestamp, stack]
-	traceEvGoBlock           = 20 // goroutine blocks [timestamp, stack]
-	traceEvGoUnblock         = 21 // goroutine is unblocked [timestamp, goroutine id, seq, stack]
-	traceEvGoBlockSend       = 22 // goroutine blocks on chan send [timestamp, stack]
-	traceEvGoBlockRecv       = 23 // goroutine blocks on chan recv [timestamp, stack]
-	traceEvGoBlockSelect     = 24 // goroutine blocks on select [timestamp, stack]
-	traceEvGoBlockSync       = 25 // goroutine blocks on Mutex/RWMutex [timestamp, stack]
-	traceEvGoBlockCond       = 26 // goroutine blocks on Cond [timestamp, stack]
-	traceEvGoBlockNet        = 27 // goroutine blocks on network [timestamp, stack]
-	traceEvGoSysCall         = 28 // syscall enter [timestamp, stack]
-	traceEvGoSysExit         = 29 // syscall exit [timestamp, goroutine id, seq, real timestamp]
-	traceEvGoSysBlock        = 30 // syscall blocks [timestamp]
-	traceEvGoWaiting         = 31 // denotes that goroutine is blocked when tracing starts [timestamp, goroutine id]
-	traceEvGoInSyscall       = 32 // denotes that goroutine is in syscall when tracing starts [timestamp, goroutine id]
-	traceEvHeapAlloc         = 33 // gcController.heapLive change [timestamp, heap_alloc]
-	traceEvHeapGoal          = 34 // gcController.heapGoal (formerly next_gc) change [timestamp, heap goal in bytes]
-	traceEvTimerGoroutine    = 35 // not currently used; previously denoted timer goroutine [timer goroutine id]
-	traceEvFutileWakeup      = 36 // denotes that the previous wakeup of this goroutine was futile [timestamp]
-	traceEvString            = 37 // string dictionary entry [ID, length, string]
-	traceEvGoStartLocal      = 38 // goroutine starts running on the same P as the last event [timestamp, goroutine id]
-	traceEvGoUnblockLocal    = 39 // goroutine is unblocked on the same P as the last event [timestamp, goroutine id, stack]
-	traceEvGoSysExitLocal    = 40 // syscall exit on the same P as the last event [timestamp, goroutine id, real timestamp]
-	traceEvGoStartLabel      = 41 // goroutine starts running with label [timestamp, goroutine id, seq, label string id]
-	traceEvGoBlockGC         = 42 // goroutine blocks on GC assist [timestamp, stack]
-	traceEvGCMarkAssistStart = 43 // GC mark assist start [timestamp, stack]
-	traceEvGCMarkAssistDone  = 44 // GC mark assist done [timestamp]
-	traceEvUserTaskCreate    = 45 // trace.NewContext [timestamp, internal task id, internal parent task id, stack, name string]
-	traceEvUserTaskEnd       = 46 // end of a task [timestamp, internal task id, stack]
-	traceEvUserRegion        = 47 // trace.WithRegion [timestamp, internal task id, mode(0:start, 1:end), stack, name string]
-	traceEvUserLog           = 48 // trace.Log [timestamp, internal task id, key string id, stack, value string]
-	traceEvCount             = 49
-	// Byte is used but only 6 bits are available for event type.
-	// The remaining 2 bits are used to specify the number of arguments.
-	// That means, the max event type value is 63.
+	traceEvEventBatch        = 1  // start of a batch of events [generation, M id, timestamp, batch length]
+	traceEvFrequency         = 2  // tracer timer frequency [frequency (ticks per second)]
+	traceEvStack             = 3  // stack [stack id, number of frames, array of {PC, func string ID, file string ID, line}]
+	traceEvString            = 4  // string dictionary entry [string ID, length, string]
+	traceEvProcsChange       = 5  // current value of GOMAXPROCS [timestamp, GOMAXPROCS, stack ID]
+	traceEvProcStart         = 6  // start of P [timestamp, P ID, P seq]
+	traceEvProcStop          = 7  // stop of P [timestamp]
+	traceEvProcSteal         = 8  // P was taken away from an M in a syscall [timestamp, P ID, P seq, M ID]
+	traceEvProcStatus        = 9  // P status at the start of a generation [timestamp, P ID, status]
+	traceEvGoCreate          = 10 // goroutine creation [timestamp, new goroutine ID, new stack ID, stack ID]
+	traceEvGoCreateSyscall   = 11 // goroutine appears in a syscall, from a cgo callback [timestamp, new goroutine ID, new stack ID]
+	traceEvGoStart           = 12 // goroutine starts running [timestamp, goroutine ID, goroutine seq]
+	traceEvGoDestroy         = 13 // goroutine ends [timestamp]
+	traceEvGoDestroySyscall  = 14 // goroutine ends in a syscall, returning from a cgo callback [timestamp]
+	traceEvGoStop            = 15 // goroutine yields its time, but is runnable [timestamp, reason string ID, stack ID]
+	traceEvGoBlock           = 16 // goroutine blocks [timestamp, reason string ID, stack ID]
+	traceEvGoUnblock         = 17 // goroutine is unblocked [timestamp, goroutine ID, goroutine seq, stack ID]
+	traceEvGoSyscallBegin    = 18 // syscall enter [timestamp, P seq, stack ID]
+	traceEvGoSyscallEnd      = 19 // syscall exit, on the P of the syscall [timestamp]
+	traceEvGoSyscallEndBlock = 20 // syscall exit, after the P was taken away [timestamp]
+	traceEvGoStatus          = 21 // goroutine status at the start of a generation [timestamp, goroutine ID, M ID, status, start stack ID]
+	traceEvSTWBegin          = 22 // stop-the-world start [timestamp, kind string ID]
+	traceEvSTWEnd            = 23 // stop-the-world done [timestamp]
+	traceEvGCBegin           = 24 // GC start [timestamp, GC seq, stack ID]
+	traceEvGCEnd             = 25 // GC done [timestamp, GC seq]
+	traceEvGCSweepBegin      = 26 // GC sweep start [timestamp, stack ID]
+	traceEvGCSweepEnd        = 27 // GC sweep done [timestamp, swept bytes, reclaimed bytes]
+	traceEvGCMarkAssistBegin = 28 // GC mark assist start [timestamp, stack ID]
+	traceEvGCMarkAssistEnd   = 29 // GC mark assist done [timestamp]
+	traceEvHeapAlloc         = 30 // gcController.heapLive change [timestamp, heap alloc in bytes]
+	traceEvHeapGoal          = 31 // gcController.heapGoal change [timestamp, heap goal in bytes]
+	traceEvGoLabel           = 32 // label of the goroutine that just started [timestamp, label string ID]
+	traceEvUserTaskBegin     = 33 // trace.NewTask [timestamp, internal task ID, internal parent task ID, name string ID, stack ID]
+	traceEvUserTaskEnd       = 34 // end of a task [timestamp, internal task ID, stack ID]
+	traceEvUserRegionBegin   = 35 // trace.{Start,With}Region [timestamp, internal task ID, name string ID, stack ID]
+	traceEvUserRegionEnd     = 36 // trace.{End,With}Region [timestamp, internal task ID, name string ID, stack ID]
+	traceEvUserLog           = 37 // trace.Log [timestamp, internal task ID, key string ID, stack ID, value string]
+	traceEvCount             = 38
 )
 
 const (
@@ -91,21 +92,94 @@ const (
 	// Since events contain only stack id rather than whole stack trace,
 	// we can allow quite large values here.
 	traceStackSize = 128
-	// Identifier of a fake P that is used when we trace without a real P.
-	traceGlobProc = -1
+	// M ID of the batches that hold the frequency, stacks and strings
+	// of a generation, rather than the events of an M.
+	traceNoM = -1
 	// Maximum number of bytes to encode uint64 in base-128.
 	traceBytesPerNumber = 10
-	// Shift of the number of arguments in the first event byte.
-	traceArgCountShift = 6
-	// Flag passed to traceGoPark to denote that the previous wakeup of this
-	// goroutine was futile. For example, a goroutine was unblocked on a mutex,
-	// but another goroutine got ahead and acquired the mutex before the first
-	// goroutine is scheduled, so the first goroutine has to block again.
-	// Such wakeups happen on buffered channels and sync.Mutex,
-	// but are generally not interesting for end user.
-	traceFutileWakeup byte = 128
+	// How often the runtime starts a new generation.
+	traceAdvancePeriod = 1e9
+)
+
+// Goroutine statuses in traceEvGoStatus.
+const (
+	traceGoRunnable = 1
+	traceGoRunning  = 2
+	traceGoSyscall  = 3
+	traceGoWaiting  = 4
+)
+
+// P statuses in traceEvProcStatus.
+const (
+	traceProcRunning = 1
+	traceProcIdle    = 2
 )
 
+// traceBlockReason is the reason a goroutine blocks, recorded in
+// traceEvGoBlock.
+type traceBlockReason uint8
+
+const (
+	traceBlockGeneric traceBlockReason = iota
+	traceBlockForever
+	traceBlockNet
+	traceBlockSelect
+	traceBlockCondWait
+	traceBlockSync
+	traceBlockChanSend
+	traceBlockChanRecv
+	traceBlockGCMarkAssist
+	traceBlockGCSweep
+	traceBlockSystemGoroutine
+	traceBlockPreempted
+	traceBlockDebugCall
+	traceBlockUntilGCEnds
+	traceBlockSleep
+	traceBlockSynctest
+)
+
+var traceBlockReasonStrings = [...]string{
+	traceBlockGeneric:         "unspecified",
+	traceBlockForever:         "forever",
+	traceBlockNet:             "network",
+	traceBlockSelect:          "select",
+	traceBlockCondWait:        "sync.(*Cond).Wait",
+	traceBlockSync:            "sync",
+	traceBlockChanSend:        "chan send",
+	traceBlockChanRecv:        "chan receive",
+	traceBlockGCMarkAssist:    "GC mark assist wait for work",
+	traceBlockGCSweep:         "GC background sweeper wait",
+	traceBlockSystemGoroutine: "system goroutine wait",
+	traceBlockPreempted:       "preempted",
+	traceBlockDebugCall:       "wait for debug call",
+	traceBlockUntilGCEnds:     "wait until GC ends",
+	traceBlockSleep:           "sleep",
+	traceBlockSynctest:        "synctest",
+}
+
+// traceGoStopReason is the reason a goroutine stops while remaining
+// runnable, recorded in traceEvGoStop.
+type traceGoStopReason uint8
+
+const (
+	traceGoStopGeneric traceGoStopReason = iota
+	traceGoStopGoSched
+	traceGoStopPreempted
+)
+
+var traceGoStopReasonStrings = [...]string{
+	traceGoStopGeneric:   "unspecified",
+	traceGoStopGoSched:   "runtime.Gosched",
+	traceGoStopPreempted: "preempted",
+}
+
+// traceSTWKindStrings are the kinds of stop-the-world recorded in
+// traceEvSTWBegin, indexed by the kind passed to traceGCSTWStart.
+var traceSTWKindStrings = [...]string{
+	0: "GC mark termination",
+	1: "GC sweep termination",
+}
+
 // trace is global tracing context.
 var trace struct {
 	lock          mutex       // protects the following members
@@ -113,13 +187,10 @@ var trace struct {
 	enabled       bool        // when set runtime traces events
 	shutdown      bool        // set when we are waiting for trace reader to finish after setting enabled to false
 	headerWritten bool        // whether ReadTrace has emitted trace header
-	footerWritten bool        // whether ReadTrace has emitted trace footer
 	shutdownSema  uint32      // used to wait for ReadTrace completion
-	seqStart      uint64      // sequence number when tracing was started
 	ticksStart    int64       // cputicks when tracing was started
-	ticksEnd      int64       // cputicks when tracing was stopped
 	timeStart     int64       // nanotime when tracing was started
-	timeEnd       int64       // nanotime when tracing was stopped
+	gen           uint64      // current generation
 	seqGC         uint64      // GC start/done sequencer
 	reading       traceBufPtr // buffer currently handed off to user
 	empty         traceBufPtr // stack of empty buffers
@@ -128,8 +199,24 @@ var trace struct {
 	reader        guintptr        // goroutine that called ReadTrace, or nil
 	stackTab      traceStackTable // maps stack traces to unique ids
 
+	// advancerNote wakes up the goroutine that periodically starts
+	// new generations when tracing stops, and advancerSema is used
+	// to wait for it to exit.
+	advancerNote note
+	advancerSema uint32
+
+	// gated is set while StartTrace may be writing the initial state
+	// of goroutines and until tracing stops. While it is set, Ms
+	// without a P change the status of goroutines under bufLock, so
+	// that the status of every goroutine in the trace is consistent
+	// with the events of the goroutine. See traceAcquireStatus.
+	gated uint32
+
 	// Dictionary for traceEvString.
 	//
+	// The dictionary is written out and reset at the end of each
+	// generation.
+	//
 	// TODO: central lock to access the map is not ideal.
 	//   option: pre-assign ids to all user annotation region names and tags
 	//   option: per-P cache
@@ -138,22 +225,27 @@ var trace struct {
 	strings     map[string]uint64
 	stringSeq   uint64
 
-	// markWorkerLabels maps gcMarkWorkerMode to string ID.
+	// String IDs of the labels and reasons of events, registered at
+	// the start of each generation.
 	markWorkerLabels [len(gcMarkWorkerModeStrings)]uint64
+	goBlockReasons   [len(traceBlockReasonStrings)]uint64
+	goStopReasons    [len(traceGoStopReasonStrings)]uint64
+	stwKinds         [len(traceSTWKindStrings)]uint64
 
-	bufLock mutex       // protects buf
-	buf     traceBufPtr // global trace buffer, used when running without a p
+	bufLock mutex // protects the trace buffers of Ms without a P
 }
 
-// traceBufHeader is per-P tracing buffer.
+// traceBufHeader is per-M tracing buffer.
 type traceBufHeader struct {
 	link      traceBufPtr             // in trace.empty/full
 	lastTicks uint64                  // when we wrote the last event
 	pos       int                     // next write offset in arr
+	gen       uint64                  // generation of the batch in the buffer
+	lenPos    int                     // offset of the batch length in arr
 	stk       [traceStackSize]uintptr // scratch buffer for traceback
 }
 
-// traceBuf is per-P tracing buffer.
+// traceBuf is per-M tracing buffer.
 //
 //go:notinheap
 type traceBuf struct {
@@ -192,68 +284,29 @@ func StartTrace() error {
 	// Prevent sysmon from running any code that could generate events.
 	lock(&sched.sysmonlock)
 
-	// We are in stop-the-world, but syscalls can finish and write to trace concurrently.
-	// Exitsyscall could check trace.enabled long before and then suddenly wake up
-	// and decide to write to trace at a random point in time.
-	// However, such syscall will use the global trace.buf buffer, because we've
-	// acquired all p's by doing stop-the-world. So this protects us from such races.
-	lock(&trace.bufLock)
-
 	if trace.enabled || trace.shutdown {
-		unlock(&trace.bufLock)
 		unlock(&sched.sysmonlock)
 		startTheWorldGC()
 		return errorString("tracing is already enabled")
 	}
 
-	// Can't set trace.enabled yet. While the world is stopped, exitsyscall could
-	// already emit a delayed event (see exitTicks in exitsyscall) if we set trace.enabled here.
-	// That would lead to an inconsistent trace:
-	// - either GoSysExit appears before EvGoInSyscall,
-	// - or GoSysExit appears for a goroutine for which we don't emit EvGoInSyscall below.
-	// To instruct traceEvent that it must not ignore events below, we set startingtrace.
-	// trace.enabled is set afterwards once we have emitted all preliminary events.
-	_g_ := getg()
-	_g_.m.startingtrace = true
-
-	// Obtain current stack ID to use in all traceEvGoCreate events below.
-	mp := acquirem()
-	stkBuf := make([]uintptr, traceStackSize)
-	stackID := traceStackID(mp, stkBuf, 2)
-	releasem(mp)
-
-	// World is stopped, no need to lock.
-	forEachGRace(func(gp *g) {
-		status := readgstatus(gp)
-		if status != _Gdead {
-			gp.traceseq = 0
-			gp.tracelastp = getg().m.p
-			// +PCQuantum because traceFrameForPC expects return PCs and subtracts PCQuantum.
-			id := trace.stackTab.put([]uintptr{gp.startpc + sys.PCQuantum})
-			traceEvent(traceEvGoCreate, -1, uint64(gp.goid), uint64(id), stackID)
+	// We are in stop-the-world, but Ms without a P, such as those
+	// returning from syscalls, keep running and change the status of
+	// goroutines. Once gated is set, they do so under trace.bufLock,
+	// so wait for those that did not see it yet, and then acquire
+	// trace.bufLock to keep them from changing the status of goroutines
+	// while we write it out.
+	atomic.Store(&trace.gated, 1)
+	for mp := allm; mp != nil; mp = mp.alllink {
+		for atomic.Load(&mp.traceStatusBusy) != 0 {
+			osyield()
 		}
-		if status == _Gwaiting {
-			// traceEvGoWaiting is implied to have seq=1.
-			gp.traceseq++
-			traceEvent(traceEvGoWaiting, -1, uint64(gp.goid))
-		}
-		if status == _Gsyscall {
-			gp.traceseq++
-			traceEvent(traceEvGoInSyscall, -1, uint64(gp.goid))
-		} else {
-			gp.sysblocktraced = false
-		}
-	})
-	traceProcStart()
-	traceGoStart()
-	// Note: ticksStart needs to be set after we emit traceEvGoInSyscall events.
-	// If we do it the other way around, it is possible that exitsyscall will
-	// query sysexitticks after ticksStart but before traceEvGoInSyscall timestamp.
-	// It will lead to a false conclusion that cputicks is broken.
+	}
+	lock(&trace.bufLock)
+
 	trace.ticksStart = cputicks()
 	trace.timeStart = nanotime()
 	trace.headerWritten = false
-	trace.footerWritten = false
 
 	// string to id mapping
 	//  0 : reserved for an empty string
@@ -261,29 +314,26 @@ func StartTrace() error {
 	trace.stringSeq = 0
 	trace.strings = make(map[string]uint64)
 
+	trace.gen = 1
 	trace.seqGC = 0
-	_g_.m.startingtrace = false
+	traceWriteStatuses()
+	traceRegisterStrings()
 	trace.enabled = true
 
-	// Register runtime goroutine labels.
-	_, pid, bufp := traceAcquireBuffer()
-	for i, label := range gcMarkWorkerModeStrings[:] {
-		trace.markWorkerLabels[i], bufp = traceString(bufp, pid, label)
-	}
-	traceReleaseBuffer(pid)
-
 	unlock(&trace.bufLock)
 
 	unlock(&sched.sysmonlock)
 
 	startTheWorldGC()
+
+	go traceAdvancer()
 	return nil
 }
 
 // StopTrace stops tracing, if it was previously enabled.
 // StopTrace only returns after all the reads for the trace have completed.
 func StopTrace() {
-	// Stop the world so that we can collect the trace buffers from all p's below,
+	// Stop the world so that we can collect the trace buffers from all Ms below,
 	// and also to avoid races with traceEvent.
 	stopTheWorldGC("stop tracing")
 
@@ -300,43 +350,22 @@ func StopTrace() {
 		return
 	}
 
-	traceGoSched()
-
-	// Loop over all allocated Ps because dead Ps may still have
-	// trace buffers.
-	for _, p := range allp[:cap(allp)] {
-		buf := p.tracebuf
-		if buf != 0 {
-			traceFullQueue(buf)
-			p.tracebuf = 0
-		}
-	}
-	if trace.buf != 0 {
-		buf := trace.buf
-		trace.buf = 0
-		if buf.ptr().pos != 0 {
-			traceFullQueue(buf)
-		}
-	}
-
-	for {
-		trace.ticksEnd = cputicks()
-		trace.timeEnd = nanotime()
-		// Windows time can tick only every 15ms, wait for at least one tick.
-		if trace.timeEnd != trace.timeStart {
-			break
-		}
-		osyield()
-	}
+	traceFinishGeneration()
 
 	trace.enabled = false
 	trace.shutdown = true
+	atomic.Store(&trace.gated, 0)
 	unlock(&trace.bufLock)
 
 	unlock(&sched.sysmonlock)
 
 	startTheWorldGC()
 
+	// Wait for the goroutine that starts new generations to exit.
+	notewakeup(&trace.advancerNote)
+	semacquire(&trace.advancerSema)
+	noteclear(&trace.advancerNote)
+
 	// The world is started but we've set trace.shutdown, so new tracing can't start.
 	// Wait for the trace reader to flush pending buffers and stop.
 	semacquire(&trace.shutdownSema)
@@ -346,14 +375,11 @@ func StopTrace() {
 
 	// The lock protects us from races with StartTrace/StopTrace because they do stop-the-world.
 	lock(&trace.lock)
-	for _, p := range allp[:cap(allp)] {
-		if p.tracebuf != 0 {
-			throw("trace: non-empty trace buffer in proc")
+	for mp := allm; mp != nil; mp = mp.alllink {
+		if mp.tracebuf != 0 {
+			throw("trace: non-empty trace buffer in M")
 		}
 	}
-	if trace.buf != 0 {
-		throw("trace: non-empty global trace buffer")
-	}
 	if trace.fullHead != 0 || trace.fullTail != 0 {
 		throw("trace: non-empty full trace buffer")
 	}
@@ -370,12 +396,191 @@ func StopTrace() {
 	unlock(&trace.lock)
 }
 
+// traceAdvancer starts a new generation of the trace every
+// traceAdvancePeriod, until tracing stops.
+func traceAdvancer() {
+	for !notetsleepg(&trace.advancerNote, traceAdvancePeriod) {
+		traceAdvance()
+	}
+	semrelease(&trace.advancerSema)
+}
+
+// traceAdvance ends the current generation of the trace and starts a
+// new one, from which the rest of the trace can be decoded without any
+// of the data before it. It returns the new generation, which ReadTrace
+// reports along with the buffers of the generation, or 0 if tracing is
+// not enabled.
+func traceAdvance() uint64 {
+	// Stop the world for a consistent snapshot, and not during GC,
+	// as in StartTrace, so that a GC is never split by a generation.
+	stopTheWorldGC("trace advance")
+
+	// See the comment in StartTrace.
+	lock(&sched.sysmonlock)
+
+	// See the comment in StartTrace.
+	lock(&trace.bufLock)
+
+	if !trace.enabled || trace.shutdown {
+		unlock(&trace.bufLock)
+		unlock(&sched.sysmonlock)
+		startTheWorldGC()
+		return 0
+	}
+
+	traceFinishGeneration()
+
+	trace.gen++
+	trace.seqGC = 0
+	// Write the statuses first, because registering the strings may
+	// allocate, which emits events that must follow them.
+	traceWriteStatuses()
+	traceRegisterStrings()
+
+	// Queue the start of the generation right away, so that readers
+	// see it without waiting for the buffer to fill up.
+	mp := acquirem()
+	lock(&trace.lock)
+	traceFullQueue(mp.tracebuf)
+	unlock(&trace.lock)
+	mp.tracebuf = 0
+	releasem(mp)
+	gen := trace.gen
+
+	unlock(&trace.bufLock)
+
+	unlock(&sched.sysmonlock)
+
+	startTheWorldGC()
+	return gen
+}
+
+// traceFinishGeneration writes out the rest of the current generation:
+// the timer frequency, the stacks and strings used by its events, and
+// the trace buffers of all Ms. The world must be stopped and
+// trace.bufLock held.
+func traceFinishGeneration() {
+	ticks, now := cputicks(), nanotime()
+	for now == trace.timeStart {
+		// Windows time can tick only every 15ms, wait for at least one tick.
+		osyield()
+		ticks, now = cputicks(), nanotime()
+	}
+	bufp := traceFlush(0, traceNoM)
+	buf := bufp.ptr()
+	buf.byte(traceEvFrequency)
+	buf.varint(traceFrequency(ticks, now))
+	lock(&trace.lock)
+	traceFullQueue(bufp)
+	unlock(&trace.lock)
+
+	// Writing the stacks adds the strings of their frames.
+	trace.stackTab.dump()
+	traceDumpStrings()
+
+	// Writing the tables may emit events on this M, so flush the
+	// buffers last.
+	lock(&trace.lock)
+	for mp := allm; mp != nil; mp = mp.alllink {
+		if mp.tracebuf != 0 {
+			traceFullQueue(mp.tracebuf)
+			mp.tracebuf = 0
+		}
+	}
+	unlock(&trace.lock)
+}
+
+// traceRegisterStrings registers the strings of the labels and reasons
+// of events in a new generation.
+func traceRegisterStrings() {
+	for i, label := range gcMarkWorkerModeStrings[:] {
+		trace.markWorkerLabels[i] = traceString(label)
+	}
+	for i, reason := range traceBlockReasonStrings[:] {
+		trace.goBlockReasons[i] = traceString(reason)
+	}
+	for i, reason := range traceGoStopReasonStrings[:] {
+		trace.goStopReasons[i] = traceString(reason)
+	}
+	for i, kind := range traceSTWKindStrings[:] {
+		trace.stwKinds[i] = traceString(kind)
+	}
+}
+
+// traceWriteStatuses writes the value of GOMAXPROCS and the status of
+// all Ps and goroutines at the start of a generation, and resets their
+// sequence numbers. The world must be stopped and trace.bufLock held.
+func traceWriteStatuses() {
+	mp := acquirem()
+	bufp := &mp.tracebuf
+	traceEventLocked(0, mp, bufp, traceEvProcsChange, 0, uint64(gomaxprocs))
+	// Loop over all allocated Ps because dead Ps may come back.
+	for _, pp := range allp[:cap(allp)] {
+		pp.traceseq = 0
+	}
+	for _, pp := range allp {
+		status := uint64(traceProcIdle)
+		if pp == mp.p.ptr() {
+			status = traceProcRunning
+		}
+		traceEventLocked(0, mp, bufp, traceEvProcStatus, -1, uint64(pp.id), status)
+	}
+	// Loop over allgs without forEachGRace, because the closure would
+	// be heap allocated, which emits events in the middle of the
+	// statuses.
+	ptr, n := atomicAllG()
+	for i := uintptr(0); i < n; i++ {
+		gp := atomicAllGIndex(ptr, i)
+		gp.traceseq = 0
+		var status uint64
+		mid := int64(traceNoM)
+		switch readgstatus(gp) &^ _Gscan {
+		case _Grunnable:
+			status = traceGoRunnable
+		case _Grunning:
+			status = traceGoRunning
+			mid = gp.m.id
+		case _Gsyscall:
+			status = traceGoSyscall
+			mid = gp.m.id
+		case _Gwaiting, _Gpreempted:
+			status = traceGoWaiting
+		default:
+			// The goroutine does not exist yet or any more.
+			continue
+		}
+		// +PCQuantum because traceFrameForPC expects return PCs and subtracts PCQuantum.
+		id := trace.stackTab.put([]uintptr{gp.startpc + sys.PCQuantum})
+		traceEventLocked(0, mp, bufp, traceEvGoStatus, -1, uint64(gp.goid), uint64(mid), status, uint64(id))
+	}
+	releasem(mp)
+}
+
+// traceFrequency returns the timer frequency in ticks per second,
+// measured from the start of the trace to the given cputicks and
+// nanotime.
+func traceFrequency(ticks, now int64) uint64 {
+	// Use float64 because (ticks - trace.ticksStart) * 1e9 can overflow int64.
+	freq := float64(ticks-trace.ticksStart) * 1e9 / float64(now-trace.timeStart) / traceTickDiv
+	if freq <= 0 {
+		throw("trace: invalid frequency")
+	}
+	return uint64(freq)
+}
+
 // ReadTrace returns the next chunk of binary tracing data, blocking until data
 // is available. If tracing is turned off and all the data accumulated while it
 // was on has been returned, ReadTrace returns nil. The caller must copy the
 // returned data before calling ReadTrace again.
 // ReadTrace must be called from one goroutine at a time.
 func ReadTrace() []byte {
+	data, _ := readTrace0()
+	return data
+}
+
+// readTrace0 is ReadTrace that also returns the generation of the
+// data, or 0 for the trace header.
+func readTrace0() (data []byte, gen uint64) {
 	// This function may need to lock trace.lock recursively
 	// (goparkunlock -> traceGoPark -> traceEvent -> traceFlush).
 	// To allow this we use trace.lockOwner.
@@ -392,7 +597,7 @@ func ReadTrace() []byte {
 		trace.lockOwner = nil
 		unlock(&trace.lock)
 		println("runtime: ReadTrace called from multiple goroutines simultaneously")
-		return nil
+		return nil, 0
 	}
 	// Recycle the old buffer.
 	if buf := trace.reading; buf != 0 {
@@ -405,12 +610,12 @@ func ReadTrace() []byte {
 		trace.headerWritten = true
 		trace.lockOwner = nil
 		unlock(&trace.lock)
-		return []byte("go 1.11 trace\x00\x00\x00")
+		return []byte("go 1.18 trace\x00\x00\x00"), 0
 	}
 	// Wait for new data.
 	if trace.fullHead == 0 && !trace.shutdown {
 		trace.reader.set(getg())
-		goparkunlock(&trace.lock, waitReasonTraceReaderBlocked, traceEvGoBlock, 2)
+		goparkunlock(&trace.lock, waitReasonTraceReaderBlocked, traceBlockGeneric, 2)
 		lock(&trace.lock)
 	}
 	// Write a buffer.
@@ -419,25 +624,7 @@ func ReadTrace() []byte {
 		trace.reading = buf
 		trace.lockOwner = nil
 		unlock(&trace.lock)
-		return buf.ptr().arr[:buf.ptr().pos]
-	}
-	// Write footer with timer frequency.
-	if !trace.footerWritten {
-		trace.footerWritten = true
-		// Use float64 because (trace.ticksEnd - trace.ticksStart) * 1e9 can overflow int64.
-		freq := float64(trace.ticksEnd-trace.ticksStart) * 1e9 / float64(trace.timeEnd-trace.timeStart) / traceTickDiv
-		if freq <= 0 {
-			throw("trace: ReadTrace got invalid frequency")
-		}
-		trace.lockOwner = nil
-		unlock(&trace.lock)
-		var data []byte
-		data = append(data, traceEvFrequency|0<<traceArgCountShift)
-		data = traceAppend(data, uint64(freq))
-		// This will emit a bunch of full buffers, we will pick them up
-		// on the next iteration.
-		trace.stackTab.dump()
-		return data
+		return buf.ptr().arr[:buf.ptr().pos], buf.ptr().gen
 	}
 	// Done.
 	if trace.shutdown {
@@ -451,13 +638,13 @@ func ReadTrace() []byte {
 		}
 		// trace.enabled is already reset, so can call traceable functions.
 		semrelease(&trace.shutdownSema)
-		return nil
+		return nil, 0
 	}
 	// Also bad, but see the comment above.
 	trace.lockOwner = nil
 	unlock(&trace.lock)
 	println("runtime: spurious wakeup of trace reader")
-	return nil
+	return nil, 0
 }
 
 // traceReader returns the trace reader that should be woken up, if any.
@@ -476,21 +663,25 @@ func traceReader() *g {
 	return gp
 }
 
-// traceProcFree frees trace buffer associated with pp.
-func traceProcFree(pp *p) {
-	buf := pp.tracebuf
-	pp.tracebuf = 0
-	if buf == 0 {
-		return
+// traceThreadDestroy queues the trace buffer of mp, which is exiting.
+// mp must not have a P, and must have been removed from allm.
+func traceThreadDestroy(mp *m) {
+	lock(&trace.bufLock)
+	if buf := mp.tracebuf; buf != 0 {
+		mp.tracebuf = 0
+		lock(&trace.lock)
+		traceFullQueue(buf)
+		unlock(&trace.lock)
 	}
-	lock(&trace.lock)
-	traceFullQueue(buf)
-	unlock(&trace.lock)
+	unlock(&trace.bufLock)
 }
 
-// traceFullQueue queues buf into queue of full buffers.
+// traceFullQueue fills in the length of the batch in buf and queues
+// buf into queue of full buffers.
 func traceFullQueue(buf traceBufPtr) {
-	buf.ptr().link = 0
+	b := buf.ptr()
+	b.varintAt(b.lenPos, uint64(b.pos-(b.lenPos+traceBytesPerNumber)))
+	b.link = 0
 	if trace.fullHead == 0 {
 		trace.fullHead = buf
 	} else {
@@ -519,7 +710,7 @@ func traceFullDequeue() traceBufPtr {
 // If skip = 0, this event type should contain a stack, but we don't want
 // to collect and remember it for this particular call.
 func traceEvent(ev byte, skip int, args ...uint64) {
-	mp, pid, bufp := traceAcquireBuffer()
+	mp, bufp := traceAcquireBuffer()
 	// Double-check trace.enabled now that we've done m.locks++ and acquired bufLock.
 	// This protects from races between traceEvent and StartTrace/StopTrace.
 
@@ -531,8 +722,8 @@ func traceEvent(ev byte, skip int, args ...uint64) {
 	// during tracing in exitsyscall is resolved by locking trace.bufLock in traceLockBuffer.
 	//
 	// Note trace_userTaskCreate runs the same check.
-	if !trace.enabled && !mp.startingtrace {
-		traceReleaseBuffer(pid)
+	if !trace.enabled {
+		traceReleaseBuffer(mp)
 		return
 	}
 
@@ -541,16 +732,16 @@ func traceEvent(ev byte, skip int, args ...uint64) {
 			skip++ // +1 because stack is captured in traceEventLocked.
 		}
 	}
-	traceEventLocked(0, mp, pid, bufp, ev, skip, args...)
-	traceReleaseBuffer(pid)
+	traceEventLocked(0, mp, bufp, ev, skip, args...)
+	traceReleaseBuffer(mp)
 }
 
-func traceEventLocked(extraBytes int, mp *m, pid int32, bufp *traceBufPtr, ev byte, skip int, args ...uint64) {
+func traceEventLocked(extraBytes int, mp *m, bufp *traceBufPtr, ev byte, skip int, args ...uint64) {
 	buf := bufp.ptr()
 	// TODO: test on non-zero extraBytes param.
-	maxSize := 2 + 5*traceBytesPerNumber + extraBytes // event type, length, sequence, timestamp, stack id and two add params
+	maxSize := 1 + 6*traceBytesPerNumber + extraBytes // event type, timestamp, up to four args and stack id
 	if buf == nil || len(buf.arr)-buf.pos < maxSize {
-		buf = traceFlush(traceBufPtrOf(buf), pid).ptr()
+		buf = traceFlush(traceBufPtrOf(buf), mp.id).ptr()
 		bufp.set(buf)
 	}
 
@@ -564,23 +755,8 @@ func traceEventLocked(extraBytes int, mp *m, pid int32, bufp *traceBufPtr, ev by
 	}
 
 	buf.lastTicks = ticks
-	narg := byte(len(args))
-	if skip >= 0 {
-		narg++
-	}
-	// We have only 2 bits for number of arguments.
-	// If number is >= 3, then the event type is followed by event length in bytes.
-	if narg > 3 {
-		narg = 3
-	}
 	startPos := buf.pos
-	buf.byte(ev | narg<<traceArgCountShift)
-	var lenp *byte
-	if narg == 3 {
-		// Reserve the byte for length assuming that length < 128.
-		buf.varint(0)
-		lenp = &buf.arr[buf.pos-1]
-	}
+	buf.byte(ev)
 	buf.varint(tickDiff)
 	for _, a := range args {
 		buf.varint(a)
@@ -590,14 +766,9 @@ func traceEventLocked(extraBytes int, mp *m, pid int32, bufp *traceBufPtr, ev by
 	} else if skip > 0 {
 		buf.varint(traceStackID(mp, buf.stk[:], skip))
 	}
-	evSize := buf.pos - startPos
-	if evSize > maxSize {
+	if buf.pos-startPos > maxSize {
 		throw("invalid length of trace event")
 	}
-	if lenp != nil {
-		// Fill in actual length.
-		*lenp = byte(evSize - 2)
-	}
 }
 
 func traceStackID(mp *m, buf []uintptr, skip int) uint64 {
@@ -620,26 +791,70 @@ func traceStackID(mp *m, buf []uintptr, skip int) uint64 {
 	return uint64(id)
 }
 
-// traceAcquireBuffer returns trace buffer to use and, if necessary, locks it.
-func traceAcquireBuffer() (mp *m, pid int32, bufp *traceBufPtr) {
+// traceAcquireBuffer returns the trace buffer of the current M to use
+// and, if the M has no P, locks it. Events of Ms with a P are ordered
+// with the start of a new generation by stopping the world, and those
+// of Ms without a P by trace.bufLock.
+func traceAcquireBuffer() (mp *m, bufp *traceBufPtr) {
 	mp = acquirem()
-	if p := mp.p.ptr(); p != nil {
-		return mp, p.id, &p.tracebuf
+	if mp.p != 0 && mp.traceBufLocks == 0 {
+		return mp, &mp.tracebuf
 	}
-	lock(&trace.bufLock)
-	return mp, traceGlobProc, &trace.buf
+	traceLockBuf(mp)
+	return mp, &mp.tracebuf
 }
 
 // traceReleaseBuffer releases a buffer previously acquired with traceAcquireBuffer.
-func traceReleaseBuffer(pid int32) {
-	if pid == traceGlobProc {
+func traceReleaseBuffer(mp *m) {
+	traceUnlockBuf(mp)
+	releasem(mp)
+}
+
+// traceLockBuf locks trace.bufLock for mp, which may already hold it.
+func traceLockBuf(mp *m) {
+	if mp.traceBufLocks == 0 {
+		lock(&trace.bufLock)
+	}
+	mp.traceBufLocks++
+}
+
+// traceUnlockBuf undoes traceLockBuf, if mp holds trace.bufLock.
+func traceUnlockBuf(mp *m) {
+	if mp.traceBufLocks == 0 {
+		return
+	}
+	mp.traceBufLocks--
+	if mp.traceBufLocks == 0 {
 		unlock(&trace.bufLock)
 	}
-	releasem(getg().m)
 }
 
-// traceFlush puts buf onto stack of full buffers and returns an empty buffer.
-func traceFlush(buf traceBufPtr, pid int32) traceBufPtr {
+// traceAcquireStatus must be called by an M before it changes the
+// status of a goroutine, along with any events about the change, and
+// traceReleaseStatus after. If the M has no P, and therefore keeps
+// running while the world is stopped, this keeps the change from
+// racing with the goroutine statuses written at the start of a
+// generation.
+func traceAcquireStatus() *m {
+	mp := acquirem()
+	atomic.Store(&mp.traceStatusBusy, 1)
+	if mp.p == 0 && atomic.Load(&trace.gated) != 0 {
+		traceLockBuf(mp)
+	}
+	return mp
+}
+
+// traceReleaseStatus releases the status previously acquired with
+// traceAcquireStatus.
+func traceReleaseStatus(mp *m) {
+	traceUnlockBuf(mp)
+	atomic.Store(&mp.traceStatusBusy, 0)
+	releasem(mp)
+}
+
+// traceFlush puts buf onto stack of full buffers and returns an empty
+// buffer, which starts a batch of events of the M with the given id.
+func traceFlush(buf traceBufPtr, mid int64) traceBufPtr {
 	owner := trace.lockOwner
 	dolock := owner == nil || owner != getg().m.curg
 	if dolock {
@@ -660,6 +875,7 @@ func traceFlush(buf traceBufPtr, pid int32) traceBufPtr {
 	bufp := buf.ptr()
 	bufp.link.set(nil)
 	bufp.pos = 0
+	bufp.gen = trace.gen
 
 	// initialize the buffer for a new batch
 	ticks := uint64(cputicks()) / traceTickDiv
@@ -667,9 +883,13 @@ func traceFlush(buf traceBufPtr, pid int32) traceBufPtr {
 		ticks = bufp.lastTicks + 1
 	}
 	bufp.lastTicks = ticks
-	bufp.byte(traceEvBatch | 1<<traceArgCountShift)
-	bufp.varint(uint64(pid))
+	bufp.byte(traceEvEventBatch)
+	bufp.varint(trace.gen)
+	bufp.varint(uint64(mid))
 	bufp.varint(ticks)
+	// Reserve space for the length, filled in by traceFullQueue.
+	bufp.lenPos = bufp.pos
+	bufp.pos += traceBytesPerNumber
 
 	if dolock {
 		unlock(&trace.lock)
@@ -678,9 +898,9 @@ func traceFlush(buf traceBufPtr, pid int32) traceBufPtr {
 }
 
 // traceString adds a string to the trace.strings and returns the id.
-func traceString(bufp *traceBufPtr, pid int32, s string) (uint64, *traceBufPtr) {
+func traceString(s string) uint64 {
 	if s == "" {
-		return 0, bufp
+		return 0
 	}
 
 	lock(&trace.stringsLock)
@@ -690,35 +910,57 @@ func traceString(bufp *traceBufPtr, pid int32, s string) (uint64, *traceBufPtr)
 		raceacquire(unsafe.Pointer(&trace.stringsLock))
 	}
 
-	if id, ok := trace.strings[s]; ok {
-		if raceenabled {
-			racerelease(unsafe.Pointer(&trace.stringsLock))
-		}
-		unlock(&trace.stringsLock)
-
-		return id, bufp
+	id, ok := trace.strings[s]
+	if !ok {
+		trace.stringSeq++
+		id = trace.stringSeq
+		trace.strings[s] = id
 	}
 
-	trace.stringSeq++
-	id := trace.stringSeq
-	trace.strings[s] = id
+	if raceenabled {
+		racerelease(unsafe.Pointer(&trace.stringsLock))
+	}
+	unlock(&trace.stringsLock)
+	return id
+}
 
+// traceDumpStrings writes all the strings of the current generation to
+// trace buffers and resets the dictionary. The world must be stopped.
+func traceDumpStrings() {
+	lock(&trace.stringsLock)
+	if raceenabled {
+		// See the comment in traceString.
+		raceacquire(unsafe.Pointer(&trace.stringsLock))
+	}
+	bufp := traceFlush(0, traceNoM)
+	for s, id := range trace.strings {
+		bufp = traceWriteString(bufp, id, s)
+	}
+	lock(&trace.lock)
+	traceFullQueue(bufp)
+	unlock(&trace.lock)
 	if raceenabled {
 		racerelease(unsafe.Pointer(&trace.stringsLock))
 	}
 	unlock(&trace.stringsLock)
 
-	// memory allocation in above may trigger tracing and
-	// cause *bufp changes. Following code now works with *bufp,
-	// so there must be no memory allocation or any activities
-	// that causes tracing after this point.
+	// Allocate the new map without trace.stringsLock, because
+	// allocation may emit events.
+	strings := make(map[string]uint64)
+	lock(&trace.stringsLock)
+	trace.strings = strings
+	trace.stringSeq = 0
+	unlock(&trace.stringsLock)
+}
 
-	buf := bufp.ptr()
+// traceWriteString writes the string dictionary entry for id and s
+// into a batch of the tables of the generation.
+func traceWriteString(bufp traceBufPtr, id uint64, s string) traceBufPtr {
 	size := 1 + 2*traceBytesPerNumber + len(s)
-	if buf == nil || len(buf.arr)-buf.pos < size {
-		buf = traceFlush(traceBufPtrOf(buf), pid).ptr()
-		bufp.set(buf)
+	if buf := bufp.ptr(); len(buf.arr)-buf.pos < size {
+		bufp = traceFlush(bufp, traceNoM)
 	}
+	buf := bufp.ptr()
 	buf.byte(traceEvString)
 	buf.varint(id)
 
@@ -726,14 +968,12 @@ func traceString(bufp *traceBufPtr, pid int32, s string) (uint64, *traceBufPtr)
 	// Otherwise, truncate the string.
 	slen := len(s)
 	if room := len(buf.arr) - buf.pos; room < slen+traceBytesPerNumber {
-		slen = room
+		slen = room - traceBytesPerNumber
 	}
 
 	buf.varint(uint64(slen))
 	buf.pos += copy(buf.arr[buf.pos:], s[:slen])
-
-	bufp.set(buf)
-	return id, bufp
+	return bufp
 }
 
 // traceAppend appends v to buf in little-endian-base-128 encoding.
@@ -757,6 +997,21 @@ func (buf *traceBuf) varint(v uint64) {
 	buf.pos = pos
 }
 
+// varintAt writes v at pos in little-endian-base-128 encoding, padded
+// to traceBytesPerNumber bytes, so that it fills space reserved before
+// the value was known.
+func (buf *traceBuf) varintAt(pos int, v uint64) {
+	for i := 0; i < traceBytesPerNumber; i++ {
+		if i < traceBytesPerNumber-1 {
+			buf.arr[pos] = 0x80 | byte(v)
+		} else {
+			buf.arr[pos] = byte(v)
+		}
+		v >>= 7
+		pos++
+	}
+}
+
 // byte appends v to buf.
 func (buf *traceBuf) byte(v byte) {
 	buf.arr[buf.pos] = v
@@ -796,7 +1051,9 @@ func (tab *traceStackTable) put(pcs []uintptr) uint32 {
 	if len(pcs) == 0 {
 		return 0
 	}
-	hash := memhash(unsafe.Pointer(&pcs[0]), 0, uintptr(len(pcs))*unsafe.Sizeof(pcs[0]))
+	// noescape so that callers can pass PCs on their stack: writing
+	// the goroutine statuses must not allocate.
+	hash := memhash(noescape(unsafe.Pointer(&pcs[0])), 0, uintptr(len(pcs))*unsafe.Sizeof(pcs[0]))
 	// First, search the hashtable w/o the mutex.
 	if id := tab.find(pcs, hash); id != 0 {
 		return id
@@ -863,7 +1120,7 @@ func allFrames(pcs []uintptr) []Frame {
 // releases all memory and resets state.
 func (tab *traceStackTable) dump() {
 	var tmp [(2 + 4*traceStackSize) * traceBytesPerNumber]byte
-	bufp := traceFlush(0, 0)
+	bufp := traceFlush(0, traceNoM)
 	for _, stk := range tab.tab {
 		stk := stk.ptr()
 		for ; stk != nil; stk = stk.link.ptr() {
@@ -872,21 +1129,19 @@ func (tab *traceStackTable) dump() {
 			frames := allFrames(stk.stack())
 			tmpbuf = traceAppend(tmpbuf, uint64(len(frames)))
 			for _, f := range frames {
-				var frame traceFrame
-				frame, bufp = traceFrameForPC(bufp, 0, f)
+				frame := traceFrameForPC(f)
 				tmpbuf = traceAppend(tmpbuf, uint64(f.PC))
 				tmpbuf = traceAppend(tmpbuf, uint64(frame.funcID))
 				tmpbuf = traceAppend(tmpbuf, uint64(frame.fileID))
 				tmpbuf = traceAppend(tmpbuf, uint64(frame.line))
 			}
 			// Now copy to the buffer.
-			size := 1 + traceBytesPerNumber + len(tmpbuf)
+			size := 1 + len(tmpbuf)
 			if buf := bufp.ptr(); len(buf.arr)-buf.pos < size {
-				bufp = traceFlush(bufp, 0)
+				bufp = traceFlush(bufp, traceNoM)
 			}
 			buf := bufp.ptr()
-			buf.byte(traceEvStack | 3<<traceArgCountShift)
-			buf.varint(uint64(len(tmpbuf)))
+			buf.byte(traceEvStack)
 			buf.pos += copy(buf.arr[buf.pos:], tmpbuf)
 		}
 	}
@@ -908,8 +1163,7 @@ type traceFrame struct {
 
 // traceFrameForPC records the frame information.
 // It may allocate memory.
-func traceFrameForPC(buf traceBufPtr, pid int32, f Frame) (traceFrame, traceBufPtr) {
-	bufp := &buf
+func traceFrameForPC(f Frame) traceFrame {
 	var frame traceFrame
 
 	fn := f.Function
@@ -917,14 +1171,14 @@ func traceFrameForPC(buf traceBufPtr, pid int32, f Frame) (traceFrame, traceBufP
 	if len(fn) > maxLen {
 		fn = fn[len(fn)-maxLen:]
 	}
-	frame.funcID, bufp = traceString(bufp, pid, fn)
+	frame.funcID = traceString(fn)
 	frame.line = uint64(f.Line)
 	file := f.File
 	if len(file) > maxLen {
 		file = file[len(file)-maxLen:]
 	}
-	frame.fileID, bufp = traceString(bufp, pid, file)
-	return frame, (*bufp)
+	frame.fileID = traceString(file)
+	return frame
 }
 
 // traceAlloc is a non-thread-safe region allocator.
@@ -984,39 +1238,49 @@ func (a *traceAlloc) drop() {
 // The following functions write specific events to trace.
 
 func traceGomaxprocs(procs int32) {
-	traceEvent(traceEvGomaxprocs, 1, uint64(procs))
+	traceEvent(traceEvProcsChange, 1, uint64(procs))
 }
 
 func traceProcStart() {
-	traceEvent(traceEvProcStart, -1, uint64(getg().m.id))
+	pp := getg().m.p.ptr()
+	pp.traceseq++
+	traceEvent(traceEvProcStart, -1, uint64(pp.id), pp.traceseq)
 }
 
-func traceProcStop(pp *p) {
-	// Sysmon and stopTheWorld can stop Ps blocked in syscalls,
-	// to handle this we temporary employ the P.
-	mp := acquirem()
-	oldp := mp.p
-	mp.p.set(pp)
+func traceProcStop() {
 	traceEvent(traceEvProcStop, -1)
-	mp.p = oldp
-	releasem(mp)
+}
+
+// traceProcSteal traces that pp, which was in a syscall, was taken
+// away from the M that made the syscall. The M may learn of it only
+// when the syscall returns.
+func traceProcSteal(pp *p) {
+	mp, bufp := traceAcquireBuffer()
+	if !trace.enabled {
+		traceReleaseBuffer(mp)
+		return
+	}
+	pp.traceseq++
+	traceEventLocked(0, mp, bufp, traceEvProcSteal, -1, uint64(pp.id), pp.traceseq, uint64(pp.traceSyscallM))
+	traceReleaseBuffer(mp)
 }
 
 func traceGCStart() {
-	traceEvent(traceEvGCStart, 3, trace.seqGC)
 	trace.seqGC++
+	traceEvent(traceEvGCBegin, 3, trace.seqGC)
 }
 
 func traceGCDone() {
-	traceEvent(traceEvGCDone, -1)
+	trace.seqGC++
+	traceEvent(traceEvGCEnd, -1, trace.seqGC)
 }
 
 func traceGCSTWStart(kind int) {
-	traceEvent(traceEvGCSTWStart, -1, uint64(kind))
+	traceEvent(traceEvSTWBegin, -1, trace.stwKinds[kind])
 }
 
 func traceGCSTWDone() {
-	traceEvent(traceEvGCSTWDone, -1)
+	traceEvent(traceEvSTWEnd, -1)
 }
 
 // traceGCSweepStart prepares to trace a sweep loop. This does not
@@ -1025,7 +1289,7 @@ func traceGCSTWDone() {
 // traceGCSweepStart must be paired with traceGCSweepDone and there
 // must be no preemption points between these two calls.
 func traceGCSweepStart() {
-	// Delay the actual GCSweepStart event until the first span
+	// Delay the actual GCSweepBegin event until the first span
 	// sweep. If we don't sweep anything, don't emit any events.
 	_p_ := getg().m.p.ptr()
 	if _p_.traceSweep {
@@ -1042,7 +1306,7 @@ func traceGCSweepSpan(bytesSwept uintptr) {
 	_p_ := getg().m.p.ptr()
 	if _p_.traceSweep {
 		if _p_.traceSwept == 0 {
-			traceEvent(traceEvGCSweepStart, 1)
+			traceEvent(traceEvGCSweepBegin, 1)
 		}
 		_p_.traceSwept += bytesSwept
 	}
@@ -1054,107 +1318,103 @@ func traceGCSweepDone() {
 		throw("missing traceGCSweepStart")
 	}
 	if _p_.traceSwept != 0 {
-		traceEvent(traceEvGCSweepDone, -1, uint64(_p_.traceSwept), uint64(_p_.traceReclaimed))
+		traceEvent(traceEvGCSweepEnd, -1, uint64(_p_.traceSwept), uint64(_p_.traceReclaimed))
 	}
 	_p_.traceSweep = false
 }
 
 func traceGCMarkAssistStart() {
-	traceEvent(traceEvGCMarkAssistStart, 1)
+	traceEvent(traceEvGCMarkAssistBegin, 1)
 }
 
 func traceGCMarkAssistDone() {
-	traceEvent(traceEvGCMarkAssistDone, -1)
+	traceEvent(traceEvGCMarkAssistEnd, -1)
 }
 
 func traceGoCreate(newg *g, pc uintptr) {
 	newg.traceseq = 0
-	newg.tracelastp = getg().m.p
 	// +PCQuantum because traceFrameForPC expects return PCs and subtracts PCQuantum.
 	id := trace.stackTab.put([]uintptr{pc + sys.PCQuantum})
 	traceEvent(traceEvGoCreate, 2, uint64(newg.goid), uint64(id))
 }
 
+// traceGoCreateSyscall traces that gp, the goroutine of an M that
+// calls into Go from a thread not created by Go, appears in a syscall.
+// The caller must hold the status with traceAcquireStatus.
+func traceGoCreateSyscall(gp *g) {
+	mp, bufp := traceAcquireBuffer()
+	if !trace.enabled {
+		traceReleaseBuffer(mp)
+		return
+	}
+	gp.traceseq = 0
+	// +PCQuantum because traceFrameForPC expects return PCs and subtracts PCQuantum.
+	id := trace.stackTab.put([]uintptr{gp.startpc + sys.PCQuantum})
+	traceEventLocked(0, mp, bufp, traceEvGoCreateSyscall, -1, uint64(gp.goid), uint64(id))
+	traceReleaseBuffer(mp)
+}
+
+// traceGoDestroySyscall traces that the goroutine of an M that called
+// into Go from a thread not created by Go goes away, in a syscall.
+// The caller must hold the status with traceAcquireStatus.
+func traceGoDestroySyscall() {
+	traceEvent(traceEvGoDestroySyscall, -1)
+}
+
 func traceGoStart() {
 	_g_ := getg().m.curg
 	_p_ := _g_.m.p
 	_g_.traceseq++
+	traceEvent(traceEvGoStart, -1, uint64(_g_.goid), _g_.traceseq)
 	if _p_.ptr().gcMarkWorkerMode != gcMarkWorkerNotWorker {
-		traceEvent(traceEvGoStartLabel, -1, uint64(_g_.goid), _g_.traceseq, trace.markWorkerLabels[_p_.ptr().gcMarkWorkerMode])
-	} else if _g_.tracelastp == _p_ {
-		traceEvent(traceEvGoStartLocal, -1, uint64(_g_.goid))
-	} else {
-		_g_.tracelastp = _p_
-		traceEvent(traceEvGoStart, -1, uint64(_g_.goid), _g_.traceseq)
+		traceEvent(traceEvGoLabel, -1, trace.markWorkerLabels[_p_.ptr().gcMarkWorkerMode])
 	}
 }
 
 func traceGoEnd() {
-	traceEvent(traceEvGoEnd, -1)
+	traceEvent(traceEvGoDestroy, -1)
 }
 
 func traceGoSched() {
-	_g_ := getg()
-	_g_.tracelastp = _g_.m.p
-	traceEvent(traceEvGoSched, 1)
+	traceEvent(traceEvGoStop, 1, trace.goStopReasons[traceGoStopGoSched])
 }
 
 func traceGoPreempt() {
-	_g_ := getg()
-	_g_.tracelastp = _g_.m.p
-	traceEvent(traceEvGoPreempt, 1)
+	traceEvent(traceEvGoStop, 1, trace.goStopReasons[traceGoStopPreempted])
 }
 
-func traceGoPark(traceEv byte, skip int) {
-	if traceEv&traceFutileWakeup != 0 {
-		traceEvent(traceEvFutileWakeup, -1)
-	}
-	traceEvent(traceEv & ^traceFutileWakeup, skip)
+func traceGoPark(reason traceBlockReason, skip int) {
+	traceEvent(traceEvGoBlock, skip, trace.goBlockReasons[reason])
 }
 
 func traceGoUnpark(gp *g, skip int) {
-	_p_ := getg().m.p
-	gp.traceseq++
-	if gp.tracelastp == _p_ {
-		traceEvent(traceEvGoUnblockLocal, skip, uint64(gp.goid))
-	} else {
-		gp.tracelastp = _p_
-		traceEvent(traceEvGoUnblock, skip, uint64(gp.goid), gp.traceseq)
+	mp, bufp := traceAcquireBuffer()
+	if !trace.enabled {
+		traceReleaseBuffer(mp)
+		return
 	}
+	gp.traceseq++
+	traceEventLocked(0, mp, bufp, traceEvGoUnblock, skip, uint64(gp.goid), gp.traceseq)
+	traceReleaseBuffer(mp)
 }
 
 func traceGoSysCall() {
-	traceEvent(traceEvGoSysCall, 1)
-}
-
-func traceGoSysExit(ts int64) {
-	if ts != 0 && ts < trace.ticksStart {
-		// There is a race between the code that initializes sysexitticks
-		// (in exitsyscall, which runs without a P, and therefore is not
-		// stopped with the rest of the world) and the code that initializes
-		// a new trace. The recorded sysexitticks must therefore be treated
-		// as "best effort". If they are valid for this trace, then great,
-		// use them for greater accuracy. But if they're not valid for this
-		// trace, assume that the trace was started after the actual syscall
-		// exit (but before we actually managed to start the goroutine,
-		// aka right now), and assign a fresh time stamp to keep the log consistent.
-		ts = 0
-	}
-	_g_ := getg().m.curg
-	_g_.traceseq++
-	_g_.tracelastp = _g_.m.p
-	traceEvent(traceEvGoSysExit, -1, uint64(_g_.goid), _g_.traceseq, uint64(ts)/traceTickDiv)
+	mp := getg().m
+	pp := mp.p.ptr()
+	pp.traceseq++
+	pp.traceSyscallM = mp.id
+	traceEvent(traceEvGoSyscallBegin, 1, pp.traceseq)
 }
 
-func traceGoSysBlock(pp *p) {
-	// Sysmon and stopTheWorld can declare syscalls running on remote Ps as blocked,
-	// to handle this we temporary employ the P.
-	mp := acquirem()
-	oldp := mp.p
-	mp.p.set(pp)
-	traceEvent(traceEvGoSysBlock, -1)
-	mp.p = oldp
-	releasem(mp)
+// traceGoSysExit traces the return from a syscall of the current
+// goroutine. If lostP is set, the P of the syscall was taken away
+// from the M during the syscall.
+func traceGoSysExit(lostP bool) {
+	if lostP {
+		traceEvent(traceEvGoSyscallEndBlock, -1)
+	} else {
+		traceEvent(traceEvGoSyscallEnd, -1)
+	}
 }
 
 func traceHeapAlloc() {
@@ -1180,15 +1440,15 @@ func trace_userTaskCreate(id, parentID uint64, taskType string) {
 	}
 
 	// Same as in traceEvent.
-	mp, pid, bufp := traceAcquireBuffer()
-	if !trace.enabled && !mp.startingtrace {
-		traceReleaseBuffer(pid)
+	mp, bufp := traceAcquireBuffer()
+	if !trace.enabled {
+		traceReleaseBuffer(mp)
 		return
 	}
 
-	typeStringID, bufp := traceString(bufp, pid, taskType)
-	traceEventLocked(0, mp, pid, bufp, traceEvUserTaskCreate, 3, id, parentID, typeStringID)
-	traceReleaseBuffer(pid)
+	typeStringID := traceString(taskType)
+	traceEventLocked(0, mp, bufp, traceEvUserTaskBegin, 3, id, parentID, typeStringID)
+	traceReleaseBuffer(mp)
 }
 
 //go:linkname trace_userTaskEnd runtime/trace.userTaskEnd
@@ -1202,15 +1462,19 @@ func trace_userRegion(id, mode uint64, name string) {
 		return
 	}
 
-	mp, pid, bufp := traceAcquireBuffer()
-	if !trace.enabled && !mp.startingtrace {
-		traceReleaseBuffer(pid)
+	mp, bufp := traceAcquireBuffer()
+	if !trace.enabled {
+		traceReleaseBuffer(mp)
 		return
 	}
 
-	nameStringID, bufp := traceString(bufp, pid, name)
-	traceEventLocked(0, mp, pid, bufp, traceEvUserRegion, 3, id, mode, nameStringID)
-	traceReleaseBuffer(pid)
+	ev := byte(traceEvUserRegionBegin)
+	if mode != 0 {
+		ev = traceEvUserRegionEnd
+	}
+	nameStringID := traceString(name)
+	traceEventLocked(0, mp, bufp, ev, 3, id, nameStringID)
+	traceReleaseBuffer(mp)
 }
 
 //go:linkname trace_userLog runtime/trace.userLog
@@ -1219,16 +1483,16 @@ func trace_userLog(id uint64, category, message string) {
 		return
 	}
 
-	mp, pid, bufp := traceAcquireBuffer()
-	if !trace.enabled && !mp.startingtrace {
-		traceReleaseBuffer(pid)
+	mp, bufp := traceAcquireBuffer()
+	if !trace.enabled {
+		traceReleaseBuffer(mp)
 		return
 	}
 
-	categoryID, bufp := traceString(bufp, pid, category)
+	categoryID := traceString(category)
 
 	extraSpace := traceBytesPerNumber + len(message) // extraSpace for the value string
-	traceEventLocked(extraSpace, mp, pid, bufp, traceEvUserLog, 3, id, categoryID)
+	traceEventLocked(extraSpace, mp, bufp, traceEvUserLog, 3, id, categoryID)
 	// traceEventLocked reserved extra space for val and len(val)
 	// in buf, so buf now has room for the following.
 	buf := bufp.ptr()
@@ -1237,10 +1501,20 @@ func trace_userLog(id uint64, category, message string) {
 	// Otherwise, truncate the message.
 	slen := len(message)
 	if room := len(buf.arr) - buf.pos; room < slen+traceBytesPerNumber {
-		slen = room
+		slen = room - traceBytesPerNumber
 	}
 	buf.varint(uint64(slen))
 	buf.pos += copy(buf.arr[buf.pos:], message[:slen])
 
-	traceReleaseBuffer(pid)
+	traceReleaseBuffer(mp)
+}
+
+//go:linkname trace_advance runtime/trace.advance
+func trace_advance() uint64 {
+	return traceAdvance()
+}
+
+//go:linkname trace_readTrace runtime/trace.readTrace
+func trace_readTrace() ([]byte, uint64) {
+	return readTrace0()
 }
@@ -0,0 +1,302 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FlightRecorderConfig is the configuration of a FlightRecorder.
+type FlightRecorderConfig struct {
+	// MinAge is a lower bound on the age of the trace data kept by the
+	// flight recorder: a snapshot covers at least the last MinAge of
+	// execution, unless MaxBytes is exceeded. Older data may be
+	// included, but the flight recorder promptly discards data that is
+	// no longer needed.
+	//
+	// If MinAge is zero, the flight recorder uses 10 seconds.
+	MinAge time.Duration
+
+	// MaxBytes is an upper bound on the amount of trace data kept by
+	// the flight recorder. It takes precedence over MinAge, but the
+	// flight recorder always keeps the current generation of the
+	// trace, which may exceed MaxBytes.
+	//
+	// If MaxBytes is zero, the flight recorder uses 10 MiB.
+	MaxBytes uint64
+}
+
+// A FlightRecorder records an execution trace into a moving window in
+// memory, instead of streaming it to a writer. The window can be
+// written out at any time, for example when a program detects that
+// something went wrong, as a complete trace covering the most recent
+// execution of the program.
+//
+// The trace is kept as a sequence of generations. Each generation
+// starts with the state of all goroutines, which the runtime briefly
+// stops the world to write, and ends with the tables needed to decode
+// it, so that the trace can be decoded from any generation on without
+// any of the data before it.
+//
+// Only one flight recorder, or one trace started by Start, may be
+// active at a time.
+type FlightRecorder struct {
+	minAge   time.Duration
+	maxBytes uint64
+
+	// writeMu serializes Start, Stop and WriteTo.
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	cond     sync.Cond
+	enabled  bool
+	reading  bool       // whether the reader goroutine is running
+	header   []byte     // trace header
+	segments []*segment // generations in the window, oldest first
+	size     uint64     // size of the segments
+	seen     uint64     // last generation seen by the reader
+	cut      chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// A segment is the trace data of a generation.
+type segment struct {
+	gen   uint64
+	start time.Time
+	data  [][]byte
+	size  uint64
+}
+
+// NewFlightRecorder creates a new flight recorder with the given
+// configuration. The flight recorder does not record anything until
+// it is started.
+func NewFlightRecorder(cfg FlightRecorderConfig) *FlightRecorder {
+	fr := &FlightRecorder{
+		minAge:   cfg.MinAge,
+		maxBytes: cfg.MaxBytes,
+	}
+	if fr.minAge == 0 {
+		fr.minAge = 10 * time.Second
+	}
+	if fr.maxBytes == 0 {
+		fr.maxBytes = 10 << 20
+	}
+	fr.cond.L = &fr.mu
+	return fr
+}
+
+// Start starts recording the execution trace.
+// Start returns an error if tracing is already enabled,
+// by this or any other flight recorder, or by Start.
+func (fr *FlightRecorder) Start() error {
+	fr.writeMu.Lock()
+	defer fr.writeMu.Unlock()
+	tracing.Lock()
+	defer tracing.Unlock()
+
+	if err := runtime.StartTrace(); err != nil {
+		return err
+	}
+	fr.mu.Lock()
+	fr.enabled = true
+	fr.reading = true
+	fr.header = nil
+	fr.segments = nil
+	fr.size = 0
+	fr.seen = 0
+	fr.cut = make(chan struct{}, 1)
+	fr.stop = make(chan struct{})
+	fr.done = make(chan struct{})
+	fr.mu.Unlock()
+
+	go fr.read()
+	go fr.generations()
+	tracing.flight = true
+	atomic.StoreInt32(&tracing.enabled, 1)
+	return nil
+}
+
+// Stop stops recording the execution trace and discards the recorded
+// data. It waits for any concurrent WriteTo call to complete.
+func (fr *FlightRecorder) Stop() {
+	fr.writeMu.Lock()
+	defer fr.writeMu.Unlock()
+	tracing.Lock()
+	defer tracing.Unlock()
+
+	fr.mu.Lock()
+	enabled := fr.enabled
+	fr.enabled = false
+	fr.mu.Unlock()
+	if !enabled {
+		return
+	}
+
+	close(fr.stop)
+	atomic.StoreInt32(&tracing.enabled, 0)
+	tracing.flight = false
+	runtime.StopTrace()
+	<-fr.done
+
+	fr.mu.Lock()
+	fr.header = nil
+	fr.segments = nil
+	fr.size = 0
+	fr.mu.Unlock()
+}
+
+// Enabled reports whether the flight recorder is recording.
+func (fr *FlightRecorder) Enabled() bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.enabled
+}
+
+// WriteTo writes the trace data in the flight recorder's window to w,
+// as a complete trace. It returns an error if the flight recorder is
+// not recording.
+func (fr *FlightRecorder) WriteTo(w io.Writer) (n int64, err error) {
+	fr.writeMu.Lock()
+	defer fr.writeMu.Unlock()
+
+	if !fr.Enabled() {
+		return 0, errors.New("trace: flight recorder is not enabled")
+	}
+
+	// End the current generation, and wait for the reader to see
+	// the start of the next one, which follows all the data of the
+	// generations before it.
+	gen := advance()
+	fr.mu.Lock()
+	for fr.seen < gen && fr.reading {
+		fr.cond.Wait()
+	}
+	if gen == 0 || fr.seen < gen {
+		fr.mu.Unlock()
+		return 0, errors.New("trace: flight recorder stopped unexpectedly")
+	}
+	data := [][]byte{fr.header}
+	for _, s := range fr.segments {
+		if s.gen >= gen {
+			break
+		}
+		data = append(data, s.data...)
+	}
+	fr.mu.Unlock()
+
+	for _, b := range data {
+		m, err := w.Write(b)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// read reads the trace data from the runtime into the window.
+func (fr *FlightRecorder) read() {
+	defer close(fr.done)
+	for {
+		data, gen := readTrace()
+		if data == nil {
+			break
+		}
+		// The runtime reuses the buffer on the next call.
+		data = append([]byte(nil), data...)
+
+		fr.mu.Lock()
+		if fr.header == nil {
+			fr.header = data
+			fr.mu.Unlock()
+			continue
+		}
+		if len(fr.segments) == 0 || fr.segments[len(fr.segments)-1].gen != gen {
+			fr.segments = append(fr.segments, &segment{gen: gen, start: time.Now()})
+			fr.seen = gen
+			fr.cond.Broadcast()
+		}
+		s := fr.segments[len(fr.segments)-1]
+		s.data = append(s.data, data)
+		s.size += uint64(len(data))
+		fr.size += uint64(len(data))
+		if s.size > fr.maxBytes/4 {
+			// Start a new generation soon, so that old data can be
+			// discarded in smaller pieces.
+			select {
+			case fr.cut <- struct{}{}:
+			default:
+			}
+		}
+		fr.trim()
+		fr.mu.Unlock()
+	}
+
+	fr.mu.Lock()
+	fr.reading = false
+	fr.cond.Broadcast()
+	fr.mu.Unlock()
+}
+
+// trim discards the oldest segments while they are not needed to cover
+// the window. The two most recent segments are always kept: the most
+// recent one is incomplete, and a snapshot needs a complete one.
+// fr.mu must be held.
+func (fr *FlightRecorder) trim() {
+	for len(fr.segments) > 2 {
+		if fr.size <= fr.maxBytes && time.Since(fr.segments[1].start) < fr.minAge {
+			// The oldest segment is still part of the window.
+			break
+		}
+		fr.size -= fr.segments[0].size
+		fr.segments[0] = nil
+		fr.segments = fr.segments[1:]
+	}
+}
+
+// generations periodically starts new generations of the trace.
+func (fr *FlightRecorder) generations() {
+	// Start generations often enough that discarding the oldest one
+	// does not discard much more than needed.
+	period := fr.minAge / 4
+	if period < 100*time.Millisecond {
+		period = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-fr.stop:
+			return
+		case <-ticker.C:
+		case <-fr.cut:
+		}
+		advance()
+
+		// Data may also age out of the window while no new data
+		// arrives.
+		fr.mu.Lock()
+		fr.trim()
+		fr.mu.Unlock()
+	}
+}
+
+//
+// Function bodies are defined in runtime/trace.go
+//
+
+// advance ends the current generation of the trace and returns the
+// new one, or 0 if tracing is not enabled.
+func advance() uint64
+
+// readTrace is runtime.ReadTrace that also returns the generation of
+// the data, or 0 for the trace header.
+func readTrace() ([]byte, uint64)
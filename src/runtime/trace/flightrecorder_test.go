@@ -0,0 +1,185 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"internal/trace"
+	"os"
+	"runtime"
+	. "runtime/trace"
+	"sync"
+	"testing"
+	"time"
+)
+
+// userLogs returns the messages of the user log events in events.
+func userLogs(events []*trace.Event) map[string]bool {
+	logs := make(map[string]bool)
+	for _, ev := range events {
+		if ev.Type == trace.EvUserLog {
+			logs[ev.SArgs[1]] = true
+		}
+	}
+	return logs
+}
+
+func TestFlightRecorder(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	fr := NewFlightRecorder(FlightRecorderConfig{MinAge: 200 * time.Millisecond})
+	if err := fr.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer fr.Stop()
+	if !fr.Enabled() {
+		t.Fatal("flight recorder is not enabled after Start")
+	}
+	if err := Start(new(bytes.Buffer)); err == nil {
+		t.Fatal("Start succeeded while the flight recorder is enabled")
+	}
+	// Stop must not stop the flight recorder.
+	Stop()
+
+	ctx := context.Background()
+	Log(ctx, "test", "old")
+	// Let the old data age out of the window.
+	time.Sleep(time.Second)
+	Log(ctx, "test", "new")
+
+	buf := new(bytes.Buffer)
+	if _, err := fr.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	saveTrace(t, buf, "TestFlightRecorder")
+	events, _ := parseTrace(t, buf)
+	logs := userLogs(events)
+	if !logs["new"] {
+		t.Errorf("recent log message is missing from the window")
+	}
+	if logs["old"] {
+		t.Errorf("old log message is still in the window")
+	}
+
+	fr.Stop()
+	if fr.Enabled() {
+		t.Fatal("flight recorder is enabled after Stop")
+	}
+	if _, err := fr.WriteTo(buf); err == nil {
+		t.Fatal("WriteTo succeeded after Stop")
+	}
+}
+
+func TestFlightRecorderWindow(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	// A long window keeps the whole trace, through a number of
+	// generations.
+	fr := NewFlightRecorder(FlightRecorderConfig{MinAge: time.Minute})
+	if err := fr.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer fr.Stop()
+
+	ctx := context.Background()
+	var events []*trace.Event
+	for i, msg := range []string{"a", "b", "c"} {
+		Log(ctx, "test", msg)
+		time.Sleep(100 * time.Millisecond)
+		buf := new(bytes.Buffer)
+		if _, err := fr.WriteTo(buf); err != nil {
+			t.Fatal(err)
+		}
+		events, _ = parseTrace(t, buf)
+		if logs := userLogs(events); len(logs) != i+1 {
+			t.Errorf("after %d messages, the window has messages %v", i+1, logs)
+		}
+	}
+}
+
+func TestFlightRecorderStress(t *testing.T) {
+	if runtime.GOOS == "js" {
+		t.Skip("no os.Pipe on js")
+	}
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	if testing.Short() {
+		t.Skip("skipping in -short mode")
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan bool)
+	rp, wp, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer func() {
+		rp.Close()
+		wp.Close()
+	}()
+
+	fr := NewFlightRecorder(FlightRecorderConfig{MinAge: 100 * time.Millisecond, MaxBytes: 1 << 20})
+	if err := fr.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer fr.Stop()
+
+	// Goroutines that block, run, and sit in syscalls
+	// across generations.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var tmp [1]byte
+		for {
+			if _, err := rp.Read(tmp[:]); err != nil || tmp[0] == 1 {
+				return
+			}
+		}
+	}()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := make(chan int)
+			go func() {
+				for range c {
+				}
+			}()
+			defer close(c)
+			for {
+				select {
+				case <-done:
+					return
+				case c <- 1:
+				}
+				_ = make([]byte, 1<<10)
+			}
+		}()
+	}
+
+	defer func() {
+		close(done)
+		wp.Write([]byte{1})
+		wg.Wait()
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for i := 0; time.Now().Before(deadline); i++ {
+		time.Sleep(50 * time.Millisecond)
+		wp.Write([]byte{0})
+		if i%4 == 0 {
+			runtime.GC()
+		}
+		buf := new(bytes.Buffer)
+		if _, err := fr.WriteTo(buf); err != nil {
+			t.Fatal(err)
+		}
+		parseTrace(t, buf)
+	}
+}
@@ -34,6 +34,13 @@
 // See the net/http/pprof package for more details about all of the
 // debug endpoints installed by this import.
 //
+// Flight recording
+//
+// Leaving a trace running and writing it out continuously is often too
+// costly in production. A FlightRecorder instead keeps only the most
+// recent part of the trace in memory, and writes it out on demand as a
+// complete trace, for example when a program notices a slow request.
+//
 // User annotation
 //
 // Package trace provides user annotation APIs that can be used to
@@ -139,9 +146,13 @@ func Start(w io.Writer) error {
 
 // Stop stops the current tracing, if any.
 // Stop only returns after all the writes for the trace have completed.
+// Stop does not stop a FlightRecorder.
 func Stop() {
 	tracing.Lock()
 	defer tracing.Unlock()
+	if tracing.flight {
+		return
+	}
 	atomic.StoreInt32(&tracing.enabled, 0)
 
 	runtime.StopTrace()
@@ -150,4 +161,5 @@ func Stop() {
 var tracing struct {
 	sync.Mutex       // gate mutators (Start, Stop)
 	enabled    int32 // accessed via atomic
+	flight     bool  // tracing is for a FlightRecorder
 }
@@ -1004,6 +1004,9 @@ func goroutineheader(gp *g) {
 		waitfor = (nanotime() - gp.waitsince) / 60e9
 	}
 	print("goroutine ", gp.goid, " [", status)
+	if gpstatus == _Gwaiting && gp.leaked {
+		print(" (leaked)")
+	}
 	if isScan {
 		print(" (scan)")
 	}
@@ -0,0 +1,109 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package strings_test
+
+import (
+	"iter"
+	. "strings"
+	"testing"
+	"unicode"
+)
+
+func collect(seq iter.Seq[string]) []string {
+	var out []string
+	for s := range seq {
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestLines(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want []string
+	}{
+		{"", nil},
+		{"\n", []string{"\n"}},
+		{"a", []string{"a"}},
+		{"a\nb", []string{"a\n", "b"}},
+		{"a\nb\n", []string{"a\n", "b\n"}},
+		{"a\n\nb\r\n", []string{"a\n", "\n", "b\r\n"}},
+	} {
+		if got := collect(Lines(tt.s)); !eq(got, tt.want) {
+			t.Errorf("Lines(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestSplitSeq(t *testing.T) {
+	for _, tt := range splittests {
+		if tt.n != -1 {
+			continue
+		}
+		if got, want := collect(SplitSeq(tt.s, tt.sep)), Split(tt.s, tt.sep); !eq(got, want) {
+			t.Errorf("SplitSeq(%q, %q) = %q, want %q", tt.s, tt.sep, got, want)
+		}
+	}
+}
+
+func TestSplitAfterSeq(t *testing.T) {
+	for _, tt := range splitaftertests {
+		if tt.n != -1 {
+			continue
+		}
+		if got, want := collect(SplitAfterSeq(tt.s, tt.sep)), SplitAfter(tt.s, tt.sep); !eq(got, want) {
+			t.Errorf("SplitAfterSeq(%q, %q) = %q, want %q", tt.s, tt.sep, got, want)
+		}
+	}
+}
+
+func TestFieldsSeq(t *testing.T) {
+	for _, tt := range fieldstests {
+		if got, want := collect(FieldsSeq(tt.s)), Fields(tt.s); !eq(got, want) {
+			t.Errorf("FieldsSeq(%q) = %q, want %q", tt.s, got, want)
+		}
+	}
+}
+
+func TestFieldsFuncSeq(t *testing.T) {
+	pred := func(c rune) bool { return c == 'X' || unicode.IsSpace(c) }
+	for _, s := range []string{"", "XX", "XXhiXXX", "aXXbXXXcX", "  a b ", " x　y"} {
+		if got, want := collect(FieldsFuncSeq(s, pred)), FieldsFunc(s, pred); !eq(got, want) {
+			t.Errorf("FieldsFuncSeq(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestSeqReuse(t *testing.T) {
+	seq := SplitSeq("a,b,c", ",")
+	for i := 0; i < 2; i++ {
+		if got, want := collect(seq), []string{"a", "b", "c"}; !eq(got, want) {
+			t.Errorf("iteration %d: got %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestSeqBreak(t *testing.T) {
+	seqs := map[string]iter.Seq[string]{
+		"Lines":          Lines("a\nb\nc\n"),
+		"SplitSeq":       SplitSeq("a,b,c", ","),
+		"SplitSeq/runes": SplitSeq("abc", ""),
+		"SplitAfterSeq":  SplitAfterSeq("a,b,c", ","),
+		"FieldsSeq":      FieldsSeq("a b c"),
+		"FieldsFuncSeq":  FieldsFuncSeq("a,b,c", func(r rune) bool { return r == ',' }),
+	}
+	for name, seq := range seqs {
+		n := 0
+		for range seq {
+			n++
+			if n == 2 {
+				break
+			}
+		}
+		if n != 2 {
+			t.Errorf("%s: got %d iterations, want 2", name, n)
+		}
+	}
+}
@@ -37,6 +37,11 @@
 // functions, are the atomic equivalents of "return *addr" and
 // "*addr = val".
 //
+// The types Bool, Int32, Int64, Uint32, Uint64, Uintptr, and Pointer[T]
+// provide the same operations as methods. Using them is recommended
+// over the functions above: values of these types can only be accessed
+// atomically, and 64-bit values are always 64-bit aligned.
+//
 package atomic
 
 import (
@@ -47,10 +52,12 @@ import (
 //
 // On non-Linux ARM, the 64-bit functions use instructions unavailable before the ARMv6k core.
 //
-// On ARM, 386, and 32-bit MIPS, it is the caller's responsibility
-// to arrange for 64-bit alignment of 64-bit words accessed atomically.
-// The first word in a variable or in an allocated struct, array, or slice can
-// be relied upon to be 64-bit aligned.
+// On ARM, 386, and 32-bit MIPS, it is the caller's responsibility to arrange
+// for 64-bit alignment of 64-bit words accessed atomically via the primitive
+// atomic functions (types Int64 and Uint64 are automatically aligned).
+// The first word in an allocated struct, array, or slice; in a global
+// variable; or in a local variable (because the subject of all atomic operations
+// will escape to the heap) can be relied upon to be 64-bit aligned.
 
 // SwapInt32 atomically stores new into *addr and returns the previous *addr value.
 func SwapInt32(addr *int32, new int32) (old int32)
@@ -0,0 +1,277 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package atomic_test
+
+import (
+	"runtime"
+	. "sync/atomic"
+	"testing"
+	"unsafe"
+)
+
+// Tests of the method forms of the atomic operations.
+// As in atomic_test.go, the struct fields x.before and x.after
+// check that the operations do not extend past the full word size.
+
+func TestBool(t *testing.T) {
+	var x struct {
+		before uint32
+		b      Bool
+		after  uint32
+	}
+	x.before = magic32
+	x.after = magic32
+	if x.b.Load() {
+		t.Fatalf("zero Bool = true, want false")
+	}
+	x.b.Store(true)
+	if !x.b.Load() {
+		t.Fatalf("Load after Store(true) = false, want true")
+	}
+	if old := x.b.Swap(false); !old || x.b.Load() {
+		t.Fatalf("Swap(false) = %v, value %v; want true, false", old, x.b.Load())
+	}
+	if x.b.CompareAndSwap(true, true) || x.b.Load() {
+		t.Fatalf("CompareAndSwap(true, true) on false value succeeded")
+	}
+	if !x.b.CompareAndSwap(false, true) || !x.b.Load() {
+		t.Fatalf("CompareAndSwap(false, true) on false value failed")
+	}
+	if x.before != magic32 || x.after != magic32 {
+		t.Fatalf("wrong magic: %#x _ %#x != %#x _ %#x", x.before, x.after, magic32, magic32)
+	}
+}
+
+func TestInt32Method(t *testing.T) {
+	var x struct {
+		before int32
+		i      Int32
+		after  int32
+	}
+	x.before = magic32
+	x.after = magic32
+	var j int32
+	for delta := int32(1); delta+delta > delta; delta += delta {
+		k := x.i.Add(delta)
+		j += delta
+		if x.i.Load() != j || k != j {
+			t.Fatalf("delta=%d i=%d j=%d k=%d", delta, x.i.Load(), j, k)
+		}
+		if old := x.i.Swap(delta); old != j {
+			t.Fatalf("Swap(%d) = %d, want %d", delta, old, j)
+		}
+		if x.i.CompareAndSwap(delta+1, j) {
+			t.Fatalf("CompareAndSwap(%d, %d) succeeded on %d", delta+1, j, x.i.Load())
+		}
+		if !x.i.CompareAndSwap(delta, j) || x.i.Load() != j {
+			t.Fatalf("CompareAndSwap(%d, %d) failed", delta, j)
+		}
+	}
+	x.i.Store(-1)
+	if x.i.Load() != -1 {
+		t.Fatalf("Load after Store(-1) = %d", x.i.Load())
+	}
+	if x.before != magic32 || x.after != magic32 {
+		t.Fatalf("wrong magic: %#x _ %#x != %#x _ %#x", x.before, x.after, magic32, magic32)
+	}
+}
+
+func TestUint32Method(t *testing.T) {
+	var x struct {
+		before uint32
+		i      Uint32
+		after  uint32
+	}
+	x.before = magic32
+	x.after = magic32
+	var j uint32
+	for delta := uint32(1); delta+delta > delta; delta += delta {
+		k := x.i.Add(delta)
+		j += delta
+		if x.i.Load() != j || k != j {
+			t.Fatalf("delta=%d i=%d j=%d k=%d", delta, x.i.Load(), j, k)
+		}
+		if old := x.i.Swap(delta); old != j {
+			t.Fatalf("Swap(%d) = %d, want %d", delta, old, j)
+		}
+		if !x.i.CompareAndSwap(delta, j) || x.i.Load() != j {
+			t.Fatalf("CompareAndSwap(%d, %d) failed", delta, j)
+		}
+	}
+	x.i.Store(magic32)
+	if x.i.Load() != magic32 {
+		t.Fatalf("Load after Store(%#x) = %#x", magic32, x.i.Load())
+	}
+	if x.before != magic32 || x.after != magic32 {
+		t.Fatalf("wrong magic: %#x _ %#x != %#x _ %#x", x.before, x.after, magic32, magic32)
+	}
+}
+
+func TestInt64Method(t *testing.T) {
+	if test64err != nil {
+		t.Skipf("Skipping 64-bit tests: %v", test64err)
+	}
+	var x struct {
+		before int64
+		i      Int64
+		after  int64
+	}
+	x.before = magic64
+	x.after = magic64
+	var j int64
+	for delta := int64(1); delta+delta > delta; delta += delta {
+		k := x.i.Add(delta)
+		j += delta
+		if x.i.Load() != j || k != j {
+			t.Fatalf("delta=%d i=%d j=%d k=%d", delta, x.i.Load(), j, k)
+		}
+		if old := x.i.Swap(delta); old != j {
+			t.Fatalf("Swap(%d) = %d, want %d", delta, old, j)
+		}
+		if x.i.CompareAndSwap(delta+1, j) {
+			t.Fatalf("CompareAndSwap(%d, %d) succeeded on %d", delta+1, j, x.i.Load())
+		}
+		if !x.i.CompareAndSwap(delta, j) || x.i.Load() != j {
+			t.Fatalf("CompareAndSwap(%d, %d) failed", delta, j)
+		}
+	}
+	x.i.Store(-1)
+	if x.i.Load() != -1 {
+		t.Fatalf("Load after Store(-1) = %d", x.i.Load())
+	}
+	if x.before != magic64 || x.after != magic64 {
+		t.Fatalf("wrong magic: %#x _ %#x != %#x _ %#x", x.before, x.after, uint64(magic64), uint64(magic64))
+	}
+}
+
+func TestUint64Method(t *testing.T) {
+	if test64err != nil {
+		t.Skipf("Skipping 64-bit tests: %v", test64err)
+	}
+	var x struct {
+		before uint64
+		i      Uint64
+		after  uint64
+	}
+	x.before = magic64
+	x.after = magic64
+	var j uint64
+	for delta := uint64(1); delta+delta > delta; delta += delta {
+		k := x.i.Add(delta)
+		j += delta
+		if x.i.Load() != j || k != j {
+			t.Fatalf("delta=%d i=%d j=%d k=%d", delta, x.i.Load(), j, k)
+		}
+		if old := x.i.Swap(delta); old != j {
+			t.Fatalf("Swap(%d) = %d, want %d", delta, old, j)
+		}
+		if !x.i.CompareAndSwap(delta, j) || x.i.Load() != j {
+			t.Fatalf("CompareAndSwap(%d, %d) failed", delta, j)
+		}
+	}
+	x.i.Store(magic64)
+	if x.i.Load() != magic64 {
+		t.Fatalf("Load after Store(%#x) = %#x", uint64(magic64), x.i.Load())
+	}
+	if x.before != magic64 || x.after != magic64 {
+		t.Fatalf("wrong magic: %#x _ %#x != %#x _ %#x", x.before, x.after, uint64(magic64), uint64(magic64))
+	}
+}
+
+func TestUintptrMethod(t *testing.T) {
+	var x struct {
+		before uintptr
+		i      Uintptr
+		after  uintptr
+	}
+	var m uint64 = magic64
+	magicptr := uintptr(m)
+	x.before = magicptr
+	x.after = magicptr
+	var j uintptr
+	for delta := uintptr(1); delta+delta > delta; delta += delta {
+		k := x.i.Add(delta)
+		j += delta
+		if x.i.Load() != j || k != j {
+			t.Fatalf("delta=%d i=%d j=%d k=%d", delta, x.i.Load(), j, k)
+		}
+		if old := x.i.Swap(delta); old != j {
+			t.Fatalf("Swap(%d) = %d, want %d", delta, old, j)
+		}
+		if !x.i.CompareAndSwap(delta, j) || x.i.Load() != j {
+			t.Fatalf("CompareAndSwap(%d, %d) failed", delta, j)
+		}
+	}
+	x.i.Store(magicptr)
+	if x.i.Load() != magicptr {
+		t.Fatalf("Load after Store(%#x) = %#x", magicptr, x.i.Load())
+	}
+	if x.before != magicptr || x.after != magicptr {
+		t.Fatalf("wrong magic: %#x _ %#x != %#x _ %#x", x.before, x.after, magicptr, magicptr)
+	}
+}
+
+func TestPointerMethod(t *testing.T) {
+	var x struct {
+		before uintptr
+		p      Pointer[int]
+		after  uintptr
+	}
+	var m uint64 = magic64
+	magicptr := uintptr(m)
+	x.before = magicptr
+	x.after = magicptr
+	if p := x.p.Load(); p != nil {
+		t.Fatalf("zero Pointer = %p, want nil", p)
+	}
+	a, b := new(int), new(int)
+	x.p.Store(a)
+	if p := x.p.Load(); p != a {
+		t.Fatalf("Load after Store(%p) = %p", a, p)
+	}
+	if old := x.p.Swap(b); old != a || x.p.Load() != b {
+		t.Fatalf("Swap(%p) = %p, value %p; want %p, %p", b, old, x.p.Load(), a, b)
+	}
+	if x.p.CompareAndSwap(a, nil) || x.p.Load() != b {
+		t.Fatalf("CompareAndSwap(%p, nil) succeeded on %p", a, b)
+	}
+	if !x.p.CompareAndSwap(b, nil) || x.p.Load() != nil {
+		t.Fatalf("CompareAndSwap(%p, nil) failed", b)
+	}
+	if x.before != magicptr || x.after != magicptr {
+		t.Fatalf("wrong magic: %#x _ %#x != %#x _ %#x", x.before, x.after, magicptr, magicptr)
+	}
+}
+
+// Test that Int64 and Uint64 are 64-bit aligned even when preceded by
+// a smaller field, including on 32-bit platforms.
+func TestTypeAlignment(t *testing.T) {
+	var x struct {
+		b byte
+		i Int64
+		j uint32
+		u Uint64
+	}
+	base := uintptr(unsafe.Pointer(&x))
+	if off := uintptr(unsafe.Pointer(&x.i)) - base; off%8 != 0 {
+		t.Errorf("Int64 field at offset %d, want multiple of 8", off)
+	}
+	if off := uintptr(unsafe.Pointer(&x.u)) - base; off%8 != 0 {
+		t.Errorf("Uint64 field at offset %d, want multiple of 8", off)
+	}
+
+	// The field offsets must also hold at run time, for which
+	// the allocation of x itself must be 64-bit aligned.
+	p := new([3]struct {
+		b byte
+		u Uint64
+	})
+	for i := range p {
+		if addr := uintptr(unsafe.Pointer(&p[i].u)); addr%8 != 0 {
+			t.Errorf("&p[%d].u = %#x, not 64-bit aligned on %s", i, addr, runtime.GOARCH)
+		}
+		p[i].u.Add(1)
+	}
+}
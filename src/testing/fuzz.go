@@ -6,6 +6,7 @@ package testing
 
 import (
 	"bytes"
+	"encoding"
 	"errors"
 	"flag"
 	"fmt"
@@ -153,7 +154,7 @@ func (f *F) Skipped() bool {
 func (f *F) Add(args ...any) {
 	var values []any
 	for i := range args {
-		if t := reflect.TypeOf(args[i]); !supportedTypes[t] {
+		if t := reflect.TypeOf(args[i]); !isSupportedType(t) {
 			panic(fmt.Sprintf("testing: unsupported type to Add %v", t))
 		}
 		values = append(values, args[i])
@@ -182,6 +183,54 @@ var supportedTypes = map[reflect.Type]bool{
 	reflect.TypeOf((uint64)(0)):   true,
 }
 
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+// isSupportedType reports whether t can be fuzzed: whether it is one of
+// supportedTypes, or a structured type built from them.
+func isSupportedType(t reflect.Type) bool {
+	return supportedTypes[t] || isStructuredType(t, make(map[reflect.Type]bool))
+}
+
+// isStructuredType reports whether t is a structured type that can be fuzzed.
+// seen holds the types being checked further up, so that recursive types
+// can be checked.
+func isStructuredType(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t.Implements(binaryMarshalerType) && reflect.PtrTo(t).Implements(binaryUnmarshalerType) {
+		return true
+	}
+	if seen[t] {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice, reflect.Array:
+		seen[t] = true
+		return isStructuredType(t.Elem(), seen)
+	case reflect.Map:
+		seen[t] = true
+		switch t.Key().Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+			return false
+		}
+		return isStructuredType(t.Key(), seen) && isStructuredType(t.Elem(), seen)
+	case reflect.Struct:
+		seen[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); !f.IsExported() || !isStructuredType(f.Type, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
 // Fuzz runs the fuzz function, ff, for fuzz testing. If ff fails for a set of
 // arguments, those arguments will be added to the seed corpus.
 //
@@ -192,8 +241,15 @@ var supportedTypes = map[reflect.Type]bool{
 //     f.Fuzz(func(t *testing.T, b []byte, i int) { ... })
 //
 // The following types are allowed: []byte, string, bool, byte, rune, float32,
-// float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64.
-// More types may be supported in the future.
+// float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+// and types whose underlying type is one of these. Structured types are
+// allowed too: structs whose fields are all exported and of allowed types;
+// slices, arrays and maps whose elements are of allowed types, where map keys
+// must have a boolean, numeric or string underlying type; and types T such
+// that T implements encoding.BinaryMarshaler and *T implements
+// encoding.BinaryUnmarshaler. Structured values are mutated element by
+// element, and values of the latter types through their binary encoding.
+// Pointers and interfaces are not allowed.
 //
 // ff must not call any *F methods, e.g. (*F).Log, (*F).Error, (*F).Skip. Use
 // the corresponding *T method instead. The only *F methods that are allowed in
@@ -232,7 +288,7 @@ func (f *F) Fuzz(ff any) {
 	var types []reflect.Type
 	for i := 1; i < fnType.NumIn(); i++ {
 		t := fnType.In(i)
-		if !supportedTypes[t] {
+		if !isSupportedType(t) {
 			panic(fmt.Sprintf("testing: unsupported type for fuzzing %v", t))
 		}
 		types = append(types, t)
@@ -372,7 +428,7 @@ func (f *F) Fuzz(ff any) {
 	case fuzzWorker:
 		// Fuzzing is enabled, and this is a worker process. Follow instructions
 		// from the coordinator.
-		if err := f.fuzzContext.deps.RunFuzzWorker(func(e corpusEntry) error {
+		if err := f.fuzzContext.deps.RunFuzzWorker(types, func(e corpusEntry) error {
 			// Don't write to f.w (which points to Stdout) if running from a
 			// fuzz worker. This would become very verbose, particularly during
 			// minimization. Return the error instead, and let the caller deal
@@ -0,0 +1,113 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// leaks finds goroutines leaked by tests. It is nil unless the
+// -test.goroutineleak flag is set.
+var leaks *leakChecker
+
+// A leakChecker finds leaked goroutines using the goroutineleak profile:
+// goroutines blocked forever on channels or synchronization objects
+// that no goroutine able to run can reach.
+//
+// A leak found when a test finishes is attributed to that test only if
+// no parallel test is running, since goroutines leaked by a parallel
+// test are indistinguishable from those leaked by the tests running
+// alongside it. Leaks that cannot be attributed to a test are reported
+// once all tests have finished.
+type leakChecker struct {
+	deps testDeps
+
+	mu           sync.Mutex
+	seen         map[string]bool // goroutines already reported, by header
+	parallel     int             // number of parallel tests running
+	pending      bool            // a check was skipped while parallel tests were running
+	unattributed []string        // stacks of leaks not attributed to a test
+}
+
+func newLeakChecker(deps testDeps) *leakChecker {
+	return &leakChecker{deps: deps, seen: make(map[string]bool)}
+}
+
+// startParallel records that a parallel test has started running.
+func (c *leakChecker) startParallel() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.parallel++
+}
+
+// checkTest looks for goroutines leaked by a test that has finished
+// running. The parallel argument reports whether the test was running
+// in parallel. It returns the number of goroutines that the test has
+// leaked and their stacks. If other parallel tests are still running,
+// or were running since the last check, leaked goroutines are recorded
+// for checkAll instead.
+func (c *leakChecker) checkTest(parallel bool) (n int, stacks string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if parallel {
+		c.parallel--
+	}
+	if c.parallel > 0 {
+		c.pending = true
+		return 0, "", nil
+	}
+	leaked, err := c.check()
+	if err != nil {
+		return 0, "", err
+	}
+	if c.pending {
+		// This is the last of a group of tests that ran in
+		// parallel: the leaks may belong to any of them.
+		c.pending = false
+		c.unattributed = append(c.unattributed, leaked...)
+		return 0, "", nil
+	}
+	return len(leaked), strings.Join(leaked, "\n\n"), nil
+}
+
+// checkAll looks for leaked goroutines once all tests have finished.
+// It returns the number and stacks of those not attributed to a test.
+func (c *leakChecker) checkAll() (n int, stacks string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	leaked, err := c.check()
+	if err != nil {
+		return 0, "", err
+	}
+	leaked = append(c.unattributed, leaked...)
+	c.unattributed = nil
+	return len(leaked), strings.Join(leaked, "\n\n"), nil
+}
+
+// check runs a garbage collection to find leaked goroutines. It returns
+// the stacks of the goroutines that have leaked since the previous call.
+// c.mu must be held.
+func (c *leakChecker) check() ([]string, error) {
+	var buf bytes.Buffer
+	if err := c.deps.WriteProfileTo("goroutineleak", &buf, 2); err != nil {
+		return nil, err
+	}
+	var leaked []string
+	for _, stk := range strings.Split(strings.TrimSpace(buf.String()), "\n\n") {
+		// Each stack begins with a header such as
+		// "goroutine 7 [chan receive (leaked)]:".
+		header, _, _ := strings.Cut(stk, " [")
+		if stk == "" || c.seen[header] {
+			continue
+		}
+		c.seen[header] = true
+		leaked = append(leaked, stk)
+	}
+	return leaked, nil
+}
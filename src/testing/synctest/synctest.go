@@ -0,0 +1,61 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package synctest provides support for testing concurrent code.
+//
+// Run runs a function in an isolated "bubble" of goroutines. Goroutines
+// started by a goroutine in the bubble join the bubble. Within the
+// bubble, package time uses a fake clock: time.Now, time.Sleep, timers,
+// tickers and everything built on them, such as context.WithTimeout,
+// read and wait on that clock rather than on the real one.
+//
+// The fake clock advances only when every goroutine in the bubble is
+// durably blocked, and then only to the time at which the next timer
+// in the bubble fires. Time passes instantly, so a test that waits for
+// an hour-long timeout completes in microseconds, and deterministically.
+//
+// A goroutine is durably blocked if it can only be unblocked by another
+// goroutine in the same bubble. A goroutine in the bubble is durably
+// blocked when it is:
+//
+//   - sending on or receiving from a channel created within the bubble
+//   - in a select statement where every case is a channel created within the bubble
+//   - sending on or receiving from a nil channel, or in select {}
+//   - in time.Sleep
+//   - in sync.Cond.Wait
+//   - in Wait
+//
+// Other blocking operations, such as locking a sync.Mutex, waiting on a
+// sync.WaitGroup, system calls and network I/O, are not durable: a
+// goroutine blocked in them keeps the clock from advancing.
+//
+// Channels created within a bubble may not be used from outside any
+// bubble; doing so panics.
+package synctest
+
+// Run executes f in a new goroutine, starting a new bubble of which
+// that goroutine is the first member.
+//
+// Run waits for every goroutine in the bubble to exit before returning.
+// Timers that are still pending in the bubble at that point are
+// discarded. If every goroutine in the bubble is durably blocked and
+// there is no pending timer that could unblock one of them, Run panics
+// with a deadlock error.
+//
+// The bubble's fake clock starts at midnight UTC 2000-01-01.
+//
+// Run may not be called from within a bubble.
+//
+// Provided by package runtime.
+func Run(f func())
+
+// Wait blocks until every goroutine within the current bubble,
+// other than the current goroutine, is durably blocked.
+// It does not advance the fake clock.
+//
+// Wait panics if called from a goroutine that is not in a bubble,
+// or if two goroutines in the same bubble call it at the same time.
+//
+// Provided by package runtime.
+func Wait()
@@ -0,0 +1,309 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// epoch is the time at which the fake clock of a bubble starts.
+var epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestNow(t *testing.T) {
+	synctest.Run(func() {
+		if got := time.Now(); !got.Equal(epoch) {
+			t.Errorf("time.Now() = %v, want %v", got, epoch)
+		}
+		time.Sleep(time.Second)
+		if got, want := time.Now(), epoch.Add(time.Second); !got.Equal(want) {
+			t.Errorf("after Sleep: time.Now() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestSleep(t *testing.T) {
+	synctest.Run(func() {
+		start := time.Now()
+		time.Sleep(24 * time.Hour)
+		if got := time.Since(start); got != 24*time.Hour {
+			t.Errorf("time.Since(start) = %v, want 24h", got)
+		}
+	})
+}
+
+func TestSleepConcurrent(t *testing.T) {
+	synctest.Run(func() {
+		var (
+			mu    sync.Mutex
+			order []int
+			wg    sync.WaitGroup
+		)
+		for i := 5; i > 0; i-- {
+			i := i
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				time.Sleep(time.Duration(i) * time.Second)
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+			}()
+		}
+		time.Sleep(10 * time.Second)
+		wg.Wait()
+		if got, want := fmt.Sprint(order), "[1 2 3 4 5]"; got != want {
+			t.Errorf("goroutines woke in order %v, want %v", got, want)
+		}
+		if got, want := time.Now(), epoch.Add(10*time.Second); !got.Equal(want) {
+			t.Errorf("time.Now() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestTimer(t *testing.T) {
+	synctest.Run(func() {
+		start := time.Now()
+		tm := time.NewTimer(time.Minute)
+		got := <-tm.C
+		if want := start.Add(time.Minute); !got.Equal(want) {
+			t.Errorf("timer fired at %v, want %v", got, want)
+		}
+	})
+}
+
+func TestTimerStopReset(t *testing.T) {
+	synctest.Run(func() {
+		start := time.Now()
+		tm := time.NewTimer(time.Hour)
+		if !tm.Stop() {
+			t.Errorf("Stop of pending timer = false, want true")
+		}
+		tm.Reset(2 * time.Hour)
+		tm2 := time.NewTimer(3 * time.Hour)
+		select {
+		case <-tm.C:
+		case <-tm2.C:
+			t.Errorf("later timer fired first")
+		}
+		if got, want := time.Since(start), 2*time.Hour; got != want {
+			t.Errorf("reset timer fired after %v, want %v", got, want)
+		}
+		tm2.Stop()
+	})
+}
+
+func TestTicker(t *testing.T) {
+	synctest.Run(func() {
+		start := time.Now()
+		tk := time.NewTicker(time.Second)
+		defer tk.Stop()
+		for i := 1; i <= 3; i++ {
+			got := <-tk.C
+			if want := start.Add(time.Duration(i) * time.Second); !got.Equal(want) {
+				t.Errorf("tick %v at %v, want %v", i, got, want)
+			}
+		}
+		tk.Reset(time.Minute)
+		<-tk.C
+		if got, want := time.Since(start), 3*time.Second+time.Minute; got != want {
+			t.Errorf("tick after Reset at %v, want %v", got, want)
+		}
+	})
+}
+
+func TestAfterFunc(t *testing.T) {
+	synctest.Run(func() {
+		done := make(chan time.Time)
+		time.AfterFunc(time.Hour, func() {
+			done <- time.Now()
+		})
+		if got, want := <-done, epoch.Add(time.Hour); !got.Equal(want) {
+			t.Errorf("AfterFunc ran at %v, want %v", got, want)
+		}
+	})
+}
+
+func TestContextWithTimeout(t *testing.T) {
+	synctest.Run(func() {
+		const timeout = 5 * time.Second
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		time.Sleep(timeout - time.Nanosecond)
+		synctest.Wait()
+		if err := ctx.Err(); err != nil {
+			t.Fatalf("before timeout: ctx.Err() = %v, want nil", err)
+		}
+
+		time.Sleep(time.Nanosecond)
+		synctest.Wait()
+		if err := ctx.Err(); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("after timeout: ctx.Err() = %v, want DeadlineExceeded", err)
+		}
+	})
+}
+
+func TestWait(t *testing.T) {
+	synctest.Run(func() {
+		var (
+			mu   sync.Mutex
+			done bool
+		)
+		ch := make(chan struct{})
+		go func() {
+			<-ch
+			mu.Lock()
+			done = true
+			mu.Unlock()
+		}()
+		synctest.Wait()
+		mu.Lock()
+		if done {
+			t.Errorf("goroutine finished before its channel was closed")
+		}
+		mu.Unlock()
+		close(ch)
+		synctest.Wait()
+		mu.Lock()
+		if !done {
+			t.Errorf("goroutine not finished after Wait")
+		}
+		mu.Unlock()
+		if got := time.Now(); !got.Equal(epoch) {
+			t.Errorf("Wait advanced the clock to %v", got)
+		}
+	})
+}
+
+func TestWaitRace(t *testing.T) {
+	synctest.Run(func() {
+		// Wait establishes a happens-before relationship,
+		// so this is not a data race.
+		x := 0
+		go func() {
+			x = 1
+		}()
+		synctest.Wait()
+		if x != 1 {
+			t.Errorf("x = %v, want 1", x)
+		}
+	})
+}
+
+func TestRunWaitsForGoroutines(t *testing.T) {
+	var n int
+	synctest.Run(func() {
+		for i := 0; i < 10; i++ {
+			go func() {
+				time.Sleep(time.Hour)
+				n++
+			}()
+			time.Sleep(time.Minute)
+		}
+	})
+	if n != 10 {
+		t.Errorf("%v goroutines finished before Run returned, want 10", n)
+	}
+}
+
+func TestDiscardedTimers(t *testing.T) {
+	synctest.Run(func() {
+		time.AfterFunc(time.Hour, func() {
+			t.Errorf("timer ran after all goroutines in the bubble exited")
+		})
+	})
+}
+
+func TestDeadlock(t *testing.T) {
+	wantPanic(t, "deadlock: all goroutines in bubble are blocked", func() {
+		synctest.Run(func() {
+			<-make(chan struct{})
+		})
+	})
+}
+
+func TestRunInBubble(t *testing.T) {
+	synctest.Run(func() {
+		wantPanic(t, "synctest.Run called from within a synctest bubble", func() {
+			synctest.Run(func() {})
+		})
+	})
+}
+
+func TestWaitOutsideBubble(t *testing.T) {
+	wantPanic(t, "goroutine is not in a bubble", func() {
+		synctest.Wait()
+	})
+}
+
+func TestChannelFromOutsideBubble(t *testing.T) {
+	var ch chan int
+	synctest.Run(func() {
+		ch = make(chan int, 1)
+	})
+	wantPanic(t, "send on synctest channel from outside bubble", func() {
+		ch <- 1
+	})
+	wantPanic(t, "receive on synctest channel from outside bubble", func() {
+		<-ch
+	})
+	wantPanic(t, "close of synctest channel from outside bubble", func() {
+		close(ch)
+	})
+}
+
+func TestChannelFromOtherBubble(t *testing.T) {
+	var ch chan int
+	synctest.Run(func() {
+		ch = make(chan int)
+	})
+	synctest.Run(func() {
+		wantPanic(t, "send on synctest channel from outside bubble", func() {
+			ch <- 1
+		})
+		wantPanic(t, "receive on synctest channel from outside bubble", func() {
+			<-ch
+		})
+		wantPanic(t, "close of synctest channel from outside bubble", func() {
+			close(ch)
+		})
+	})
+}
+
+func TestChannelFromOutsideBubbleNotDurable(t *testing.T) {
+	// A goroutine in a bubble blocked on a channel created
+	// outside the bubble is not durably blocked.
+	ch := make(chan struct{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(ch)
+	}()
+	synctest.Run(func() {
+		<-ch
+	})
+}
+
+func wantPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		e := recover()
+		if e == nil {
+			t.Errorf("got no panic, want %q", want)
+			return
+		}
+		if got := fmt.Sprint(e); !strings.Contains(got, want) {
+			t.Errorf("got panic %q, want %q", got, want)
+		}
+	}()
+	f()
+}
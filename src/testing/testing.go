@@ -402,6 +402,7 @@ func Init() {
 	parallel = flag.Int("test.parallel", runtime.GOMAXPROCS(0), "run at most `n` tests in parallel")
 	testlog = flag.String("test.testlogfile", "", "write test action log to `file` (for use only by cmd/go)")
 	shuffle = flag.String("test.shuffle", "off", "randomize the execution order of tests and benchmarks")
+	goroutineLeak = flag.Bool("test.goroutineleak", false, "fail tests that leak goroutines blocked forever on unreachable objects")
 
 	initBenchmarkFlags()
 	initFuzzFlags()
@@ -430,6 +431,7 @@ var (
 	cpuListStr           *string
 	parallel             *int
 	shuffle              *string
+	goroutineLeak        *bool
 	testlog              *string
 
 	haveExamples bool // are there examples?
@@ -1264,6 +1266,9 @@ func (t *T) Parallel() {
 	t.signal <- true   // Release calling test.
 	<-t.parent.barrier // Wait for the parent test to complete.
 	t.context.waitParallel()
+	if leaks != nil {
+		leaks.startParallel()
+	}
 
 	if t.chatty != nil {
 		t.chatty.Updatef(t.name, "=== CONT  %s\n", t.name)
@@ -1420,6 +1425,20 @@ func tRunner(t *T, fn func(t *T)) {
 			// test. See comment in Run method.
 			t.context.release()
 		}
+		if leaks != nil && t.parent != nil && !t.context.isFuzzing {
+			// Check after cleanups and parallel subtests have finished,
+			// so that goroutines they leak are reported by the subtests
+			// and goroutines they stop are not reported at all.
+			failed := t.Failed()
+			if n, stacks, err := leaks.checkTest(t.isParallel); err != nil {
+				t.Errorf("checking for leaked goroutines: %v", err)
+			} else if n > 0 {
+				t.Errorf("%d leaked goroutine(s):\n\n%s", n, stacks)
+			}
+			if t.Failed() && !failed {
+				atomic.AddUint32(&numFailed, 1)
+			}
+		}
 		t.report() // Report after all subtests have finished.
 
 		// Do not lock t.done to allow race detector to detect race in case
@@ -1580,7 +1599,9 @@ func (f matchStringOnly) SetPanicOnExit0(bool)                        {}
 func (f matchStringOnly) CoordinateFuzzing(time.Duration, int64, time.Duration, int64, int, []corpusEntry, []reflect.Type, string, string) error {
 	return errMain
 }
-func (f matchStringOnly) RunFuzzWorker(func(corpusEntry) error) error { return errMain }
+func (f matchStringOnly) RunFuzzWorker([]reflect.Type, func(corpusEntry) error) error {
+	return errMain
+}
 func (f matchStringOnly) ReadCorpus(string, []reflect.Type) ([]corpusEntry, error) {
 	return nil, errMain
 }
@@ -1630,7 +1651,7 @@ type testDeps interface {
 	StopTestLog() error
 	WriteProfileTo(string, io.Writer, int) error
 	CoordinateFuzzing(time.Duration, int64, time.Duration, int64, int, []corpusEntry, []reflect.Type, string, string) error
-	RunFuzzWorker(func(corpusEntry) error) error
+	RunFuzzWorker([]reflect.Type, func(corpusEntry) error) error
 	ReadCorpus(string, []reflect.Type) ([]corpusEntry, error)
 	CheckCorpus([]any, []reflect.Type) error
 	ResetCoverage()
@@ -1847,6 +1868,15 @@ func runTests(matchString func(pat, str string) (bool, error), tests []InternalT
 			}
 			ok = ok && !t.Failed()
 			ran = ran || t.ran
+			if leaks != nil {
+				if n, stacks, err := leaks.checkAll(); err != nil {
+					fmt.Printf("testing: checking for leaked goroutines: %v\n", err)
+					ok = false
+				} else if n > 0 {
+					fmt.Printf("testing: %d leaked goroutine(s) not attributed to a test:\n\n%s\n", n, stacks)
+					ok = false
+				}
+			}
 		}
 	}
 	return ran, ok
@@ -1889,6 +1919,9 @@ func (m *M) before() {
 	if *mutexProfile != "" && *mutexProfileFraction >= 0 {
 		runtime.SetMutexProfileFraction(*mutexProfileFraction)
 	}
+	if *goroutineLeak {
+		leaks = newLeakChecker(m.deps)
+	}
 	if *coverProfile != "" && cover.Mode == "" {
 		fmt.Fprintf(os.Stderr, "testing: cannot use -test.coverprofile because test binary was not built with coverage enabled\n")
 		os.Exit(2)
@@ -1056,8 +1056,14 @@ func daysSinceEpoch(year int) uint64 {
 // Provided by package runtime.
 func now() (sec int64, nsec int32, mono int64)
 
-// runtimeNano returns the current value of the runtime clock in nanoseconds.
-//go:linkname runtimeNano runtime.nanotime
+// runtimeNow returns the same values as now, except in a goroutine of a
+// testing/synctest bubble, where it returns the bubble's fake time.
+// Provided by package runtime.
+func runtimeNow() (sec int64, nsec int32, mono int64)
+
+// runtimeNano returns the current value of the runtime clock in nanoseconds,
+// or of the fake clock in a goroutine of a testing/synctest bubble.
+// Provided by package runtime.
 func runtimeNano() int64
 
 // Monotonic times are reported as offsets from startNano.
@@ -1070,7 +1076,7 @@ var startNano int64 = runtimeNano() - 1
 
 // Now returns the current local time.
 func Now() Time {
-	sec, nsec, mono := now()
+	sec, nsec, mono := runtimeNow()
 	mono -= startNano
 	sec += unixToInternal - minWall
 	if uint64(sec)>>33 != 0 {
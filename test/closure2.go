@@ -1,4 +1,4 @@
-// run
+// run -gcflags=-lang=go1.17
 
 // Copyright 2015 The Go Authors. All rights reserved.
 // Use of this source code is governed by a BSD-style
@@ -0,0 +1,102 @@
+// run
+
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test that variables declared by for and range loops are
+// per-iteration when captured or address-taken.
+
+package main
+
+import "fmt"
+
+func threeClause() {
+	var fs []func() int
+	for i := 0; i < 3; i++ {
+		fs = append(fs, func() int { return i })
+	}
+	for want, f := range fs {
+		if got := f(); got != want {
+			panic(fmt.Sprintf("threeClause: got %d, want %d", got, want))
+		}
+	}
+}
+
+func threeClauseModified() {
+	// Changes made to the variable in the body are visible to the post
+	// statement, and to closures capturing that iteration's variable.
+	var fs []func() int
+	for i := 0; i < 10; i++ {
+		fs = append(fs, func() int { return i })
+		i += 2
+	}
+	for n, f := range fs {
+		if got, want := f(), 3*n+2; got != want {
+			panic(fmt.Sprintf("threeClauseModified: got %d, want %d", got, want))
+		}
+	}
+}
+
+func threeClauseContinue() {
+	var ps []*int
+	for i := 0; i < 4; i++ {
+		ps = append(ps, &i)
+		if i%2 == 0 {
+			continue
+		}
+	}
+	for want, p := range ps {
+		if *p != want {
+			panic(fmt.Sprintf("threeClauseContinue: got %d, want %d", *p, want))
+		}
+	}
+}
+
+func threeClauseMulti() {
+	var fs []func() int
+	for i, j := 0, 10; i < j; i, j = i+1, j-1 {
+		fs = append(fs, func() int { return 100*i + j })
+	}
+	for n, f := range fs {
+		if got, want := f(), 100*n+10-n; got != want {
+			panic(fmt.Sprintf("threeClauseMulti: got %d, want %d", got, want))
+		}
+	}
+}
+
+func rangeLoop() {
+	var fs []func() (int, string)
+	for i, s := range []string{"a", "b", "c"} {
+		fs = append(fs, func() (int, string) { return i, s })
+	}
+	for n, f := range fs {
+		i, s := f()
+		if i != n || s != string(rune('a'+n)) {
+			panic(fmt.Sprintf("rangeLoop: got %d, %q at %d", i, s, n))
+		}
+	}
+}
+
+func rangeLoopGoroutines() {
+	c := make(chan int)
+	for _, v := range []int{1, 2, 4, 8} {
+		go func() { c <- v }()
+	}
+	sum := 0
+	for i := 0; i < 4; i++ {
+		sum += <-c
+	}
+	if sum != 15 {
+		panic(fmt.Sprintf("rangeLoopGoroutines: got %d, want 15", sum))
+	}
+}
+
+func main() {
+	threeClause()
+	threeClauseModified()
+	threeClauseContinue()
+	threeClauseMulti()
+	rangeLoop()
+	rangeLoopGoroutines()
+}
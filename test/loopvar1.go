@@ -0,0 +1,31 @@
+// run -gcflags=-lang=go1.17
+
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test that loop variables are shared by all iterations
+// before Go 1.18.
+
+package main
+
+import "fmt"
+
+func main() {
+	var fs []func() int
+	for i := 0; i < 3; i++ {
+		fs = append(fs, func() int { return i })
+	}
+	for _, v := range []int{10, 20, 30} {
+		fs = append(fs, func() int { return v })
+	}
+	for n, f := range fs {
+		want := 3
+		if n >= 3 {
+			want = 30
+		}
+		if got := f(); got != want {
+			panic(fmt.Sprintf("closure %d: got %d, want %d", n, got, want))
+		}
+	}
+}
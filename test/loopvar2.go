@@ -0,0 +1,25 @@
+// errorcheck -0 -d=loopvar=1
+
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test the reports of -d=loopvar about loops whose variables
+// became per-iteration.
+
+package p
+
+var sink []*int
+
+func f(s []int) (fs []func() int) {
+	for i := 0; i < len(s); i++ { // ERROR "loop variable i now per-iteration, stack-allocated"
+		fs = append(fs, func() int { return i })
+	}
+	for _, v := range s { // ERROR "loop variable v now per-iteration, heap-allocated"
+		sink = append(sink, &v)
+	}
+	for i, v := range s { // no report, not captured
+		s[i] = v + 1
+	}
+	return fs
+}
@@ -0,0 +1,257 @@
+// run -gcflags=-G=3
+
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test range over functions.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Seq[V any] func(yield func(V) bool)
+
+func count(n int) func(func(int) bool) {
+	return func(yield func(int) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func pairs(s []string) func(func(int, string) bool) {
+	return func(yield func(int, string) bool) {
+		for i, v := range s {
+			if !yield(i, v) {
+				return
+			}
+		}
+	}
+}
+
+func check(name string, got, want interface{}) {
+	if g, w := fmt.Sprint(got), fmt.Sprint(want); g != w {
+		panic(fmt.Sprintf("%s: got %s, want %s", name, g, w))
+	}
+}
+
+func find(s []string, x string) (int, bool) {
+	for i, v := range pairs(s) {
+		if v == x {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func named() (n int, err error) {
+	for i := range count(10) {
+		n = i
+		if i == 3 {
+			return
+		}
+	}
+	return 100, nil
+}
+
+func nested() string {
+	var out []string
+outer:
+	for i := range count(4) {
+	inner:
+		for j := range count(4) {
+			switch {
+			case j == 2:
+				continue outer
+			case i == 3:
+				break outer
+			case i == 1:
+				break inner
+			}
+			out = append(out, fmt.Sprint(i, j))
+		}
+		out = append(out, "end")
+	}
+	return strings.Join(out, ",")
+}
+
+func regularOuter() []int {
+	var out []int
+L:
+	for k := 0; k < 3; k++ {
+		for i := range count(5) {
+			if i == 2 {
+				continue L
+			}
+			if k == 2 {
+				break L
+			}
+			out = append(out, k*10+i)
+		}
+	}
+	return out
+}
+
+func gotoOut() int {
+	n := 0
+	for i := range count(10) {
+		n += i
+		if i == 4 {
+			goto done
+		}
+	}
+	n = -1
+done:
+	return n
+}
+
+func assign() (int, string) {
+	var i int
+	var s string
+	for i, s = range pairs([]string{"a", "b", "c"}) {
+	}
+	return i, s
+}
+
+func sum[T int | float64](seq Seq[T]) T {
+	var t T
+	for v := range seq {
+		t += v
+	}
+	return t
+}
+
+func first[S ~func(func(int) bool)](seq S) int {
+	for v := range seq {
+		return v
+	}
+	return -1
+}
+
+func capture() []int {
+	var fs []func() int
+	for i := range count(3) {
+		fs = append(fs, func() int { return i })
+	}
+	var out []int
+	for _, f := range fs {
+		out = append(out, f())
+	}
+	return out
+}
+
+func noVars() int {
+	n := 0
+	for range count(5) {
+		n++
+	}
+	return n
+}
+
+func nestedReturn() string {
+	for i := range count(3) {
+		for j := range count(3) {
+			if i == 1 && j == 2 {
+				return fmt.Sprint(i, j)
+			}
+		}
+	}
+	return "none"
+}
+
+func funcLit() int {
+	f := func() int {
+		for i := range count(10) {
+			if i == 7 {
+				return i
+			}
+		}
+		return 0
+	}
+	return f()
+}
+
+func expectPanic(name, want string, f func()) {
+	defer func() {
+		e := recover()
+		if e == nil {
+			panic(name + ": did not panic")
+		}
+		if got := fmt.Sprint(e); !strings.Contains(got, want) {
+			panic(fmt.Sprintf("%s: got panic %q, want %q", name, got, want))
+		}
+	}()
+	f()
+}
+
+func main() {
+	i, ok := find([]string{"x", "y", "z"}, "y")
+	check("find", []interface{}{i, ok}, []interface{}{1, true})
+	i, ok = find([]string{"x"}, "q")
+	check("find missing", []interface{}{i, ok}, []interface{}{-1, false})
+	n, err := named()
+	check("named", []interface{}{n, err}, []interface{}{3, nil})
+	check("nested", nested(), "0 0,0 1,end,2 0,2 1")
+	check("regularOuter", regularOuter(), []int{0, 1, 10, 11})
+	check("gotoOut", gotoOut(), 10)
+	i, s := assign()
+	check("assign", []interface{}{i, s}, []interface{}{2, "c"})
+	check("sum", sum(Seq[int](count(5))), 10)
+	check("sum float", sum[float64](func(yield func(float64) bool) { _ = yield(1.5) && yield(2) }), 3.5)
+	check("first", first(count(3)), 0)
+	check("capture", capture(), []int{0, 1, 2})
+	check("noVars", noVars(), 5)
+	check("nestedReturn", nestedReturn(), "1 2")
+	check("funcLit", funcLit(), 7)
+
+	expectPanic("call after break", "after function for loop body returned false", func() {
+		f := func(yield func(int) bool) {
+			yield(1)
+			yield(2)
+		}
+		for range f {
+			break
+		}
+	})
+	expectPanic("call after exit", "after whole loop exit", func() {
+		var saved func(int) bool
+		f := func(yield func(int) bool) {
+			saved = yield
+		}
+		for range f {
+		}
+		saved(1)
+	})
+	expectPanic("call after panic", "after loop body panic", func() {
+		f := func(yield func(int) bool) {
+			defer func() {
+				recover()
+				yield(2)
+			}()
+			yield(1)
+		}
+		for range f {
+			panic("body")
+		}
+	})
+	expectPanic("swallowed panic", "did not resume panicking", func() {
+		f := func(yield func(int) bool) {
+			defer func() { recover() }()
+			yield(1)
+		}
+		for range f {
+			panic("body")
+		}
+	})
+	expectPanic("body panic", "body", func() {
+		for range count(3) {
+			panic("body")
+		}
+	})
+}
@@ -0,0 +1,124 @@
+// run -gcflags=-G=3
+
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test defer in the body of a range-over-func loop.
+
+package main
+
+import "fmt"
+
+func count(n int) func(func(int) bool) {
+	return func(yield func(int) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+var out []string
+
+func log(format string, args ...interface{}) {
+	out = append(out, fmt.Sprintf(format, args...))
+}
+
+func check(name string, want ...string) {
+	if g, w := fmt.Sprint(out), fmt.Sprint(want); g != w {
+		panic(fmt.Sprintf("%s: got %s, want %s", name, g, w))
+	}
+	out = nil
+}
+
+func simple() {
+	defer log("first")
+	for i := range count(3) {
+		defer log("body %d", i)
+		log("loop %d", i)
+	}
+	defer log("last")
+	log("return")
+}
+
+func nested() {
+	for i := range count(2) {
+		for j := range count(2) {
+			defer log("body %d %d", i, j)
+		}
+		func() {
+			defer log("literal %d", i)
+		}()
+	}
+	log("return")
+}
+
+func breakOut() int {
+	for i := range count(10) {
+		defer log("body %d", i)
+		if i == 1 {
+			return i
+		}
+	}
+	return -1
+}
+
+func inLoop() {
+	for k := 0; k < 2; k++ {
+		for i := range count(2) {
+			defer log("body %d %d", k, i)
+		}
+	}
+	log("return")
+}
+
+func recovered() (r int) {
+	for i := range count(3) {
+		defer func() {
+			if recover() != nil {
+				r = i
+			}
+		}()
+		if i == 1 {
+			panic("boom")
+		}
+	}
+	return -1
+}
+
+func noReturn() (r int) {
+	for i := range count(2) {
+		defer func() {
+			if recover() != nil {
+				r += i + 1
+			}
+		}()
+	}
+	panic("boom")
+}
+
+func main() {
+	simple()
+	check("simple", "loop 0", "loop 1", "loop 2", "return", "last", "body 2", "body 1", "body 0", "first")
+
+	nested()
+	check("nested", "literal 0", "literal 1", "return", "body 1 1", "body 1 0", "body 0 1", "body 0 0")
+
+	if r := breakOut(); r != 1 {
+		panic(fmt.Sprintf("breakOut: got %d, want 1", r))
+	}
+	check("breakOut", "body 1", "body 0")
+
+	inLoop()
+	check("inLoop", "return", "body 1 1", "body 1 0", "body 0 1", "body 0 0")
+
+	if r := recovered(); r != 1 {
+		panic(fmt.Sprintf("recovered: got %d, want 1", r))
+	}
+
+	if r := noReturn(); r != 2 {
+		panic(fmt.Sprintf("noReturn: got %d, want 2", r))
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package a
+
+func Count(n int) func(func(int) bool) {
+	return func(yield func(int) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// Collect appends the first max values of seq to out in reverse order
+// and returns how many it appended.
+func Collect[T any](out *[]T, seq func(func(T) bool), max int) (n int) {
+	for v := range seq {
+		if n == max {
+			break
+		}
+		defer func() { *out = append(*out, v) }()
+		n++
+	}
+	return n
+}
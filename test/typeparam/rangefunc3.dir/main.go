@@ -0,0 +1,20 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"a"
+	"fmt"
+)
+
+func main() {
+	var out []int
+	if got, want := a.Collect(&out, a.Count(5), 3), 3; got != want {
+		panic(fmt.Sprintf("got %d, want %d", got, want))
+	}
+	if got, want := fmt.Sprint(out), "[2 1 0]"; got != want {
+		panic(fmt.Sprintf("got %s, want %s", got, want))
+	}
+}